@@ -0,0 +1,456 @@
+package adl_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/storage"
+	"github.com/ipld/go-ipld-prime/traversal"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/adl"
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	logcodec "github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+)
+
+func uintBytes(n ipld.Node, field string) (uint64, error) {
+	fieldNode, err := n.LookupByString(field)
+	if err != nil {
+		return 0, err
+	}
+	b, err := fieldNode.AsBytes()
+	if err != nil {
+		return 0, err
+	}
+	return new(big.Int).SetBytes(b).Uint64(), nil
+}
+
+func TestStateMap(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	accounts := make(map[common.Address]*types.StateAccount)
+	kvs := make(map[string][]byte)
+	for i := int64(1); i <= 100; i++ {
+		addr := common.BigToAddress(big.NewInt(i))
+		accounts[addr] = &types.StateAccount{
+			Nonce:    uint64(i),
+			Balance:  uint256.NewInt(uint64(i) * 1000),
+			Root:     types.EmptyRootHash,
+			CodeHash: types.EmptyCodeHash.Bytes(),
+		}
+		enc, _ := rlp.EncodeToBytes(accounts[addr])
+		kvs[string(shared.AddressToLeafKey(addr))] = enc
+	}
+	root := testutil.BuildTrieLink(t, store, state_trie.MultiCodecType, kvs)
+	lsys := codecs.NewLinkSystem(store)
+	stateMap := adl.NewStateMap(lsys, root)
+
+	for addr, acct := range accounts {
+		acctNode, err := stateMap.LookupAccount(addr)
+		if err != nil {
+			t.Fatalf("unable to look up account %s: %v", addr.Hex(), err)
+		}
+		if nonce, err := uintBytes(acctNode, "Nonce"); err != nil || nonce != acct.Nonce {
+			t.Errorf("account %s nonce (%d) does not match expected nonce (%d): %v", addr.Hex(), nonce, acct.Nonce, err)
+		}
+		if _, err := stateMap.LookupByString("0x" + hex.EncodeToString(shared.AddressToLeafKey(addr))); err != nil {
+			t.Errorf("unable to look up account %s by its 0x prefixed key: %v", addr.Hex(), err)
+		}
+	}
+	if _, err := stateMap.LookupAccount(common.HexToAddress("0xdeadbeef")); err == nil {
+		t.Error("expected an error looking up an account that is not in the trie")
+	} else if _, ok := err.(ipld.ErrNotExists); !ok {
+		t.Errorf("expected an ipld.ErrNotExists looking up an account that is not in the trie, got %v", err)
+	}
+	if _, err := stateMap.LookupByString("abcd"); err == nil {
+		t.Error("expected an error looking up a key of the wrong length")
+	}
+
+	// the length is counted once, by whichever of the concurrent callers comes first
+	lengths := make(chan int64, 8)
+	for i := 0; i < cap(lengths); i++ {
+		go func() { lengths <- stateMap.Length() }()
+	}
+	for i := 0; i < cap(lengths); i++ {
+		if length := <-lengths; length != int64(len(accounts)) {
+			t.Errorf("state map length (%d) does not match expected length (%d)", length, len(accounts))
+		}
+	}
+	var prevKey string
+	count := 0
+	for it := stateMap.MapIterator(); !it.Done(); {
+		k, v, err := it.Next()
+		if err != nil {
+			t.Fatalf("unable to iterate state map: %v", err)
+		}
+		key, _ := k.AsString()
+		if key <= prevKey {
+			t.Errorf("state map key %s does not follow %s", key, prevKey)
+		}
+		prevKey = key
+		keyBytes, _ := hex.DecodeString(key)
+		expected := kvs[string(keyBytes)]
+		if expected == nil {
+			t.Fatalf("state map key %s is not in the trie", key)
+		}
+		var acct types.StateAccount
+		if err := rlp.DecodeBytes(expected, &acct); err != nil {
+			t.Fatal(err)
+		}
+		if nonce, err := uintBytes(v, "Nonce"); err != nil || nonce != acct.Nonce {
+			t.Errorf("state map entry %s nonce (%d) does not match expected nonce (%d): %v", key, nonce, acct.Nonce, err)
+		}
+		count++
+	}
+	if count != len(accounts) {
+		t.Errorf("state map iteration yielded %d entries, expected %d", count, len(accounts))
+	}
+
+	// the map can be traversed like any other node
+	addr := common.BigToAddress(big.NewInt(42))
+	path := ipld.ParsePath(hex.EncodeToString(shared.AddressToLeafKey(addr)) + "/Balance")
+	prog := traversal.Progress{Cfg: &traversal.Config{LinkSystem: lsys, LinkTargetNodePrototypeChooser: codecs.NodePrototypeChooser}}
+	if err := prog.Focus(stateMap, path, func(_ traversal.Progress, n ipld.Node) error {
+		balance, err := n.AsBytes()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(balance, accounts[addr].Balance.Bytes()) {
+			t.Errorf("balance (%x) does not match expected balance (%x)", balance, accounts[addr].Balance.Bytes())
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("unable to traverse %s: %v", path.String(), err)
+	}
+}
+
+func TestTransactionList(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	// more than 128 transactions, so the RLP encoded index keys are not in index order
+	txs := make(types.Transactions, 150)
+	kvs := make(map[string][]byte, len(txs))
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.BigToAddress(big.NewInt(int64(i))), big.NewInt(1), 21000, big.NewInt(1), nil)
+		key, _ := rlp.EncodeToBytes(uint64(i))
+		enc, _ := txs[i].MarshalBinary()
+		kvs[string(key)] = enc
+	}
+	root := testutil.BuildTrieLink(t, store, tx_trie.MultiCodecType, kvs)
+	if expected := types.DeriveSha(txs, gethtrie.NewStackTrie(nil)); !bytes.Equal(root.(cidlink.Link).Hash()[2:], expected.Bytes()) {
+		t.Fatalf("transaction trie root does not match expected root %s", expected.Hex())
+	}
+	lsys := codecs.NewLinkSystem(store)
+	txList := adl.NewTransactionList(lsys, root)
+
+	if txList.Length() != int64(len(txs)) {
+		t.Errorf("transaction list length (%d) does not match expected length (%d)", txList.Length(), len(txs))
+	}
+	count := 0
+	for it := txList.ListIterator(); !it.Done(); {
+		idx, txNode, err := it.Next()
+		if err != nil {
+			t.Fatalf("unable to iterate transaction list: %v", err)
+		}
+		if nonce, err := uintBytes(txNode, "AccountNonce"); err != nil || nonce != uint64(idx) {
+			t.Errorf("transaction %d nonce (%d) does not match its index: %v", idx, nonce, err)
+		}
+		count++
+	}
+	if count != len(txs) {
+		t.Errorf("transaction list iteration yielded %d transactions, expected %d", count, len(txs))
+	}
+	if _, err := txList.LookupByIndex(int64(len(txs))); err == nil {
+		t.Error("expected an error looking up an index past the end of the list")
+	} else if _, ok := err.(ipld.ErrNotExists); !ok {
+		t.Errorf("expected an ipld.ErrNotExists looking up an index past the end of the list, got %v", err)
+	}
+
+	// a range selector picks out transactions 5 through 20
+	ssb := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any)
+	sel, err := ssb.ExploreRange(5, 21, ssb.Matcher()).Selector()
+	if err != nil {
+		t.Fatalf("unable to compile range selector: %v", err)
+	}
+	var nonces []uint64
+	prog := traversal.Progress{Cfg: &traversal.Config{LinkSystem: lsys, LinkTargetNodePrototypeChooser: codecs.NodePrototypeChooser}}
+	if err := prog.WalkMatching(txList, sel, func(_ traversal.Progress, n ipld.Node) error {
+		nonce, err := uintBytes(n, "AccountNonce")
+		nonces = append(nonces, nonce)
+		return err
+	}); err != nil {
+		t.Fatalf("unable to walk range selector: %v", err)
+	}
+	if len(nonces) != 16 || nonces[0] != 5 || nonces[15] != 20 {
+		t.Errorf("expected the range selector to match transactions 5 through 20, got nonces %v", nonces)
+	}
+}
+
+func TestReceiptList(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	txs := make(types.Transactions, 140)
+	rcts := make(types.Receipts, len(txs))
+	txKVs := make(map[string][]byte, len(txs))
+	rctKVs := make(map[string][]byte, len(rcts))
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.BigToAddress(big.NewInt(int64(i))), big.NewInt(1), 21000, big.NewInt(1), nil)
+		rcts[i] = &types.Receipt{
+			Type:              types.LegacyTxType,
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(i+1) * 21000,
+			Logs:              []*types.Log{},
+		}
+		rcts[i].Bloom = types.CreateBloom(rcts[i])
+		key, _ := rlp.EncodeToBytes(uint64(i))
+		txEnc, _ := txs[i].MarshalBinary()
+		rctEnc, _ := rcts[i].MarshalBinary()
+		txKVs[string(key)] = txEnc
+		rctKVs[string(key)] = rctEnc
+	}
+	txRoot := testutil.BuildTrieLink(t, store, tx_trie.MultiCodecType, txKVs)
+	rctRoot := testutil.BuildTrieLink(t, store, rct_trie.MultiCodecType, rctKVs)
+	if expected := types.DeriveSha(rcts, gethtrie.NewStackTrie(nil)); !bytes.Equal(rctRoot.(cidlink.Link).Hash()[2:], expected.Bytes()) {
+		t.Fatalf("receipt trie root does not match expected root %s", expected.Hex())
+	}
+	lsys := codecs.NewLinkSystem(store)
+	txList := adl.NewTransactionList(lsys, txRoot)
+	rctList := adl.NewReceiptList(lsys, rctRoot)
+
+	if rctList.Length() != txList.Length() {
+		t.Fatalf("receipt list length (%d) does not match transaction list length (%d)", rctList.Length(), txList.Length())
+	}
+	// transactions and their receipts are traversed jointly by index
+	for it := rctList.ListIterator(); !it.Done(); {
+		idx, rctNode, err := it.Next()
+		if err != nil {
+			t.Fatalf("unable to iterate receipt list: %v", err)
+		}
+		txNode, err := txList.LookupByIndex(idx)
+		if err != nil {
+			t.Fatalf("unable to look up transaction %d: %v", idx, err)
+		}
+		nonce, err := uintBytes(txNode, "AccountNonce")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gasUsed, err := uintBytes(rctNode, "CumulativeGasUsed"); err != nil || gasUsed != (nonce+1)*21000 {
+			t.Errorf("receipt %d cumulative gas used (%d) does not match its transaction: %v", idx, gasUsed, err)
+		}
+	}
+	if _, err := rctList.LookupByIndex(-1); err == nil {
+		t.Error("expected an error looking up a negative index")
+	}
+}
+
+func TestReceiptListLogIndexes(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	rctKVs := make(map[string][]byte)
+	// receipt i holds i%3 logs
+	firstLogIndex := make([]int64, 140)
+	var logs int64
+	for i := range firstLogIndex {
+		rct := &types.Receipt{Type: types.DynamicFeeTxType, Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{}}
+		for j := 0; j < i%3; j++ {
+			rct.Logs = append(rct.Logs, &types.Log{Address: common.BigToAddress(big.NewInt(int64(i))), Data: []byte{byte(j)}})
+		}
+		rct.Bloom = types.CreateBloom(rct)
+		firstLogIndex[i] = logs
+		logs += int64(len(rct.Logs))
+		key, _ := rlp.EncodeToBytes(uint64(i))
+		rctKVs[string(key)], _ = rct.MarshalBinary()
+	}
+	lsys := codecs.NewLinkSystem(store)
+	rctList := adl.ReceiptListOptions{LogIndexes: true}.NewReceiptList(lsys, testutil.BuildTrieLink(t, store, rct_trie.MultiCodecType, rctKVs))
+
+	checkLogs := func(idx int64, rctNode ipld.Node) {
+		logsNode, err := rctNode.LookupByString("Logs")
+		if err != nil {
+			t.Fatalf("receipt %d has no logs: %v", idx, err)
+		}
+		if logsNode.Length() != idx%3 {
+			t.Fatalf("expected %d logs in receipt %d, got %d", idx%3, idx, logsNode.Length())
+		}
+		for it := logsNode.ListIterator(); !it.Done(); {
+			i, logNode, _ := it.Next()
+			blockIndexNode, err := logNode.LookupByString(adl.BlockLogIndexField)
+			if err != nil {
+				t.Fatalf("log %d of receipt %d has no block log index: %v", i, idx, err)
+			}
+			txIndexNode, _ := logNode.LookupByString(adl.TxLogIndexField)
+			blockIndex, _ := blockIndexNode.AsInt()
+			txIndex, _ := txIndexNode.AsInt()
+			if blockIndex != firstLogIndex[idx]+i || txIndex != i {
+				t.Errorf("log %d of receipt %d: expected indexes %d and %d, got %d and %d", i, idx, firstLogIndex[idx]+i, i, blockIndex, txIndex)
+			}
+			if _, err := logNode.LookupByString("Address"); err != nil {
+				t.Errorf("log %d of receipt %d lost its address: %v", i, idx, err)
+			}
+		}
+	}
+	// a lookup out of order counts the logs of the receipts before it
+	rctNode, err := rctList.LookupByIndex(131)
+	if err != nil {
+		t.Fatalf("unable to look up receipt: %v", err)
+	}
+	checkLogs(131, rctNode)
+	for it := rctList.ListIterator(); !it.Done(); {
+		idx, rctNode, err := it.Next()
+		if err != nil {
+			t.Fatalf("unable to iterate receipt list: %v", err)
+		}
+		checkLogs(idx, rctNode)
+	}
+
+	// without the option, logs are left as they are decoded
+	rctNode, err = adl.NewReceiptList(lsys, testutil.BuildTrieLink(t, store, rct_trie.MultiCodecType, rctKVs)).LookupByIndex(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logsNode, _ := rctNode.LookupByString("Logs")
+	logNode, _ := logsNode.LookupByIndex(0)
+	if _, err := logNode.LookupByString(adl.BlockLogIndexField); err == nil {
+		t.Error("expected no derived log index by default")
+	}
+}
+
+func TestStorageMap(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	slots := make(map[common.Hash][]byte)
+	kvs := make(map[string][]byte)
+	for i := int64(0); i < 50; i++ {
+		slot := common.BigToHash(big.NewInt(i))
+		slots[slot], _ = rlp.EncodeToBytes(big.NewInt(i*7 + 1).Bytes())
+		kvs[string(crypto.Keccak256(slot.Bytes()))] = slots[slot]
+	}
+	root := testutil.BuildTrieLink(t, store, storage_trie.MultiCodecType, kvs)
+	storageMap := adl.NewStorageMap(codecs.NewLinkSystem(store), root)
+
+	for slot, expected := range slots {
+		valNode, err := storageMap.LookupSlot(slot)
+		if err != nil {
+			t.Fatalf("unable to look up slot %s: %v", slot.Hex(), err)
+		}
+		val, _ := valNode.AsBytes()
+		if !bytes.Equal(val, expected) {
+			t.Errorf("slot %s value (%x) does not match expected value (%x)", slot.Hex(), val, expected)
+		}
+	}
+	if _, err := storageMap.LookupSlot(common.BigToHash(big.NewInt(50))); err == nil {
+		t.Error("expected an error looking up a slot that is not in the trie")
+	}
+
+	// iterating the map yields every slot, keyed by its hash
+	count := 0
+	for it := storageMap.MapIterator(); !it.Done(); {
+		k, v, err := it.Next()
+		if err != nil {
+			t.Fatalf("unable to iterate storage map: %v", err)
+		}
+		key, _ := k.AsString()
+		keyBytes, _ := hex.DecodeString(key)
+		val, _ := v.AsBytes()
+		if !bytes.Equal(val, kvs[string(keyBytes)]) {
+			t.Errorf("storage map entry %s (%x) does not match expected value (%x)", key, val, kvs[string(keyBytes)])
+		}
+		count++
+	}
+	if count != len(slots) || storageMap.Length() != int64(len(slots)) {
+		t.Errorf("storage map holds %d entries (length %d), expected %d", count, storageMap.Length(), len(slots))
+	}
+}
+
+func TestLogIterator(t *testing.T) {
+	g := testutil.NewGenerator(1)
+	blk, receipts := g.Block(32)
+	lsys := codecs.NewLinkSystem(&storage.Memory{})
+	headerLink, err := block.PackBlock(blk, receipts, lsys)
+	if err != nil {
+		t.Fatalf("unable to pack block: %v", err)
+	}
+	headerNode, err := lsys.Load(ipld.LinkContext{}, headerLink, dageth.Type.Header)
+	if err != nil {
+		t.Fatalf("unable to load header: %v", err)
+	}
+	rctRootNode, _ := headerNode.LookupByString("RctRootCID")
+	rctRoot, _ := rctRootNode.AsLink()
+
+	type position struct{ tx, index int64 }
+	var all []*types.Log
+	var positions []position
+	for i, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			positions = append(positions, position{int64(i), int64(len(all))})
+			all = append(all, log)
+		}
+	}
+	if len(all) < 3 {
+		t.Fatalf("expected the block to hold logs, got %d", len(all))
+	}
+	filters := map[string]struct {
+		filter   adl.LogFilter
+		expected func(*types.Log) bool
+	}{
+		"all logs": {adl.LogFilter{}, func(*types.Log) bool { return true }},
+		"address": {adl.LogFilter{Addresses: []common.Address{all[1].Address}}, func(l *types.Log) bool {
+			return l.Address == all[1].Address
+		}},
+		"first topic": {adl.LogFilter{Topics: [][]common.Hash{{all[2].Topics[0]}}}, func(l *types.Log) bool {
+			return len(l.Topics) > 0 && l.Topics[0] == all[2].Topics[0]
+		}},
+		"no match": {adl.LogFilter{Addresses: []common.Address{{0x01}}}, func(*types.Log) bool { return false }},
+	}
+	for name, test := range filters {
+		var expected []position
+		for i, log := range all {
+			if test.expected(log) {
+				expected = append(expected, positions[i])
+			}
+		}
+		var got []position
+		it := adl.NewLogIterator(lsys, rctRoot, test.filter)
+		for !it.Done() {
+			log, err := it.Next()
+			if err != nil {
+				t.Fatalf("unable to iterate %s: %v", name, err)
+			}
+			got = append(got, position{log.TxIndex, log.Index})
+			enc, err := rlp.EncodeToBytes(all[log.Index])
+			if err != nil {
+				t.Fatal(err)
+			}
+			nb := dageth.Type.Log.NewBuilder()
+			if err := logcodec.DecodeBytes(nb, enc); err != nil {
+				t.Fatal(err)
+			}
+			if !ipld.DeepEqual(nb.Build(), log.Log) {
+				t.Errorf("%s log %d does not match the log of the block", name, log.Index)
+			}
+		}
+		if len(got) != len(expected) {
+			t.Errorf("expected %d logs for %s, got %d", len(expected), name, len(got))
+			continue
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Errorf("expected %s log %d at %v, got %v", name, i, expected[i], got[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package adl
+
+import (
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/node/mixins"
+)
+
+// trieList presents a trie keyed by RLP encoded indexes, such as a transaction trie, as a list of the trie values
+type trieList struct {
+	r        *trieReader
+	typeName string
+	// wrap, if set, replaces each value looked up with the node it returns, e.g. to attach derived fields
+	wrap func(idx int64, val ipld.Node) (ipld.Node, error)
+}
+
+var _ ipld.Node = &trieList{}
+
+func (l *trieList) mixin() mixins.List {
+	return mixins.List{TypeName: l.typeName}
+}
+
+func (l *trieList) Kind() ipld.Kind {
+	return ipld.Kind_List
+}
+
+func (l *trieList) LookupByString(key string) (ipld.Node, error) {
+	return l.mixin().LookupByString(key)
+}
+
+func (l *trieList) LookupByNode(key ipld.Node) (ipld.Node, error) {
+	idx, err := key.AsInt()
+	if err != nil {
+		return nil, err
+	}
+	return l.LookupByIndex(idx)
+}
+
+// LookupByIndex looks up the value at the index by walking the trie along the RLP encoding of the index
+func (l *trieList) LookupByIndex(idx int64) (ipld.Node, error) {
+	val, err := l.lookupIndex(idx)
+	if err != nil || l.wrap == nil {
+		return val, err
+	}
+	return l.wrap(idx, val)
+}
+
+// lookupIndex is LookupByIndex without wrap
+func (l *trieList) lookupIndex(idx int64) (ipld.Node, error) {
+	if idx < 0 {
+		return nil, ipld.ErrNotExists{Segment: ipld.PathSegmentOfInt(idx)}
+	}
+	key, err := rlp.EncodeToBytes(uint64(idx))
+	if err != nil {
+		return nil, err
+	}
+	val, err := l.r.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, ipld.ErrNotExists{Segment: ipld.PathSegmentOfInt(idx)}
+	}
+	return val, nil
+}
+
+func (l *trieList) LookupBySegment(seg ipld.PathSegment) (ipld.Node, error) {
+	idx, err := seg.Index()
+	if err != nil {
+		return nil, err
+	}
+	return l.LookupByIndex(idx)
+}
+
+func (l *trieList) MapIterator() ipld.MapIterator {
+	return nil
+}
+
+// ListIterator iterates the values of the trie in index order
+func (l *trieList) ListIterator() ipld.ListIterator {
+	return &trieListIterator{l: l, length: l.Length()}
+}
+
+// Length returns the number of values in the trie, which requires walking the whole trie the first time it is called.
+// It returns -1 if the trie cannot be walked.
+func (l *trieList) Length() int64 {
+	length, err := l.r.count()
+	if err != nil {
+		return -1
+	}
+	return length
+}
+
+func (l *trieList) IsAbsent() bool {
+	return false
+}
+
+func (l *trieList) IsNull() bool {
+	return false
+}
+
+func (l *trieList) AsBool() (bool, error) {
+	return l.mixin().AsBool()
+}
+
+func (l *trieList) AsInt() (int64, error) {
+	return l.mixin().AsInt()
+}
+
+func (l *trieList) AsFloat() (float64, error) {
+	return l.mixin().AsFloat()
+}
+
+func (l *trieList) AsString() (string, error) {
+	return l.mixin().AsString()
+}
+
+func (l *trieList) AsBytes() ([]byte, error) {
+	return l.mixin().AsBytes()
+}
+
+func (l *trieList) AsLink() (ipld.Link, error) {
+	return l.mixin().AsLink()
+}
+
+func (l *trieList) Prototype() ipld.NodePrototype {
+	return basicnode.Prototype.List
+}
+
+// trieListIterator looks up each index in turn, as the trie orders its leaves by the RLP encoding of
+// the index rather than by the index itself
+type trieListIterator struct {
+	l      *trieList
+	idx    int64
+	length int64
+}
+
+func (li *trieListIterator) Next() (int64, ipld.Node, error) {
+	if li.Done() {
+		return -1, nil, ipld.ErrIteratorOverread{}
+	}
+	idx := li.idx
+	li.idx++
+	val, err := li.l.LookupByIndex(idx)
+	return idx, val, err
+}
+
+func (li *trieListIterator) Done() bool {
+	return li.idx >= li.length
+}
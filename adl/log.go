@@ -0,0 +1,246 @@
+package adl
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipld/go-ipld-prime"
+)
+
+// LogList is an ADL presenting the log trie of a receipt as a list of Logs in log index order
+type LogList struct {
+	trieList
+}
+
+// NewLogList returns a LogList over the log trie whose root node is referenced by root,
+// loading the trie nodes through the LinkSystem
+func NewLogList(lsys ipld.LinkSystem, root ipld.Link) *LogList {
+	return &LogList{trieList{r: newTrieReader(lsys, root), typeName: "LogList"}}
+}
+
+// LogFilter selects logs as the eth_getLogs filter does: a log matches if it was emitted by one of the Addresses,
+// and if for every position of Topics its topic at that position is one of the hashes listed there.
+// An empty Addresses list, or an empty list at a position of Topics, matches anything.
+type LogFilter struct {
+	Addresses []common.Address
+	Topics    [][]common.Hash
+}
+
+// Match returns whether the Log node matches the filter
+func (f LogFilter) Match(log ipld.Node) (bool, error) {
+	if len(f.Addresses) > 0 {
+		addrNode, err := log.LookupByString("Address")
+		if err != nil {
+			return false, fmt.Errorf("log is missing an Address node: %v", err)
+		}
+		addr, err := addrNode.AsBytes()
+		if err != nil {
+			return false, err
+		}
+		if !containsAddress(f.Addresses, common.BytesToAddress(addr)) {
+			return false, nil
+		}
+	}
+	if len(f.Topics) == 0 {
+		return true, nil
+	}
+	topicsNode, err := log.LookupByString("Topics")
+	if err != nil {
+		return false, fmt.Errorf("log is missing a Topics node: %v", err)
+	}
+	if int64(len(f.Topics)) > topicsNode.Length() {
+		return false, nil
+	}
+	for i, sub := range f.Topics {
+		if len(sub) == 0 {
+			continue
+		}
+		topicNode, err := topicsNode.LookupByIndex(int64(i))
+		if err != nil {
+			return false, err
+		}
+		topic, err := topicNode.AsBytes()
+		if err != nil {
+			return false, err
+		}
+		if !containsHash(sub, common.BytesToHash(topic)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// mayMatch returns whether a bloom may hold logs matching the filter
+func (f LogFilter) mayMatch(b types.Bloom) bool {
+	if len(f.Addresses) > 0 {
+		found := false
+		for _, addr := range f.Addresses {
+			if types.BloomLookup(b, addr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, sub := range f.Topics {
+		if len(sub) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range sub {
+			if types.BloomLookup(b, topic) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FilteredLog is a log matching a LogFilter, along with its position in the block
+type FilteredLog struct {
+	// TxIndex is the index of the transaction that emitted the log
+	TxIndex int64
+	// Index is the index of the log in the block
+	Index int64
+	Log   ipld.Node
+}
+
+// LogIterator iterates the logs of a block matching a LogFilter, in block order. It walks the receipt trie of the
+// block, skips the receipts whose Bloom rules out a match, and walks the log tries of the others, so only the log
+// tries that may hold matching logs are loaded.
+type LogIterator struct {
+	lsys     ipld.LinkSystem
+	filter   LogFilter
+	receipts ipld.ListIterator
+	logs     ipld.ListIterator
+	txIndex  int64
+	logIndex int64
+	next     *FilteredLog
+	err      error
+}
+
+// NewLogIterator returns a LogIterator over the logs of the block whose receipt trie root node is referenced by
+// rctRoot, loading the trie nodes through the LinkSystem
+func NewLogIterator(lsys ipld.LinkSystem, rctRoot ipld.Link, filter LogFilter) *LogIterator {
+	it := &LogIterator{lsys: lsys, filter: filter, receipts: NewReceiptList(lsys, rctRoot).ListIterator()}
+	it.fetch()
+	return it
+}
+
+// Done returns whether the iterator has no more matching logs, or has failed
+func (it *LogIterator) Done() bool {
+	return it.next == nil && it.err == nil
+}
+
+// Next returns the next matching log, or the error the iteration failed with
+func (it *LogIterator) Next() (FilteredLog, error) {
+	if it.err != nil {
+		err := it.err
+		it.err = nil
+		return FilteredLog{}, err
+	}
+	if it.next == nil {
+		return FilteredLog{}, ipld.ErrIteratorOverread{}
+	}
+	next := *it.next
+	it.fetch()
+	return next, nil
+}
+
+// fetch advances to the next matching log
+func (it *LogIterator) fetch() {
+	it.next = nil
+	for {
+		for it.logs != nil && !it.logs.Done() {
+			_, log, err := it.logs.Next()
+			if err != nil {
+				it.err = fmt.Errorf("unable to load log %d: %v", it.logIndex, err)
+				return
+			}
+			index := it.logIndex
+			it.logIndex++
+			ok, err := it.filter.Match(log)
+			if err != nil {
+				it.err = fmt.Errorf("invalid log %d: %v", index, err)
+				return
+			}
+			if ok {
+				it.next = &FilteredLog{TxIndex: it.txIndex, Index: index, Log: log}
+				return
+			}
+		}
+		it.logs = nil
+		if it.receipts.Done() {
+			return
+		}
+		txIndex, receipt, err := it.receipts.Next()
+		if err != nil {
+			it.err = fmt.Errorf("unable to load receipt %d: %v", txIndex, err)
+			return
+		}
+		it.txIndex = txIndex
+		if err := it.openLogs(receipt); err != nil {
+			it.err = fmt.Errorf("invalid receipt %d: %v", txIndex, err)
+			return
+		}
+	}
+}
+
+// openLogs starts iterating the log trie of the receipt, or skips its logs if its Bloom rules out a match
+func (it *LogIterator) openLogs(receipt ipld.Node) error {
+	logsNode, err := receipt.LookupByString("Logs")
+	if err != nil {
+		return fmt.Errorf("receipt is missing a Logs node: %v", err)
+	}
+	count := logsNode.Length()
+	if count == 0 {
+		return nil
+	}
+	bloomNode, err := receipt.LookupByString("Bloom")
+	if err != nil {
+		return fmt.Errorf("receipt is missing a Bloom node: %v", err)
+	}
+	bloomBytes, err := bloomNode.AsBytes()
+	if err != nil {
+		return err
+	}
+	if !it.filter.mayMatch(types.BytesToBloom(bloomBytes)) {
+		it.logIndex += count
+		return nil
+	}
+	rootNode, err := receipt.LookupByString("LogRootCID")
+	if err != nil {
+		return fmt.Errorf("receipt is missing a LogRootCID node: %v", err)
+	}
+	root, err := rootNode.AsLink()
+	if err != nil {
+		return err
+	}
+	it.logs = NewLogList(it.lsys, root).ListIterator()
+	return nil
+}
+
+func containsAddress(addrs []common.Address, addr common.Address) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func containsHash(hashes []common.Hash, hash common.Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
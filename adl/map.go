@@ -0,0 +1,141 @@
+package adl
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ipld/go-ipld-prime"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/node/mixins"
+
+	"github.com/vulcanize/go-codec-dageth/iterator"
+)
+
+// trieMap presents a trie with fixed length keys as a map from the hex encoded keys to the trie values
+type trieMap struct {
+	r        *trieReader
+	typeName string
+	keyLen   int
+}
+
+var _ ipld.Node = &trieMap{}
+
+func (m *trieMap) mixin() mixins.Map {
+	return mixins.Map{TypeName: m.typeName}
+}
+
+// lookupKey returns the value stored at the key, or an ipld.ErrNotExists if the trie does not hold it
+func (m *trieMap) lookupKey(key []byte) (ipld.Node, error) {
+	val, err := m.r.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, ipld.ErrNotExists{Segment: ipld.PathSegmentOfString(hex.EncodeToString(key))}
+	}
+	return val, nil
+}
+
+func (m *trieMap) Kind() ipld.Kind {
+	return ipld.Kind_Map
+}
+
+// LookupByString looks up the value at the hex encoded key, with or without a 0x prefix
+func (m *trieMap) LookupByString(key string) (ipld.Node, error) {
+	keyBytes, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%s key needs to be hex encoded: %v", m.typeName, err)
+	}
+	if len(keyBytes) != m.keyLen {
+		return nil, fmt.Errorf("%s key needs to be %d bytes, got %d", m.typeName, m.keyLen, len(keyBytes))
+	}
+	return m.lookupKey(keyBytes)
+}
+
+func (m *trieMap) LookupByNode(key ipld.Node) (ipld.Node, error) {
+	ks, err := key.AsString()
+	if err != nil {
+		return nil, err
+	}
+	return m.LookupByString(ks)
+}
+
+func (m *trieMap) LookupByIndex(idx int64) (ipld.Node, error) {
+	return m.mixin().LookupByIndex(idx)
+}
+
+func (m *trieMap) LookupBySegment(seg ipld.PathSegment) (ipld.Node, error) {
+	return m.LookupByString(seg.String())
+}
+
+// MapIterator iterates the entries of the trie in key order, loading trie nodes as it reaches them
+func (m *trieMap) MapIterator() ipld.MapIterator {
+	return &trieMapIterator{it: m.r.leaves()}
+}
+
+func (m *trieMap) ListIterator() ipld.ListIterator {
+	return nil
+}
+
+// Length returns the number of entries in the trie, which requires walking the whole trie the first time it is called.
+// It returns -1 if the trie cannot be walked.
+func (m *trieMap) Length() int64 {
+	length, err := m.r.count()
+	if err != nil {
+		return -1
+	}
+	return length
+}
+
+func (m *trieMap) IsAbsent() bool {
+	return false
+}
+
+func (m *trieMap) IsNull() bool {
+	return false
+}
+
+func (m *trieMap) AsBool() (bool, error) {
+	return m.mixin().AsBool()
+}
+
+func (m *trieMap) AsInt() (int64, error) {
+	return m.mixin().AsInt()
+}
+
+func (m *trieMap) AsFloat() (float64, error) {
+	return m.mixin().AsFloat()
+}
+
+func (m *trieMap) AsString() (string, error) {
+	return m.mixin().AsString()
+}
+
+func (m *trieMap) AsBytes() ([]byte, error) {
+	return m.mixin().AsBytes()
+}
+
+func (m *trieMap) AsLink() (ipld.Link, error) {
+	return m.mixin().AsLink()
+}
+
+func (m *trieMap) Prototype() ipld.NodePrototype {
+	return basicnode.Prototype.Map
+}
+
+type trieMapIterator struct {
+	it *iterator.Iterator
+}
+
+func (mi *trieMapIterator) Next() (ipld.Node, ipld.Node, error) {
+	key, val, err := mi.it.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+	return basicnode.NewString(hex.EncodeToString(key)), val, nil
+}
+
+func (mi *trieMapIterator) Done() bool {
+	return mi.it.Done()
+}
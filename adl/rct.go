@@ -0,0 +1,164 @@
+package adl
+
+import (
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+)
+
+const (
+	// BlockLogIndexField is the derived entry ReceiptListOptions.LogIndexes attaches to logs, holding the index of
+	// the log among the logs of its block, which JSON-RPC reports as logIndex
+	BlockLogIndexField = "BlockLogIndex"
+	// TxLogIndexField is the derived entry ReceiptListOptions.LogIndexes attaches to logs, holding the index of the
+	// log among the logs of its receipt
+	TxLogIndexField = "TxLogIndex"
+)
+
+// ReceiptList is an ADL presenting a receipt trie as a list of Receipts in transaction index order,
+// so the receipt of a transaction sits at the same index as the transaction does in the TransactionList of its block
+type ReceiptList struct {
+	trieList
+	// logOffsets holds the block log index of the first log of each receipt counted so far, in order
+	logOffsets []int64
+}
+
+// ReceiptListOptions can be used to customize the receipts a ReceiptList presents.
+// The zero value presents the receipts as they are decoded.
+type ReceiptListOptions struct {
+	// LogIndexes attaches the BlockLogIndex and TxLogIndex entries to every log of the receipts, so consumers get
+	// the same log coordinates as JSON-RPC provides. The entries are derived from the position of the receipt in the
+	// trie and are not part of the encoding, so receipts carrying them do not encode with the receipt codec.
+	// Looking up a receipt counts the logs of every receipt before it that has not been looked up yet.
+	LogIndexes bool
+}
+
+// NewReceiptList returns a ReceiptList over the receipt trie whose root node is referenced by root,
+// loading the trie nodes through the LinkSystem
+func NewReceiptList(lsys ipld.LinkSystem, root ipld.Link) *ReceiptList {
+	return ReceiptListOptions{}.NewReceiptList(lsys, root)
+}
+
+// NewReceiptList is like the package level NewReceiptList, but uses the provided options
+func (opts ReceiptListOptions) NewReceiptList(lsys ipld.LinkSystem, root ipld.Link) *ReceiptList {
+	l := &ReceiptList{trieList: trieList{r: newTrieReader(lsys, root), typeName: "ReceiptList"}}
+	if opts.LogIndexes {
+		l.wrap = l.withLogIndexes
+	}
+	return l
+}
+
+// withLogIndexes returns a copy of the receipt at the index whose logs carry their derived indexes
+func (l *ReceiptList) withLogIndexes(idx int64, receipt ipld.Node) (ipld.Node, error) {
+	first, err := l.firstLogIndex(idx)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := receipt.LookupByString("Logs")
+	if err != nil {
+		return nil, fmt.Errorf("receipt is missing a Logs node: %v", err)
+	}
+	if int64(len(l.logOffsets)) == idx+1 {
+		l.logOffsets = append(l.logOffsets, first+logs.Length())
+	}
+
+	nb := basicnode.Prototype.Map.NewBuilder()
+	ma, err := nb.BeginMap(receipt.Length())
+	if err != nil {
+		return nil, err
+	}
+	for it := receipt.MapIterator(); !it.Done(); {
+		k, v, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		if v.IsAbsent() {
+			continue
+		}
+		if err := ma.AssembleKey().AssignNode(k); err != nil {
+			return nil, err
+		}
+		key, _ := k.AsString()
+		if key != "Logs" {
+			if err := ma.AssembleValue().AssignNode(v); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := assembleIndexedLogs(ma.AssembleValue(), v, first); err != nil {
+			return nil, err
+		}
+	}
+	if err := ma.Finish(); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
+}
+
+// firstLogIndex returns the block log index of the first log of the receipt at the index, counting the logs of the
+// receipts before it
+func (l *ReceiptList) firstLogIndex(idx int64) (int64, error) {
+	if l.logOffsets == nil {
+		l.logOffsets = []int64{0}
+	}
+	for int64(len(l.logOffsets)) <= idx {
+		prev := int64(len(l.logOffsets)) - 1
+		receipt, err := l.lookupIndex(prev)
+		if err != nil {
+			return 0, fmt.Errorf("unable to count the logs of receipt %d: %v", prev, err)
+		}
+		logs, err := receipt.LookupByString("Logs")
+		if err != nil {
+			return 0, fmt.Errorf("receipt %d is missing a Logs node: %v", prev, err)
+		}
+		l.logOffsets = append(l.logOffsets, l.logOffsets[prev]+logs.Length())
+	}
+	return l.logOffsets[idx], nil
+}
+
+// assembleIndexedLogs assembles the list of logs, each with its BlockLogIndex, starting at first, and TxLogIndex
+func assembleIndexedLogs(na ipld.NodeAssembler, logs ipld.Node, first int64) error {
+	la, err := na.BeginList(logs.Length())
+	if err != nil {
+		return err
+	}
+	for it := logs.ListIterator(); !it.Done(); {
+		i, log, err := it.Next()
+		if err != nil {
+			return err
+		}
+		ma, err := la.AssembleValue().BeginMap(log.Length() + 2)
+		if err != nil {
+			return err
+		}
+		for lit := log.MapIterator(); !lit.Done(); {
+			k, v, err := lit.Next()
+			if err != nil {
+				return err
+			}
+			if err := ma.AssembleKey().AssignNode(k); err != nil {
+				return err
+			}
+			if err := ma.AssembleValue().AssignNode(v); err != nil {
+				return err
+			}
+		}
+		if err := ma.AssembleKey().AssignString(BlockLogIndexField); err != nil {
+			return err
+		}
+		if err := ma.AssembleValue().AssignInt(first + i); err != nil {
+			return err
+		}
+		if err := ma.AssembleKey().AssignString(TxLogIndexField); err != nil {
+			return err
+		}
+		if err := ma.AssembleValue().AssignInt(i); err != nil {
+			return err
+		}
+		if err := ma.Finish(); err != nil {
+			return err
+		}
+	}
+	return la.Finish()
+}
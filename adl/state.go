@@ -0,0 +1,24 @@
+package adl
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// StateMap is an ADL presenting a state trie as a map from hex encoded keccak256(address) keys to Accounts
+type StateMap struct {
+	trieMap
+}
+
+// NewStateMap returns a StateMap over the state trie whose root node is referenced by root,
+// loading the trie nodes through the LinkSystem
+func NewStateMap(lsys ipld.LinkSystem, root ipld.Link) *StateMap {
+	return &StateMap{trieMap{r: newTrieReader(lsys, root), typeName: "StateMap", keyLen: common.HashLength}}
+}
+
+// LookupAccount looks up the Account of the address
+func (m *StateMap) LookupAccount(address common.Address) (ipld.Node, error) {
+	return m.lookupKey(shared.AddressToLeafKey(address))
+}
@@ -0,0 +1,24 @@
+package adl
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipld/go-ipld-prime"
+)
+
+// StorageMap is an ADL presenting a storage trie as a map from hex encoded keccak256(slot) keys to the
+// storage values, which are Bytes holding the RLP encoded value as it is stored in the trie
+type StorageMap struct {
+	trieMap
+}
+
+// NewStorageMap returns a StorageMap over the storage trie whose root node is referenced by root,
+// loading the trie nodes through the LinkSystem
+func NewStorageMap(lsys ipld.LinkSystem, root ipld.Link) *StorageMap {
+	return &StorageMap{trieMap{r: newTrieReader(lsys, root), typeName: "StorageMap", keyLen: common.HashLength}}
+}
+
+// LookupSlot looks up the value stored in the slot
+func (m *StorageMap) LookupSlot(slot common.Hash) (ipld.Node, error) {
+	return m.lookupKey(crypto.Keccak256(slot.Bytes()))
+}
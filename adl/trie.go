@@ -0,0 +1,88 @@
+/*
+Package adl provides Advanced Data Layouts that present DAG-ETH tries as plain IPLD maps and lists.
+The trie nodes are loaded lazily through a LinkSystem, so consumers can look up and iterate the
+values of a trie without knowing about its branch, extension and leaf nodes.
+LogIterator walks the logs of a block matching a LogFilter, loading only the log tries of the receipts whose bloom
+may match.
+*/
+package adl
+
+import (
+	"github.com/ipld/go-ipld-prime"
+	"sync"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/helpers"
+	"github.com/vulcanize/go-codec-dageth/iterator"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// trieReader resolves the leaves of a trie, loading its nodes through the LinkSystem as they are reached
+type trieReader struct {
+	lsys ipld.LinkSystem
+	root ipld.Link
+	// countOnce guards length and countErr, so the nodes sharing the reader can count concurrently
+	countOnce sync.Once
+	length    int64
+	countErr  error
+}
+
+func newTrieReader(lsys ipld.LinkSystem, root ipld.Link) *trieReader {
+	return &trieReader{lsys: lsys, root: root}
+}
+
+func (r *trieReader) load(lnk ipld.Link) (ipld.Node, error) {
+	return r.lsys.Load(ipld.LinkContext{}, lnk, dageth.Type.TrieNode)
+}
+
+// lookup returns the value stored at the key, or nil if the trie does not hold the key
+func (r *trieReader) lookup(key []byte) (ipld.Node, error) {
+	remaining := helpers.KeyToNibbles(key)
+	lnk := r.root
+	for {
+		node, err := r.load(lnk)
+		if err != nil {
+			return nil, err
+		}
+		val, next, rest, err := helpers.Step(node, remaining)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			if val == nil {
+				return nil, nil
+			}
+			return unwrapValue(val)
+		}
+		lnk, remaining = next, rest
+	}
+}
+
+// count returns the number of leaves in the trie, walking the whole trie the first time it is called.
+// The error of that walk is returned by every call.
+func (r *trieReader) count() (int64, error) {
+	r.countOnce.Do(func() {
+		it := r.leaves()
+		for !it.Done() {
+			if _, _, err := it.Next(); err != nil {
+				r.countErr = err
+				return
+			}
+			r.length++
+		}
+	})
+	if r.countErr != nil {
+		return 0, r.countErr
+	}
+	return r.length, nil
+}
+
+func (r *trieReader) leaves() *iterator.Iterator {
+	return iterator.New(r.lsys, r.root)
+}
+
+// unwrapValue returns the member of the Value union, e.g. the Account of a state trie leaf
+func unwrapValue(val ipld.Node) (ipld.Node, error) {
+	n, _, err := trie.ValueAndKind(val)
+	return n, err
+}
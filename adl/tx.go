@@ -0,0 +1,16 @@
+package adl
+
+import (
+	"github.com/ipld/go-ipld-prime"
+)
+
+// TransactionList is an ADL presenting a transaction trie as a list of Transactions in transaction index order
+type TransactionList struct {
+	trieList
+}
+
+// NewTransactionList returns a TransactionList over the transaction trie whose root node is referenced by root,
+// loading the trie nodes through the LinkSystem
+func NewTransactionList(lsys ipld.LinkSystem, root ipld.Link) *TransactionList {
+	return &TransactionList{trieList{r: newTrieReader(lsys, root), typeName: "TransactionList"}}
+}
@@ -0,0 +1,16 @@
+package adl
+
+import (
+	"github.com/ipld/go-ipld-prime"
+)
+
+// WithdrawalList is an ADL presenting a withdrawal trie as a list of Withdrawals in the order of the block body
+type WithdrawalList struct {
+	trieList
+}
+
+// NewWithdrawalList returns a WithdrawalList over the withdrawal trie whose root node is referenced by root,
+// loading the trie nodes through the LinkSystem
+func NewWithdrawalList(lsys ipld.LinkSystem, root ipld.Link) *WithdrawalList {
+	return &WithdrawalList{trieList{r: newTrieReader(lsys, root), typeName: "WithdrawalList"}}
+}
@@ -0,0 +1,87 @@
+/*
+Package archive reads the block history kept in go-ethereum archives, era1 files and the freezer tables of a node's
+ancient store, and packs every block it reads into DAG-ETH IPLD blocks under their computed CIDs, so whole ranges of
+history can be ingested in bulk without going through JSON-RPC.
+*/
+package archive
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+)
+
+// Block is a block read from an archive, packed into DAG-ETH IPLD blocks
+type Block struct {
+	Number     uint64
+	Hash       common.Hash
+	HeaderLink ipld.Link
+	// Blocks holds the encoded header, uncles, transactions, receipts, logs, withdrawals and the nodes of the
+	// tries linking them, by link
+	Blocks map[ipld.Link][]byte
+}
+
+// Store writes the IPLD blocks of the block through the LinkSystem
+func (b *Block) Store(lsys ipld.LinkSystem) error {
+	if lsys.StorageWriteOpener == nil {
+		return fmt.Errorf("no storage configured for writing")
+	}
+	for lnk, data := range b.Blocks {
+		w, commit, err := lsys.StorageWriteOpener(ipld.LinkContext{})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		if err := commit(lnk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reader reads blocks from an archive by number
+type Reader interface {
+	Block(number uint64) (*Block, error)
+}
+
+// ForEach reads the blocks from through to, inclusive, from the archive, and calls fn with each of them in order.
+// It stops at the first error, from reading a block or returned by fn.
+func ForEach(r Reader, from, to uint64, fn func(*Block) error) error {
+	for number := from; number <= to; number++ {
+		b, err := r.Block(number)
+		if err != nil {
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+		if number == to {
+			// to may be the largest uint64
+			break
+		}
+	}
+	return nil
+}
+
+// packBlock packs the block and its receipts into memory, checking them against the roots of the header
+func packBlock(blk *types.Block, receipts types.Receipts) (*Block, error) {
+	mem := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	headerLink, err := block.PackBlock(blk, receipts, codecs.NewLinkSystem(mem))
+	if err != nil {
+		return nil, fmt.Errorf("unable to pack block %d %s: %v", blk.NumberU64(), blk.Hash().Hex(), err)
+	}
+	return &Block{
+		Number:     blk.NumberU64(),
+		Hash:       blk.Hash(),
+		HeaderLink: headerLink,
+		Blocks:     mem.Bag,
+	}, nil
+}
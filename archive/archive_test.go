@@ -0,0 +1,196 @@
+package archive_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/golang/snappy"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/archive"
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/convert"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+// mockBlocks returns n blocks numbered from start, along with their receipts
+func mockBlocks(start uint64, n int) ([]*types.Block, []types.Receipts) {
+	g := testutil.NewGenerator(1)
+	blocks := make([]*types.Block, n)
+	receipts := make([]types.Receipts, n)
+	for i := range blocks {
+		h := g.Header()
+		h.Number.SetUint64(start + uint64(i))
+		txs := g.Transactions(4)
+		body := &types.Body{Transactions: txs, Uncles: g.Headers(1)}
+		if h.WithdrawalsHash != nil {
+			body.Withdrawals = g.Withdrawals(2)
+		}
+		receipts[i] = g.Receipts(txs)
+		blocks[i] = types.NewBlock(h, body, receipts[i], gethtrie.NewStackTrie(nil))
+	}
+	return blocks, receipts
+}
+
+// writeEra1 writes the blocks into an era1 file, with a zero accumulator root
+func writeEra1(t *testing.T, blocks []*types.Block, receipts []types.Receipts) string {
+	buf := new(bytes.Buffer)
+	writeEntry := func(typ uint16, data []byte) {
+		header := make([]byte, 8)
+		binary.LittleEndian.PutUint16(header, typ)
+		binary.LittleEndian.PutUint32(header[2:], uint32(len(data)))
+		buf.Write(header)
+		buf.Write(data)
+	}
+	writeCompressed := func(typ uint16, val interface{}) {
+		enc, err := rlp.EncodeToBytes(val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compressed := new(bytes.Buffer)
+		w := snappy.NewBufferedWriter(compressed)
+		w.Write(enc)
+		w.Close()
+		writeEntry(typ, compressed.Bytes())
+	}
+
+	writeEntry(0x3265, nil)
+	offsets := make([]int, len(blocks))
+	for i, blk := range blocks {
+		offsets[i] = buf.Len()
+		writeCompressed(0x03, blk.Header())
+		writeCompressed(0x04, blk.Body())
+		writeCompressed(0x05, receipts[i])
+		writeEntry(0x06, make([]byte, 32))
+	}
+	writeEntry(0x07, make([]byte, 32))
+	index := make([]byte, 16+8*len(blocks))
+	binary.LittleEndian.PutUint64(index, blocks[0].NumberU64())
+	for i, off := range offsets {
+		binary.LittleEndian.PutUint64(index[8+i*8:], uint64(int64(off-buf.Len())))
+	}
+	binary.LittleEndian.PutUint64(index[8+8*len(blocks):], uint64(len(blocks)))
+	writeEntry(0x3266, index)
+
+	path := filepath.Join(t.TempDir(), "mainnet-00001-00000000.era1")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkBlock checks that the packed block unpacks into the expected block
+func checkBlock(t *testing.T, b *archive.Block, expected *types.Block) {
+	if b.Number != expected.NumberU64() || b.Hash != expected.Hash() {
+		t.Errorf("read block %d %s, expected block %d %s", b.Number, b.Hash.Hex(), expected.NumberU64(), expected.Hash().Hex())
+	}
+	c, err := convert.HeaderCID(expected.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.HeaderLink.String() != c.String() {
+		t.Errorf("block %d header link %s does not match the header CID %s", b.Number, b.HeaderLink.String(), c.String())
+	}
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	if err := b.Store(lsys); err != nil {
+		t.Fatalf("unable to store block %d: %v", b.Number, err)
+	}
+	unpacked, receipts, err := block.UnpackBlock(lsys, b.HeaderLink)
+	if err != nil {
+		t.Fatalf("unable to unpack block %d: %v", b.Number, err)
+	}
+	if unpacked.Hash() != expected.Hash() || len(receipts) != len(expected.Transactions()) {
+		t.Errorf("unpacked block %d does not match the expected block", b.Number)
+	}
+}
+
+func TestEra1Reader(t *testing.T) {
+	blocks, receipts := mockBlocks(8192, 3)
+	r, closeFile, err := archive.OpenEra1(writeEra1(t, blocks, receipts))
+	if err != nil {
+		t.Fatalf("unable to open era1 file: %v", err)
+	}
+	defer closeFile()
+	if r.Start() != 8192 || r.Count() != 3 {
+		t.Fatalf("expected era1 file of blocks 8192 through 8194, got %d blocks from %d", r.Count(), r.Start())
+	}
+	var read int
+	if err := archive.ForEach(r, r.Start(), r.Start()+r.Count()-1, func(b *archive.Block) error {
+		checkBlock(t, b, blocks[read])
+		read++
+		return nil
+	}); err != nil {
+		t.Fatalf("unable to read era1 blocks: %v", err)
+	}
+	if read != len(blocks) {
+		t.Errorf("expected to read %d blocks, read %d", len(blocks), read)
+	}
+	if _, err := r.Block(8195); err == nil {
+		t.Error("expected an error reading a block past the end of the era1 file")
+	}
+
+	data, err := os.ReadFile(writeEra1(t, blocks, receipts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := archive.NewEra1Reader(bytes.NewReader(data[:len(data)-1]), int64(len(data)-1)); err == nil {
+		t.Error("expected an error opening a truncated era1 file")
+	}
+	// a receipt that does not match the receipt root of its header fails the block
+	receipts[1][0].CumulativeGasUsed++
+	data, err = os.ReadFile(writeEra1(t, blocks, receipts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = archive.NewEra1Reader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Block(8193); err == nil {
+		t.Error("expected an error reading a block whose receipts do not match its header")
+	}
+}
+
+func TestFreezerReader(t *testing.T) {
+	blocks, receipts := mockBlocks(0, 3)
+	dir := t.TempDir()
+	db, err := rawdb.NewDatabaseWithFreezer(memorydb.New(), dir, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rawdb.WriteAncientBlocks(db, blocks, receipts); err != nil {
+		t.Fatalf("unable to write blocks into the freezer: %v", err)
+	}
+	db.Close()
+
+	r, closeFreezer, err := archive.OpenFreezer(dir)
+	if err != nil {
+		t.Fatalf("unable to open freezer: %v", err)
+	}
+	defer closeFreezer()
+	count, err := r.Count()
+	if err != nil || count != 3 {
+		t.Fatalf("expected 3 blocks in the freezer, got %d (%v)", count, err)
+	}
+	for i, expected := range blocks {
+		b, err := r.Block(uint64(i))
+		if err != nil {
+			t.Fatalf("unable to read block %d: %v", i, err)
+		}
+		checkBlock(t, b, expected)
+	}
+	if _, err := r.Block(3); err == nil {
+		t.Error("expected an error reading a block past the end of the freezer")
+	}
+}
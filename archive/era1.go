@@ -0,0 +1,191 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+)
+
+// The e2store entry types of an era1 file
+const (
+	era1Version            = uint16(0x3265)
+	era1CompressedHeader   = uint16(0x03)
+	era1CompressedBody     = uint16(0x04)
+	era1CompressedReceipts = uint16(0x05)
+	era1BlockIndex         = uint16(0x3266)
+
+	// e2storeHeaderSize is the size of the header of an e2store entry: its type, the length of its data and two
+	// reserved bytes
+	e2storeHeaderSize = 8
+	// MaxEra1Blocks is the number of blocks an era1 file holds at most, the limit of its accumulator
+	MaxEra1Blocks = 8192
+)
+
+// Era1Reader reads the blocks of an era1 file, the e2store archive of pre-merge history. Each block is stored as
+// its snappy compressed header, body and receipts, followed by its total difficulty, and found through the block
+// index at the end of the file.
+// It is safe for concurrent use if the underlying io.ReaderAt is.
+type Era1Reader struct {
+	r     io.ReaderAt
+	start uint64
+	// offsets are the absolute offsets of the header entries of the blocks
+	offsets []int64
+}
+
+// OpenEra1 returns an Era1Reader reading the era1 file at path, along with a func closing the file
+func OpenEra1(path string) (*Era1Reader, func() error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	r, err := NewEra1Reader(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return r, f.Close, nil
+}
+
+// NewEra1Reader returns an Era1Reader reading the era1 file of the given size from r.
+// It checks the version entry the file starts with and reads the block index the file ends with.
+func NewEra1Reader(r io.ReaderAt, size int64) (*Era1Reader, error) {
+	typ, length, err := readEntryHeader(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if typ != era1Version || length != 0 {
+		return nil, fmt.Errorf("invalid era1 file (expected an empty version entry, got type %#x of length %d)", typ, length)
+	}
+
+	// the block index is the last entry: the starting number, an offset per block and the block count
+	var count uint64
+	if err := readUint64(r, size-8, &count); err != nil {
+		return nil, err
+	}
+	if count == 0 || count > MaxEra1Blocks {
+		return nil, fmt.Errorf("invalid era1 file (block index holds %d blocks)", count)
+	}
+	indexLength := int64(16 + count*8)
+	indexOffset := size - e2storeHeaderSize - indexLength
+	if indexOffset < e2storeHeaderSize {
+		return nil, fmt.Errorf("invalid era1 file (%d bytes is too short for a block index of %d blocks)", size, count)
+	}
+	typ, length, err = readEntryHeader(r, indexOffset)
+	if err != nil {
+		return nil, err
+	}
+	if typ != era1BlockIndex || int64(length) != indexLength {
+		return nil, fmt.Errorf("invalid era1 file (expected a block index entry at offset %d, got type %#x of length %d)", indexOffset, typ, length)
+	}
+	index := make([]byte, indexLength)
+	if _, err := r.ReadAt(index, indexOffset+e2storeHeaderSize); err != nil {
+		return nil, err
+	}
+	e := &Era1Reader{r: r, start: binary.LittleEndian.Uint64(index), offsets: make([]int64, count)}
+	for i := range e.offsets {
+		// the offsets are relative to the start of the block index entry
+		rel := int64(binary.LittleEndian.Uint64(index[8+i*8:]))
+		e.offsets[i] = indexOffset + rel
+		if e.offsets[i] < e2storeHeaderSize || e.offsets[i] >= indexOffset {
+			return nil, fmt.Errorf("invalid era1 file (block %d is indexed at offset %d, outside of the file)", e.start+uint64(i), e.offsets[i])
+		}
+	}
+	return e, nil
+}
+
+// Start returns the number of the first block of the file
+func (e *Era1Reader) Start() uint64 {
+	return e.start
+}
+
+// Count returns the number of blocks in the file
+func (e *Era1Reader) Count() uint64 {
+	return uint64(len(e.offsets))
+}
+
+// Block reads the block with the number from the file and packs it, along with its receipts
+func (e *Era1Reader) Block(number uint64) (*Block, error) {
+	if number < e.start || number-e.start >= e.Count() {
+		return nil, fmt.Errorf("block %d is not in the era1 file of blocks %d through %d", number, e.start, e.start+e.Count()-1)
+	}
+	off := e.offsets[number-e.start]
+	headerRLP, off, err := e.readCompressed(off, era1CompressedHeader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read header of block %d: %v", number, err)
+	}
+	bodyRLP, off, err := e.readCompressed(off, era1CompressedBody)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read body of block %d: %v", number, err)
+	}
+	receiptsRLP, _, err := e.readCompressed(off, era1CompressedReceipts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read receipts of block %d: %v", number, err)
+	}
+
+	h := new(types.Header)
+	if err := rlp.DecodeBytes(headerRLP, h); err != nil {
+		return nil, fmt.Errorf("invalid header of block %d: %v", number, err)
+	}
+	if h.Number == nil || h.Number.Uint64() != number {
+		return nil, fmt.Errorf("block %d is indexed at the header of block %v", number, h.Number)
+	}
+	body := new(types.Body)
+	if err := rlp.DecodeBytes(bodyRLP, body); err != nil {
+		return nil, fmt.Errorf("invalid body of block %d: %v", number, err)
+	}
+	var receipts types.Receipts
+	if err := rlp.DecodeBytes(receiptsRLP, &receipts); err != nil {
+		return nil, fmt.Errorf("invalid receipts of block %d: %v", number, err)
+	}
+	return packBlock(types.NewBlockWithHeader(h).WithBody(*body), receipts)
+}
+
+// readCompressed reads the snappy framed data of the entry of the type at the offset, and returns it decompressed
+// along with the offset of the next entry
+func (e *Era1Reader) readCompressed(off int64, expected uint16) ([]byte, int64, error) {
+	typ, length, err := readEntryHeader(e.r, off)
+	if err != nil {
+		return nil, 0, err
+	}
+	if typ != expected {
+		return nil, 0, fmt.Errorf("expected an entry of type %#x at offset %d, got type %#x", expected, off, typ)
+	}
+	data, err := ioutil.ReadAll(snappy.NewReader(io.NewSectionReader(e.r, off+e2storeHeaderSize, int64(length))))
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, off + e2storeHeaderSize + int64(length), nil
+}
+
+// readEntryHeader reads the type and data length of the e2store entry at the offset
+func readEntryHeader(r io.ReaderAt, off int64) (uint16, uint32, error) {
+	header := make([]byte, e2storeHeaderSize)
+	if _, err := r.ReadAt(header, off); err != nil {
+		return 0, 0, fmt.Errorf("unable to read e2store entry at offset %d: %v", off, err)
+	}
+	if !bytes.Equal(header[6:], []byte{0, 0}) {
+		return 0, 0, fmt.Errorf("e2store entry at offset %d has non-zero reserved bytes", off)
+	}
+	return binary.LittleEndian.Uint16(header), binary.LittleEndian.Uint32(header[2:]), nil
+}
+
+func readUint64(r io.ReaderAt, off int64, val *uint64) error {
+	b := make([]byte, 8)
+	if _, err := r.ReadAt(b, off); err != nil {
+		return err
+	}
+	*val = binary.LittleEndian.Uint64(b)
+	return nil
+}
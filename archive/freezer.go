@@ -0,0 +1,60 @@
+package archive
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+// FreezerReader reads the blocks of the freezer tables of a go-ethereum node, the ancient store holding the
+// headers, bodies and receipts of finalized blocks, by the canonical hash the freezer holds for each number.
+// It is safe for concurrent use.
+type FreezerReader struct {
+	db ethdb.Reader
+}
+
+// OpenFreezer returns a FreezerReader reading the freezer tables in the ancient directory of a node, e.g.
+// geth/chaindata/ancient, opened read-only, along with a func closing them.
+// The node should not be running, as it holds a lock on its freezer.
+func OpenFreezer(ancientDir string) (*FreezerReader, func() error, error) {
+	db, err := rawdb.NewDatabaseWithFreezer(memorydb.New(), ancientDir, "", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewFreezerReader(db), db.Close, nil
+}
+
+// NewFreezerReader returns a FreezerReader reading the ancient store of the database
+func NewFreezerReader(db ethdb.Reader) *FreezerReader {
+	return &FreezerReader{db: db}
+}
+
+// Count returns the number of blocks in the freezer, which hold blocks 0 through Count-1
+func (f *FreezerReader) Count() (uint64, error) {
+	return f.db.Ancients()
+}
+
+// Block reads the canonical block with the number from the freezer and packs it, along with its receipts
+func (f *FreezerReader) Block(number uint64) (*Block, error) {
+	hash := rawdb.ReadCanonicalHash(f.db, number)
+	if hash == (common.Hash{}) {
+		return nil, fmt.Errorf("block %d is not in the freezer", number)
+	}
+	blk := rawdb.ReadBlock(f.db, hash, number)
+	if blk == nil {
+		return nil, fmt.Errorf("body of block %d %s is not in the freezer", number, hash.Hex())
+	}
+	receipts := rawdb.ReadRawReceipts(f.db, hash, number)
+	txs := blk.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipts of block %d %s are not in the freezer", number, hash.Hex())
+	}
+	// the stored receipts drop the type, which their consensus encoding needs
+	for i, receipt := range receipts {
+		receipt.Type = txs[i].Type()
+	}
+	return packBlock(blk, receipts)
+}
@@ -0,0 +1,142 @@
+package beacon_block_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/beacon_block"
+	"github.com/vulcanize/go-codec-dageth/beacon_block_body"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/consensus"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/ssz"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+func TestBeaconBlockCodec(t *testing.T) {
+	g := testutil.NewGenerator(1)
+	blockSSZ := g.SSZ(consensus.BeaconBlock)
+
+	nb := basicnode.Prototype.Map.NewBuilder()
+	if err := beacon_block.Decode(nb, bytes.NewReader(blockSSZ)); err != nil {
+		t.Fatalf("unable to decode beacon block into an IPLD node: %v", err)
+	}
+	blockNode := nb.Build()
+	slotNode, err := blockNode.LookupByString("Slot")
+	if err != nil {
+		t.Fatalf("beacon block is missing a Slot: %v", err)
+	}
+	if slot, _ := slotNode.AsBytes(); !bytes.Equal(slot, []byte{blockSSZ[7], blockSSZ[6], blockSSZ[5], blockSSZ[4], blockSSZ[3], blockSSZ[2], blockSSZ[1], blockSSZ[0]}) {
+		t.Errorf("beacon block slot (%x) does not match the big-endian slot of the input (%x)", slot, blockSSZ[:8])
+	}
+	parentNode, err := blockNode.LookupByString("ParentRootCID")
+	if err != nil {
+		t.Fatalf("beacon block is missing a ParentRootCID: %v", err)
+	}
+	parent, err := parentNode.AsLink()
+	if err != nil {
+		t.Fatalf("beacon block ParentRootCID is not a link: %v", err)
+	}
+	if codec := parent.(cidlink.Link).Cid.Prefix().Codec; codec != beacon_block.MultiCodecType {
+		t.Errorf("expected the parent root to link to a beacon block, got multicodec type %#x", codec)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := beacon_block.Encode(blockNode, buf); err != nil {
+		t.Fatalf("unable to encode beacon block: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), blockSSZ) {
+		t.Errorf("beacon block encoding (%x) does not match the input (%x)", buf.Bytes(), blockSSZ)
+	}
+}
+
+func TestBeaconBlockRoot(t *testing.T) {
+	g := testutil.NewGenerator(2)
+	blockSSZ := g.SSZ(consensus.BeaconBlock)
+	blockCID, err := beacon_block.Cid(blockSSZ)
+	if err != nil {
+		t.Fatalf("unable to compute beacon block CID: %v", err)
+	}
+
+	// the block root is the root of the block header, which holds the root of the body in place of the body
+	nb := basicnode.Prototype.Map.NewBuilder()
+	if err := beacon_block.DecodeBytes(nb, blockSSZ); err != nil {
+		t.Fatalf("unable to decode beacon block: %v", err)
+	}
+	bodyNode, _ := nb.Build().LookupByString("Body")
+	bodySSZ, err := beacon_block_body.AppendEncode(nil, bodyNode)
+	if err != nil {
+		t.Fatalf("unable to encode beacon block body: %v", err)
+	}
+	bodyCID, err := beacon_block_body.Cid(bodySSZ)
+	if err != nil {
+		t.Fatalf("unable to compute beacon block body CID: %v", err)
+	}
+	bodyRoot := bodyCID.Hash()[len(bodyCID.Hash())-ssz.BytesPerChunk:]
+	headerSSZ := append(append([]byte{}, blockSSZ[:80]...), bodyRoot...)
+	headerRoot, err := ssz.HashTreeRoot(consensus.BeaconBlockHeader, headerSSZ)
+	if err != nil {
+		t.Fatalf("unable to compute beacon block header root: %v", err)
+	}
+	expected, err := ssz.RootToCid(beacon_block.MultiCodecType, headerRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !blockCID.Equals(expected) {
+		t.Errorf("beacon block CID %s does not match the CID of its header %s", blockCID.String(), expected.String())
+	}
+
+	// the parent beacon root of a Cancun execution header links to the block
+	h := g.Header()
+	for h.ParentBeaconRoot == nil {
+		h = g.Header()
+	}
+	root := common.BytesToHash(headerRoot[:])
+	h.ParentBeaconRoot = &root
+	headerRLP, err := rlp.EncodeToBytes(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerBuilder := dageth.Type.Header.NewBuilder()
+	if err := header.DecodeBytes(headerBuilder, headerRLP); err != nil {
+		t.Fatalf("unable to decode header: %v", err)
+	}
+	beaconNode, err := headerBuilder.Build().LookupByString("ParentBeaconRootCID")
+	if err != nil {
+		t.Fatalf("header is missing a ParentBeaconRootCID: %v", err)
+	}
+	if lnk, err := beaconNode.AsLink(); err != nil || lnk != (cidlink.Link{Cid: blockCID}) {
+		t.Errorf("expected the header ParentBeaconRootCID to link to beacon block %s, got %v (%v)", blockCID.String(), lnk, err)
+	}
+
+	// blocks from untrusted sources are decoded against their CID
+	if err := codecs.DecodeVerified(basicnode.Prototype.Map.NewBuilder(), bytes.NewReader(blockSSZ), blockCID); err != nil {
+		t.Errorf("unable to decode beacon block against its CID: %v", err)
+	}
+	blockSSZ[0]++
+	if err := beacon_block.DecodeVerified(basicnode.Prototype.Map.NewBuilder(), bytes.NewReader(blockSSZ), blockCID); err == nil {
+		t.Error("expected decoding a changed beacon block against its CID to fail")
+	}
+}
+
+func TestBeaconBlockChooser(t *testing.T) {
+	g := testutil.NewGenerator(3)
+	blockCID, err := beacon_block.Cid(g.SSZ(consensus.BeaconBlock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	np, err := codecs.NodePrototypeChooser(cidlink.Link{Cid: blockCID}, ipld.LinkContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if np != basicnode.Prototype.Map {
+		t.Errorf("expected beacon blocks to load into maps, got %T", np)
+	}
+}
@@ -0,0 +1,7 @@
+/*
+Package beacon_block is the codec of SSZ encoded Deneb beacon blocks.
+
+The CIDs of beacon blocks carry their SSZ hash tree root as multihash 0xb502, so the ParentBeaconRootCID of a header
+links to its beacon block. That root is not a hash of the block bytes, so Cid computes the CID to store a block under.
+*/
+package beacon_block
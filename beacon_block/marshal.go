@@ -0,0 +1,54 @@
+package beacon_block
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/consensus"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/ssz"
+)
+
+// Encode provides an IPLD codec encode interface for SSZ encoded beacon block IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0x01a0 (proposed) when this package is invoked via init.
+func Encode(node ipld.Node, w io.Writer) error {
+	return shared.WriteEncoded(w, node, AppendEncode)
+}
+
+// AppendEncode is like Encode, but it uses a destination buffer directly.
+// This means less copying of bytes, and if the destination has enough capacity,
+// fewer allocations.
+func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	enc, err := ssz.AppendEncode(consensus.BeaconBlock, enc, inNode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DAG-ETH BeaconBlock form (%v)", err)
+	}
+	return enc, nil
+}
+
+// EncodeOptions can be used to customize the behavior of beacon block encoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type EncodeOptions struct{}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return Encode(node, w)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return AppendEncode(enc, inNode)
+}
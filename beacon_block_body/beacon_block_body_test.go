@@ -0,0 +1,60 @@
+package beacon_block_body_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/beacon_block_body"
+	"github.com/vulcanize/go-codec-dageth/consensus"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+func TestBeaconBlockBodyCodec(t *testing.T) {
+	bodySSZ := testutil.NewGenerator(1).SSZ(consensus.BeaconBlockBody)
+
+	nb := basicnode.Prototype.Map.NewBuilder()
+	if err := beacon_block_body.Decode(nb, bytes.NewReader(bodySSZ)); err != nil {
+		t.Fatalf("unable to decode beacon block body into an IPLD node: %v", err)
+	}
+	bodyNode := nb.Build()
+	payloadNode, err := bodyNode.LookupByString("ExecutionPayload")
+	if err != nil {
+		t.Fatalf("beacon block body is missing an ExecutionPayload: %v", err)
+	}
+	blockHashNode, err := payloadNode.LookupByString("BlockHashCID")
+	if err != nil {
+		t.Fatalf("execution payload is missing a BlockHashCID: %v", err)
+	}
+	lnk, err := blockHashNode.AsLink()
+	if err != nil {
+		t.Fatalf("execution payload BlockHashCID is not a link: %v", err)
+	}
+	if prefix := lnk.(cidlink.Link).Cid.Prefix(); prefix.Codec != cid.EthBlock || prefix.MhType != multihash.KECCAK_256 {
+		t.Errorf("expected the execution block hash to link to a keccak256 eth-block, got %#x %#x", prefix.Codec, prefix.MhType)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := beacon_block_body.Encode(bodyNode, buf); err != nil {
+		t.Fatalf("unable to encode beacon block body: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), bodySSZ) {
+		t.Errorf("beacon block body encoding (%x) does not match the input (%x)", buf.Bytes(), bodySSZ)
+	}
+
+	bodyCID, err := beacon_block_body.Cid(bodySSZ)
+	if err != nil {
+		t.Fatalf("unable to compute beacon block body CID: %v", err)
+	}
+	if err := beacon_block_body.DecodeVerified(basicnode.Prototype.Map.NewBuilder(), bytes.NewReader(bodySSZ), bodyCID); err != nil {
+		t.Errorf("unable to decode beacon block body against its CID: %v", err)
+	}
+	bodySSZ[0]++
+	if err := beacon_block_body.DecodeVerified(basicnode.Prototype.Map.NewBuilder(), bytes.NewReader(bodySSZ), bodyCID); err == nil {
+		t.Error("expected decoding a changed beacon block body against its CID to fail")
+	}
+}
@@ -0,0 +1,55 @@
+package beacon_block_body
+
+import (
+	"io"
+
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/multicodec"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal"
+
+	"github.com/vulcanize/go-codec-dageth/ssz"
+)
+
+var (
+	_ ipld.Decoder = Decode
+	_ ipld.Encoder = Encode
+
+	MultiCodecType = uint64(0x01a1) // Proposed
+	MultiHashType  = ssz.MultiHashType
+)
+
+func init() {
+	multicodec.RegisterDecoder(MultiCodecType, Decode)
+	multicodec.RegisterEncoder(MultiCodecType, Encode)
+}
+
+// AddSupportToChooser takes an existing node prototype chooser and subs in
+// a map for the beacon block body multicodec code. Beacon block bodies have no DAG-ETH
+// schema type, they take the data model form of their SSZ type.
+func AddSupportToChooser(existing traversal.LinkTargetNodePrototypeChooser) traversal.LinkTargetNodePrototypeChooser {
+	return func(lnk ipld.Link, lnkCtx ipld.LinkContext) (ipld.NodePrototype, error) {
+		if lnk, ok := lnk.(cidlink.Link); ok && lnk.Cid.Prefix().Codec == MultiCodecType {
+			return basicnode.Prototype.Map, nil
+		}
+		return existing(lnk, lnkCtx)
+	}
+}
+
+// We switched to simpler API names after v1.0.0, so keep the old names around
+// as deprecated forwarding funcs until a future v2+.
+// TODO: consider deprecating Marshal/Unmarshal too, since it's a bit
+// unnecessary to have two supported names for each API.
+
+// Deprecated: use Decode instead.
+func Decoder(na ipld.NodeAssembler, r io.Reader) error { return Decode(na, r) }
+
+// Deprecated: use Decode instead.
+func Unmarshal(na ipld.NodeAssembler, r io.Reader) error { return Decode(na, r) }
+
+// Deprecated: use Encode instead.
+func Encoder(inNode ipld.Node, w io.Writer) error { return Encode(inNode, w) }
+
+// Deprecated: use Encode instead.
+func Marshal(inNode ipld.Node, w io.Writer) error { return Encode(inNode, w) }
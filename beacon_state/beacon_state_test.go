@@ -0,0 +1,65 @@
+package beacon_state_test
+
+import (
+	"bytes"
+	"testing"
+
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+
+	"github.com/vulcanize/go-codec-dageth/beacon_block_body"
+	"github.com/vulcanize/go-codec-dageth/beacon_state"
+	"github.com/vulcanize/go-codec-dageth/consensus"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+func TestBeaconStateCodec(t *testing.T) {
+	stateSSZ := testutil.NewGenerator(1).SSZ(consensus.BeaconState)
+
+	nb := basicnode.Prototype.Map.NewBuilder()
+	if err := beacon_state.Decode(nb, bytes.NewReader(stateSSZ)); err != nil {
+		t.Fatalf("unable to decode beacon state into an IPLD node: %v", err)
+	}
+	stateNode := nb.Build()
+	blockRootsNode, err := stateNode.LookupByString("BlockRootCIDs")
+	if err != nil {
+		t.Fatalf("beacon state is missing BlockRootCIDs: %v", err)
+	}
+	if blockRootsNode.Length() != consensus.SlotsPerHistoricalRoot {
+		t.Errorf("expected %d block roots, got %d", consensus.SlotsPerHistoricalRoot, blockRootsNode.Length())
+	}
+	headerNode, err := stateNode.LookupByString("LatestBlockHeader")
+	if err != nil {
+		t.Fatalf("beacon state is missing a LatestBlockHeader: %v", err)
+	}
+	bodyRootNode, err := headerNode.LookupByString("BodyRootCID")
+	if err != nil {
+		t.Fatalf("beacon block header is missing a BodyRootCID: %v", err)
+	}
+	lnk, err := bodyRootNode.AsLink()
+	if err != nil {
+		t.Fatalf("beacon block header BodyRootCID is not a link: %v", err)
+	}
+	if codec := lnk.(cidlink.Link).Cid.Prefix().Codec; codec != beacon_block_body.MultiCodecType {
+		t.Errorf("expected the body root to link to a beacon block body, got multicodec type %#x", codec)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := beacon_state.Encode(stateNode, buf); err != nil {
+		t.Fatalf("unable to encode beacon state: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), stateSSZ) {
+		t.Error("beacon state encoding does not match the input")
+	}
+
+	stateCID, err := beacon_state.Cid(stateSSZ)
+	if err != nil {
+		t.Fatalf("unable to compute beacon state CID: %v", err)
+	}
+	if err := beacon_state.DecodeVerified(basicnode.Prototype.Map.NewBuilder(), bytes.NewReader(stateSSZ), stateCID); err != nil {
+		t.Errorf("unable to decode beacon state against its CID: %v", err)
+	}
+	if err := beacon_state.DecodeVerified(basicnode.Prototype.Map.NewBuilder(), bytes.NewReader(stateSSZ[:len(stateSSZ)-1]), stateCID); err == nil {
+		t.Error("expected decoding a truncated beacon state against its CID to fail")
+	}
+}
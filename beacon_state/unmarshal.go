@@ -0,0 +1,88 @@
+package beacon_state
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/consensus"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/ssz"
+)
+
+// Decode provides an IPLD codec decode interface for SSZ encoded beacon state IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0x01a2 (proposed) when this package is invoked via init.
+func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	var src []byte
+	if buf, ok := in.(interface{ Bytes() []byte }); ok {
+		src = buf.Bytes()
+	} else {
+		var err error
+		src, err = ioutil.ReadAll(in)
+		if err != nil {
+			return err
+		}
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	if err := ssz.Decode(consensus.BeaconState, na, src); err != nil {
+		return fmt.Errorf("invalid DAG-ETH BeaconState binary (%v)", err)
+	}
+	return nil
+}
+
+// DecodeVerified is like Decode, but it first checks that the hash tree root of the input matches the expected CID,
+// and that the CID carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := ssz.VerifyCID(consensus.BeaconState, src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// Cid returns the CID of the SSZ encoded beacon state, which carries its hash tree root, the beacon state root.
+// Beacon state CIDs cannot be computed by the multihash registry, so blocks are stored under the CID returned here.
+func Cid(src []byte) (cid.Cid, error) {
+	root, err := ssz.HashTreeRoot(consensus.BeaconState, src)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return ssz.RootToCid(MultiCodecType, root)
+}
+
+// DecodeOptions can be used to customize the behavior of beacon state decoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type DecodeOptions struct{}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return Decode(na, in)
+}
+
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeBytes(na, src)
+}
@@ -0,0 +1,280 @@
+/*
+Package bind decodes DAG-ETH blocks straight into plain Go structs, as a lightweight alternative to the generated
+dageth types for applications that keep their own struct definitions.
+
+Binding follows the rules of bindnode: map entries are bound to the struct fields of the same name, or to the field
+tagged with `ipld:"<name>"`, lists to slices, and null or absent values leave pointer, slice and interface fields nil.
+Unlike bindnode, bytes are bound to the go-ethereum types they hold: []byte, fixed size byte arrays such as
+common.Hash, common.Address and types.Bloom, big-endian unsigned integers, big.Int and uint256.Int, and links to
+ipld.Link or cid.Cid. Entries without a matching field are skipped, so a struct only needs the fields it uses.
+
+Header, Transaction, Receipt, Account and TrieNode, along with the types they nest, bind every field of their
+schema types. bindnode itself is not used, as the version go-ipld-prime is pinned at cannot bind nullable links
+nor unions of bytes and links, which the DAG-ETH schema is made of.
+*/
+package bind
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/holiman/uint256"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+)
+
+// Decode decodes the block with the DAG-ETH codec of the multicodec code, and binds it to the value ptr points to
+func Decode(data []byte, codec uint64, ptr interface{}) error {
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := codecs.DecodeByCodec(nb, bytes.NewReader(data), codec); err != nil {
+		return err
+	}
+	return Bind(nb.Build(), ptr)
+}
+
+// Load loads the block the link references through the LinkSystem, and binds it to the value ptr points to
+func Load(lsys ipld.LinkSystem, lnk ipld.Link, ptr interface{}) error {
+	node, err := lsys.Load(ipld.LinkContext{}, lnk, basicnode.Prototype.Any)
+	if err != nil {
+		return err
+	}
+	return Bind(node, ptr)
+}
+
+// Bind binds the node to the value ptr points to, which can be a node of the generated dageth types as well as
+// a basic node
+func Bind(node ipld.Node, ptr interface{}) error {
+	val := reflect.ValueOf(ptr)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return fmt.Errorf("expected a non-nil pointer, got %T", ptr)
+	}
+	return bind(node, val.Elem())
+}
+
+var (
+	typeBigInt  = reflect.TypeOf(big.Int{})
+	typeUint256 = reflect.TypeOf(uint256.Int{})
+	typeCid     = reflect.TypeOf(cid.Cid{})
+	typeLink    = reflect.TypeOf((*ipld.Link)(nil)).Elem()
+	typeNode    = reflect.TypeOf((*ipld.Node)(nil)).Elem()
+)
+
+func bind(node ipld.Node, val reflect.Value) error {
+	if node.IsNull() || node.IsAbsent() {
+		val.Set(reflect.Zero(val.Type()))
+		return nil
+	}
+	switch {
+	case val.Type() == typeNode:
+		val.Set(reflect.ValueOf(node))
+		return nil
+	case val.Kind() == reflect.Ptr:
+		elem := reflect.New(val.Type().Elem())
+		if err := bind(node, elem.Elem()); err != nil {
+			return err
+		}
+		val.Set(elem)
+		return nil
+	}
+	switch node.Kind() {
+	case ipld.Kind_Map:
+		return bindMap(node, val)
+	case ipld.Kind_List:
+		return bindList(node, val)
+	case ipld.Kind_Bytes:
+		b, err := node.AsBytes()
+		if err != nil {
+			return err
+		}
+		return bindBytes(b, val)
+	case ipld.Kind_Link:
+		lnk, err := node.AsLink()
+		if err != nil {
+			return err
+		}
+		return bindLink(lnk, val)
+	case ipld.Kind_String:
+		s, err := node.AsString()
+		if err != nil {
+			return err
+		}
+		if val.Kind() != reflect.String {
+			return fmt.Errorf("cannot bind a string to %s", val.Type())
+		}
+		val.SetString(s)
+		return nil
+	case ipld.Kind_Bool:
+		b, err := node.AsBool()
+		if err != nil {
+			return err
+		}
+		if val.Kind() != reflect.Bool {
+			return fmt.Errorf("cannot bind a bool to %s", val.Type())
+		}
+		val.SetBool(b)
+		return nil
+	case ipld.Kind_Int:
+		i, err := node.AsInt()
+		if err != nil {
+			return err
+		}
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if val.OverflowInt(i) {
+				return fmt.Errorf("int %d overflows %s", i, val.Type())
+			}
+			val.SetInt(i)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if i < 0 || val.OverflowUint(uint64(i)) {
+				return fmt.Errorf("int %d overflows %s", i, val.Type())
+			}
+			val.SetUint(uint64(i))
+		default:
+			return fmt.Errorf("cannot bind an int to %s", val.Type())
+		}
+		return nil
+	default:
+		return fmt.Errorf("cannot bind a %s node to %s", node.Kind(), val.Type())
+	}
+}
+
+func bindMap(node ipld.Node, val reflect.Value) error {
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot bind a map to %s", val.Type())
+	}
+	fields := structFields(val.Type())
+	for it := node.MapIterator(); !it.Done(); {
+		k, v, err := it.Next()
+		if err != nil {
+			return err
+		}
+		key, err := k.AsString()
+		if err != nil {
+			return err
+		}
+		i, ok := fields[strings.ToLower(key)]
+		if !ok {
+			continue
+		}
+		if err := bind(v, val.Field(i)); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+func bindList(node ipld.Node, val reflect.Value) error {
+	if val.Kind() != reflect.Slice {
+		return fmt.Errorf("cannot bind a list to %s", val.Type())
+	}
+	list := reflect.MakeSlice(val.Type(), int(node.Length()), int(node.Length()))
+	for it := node.ListIterator(); !it.Done(); {
+		i, v, err := it.Next()
+		if err != nil {
+			return err
+		}
+		if err := bind(v, list.Index(int(i))); err != nil {
+			return fmt.Errorf("[%d]: %v", i, err)
+		}
+	}
+	val.Set(list)
+	return nil
+}
+
+func bindBytes(b []byte, val reflect.Value) error {
+	switch {
+	case val.Type() == typeBigInt:
+		val.Set(reflect.ValueOf(*new(big.Int).SetBytes(b)))
+		return nil
+	case val.Type() == typeUint256:
+		if len(b) > 32 {
+			return fmt.Errorf("%d bytes overflow uint256", len(b))
+		}
+		val.Set(reflect.ValueOf(*new(uint256.Int).SetBytes(b)))
+		return nil
+	}
+	switch val.Kind() {
+	case reflect.Slice:
+		if val.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		val.SetBytes(append([]byte{}, b...))
+		return nil
+	case reflect.Array:
+		if val.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		if len(b) != val.Len() {
+			return fmt.Errorf("cannot bind %d bytes to %s of %d bytes", len(b), val.Type(), val.Len())
+		}
+		reflect.Copy(val, reflect.ValueOf(b))
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		trimmed := bytes.TrimLeft(b, "\x00")
+		if len(trimmed) > 8 {
+			return fmt.Errorf("%d bytes overflow %s", len(b), val.Type())
+		}
+		var v uint64
+		for _, c := range trimmed {
+			v = v<<8 | uint64(c)
+		}
+		if val.OverflowUint(v) {
+			return fmt.Errorf("%d overflows %s", v, val.Type())
+		}
+		val.SetUint(v)
+		return nil
+	}
+	return fmt.Errorf("cannot bind bytes to %s", val.Type())
+}
+
+func bindLink(lnk ipld.Link, val reflect.Value) error {
+	switch val.Type() {
+	case typeLink:
+		val.Set(reflect.ValueOf(&lnk).Elem())
+		return nil
+	case typeCid:
+		cl, ok := lnk.(cidlink.Link)
+		if !ok {
+			return fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+		}
+		val.Set(reflect.ValueOf(cl.Cid))
+		return nil
+	}
+	return fmt.Errorf("cannot bind a link to %s", val.Type())
+}
+
+// fieldCache holds the field indexes of the struct types bound so far, by lowercased field name
+var fieldCache sync.Map
+
+// structFields returns the indexes of the exported fields of the struct type by their lowercased names, which
+// are the names of their ipld tags if they have one
+func structFields(typ reflect.Type) map[string]int {
+	if fields, ok := fieldCache.Load(typ); ok {
+		return fields.(map[string]int)
+	}
+	fields := make(map[string]int, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("ipld"); ok {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		fields[strings.ToLower(name)] = i
+	}
+	fieldCache.Store(typ, fields)
+	return fields
+}
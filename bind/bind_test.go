@@ -0,0 +1,238 @@
+package bind_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/bind"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/trie"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/util"
+)
+
+func TestHeader(t *testing.T) {
+	g := testutil.NewGenerator(21)
+	for _, expected := range g.Headers(20) {
+		enc, err := rlp.EncodeToBytes(expected)
+		if err != nil {
+			t.Fatalf("unable to encode header: %v", err)
+		}
+		var h bind.Header
+		if err := bind.Decode(enc, header.MultiCodecType, &h); err != nil {
+			t.Fatalf("unable to bind header: %v", err)
+		}
+		parent, _ := util.LinkToKeccak256(h.ParentCID)
+		if parent != expected.ParentHash || h.Coinbase != expected.Coinbase || h.Bloom != expected.Bloom ||
+			h.Number.Cmp(expected.Number) != 0 || h.GasLimit != expected.GasLimit || h.Time != expected.Time ||
+			!bytes.Equal(h.Extra, expected.Extra) || h.Nonce != expected.Nonce {
+			t.Errorf("bound header does not match header %d", expected.Number)
+		}
+		if (h.BaseFee == nil) != (expected.BaseFee == nil) || (h.BaseFee != nil && h.BaseFee.Cmp(expected.BaseFee) != 0) {
+			t.Errorf("expected base fee %v, got %v", expected.BaseFee, h.BaseFee)
+		}
+		if (h.WithdrawalsRootCID == nil) != (expected.WithdrawalsHash == nil) ||
+			(h.BlobGasUsed == nil) != (expected.BlobGasUsed == nil) ||
+			(h.BlobGasUsed != nil && *h.BlobGasUsed != *expected.BlobGasUsed) {
+			t.Errorf("bound header does not match the optional fields of header %d", expected.Number)
+		}
+	}
+
+	// an application struct binds only the fields it needs
+	var custom struct {
+		Number *big.Int
+		Fee    *big.Int `ipld:"BaseFee"`
+		Ignore []byte   `ipld:"-"`
+	}
+	expected := g.Header()
+	expected.BaseFee = big.NewInt(7)
+	enc, _ := rlp.EncodeToBytes(expected)
+	if err := bind.Decode(enc, header.MultiCodecType, &custom); err != nil {
+		t.Fatalf("unable to bind header: %v", err)
+	}
+	if custom.Number.Cmp(expected.Number) != 0 || custom.Fee.Int64() != 7 {
+		t.Errorf("expected number %v and base fee 7, got %v and %v", expected.Number, custom.Number, custom.Fee)
+	}
+
+	var wrong struct{ Number uint8 }
+	if err := bind.Decode(enc, header.MultiCodecType, &wrong); err == nil && expected.Number.BitLen() > 8 {
+		t.Error("expected an error binding a block number to a field too small for it")
+	}
+	if err := bind.Decode(enc, header.MultiCodecType, custom); err == nil {
+		t.Error("expected an error binding to a non-pointer")
+	}
+}
+
+func TestTransactionAndReceipt(t *testing.T) {
+	g := testutil.NewGenerator(22)
+	for _, txType := range testutil.TxTypes {
+		expected := g.Transaction(txType)
+		enc, err := expected.MarshalBinary()
+		if err != nil {
+			t.Fatalf("unable to encode transaction: %v", err)
+		}
+		var transaction bind.Transaction
+		if err := bind.Decode(enc, tx.MultiCodecType, &transaction); err != nil {
+			t.Fatalf("type %d: unable to bind transaction: %v", txType, err)
+		}
+		if transaction.TxType != expected.Type() || transaction.AccountNonce != expected.Nonce() ||
+			transaction.Amount.Cmp(expected.Value()) != 0 || !bytes.Equal(transaction.Data, expected.Data()) ||
+			len(transaction.AccessList) != len(expected.AccessList()) ||
+			len(transaction.BlobVersionedHashes) != len(expected.BlobHashes()) ||
+			len(transaction.AuthorizationList) != len(expected.SetCodeAuthorizations()) {
+			t.Errorf("type %d: bound transaction does not match", txType)
+		}
+		if (transaction.Recipient == nil) != (expected.To() == nil) || (transaction.Recipient != nil && *transaction.Recipient != *expected.To()) {
+			t.Errorf("type %d: expected recipient %v, got %v", txType, expected.To(), transaction.Recipient)
+		}
+
+		receipt := g.Receipt(txType)
+		enc, err = receipt.MarshalBinary()
+		if err != nil {
+			t.Fatalf("unable to encode receipt: %v", err)
+		}
+		var r bind.Receipt
+		if err := bind.Decode(enc, rct.MultiCodecType, &r); err != nil {
+			t.Fatalf("type %d: unable to bind receipt: %v", txType, err)
+		}
+		if r.TxType != receipt.Type || r.CumulativeGasUsed != receipt.CumulativeGasUsed || r.Bloom != receipt.Bloom ||
+			len(r.Logs) != len(receipt.Logs) || !bytes.Equal(r.PostState, receipt.PostState) {
+			t.Errorf("type %d: bound receipt does not match", txType)
+		}
+		if len(receipt.PostState) == 0 && (r.Status == nil || *r.Status != receipt.Status) {
+			t.Errorf("type %d: expected status %d, got %v", txType, receipt.Status, r.Status)
+		}
+		for i, l := range r.Logs {
+			if l.Address != receipt.Logs[i].Address || len(l.Topics) != len(receipt.Logs[i].Topics) || !bytes.Equal(l.Data, receipt.Logs[i].Data) {
+				t.Errorf("type %d: bound log %d does not match", txType, i)
+			}
+		}
+	}
+}
+
+func TestTrieNode(t *testing.T) {
+	g := testutil.NewGenerator(23)
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	b := trie.NewBuilder(lsys, state_trie.MultiCodecType)
+	accounts := make(map[string]*types.StateAccount)
+	keys := make([][]byte, 40)
+	for i := range keys {
+		keys[i] = g.Hash().Bytes()
+	}
+	sortKeys(keys)
+	for _, key := range keys {
+		acct := g.Account()
+		enc, _ := rlp.EncodeToBytes(acct)
+		if err := b.Update(key, enc); err != nil {
+			t.Fatalf("unable to update trie: %v", err)
+		}
+		accounts[string(key)] = acct
+	}
+	root, err := b.Commit()
+	if err != nil {
+		t.Fatalf("unable to commit trie: %v", err)
+	}
+
+	var rootNode bind.TrieNode
+	if err := bind.Load(lsys, root, &rootNode); err != nil {
+		t.Fatalf("unable to bind root: %v", err)
+	}
+	if rootNode.Branch == nil || rootNode.Leaf != nil || rootNode.Extension != nil {
+		t.Fatal("expected the root of the trie to bind as a branch")
+	}
+	// walk the trie down to its leaves, and check their accounts
+	leaves := 0
+	var walk func(node *bind.TrieNode)
+	var walkChild func(child *bind.Child)
+	walk = func(node *bind.TrieNode) {
+		switch {
+		case node.Leaf != nil:
+			leaves++
+			if node.Leaf.Value.State == nil {
+				t.Fatal("expected the leaves of a state trie to hold accounts")
+			}
+			found := false
+			for _, acct := range accounts {
+				if acct.Nonce == node.Leaf.Value.State.Nonce && acct.Balance.Eq(node.Leaf.Value.State.Balance) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("bound account %+v is not in the trie", node.Leaf.Value.State)
+			}
+		case node.Branch != nil:
+			for _, child := range node.Branch.Children() {
+				if child != nil {
+					walkChild(child)
+				}
+			}
+		case node.Extension != nil:
+			walkChild(node.Extension.Child)
+		}
+	}
+	walkChild = func(child *bind.Child) {
+		if child.TrieNode != nil {
+			walk(child.TrieNode)
+			return
+		}
+		var next bind.TrieNode
+		if err := bind.Load(lsys, child.Link, &next); err != nil {
+			t.Fatalf("unable to bind child: %v", err)
+		}
+		walk(&next)
+	}
+	walk(&rootNode)
+	if leaves != len(accounts) {
+		t.Errorf("expected %d leaves, bound %d", len(accounts), leaves)
+	}
+
+	// the generated types bind the same way
+	node, err := lsys.Load(ipld.LinkContext{}, root, dageth.Type.TrieNode)
+	if err != nil {
+		t.Fatalf("unable to load root: %v", err)
+	}
+	var typed bind.TrieNode
+	if err := bind.Bind(node, &typed); err != nil {
+		t.Fatalf("unable to bind generated node: %v", err)
+	}
+	if typed.Branch == nil {
+		t.Fatal("expected the generated node to bind as a branch")
+	}
+	for i, child := range typed.Branch.Children() {
+		want := rootNode.Branch.Children()[i]
+		if (child == nil) != (want == nil) || (child != nil && child.Link != want.Link) {
+			t.Errorf("expected child %x of the generated node to bind as %+v, got %+v", i, want, child)
+		}
+	}
+
+	expected := g.Account()
+	enc, _ := rlp.EncodeToBytes(expected)
+	var acct bind.Account
+	if err := bind.Decode(enc, account.MultiCodecType, &acct); err != nil {
+		t.Fatalf("unable to bind account: %v", err)
+	}
+	if acct.Nonce != expected.Nonce || !acct.Balance.Eq(expected.Balance) {
+		t.Errorf("expected account %+v, got %+v", expected, acct)
+	}
+}
+
+func sortKeys(keys [][]byte) {
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && bytes.Compare(keys[j-1], keys[j]) > 0; j-- {
+			keys[j-1], keys[j] = keys[j], keys[j-1]
+		}
+	}
+}
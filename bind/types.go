@@ -0,0 +1,158 @@
+package bind
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+	"github.com/ipld/go-ipld-prime"
+)
+
+// Header binds the Header schema type
+type Header struct {
+	ParentCID           ipld.Link
+	UnclesCID           ipld.Link
+	Coinbase            common.Address
+	StateRootCID        ipld.Link
+	TxRootCID           ipld.Link
+	RctRootCID          ipld.Link
+	Bloom               types.Bloom
+	Difficulty          *big.Int
+	Number              *big.Int
+	GasLimit            uint64
+	GasUsed             uint64
+	Time                uint64
+	Extra               []byte
+	MixDigest           common.Hash
+	Nonce               types.BlockNonce
+	BaseFee             *big.Int
+	WithdrawalsRootCID  ipld.Link
+	BlobGasUsed         *uint64
+	ExcessBlobGas       *uint64
+	ParentBeaconRootCID ipld.Link
+	RequestsCID         ipld.Link
+}
+
+// Transaction binds the Transaction schema type
+type Transaction struct {
+	TxType              uint8
+	ChainID             *big.Int
+	AccountNonce        uint64
+	GasPrice            *big.Int
+	GasTipCap           *big.Int
+	GasFeeCap           *big.Int
+	GasLimit            uint64
+	Recipient           *common.Address
+	Amount              *big.Int
+	Data                []byte
+	AccessList          []AccessElement
+	MaxFeePerBlobGas    *big.Int
+	BlobVersionedHashes []common.Hash
+	AuthorizationList   []Authorization
+	V                   *big.Int
+	R                   *big.Int
+	S                   *big.Int
+	From                *common.Address
+}
+
+// AccessElement binds the AccessElement schema type
+type AccessElement struct {
+	Address     common.Address
+	StorageKeys []common.Hash
+}
+
+// Authorization binds the Authorization schema type
+type Authorization struct {
+	ChainID *big.Int
+	Address common.Address
+	Nonce   uint64
+	YParity uint8
+	R       *big.Int
+	S       *big.Int
+}
+
+// Receipt binds the Receipt schema type
+type Receipt struct {
+	TxType            uint8
+	PostState         []byte
+	Status            *uint64
+	CumulativeGasUsed uint64
+	Bloom             types.Bloom
+	Logs              []Log
+	LogRootCID        ipld.Link
+}
+
+// Log binds the Log schema type
+type Log struct {
+	Address common.Address
+	Topics  []common.Hash
+	Data    []byte
+}
+
+// Account binds the Account schema type
+type Account struct {
+	Nonce          uint64
+	Balance        *uint256.Int
+	StorageRootCID ipld.Link
+	CodeCID        ipld.Link
+}
+
+// Withdrawal binds the Withdrawal schema type
+type Withdrawal struct {
+	Index          uint64
+	ValidatorIndex uint64
+	Address        common.Address
+	Amount         uint64
+}
+
+// TrieNode binds the TrieNode schema type, only the field of the kind of node is set
+type TrieNode struct {
+	Branch    *TrieBranchNode    `ipld:"TrieBranchNode"`
+	Extension *TrieExtensionNode `ipld:"TrieExtensionNode"`
+	Leaf      *TrieLeafNode      `ipld:"TrieLeafNode"`
+}
+
+// TrieBranchNode binds the TrieBranchNode schema type
+type TrieBranchNode struct {
+	Child0, Child1, Child2, Child3, Child4, Child5, Child6, Child7 *Child
+	Child8, Child9, ChildA, ChildB, ChildC, ChildD, ChildE, ChildF *Child
+	Value                                                          *Value
+}
+
+// Children returns the children of the branch by nibble
+func (b *TrieBranchNode) Children() [16]*Child {
+	return [16]*Child{
+		b.Child0, b.Child1, b.Child2, b.Child3, b.Child4, b.Child5, b.Child6, b.Child7,
+		b.Child8, b.Child9, b.ChildA, b.ChildB, b.ChildC, b.ChildD, b.ChildE, b.ChildF,
+	}
+}
+
+// Child binds the Child schema type: a link to the child node, or the child node itself when it is embedded in
+// its parent
+type Child struct {
+	Link     ipld.Link
+	TrieNode *TrieNode
+}
+
+// TrieExtensionNode binds the TrieExtensionNode schema type
+type TrieExtensionNode struct {
+	PartialPath []byte
+	Child       *Child
+}
+
+// TrieLeafNode binds the TrieLeafNode schema type
+type TrieLeafNode struct {
+	PartialPath []byte
+	Value       Value
+}
+
+// Value binds the Value schema type, only the field of the kind of trie is set
+type Value struct {
+	Tx         *Transaction `ipld:"Transaction"`
+	Rct        *Receipt     `ipld:"Receipt"`
+	State      *Account     `ipld:"Account"`
+	Storage    []byte       `ipld:"Bytes"`
+	Log        *Log
+	Withdrawal *Withdrawal
+}
@@ -0,0 +1,107 @@
+package blob_sidecar_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/blob_sidecar"
+)
+
+var (
+	mockSidecar = &types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{{1, 2, 3}, {4, 5, 6}},
+		Commitments: []kzg4844.Commitment{{0xc0, 1}, {0xc0, 2}},
+		Proofs:      []kzg4844.Proof{{0xc0, 3}, {0xc0, 4}},
+	}
+	sidecarRLP  []byte
+	sidecarNode ipld.Node
+)
+
+/* IPLD Schemas
+type Blobs [Bytes]
+
+type KZGCommitments [Bytes]
+
+type KZGProofs [Bytes]
+
+type BlobSidecar struct {
+	Blobs       Blobs
+	Commitments KZGCommitments
+	Proofs      KZGProofs
+}
+*/
+
+func TestBlobSidecarCodec(t *testing.T) {
+	var err error
+	sidecarRLP, err = rlp.EncodeToBytes(mockSidecar)
+	if err != nil {
+		t.Fatalf("unable to RLP encode blob sidecar: %v", err)
+	}
+	testBlobSidecarDecode(t)
+	testBlobSidecarNodeContents(t)
+	testBlobSidecarEncode(t)
+}
+
+func testBlobSidecarDecode(t *testing.T) {
+	sidecarBuilder := dageth.Type.BlobSidecar.NewBuilder()
+	sidecarReader := bytes.NewReader(sidecarRLP)
+	if err := blob_sidecar.Decode(sidecarBuilder, sidecarReader); err != nil {
+		t.Fatalf("unable to decode blob sidecar into an IPLD node: %v", err)
+	}
+	sidecarNode = sidecarBuilder.Build()
+}
+
+func testBlobSidecarNodeContents(t *testing.T) {
+	for key, expected := range map[string][][]byte{
+		"Blobs":       {mockSidecar.Blobs[0][:], mockSidecar.Blobs[1][:]},
+		"Commitments": {mockSidecar.Commitments[0][:], mockSidecar.Commitments[1][:]},
+		"Proofs":      {mockSidecar.Proofs[0][:], mockSidecar.Proofs[1][:]},
+	} {
+		listNode, err := sidecarNode.LookupByString(key)
+		if err != nil {
+			t.Fatalf("blob sidecar missing %s: %v", key, err)
+		}
+		if listNode.Length() != int64(len(expected)) {
+			t.Fatalf("blob sidecar %s should have %d entries", key, len(expected))
+		}
+		for i, expectedBytes := range expected {
+			elementNode, err := listNode.LookupByIndex(int64(i))
+			if err != nil {
+				t.Fatalf("unable to look up blob sidecar %s entry %d: %v", key, i, err)
+			}
+			elementBytes, err := elementNode.AsBytes()
+			if err != nil {
+				t.Fatalf("blob sidecar %s entry should be of type Bytes: %v", key, err)
+			}
+			if !bytes.Equal(elementBytes, expectedBytes) {
+				t.Errorf("blob sidecar %s entry %d does not match the expected bytes", key, i)
+			}
+		}
+	}
+
+	blobHashes, err := blob_sidecar.BlobHashes(sidecarNode)
+	if err != nil {
+		t.Fatalf("unable to derive blob sidecar versioned hashes: %v", err)
+	}
+	for i, expectedHash := range mockSidecar.BlobHashes() {
+		if blobHashes[i] != expectedHash {
+			t.Errorf("blob sidecar versioned hash (%x) does not match expected hash (%x)", blobHashes[i], expectedHash)
+		}
+	}
+}
+
+func testBlobSidecarEncode(t *testing.T) {
+	sidecarWriter := new(bytes.Buffer)
+	if err := blob_sidecar.Encode(sidecarNode, sidecarWriter); err != nil {
+		t.Fatalf("unable to encode blob sidecar into writer: %v", err)
+	}
+	if !bytes.Equal(sidecarWriter.Bytes(), sidecarRLP) {
+		t.Errorf("blob sidecar encoding does not match the expected RLP encoding")
+	}
+}
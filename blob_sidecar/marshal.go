@@ -0,0 +1,144 @@
+package blob_sidecar
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// Encode provides an IPLD codec encode interface for eth blob sidecar IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0x9e (proposed) when this package is invoked via init.
+func Encode(node ipld.Node, w io.Writer) error {
+	// every blob is 128KiB, so there is nothing to gain from a small preallocated buffer
+	enc, err := AppendEncode(nil, node)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(enc)
+	return err
+}
+
+// AppendEncode is like Encode, but it uses a destination buffer directly.
+// This means less copying of bytes, and if the destination has enough capacity,
+// fewer allocations.
+func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	sidecar := new(types.BlobTxSidecar)
+	if err := EncodeSidecar(sidecar, inNode); err != nil {
+		return enc, err
+	}
+	wbs := shared.NewWriteableByteSlice(&enc)
+	if err := rlp.Encode(wbs, sidecar); err != nil {
+		return enc, fmt.Errorf("invalid DAG-ETH BlobSidecar form (unable to RLP encode sidecar: %v)", err)
+	}
+	return enc, nil
+}
+
+// EncodeOptions can be used to customize the behavior of blob sidecar encoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type EncodeOptions struct{}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return Encode(node, w)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return AppendEncode(enc, inNode)
+}
+
+// EncodeSidecar packs the node into a go-ethereum BlobTxSidecar
+func EncodeSidecar(sidecar *types.BlobTxSidecar, inNode ipld.Node) error {
+	// Wrap in a typed node for some basic schema form checking
+	builder := dageth.Type.BlobSidecar.NewBuilder()
+	if err := builder.AssignNode(inNode); err != nil {
+		return err
+	}
+	node := builder.Build()
+	blobs, err := packBytesList(node, "Blobs", len(kzg4844.Blob{}))
+	if err != nil {
+		return fmt.Errorf("invalid DAG-ETH BlobSidecar form (%v)", err)
+	}
+	sidecar.Blobs = make([]kzg4844.Blob, len(blobs))
+	for i, blob := range blobs {
+		copy(sidecar.Blobs[i][:], blob)
+	}
+	commitments, err := packBytesList(node, "Commitments", len(kzg4844.Commitment{}))
+	if err != nil {
+		return fmt.Errorf("invalid DAG-ETH BlobSidecar form (%v)", err)
+	}
+	sidecar.Commitments = make([]kzg4844.Commitment, len(commitments))
+	for i, commitment := range commitments {
+		copy(sidecar.Commitments[i][:], commitment)
+	}
+	proofs, err := packBytesList(node, "Proofs", len(kzg4844.Proof{}))
+	if err != nil {
+		return fmt.Errorf("invalid DAG-ETH BlobSidecar form (%v)", err)
+	}
+	sidecar.Proofs = make([]kzg4844.Proof, len(proofs))
+	for i, proof := range proofs {
+		copy(sidecar.Proofs[i][:], proof)
+	}
+	return nil
+}
+
+func packBytesList(node ipld.Node, key string, size int) ([][]byte, error) {
+	listNode, err := node.LookupByString(key)
+	if err != nil {
+		return nil, err
+	}
+	list := make([][]byte, listNode.Length())
+	listIt := listNode.ListIterator()
+	for !listIt.Done() {
+		index, elementNode, err := listIt.Next()
+		if err != nil {
+			return nil, err
+		}
+		element, err := elementNode.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(element) != size {
+			return nil, fmt.Errorf("%s entry %d should be %d bytes; got %d", key, index, size, len(element))
+		}
+		list[index] = element
+	}
+	return list, nil
+}
+
+// BlobHashes derives the versioned hashes of the sidecar's commitments,
+// these are the BlobVersionedHashes of the transaction the sidecar belongs to
+func BlobHashes(node ipld.Node) ([]common.Hash, error) {
+	commitments, err := packBytesList(node, "Commitments", len(kzg4844.Commitment{}))
+	if err != nil {
+		return nil, err
+	}
+	hasher := sha256.New()
+	blobHashes := make([]common.Hash, len(commitments))
+	for i, commitment := range commitments {
+		var c kzg4844.Commitment
+		copy(c[:], commitment)
+		blobHashes[i] = kzg4844.CalcBlobHashV1(hasher, &c)
+	}
+	return blobHashes, nil
+}
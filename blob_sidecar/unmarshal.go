@@ -0,0 +1,124 @@
+package blob_sidecar
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// Decode provides an IPLD codec decode interface for eth blob sidecar IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0x9e (proposed) when this package is invoked via init.
+func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	var src []byte
+	if buf, ok := in.(interface{ Bytes() []byte }); ok {
+		src = buf.Bytes()
+	} else {
+		var err error
+		src, err = ioutil.ReadAll(in)
+		if err != nil {
+			return err
+		}
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	var sidecar types.BlobTxSidecar
+	if err := rlp.DecodeBytes(src, &sidecar); err != nil {
+		return err
+	}
+	return DecodeSidecar(na, &sidecar)
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeOptions can be used to customize the behavior of blob sidecar decoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type DecodeOptions struct{}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return Decode(na, in)
+}
+
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeBytes(na, src)
+}
+
+// DecodeSidecar unpacks a go-ethereum BlobTxSidecar into a NodeAssembler
+func DecodeSidecar(na ipld.NodeAssembler, sidecar *types.BlobTxSidecar) error {
+	ma, err := na.BeginMap(3)
+	if err != nil {
+		return err
+	}
+	blobs := make([][]byte, len(sidecar.Blobs))
+	for i := range sidecar.Blobs {
+		blobs[i] = sidecar.Blobs[i][:]
+	}
+	if err := unpackBytesList(ma, "Blobs", blobs); err != nil {
+		return err
+	}
+	commitments := make([][]byte, len(sidecar.Commitments))
+	for i := range sidecar.Commitments {
+		commitments[i] = sidecar.Commitments[i][:]
+	}
+	if err := unpackBytesList(ma, "Commitments", commitments); err != nil {
+		return err
+	}
+	proofs := make([][]byte, len(sidecar.Proofs))
+	for i := range sidecar.Proofs {
+		proofs[i] = sidecar.Proofs[i][:]
+	}
+	if err := unpackBytesList(ma, "Proofs", proofs); err != nil {
+		return err
+	}
+	return ma.Finish()
+}
+
+func unpackBytesList(ma ipld.MapAssembler, key string, list [][]byte) error {
+	if err := ma.AssembleKey().AssignString(key); err != nil {
+		return err
+	}
+	la, err := ma.AssembleValue().BeginList(int64(len(list)))
+	if err != nil {
+		return err
+	}
+	for _, b := range list {
+		if err := la.AssembleValue().AssignBytes(b); err != nil {
+			return err
+		}
+	}
+	return la.Finish()
+}
@@ -2,162 +2,394 @@ package block_test
 
 import (
 	"bytes"
-	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ipfs/go-cid"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
 	"github.com/ipld/go-ipld-prime"
-	"github.com/ipld/go-ipld-prime/codec/dagcbor"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
-	"github.com/multiformats/go-multihash"
+	"github.com/ipld/go-ipld-prime/storage"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/adl"
 	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/consensus"
+	"github.com/vulcanize/go-codec-dageth/convert"
 	"github.com/vulcanize/go-codec-dageth/rct_list"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/testutil"
 	"github.com/vulcanize/go-codec-dageth/tx_list"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
 )
 
-var (
-	headerHash      = common.HexToHash("0x4da3b2a073862e40d11acfa94b94f048cdeda19e97e18a99eee43ff9c9c11da0")
-	txsHash         = common.HexToHash("0x14b05960bf92af6264a8904706c47cbd9c0c4256f4c9acd00839df607ab5957e")
-	rctsHash        = common.HexToHash("0xdf31820e936cdf974bccf5319d99429e6b8780324fd083ad512dffced89a693d")
-	blk             = new(block.Block)
-	blkCBOREncoding = common.Hex2Bytes("a366486561646572d82a5826000190011b204da3b2a073862e40d11acfa94b94f048cdeda19e97e18a99eee43ff9c9c11da06c5472616e73616374696f6e73d82a582600019c011b2014b05960bf92af6264a8904706c47cbd9c0c4256f4c9acd00839df607ab5957e685265636569707473d82a582600019d011b20df31820e936cdf974bccf5319d99429e6b8780324fd083ad512dffced89a693d")
-	blockNode       ipld.Node
-)
-
-/*
-# Block represents an entire block in the Ethereum blockchain.
-type Block struct {
-   # CID link to the block at this block
-   # This CID is composed of the KECCAK_256 multihash of the RLP encoded block and the EthBlock codec (0x90)
-   # Note that the block contains references to the uncles and tx, receipt, and state tries at this height
-   HeaderCID       &Header
-   # CID link to the list of transactions at this block
-   # This CID is composed of the KECCAK_256 multihash of the RLP encoded list of transactions and the EthTxList codec (0x9c)
-   TransactionsCID &Transactions
-   # CID link to the list of receipts at this block
-   # This CID is composed of the KECCAK_256 multihash of the RLP encoded list of receipts and the EthTxReceiptList codec (0x9d)
-   ReceiptsCID     &Receipts
+// mockBlock returns a block with enough transactions for the RLP encoded index keys to run past 0x7f,
+// along with its receipts, an uncle and withdrawals
+func mockBlock() (*types.Block, types.Receipts) {
+	txs := make(types.Transactions, 130)
+	receipts := make(types.Receipts, len(txs))
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), shared.RandomAddr(), big.NewInt(1), 21000, big.NewInt(1), nil)
+		receipts[i] = &types.Receipt{
+			Type:              types.LegacyTxType,
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(i+1) * 21000,
+			Logs:              []*types.Log{},
+		}
+		for j := 0; j < i%3; j++ {
+			receipts[i].Logs = append(receipts[i].Logs, &types.Log{
+				Address: shared.RandomAddr(),
+				Topics:  []common.Hash{shared.RandomHash()},
+				Data:    []byte{byte(j)},
+			})
+		}
+		receipts[i].Bloom = types.CreateBloom(receipts[i])
+	}
+	uncle := &types.Header{
+		ParentHash: shared.RandomHash(),
+		Number:     big.NewInt(99),
+		Difficulty: big.NewInt(1),
+		Extra:      []byte("uncle"),
+	}
+	withdrawals := []*types.Withdrawal{
+		{Index: 1, Validator: 10, Address: shared.RandomAddr(), Amount: 100},
+		{Index: 2, Validator: 20, Address: shared.RandomAddr(), Amount: 200},
+	}
+	h := &types.Header{
+		ParentHash: shared.RandomHash(),
+		Coinbase:   shared.RandomAddr(),
+		Root:       shared.RandomHash(),
+		Difficulty: big.NewInt(1),
+		Number:     big.NewInt(100),
+		GasLimit:   30000000,
+		GasUsed:    uint64(len(txs)) * 21000,
+		Time:       1700000000,
+		BaseFee:    big.NewInt(7),
+	}
+	body := &types.Body{Transactions: txs, Uncles: []*types.Header{uncle}, Withdrawals: withdrawals}
+	return types.NewBlock(h, body, receipts, gethtrie.NewStackTrie(nil)), receipts
 }
-*/
 
-func TestBlock(t *testing.T) {
-	headerMh, err := multihash.Encode(headerHash.Bytes(), multihash.KECCAK_256)
+func lookupLink(t *testing.T, node ipld.Node, field string) ipld.Link {
+	linkNode, err := node.LookupByString(field)
 	if err != nil {
-		t.Fatalf("unable to derive multihash for header hash: %v", err)
+		t.Fatalf("unable to look up %s: %v", field, err)
 	}
-	headerCID := cid.NewCidV1(cid.EthBlock, headerMh)
-	blk.Header = append([]byte{0}, headerCID.Bytes()...)
+	lnk, err := linkNode.AsLink()
+	if err != nil {
+		t.Fatalf("%s is not a link: %v", field, err)
+	}
+	return lnk
+}
 
-	txsMh, err := multihash.Encode(txsHash.Bytes(), multihash.KECCAK_256)
+func TestPackBlock(t *testing.T) {
+	blk, receipts := mockBlock()
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	headerLink, err := block.PackBlock(blk, receipts, lsys)
 	if err != nil {
-		t.Fatalf("unable to derive multihash for transactions hash: %v", err)
+		t.Fatalf("unable to pack block: %v", err)
 	}
-	txsCID := cid.NewCidV1(tx_list.MultiCodecType, txsMh)
-	blk.Transactions = append([]byte{0}, txsCID.Bytes()...)
 
-	rctsMh, err := multihash.Encode(rctsHash.Bytes(), multihash.KECCAK_256)
+	headerNode, err := lsys.Load(ipld.LinkContext{}, headerLink, dageth.Type.Header)
 	if err != nil {
-		t.Fatalf("unable to derive multihash for receipts hash: %v", err)
+		t.Fatalf("unable to load header: %v", err)
+	}
+	unclesNode, err := lsys.Load(ipld.LinkContext{}, lookupLink(t, headerNode, "UnclesCID"), dageth.Type.Uncles)
+	if err != nil {
+		t.Fatalf("unable to load uncles: %v", err)
+	}
+	if unclesNode.Length() != 1 {
+		t.Errorf("expected 1 uncle, got %d", unclesNode.Length())
 	}
-	rctsCID := cid.NewCidV1(rct_list.MultiCodecType, rctsMh)
-	blk.Receipts = append([]byte{0}, rctsCID.Bytes()...)
 
-	/*
-		opts := cbor.CanonicalEncOptions()
-		ts := cbor.NewTagSet()
-		tsOpts := cbor.TagOptions{
-			DecTag: cbor.DecTagRequired,
-			EncTag: cbor.EncTagRequired,
-		}
-		ts.Add(tsOpts, reflect.TypeOf(block.CIDLink{}), 42)
-		enc, err := opts.EncModeWithTags(ts)
+	txList := adl.NewTransactionList(lsys, lookupLink(t, headerNode, "TxRootCID"))
+	rctList := adl.NewReceiptList(lsys, lookupLink(t, headerNode, "RctRootCID"))
+	if txList.Length() != int64(len(blk.Transactions())) || rctList.Length() != int64(len(receipts)) {
+		t.Fatalf("expected %d transactions and receipts, got %d and %d", len(receipts), txList.Length(), rctList.Length())
+	}
+	for i := range receipts {
+		rctNode, err := rctList.LookupByIndex(int64(i))
 		if err != nil {
-			t.Fatalf("unable to create CBOR marshaller: %v", err)
+			t.Fatalf("unable to look up receipt %d: %v", i, err)
 		}
-		blkCBOREncoding, err = enc.Marshal(blk)
-		if err != nil {
-			t.Fatalf("unable to encode Block to CBOR: %v", err)
+		// every receipt with logs links to a stored log trie
+		if len(receipts[i].Logs) > 0 {
+			if _, err := lsys.Load(ipld.LinkContext{}, lookupLink(t, rctNode, "LogRootCID"), dageth.Type.TrieNode); err != nil {
+				t.Errorf("unable to load log trie of receipt %d: %v", i, err)
+			}
 		}
-	*/
+	}
+	if _, err := lsys.Load(ipld.LinkContext{}, lookupLink(t, headerNode, "WithdrawalsRootCID"), dageth.Type.TrieNode); err != nil {
+		t.Errorf("unable to load withdrawal trie: %v", err)
+	}
 
-	testBlockDecode(t)
-	testBlockNodeContents(t)
-	testBlockEncode(t)
+	if _, err := block.PackBlock(blk, receipts[1:], lsys); err == nil {
+		t.Error("expected an error packing a block with a missing receipt")
+	}
+	tampered := make(types.Receipts, len(receipts))
+	copy(tampered, receipts)
+	tampered[0] = &types.Receipt{Type: types.LegacyTxType, Status: types.ReceiptStatusFailed, Logs: []*types.Log{}}
+	if _, err := block.PackBlock(blk, tampered, lsys); err == nil {
+		t.Error("expected an error packing a block with receipts that do not match its receipt root")
+	}
 }
 
-func testBlockDecode(t *testing.T) {
-	blockBuilder := dageth.Type.Block.NewBuilder()
-	fmt.Printf("CBOR: %x\r\n", blkCBOREncoding)
-	blockReader := bytes.NewReader(blkCBOREncoding)
-	if err := dagcbor.Decode(blockBuilder, blockReader); err != nil {
-		t.Fatalf("unable to decode block into a DAG-CBOR IPLD node: %v", err)
+func TestUnpackBlock(t *testing.T) {
+	blk, receipts := mockBlock()
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	headerLink, err := block.PackBlock(blk, receipts, lsys)
+	if err != nil {
+		t.Fatalf("unable to pack block: %v", err)
+	}
+
+	unpacked, unpackedRcts, err := block.UnpackBlock(lsys, headerLink)
+	if err != nil {
+		t.Fatalf("unable to unpack block: %v", err)
+	}
+	if unpacked.Hash() != blk.Hash() {
+		t.Errorf("unpacked block hash (%s) does not match expected hash (%s)", unpacked.Hash().Hex(), blk.Hash().Hex())
+	}
+	if len(unpacked.Uncles()) != 1 || unpacked.Uncles()[0].Hash() != blk.Uncles()[0].Hash() {
+		t.Error("unpacked uncles do not match the block's uncles")
+	}
+	if len(unpacked.Withdrawals()) != len(blk.Withdrawals()) || *unpacked.Withdrawals()[1] != *blk.Withdrawals()[1] {
+		t.Error("unpacked withdrawals do not match the block's withdrawals")
+	}
+	for i, trx := range blk.Transactions() {
+		if unpacked.Transactions()[i].Hash() != trx.Hash() {
+			t.Errorf("unpacked transaction %d hash does not match expected hash %s", i, trx.Hash().Hex())
+		}
+		receipt := unpackedRcts[i]
+		if receipt.TxHash != trx.Hash() || receipt.BlockHash != blk.Hash() || receipt.TransactionIndex != uint(i) {
+			t.Errorf("unpacked receipt %d does not locate its transaction", i)
+		}
+		if receipt.CumulativeGasUsed != receipts[i].CumulativeGasUsed || len(receipt.Logs) != len(receipts[i].Logs) {
+			t.Errorf("unpacked receipt %d does not match the expected receipt", i)
+		}
+	}
+
+	// tampering with any stored block is caught when it is loaded
+	txRoot := testutil.KeccakLink(t, tx_trie.MultiCodecType, blk.TxHash().Bytes())
+	tampered := append([]byte{}, store.Bag[txRoot]...)
+	tampered[len(tampered)-1] ^= 0xff
+	store.Bag[txRoot] = tampered
+	lsys.TrustedStorage = true
+	if _, _, err := block.UnpackBlock(lsys, headerLink); err == nil {
+		t.Error("expected an error unpacking a block with a tampered IPLD block")
 	}
-	blockNode = blockBuilder.Build()
 }
 
-func testBlockNodeContents(t *testing.T) {
-	headerNode, err := blockNode.LookupByString("Header")
+func TestPackRequests(t *testing.T) {
+	g := testutil.NewGenerator(1)
+	requests := g.Requests()
+	// make sure a request carries data, and one does not and is left out of the requests hash
+	requests[1] = append([]byte{1}, g.SSZ(consensus.WithdrawalRequest)...)
+	requests[2] = []byte{2}
+	blk, receipts := mockBlock()
+	h := blk.Header()
+	blobGasUsed, excessBlobGas, parentBeaconRoot := uint64(0), uint64(0), shared.RandomHash()
+	h.BlobGasUsed, h.ExcessBlobGas, h.ParentBeaconRoot = &blobGasUsed, &excessBlobGas, &parentBeaconRoot
+	requestsHash := types.CalcRequestsHash(requests)
+	h.RequestsHash = &requestsHash
+	blk = blk.WithSeal(h)
+
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	headerLink, err := block.PackBlock(blk, receipts, lsys)
 	if err != nil {
-		t.Fatalf("block is missing HeaderCID: %v", err)
+		t.Fatalf("unable to pack block: %v", err)
 	}
-	headerLink, err := headerNode.AsLink()
+	requestsLink, err := block.PackRequests(requests, h, lsys)
 	if err != nil {
-		t.Fatalf("block HeaderCID is not a link: %v", err)
+		t.Fatalf("unable to pack requests: %v", err)
 	}
-	headerCIDLink, ok := headerLink.(cidlink.Link)
-	if !ok {
-		t.Fatalf("block HeaderCID is not a CID: %v", err)
+	headerNode, err := lsys.Load(ipld.LinkContext{}, headerLink, dageth.Type.Header)
+	if err != nil {
+		t.Fatalf("unable to load header: %v", err)
 	}
-	headerLinkBytes := append([]byte{0}, headerCIDLink.Bytes()...)
-	if !bytes.Equal(headerLinkBytes, blk.Header) {
-		t.Errorf("block header cid (%x) does not match expected cid (%x)", headerLinkBytes, blk.Header)
+	if lnk := lookupLink(t, headerNode, "RequestsCID"); lnk != requestsLink {
+		t.Errorf("header RequestsCID %s does not link to the packed requests %s", lnk.String(), requestsLink.String())
 	}
 
-	txsNode, err := blockNode.LookupByString("Transactions")
+	unpacked, err := block.UnpackRequests(lsys, headerLink)
 	if err != nil {
-		t.Fatalf("block is missing TransactionsCID: %v", err)
+		t.Fatalf("unable to unpack requests: %v", err)
 	}
-	txsLink, err := txsNode.AsLink()
-	if err != nil {
-		t.Fatalf("block TransactionsCID is not a link: %v", err)
+	var expected [][]byte
+	for _, req := range requests {
+		if len(req) > 1 {
+			expected = append(expected, req)
+		}
 	}
-	txsCIDLink, ok := txsLink.(cidlink.Link)
-	if !ok {
-		t.Fatalf("block TransactionsCID is not a CID: %v", err)
+	if len(unpacked) != len(expected) {
+		t.Fatalf("expected %d unpacked requests, got %d", len(expected), len(unpacked))
 	}
-	txsLinkBytes := append([]byte{0}, txsCIDLink.Bytes()...)
-	if !bytes.Equal(txsLinkBytes, blk.Transactions) {
-		t.Errorf("block txs cid (%x) does not match expected cid (%x)", txsLinkBytes, blk.Transactions)
+	for i := range expected {
+		if !bytes.Equal(unpacked[i], expected[i]) {
+			t.Errorf("unpacked request %d (%x) does not match the packed request (%x)", i, unpacked[i], expected[i])
+		}
 	}
 
-	rctsNode, err := blockNode.LookupByString("Receipts")
+	if _, err := block.PackRequests(requests[:1], h, lsys); err == nil {
+		t.Error("expected an error packing requests that do not match the header requests hash")
+	}
+	if _, err := block.PackRequests([][]byte{{0x7f, 0x01}}, nil, lsys); err == nil {
+		t.Error("expected an error packing a request of an unknown type")
+	}
+	// headers from before Prague have no requests
+	h.RequestsHash = nil
+	preRequestsLink, err := block.PackBlock(blk.WithSeal(h), receipts, lsys)
 	if err != nil {
-		t.Fatalf("block is missing ReceiptsCID: %v", err)
+		t.Fatalf("unable to pack block: %v", err)
+	}
+	if requests, err := block.UnpackRequests(lsys, preRequestsLink); err != nil || requests != nil {
+		t.Errorf("expected no requests for a pre-Prague header, got %d (%v)", len(requests), err)
 	}
-	rctsLink, err := rctsNode.AsLink()
+}
+
+func TestTxReceiptIterator(t *testing.T) {
+	blk, receipts := mockBlock()
+	lsys := codecs.NewLinkSystem(&storage.Memory{})
+	headerLink, err := block.PackBlock(blk, receipts, lsys)
 	if err != nil {
-		t.Fatalf("block ReceiptsCID is not a link: %v", err)
+		t.Fatalf("unable to pack block: %v", err)
 	}
-	rctsCIDLink, ok := rctsLink.(cidlink.Link)
-	if !ok {
-		t.Fatalf("block ReceiptsCID is not a CID: %v", err)
+	it, err := block.NewTxReceiptIterator(lsys, headerLink)
+	if err != nil {
+		t.Fatalf("unable to iterate block: %v", err)
+	}
+	var n int
+	for !it.Done() {
+		idx, txNode, rctNode, err := it.Next()
+		if err != nil {
+			t.Fatalf("unable to iterate transaction %d: %v", n, err)
+		}
+		if idx != int64(n) {
+			t.Fatalf("expected transaction %d, got %d", n, idx)
+		}
+		trx, err := convert.ToTransaction(txNode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trx.Hash() != blk.Transactions()[n].Hash() {
+			t.Errorf("transaction %d hash %x does not match %x", n, trx.Hash(), blk.Transactions()[n].Hash())
+		}
+		receipt, err := convert.ToReceipt(rctNode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if receipt.CumulativeGasUsed != receipts[n].CumulativeGasUsed {
+			t.Errorf("receipt %d is not paired with transaction %d", n, n)
+		}
+		n++
 	}
-	rctsLinkBytes := append([]byte{0}, rctsCIDLink.Bytes()...)
-	if !bytes.Equal(rctsLinkBytes, blk.Receipts) {
-		t.Errorf("block rcts hash (%x) does not match expected hash (%x)", rctsLinkBytes, blk.Receipts)
+	if n != len(receipts) {
+		t.Errorf("expected %d pairs, got %d", len(receipts), n)
+	}
+
+	// with log indexes, the logs of the block are numbered consecutively across its receipts
+	it, err = block.TxReceiptIteratorOptions{LogIndexes: true}.NewTxReceiptIterator(lsys, headerLink)
+	if err != nil {
+		t.Fatalf("unable to iterate block: %v", err)
+	}
+	var logIndex int64
+	for !it.Done() {
+		idx, _, rctNode, err := it.Next()
+		if err != nil {
+			t.Fatalf("unable to iterate transaction %d: %v", idx, err)
+		}
+		logsNode, _ := rctNode.LookupByString("Logs")
+		for lit := logsNode.ListIterator(); !lit.Done(); {
+			_, logNode, _ := lit.Next()
+			indexNode, err := logNode.LookupByString(adl.BlockLogIndexField)
+			if err != nil {
+				t.Fatalf("log of receipt %d has no block log index: %v", idx, err)
+			}
+			if index, _ := indexNode.AsInt(); index != logIndex {
+				t.Errorf("expected block log index %d, got %d", logIndex, index)
+			}
+			logIndex++
+		}
+	}
+	var logs int
+	for _, receipt := range receipts {
+		logs += len(receipt.Logs)
+	}
+	if logIndex != int64(logs) || logs == 0 {
+		t.Errorf("expected %d indexed logs, got %d", logs, logIndex)
+	}
+
+	empty := types.NewBlock(blk.Header(), nil, nil, gethtrie.NewStackTrie(nil))
+	emptyLink, err := block.PackBlock(empty, nil, lsys)
+	if err != nil {
+		t.Fatalf("unable to pack empty block: %v", err)
+	}
+	it, err = block.NewTxReceiptIterator(lsys, emptyLink)
+	if err != nil || !it.Done() {
+		t.Errorf("expected no pairs for an empty block (%v)", err)
 	}
 }
 
-func testBlockEncode(t *testing.T) {
-	blockWriter := new(bytes.Buffer)
-	if err := dagcbor.Encode(blockNode, blockWriter); err != nil {
-		t.Fatalf("unable to encode block into writer: %v", err)
+func TestVerifyBlockBody(t *testing.T) {
+	blk, receipts := mockBlock()
+	store := &storage.Memory{}
+	lsys := codecs.NewLinkSystem(store)
+	headerLink, err := block.PackBlock(blk, receipts, lsys)
+	if err != nil {
+		t.Fatalf("unable to pack block: %v", err)
+	}
+	headerNode, err := lsys.Load(ipld.LinkContext{}, headerLink, dageth.Type.Header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := block.VerifyBlockBody(headerNode, lsys); err != nil {
+		t.Errorf("expected the body of the packed block to verify: %v", err)
+	}
+
+	storeList := func(codec uint64, v interface{}) ipld.Link {
+		enc, err := rlp.EncodeToBytes(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := shared.RawToCid(codec, enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Bag[cidlink.Link{Cid: c}] = enc
+		return cidlink.Link{Cid: c}
+	}
+	txList := storeList(tx_list.MultiCodecType, blk.Transactions())
+	rctList := storeList(rct_list.MultiCodecType, receipts)
+	if err := block.VerifyBlockBodyLists(headerNode, lsys, txList, rctList); err != nil {
+		t.Errorf("expected the lists of the packed block to verify: %v", err)
+	}
+	shortList := storeList(tx_list.MultiCodecType, blk.Transactions()[1:])
+	if err := block.VerifyBlockBodyLists(headerNode, lsys, shortList, rctList); err == nil {
+		t.Error("expected a transaction list missing a transaction not to verify")
+	}
+
+	// a trie node missing from the store fails the walk
+	txRoot := lookupLink(t, headerNode, "TxRootCID")
+	delete(store.Bag, txRoot)
+	if err := block.VerifyBlockBody(headerNode, lsys); err == nil {
+		t.Error("expected a block whose transaction trie root is missing not to verify")
+	}
+
+	empty := types.NewBlock(blk.Header(), nil, nil, gethtrie.NewStackTrie(nil))
+	emptyLink, err := block.PackBlock(empty, nil, lsys)
+	if err != nil {
+		t.Fatalf("unable to pack empty block: %v", err)
+	}
+	emptyHeader, err := lsys.Load(ipld.LinkContext{}, emptyLink, dageth.Type.Header)
+	if err != nil {
+		t.Fatal(err)
 	}
-	encodedBlockBytes := blockWriter.Bytes()
-	if !bytes.Equal(encodedBlockBytes, blkCBOREncoding) {
-		t.Errorf("block encoding (%x) does not match the expected CBOR encoding (%x)", encodedBlockBytes, blkCBOREncoding)
+	if err := block.VerifyBlockBody(emptyHeader, lsys); err != nil {
+		t.Errorf("expected the body of an empty block to verify: %v", err)
 	}
 }
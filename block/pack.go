@@ -0,0 +1,186 @@
+/*
+Package block packages go-ethereum blocks into DAG-ETH IPLD blocks, and unpacks them again.
+VerifyBlockBody checks the transaction and receipt tries a header links to against its roots, and
+TxReceiptIterator walks them side by side.
+*/
+package block
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/log_trie"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/trie"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+// PackBlock encodes the header, uncles, transactions, receipts, logs and withdrawals of the block, builds the
+// transaction, receipt, log and withdrawal tries that link them together, stores all of the resulting IPLD blocks
+// through the LinkSystem, and returns the link to the header.
+// The receipts need to be those of the block's transactions, in order; the trie roots built from the block
+// contents are checked against the roots committed to in the header.
+func PackBlock(block *types.Block, receipts types.Receipts, lsys ipld.LinkSystem) (ipld.Link, error) {
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("block has %d transactions but %d receipts were provided", len(txs), len(receipts))
+	}
+	h := block.Header()
+	p := &packer{lsys: lsys}
+
+	headerRLP, err := rlp.EncodeToBytes(h)
+	if err != nil {
+		return nil, err
+	}
+	headerHash := p.store(header.MultiCodecType, headerRLP)
+	unclesRLP, err := rlp.EncodeToBytes(block.Uncles())
+	if err != nil {
+		return nil, err
+	}
+	p.check("uncles", h.UncleHash, p.store(uncles.MultiCodecType, unclesRLP))
+
+	txRLPs := make([][]byte, len(txs))
+	for i, trx := range txs {
+		if txRLPs[i], err = trx.MarshalBinary(); err != nil {
+			return nil, err
+		}
+		p.store(tx.MultiCodecType, txRLPs[i])
+	}
+	p.check("transaction trie", h.TxHash, p.storeTrie(tx_trie.MultiCodecType, txRLPs))
+
+	rctRLPs := make([][]byte, len(receipts))
+	for i, receipt := range receipts {
+		if rctRLPs[i], err = receipt.MarshalBinary(); err != nil {
+			return nil, err
+		}
+		p.store(rct.MultiCodecType, rctRLPs[i])
+		logRLPs := make([][]byte, len(receipt.Logs))
+		for j, l := range receipt.Logs {
+			if logRLPs[j], err = rlp.EncodeToBytes(l); err != nil {
+				return nil, err
+			}
+			p.store(log.MultiCodecType, logRLPs[j])
+		}
+		p.storeTrie(log_trie.MultiCodecType, logRLPs)
+	}
+	p.check("receipt trie", h.ReceiptHash, p.storeTrie(rct_trie.MultiCodecType, rctRLPs))
+
+	if withdrawals := block.Withdrawals(); withdrawals != nil {
+		wdRLPs := make([][]byte, len(withdrawals))
+		for i, wd := range withdrawals {
+			if wdRLPs[i], err = rlp.EncodeToBytes(wd); err != nil {
+				return nil, err
+			}
+			p.store(withdrawal.MultiCodecType, wdRLPs[i])
+		}
+		root := p.storeTrie(withdrawal_trie.MultiCodecType, wdRLPs)
+		if h.WithdrawalsHash == nil {
+			return nil, fmt.Errorf("block has withdrawals but its header has no withdrawals root")
+		}
+		p.check("withdrawal trie", *h.WithdrawalsHash, root)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	c, err := shared.Keccak256ToCid(header.MultiCodecType, headerHash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return cidlink.Link{Cid: c}, nil
+}
+
+// packer stores raw IPLD blocks through a LinkSystem, keeping the first error it runs into
+type packer struct {
+	lsys ipld.LinkSystem
+	err  error
+}
+
+// store writes the encoded block under the CID derived from its keccak256 hash, and returns that hash
+func (p *packer) store(codec uint64, enc []byte) common.Hash {
+	hash := crypto.Keccak256Hash(enc)
+	if p.err != nil {
+		return hash
+	}
+	if p.lsys.StorageWriteOpener == nil {
+		p.err = fmt.Errorf("no storage configured for writing")
+		return hash
+	}
+	w, commit, err := p.lsys.StorageWriteOpener(ipld.LinkContext{})
+	if err != nil {
+		p.err = err
+		return hash
+	}
+	if _, err := w.Write(enc); err != nil {
+		p.err = err
+		return hash
+	}
+	c, err := shared.Keccak256ToCid(codec, hash.Bytes())
+	if err != nil {
+		p.err = err
+		return hash
+	}
+	p.err = commit(cidlink.Link{Cid: c})
+	return hash
+}
+
+// storeTrie builds the trie keyed by the RLP encoded index of each value, stores its nodes, and returns its root hash
+func (p *packer) storeTrie(codec uint64, values [][]byte) common.Hash {
+	if len(values) == 0 {
+		return types.EmptyRootHash
+	}
+	// the trie builder needs its keys in order, which for RLP encoded indexes puts 0 after 1 through 127
+	keys := make([][]byte, len(values))
+	for i := range values {
+		keys[i], _ = rlp.EncodeToBytes(uint64(i))
+	}
+	order := make([]int, 0, len(values))
+	for i := 1; i < len(values) && i <= 0x7f; i++ {
+		order = append(order, i)
+	}
+	order = append(order, 0)
+	for i := 0x80; i < len(values); i++ {
+		order = append(order, i)
+	}
+	b := trie.NewBuilder(p.lsys, codec)
+	for _, i := range order {
+		if err := b.Update(keys[i], values[i]); err != nil {
+			p.fail(err)
+			return common.Hash{}
+		}
+	}
+	root, err := b.Commit()
+	if err != nil {
+		p.fail(err)
+		return common.Hash{}
+	}
+	return common.BytesToHash(root.(cidlink.Link).Hash()[2:])
+}
+
+// fail records the error, unless an earlier one has been recorded
+func (p *packer) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// check records an error if the root built from the block contents does not match the root committed to in the header
+func (p *packer) check(name string, expected, actual common.Hash) {
+	if p.err == nil && !bytes.Equal(expected.Bytes(), actual.Bytes()) {
+		p.err = fmt.Errorf("%s root %s does not match header root %s", name, actual.Hex(), expected.Hex())
+	}
+}
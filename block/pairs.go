@@ -0,0 +1,112 @@
+package block
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipld/go-ipld-prime"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/adl"
+	"github.com/vulcanize/go-codec-dageth/util"
+)
+
+// TxReceiptIterator iterates the transactions of a block paired with their receipts, in transaction index order
+type TxReceiptIterator struct {
+	txs      ipld.ListIterator
+	receipts ipld.ListIterator
+}
+
+// TxReceiptIteratorOptions can be used to customize the receipts a TxReceiptIterator yields.
+// The zero value yields the receipts as they are decoded.
+type TxReceiptIteratorOptions struct {
+	// LogIndexes attaches the derived BlockLogIndex and TxLogIndex entries to the logs of the receipts, see
+	// adl.ReceiptListOptions
+	LogIndexes bool
+}
+
+// NewTxReceiptIterator loads the header referenced by headerLink through the LinkSystem and returns an iterator
+// walking its transaction and receipt tries side by side, so each Transaction node comes with its Receipt node.
+// It returns an error if the tries do not hold the same number of entries.
+func NewTxReceiptIterator(lsys ipld.LinkSystem, headerLink ipld.Link) (*TxReceiptIterator, error) {
+	return TxReceiptIteratorOptions{}.NewTxReceiptIterator(lsys, headerLink)
+}
+
+// NewTxReceiptIterator is like the package level NewTxReceiptIterator, but uses the provided options
+func (opts TxReceiptIteratorOptions) NewTxReceiptIterator(lsys ipld.LinkSystem, headerLink ipld.Link) (*TxReceiptIterator, error) {
+	headerNode, err := lsys.Load(ipld.LinkContext{}, headerLink, dageth.Type.Header)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load header: %v", err)
+	}
+	txs, err := trieListOf(headerNode, "TxRootCID", func(root ipld.Link) ipld.Node {
+		return adl.NewTransactionList(lsys, root)
+	})
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := trieListOf(headerNode, "RctRootCID", func(root ipld.Link) ipld.Node {
+		return adl.ReceiptListOptions{LogIndexes: opts.LogIndexes}.NewReceiptList(lsys, root)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if txs.Length() < 0 || receipts.Length() < 0 {
+		// the walk failed, look up the first entries to surface why
+		if _, err := txs.LookupByIndex(0); err != nil {
+			return nil, fmt.Errorf("unable to load transactions: %v", err)
+		}
+		_, err := receipts.LookupByIndex(0)
+		return nil, fmt.Errorf("unable to load receipts: %v", err)
+	}
+	if txs.Length() != receipts.Length() {
+		return nil, fmt.Errorf("block has %d transactions but %d receipts", txs.Length(), receipts.Length())
+	}
+	return &TxReceiptIterator{txs: txs.ListIterator(), receipts: receipts.ListIterator()}, nil
+}
+
+// Done returns whether every transaction has been iterated
+func (it *TxReceiptIterator) Done() bool {
+	return it.txs.Done()
+}
+
+// Next returns the index of the next transaction, its Transaction node and its Receipt node
+func (it *TxReceiptIterator) Next() (int64, ipld.Node, ipld.Node, error) {
+	if it.Done() {
+		return -1, nil, nil, ipld.ErrIteratorOverread{}
+	}
+	idx, tx, err := it.txs.Next()
+	if err != nil {
+		return idx, nil, nil, fmt.Errorf("unable to load transaction %d: %v", idx, err)
+	}
+	_, receipt, err := it.receipts.Next()
+	if err != nil {
+		return idx, nil, nil, fmt.Errorf("unable to load receipt %d: %v", idx, err)
+	}
+	return idx, tx, receipt, nil
+}
+
+// trieListOf returns the ADL newList builds over the trie the field of the header links to, or an empty list if the
+// trie is empty
+func trieListOf(headerNode ipld.Node, field string, newList func(root ipld.Link) ipld.Node) (ipld.Node, error) {
+	root, err := linkOf(headerNode, field)
+	if err != nil {
+		return nil, fmt.Errorf("header is missing a %s: %v", field, err)
+	}
+	hash, err := util.LinkToKeccak256(root)
+	if err != nil {
+		return nil, err
+	}
+	if hash == types.EmptyRootHash {
+		nb := basicnode.Prototype.List.NewBuilder()
+		la, err := nb.BeginList(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := la.Finish(); err != nil {
+			return nil, err
+		}
+		return nb.Build(), nil
+	}
+	return newList(root), nil
+}
@@ -0,0 +1,112 @@
+package block
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/request"
+	"github.com/vulcanize/go-codec-dageth/request_list"
+)
+
+// PackRequests stores the EIP-7685 requests of a block, and the request list linking them, through the LinkSystem,
+// and returns the link to the list, which is what the RequestsCID of the block's header links to.
+// Each request is its type byte followed by its data, as in the engine API; requests without data are not part of
+// the requests hash, so they are left out. If the header is given, the list is checked against its requests hash.
+func PackRequests(requests [][]byte, h *types.Header, lsys ipld.LinkSystem) (ipld.Link, error) {
+	var hashed [][]byte
+	lb := dageth.Type.Requests.NewBuilder()
+	la, err := lb.BeginList(int64(len(requests)))
+	if err != nil {
+		return nil, err
+	}
+	for i, req := range requests {
+		if len(req) <= 1 {
+			continue
+		}
+		hashed = append(hashed, req)
+		nb := dageth.Type.Request.NewBuilder()
+		if err := request.DecodeBytes(nb, req); err != nil {
+			return nil, fmt.Errorf("unable to decode request %d: %v", i, err)
+		}
+		lnk, err := lsys.Store(ipld.LinkContext{}, sha256LinkPrototype(request.MultiCodecType), nb.Build())
+		if err != nil {
+			return nil, err
+		}
+		if err := la.AssembleValue().AssignLink(lnk); err != nil {
+			return nil, err
+		}
+	}
+	if err := la.Finish(); err != nil {
+		return nil, err
+	}
+	lnk, err := lsys.Store(ipld.LinkContext{}, sha256LinkPrototype(request_list.MultiCodecType), lb.Build())
+	if err != nil {
+		return nil, err
+	}
+	if h != nil {
+		if h.RequestsHash == nil {
+			return nil, fmt.Errorf("header has no requests hash to check the requests against")
+		}
+		if expected := types.CalcRequestsHash(hashed); *h.RequestsHash != expected {
+			return nil, fmt.Errorf("requests hash %s does not match header requests hash %s", expected.Hex(), h.RequestsHash.Hex())
+		}
+	}
+	return lnk, nil
+}
+
+// UnpackRequests is the inverse of PackRequests: it loads the header referenced by headerLink through the
+// LinkSystem, and returns the requests its RequestsCID links to, or nil if the header predates Prague.
+// Every IPLD block is verified against the hash in its CID as it is loaded, even if the LinkSystem
+// is configured with TrustedStorage.
+func UnpackRequests(lsys ipld.LinkSystem, headerLink ipld.Link) ([][]byte, error) {
+	lsys.TrustedStorage = false
+	headerNode, err := lsys.Load(ipld.LinkContext{}, headerLink, dageth.Type.Header)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load header: %v", err)
+	}
+	requestsCID, err := headerNode.LookupByString("RequestsCID")
+	if err != nil {
+		return nil, err
+	}
+	if requestsCID.IsNull() {
+		return nil, nil
+	}
+	listNode, err := loadLinked(lsys, headerNode, "RequestsCID", dageth.Type.Requests)
+	if err != nil {
+		return nil, err
+	}
+	requests := make([][]byte, 0, listNode.Length())
+	if err := forEach(listNode, func(n ipld.Node) error {
+		lnk, err := n.AsLink()
+		if err != nil {
+			return err
+		}
+		requestNode, err := lsys.Load(ipld.LinkContext{}, lnk, dageth.Type.Request)
+		if err != nil {
+			return fmt.Errorf("unable to load request %s: %v", lnk.String(), err)
+		}
+		req, err := request.AppendEncode(nil, requestNode)
+		requests = append(requests, req)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("unable to unpack requests: %v", err)
+	}
+	return requests, nil
+}
+
+// sha256LinkPrototype returns the prototype of the links to the requests and request lists,
+// which are hashed with sha256 rather than keccak256
+func sha256LinkPrototype(codec uint64) cidlink.LinkPrototype {
+	return cidlink.LinkPrototype{Prefix: cid.Prefix{
+		Version:  1,
+		Codec:    codec,
+		MhType:   multihash.SHA2_256,
+		MhLength: -1,
+	}}
+}
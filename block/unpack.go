@@ -0,0 +1,176 @@
+package block
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/adl"
+	"github.com/vulcanize/go-codec-dageth/convert"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+)
+
+// UnpackBlock is the inverse of PackBlock: it loads the header referenced by headerLink through the LinkSystem,
+// walks its uncle, transaction, receipt and withdrawal links, and returns the go-ethereum block and its receipts.
+// Every IPLD block is verified against the keccak256 hash in its CID as it is loaded, even if the LinkSystem
+// is configured with TrustedStorage.
+// The receipts carry their consensus fields along with the block and transaction context of each receipt and log;
+// fields that depend on the chain config, such as the effective gas price and contract address, are not derived.
+func UnpackBlock(lsys ipld.LinkSystem, headerLink ipld.Link) (*types.Block, types.Receipts, error) {
+	lsys.TrustedStorage = false
+	headerNode, err := lsys.Load(ipld.LinkContext{}, headerLink, dageth.Type.Header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to load header: %v", err)
+	}
+	h, err := convert.ToHeader(headerNode)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := convert.HeaderCID(h)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !c.Equals(headerLink.(cidlink.Link).Cid) {
+		return nil, nil, fmt.Errorf("unpacked header CID %s does not match %s", c.String(), headerLink.String())
+	}
+
+	unclesNode, err := loadLinked(lsys, headerNode, "UnclesCID", dageth.Type.Uncles)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ommers []*types.Header
+	if err := uncles.EncodeUncles(&ommers, unclesNode); err != nil {
+		return nil, nil, err
+	}
+
+	var txs types.Transactions
+	if h.TxHash != types.EmptyRootHash {
+		txRoot, err := linkOf(headerNode, "TxRootCID")
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := forEach(adl.NewTransactionList(lsys, txRoot), func(n ipld.Node) error {
+			trx, err := convert.ToTransaction(n)
+			txs = append(txs, trx)
+			return err
+		}); err != nil {
+			return nil, nil, fmt.Errorf("unable to unpack transactions: %v", err)
+		}
+	}
+
+	var receipts types.Receipts
+	if h.ReceiptHash != types.EmptyRootHash {
+		rctRoot, err := linkOf(headerNode, "RctRootCID")
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := forEach(adl.NewReceiptList(lsys, rctRoot), func(n ipld.Node) error {
+			receipt, err := convert.ToReceipt(n)
+			receipts = append(receipts, receipt)
+			return err
+		}); err != nil {
+			return nil, nil, fmt.Errorf("unable to unpack receipts: %v", err)
+		}
+	}
+	if len(receipts) != len(txs) {
+		return nil, nil, fmt.Errorf("block has %d transactions but %d receipts", len(txs), len(receipts))
+	}
+
+	var withdrawals []*types.Withdrawal
+	if h.WithdrawalsHash != nil {
+		withdrawals = []*types.Withdrawal{}
+		if *h.WithdrawalsHash != types.EmptyRootHash {
+			wdRoot, err := linkOf(headerNode, "WithdrawalsRootCID")
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := forEach(adl.NewWithdrawalList(lsys, wdRoot), func(n ipld.Node) error {
+				wd := new(types.Withdrawal)
+				err := withdrawal.EncodeWithdrawal(wd, n)
+				withdrawals = append(withdrawals, wd)
+				return err
+			}); err != nil {
+				return nil, nil, fmt.Errorf("unable to unpack withdrawals: %v", err)
+			}
+		}
+	}
+
+	blk := types.NewBlockWithHeader(h).WithBody(types.Body{Transactions: txs, Uncles: ommers, Withdrawals: withdrawals})
+	// the trie nodes are verified as they are loaded, but the values read out of them are re-encoded by
+	// go-ethereum, so the roots are rebuilt to make sure nothing was lost in the conversion
+	if root := types.DeriveSha(txs, gethtrie.NewStackTrie(nil)); root != h.TxHash {
+		return nil, nil, fmt.Errorf("unpacked transactions have root %s, header has %s", root.Hex(), h.TxHash.Hex())
+	}
+	if root := types.DeriveSha(receipts, gethtrie.NewStackTrie(nil)); root != h.ReceiptHash {
+		return nil, nil, fmt.Errorf("unpacked receipts have root %s, header has %s", root.Hex(), h.ReceiptHash.Hex())
+	}
+	setReceiptContext(blk, receipts)
+	return blk, receipts, nil
+}
+
+// setReceiptContext sets the fields of the receipts and their logs that locate them in the block
+func setReceiptContext(blk *types.Block, receipts types.Receipts) {
+	var logIndex uint
+	txs := blk.Transactions()
+	for i, receipt := range receipts {
+		receipt.TxHash = txs[i].Hash()
+		receipt.BlockHash = blk.Hash()
+		receipt.BlockNumber = blk.Number()
+		receipt.TransactionIndex = uint(i)
+		receipt.GasUsed = receipt.CumulativeGasUsed
+		if i > 0 {
+			receipt.GasUsed -= receipts[i-1].CumulativeGasUsed
+		}
+		for _, l := range receipt.Logs {
+			l.BlockNumber = blk.NumberU64()
+			l.BlockHash = blk.Hash()
+			l.TxHash = receipt.TxHash
+			l.TxIndex = uint(i)
+			l.Index = logIndex
+			logIndex++
+		}
+	}
+}
+
+func forEach(list ipld.Node, f func(ipld.Node) error) error {
+	if list.Length() < 0 {
+		// the walk failed, look up the first entry to surface why
+		_, err := list.LookupByIndex(0)
+		return err
+	}
+	for it := list.ListIterator(); !it.Done(); {
+		_, n, err := it.Next()
+		if err != nil {
+			return err
+		}
+		if err := f(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func linkOf(node ipld.Node, field string) (ipld.Link, error) {
+	linkNode, err := node.LookupByString(field)
+	if err != nil {
+		return nil, err
+	}
+	return linkNode.AsLink()
+}
+
+func loadLinked(lsys ipld.LinkSystem, node ipld.Node, field string, np ipld.NodePrototype) (ipld.Node, error) {
+	lnk, err := linkOf(node, field)
+	if err != nil {
+		return nil, err
+	}
+	n, err := lsys.Load(ipld.LinkContext{}, lnk, np)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load %s: %v", field, err)
+	}
+	return n, nil
+}
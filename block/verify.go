@@ -0,0 +1,95 @@
+package block
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/adl"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/util"
+)
+
+// VerifyBlockBody walks the transaction and receipt tries the Header node links to, loading every trie node through
+// the LinkSystem, re-encodes the transactions and receipts they hold in index order, and rebuilds the roots of the
+// tries from them, returning an error if either root does not match the TxRootCID or RctRootCID of the header.
+// This catches tries that hash correctly node by node but whose values do not form the block body the header
+// commits to, e.g. a trie missing entries or holding values that do not re-encode canonically.
+func VerifyBlockBody(headerNode ipld.Node, lsys ipld.LinkSystem) error {
+	lsys.TrustedStorage = false
+	txs, err := trieListOf(headerNode, "TxRootCID", func(root ipld.Link) ipld.Node {
+		return adl.NewTransactionList(lsys, root)
+	})
+	if err != nil {
+		return err
+	}
+	receipts, err := trieListOf(headerNode, "RctRootCID", func(root ipld.Link) ipld.Node {
+		return adl.NewReceiptList(lsys, root)
+	})
+	if err != nil {
+		return err
+	}
+	return verifyBody(headerNode, txs, receipts)
+}
+
+// VerifyBlockBodyLists is like VerifyBlockBody, but rebuilds the roots from the transaction and receipt lists the
+// links reference, e.g. those of the Transactions and Receipts of a Block, rather than from the tries of the header
+func VerifyBlockBodyLists(headerNode ipld.Node, lsys ipld.LinkSystem, txList, rctList ipld.Link) error {
+	lsys.TrustedStorage = false
+	txs, err := lsys.Load(ipld.LinkContext{}, txList, dageth.Type.Transactions)
+	if err != nil {
+		return fmt.Errorf("unable to load transaction list: %v", err)
+	}
+	receipts, err := lsys.Load(ipld.LinkContext{}, rctList, dageth.Type.Receipts)
+	if err != nil {
+		return fmt.Errorf("unable to load receipt list: %v", err)
+	}
+	return verifyBody(headerNode, txs, receipts)
+}
+
+func verifyBody(headerNode ipld.Node, txs, receipts ipld.Node) error {
+	if err := verifyRoot(headerNode, "TxRootCID", "transactions", txs, tx.Encode); err != nil {
+		return err
+	}
+	return verifyRoot(headerNode, "RctRootCID", "receipts", receipts, rct.Encode)
+}
+
+// verifyRoot rebuilds the root of the trie of the encodings of the list entries, and compares it with the root the
+// field of the header links to
+func verifyRoot(headerNode ipld.Node, field, name string, list ipld.Node, encode ipld.Encoder) error {
+	rootLink, err := linkOf(headerNode, field)
+	if err != nil {
+		return fmt.Errorf("header is missing a %s: %v", field, err)
+	}
+	expected, err := util.LinkToKeccak256(rootLink)
+	if err != nil {
+		return err
+	}
+	var values encodedList
+	if err := forEach(list, func(n ipld.Node) error {
+		buf := new(bytes.Buffer)
+		if err := encode(n, buf); err != nil {
+			return fmt.Errorf("unable to encode entry %d of the %s: %v", len(values), name, err)
+		}
+		values = append(values, buf.Bytes())
+		return nil
+	}); err != nil {
+		return fmt.Errorf("unable to load %s: %v", name, err)
+	}
+	if root := types.DeriveSha(values, gethtrie.NewStackTrie(nil)); root != expected {
+		return fmt.Errorf("%d %s have root %s, header has %s", len(values), name, root.Hex(), expected.Hex())
+	}
+	return nil
+}
+
+// encodedList adapts the encodings of the entries of a list to the interface types.DeriveSha takes
+type encodedList [][]byte
+
+func (l encodedList) Len() int { return len(l) }
+
+func (l encodedList) EncodeIndex(i int, w *bytes.Buffer) { w.Write(l[i]) }
@@ -0,0 +1,159 @@
+/*
+Package bloom builds and tests the 2048-bit logs blooms of receipts and headers from DAG-ETH nodes, and verifies the
+LogsBloom of a header against the logs of the receipts its receipt trie holds, so logs can be filtered over an IPLD
+store without go-ethereum types.
+*/
+package bloom
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/adl"
+	"github.com/vulcanize/go-codec-dageth/util"
+)
+
+// FromLogs returns the bloom of the Log nodes, holding the address and topics of every log
+func FromLogs(logs ...ipld.Node) (types.Bloom, error) {
+	var b types.Bloom
+	for _, log := range logs {
+		if err := Add(&b, log); err != nil {
+			return types.Bloom{}, err
+		}
+	}
+	return b, nil
+}
+
+// FromReceipt returns the bloom of the logs of the Receipt node, which matches its Bloom if the receipt is valid
+func FromReceipt(receipt ipld.Node) (types.Bloom, error) {
+	var b types.Bloom
+	if err := addReceipt(&b, receipt); err != nil {
+		return types.Bloom{}, err
+	}
+	return b, nil
+}
+
+// Add adds the address and topics of the Log node to the bloom
+func Add(b *types.Bloom, log ipld.Node) error {
+	addrNode, err := log.LookupByString("Address")
+	if err != nil {
+		return fmt.Errorf("log is missing an Address node: %v", err)
+	}
+	addr, err := addrNode.AsBytes()
+	if err != nil {
+		return err
+	}
+	b.Add(addr)
+	topicsNode, err := log.LookupByString("Topics")
+	if err != nil {
+		return fmt.Errorf("log is missing a Topics node: %v", err)
+	}
+	it := topicsNode.ListIterator()
+	for !it.Done() {
+		_, topicNode, err := it.Next()
+		if err != nil {
+			return err
+		}
+		topic, err := topicNode.AsBytes()
+		if err != nil {
+			return err
+		}
+		b.Add(topic)
+	}
+	return nil
+}
+
+// Test returns whether the bloom may hold the value, an address or a topic; false positives are possible
+func Test(b types.Bloom, value []byte) bool {
+	return types.BloomLookup(b, bytesBacked(value))
+}
+
+// TestAddress returns whether the bloom may hold the address of a log
+func TestAddress(b types.Bloom, address common.Address) bool {
+	return Test(b, address.Bytes())
+}
+
+// TestTopic returns whether the bloom may hold a topic of a log
+func TestTopic(b types.Bloom, topic common.Hash) bool {
+	return Test(b, topic.Bytes())
+}
+
+// Of returns the Bloom of a Header or Receipt node
+func Of(node ipld.Node) (types.Bloom, error) {
+	bloomNode, err := node.LookupByString("Bloom")
+	if err != nil {
+		return types.Bloom{}, fmt.Errorf("node is missing a Bloom: %v", err)
+	}
+	bloomBytes, err := bloomNode.AsBytes()
+	if err != nil {
+		return types.Bloom{}, err
+	}
+	if len(bloomBytes) != types.BloomByteLength {
+		return types.Bloom{}, fmt.Errorf("bloom of %d bytes, expected %d", len(bloomBytes), types.BloomByteLength)
+	}
+	return types.BytesToBloom(bloomBytes), nil
+}
+
+// VerifyHeader rebuilds the logs bloom of the Header node from the logs of the receipts in its receipt trie, loaded
+// through the LinkSystem, and returns an error if it does not match the Bloom of the header
+func VerifyHeader(lsys ipld.LinkSystem, header ipld.Node) error {
+	expected, err := Of(header)
+	if err != nil {
+		return err
+	}
+	rctRootNode, err := header.LookupByString("RctRootCID")
+	if err != nil {
+		return fmt.Errorf("header is missing a RctRootCID: %v", err)
+	}
+	rctRoot, err := rctRootNode.AsLink()
+	if err != nil {
+		return err
+	}
+	rctRootHash, err := util.LinkToKeccak256(rctRoot)
+	if err != nil {
+		return err
+	}
+	var b types.Bloom
+	if rctRootHash != types.EmptyReceiptsHash {
+		it := adl.NewReceiptList(lsys, rctRoot).ListIterator()
+		for !it.Done() {
+			idx, receipt, err := it.Next()
+			if err != nil {
+				return fmt.Errorf("unable to load receipt %d: %v", idx, err)
+			}
+			if err := addReceipt(&b, receipt); err != nil {
+				return fmt.Errorf("invalid receipt %d: %v", idx, err)
+			}
+		}
+	}
+	if b != expected {
+		return fmt.Errorf("header Bloom (%x) does not match the bloom of its receipt logs (%x)", expected.Bytes(), b.Bytes())
+	}
+	return nil
+}
+
+func addReceipt(b *types.Bloom, receipt ipld.Node) error {
+	logsNode, err := receipt.LookupByString("Logs")
+	if err != nil {
+		return fmt.Errorf("receipt is missing a Logs node: %v", err)
+	}
+	it := logsNode.ListIterator()
+	for !it.Done() {
+		_, log, err := it.Next()
+		if err != nil {
+			return err
+		}
+		if err := Add(b, log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bytesBacked adapts a byte slice to the interface types.BloomLookup takes
+type bytesBacked []byte
+
+func (b bytesBacked) Bytes() []byte { return b }
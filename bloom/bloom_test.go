@@ -0,0 +1,92 @@
+package bloom_test
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/bloom"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+func TestBloom(t *testing.T) {
+	g := testutil.NewGenerator(1)
+	receipt := g.Receipt(types.DynamicFeeTxType)
+	for len(receipt.Logs) == 0 {
+		receipt = g.Receipt(types.DynamicFeeTxType)
+	}
+	enc, err := receipt.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb := dageth.Type.Receipt.NewBuilder()
+	if err := rct.DecodeBytes(nb, enc); err != nil {
+		t.Fatalf("unable to decode receipt: %v", err)
+	}
+	receiptNode := nb.Build()
+	b, err := bloom.FromReceipt(receiptNode)
+	if err != nil {
+		t.Fatalf("unable to build receipt bloom: %v", err)
+	}
+	if b != receipt.Bloom {
+		t.Errorf("receipt bloom %x does not match the go-ethereum bloom %x", b.Bytes(), receipt.Bloom.Bytes())
+	}
+	if stored, err := bloom.Of(receiptNode); err != nil || stored != b {
+		t.Errorf("expected the receipt Bloom to match the bloom of its logs (%v)", err)
+	}
+	for _, log := range receipt.Logs {
+		if !bloom.TestAddress(b, log.Address) {
+			t.Errorf("expected the bloom to hold log address %x", log.Address)
+		}
+		for _, topic := range log.Topics {
+			if !bloom.TestTopic(b, topic) {
+				t.Errorf("expected the bloom to hold log topic %x", topic)
+			}
+		}
+	}
+	if bloom.TestAddress(types.Bloom{}, receipt.Logs[0].Address) {
+		t.Error("expected the empty bloom to hold nothing")
+	}
+}
+
+func TestVerifyHeader(t *testing.T) {
+	g := testutil.NewGenerator(2)
+	for _, n := range []int{0, 24} {
+		blk, receipts := g.Block(n)
+		store := &storage.Memory{}
+		lsys := codecs.NewLinkSystem(store)
+		headerLink, err := block.PackBlock(blk, receipts, lsys)
+		if err != nil {
+			t.Fatalf("unable to pack block: %v", err)
+		}
+		headerNode, err := lsys.Load(ipld.LinkContext{}, headerLink, dageth.Type.Header)
+		if err != nil {
+			t.Fatalf("unable to load header: %v", err)
+		}
+		if err := bloom.VerifyHeader(lsys, headerNode); err != nil {
+			t.Errorf("unable to verify the bloom of a header of %d transactions: %v", n, err)
+		}
+
+		h := blk.Header()
+		h.Bloom[0] ^= 0x80
+		enc, err := rlp.EncodeToBytes(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nb := dageth.Type.Header.NewBuilder()
+		if err := header.DecodeBytes(nb, enc); err != nil {
+			t.Fatal(err)
+		}
+		if err := bloom.VerifyHeader(lsys, nb.Build()); err == nil {
+			t.Errorf("expected verifying a changed bloom of a header of %d transactions to fail", n)
+		}
+	}
+}
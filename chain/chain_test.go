@@ -0,0 +1,180 @@
+package chain_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/chain"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/convert"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+// storeChain stores a chain of n headers from genesis, and returns their links from genesis to the tip
+func storeChain(tb testing.TB, store *storage.Memory, n int) ([]ipld.Link, []*types.Header) {
+	g := testutil.NewGenerator(1)
+	links := make([]ipld.Link, n)
+	headers := make([]*types.Header, n)
+	parent := common.Hash{}
+	for i := range headers {
+		h := g.Header()
+		h.Number = big.NewInt(int64(i))
+		h.ParentHash = parent
+		enc, err := rlp.EncodeToBytes(h)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		parent = h.Hash()
+		links[i] = testutil.KeccakLink(tb, header.MultiCodecType, parent.Bytes())
+		headers[i] = h
+		store.Bag[links[i]] = enc
+	}
+	return links, headers
+}
+
+func TestWalkHeaders(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	links, headers := storeChain(t, store, 100)
+	lsys := codecs.NewLinkSystem(store)
+
+	for _, test := range []struct {
+		name     string
+		opts     chain.WalkOptions
+		n        int
+		expected int
+	}{
+		{"back to genesis", chain.WalkOptions{}, 0, 100},
+		{"10 headers", chain.WalkOptions{}, 10, 10},
+		{"past genesis", chain.WalkOptions{Prefetch: 3}, 200, 100},
+		{"without prefetching", chain.WalkOptions{Prefetch: -1}, 20, 20},
+	} {
+		walked := 0
+		err := test.opts.WalkHeaders(lsys, links[len(links)-1], test.n, func(lnk ipld.Link, node ipld.Node) error {
+			i := len(links) - 1 - walked
+			if lnk != links[i] {
+				t.Errorf("%s: expected header %d to be %s, got %s", test.name, walked, links[i].String(), lnk.String())
+			}
+			numberNode, err := node.LookupByString("Number")
+			if err != nil {
+				return err
+			}
+			number, _ := numberNode.AsBytes()
+			if new(big.Int).SetBytes(number).Cmp(headers[i].Number) != 0 {
+				t.Errorf("%s: expected header %d to be number %d, got %x", test.name, walked, headers[i].Number, number)
+			}
+			walked++
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: unable to walk headers: %v", test.name, err)
+		}
+		if walked != test.expected {
+			t.Errorf("%s: expected to walk %d headers, walked %d", test.name, test.expected, walked)
+		}
+	}
+}
+
+func TestWalkHeadersErrors(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	links, _ := storeChain(t, store, 50)
+	lsys := codecs.NewLinkSystem(store)
+
+	// the walk stops at the first error of the callback, even with headers prefetched
+	errStop := errors.New("stop")
+	walked := 0
+	err := chain.WalkHeaders(lsys, links[len(links)-1], 0, func(ipld.Link, ipld.Node) error {
+		walked++
+		if walked == 5 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || walked != 5 {
+		t.Errorf("expected the walk to stop after 5 headers with the callback error, walked %d and got %v", walked, err)
+	}
+
+	// a missing header ends the walk with an error
+	delete(store.Bag, links[40])
+	walked = 0
+	err = chain.WalkHeaders(lsys, links[len(links)-1], 0, func(ipld.Link, ipld.Node) error {
+		walked++
+		return nil
+	})
+	if err == nil || walked != 9 {
+		t.Errorf("expected the walk to fail after 9 headers, walked %d and got %v", walked, err)
+	}
+}
+
+func TestValidateHeader(t *testing.T) {
+	g := testutil.NewGenerator(2)
+	mockHeader := func(parent *types.Header, london bool) *types.Header {
+		h := g.Header()
+		h.Number = big.NewInt(1)
+		h.Time = 1000
+		h.GasLimit = 30_000_000
+		h.GasUsed = 15_000_000
+		h.Extra = []byte("extra")
+		h.BaseFee, h.WithdrawalsHash, h.BlobGasUsed, h.ExcessBlobGas, h.ParentBeaconRoot, h.RequestsHash = nil, nil, nil, nil, nil, nil
+		if parent != nil {
+			h.ParentHash = parent.Hash()
+			h.Number = new(big.Int).Add(parent.Number, big.NewInt(1))
+			h.Time = parent.Time + 12
+			h.GasLimit = parent.GasLimit
+		}
+		if london {
+			h.BaseFee = big.NewInt(params.InitialBaseFee)
+			if parent != nil && parent.BaseFee != nil {
+				h.BaseFee = eip1559.CalcBaseFee(&params.ChainConfig{LondonBlock: new(big.Int)}, parent)
+			}
+		}
+		return h
+	}
+	node := func(h *types.Header) ipld.Node {
+		n, err := convert.FromHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	frontier := mockHeader(nil, false)
+	transition := mockHeader(frontier, true)
+	transition.GasLimit = frontier.GasLimit * params.DefaultElasticityMultiplier
+	london := mockHeader(transition, true)
+	for name, pair := range map[string][2]*types.Header{
+		"frontier":          {frontier, mockHeader(frontier, false)},
+		"london transition": {frontier, transition},
+		"london":            {transition, london},
+	} {
+		if err := chain.ValidateHeader(node(pair[0]), node(pair[1])); err != nil {
+			t.Errorf("expected the %s child header to be valid: %v", name, err)
+		}
+	}
+
+	for name, change := range map[string]func(h *types.Header){
+		"wrong parent":        func(h *types.Header) { h.ParentHash = common.Hash{1} },
+		"skipped number":      func(h *types.Header) { h.Number.Add(h.Number, big.NewInt(1)) },
+		"same timestamp":      func(h *types.Header) { h.Time = transition.Time },
+		"long extra data":     func(h *types.Header) { h.Extra = make([]byte, 33) },
+		"gas used over limit": func(h *types.Header) { h.GasUsed = h.GasLimit + 1 },
+		"gas limit jump":      func(h *types.Header) { h.GasLimit = h.GasLimit * 2 },
+		"wrong base fee":      func(h *types.Header) { h.BaseFee = new(big.Int).Add(h.BaseFee, big.NewInt(1)) },
+		"missing base fee":    func(h *types.Header) { h.BaseFee = nil },
+	} {
+		child := types.CopyHeader(london)
+		change(child)
+		if err := chain.ValidateHeader(node(transition), node(child)); err == nil {
+			t.Errorf("expected a child header with a %s to be invalid", name)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package chain
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/convert"
+)
+
+// ValidateHeader runs the intrinsic consistency checks of a child header against its parent, both Header nodes, so
+// headers sourced from IPFS can be sanity-checked without a full client: that the child links to the parent and
+// increments its number, that its timestamp is later, that its extra data fits the 32 byte limit, that its gas used
+// fits its gas limit and that its gas limit stays within the bounds of the parent's, and that its base fee is the one
+// EIP-1559 derives from the parent. London is taken to activate at the first header carrying a base fee.
+// Seals and fork specific fields are not checked.
+func ValidateHeader(parent, child ipld.Node) error {
+	p, err := convert.ToHeader(parent)
+	if err != nil {
+		return fmt.Errorf("invalid parent header: %v", err)
+	}
+	c, err := convert.ToHeader(child)
+	if err != nil {
+		return fmt.Errorf("invalid child header: %v", err)
+	}
+	return validateHeader(p, c)
+}
+
+func validateHeader(parent, child *types.Header) error {
+	if child.ParentHash != parent.Hash() {
+		return fmt.Errorf("header %d links to parent %x, not %x", child.Number, child.ParentHash, parent.Hash())
+	}
+	if expected := new(big.Int).Add(parent.Number, big.NewInt(1)); child.Number.Cmp(expected) != 0 {
+		return fmt.Errorf("header number %d does not follow its parent number %d", child.Number, parent.Number)
+	}
+	if child.Time <= parent.Time {
+		return fmt.Errorf("header %d timestamp %d is not later than its parent timestamp %d", child.Number, child.Time, parent.Time)
+	}
+	if uint64(len(child.Extra)) > params.MaximumExtraDataSize {
+		return fmt.Errorf("header %d extra data of %d bytes exceeds the limit of %d bytes", child.Number, len(child.Extra), params.MaximumExtraDataSize)
+	}
+	if child.GasLimit > params.MaxGasLimit {
+		return fmt.Errorf("header %d gas limit %d exceeds the maximum of %d", child.Number, child.GasLimit, params.MaxGasLimit)
+	}
+	if child.GasUsed > child.GasLimit {
+		return fmt.Errorf("header %d gas used %d exceeds its gas limit %d", child.Number, child.GasUsed, child.GasLimit)
+	}
+	if child.BaseFee == nil {
+		if parent.BaseFee != nil {
+			return fmt.Errorf("header %d is missing the base fee of its London parent", child.Number)
+		}
+		if err := misc.VerifyGaslimit(parent.GasLimit, child.GasLimit); err != nil {
+			return fmt.Errorf("header %d: %v", child.Number, err)
+		}
+		return nil
+	}
+	// the config activates London either with the child, the first header carrying a base fee, or before the parent
+	config := &params.ChainConfig{LondonBlock: new(big.Int)}
+	if parent.BaseFee == nil {
+		config.LondonBlock = child.Number
+	}
+	if err := eip1559.VerifyEIP1559Header(config, parent, child); err != nil {
+		return fmt.Errorf("header %d: %v", child.Number, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Package chain follows the header chain of the DAG, the ParentCID links from a header back towards genesis, as the
+building block of IPLD-native chain indexers, and checks that each header is consistent with its parent.
+*/
+package chain
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/metrics"
+)
+
+// defaultPrefetch is the number of headers loaded ahead of the callback when WalkOptions.Prefetch is zero
+const defaultPrefetch = 64
+
+// WalkOptions can be used to customize header walks.
+// The zero value is the default behavior used by WalkHeaders.
+type WalkOptions struct {
+	// Prefetch is the number of headers loaded ahead of the callback while it runs, which hides the latency of
+	// network backed LinkSystems; it defaults to 64, and a negative value loads each header only once the callback
+	// has returned for its child
+	Prefetch int
+	// Metrics, if set, receives the headers loaded by the walk, and the number of headers walked back from the tip
+	// as its depth
+	Metrics metrics.Recorder
+}
+
+// WalkHeaders loads the header the tip link references through the LinkSystem, and calls fn with its link and
+// Header node, then does the same for its parent, following ParentCID links backwards until n headers have been
+// walked or the genesis header has been. A non-positive n walks all the way back to genesis.
+// The headers of the next links are loaded in the background while fn runs, see WalkOptions.Prefetch.
+// WalkHeaders stops at the first error returned by fn or by loading a header, and returns it.
+func WalkHeaders(lsys ipld.LinkSystem, tip ipld.Link, n int, fn func(ipld.Link, ipld.Node) error) error {
+	return WalkOptions{}.WalkHeaders(lsys, tip, n, fn)
+}
+
+// WalkHeaders is like the package level WalkHeaders, but uses the provided options
+func (opts WalkOptions) WalkHeaders(lsys ipld.LinkSystem, tip ipld.Link, n int, fn func(ipld.Link, ipld.Node) error) error {
+	prefetch := opts.Prefetch
+	if prefetch == 0 {
+		prefetch = defaultPrefetch
+	}
+	if prefetch < 0 {
+		prefetch = 0
+	}
+	headers := make(chan loadedHeader, prefetch)
+	done := make(chan struct{})
+	// the loader is done with the LinkSystem by the time the walk returns
+	defer func() {
+		close(done)
+		for range headers {
+		}
+	}()
+	go loadHeaders(metrics.Instrument(lsys, opts.Metrics), tip, n, headers, done)
+	rec := metrics.OrNop(opts.Metrics)
+	depth := 0
+	for h := range headers {
+		if h.err != nil {
+			return h.err
+		}
+		rec.DepthReached(depth)
+		depth++
+		if err := fn(h.link, h.node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadedHeader is a header loaded by loadHeaders, or the error that ended the walk
+type loadedHeader struct {
+	link ipld.Link
+	node ipld.Node
+	err  error
+}
+
+// loadHeaders sends the headers of the walk down the channel, and closes it once the walk is complete or has failed,
+// or once done has been closed
+func loadHeaders(lsys ipld.LinkSystem, lnk ipld.Link, n int, headers chan<- loadedHeader, done <-chan struct{}) {
+	defer close(headers)
+	for i := 0; n <= 0 || i < n; i++ {
+		node, err := lsys.Load(ipld.LinkContext{}, lnk, dageth.Type.Header)
+		if err != nil {
+			err = fmt.Errorf("unable to load header %s: %v", lnk.String(), err)
+		}
+		var parent ipld.Link
+		genesis := false
+		if err == nil {
+			parent, genesis, err = parentOf(node)
+		}
+		select {
+		case headers <- loadedHeader{link: lnk, node: node, err: err}:
+		case <-done:
+			return
+		}
+		if err != nil || genesis {
+			return
+		}
+		lnk = parent
+	}
+}
+
+// parentOf returns the ParentCID of the header, and whether it is the genesis header, which has no parent
+func parentOf(header ipld.Node) (ipld.Link, bool, error) {
+	numberNode, err := header.LookupByString("Number")
+	if err != nil {
+		return nil, false, err
+	}
+	number, err := numberNode.AsBytes()
+	if err != nil {
+		return nil, false, err
+	}
+	if new(big.Int).SetBytes(number).Sign() == 0 {
+		return nil, true, nil
+	}
+	parentNode, err := header.LookupByString("ParentCID")
+	if err != nil {
+		return nil, false, err
+	}
+	parent, err := parentNode.AsLink()
+	return parent, false, err
+}
@@ -0,0 +1,271 @@
+package chaindata_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/chaindata"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/export"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/tx"
+)
+
+// mockChaindata writes n blocks and their receipts and transaction lookup entries into a memory database,
+// along with the nodes of a state trie and some contract code
+func mockChaindata(n int) (ethdb.Database, []*types.Block, [][]byte, []byte) {
+	g := testutil.NewGenerator(1)
+	db := rawdb.NewMemoryDatabase()
+	blocks := make([]*types.Block, n)
+	for i := range blocks {
+		h := g.Header()
+		h.Number.SetUint64(uint64(i))
+		txs := g.Transactions(4)
+		body := &types.Body{Transactions: txs, Uncles: g.Headers(1)}
+		if h.WithdrawalsHash != nil {
+			body.Withdrawals = g.Withdrawals(2)
+		}
+		receipts := g.Receipts(txs)
+		blocks[i] = types.NewBlock(h, body, receipts, gethtrie.NewStackTrie(nil))
+		rawdb.WriteBlock(db, blocks[i])
+		rawdb.WriteReceipts(db, blocks[i].Hash(), blocks[i].NumberU64(), receipts)
+		rawdb.WriteCanonicalHash(db, blocks[i].Hash(), blocks[i].NumberU64())
+		rawdb.WriteTxLookupEntriesByBlock(db, blocks[i])
+	}
+	accounts := make([][]byte, 32)
+	for i := range accounts {
+		accounts[i], _ = rlp.EncodeToBytes(g.Account())
+	}
+	nodes := g.TrieNodes(accounts)
+	for _, node := range nodes {
+		rawdb.WriteLegacyTrieNode(db, crypto.Keccak256Hash(node), node)
+	}
+	code := g.Bytes(100)
+	rawdb.WriteCode(db, crypto.Keccak256Hash(code), code)
+	return db, blocks, nodes, code
+}
+
+func newLinkSystem(store *chaindata.ReadStore) ipld.LinkSystem {
+	lsys := cidlink.DefaultLinkSystem()
+	lsys.StorageReadOpener = store.OpenRead
+	return lsys
+}
+
+func TestReadStoreBlocks(t *testing.T) {
+	// more blocks than the store keeps packed, so that the first ones are evicted and packed again
+	db, blocks, _, _ := mockChaindata(20)
+	store := chaindata.NewReadStore(db)
+	lsys := newLinkSystem(store)
+	for round := 0; round < 2; round++ {
+		for _, blk := range blocks {
+			headerLink := testutil.KeccakLink(t, header.MultiCodecType, blk.Hash().Bytes())
+			unpacked, receipts, err := block.UnpackBlock(lsys, headerLink)
+			if err != nil {
+				t.Fatalf("unable to unpack block %d: %v", blk.NumberU64(), err)
+			}
+			if unpacked.Hash() != blk.Hash() {
+				t.Errorf("unpacked block %d hash (%s) does not match expected hash (%s)", blk.NumberU64(), unpacked.Hash().Hex(), blk.Hash().Hex())
+			}
+			if types.DeriveSha(receipts, gethtrie.NewStackTrie(nil)) != blk.ReceiptHash() {
+				t.Errorf("unpacked receipts of block %d do not match the receipt root", blk.NumberU64())
+			}
+		}
+	}
+}
+
+func TestReadStoreLookups(t *testing.T) {
+	db, blocks, nodes, code := mockChaindata(2)
+	store := chaindata.NewReadStore(db)
+
+	// transactions are found through the lookup index, without reading their header first
+	trx := blocks[1].Transactions()[2]
+	data, err := store.Get(testutil.KeccakLink(t, tx.MultiCodecType, trx.Hash().Bytes()))
+	if err != nil {
+		t.Fatalf("unable to read transaction: %v", err)
+	}
+	if enc, _ := trx.MarshalBinary(); !bytes.Equal(data, enc) {
+		t.Errorf("read transaction %x does not match expected %x", data, enc)
+	}
+
+	// trie roots are only found once the header committing to them has been read
+	rctRoot := testutil.KeccakLink(t, rct_trie.MultiCodecType, blocks[0].ReceiptHash().Bytes())
+	if _, err := store.Get(rctRoot); !errors.Is(err, chaindata.ErrNotFound) {
+		t.Errorf("expected the receipt root of an unread header not to be found, got %v", err)
+	}
+	if !store.Has(testutil.KeccakLink(t, header.MultiCodecType, blocks[0].Hash().Bytes())) {
+		t.Fatal("expected the header to be found")
+	}
+	if !store.Has(rctRoot) {
+		t.Error("expected the receipt root of a read header to be found")
+	}
+
+	codeLink := testutil.KeccakLink(t, cid.Raw, crypto.Keccak256(code))
+	if data, err := store.Get(codeLink); err != nil || !bytes.Equal(data, code) {
+		t.Errorf("unable to read code: %v", err)
+	}
+	for _, node := range nodes {
+		lnk := testutil.KeccakLink(t, state_trie.MultiCodecType, crypto.Keccak256(node))
+		if data, err := store.Get(lnk); err != nil || !bytes.Equal(data, node) {
+			t.Errorf("unable to read state trie node %s: %v", lnk.String(), err)
+		}
+	}
+
+	// the state trie can be walked straight out of the database
+	f, err := os.Create(filepath.Join(t.TempDir(), "state.car"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stateRoot := testutil.KeccakLink(t, state_trie.MultiCodecType, crypto.Keccak256(nodes[len(nodes)-1]))
+	if err := export.ExportState(f, newLinkSystem(store), stateRoot); err != nil {
+		t.Fatalf("unable to export the state trie from chaindata: %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	// the accounts link to storage tries and code that are not in the snapshot
+	opts := export.ImportOptions{AllowMissing: func(cid.Cid, ipld.Link) bool { return true }}
+	summary, err := opts.ImportCAR(f, codecs.NewLinkSystem(&storage.Memory{}))
+	if err != nil {
+		t.Fatalf("unable to import the exported state trie: %v", err)
+	}
+	if summary.Codecs[state_trie.MultiCodecType] != len(nodes) {
+		t.Errorf("expected the %d state trie nodes to be exported, got %d", len(nodes), summary.Codecs[state_trie.MultiCodecType])
+	}
+
+	missing := testutil.KeccakLink(t, header.MultiCodecType, crypto.Keccak256([]byte("missing")))
+	if _, err := store.Get(missing); !errors.Is(err, chaindata.ErrNotFound) {
+		t.Errorf("expected a missing header not to be found, got %v", err)
+	}
+	sha, _ := cid.Prefix{Version: 1, Codec: header.MultiCodecType, MhType: multihash.SHA2_256, MhLength: -1}.Sum([]byte("header"))
+	if store.Has(cidlink.Link{Cid: sha}) {
+		t.Error("expected a link that is not keccak256 not to be found")
+	}
+}
+
+func TestWriteStore(t *testing.T) {
+	src, blocks, nodes, code := mockChaindata(3)
+	packed := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	headerLinks := make([]ipld.Link, len(blocks))
+	for i, blk := range blocks {
+		var err error
+		receipts := rawdb.ReadRawReceipts(src, blk.Hash(), blk.NumberU64())
+		for j, receipt := range receipts {
+			receipt.Type = blk.Transactions()[j].Type()
+		}
+		if headerLinks[i], err = block.PackBlock(blk, receipts, codecs.NewLinkSystem(packed)); err != nil {
+			t.Fatalf("unable to pack block %d: %v", i, err)
+		}
+	}
+
+	db := rawdb.NewMemoryDatabase()
+	store := chaindata.WriteOptions{Canonical: true}.NewWriteStore(db)
+	// the blocks of the DAGs arrive in no particular order
+	for lnk, data := range packed.Bag {
+		if err := store.Put(lnk, data); err != nil {
+			t.Fatalf("unable to write block %s: %v", lnk.String(), err)
+		}
+	}
+	if len(store.Pending()) != len(blocks) {
+		t.Fatalf("expected %d pending headers before flushing, got %d", len(blocks), len(store.Pending()))
+	}
+	// the staged blocks are served before they are flushed
+	if _, _, err := block.UnpackBlock(codecs.NewLinkSystem(store), headerLinks[1]); err != nil {
+		t.Fatalf("unable to unpack a staged block: %v", err)
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("unable to flush: %v", err)
+	}
+	if len(store.Pending()) != 0 {
+		t.Fatalf("expected no pending headers after flushing, got %d", len(store.Pending()))
+	}
+
+	for _, blk := range blocks {
+		written := rawdb.ReadBlock(db, blk.Hash(), blk.NumberU64())
+		if written == nil || written.Hash() != blk.Hash() || len(written.Transactions()) != len(blk.Transactions()) {
+			t.Fatalf("block %d is not written", blk.NumberU64())
+		}
+		if rawdb.ReadCanonicalHash(db, blk.NumberU64()) != blk.Hash() {
+			t.Errorf("block %d is not canonical", blk.NumberU64())
+		}
+		receipts := rawdb.ReadRawReceipts(db, blk.Hash(), blk.NumberU64())
+		if len(receipts) != len(blk.Transactions()) {
+			t.Fatalf("expected %d receipts of block %d, got %d", len(blk.Transactions()), blk.NumberU64(), len(receipts))
+		}
+		for i, receipt := range receipts {
+			receipt.Type = blk.Transactions()[i].Type()
+		}
+		if types.DeriveSha(receipts, gethtrie.NewStackTrie(nil)) != blk.ReceiptHash() {
+			t.Errorf("written receipts of block %d do not match the receipt root", blk.NumberU64())
+		}
+		// the lookup entry of a genesis transaction encodes block number 0 as no bytes, which reads as missing
+		if blk.NumberU64() == 0 {
+			continue
+		}
+		trx := blk.Transactions()[0]
+		if _, blockHash, _, _ := rawdb.ReadTransaction(db, trx.Hash()); blockHash != blk.Hash() {
+			t.Errorf("transaction %s of block %d is not indexed", trx.Hash().Hex(), blk.NumberU64())
+		}
+	}
+	if head := rawdb.ReadHeadBlockHash(db); head != blocks[len(blocks)-1].Hash() {
+		t.Errorf("expected the head block to be the last block, got %s", head.Hex())
+	}
+	// once written, the blocks are read back from the database
+	if _, _, err := block.UnpackBlock(newLinkSystem(chaindata.NewReadStore(db)), headerLinks[2]); err != nil {
+		t.Errorf("unable to unpack a written block: %v", err)
+	}
+
+	lsys := codecs.NewLinkSystem(store)
+	for _, node := range nodes {
+		w, commit, _ := lsys.StorageWriteOpener(ipld.LinkContext{})
+		w.Write(node)
+		if err := commit(testutil.KeccakLink(t, state_trie.MultiCodecType, crypto.Keccak256(node))); err != nil {
+			t.Fatalf("unable to write state trie node: %v", err)
+		}
+		if !bytes.Equal(rawdb.ReadLegacyTrieNode(db, crypto.Keccak256Hash(node)), node) {
+			t.Errorf("state trie node %x is not written", crypto.Keccak256(node))
+		}
+	}
+	if err := store.Put(testutil.KeccakLink(t, cid.Raw, crypto.Keccak256(code)), code); err != nil {
+		t.Fatalf("unable to write code: %v", err)
+	}
+	if !bytes.Equal(rawdb.ReadCode(db, crypto.Keccak256Hash(code)), code) {
+		t.Error("code is not written")
+	}
+	if err := store.Put(testutil.KeccakLink(t, cid.Raw, crypto.Keccak256(code)), code[1:]); err == nil {
+		t.Error("expected writing a block that does not match its link to fail")
+	}
+
+	// a header without the rest of its DAG stays pending
+	g := testutil.NewGenerator(9)
+	headerRLP, _ := rlp.EncodeToBytes(g.Header())
+	if err := store.Put(testutil.KeccakLink(t, header.MultiCodecType, crypto.Keccak256(headerRLP)), headerRLP); err != nil {
+		t.Fatalf("unable to write header: %v", err)
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("unable to flush: %v", err)
+	}
+	if pending := store.Pending(); len(pending) != 1 || pending[0] != crypto.Keccak256Hash(headerRLP) {
+		t.Errorf("expected the lone header to stay pending, got %v", pending)
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Package chaindata serves DAG-ETH IPLD blocks straight out of a go-ethereum database, so IPLD traversals, selectors and
+graphsync can run against the chaindata of an existing node without duplicating it into a blockstore, and writes
+DAG-ETH blocks back into a go-ethereum database.
+*/
+package chaindata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/util"
+)
+
+// ErrNotFound is returned for links to blocks the database does not hold, or that the ReadStore cannot locate
+var ErrNotFound = errors.New("block not found in chaindata")
+
+const (
+	// packedBlocks is the number of packed Ethereum blocks kept in memory
+	packedBlocks = 16
+	// indexedHeaders is the number of read headers whose roots are remembered
+	indexedHeaders = 4096
+)
+
+// blockRef identifies a block by hash and number, which together key its contents in the database
+type blockRef struct {
+	hash   common.Hash
+	number uint64
+}
+
+// ReadStore maps links onto the go-ethereum key scheme, and its OpenRead can be used as the StorageReadOpener of
+// an ipld.LinkSystem.
+//
+// Headers, contract code, and state and storage trie nodes of a hash scheme database are read by their hash, and
+// transactions through the transaction lookup index. Uncles, receipts, logs, withdrawals and the nodes of the
+// transaction, receipt, log and withdrawal tries are not stored by hash, so they are found through the header that
+// commits to them: once a header has been read, a link to one of its roots packs the block with the block package
+// and serves its IPLD blocks from memory. As traversals reach those roots through the header, this covers them.
+// State accounts, transaction and receipt lists, traces and blob sidecars cannot be located and are never found.
+// It is safe for concurrent use.
+type ReadStore struct {
+	db ethdb.Reader
+
+	mu sync.Mutex
+	// roots maps the uncle hash and trie roots of read headers to their block
+	roots lru.BasicLRU[common.Hash, blockRef]
+	// packed holds the IPLD blocks of the most recently packed blocks, and owner the packed block of each link
+	packed lru.BasicLRU[common.Hash, *storage.Memory]
+	owner  map[ipld.Link]common.Hash
+}
+
+// NewReadStore returns a ReadStore reading from the database
+func NewReadStore(db ethdb.Reader) *ReadStore {
+	return &ReadStore{
+		db:     db,
+		roots:  lru.NewBasicLRU[common.Hash, blockRef](indexedHeaders),
+		packed: lru.NewBasicLRU[common.Hash, *storage.Memory](packedBlocks),
+		owner:  make(map[ipld.Link]common.Hash),
+	}
+}
+
+// OpenRead returns the encoded block the link points to, or an error wrapping ErrNotFound
+func (s *ReadStore) OpenRead(_ ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+	data, err := s.Get(lnk)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// Has returns whether the block the link points to can be read
+func (s *ReadStore) Has(lnk ipld.Link) bool {
+	_, err := s.Get(lnk)
+	return err == nil
+}
+
+// Get returns the encoded block the link points to, or an error wrapping ErrNotFound
+func (s *ReadStore) Get(lnk ipld.Link) ([]byte, error) {
+	c, hash, err := keccakLink(lnk)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	switch codec := c.Prefix().Codec; codec {
+	case header.MultiCodecType:
+		data = s.readHeader(hash)
+	case state_trie.MultiCodecType, storage_trie.MultiCodecType:
+		data = rawdb.ReadLegacyTrieNode(s.db, hash)
+	case cid.Raw:
+		data = rawdb.ReadCode(s.db, hash)
+	case tx.MultiCodecType:
+		if data, err = s.readPacked(lnk, hash); err != nil {
+			return nil, err
+		}
+		if data == nil {
+			data, err = s.readTransaction(hash)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if data, err = s.readPacked(lnk, hash); err != nil {
+			return nil, err
+		}
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, c.String())
+	}
+	return data, nil
+}
+
+// readHeader reads the RLP encoded header, and remembers the roots it commits to
+func (s *ReadStore) readHeader(hash common.Hash) []byte {
+	number := rawdb.ReadHeaderNumber(s.db, hash)
+	if number == nil {
+		return nil
+	}
+	data := rawdb.ReadHeaderRLP(s.db, hash, *number)
+	if data == nil {
+		return nil
+	}
+	h := new(types.Header)
+	if err := rlp.DecodeBytes(data, h); err != nil {
+		return nil
+	}
+	ref := blockRef{hash: hash, number: *number}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	roots := []common.Hash{h.UncleHash, h.TxHash, h.ReceiptHash}
+	if h.WithdrawalsHash != nil {
+		roots = append(roots, *h.WithdrawalsHash)
+	}
+	for _, root := range roots {
+		// empty tries are never stored, so there is nothing to find through them
+		if root != types.EmptyRootHash {
+			s.roots.Add(root, ref)
+		}
+	}
+	return data
+}
+
+// readTransaction reads the transaction through the transaction lookup index
+func (s *ReadStore) readTransaction(hash common.Hash) ([]byte, error) {
+	trx, _, _, _ := rawdb.ReadTransaction(s.db, hash)
+	if trx == nil {
+		return nil, nil
+	}
+	return trx.MarshalBinary()
+}
+
+// readPacked returns the block from a packed block, packing the block the hash is a root of first if need be.
+// It returns nil data if the block is neither packed nor a root of a read header.
+func (s *ReadStore) readPacked(lnk ipld.Link, hash common.Hash) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data, ok := s.packedBlock(lnk); ok {
+		return data, nil
+	}
+	ref, ok := s.roots.Get(hash)
+	if !ok {
+		return nil, nil
+	}
+	if err := s.pack(ref); err != nil {
+		return nil, err
+	}
+	data, _ := s.packedBlock(lnk)
+	return data, nil
+}
+
+func (s *ReadStore) packedBlock(lnk ipld.Link) ([]byte, bool) {
+	blockHash, ok := s.owner[lnk]
+	if !ok {
+		return nil, false
+	}
+	mem, ok := s.packed.Get(blockHash)
+	if !ok {
+		return nil, false
+	}
+	data, ok := mem.Bag[lnk]
+	return data, ok
+}
+
+// pack packs the block into memory, evicting the least recently used packed block if the cache is full
+func (s *ReadStore) pack(ref blockRef) error {
+	blk := rawdb.ReadBlock(s.db, ref.hash, ref.number)
+	if blk == nil {
+		return fmt.Errorf("%w: body of block %d %s", ErrNotFound, ref.number, ref.hash.Hex())
+	}
+	receipts := rawdb.ReadRawReceipts(s.db, ref.hash, ref.number)
+	txs := blk.Transactions()
+	if len(receipts) != len(txs) {
+		return fmt.Errorf("%w: receipts of block %d %s", ErrNotFound, ref.number, ref.hash.Hex())
+	}
+	// the stored receipts drop the type, which their consensus encoding needs
+	for i, receipt := range receipts {
+		receipt.Type = txs[i].Type()
+	}
+	mem := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	if _, err := block.PackBlock(blk, receipts, codecs.NewLinkSystem(mem)); err != nil {
+		return fmt.Errorf("unable to pack block %d %s: %v", ref.number, ref.hash.Hex(), err)
+	}
+	if s.packed.Len() == packedBlocks {
+		if evictedHash, evicted, ok := s.packed.RemoveOldest(); ok {
+			for lnk := range evicted.Bag {
+				if s.owner[lnk] == evictedHash {
+					delete(s.owner, lnk)
+				}
+			}
+		}
+	}
+	s.packed.Add(ref.hash, mem)
+	for lnk := range mem.Bag {
+		s.owner[lnk] = ref.hash
+	}
+	return nil
+}
+
+// keccakLink returns the CID of the link and the keccak256 hash it carries
+func keccakLink(lnk ipld.Link) (cid.Cid, common.Hash, error) {
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return cid.Undef, common.Hash{}, fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+	}
+	hash, err := util.CidToKeccak256(cl.Cid)
+	if err != nil {
+		return cid.Undef, common.Hash{}, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return cl.Cid, hash, nil
+}
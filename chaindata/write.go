@@ -0,0 +1,209 @@
+package chaindata
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+)
+
+// WriteOptions can be used to customize how a WriteStore writes completed blocks.
+// The zero value writes headers, bodies and receipts without touching the canonical chain.
+type WriteOptions struct {
+	// Canonical marks every completed block as the canonical block of its number, indexes its transactions, and
+	// moves the head header and head block markers to it if it is above the current head block.
+	// Blocks of a single chain should be written with it, as the last completed block of a number wins.
+	Canonical bool
+}
+
+// WriteStore is the inverse of ReadStore: its OpenWrite takes encoded DAG-ETH blocks and writes them into a
+// go-ethereum database under the rawdb key scheme, so a dataset synced over IPFS can bootstrap a native node.
+//
+// Headers, contract code, and state and storage trie nodes, under the hash scheme, are written as they are
+// committed. The rest of a block is spread over the blocks of its uncles and of its transaction, receipt, log and
+// withdrawal tries, which arrive in any order, so those are staged in memory until Flush finds every block of the
+// DAG under a written header and writes the body and receipts of that block. OpenRead serves the staged blocks and
+// then everything a ReadStore finds in the database.
+// It is safe for concurrent use.
+type WriteStore struct {
+	*ReadStore
+	db   ethdb.Database
+	opts WriteOptions
+
+	mu sync.Mutex
+	// staged holds the committed blocks that are not written to the database on their own
+	staged map[ipld.Link][]byte
+	// pending are the written headers whose bodies and receipts are not written yet
+	pending map[common.Hash]blockRef
+	// done are the staged blocks that written bodies and receipts were assembled from
+	done map[ipld.Link]bool
+}
+
+// NewWriteStore returns a WriteStore writing into the database, with the default WriteOptions
+func NewWriteStore(db ethdb.Database) *WriteStore {
+	return WriteOptions{}.NewWriteStore(db)
+}
+
+// NewWriteStore returns a WriteStore writing into the database with these options
+func (opts WriteOptions) NewWriteStore(db ethdb.Database) *WriteStore {
+	return &WriteStore{
+		ReadStore: NewReadStore(db),
+		db:        db,
+		opts:      opts,
+		staged:    make(map[ipld.Link][]byte),
+		pending:   make(map[common.Hash]blockRef),
+		done:      make(map[ipld.Link]bool),
+	}
+}
+
+// OpenWrite can be used as the StorageWriteOpener of an ipld.LinkSystem.
+// The committed block is checked against the keccak256 hash of its link.
+func (s *WriteStore) OpenWrite(ipld.LinkContext) (io.Writer, ipld.BlockWriteCommitter, error) {
+	buf := new(bytes.Buffer)
+	return buf, func(lnk ipld.Link) error {
+		return s.Put(lnk, buf.Bytes())
+	}, nil
+}
+
+// Put writes the encoded block the link points to
+func (s *WriteStore) Put(lnk ipld.Link, data []byte) error {
+	c, hash, err := keccakLink(lnk)
+	if err != nil {
+		return err
+	}
+	codec := c.Prefix().Codec
+	if err := shared.VerifyCID(data, c, codec); err != nil {
+		return err
+	}
+	switch codec {
+	case header.MultiCodecType:
+		h := new(types.Header)
+		if err := rlp.DecodeBytes(data, h); err != nil {
+			return fmt.Errorf("unable to decode header %s: %v", c.String(), err)
+		}
+		rawdb.WriteHeader(s.db, h)
+		s.mu.Lock()
+		s.pending[hash] = blockRef{hash: hash, number: h.Number.Uint64()}
+		s.mu.Unlock()
+	case state_trie.MultiCodecType, storage_trie.MultiCodecType:
+		rawdb.WriteLegacyTrieNode(s.db, hash, data)
+	case cid.Raw:
+		rawdb.WriteCode(s.db, hash, data)
+	default:
+		s.mu.Lock()
+		s.staged[lnk] = append([]byte(nil), data...)
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// Get returns a staged block, or the block the ReadStore finds in the database
+func (s *WriteStore) Get(lnk ipld.Link) ([]byte, error) {
+	s.mu.Lock()
+	data, ok := s.staged[lnk]
+	s.mu.Unlock()
+	if ok {
+		return data, nil
+	}
+	return s.ReadStore.Get(lnk)
+}
+
+// OpenRead can be used as the StorageReadOpener of an ipld.LinkSystem
+func (s *WriteStore) OpenRead(_ ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+	data, err := s.Get(lnk)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// Has returns whether the block the link points to is staged or can be read from the database
+func (s *WriteStore) Has(lnk ipld.Link) bool {
+	_, err := s.Get(lnk)
+	return err == nil
+}
+
+// Flush writes the body and receipts of every written header whose DAG is complete. Headers whose DAG is still
+// incomplete stay pending, and are listed by Pending. Blocks can be shared between DAGs, e.g. an empty uncle list or
+// the same log, so the staged blocks that were written are only dropped once no header is pending.
+func (s *WriteStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, ref := range s.pending {
+		read := make(map[ipld.Link]bool)
+		lsys := cidlink.DefaultLinkSystem()
+		lsys.StorageReadOpener = func(_ ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+			if data, ok := s.staged[lnk]; ok {
+				read[lnk] = true
+				return bytes.NewReader(data), nil
+			}
+			if lnk.(cidlink.Link).Prefix().Codec == header.MultiCodecType {
+				if data := rawdb.ReadHeaderRLP(s.db, hash, ref.number); data != nil {
+					return bytes.NewReader(data), nil
+				}
+			}
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, lnk.String())
+		}
+		headerCID, err := shared.Keccak256ToCid(header.MultiCodecType, hash.Bytes())
+		if err != nil {
+			return err
+		}
+		blk, receipts, err := block.UnpackBlock(lsys, cidlink.Link{Cid: headerCID})
+		if err != nil {
+			// the rest of the DAG has not been written yet
+			continue
+		}
+		batch := s.db.NewBatch()
+		rawdb.WriteBody(batch, hash, ref.number, blk.Body())
+		rawdb.WriteReceipts(batch, hash, ref.number, receipts)
+		if s.opts.Canonical {
+			rawdb.WriteCanonicalHash(batch, hash, ref.number)
+			rawdb.WriteTxLookupEntriesByBlock(batch, blk)
+			head := rawdb.ReadHeaderNumber(s.db, rawdb.ReadHeadBlockHash(s.db))
+			if head == nil || ref.number > *head {
+				rawdb.WriteHeadHeaderHash(batch, hash)
+				rawdb.WriteHeadBlockHash(batch, hash)
+			}
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		delete(s.pending, hash)
+		for lnk := range read {
+			s.done[lnk] = true
+		}
+	}
+	if len(s.pending) == 0 {
+		for lnk := range s.done {
+			delete(s.staged, lnk)
+		}
+		s.done = make(map[ipld.Link]bool)
+	}
+	return nil
+}
+
+// Pending returns the hashes of the written headers whose bodies and receipts are not written yet
+func (s *WriteStore) Pending() []common.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hashes := make([]common.Hash, 0, len(s.pending))
+	for hash := range s.pending {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
@@ -0,0 +1,199 @@
+/*
+Package clique splits the Extra field of the headers of clique (proof-of-authority) chains, such as the Goerli
+testnet, into the vanity bytes, the signer list of checkpoint blocks and the seal, and recovers the address of the
+signer from the seal, as a CliqueExtra node.
+*/
+package clique
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/convert"
+)
+
+const (
+	// ExtraVanity is the number of vanity bytes leading the Extra field
+	ExtraVanity = 32
+	// ExtraSeal is the number of bytes of the seal closing the Extra field
+	ExtraSeal = crypto.SignatureLength
+	// DefaultEpoch is the number of blocks between checkpoints when Options.Epoch is zero, the default of clique
+	DefaultEpoch = 30000
+)
+
+// Options can be used to customize clique extra data decoding.
+// The zero value is the default behavior used by DecodeExtra.
+type Options struct {
+	// Epoch is the number of blocks between the checkpoint blocks of the chain, which carry the list of signers;
+	// it defaults to DefaultEpoch
+	Epoch uint64
+}
+
+// DecodeExtra splits the Extra field of the Header node into the fields of a CliqueExtra, and assembles it into the
+// NodeAssembler, recovering the Signer from the seal
+func DecodeExtra(na ipld.NodeAssembler, header ipld.Node) error {
+	return Options{}.DecodeExtra(na, header)
+}
+
+// Extra is like DecodeExtra, but builds and returns a CliqueExtra node
+func Extra(header ipld.Node) (ipld.Node, error) {
+	return Options{}.Extra(header)
+}
+
+// Signer recovers the address of the signer of the Header node from the seal of its Extra field
+func Signer(header ipld.Node) (common.Address, error) {
+	h, err := convert.ToHeader(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return RecoverSigner(h)
+}
+
+// DecodeExtra is like the package level DecodeExtra, but uses the provided options
+func (opts Options) DecodeExtra(na ipld.NodeAssembler, header ipld.Node) error {
+	h, err := convert.ToHeader(header)
+	if err != nil {
+		return err
+	}
+	return opts.DecodeHeaderExtra(na, h)
+}
+
+// DecodeHeaderExtra is like DecodeExtra, for a go-ethereum header
+func (opts Options) DecodeHeaderExtra(na ipld.NodeAssembler, h *types.Header) error {
+	if len(h.Extra) < ExtraVanity+ExtraSeal {
+		return fmt.Errorf("clique extra data of %d bytes cannot hold %d vanity and %d seal bytes", len(h.Extra), ExtraVanity, ExtraSeal)
+	}
+	signersBytes := h.Extra[ExtraVanity : len(h.Extra)-ExtraSeal]
+	epoch := opts.Epoch
+	if epoch == 0 {
+		epoch = DefaultEpoch
+	}
+	checkpoint := h.Number.Uint64()%epoch == 0
+	if !checkpoint && len(signersBytes) != 0 {
+		return fmt.Errorf("clique header %d is not a checkpoint, but carries %d bytes of signers", h.Number, len(signersBytes))
+	}
+	if len(signersBytes)%common.AddressLength != 0 {
+		return fmt.Errorf("clique checkpoint header %d carries %d bytes of signers, not a list of addresses", h.Number, len(signersBytes))
+	}
+	signer, err := RecoverSigner(h)
+	if err != nil {
+		return err
+	}
+
+	ma, err := na.BeginMap(4)
+	if err != nil {
+		return err
+	}
+	if err := ma.AssembleKey().AssignString("Vanity"); err != nil {
+		return err
+	}
+	if err := ma.AssembleValue().AssignBytes(h.Extra[:ExtraVanity]); err != nil {
+		return err
+	}
+	if err := ma.AssembleKey().AssignString("Signers"); err != nil {
+		return err
+	}
+	if checkpoint {
+		la, err := ma.AssembleValue().BeginList(int64(len(signersBytes) / common.AddressLength))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(signersBytes); i += common.AddressLength {
+			if err := la.AssembleValue().AssignBytes(signersBytes[i : i+common.AddressLength]); err != nil {
+				return err
+			}
+		}
+		if err := la.Finish(); err != nil {
+			return err
+		}
+	} else if err := ma.AssembleValue().AssignNull(); err != nil {
+		return err
+	}
+	if err := ma.AssembleKey().AssignString("Seal"); err != nil {
+		return err
+	}
+	if err := ma.AssembleValue().AssignBytes(h.Extra[len(h.Extra)-ExtraSeal:]); err != nil {
+		return err
+	}
+	if err := ma.AssembleKey().AssignString("Signer"); err != nil {
+		return err
+	}
+	if err := ma.AssembleValue().AssignBytes(signer.Bytes()); err != nil {
+		return err
+	}
+	return ma.Finish()
+}
+
+// Extra is like the package level Extra, but uses the provided options
+func (opts Options) Extra(header ipld.Node) (ipld.Node, error) {
+	nb := dageth.Type.CliqueExtra.NewBuilder()
+	if err := opts.DecodeExtra(nb, header); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
+}
+
+// RecoverSigner recovers the address of the signer of the header from the seal of its Extra field
+func RecoverSigner(h *types.Header) (common.Address, error) {
+	if len(h.Extra) < ExtraSeal {
+		return common.Address{}, fmt.Errorf("clique extra data of %d bytes is missing its seal", len(h.Extra))
+	}
+	hash, err := SealHash(h)
+	if err != nil {
+		return common.Address{}, err
+	}
+	pubkey, err := crypto.Ecrecover(hash.Bytes(), h.Extra[len(h.Extra)-ExtraSeal:])
+	if err != nil {
+		return common.Address{}, fmt.Errorf("unable to recover the signer of clique header %d: %v", h.Number, err)
+	}
+	return common.BytesToAddress(crypto.Keccak256(pubkey[1:])[12:]), nil
+}
+
+// SealHash returns the hash the signer of a clique header signs, the hash of the header without the seal of its
+// Extra field. Clique predates the withdrawals of Shanghai, so headers carrying them are rejected.
+func SealHash(h *types.Header) (common.Hash, error) {
+	hasher := crypto.NewKeccakState()
+	if err := encodeSigHeader(hasher, h); err != nil {
+		return common.Hash{}, err
+	}
+	var hash common.Hash
+	hasher.Read(hash[:])
+	return hash, nil
+}
+
+func encodeSigHeader(w io.Writer, h *types.Header) error {
+	if len(h.Extra) < ExtraSeal {
+		return fmt.Errorf("clique extra data of %d bytes is missing its seal", len(h.Extra))
+	}
+	if h.WithdrawalsHash != nil || h.BlobGasUsed != nil || h.ExcessBlobGas != nil || h.ParentBeaconRoot != nil || h.RequestsHash != nil {
+		return fmt.Errorf("clique header %d carries post-Shanghai fields", h.Number)
+	}
+	enc := []interface{}{
+		h.ParentHash,
+		h.UncleHash,
+		h.Coinbase,
+		h.Root,
+		h.TxHash,
+		h.ReceiptHash,
+		h.Bloom,
+		h.Difficulty,
+		h.Number,
+		h.GasLimit,
+		h.GasUsed,
+		h.Time,
+		h.Extra[:len(h.Extra)-ExtraSeal],
+		h.MixDigest,
+		h.Nonce,
+	}
+	if h.BaseFee != nil {
+		enc = append(enc, h.BaseFee)
+	}
+	return rlp.Encode(w, enc)
+}
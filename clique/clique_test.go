@@ -0,0 +1,129 @@
+package clique_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/vulcanize/go-codec-dageth/clique"
+	"github.com/vulcanize/go-codec-dageth/convert"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+var (
+	mockKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	mockSigner = crypto.PubkeyToAddress(mockKey.PublicKey)
+	mockVanity = bytes.Repeat([]byte{0x11}, clique.ExtraVanity)
+)
+
+// sealedHeader returns a clique header of the block number carrying the signers, sealed by mockKey
+func sealedHeader(t *testing.T, number int64, signers []common.Address) *types.Header {
+	h := testutil.NewGenerator(number).Header()
+	h.Number = big.NewInt(number)
+	h.WithdrawalsHash, h.BlobGasUsed, h.ExcessBlobGas, h.ParentBeaconRoot, h.RequestsHash = nil, nil, nil, nil, nil
+	h.Extra = append([]byte{}, mockVanity...)
+	for _, signer := range signers {
+		h.Extra = append(h.Extra, signer.Bytes()...)
+	}
+	h.Extra = append(h.Extra, make([]byte, clique.ExtraSeal)...)
+	hash, err := clique.SealHash(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seal, err := crypto.Sign(hash.Bytes(), mockKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(h.Extra[len(h.Extra)-clique.ExtraSeal:], seal)
+	return h
+}
+
+func TestExtra(t *testing.T) {
+	signers := []common.Address{mockSigner, {1}, {2}}
+	for _, test := range []struct {
+		name    string
+		number  int64
+		signers []common.Address
+	}{
+		{"checkpoint", 30000, signers},
+		{"genesis", 0, signers[:1]},
+		{"non-checkpoint", 30001, nil},
+	} {
+		h := sealedHeader(t, test.number, test.signers)
+		headerNode, err := convert.FromHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		extra, err := clique.Extra(headerNode)
+		if err != nil {
+			t.Fatalf("%s: unable to decode clique extra data: %v", test.name, err)
+		}
+		vanityNode, _ := extra.LookupByString("Vanity")
+		if vanity, _ := vanityNode.AsBytes(); !bytes.Equal(vanity, mockVanity) {
+			t.Errorf("%s: expected vanity %x, got %x", test.name, mockVanity, vanity)
+		}
+		sealNode, _ := extra.LookupByString("Seal")
+		if seal, _ := sealNode.AsBytes(); !bytes.Equal(seal, h.Extra[len(h.Extra)-clique.ExtraSeal:]) {
+			t.Errorf("%s: expected the seal to close the extra data, got %x", test.name, seal)
+		}
+		signerNode, _ := extra.LookupByString("Signer")
+		if signer, _ := signerNode.AsBytes(); !bytes.Equal(signer, mockSigner.Bytes()) {
+			t.Errorf("%s: expected signer %x, got %x", test.name, mockSigner, signer)
+		}
+		signersNode, _ := extra.LookupByString("Signers")
+		if test.number%clique.DefaultEpoch != 0 {
+			if !signersNode.IsNull() {
+				t.Errorf("%s: expected no signers outside of checkpoints", test.name)
+			}
+			continue
+		}
+		if signersNode.Length() != int64(len(test.signers)) {
+			t.Fatalf("%s: expected %d signers, got %d", test.name, len(test.signers), signersNode.Length())
+		}
+		for i, expected := range test.signers {
+			n, _ := signersNode.LookupByIndex(int64(i))
+			if signer, _ := n.AsBytes(); !bytes.Equal(signer, expected.Bytes()) {
+				t.Errorf("%s: expected signer %d to be %x, got %x", test.name, i, expected, signer)
+			}
+		}
+	}
+}
+
+func TestExtraInvalid(t *testing.T) {
+	// signers outside of a checkpoint of the epoch
+	h := sealedHeader(t, 101, []common.Address{mockSigner})
+	headerNode, _ := convert.FromHeader(h)
+	if _, err := clique.Extra(headerNode); err == nil {
+		t.Error("expected signers outside of a checkpoint to be rejected")
+	}
+	if _, err := (clique.Options{Epoch: 101}).Extra(headerNode); err != nil {
+		t.Errorf("expected signers at a checkpoint of a custom epoch to be accepted: %v", err)
+	}
+
+	// a partial signer address
+	h = sealedHeader(t, 0, nil)
+	h.Extra = append(append(append([]byte{}, mockVanity...), 1, 2, 3), h.Extra[clique.ExtraVanity:]...)
+	headerNode, _ = convert.FromHeader(h)
+	if _, err := clique.Extra(headerNode); err == nil {
+		t.Error("expected a partial signer address to be rejected")
+	}
+
+	// a forged header no longer recovers to its signer
+	h = sealedHeader(t, 5, nil)
+	h.GasLimit++
+	headerNode, _ = convert.FromHeader(h)
+	if signer, err := clique.Signer(headerNode); err == nil && signer == mockSigner {
+		t.Error("expected a changed header not to recover to its original signer")
+	}
+
+	// extra data too short for a seal
+	h.Extra = mockVanity
+	headerNode, _ = convert.FromHeader(h)
+	if _, err := clique.Extra(headerNode); err == nil {
+		t.Error("expected extra data without a seal to be rejected")
+	}
+}
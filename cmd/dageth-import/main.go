@@ -0,0 +1,187 @@
+/*
+Command dageth-import ingests blocks from an Ethereum node into DAG-ETH IPLD blocks.
+
+Each block of the range is read from one source, packed with the block package into its header, uncles,
+transaction, receipt, log and withdrawal tries, and written to one destination:
+
+	dageth-import -rpc http://localhost:8545 -from 17000000 -to 17000009 -car blocks.car
+	dageth-import -freezer ~/.ethereum/geth/chaindata/ancient -from 0 -to 999 -store ./blocks
+	dageth-import -fixtures ./testdata -from 1 -store ./blocks
+
+The sources are:
+
+	-rpc URL          a node serving debug_getRawBlock, debug_getRawReceipts and eth_getProof
+	-freezer DIR      the ancient directory of a go-ethereum datadir, opened read only
+	-fixtures DIR     blocks written by the fixtures package
+
+The destinations are:
+
+	-car FILE         a CARv2 archive rooted at the headers of the blocks, in import order
+	-store DIR        a flat directory holding one file per block, named by its CID, which can be imported into again
+
+With -rpc the state and storage trie nodes proving the coinbase, and the accounts and storage slots listed with
+-accounts and -storage-keys, in the state after each block are imported as well, as are the proofs stored with
+-fixtures. The freezer only holds chain segments, so no state is imported from it.
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+)
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "dageth-import:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// config holds the parsed command line
+type config struct {
+	rpcURL, freezerDir, fixturesDir string
+	carFile, storeDir               string
+	from, to                        uint64
+	accounts                        []common.Address
+	storageKeys                     []common.Hash
+}
+
+func parseFlags(args []string, stderr io.Writer) (*config, error) {
+	cfg := new(config)
+	fs := flag.NewFlagSet("dageth-import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&cfg.rpcURL, "rpc", "", "read blocks from the node at this RPC URL")
+	fs.StringVar(&cfg.freezerDir, "freezer", "", "read blocks from this go-ethereum ancient directory")
+	fs.StringVar(&cfg.fixturesDir, "fixtures", "", "read blocks from this fixtures directory")
+	fs.StringVar(&cfg.carFile, "car", "", "write the IPLD blocks into this CAR file")
+	fs.StringVar(&cfg.storeDir, "store", "", "write the IPLD blocks into this blockstore directory")
+	fs.Uint64Var(&cfg.from, "from", 0, "first block number to import")
+	to := fs.Int64("to", -1, "last block number to import, defaults to -from")
+	accounts := fs.String("accounts", "", "comma separated accounts whose proofs are imported along with each block")
+	storageKeys := fs.String("storage-keys", "", "comma separated storage slots proven for each account")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	if count(cfg.rpcURL, cfg.freezerDir, cfg.fixturesDir) != 1 {
+		return nil, fmt.Errorf("exactly one of -rpc, -freezer and -fixtures is required")
+	}
+	if count(cfg.carFile, cfg.storeDir) != 1 {
+		return nil, fmt.Errorf("exactly one of -car and -store is required")
+	}
+	cfg.to = cfg.from
+	if *to >= 0 {
+		cfg.to = uint64(*to)
+	}
+	if cfg.to < cfg.from {
+		return nil, fmt.Errorf("-to %d is below -from %d", cfg.to, cfg.from)
+	}
+	for _, addr := range splitList(*accounts) {
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid account %q", addr)
+		}
+		cfg.accounts = append(cfg.accounts, common.HexToAddress(addr))
+	}
+	for _, key := range splitList(*storageKeys) {
+		cfg.storageKeys = append(cfg.storageKeys, common.HexToHash(key))
+	}
+	if cfg.rpcURL == "" && len(cfg.accounts)+len(cfg.storageKeys) > 0 {
+		return nil, fmt.Errorf("-accounts and -storage-keys can only be used with -rpc")
+	}
+	return cfg, nil
+}
+
+func run(ctx context.Context, args []string, stderr io.Writer) error {
+	cfg, err := parseFlags(args, stderr)
+	if err != nil {
+		return err
+	}
+	src, err := openSource(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	var store codecs.Storage
+	var car *carStore
+	if cfg.storeDir != "" {
+		if store, err = newDirStore(cfg.storeDir); err != nil {
+			return err
+		}
+	} else {
+		car = newCARStore()
+		store = car
+	}
+	lsys := codecs.NewLinkSystem(store)
+
+	var headerLinks []ipld.Link
+	for number := cfg.from; number <= cfg.to; number++ {
+		b, err := src.Block(ctx, number)
+		if err != nil {
+			return err
+		}
+		headerLink, err := block.PackBlock(b.block, b.receipts, lsys)
+		if err != nil {
+			return fmt.Errorf("unable to pack block %d: %v", number, err)
+		}
+		for _, node := range b.stateNodes {
+			if err := storeNode(lsys, state_trie.MultiCodecType, node); err != nil {
+				return err
+			}
+		}
+		for _, node := range b.storageNodes {
+			if err := storeNode(lsys, storage_trie.MultiCodecType, node); err != nil {
+				return err
+			}
+		}
+		headerLinks = append(headerLinks, headerLink)
+		fmt.Fprintf(stderr, "imported block %d as %s\n", number, headerLink.String())
+		if number == cfg.to {
+			// the range can end at the highest block number
+			break
+		}
+	}
+	if car != nil {
+		return car.WriteCAR(cfg.carFile, headerLinks)
+	}
+	return nil
+}
+
+// count returns how many of the values are set
+func count(values ...string) int {
+	var n int
+	for _, value := range values {
+		if value != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/beacon_block"
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/export"
+	"github.com/vulcanize/go-codec-dageth/fixtures"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/request_list"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+)
+
+// mockChain generates blocks with the numbers from 0 to n-1, along with their receipts
+func mockChain(n int) ([]*types.Block, []types.Receipts) {
+	g := testutil.NewGenerator(1)
+	blocks := make([]*types.Block, n)
+	receipts := make([]types.Receipts, n)
+	for i := range blocks {
+		h := g.Header()
+		h.Number.SetUint64(uint64(i))
+		txs := g.Transactions(4)
+		body := &types.Body{Transactions: txs, Uncles: g.Headers(1)}
+		if h.WithdrawalsHash != nil {
+			body.Withdrawals = g.Withdrawals(2)
+		}
+		receipts[i] = g.Receipts(txs)
+		blocks[i] = types.NewBlock(h, body, receipts[i], gethtrie.NewStackTrie(nil))
+	}
+	return blocks, receipts
+}
+
+// checkBlocks unpacks every block from the LinkSystem and compares it with the block it was imported from
+func checkBlocks(t *testing.T, lsys ipld.LinkSystem, blocks []*types.Block, headerLinks []ipld.Link) {
+	t.Helper()
+	if len(headerLinks) != len(blocks) {
+		t.Fatalf("expected %d headers, got %d", len(blocks), len(headerLinks))
+	}
+	for i, lnk := range headerLinks {
+		unpacked, receipts, err := block.UnpackBlock(lsys, lnk)
+		if err != nil {
+			t.Fatalf("unable to unpack block %d: %v", i, err)
+		}
+		if unpacked.Hash() != blocks[i].Hash() {
+			t.Errorf("unpacked block %d hash (%s) does not match expected hash (%s)", i, unpacked.Hash().Hex(), blocks[i].Hash().Hex())
+		}
+		if len(receipts) != len(blocks[i].Transactions()) {
+			t.Errorf("expected %d receipts of block %d, got %d", len(blocks[i].Transactions()), i, len(receipts))
+		}
+	}
+}
+
+func headerLinkOf(t testing.TB, b *types.Block) ipld.Link {
+	return testutil.KeccakLink(t, header.MultiCodecType, b.Hash().Bytes())
+}
+
+func TestImportFixturesToCAR(t *testing.T) {
+	blocks, receipts := mockChain(3)
+	g := testutil.NewGenerator(2)
+	accounts := make([][]byte, 16)
+	for i := range accounts {
+		accounts[i], _ = rlp.EncodeToBytes(g.Account())
+	}
+	proof := g.TrieNodes(accounts)
+	slots := make([][]byte, 16)
+	for i := range slots {
+		slots[i], _ = rlp.EncodeToBytes(g.Bytes(32))
+	}
+	storageProof := g.TrieNodes(slots)
+
+	dir := t.TempDir()
+	for i, b := range blocks {
+		fixture := &fixtures.Block{
+			Number: uint64(i),
+			Proofs: []fixtures.Proof{{
+				Address:       b.Coinbase(),
+				AccountProof:  proof,
+				StorageKeys:   []common.Hash{{0x01}},
+				StorageProofs: [][][]byte{storageProof},
+			}},
+		}
+		fixture.Header, _ = rlp.EncodeToBytes(b.Header())
+		fixture.Block, _ = rlp.EncodeToBytes(b)
+		for _, receipt := range receipts[i] {
+			enc, _ := receipt.MarshalBinary()
+			fixture.Receipts = append(fixture.Receipts, enc)
+		}
+		if err := fixtures.Write(dir, fixture); err != nil {
+			t.Fatalf("unable to write fixture %d: %v", i, err)
+		}
+	}
+
+	carFile := filepath.Join(t.TempDir(), "blocks.car")
+	args := []string{"-fixtures", dir, "-from", "1", "-to", "2", "-car", carFile}
+	if err := run(context.Background(), args, io.Discard); err != nil {
+		t.Fatalf("unable to import fixtures: %v", err)
+	}
+	f, err := os.Open(carFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	// proofs link to the rest of the state, the first header to its parent, headers to their beacon block and
+	// requests, and uncle headers to their own tries
+	allowed := export.AllowMissingCodecs(header.MultiCodecType, state_trie.MultiCodecType, storage_trie.MultiCodecType, 0x55,
+		beacon_block.MultiCodecType, request_list.MultiCodecType)
+	opts := export.ImportOptions{AllowMissing: func(from cid.Cid, to ipld.Link) bool {
+		return from.Prefix().Codec == uncles.MultiCodecType || allowed(from, to)
+	}}
+	summary, err := opts.ImportCAR(f, lsys)
+	if err != nil {
+		t.Fatalf("unable to import CAR: %v", err)
+	}
+	if len(summary.Roots) != 2 {
+		t.Fatalf("expected the 2 imported headers as roots, got %d", len(summary.Roots))
+	}
+	headerLinks := []ipld.Link{cidlink.Link{Cid: summary.Roots[0]}, cidlink.Link{Cid: summary.Roots[1]}}
+	checkBlocks(t, lsys, blocks[1:], headerLinks)
+	if summary.Codecs[state_trie.MultiCodecType] != len(proof) || summary.Codecs[storage_trie.MultiCodecType] != len(storageProof) {
+		t.Errorf("expected %d state and %d storage trie nodes, got %d and %d", len(proof), len(storageProof),
+			summary.Codecs[state_trie.MultiCodecType], summary.Codecs[storage_trie.MultiCodecType])
+	}
+	for _, node := range proof {
+		lnk := testutil.KeccakLink(t, state_trie.MultiCodecType, crypto.Keccak256(node))
+		if _, ok := store.Bag[lnk]; !ok {
+			t.Errorf("state trie node %s is not in the CAR", lnk.String())
+		}
+	}
+}
+
+func TestImportFreezerToStore(t *testing.T) {
+	blocks, receipts := mockChain(4)
+	freezerDir := t.TempDir()
+	db, err := rawdb.NewDatabaseWithFreezer(memorydb.New(), freezerDir, "", false)
+	if err != nil {
+		t.Fatalf("unable to create freezer: %v", err)
+	}
+	if _, err := rawdb.WriteAncientBlocks(db, blocks, receipts); err != nil {
+		t.Fatalf("unable to write blocks into the freezer: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	storeDir := t.TempDir()
+	args := []string{"-freezer", freezerDir, "-from", "0", "-to", "3", "-store", storeDir}
+	// importing into the same blockstore again leaves it as it was
+	for i := 0; i < 2; i++ {
+		if err := run(context.Background(), args, io.Discard); err != nil {
+			t.Fatalf("unable to import freezer: %v", err)
+		}
+	}
+	store, err := newDirStore(storeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerLinks := make([]ipld.Link, len(blocks))
+	for i, b := range blocks {
+		headerLinks[i] = headerLinkOf(t, b)
+	}
+	checkBlocks(t, codecs.NewLinkSystem(store), blocks, headerLinks)
+
+	args = []string{"-freezer", freezerDir, "-from", strconv.Itoa(len(blocks)), "-store", storeDir}
+	if err := run(context.Background(), args, io.Discard); err == nil {
+		t.Error("expected importing a block that is not in the freezer to fail")
+	}
+}
+
+func TestFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-car", "out.car"},
+		{"-rpc", "http://localhost:8545", "-fixtures", "dir", "-car", "out.car"},
+		{"-fixtures", "dir"},
+		{"-fixtures", "dir", "-car", "out.car", "-store", "dir"},
+		{"-fixtures", "dir", "-car", "out.car", "-from", "2", "-to", "1"},
+		{"-fixtures", "dir", "-car", "out.car", "-accounts", "0x01"},
+		{"-rpc", "http://localhost:8545", "-car", "out.car", "-accounts", "0x01"},
+		{"-fixtures", "dir", "-car", "out.car", "extra"},
+	} {
+		if _, err := parseFlags(args, io.Discard); err == nil {
+			t.Errorf("expected parsing %v to fail", args)
+		}
+	}
+	cfg, err := parseFlags([]string{"-rpc", "http://localhost:8545", "-from", "5", "-store", "dir",
+		"-accounts", "0x00000000219ab540356cBB839Cbe05303d7705Fa", "-storage-keys", "0x01, 0x02"}, io.Discard)
+	if err != nil {
+		t.Fatalf("unable to parse flags: %v", err)
+	}
+	if cfg.to != 5 || len(cfg.accounts) != 1 || len(cfg.storageKeys) != 2 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
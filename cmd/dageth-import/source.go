@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/vulcanize/go-codec-dageth/fixtures"
+)
+
+// importBlock is a block read from a source, along with the trie nodes proving some of the state after it
+type importBlock struct {
+	block        *types.Block
+	receipts     types.Receipts
+	stateNodes   [][]byte
+	storageNodes [][]byte
+}
+
+// source reads blocks by number
+type source interface {
+	Block(ctx context.Context, number uint64) (*importBlock, error)
+	Close() error
+}
+
+func openSource(ctx context.Context, cfg *config) (source, error) {
+	switch {
+	case cfg.rpcURL != "":
+		client, err := rpc.DialContext(ctx, cfg.rpcURL)
+		if err != nil {
+			return nil, err
+		}
+		opts := fixtures.FetchOptions{Accounts: cfg.accounts, StorageKeys: cfg.storageKeys}
+		return &rpcSource{client: client, opts: opts}, nil
+	case cfg.freezerDir != "":
+		db, err := rawdb.NewDatabaseWithFreezer(memorydb.New(), cfg.freezerDir, "", true)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open freezer %s: %v", cfg.freezerDir, err)
+		}
+		return &freezerSource{db: db}, nil
+	default:
+		return &fixturesSource{dir: cfg.fixturesDir}, nil
+	}
+}
+
+// rpcSource fetches blocks, receipts and proofs from a node over JSON-RPC
+type rpcSource struct {
+	client *rpc.Client
+	opts   fixtures.FetchOptions
+}
+
+func (s *rpcSource) Block(ctx context.Context, number uint64) (*importBlock, error) {
+	b, err := s.opts.Fetch(ctx, s.client, number)
+	if err != nil {
+		return nil, err
+	}
+	return fromFixture(b)
+}
+
+func (s *rpcSource) Close() error {
+	s.client.Close()
+	return nil
+}
+
+// fixturesSource loads blocks written by the fixtures package
+type fixturesSource struct {
+	dir string
+}
+
+func (s *fixturesSource) Block(_ context.Context, number uint64) (*importBlock, error) {
+	b, err := fixtures.Load(s.dir, number)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load block %d: %v", number, err)
+	}
+	return fromFixture(b)
+}
+
+func (s *fixturesSource) Close() error {
+	return nil
+}
+
+// fromFixture decodes the block and its receipts, and collects the nodes of its proofs
+func fromFixture(b *fixtures.Block) (*importBlock, error) {
+	gethBlock, err := b.GethBlock()
+	if err != nil {
+		return nil, err
+	}
+	ib := &importBlock{block: gethBlock, receipts: make(types.Receipts, len(b.Receipts))}
+	for i, enc := range b.Receipts {
+		ib.receipts[i] = new(types.Receipt)
+		if err := ib.receipts[i].UnmarshalBinary(enc); err != nil {
+			return nil, fmt.Errorf("unable to decode receipt %d of block %d: %v", i, b.Number, err)
+		}
+	}
+	for _, proof := range b.Proofs {
+		ib.stateNodes = append(ib.stateNodes, proof.AccountProof...)
+		for _, storageProof := range proof.StorageProofs {
+			ib.storageNodes = append(ib.storageNodes, storageProof...)
+		}
+	}
+	return ib, nil
+}
+
+// freezerSource reads canonical blocks and receipts from the ancient store of a go-ethereum node
+type freezerSource struct {
+	db ethdb.Database
+}
+
+func (s *freezerSource) Block(_ context.Context, number uint64) (*importBlock, error) {
+	hash := rawdb.ReadCanonicalHash(s.db, number)
+	gethBlock := rawdb.ReadBlock(s.db, hash, number)
+	if gethBlock == nil {
+		return nil, fmt.Errorf("block %d is not in the freezer", number)
+	}
+	receipts := rawdb.ReadRawReceipts(s.db, hash, number)
+	if receipts == nil {
+		return nil, fmt.Errorf("receipts of block %d are not in the freezer", number)
+	}
+	txs := gethBlock.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("block %d has %d transactions but %d receipts in the freezer", number, len(txs), len(receipts))
+	}
+	// the stored receipts drop the type, which their consensus encoding needs
+	for i, receipt := range receipts {
+		receipt.Type = txs[i].Type()
+	}
+	return &importBlock{block: gethBlock, receipts: receipts}, nil
+}
+
+func (s *freezerSource) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/export"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// storeNode writes the trie node, keyed by the CID of its codec and keccak256 hash, into the LinkSystem's storage
+func storeNode(lsys ipld.LinkSystem, codec uint64, node []byte) error {
+	w, commit, err := lsys.StorageWriteOpener(ipld.LinkContext{})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(node); err != nil {
+		return err
+	}
+	c, err := shared.Keccak256ToCid(codec, crypto.Keccak256(node))
+	if err != nil {
+		return err
+	}
+	return commit(cidlink.Link{Cid: c})
+}
+
+// dirStore is a blockstore that keeps each block in a file of the directory named by its CID
+type dirStore struct {
+	dir string
+}
+
+func newDirStore(dir string) (*dirStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &dirStore{dir: dir}, nil
+}
+
+func (s *dirStore) path(lnk ipld.Link) (string, error) {
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return "", fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+	}
+	return filepath.Join(s.dir, cl.Cid.String()), nil
+}
+
+func (s *dirStore) OpenRead(_ ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+	path, err := s.path(lnk)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (s *dirStore) OpenWrite(ipld.LinkContext) (io.Writer, ipld.BlockWriteCommitter, error) {
+	buf := new(bytes.Buffer)
+	return buf, func(lnk ipld.Link) error {
+		path, err := s.path(lnk)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+		// blocks are written to a temporary file first, so an interrupted import never leaves a partial block
+		tmp, err := os.CreateTemp(s.dir, ".tmp-")
+		if err != nil {
+			return err
+		}
+		if _, err := tmp.Write(buf.Bytes()); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+		return os.Rename(tmp.Name(), path)
+	}, nil
+}
+
+// carStore holds the blocks in memory, in the order they are first written, until they are written into a CAR file
+type carStore struct {
+	storage.Memory
+	order []cid.Cid
+}
+
+func newCARStore() *carStore {
+	return &carStore{Memory: storage.Memory{Bag: make(map[ipld.Link][]byte)}}
+}
+
+func (s *carStore) OpenWrite(lnkCtx ipld.LinkContext) (io.Writer, ipld.BlockWriteCommitter, error) {
+	w, commit, err := s.Memory.OpenWrite(lnkCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return w, func(lnk ipld.Link) error {
+		if _, ok := s.Bag[lnk]; !ok {
+			s.order = append(s.order, lnk.(cidlink.Link).Cid)
+		}
+		return commit(lnk)
+	}, nil
+}
+
+// WriteCAR writes every block into a CARv2 file rooted at the header links
+func (s *carStore) WriteCAR(path string, headerLinks []ipld.Link) error {
+	roots := make([]cid.Cid, len(headerLinks))
+	for i, lnk := range headerLinks {
+		roots[i] = lnk.(cidlink.Link).Cid
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cw, err := export.NewCARWriter(f, roots)
+	if err != nil {
+		return err
+	}
+	for _, c := range s.order {
+		if _, err := cw.Put(c, s.Bag[cidlink.Link{Cid: c}]); err != nil {
+			return err
+		}
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
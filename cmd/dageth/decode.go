@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/multicodec"
+	"github.com/ipld/go-ipld-prime/schema"
+
+	"github.com/vulcanize/go-codec-dageth/beacon_block"
+	"github.com/vulcanize/go-codec-dageth/beacon_block_body"
+	"github.com/vulcanize/go-codec-dageth/beacon_state"
+	"github.com/vulcanize/go-codec-dageth/blob_sidecar"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/log_trie"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/rct_list"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/request"
+	"github.com/vulcanize/go-codec-dageth/request_list"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	snapshot "github.com/vulcanize/go-codec-dageth/snapshot_account"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/tx_list"
+	"github.com/vulcanize/go-codec-dageth/tx_trace"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+// codecNames are the DAG-ETH codecs by package name, in the order they are tried when none is given.
+// Single values come before lists, and lists before trie nodes, since the more general shapes also fit the others.
+var codecNames = []struct {
+	name  string
+	codec uint64
+}{
+	{"header", header.MultiCodecType},
+	{"tx", tx.MultiCodecType},
+	{"rct", rct.MultiCodecType},
+	{"tx_trace", tx_trace.MultiCodecType},
+	{"blob_sidecar", blob_sidecar.MultiCodecType},
+	{"withdrawal", withdrawal.MultiCodecType},
+	{"request", request.MultiCodecType},
+	{"log", log.MultiCodecType},
+	{"state_account", account.MultiCodecType},
+	{"snapshot_account", snapshot.MultiCodecType},
+	{"uncles", uncles.MultiCodecType},
+	{"tx_list", tx_list.MultiCodecType},
+	{"rct_list", rct_list.MultiCodecType},
+	{"request_list", request_list.MultiCodecType},
+	{"state_trie", state_trie.MultiCodecType},
+	{"storage_trie", storage_trie.MultiCodecType},
+	{"tx_trie", tx_trie.MultiCodecType},
+	{"rct_trie", rct_trie.MultiCodecType},
+	{"log_trie", log_trie.MultiCodecType},
+	{"withdrawal_trie", withdrawal_trie.MultiCodecType},
+	{"beacon_block", beacon_block.MultiCodecType},
+	{"beacon_block_body", beacon_block_body.MultiCodecType},
+	{"beacon_state", beacon_state.MultiCodecType},
+}
+
+// codecName returns the package name of the codec, or its code in hex if it is not a DAG-ETH codec
+func codecName(codec uint64) string {
+	for _, c := range codecNames {
+		if c.codec == codec {
+			return c.name
+		}
+	}
+	return fmt.Sprintf("%#x", codec)
+}
+
+// parseCodec parses a codec package name or multicodec code
+func parseCodec(s string) (uint64, error) {
+	for _, c := range codecNames {
+		if c.name == s {
+			return c.codec, nil
+		}
+	}
+	codec, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unknown codec %q", s)
+	}
+	if codecName(codec) == fmt.Sprintf("%#x", codec) {
+		return 0, fmt.Errorf("multicodec type %#x is not a dag-eth codec", codec)
+	}
+	return codec, nil
+}
+
+func decodeCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("decode", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	codecFlag := fs.String("codec", "", "codec package name or multicodec code, detected when not given")
+	format := fs.String("format", "tree", "output format, tree or json")
+	cidFlag := fs.String("cid", "", "CID of the block to read from -store")
+	storeDir := fs.String("store", "", "blockstore directory to read -cid from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "tree" && *format != "json" {
+		return fmt.Errorf("unknown format %q, expected tree or json", *format)
+	}
+
+	var node ipld.Node
+	if *cidFlag != "" || *storeDir != "" {
+		if *cidFlag == "" || *storeDir == "" {
+			return fmt.Errorf("-cid and -store have to be given together")
+		}
+		if *codecFlag != "" || fs.NArg() > 0 {
+			return fmt.Errorf("-cid reads the block and its codec from -store, not from -codec or a file")
+		}
+		c, err := cid.Decode(*cidFlag)
+		if err != nil {
+			return fmt.Errorf("invalid CID %q: %v", *cidFlag, err)
+		}
+		data, err := os.ReadFile(filepath.Join(*storeDir, c.String()))
+		if err != nil {
+			return err
+		}
+		nb, err := newBuilder(c)
+		if err != nil {
+			return err
+		}
+		if err := codecs.DecodeVerified(nb, bytes.NewReader(data), c); err != nil {
+			return err
+		}
+		node = nb.Build()
+	} else {
+		if fs.NArg() > 1 {
+			return fmt.Errorf("expected at most one file, got %v", fs.Args())
+		}
+		data, err := readInput(fs.Arg(0), stdin)
+		if err != nil {
+			return err
+		}
+		if *codecFlag != "" {
+			codec, err := parseCodec(*codecFlag)
+			if err != nil {
+				return err
+			}
+			if node, err = decodeBlock(codec, data); err != nil {
+				return err
+			}
+		} else {
+			codec, others, err := detectCodec(data)
+			if err != nil {
+				return err
+			}
+			if len(others) > 0 {
+				fmt.Fprintf(stderr, "decoded as %s, the block also decodes as %s\n", codecName(codec), strings.Join(others, ", "))
+			} else {
+				fmt.Fprintf(stderr, "decoded as %s\n", codecName(codec))
+			}
+			if node, err = decodeBlock(codec, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *format == "json" {
+		if tn, ok := node.(schema.TypedNode); ok {
+			node = tn.Representation()
+		}
+		if err := dagjson.Encode(node, stdout); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(stdout)
+		return err
+	}
+	return writeTree(stdout, "", node, 0)
+}
+
+// readInput reads the file, or stdin when the path is empty or "-", and decodes it from hex if it is hex
+func readInput(path string, stdin io.Reader) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == "" || path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(bytes.TrimSpace(data)), "0x")
+	if decoded, err := hex.DecodeString(text); err == nil && len(decoded) > 0 {
+		return decoded, nil
+	}
+	return data, nil
+}
+
+// newBuilder returns a builder for the schema type the codecs package loads blocks with the CID into
+func newBuilder(c cid.Cid) (ipld.NodeBuilder, error) {
+	np, err := codecs.NodePrototypeChooser(cidlink.Link{Cid: c}, ipld.LinkContext{})
+	if err != nil {
+		return nil, err
+	}
+	return np.NewBuilder(), nil
+}
+
+// decodeBlock decodes the data with the codec
+func decodeBlock(codec uint64, data []byte) (ipld.Node, error) {
+	c, err := shared.Keccak256ToCid(codec, crypto.Keccak256(data))
+	if err != nil {
+		return nil, err
+	}
+	nb, err := newBuilder(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := codecs.DecodeByCodec(nb, bytes.NewReader(data), codec); err != nil {
+		return nil, fmt.Errorf("unable to decode %s: %v", codecName(codec), err)
+	}
+	return nb.Build(), nil
+}
+
+// detectCodec returns the first codec that decodes the data and encodes it back into the same bytes,
+// along with the names of the other codecs that do
+func detectCodec(data []byte) (uint64, []string, error) {
+	var found []uint64
+	for _, c := range codecNames {
+		node, err := decodeBlock(c.codec, data)
+		if err != nil {
+			continue
+		}
+		encoder, err := multicodec.LookupEncoder(c.codec)
+		if err != nil {
+			return 0, nil, err
+		}
+		buf := new(bytes.Buffer)
+		if err := encoder(node, buf); err != nil || !bytes.Equal(buf.Bytes(), data) {
+			continue
+		}
+		found = append(found, c.codec)
+	}
+	if len(found) == 0 {
+		return 0, nil, fmt.Errorf("no dag-eth codec decodes the input")
+	}
+	others := make([]string, len(found)-1)
+	for i, codec := range found[1:] {
+		others[i] = codecName(codec)
+	}
+	return found[0], others, nil
+}
+
+// writeTree writes the node as an indented tree, one field, element or scalar per line
+func writeTree(w io.Writer, key string, node ipld.Node, depth int) error {
+	line := strings.Repeat("  ", depth)
+	if key != "" {
+		line += key + ": "
+	}
+	if node.IsAbsent() {
+		_, err := fmt.Fprintln(w, line+"absent")
+		return err
+	}
+	switch node.Kind() {
+	case ipld.Kind_Map:
+		if _, err := fmt.Fprintln(w, line+typeName(node, "map")); err != nil {
+			return err
+		}
+		for itr := node.MapIterator(); !itr.Done(); {
+			k, v, err := itr.Next()
+			if err != nil {
+				return err
+			}
+			name, err := k.AsString()
+			if err != nil {
+				return err
+			}
+			if err := writeTree(w, name, v, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ipld.Kind_List:
+		if _, err := fmt.Fprintf(w, "%s%s (%d)\n", line, typeName(node, "list"), node.Length()); err != nil {
+			return err
+		}
+		for itr := node.ListIterator(); !itr.Done(); {
+			i, v, err := itr.Next()
+			if err != nil {
+				return err
+			}
+			if err := writeTree(w, fmt.Sprintf("[%d]", i), v, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		scalar, err := scalarString(node)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, line+scalar)
+		return err
+	}
+}
+
+// typeName returns the schema type name of a typed node, or the fallback for an untyped one.
+// The generated types do not implement Type yet, so the name is taken from the Go type, e.g. *_Header.
+func typeName(node ipld.Node, fallback string) string {
+	if _, ok := node.(schema.TypedNode); !ok {
+		return fallback
+	}
+	typ := reflect.TypeOf(node)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return strings.TrimPrefix(typ.Name(), "_")
+}
+
+// scalarString formats a scalar node, with bytes in 0x prefixed hex and links as their CID
+func scalarString(node ipld.Node) (string, error) {
+	switch node.Kind() {
+	case ipld.Kind_Null:
+		return "null", nil
+	case ipld.Kind_Bool:
+		b, err := node.AsBool()
+		return strconv.FormatBool(b), err
+	case ipld.Kind_Int:
+		i, err := node.AsInt()
+		return strconv.FormatInt(i, 10), err
+	case ipld.Kind_Float:
+		f, err := node.AsFloat()
+		return strconv.FormatFloat(f, 'g', -1, 64), err
+	case ipld.Kind_String:
+		s, err := node.AsString()
+		return strconv.Quote(s), err
+	case ipld.Kind_Bytes:
+		b, err := node.AsBytes()
+		return "0x" + hex.EncodeToString(b), err
+	case ipld.Kind_Link:
+		lnk, err := node.AsLink()
+		if err != nil {
+			return "", err
+		}
+		return lnk.String(), nil
+	default:
+		return "", fmt.Errorf("unexpected %s node", node.Kind())
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/tx"
+)
+
+func decode(t *testing.T, stdin []byte, args ...string) (string, string) {
+	t.Helper()
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	if err := run(append([]string{"decode"}, args...), bytes.NewReader(stdin), stdout, stderr); err != nil {
+		t.Fatalf("unable to decode with %v: %v", args, err)
+	}
+	return stdout.String(), stderr.String()
+}
+
+func TestDecodeDetectsCodec(t *testing.T) {
+	g := testutil.NewGenerator(1)
+	h := g.Header()
+	headerRLP, err := rlp.EncodeToBytes(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, detected := decode(t, headerRLP)
+	if detected != "decoded as header\n" {
+		t.Errorf("expected the header codec to be detected, got %q", detected)
+	}
+	if !strings.HasPrefix(out, "Header\n") {
+		t.Errorf("expected a Header tree, got %q", out)
+	}
+	if !strings.Contains(out, "  StateRootCID: baglacgza") || !strings.Contains(out, "0x"+hex.EncodeToString(h.Extra)) {
+		t.Errorf("expected the tree to hold the state root link and extra data, got %q", out)
+	}
+
+	trx, err := g.Transaction(2).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, detected = decode(t, []byte("0x"+hex.EncodeToString(trx)+"\n"), "-format", "json")
+	if detected != "decoded as tx\n" {
+		t.Errorf("expected the tx codec to be detected in hex input, got %q", detected)
+	}
+	if !strings.Contains(out, `"AccessList"`) || !strings.Contains(out, `"bytes": `) {
+		t.Errorf("expected dag-json of a dynamic fee transaction, got %q", out)
+	}
+
+	accounts := make([][]byte, 16)
+	for i := range accounts {
+		accounts[i], _ = rlp.EncodeToBytes(g.Account())
+	}
+	nodes := g.TrieNodes(accounts)
+	_, detected = decode(t, nodes[0])
+	if !strings.HasPrefix(detected, "decoded as state_trie, the block also decodes as storage_trie") {
+		t.Errorf("expected trie nodes to be detected as state trie nodes, got %q", detected)
+	}
+
+	if err := run([]string{"decode"}, bytes.NewReader([]byte{0x01, 0x02}), io.Discard, io.Discard); err == nil {
+		t.Error("expected detecting the codec of invalid input to fail")
+	}
+}
+
+func TestDecodeWithCodec(t *testing.T) {
+	g := testutil.NewGenerator(2)
+	trx, err := g.Transaction(3).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "tx.bin")
+	if err := os.WriteFile(file, trx, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, codec := range []string{"tx", "0x93", "147"} {
+		out, detected := decode(t, nil, "-codec", codec, file)
+		if detected != "" || !strings.Contains(out, "BlobVersionedHashes: ") {
+			t.Errorf("expected -codec %s to decode a blob transaction, got %q", codec, out)
+		}
+	}
+	for _, args := range [][]string{
+		{"-codec", "header", file},
+		{"-codec", "nope", file},
+		{"-codec", "0x55", file},
+		{"-format", "yaml", file},
+		{file, file},
+	} {
+		if err := run(append([]string{"decode"}, args...), nil, io.Discard, io.Discard); err == nil {
+			t.Errorf("expected decode %v to fail", args)
+		}
+	}
+}
+
+func TestDecodeFromStore(t *testing.T) {
+	g := testutil.NewGenerator(3)
+	headerRLP, err := rlp.EncodeToBytes(g.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := testutil.KeccakCid(t, header.MultiCodecType, crypto.Keccak256(headerRLP))
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, c.String()), headerRLP, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := decode(t, nil, "-cid", c.String(), "-store", dir, "-format", "json")
+	if !strings.Contains(out, `"ParentCID": {`) {
+		t.Errorf("expected dag-json of a header, got %q", out)
+	}
+
+	// a block stored under the CID of another codec or hash is rejected
+	wrong := testutil.KeccakCid(t, state_trie.MultiCodecType, crypto.Keccak256(headerRLP))
+	if err := os.WriteFile(filepath.Join(dir, wrong.String()), headerRLP[1:], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"decode", "-cid", wrong.String(), "-store", dir}, nil, io.Discard, io.Discard); err == nil {
+		t.Error("expected decoding a block that does not match its CID to fail")
+	}
+	for _, args := range [][]string{
+		{"-cid", c.String()},
+		{"-cid", c.String(), "-store", dir, "-codec", "header"},
+		{"-cid", "nope", "-store", dir},
+		{"-cid", testutil.KeccakCid(t, tx.MultiCodecType, crypto.Keccak256(nil)).String(), "-store", dir},
+	} {
+		if err := run(append([]string{"decode"}, args...), nil, io.Discard, io.Discard); err == nil {
+			t.Errorf("expected decode %v to fail", args)
+		}
+	}
+	if err := run([]string{"nope"}, nil, io.Discard, io.Discard); err == nil {
+		t.Error("expected an unknown subcommand to fail")
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Command dageth inspects DAG-ETH IPLD blocks.
+
+	dageth decode [-codec NAME] [-format tree|json] [FILE]
+	dageth decode -cid CID -store DIR [-format tree|json]
+
+The decode subcommand decodes a block and prints it as dag-json, or as an indented tree of the fields of its schema
+type. The block is read from FILE, or stdin when no FILE is given, as raw bytes or as hex with an optional 0x
+prefix. The codec is named by its package, e.g. state_trie, or by its multicodec code, e.g. 0x96. When no codec
+is given every DAG-ETH codec is tried, and the first that decodes the block and encodes it back into the same
+bytes is used, trying headers, transactions and receipts before lists and trie nodes.
+
+With -cid the block is read from a blockstore directory written by dageth-import, checked against the CID,
+and decoded with the codec of the CID.
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// commands are the subcommands by name
+var commands = map[string]func(args []string, stdin io.Reader, stdout, stderr io.Writer) error{
+	"decode": decodeCmd,
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "dageth:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected a subcommand, one of %v", commandNames())
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown subcommand %q, expected one of %v", args[0], commandNames())
+	}
+	return cmd(args[1:], stdin, stdout, stderr)
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,200 @@
+/*
+Package code stores contract bytecode as raw blocks keyed by the keccak256 hash of the code, the CIDs the CodeCID of an
+Account links to, so code dedupes across every account and block of the DAG.
+
+Code beyond the EIP-170 contract size limit, such as initcode up to the EIP-3860 limit, can be split with Chunk at
+instruction boundaries, so that each chunk disassembles on its own, and stored with StoreChunks as raw chunk blocks
+along with a raw index block of their hashes.
+*/
+package code
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+var (
+	MultiCodecType = uint64(cid.Raw)
+	MultiHashType  = uint64(multihash.KECCAK_256)
+)
+
+const (
+	// MaxCodeSize is the EIP-170 limit on the size of deployed contract code
+	MaxCodeSize = params.MaxCodeSize
+	// MaxInitCodeSize is the EIP-3860 limit on the size of the initcode of a contract creation
+	MaxInitCodeSize = params.MaxInitCodeSize
+	// minChunkSize fits the longest instruction, a PUSH32 with its immediate
+	minChunkSize = 33
+)
+
+// Cid returns the CID of the code, which the CodeCID of an Account holding the code links to
+func Cid(code []byte) (cid.Cid, error) {
+	return shared.Keccak256ToCid(MultiCodecType, crypto.Keccak256(code))
+}
+
+// CheckSize checks the code against the EIP-170 limit on deployed code, or the EIP-3860 limit if it is initcode
+func CheckSize(code []byte, initCode bool) error {
+	if initCode {
+		if len(code) > MaxInitCodeSize {
+			return fmt.Errorf("initcode of %d bytes exceeds the limit of %d bytes", len(code), MaxInitCodeSize)
+		}
+		return nil
+	}
+	if len(code) > MaxCodeSize {
+		return fmt.Errorf("code of %d bytes exceeds the limit of %d bytes", len(code), MaxCodeSize)
+	}
+	return nil
+}
+
+// Store writes the code through the LinkSystem as a raw block, and returns its link
+func Store(lsys ipld.LinkSystem, code []byte) (ipld.Link, error) {
+	if lsys.StorageWriteOpener == nil {
+		return nil, fmt.Errorf("no storage configured for writing")
+	}
+	c, err := Cid(code)
+	if err != nil {
+		return nil, err
+	}
+	lnk := cidlink.Link{Cid: c}
+	w, commit, err := lsys.StorageWriteOpener(ipld.LinkContext{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(code); err != nil {
+		return nil, err
+	}
+	if err := commit(lnk); err != nil {
+		return nil, err
+	}
+	return lnk, nil
+}
+
+// Load reads the code the link references through the LinkSystem, and checks it against the hash of the link
+func Load(lsys ipld.LinkSystem, lnk ipld.Link) ([]byte, error) {
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return nil, fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+	}
+	if err := shared.CheckLink(cl.Cid, MultiCodecType, MultiHashType); err != nil {
+		return nil, err
+	}
+	if lsys.StorageReadOpener == nil {
+		return nil, fmt.Errorf("no storage configured for reading")
+	}
+	r, err := lsys.StorageReadOpener(ipld.LinkContext{}, lnk)
+	if err != nil {
+		return nil, err
+	}
+	code, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Cid(code)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Equals(cl.Cid) {
+		return nil, fmt.Errorf("code hashes to CID %s, not the expected CID %s", c.String(), cl.Cid.String())
+	}
+	return code, nil
+}
+
+// AccountLink returns the link to the code of an Account node
+func AccountLink(account ipld.Node) (ipld.Link, error) {
+	codeNode, err := account.LookupByString("CodeCID")
+	if err != nil {
+		return nil, err
+	}
+	return codeNode.AsLink()
+}
+
+// LoadAccount reads the code of an Account node through the LinkSystem
+func LoadAccount(lsys ipld.LinkSystem, account ipld.Node) ([]byte, error) {
+	lnk, err := AccountLink(account)
+	if err != nil {
+		return nil, err
+	}
+	return Load(lsys, lnk)
+}
+
+// Chunk splits the code into chunks of at most size bytes, cutting only at instruction boundaries so the immediate
+// of a PUSH instruction is never split from its opcode. The size has to fit a PUSH32 instruction of 33 bytes.
+func Chunk(code []byte, size int) ([][]byte, error) {
+	if size < minChunkSize {
+		return nil, fmt.Errorf("chunk size %d cannot fit a PUSH32 instruction of %d bytes", size, minChunkSize)
+	}
+	var chunks [][]byte
+	start := 0
+	for pc := 0; pc < len(code); {
+		next := pc + 1
+		if op := vm.OpCode(code[pc]); op >= vm.PUSH1 && op <= vm.PUSH32 {
+			next += int(op-vm.PUSH1) + 1
+		}
+		if next > len(code) {
+			// a truncated PUSH at the end of the code runs to its end
+			next = len(code)
+		}
+		if next-start > size {
+			chunks = append(chunks, code[start:pc])
+			start = pc
+		}
+		pc = next
+	}
+	if start < len(code) {
+		chunks = append(chunks, code[start:])
+	}
+	return chunks, nil
+}
+
+// StoreChunks splits the code with Chunk and writes each chunk through the LinkSystem as a raw block, followed by a
+// raw index block concatenating the keccak256 hashes of the chunks in order, and returns the link to the index
+func StoreChunks(lsys ipld.LinkSystem, code []byte, size int) (ipld.Link, error) {
+	chunks, err := Chunk(code, size)
+	if err != nil {
+		return nil, err
+	}
+	index := make([]byte, 0, len(chunks)*32)
+	for _, chunk := range chunks {
+		if _, err := Store(lsys, chunk); err != nil {
+			return nil, err
+		}
+		index = append(index, crypto.Keccak256(chunk)...)
+	}
+	return Store(lsys, index)
+}
+
+// LoadChunks reads the chunk index the link references and the chunks it lists through the LinkSystem, and returns
+// the code they reassemble into
+func LoadChunks(lsys ipld.LinkSystem, lnk ipld.Link) ([]byte, error) {
+	index, err := Load(lsys, lnk)
+	if err != nil {
+		return nil, err
+	}
+	if len(index)%32 != 0 {
+		return nil, fmt.Errorf("chunk index of %d bytes is not a list of 32 byte hashes", len(index))
+	}
+	code := new(bytes.Buffer)
+	for i := 0; i < len(index); i += 32 {
+		c, err := shared.Keccak256ToCid(MultiCodecType, index[i:i+32])
+		if err != nil {
+			return nil, err
+		}
+		chunk, err := Load(lsys, cidlink.Link{Cid: c})
+		if err != nil {
+			return nil, fmt.Errorf("unable to load chunk %d: %v", i/32, err)
+		}
+		code.Write(chunk)
+	}
+	return code.Bytes(), nil
+}
@@ -0,0 +1,159 @@
+package code_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/code"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+// mockCode repeats PUSH32 and PUSH1 instructions with a few single byte instructions between them
+func mockCode(n int) []byte {
+	var c []byte
+	for len(c) < n {
+		c = append(c, byte(vm.PUSH32))
+		c = append(c, bytes.Repeat([]byte{0xaa}, 32)...)
+		c = append(c, byte(vm.PUSH1), 0x80, byte(vm.MSTORE), byte(vm.JUMPDEST))
+	}
+	return c
+}
+
+func TestStoreAccountCode(t *testing.T) {
+	store := new(storage.Memory)
+	lsys := codecs.NewLinkSystem(store)
+	byteCode := mockCode(1000)
+	lnk, err := code.Store(lsys, byteCode)
+	if err != nil {
+		t.Fatalf("unable to store code: %v", err)
+	}
+
+	// the code is stored under the CID the CodeCID of an account holding it links to
+	codeCID, err := code.Cid(byteCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acct := &types.StateAccount{Balance: uint256.NewInt(1), Root: types.EmptyRootHash, CodeHash: codeCID.Hash()[2:]}
+	acctRLP, err := rlp.EncodeToBytes(acct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb := dageth.Type.Account.NewBuilder()
+	if err := account.DecodeBytes(nb, acctRLP); err != nil {
+		t.Fatalf("unable to decode account: %v", err)
+	}
+	acctNode := nb.Build()
+	if acctLink, err := code.AccountLink(acctNode); err != nil || acctLink != lnk {
+		t.Errorf("expected the account to link to the stored code %s, got %v (%v)", lnk.String(), acctLink, err)
+	}
+	loaded, err := code.LoadAccount(lsys, acctNode)
+	if err != nil {
+		t.Fatalf("unable to load the code of the account: %v", err)
+	}
+	if !bytes.Equal(loaded, byteCode) {
+		t.Errorf("loaded code (%x) does not match the stored code (%x)", loaded, byteCode)
+	}
+
+	// storing the same code again dedupes into the same block
+	if _, err := code.Store(lsys, byteCode); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.Bag) != 1 {
+		t.Errorf("expected a single stored block, got %d", len(store.Bag))
+	}
+	store.Bag[lnk] = byteCode[1:]
+	if _, err := code.Load(lsys, lnk); err == nil {
+		t.Error("expected loading code that does not match its link to fail")
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	if err := code.CheckSize(make([]byte, code.MaxCodeSize), false); err != nil {
+		t.Errorf("expected code at the EIP-170 limit to pass: %v", err)
+	}
+	if err := code.CheckSize(make([]byte, code.MaxCodeSize+1), false); err == nil {
+		t.Error("expected code beyond the EIP-170 limit to fail")
+	}
+	if err := code.CheckSize(make([]byte, code.MaxInitCodeSize), true); err != nil {
+		t.Errorf("expected initcode at the EIP-3860 limit to pass: %v", err)
+	}
+	if err := code.CheckSize(make([]byte, code.MaxInitCodeSize+1), true); err == nil {
+		t.Error("expected initcode beyond the EIP-3860 limit to fail")
+	}
+}
+
+func TestChunks(t *testing.T) {
+	initCode := mockCode(code.MaxInitCodeSize - 40)
+	chunks, err := code.Chunk(initCode, code.MaxCodeSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 {
+		t.Errorf("expected initcode at the EIP-3860 limit to split into 2 chunks, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > code.MaxCodeSize {
+			t.Errorf("chunk %d of %d bytes exceeds the chunk size", i, len(chunk))
+		}
+		// every chunk starts at an instruction of the code
+		if op := vm.OpCode(chunk[0]); op != vm.PUSH32 && op != vm.PUSH1 && op != vm.MSTORE && op != vm.JUMPDEST {
+			t.Errorf("chunk %d starts inside a PUSH immediate with %#x", i, chunk[0])
+		}
+	}
+	if _, err := code.Chunk(initCode, 32); err == nil {
+		t.Error("expected chunks too small for a PUSH32 instruction to fail")
+	}
+
+	store := new(storage.Memory)
+	lsys := codecs.NewLinkSystem(store)
+	lnk, err := code.StoreChunks(lsys, initCode, 1024)
+	if err != nil {
+		t.Fatalf("unable to store chunks: %v", err)
+	}
+	loaded, err := code.LoadChunks(lsys, lnk)
+	if err != nil {
+		t.Fatalf("unable to load chunks: %v", err)
+	}
+	if !bytes.Equal(loaded, initCode) {
+		t.Error("reassembled chunks do not match the initcode")
+	}
+	// the chunks of the index are addressed like any other code
+	for c := range store.Bag {
+		if c.(cidlink.Link).Cid.Prefix().Codec != code.MultiCodecType {
+			t.Errorf("expected chunks to be stored as raw blocks, got %s", c.String())
+		}
+	}
+}
+
+func TestChunkTruncatedPush(t *testing.T) {
+	g := testutil.NewGenerator(1)
+	byteCode := append(g.Bytes(100), byte(vm.PUSH32), 1, 2)
+	chunks, err := code.Chunk(byteCode, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var joined []byte
+	for _, chunk := range chunks {
+		joined = append(joined, chunk...)
+	}
+	if !bytes.Equal(joined, byteCode) {
+		t.Errorf("joined chunks (%x) do not match the code (%x)", joined, byteCode)
+	}
+	codeCID, err := code.Cid(byteCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := code.LoadChunks(codecs.NewLinkSystem(new(storage.Memory)), cidlink.Link{Cid: codeCID}); err == nil {
+		t.Error("expected loading missing chunks to fail")
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Package codecs dispatches to the DAG-ETH codec package matching a multicodec code.
+
+Importing this package also imports, and so registers, every DAG-ETH codec with the
+go-ipld-prime multicodec registry. RegisterAll registers them into a private registry instead, and NewLinkSystem
+returns a LinkSystem loading links of every DAG-ETH type.
+
+The decode and encode benchmarks and fuzz targets of every codec live in this package:
+
+	go test ./codecs -run - -bench . -benchmem
+	go test ./codecs -run - -fuzz FuzzDecodeStateTrie
+*/
+package codecs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/beacon_block"
+	"github.com/vulcanize/go-codec-dageth/beacon_block_body"
+	"github.com/vulcanize/go-codec-dageth/beacon_state"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// DecodeByCodec decodes the input into the provided NodeAssembler using the
+// DAG-ETH codec registered for the given multicodec code.
+// It returns an error if the code is not a DAG-ETH codec.
+func DecodeByCodec(na ipld.NodeAssembler, in io.Reader, codec uint64) error {
+	c, ok := Lookup(codec)
+	if !ok {
+		return fmt.Errorf("multicodec type %#x is not a dag-eth codec", codec)
+	}
+	return c.Decode(na, in)
+}
+
+// DecodeVerified decodes the input into the provided NodeAssembler using the DAG-ETH codec matching the multicodec
+// code of the expected CID, after checking that the input hashes to that CID.
+// It returns an error if the hash does not match, or if the code is not a DAG-ETH codec.
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	codec := expected.Prefix().Codec
+	// the CIDs of SSZ blocks carry their hash tree root, which only their codec can compute
+	switch codec {
+	case beacon_block.MultiCodecType:
+		return beacon_block.DecodeVerified(na, bytes.NewReader(src), expected)
+	case beacon_block_body.MultiCodecType:
+		return beacon_block_body.DecodeVerified(na, bytes.NewReader(src), expected)
+	case beacon_state.MultiCodecType:
+		return beacon_state.DecodeVerified(na, bytes.NewReader(src), expected)
+	}
+	if err := shared.VerifyCID(src, expected, codec); err != nil {
+		return err
+	}
+	return DecodeByCodec(na, bytes.NewReader(src), codec)
+}
@@ -0,0 +1,593 @@
+package codecs_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/multicodec"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/storage"
+	"github.com/ipld/go-ipld-prime/traversal"
+	"github.com/multiformats/go-multihash"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/blob_sidecar"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/log_trie"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/rct_list"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	snapshot "github.com/vulcanize/go-codec-dageth/snapshot_account"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/tx_list"
+	"github.com/vulcanize/go-codec-dageth/tx_trace"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+var (
+	mockAccount = &types.StateAccount{
+		Root:     crypto.Keccak256Hash([]byte{0x80}),
+		Balance:  uint256.NewInt(1000000000),
+		CodeHash: crypto.Keccak256([]byte{}),
+		Nonce:    1,
+	}
+	dagCBORMultiCodecType = uint64(0x71)
+)
+
+func TestDecodeByCodec(t *testing.T) {
+	accountRLP, err := rlp.EncodeToBytes(mockAccount)
+	if err != nil {
+		t.Fatalf("unable to RLP encode state account: %v", err)
+	}
+
+	accountBuilder := dageth.Type.Account.NewBuilder()
+	if err := codecs.DecodeByCodec(accountBuilder, bytes.NewReader(accountRLP), account.MultiCodecType); err != nil {
+		t.Fatalf("unable to decode state account by codec: %v", err)
+	}
+	accountBuf := new(bytes.Buffer)
+	if err := account.Encode(accountBuilder.Build(), accountBuf); err != nil {
+		t.Fatalf("unable to encode state account: %v", err)
+	}
+	if !bytes.Equal(accountBuf.Bytes(), accountRLP) {
+		t.Errorf("state account decoded by codec encoding (%x) does not match the input RLP (%x)", accountBuf.Bytes(), accountRLP)
+	}
+
+	// dag-cbor is registered with the go-ipld-prime multicodec registry, but it is not a dag-eth codec
+	cborBuf := new(bytes.Buffer)
+	if err := dagcbor.Encode(basicnode.NewString("not dag-eth"), cborBuf); err != nil {
+		t.Fatalf("unable to encode dag-cbor node: %v", err)
+	}
+	if err := codecs.DecodeByCodec(basicnode.Prototype.Any.NewBuilder(), cborBuf, dagCBORMultiCodecType); err == nil {
+		t.Error("expected decoding with the dag-cbor multicodec type to fail")
+	}
+}
+
+func TestDecodeVerified(t *testing.T) {
+	accountRLP, err := rlp.EncodeToBytes(mockAccount)
+	if err != nil {
+		t.Fatalf("unable to RLP encode state account: %v", err)
+	}
+	expected := testutil.KeccakCid(t, account.MultiCodecType, crypto.Keccak256(accountRLP))
+	accountBuilder := dageth.Type.Account.NewBuilder()
+	if err := codecs.DecodeVerified(accountBuilder, bytes.NewReader(accountRLP), expected); err != nil {
+		t.Fatalf("unable to decode state account against its CID: %v", err)
+	}
+	nonceNode, err := accountBuilder.Build().LookupByString("Nonce")
+	if err != nil {
+		t.Fatalf("decoded state account is missing Nonce: %v", err)
+	}
+	if nonce, _ := nonceNode.AsBytes(); new(big.Int).SetBytes(nonce).Uint64() != mockAccount.Nonce {
+		t.Errorf("decoded state account nonce (%x) does not match expected nonce (%d)", nonce, mockAccount.Nonce)
+	}
+
+	// the multicodec type of the CID selects the codec, so a state trie CID over the same hash is checked as such
+	if err := codecs.DecodeVerified(dageth.Type.TrieNode.NewBuilder(), bytes.NewReader(accountRLP), testutil.KeccakCid(t, state_trie.MultiCodecType, expected.Hash()[2:])); err == nil {
+		t.Error("expected an error decoding a state account as a state trie node")
+	}
+	if err := codecs.DecodeVerified(dageth.Type.Account.NewBuilder(), bytes.NewReader(accountRLP[:len(accountRLP)-1]), expected); err == nil {
+		t.Error("expected an error decoding a truncated state account against its CID")
+	}
+}
+
+func TestRegisterAll(t *testing.T) {
+	var reg multicodec.Registry
+	codecs.RegisterAll(&reg)
+	if decoders := reg.ListDecoders(); len(decoders) != len(codecs.Codecs) {
+		t.Errorf("expected %d decoders registered, got %d", len(codecs.Codecs), len(decoders))
+	}
+	names := make(map[string]bool)
+	for i, c := range codecs.Codecs {
+		if i > 0 && c.Code <= codecs.Codecs[i-1].Code {
+			t.Errorf("codec %s (%#x) is not listed after %s (%#x) in multicodec code order", c.Name, c.Code, codecs.Codecs[i-1].Name, codecs.Codecs[i-1].Code)
+		}
+		if names[c.Name] {
+			t.Errorf("codec name %s is listed twice", c.Name)
+		}
+		names[c.Name] = true
+		if lookedUp, ok := codecs.Lookup(c.Code); !ok || lookedUp.Name != c.Name {
+			t.Errorf("expected multicodec type %#x to look up codec %s, got %s", c.Code, c.Name, lookedUp.Name)
+		}
+		if _, err := reg.LookupEncoder(c.Code); err != nil {
+			t.Errorf("%s encoder is not registered: %v", c.Name, err)
+		}
+	}
+	if _, ok := codecs.Lookup(dagCBORMultiCodecType); ok {
+		t.Error("expected dag-cbor not to be a dag-eth codec")
+	}
+
+	accountRLP, err := rlp.EncodeToBytes(mockAccount)
+	if err != nil {
+		t.Fatalf("unable to RLP encode state account: %v", err)
+	}
+	decoder, err := reg.LookupDecoder(account.MultiCodecType)
+	if err != nil {
+		t.Fatalf("state account decoder is not registered: %v", err)
+	}
+	accountBuilder := dageth.Type.Account.NewBuilder()
+	if err := decoder(accountBuilder, bytes.NewReader(accountRLP)); err != nil {
+		t.Fatalf("unable to decode state account with the registered decoder: %v", err)
+	}
+	encoder, _ := reg.LookupEncoder(account.MultiCodecType)
+	accountBuf := new(bytes.Buffer)
+	if err := encoder(accountBuilder.Build(), accountBuf); err != nil || !bytes.Equal(accountBuf.Bytes(), accountRLP) {
+		t.Errorf("state account encoding (%x) with the registered encoder does not match the input RLP (%x): %v", accountBuf.Bytes(), accountRLP, err)
+	}
+}
+
+func TestNewLinkSystem(t *testing.T) {
+	accountRLP, err := rlp.EncodeToBytes(mockAccount)
+	if err != nil {
+		t.Fatalf("unable to RLP encode state account: %v", err)
+	}
+	accountBuilder := dageth.Type.Account.NewBuilder()
+	if err := account.DecodeBytes(accountBuilder, accountRLP); err != nil {
+		t.Fatalf("unable to decode state account: %v", err)
+	}
+
+	lsys := codecs.NewLinkSystem(&storage.Memory{})
+	lp := cidlink.LinkPrototype{Prefix: cid.Prefix{
+		Version:  1,
+		Codec:    account.MultiCodecType,
+		MhType:   account.MultiHashType,
+		MhLength: -1,
+	}}
+	lnk, err := lsys.Store(ipld.LinkContext{}, lp, accountBuilder.Build())
+	if err != nil {
+		t.Fatalf("unable to store state account: %v", err)
+	}
+	decodedMh, err := multihash.Decode(lnk.(cidlink.Link).Hash())
+	if err != nil {
+		t.Fatalf("unable to decode state account multihash: %v", err)
+	}
+	if expectedHash := crypto.Keccak256(accountRLP); !bytes.Equal(decodedMh.Digest, expectedHash) {
+		t.Errorf("state account link hash (%x) does not match expected hash (%x)", decodedMh.Digest, expectedHash)
+	}
+
+	np, err := codecs.NodePrototypeChooser(lnk, ipld.LinkContext{})
+	if err != nil {
+		t.Fatalf("unable to choose node prototype for state account link: %v", err)
+	}
+	if np != dageth.Type.Account {
+		t.Fatalf("expected state account link to load as an Account, got %T", np)
+	}
+	node, err := lsys.Load(ipld.LinkContext{}, lnk, np)
+	if err != nil {
+		t.Fatalf("unable to load state account: %v", err)
+	}
+	nonceNode, err := node.LookupByString("Nonce")
+	if err != nil {
+		t.Fatalf("loaded state account is missing Nonce: %v", err)
+	}
+	nonceBytes, err := nonceNode.AsBytes()
+	if err != nil {
+		t.Fatalf("loaded state account Nonce should be of type Bytes: %v", err)
+	}
+	if nonce := binary.BigEndian.Uint64(nonceBytes); nonce != mockAccount.Nonce {
+		t.Errorf("loaded state account nonce (%d) does not match expected nonce (%d)", nonce, mockAccount.Nonce)
+	}
+}
+
+func TestAddSupportToChooserTraversal(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	putRLP := func(codec uint64, raw []byte) common.Hash {
+		store.Bag[testutil.KeccakLink(t, codec, crypto.Keccak256(raw))] = raw
+		return crypto.Keccak256Hash(raw)
+	}
+
+	storageVal, _ := rlp.EncodeToBytes([]byte{1, 2, 3, 4, 5})
+	storageLeafRLP, _ := rlp.EncodeToBytes([]interface{}{shared.HexToCompact([]byte{1, 2, 3, 16}), storageVal})
+	storageAccount := *mockAccount
+	storageAccount.Root = putRLP(storage_trie.MultiCodecType, storageLeafRLP)
+	accountRLP, _ := rlp.EncodeToBytes(&storageAccount)
+	stateLeafRLP, _ := rlp.EncodeToBytes([]interface{}{shared.HexToCompact([]byte{4, 5, 6, 16}), accountRLP})
+	headerRLP, _ := rlp.EncodeToBytes(&types.Header{
+		Root:       putRLP(state_trie.MultiCodecType, stateLeafRLP),
+		Difficulty: big.NewInt(1),
+		Number:     big.NewInt(1),
+	})
+	headerBuilder := dageth.Type.Header.NewBuilder()
+	if err := header.DecodeBytes(headerBuilder, headerRLP); err != nil {
+		t.Fatalf("unable to decode header: %v", err)
+	}
+
+	// fall back to a chooser that rejects everything, so only the dag-eth prototypes can resolve the links
+	var chosen []ipld.NodePrototype
+	chooser := codecs.AddSupportToChooser(func(lnk ipld.Link, _ ipld.LinkContext) (ipld.NodePrototype, error) {
+		return nil, fmt.Errorf("unexpected link %s", lnk)
+	})
+	prog := traversal.Progress{Cfg: &traversal.Config{
+		LinkSystem: codecs.NewLinkSystem(store),
+		LinkTargetNodePrototypeChooser: func(lnk ipld.Link, lnkCtx ipld.LinkContext) (ipld.NodePrototype, error) {
+			np, err := chooser(lnk, lnkCtx)
+			chosen = append(chosen, np)
+			return np, err
+		},
+	}}
+	path := ipld.ParsePath("StateRootCID/TrieLeafNode/Value/Account/StorageRootCID/TrieLeafNode/Value/Bytes")
+	if err := prog.Focus(headerBuilder.Build(), path, func(_ traversal.Progress, n ipld.Node) error {
+		val, err := n.AsBytes()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(val, storageVal) {
+			t.Errorf("storage leaf value (%x) does not match expected value (%x)", val, storageVal)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("unable to traverse from header to storage leaf: %v", err)
+	}
+	if len(chosen) != 2 || chosen[0] != dageth.Type.TrieNode || chosen[1] != dageth.Type.TrieNode {
+		t.Errorf("expected the state and storage trie links to load as TrieNodes, got %v", chosen)
+	}
+}
+
+func TestStateAccountTraversal(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	byteCode := []byte{0x60, 0x80, 0x60, 0x40, 0x52}
+	store.Bag[testutil.KeccakLink(t, cid.Raw, crypto.Keccak256(byteCode))] = byteCode
+	storageVal, _ := rlp.EncodeToBytes([]byte{1, 2, 3, 4, 5})
+	storageLeafRLP, _ := rlp.EncodeToBytes([]interface{}{shared.HexToCompact([]byte{1, 2, 3, 16}), storageVal})
+	store.Bag[testutil.KeccakLink(t, storage_trie.MultiCodecType, crypto.Keccak256(storageLeafRLP))] = storageLeafRLP
+
+	contractAccount := *mockAccount
+	contractAccount.Root = crypto.Keccak256Hash(storageLeafRLP)
+	contractAccount.CodeHash = crypto.Keccak256(byteCode)
+	accountRLP, _ := rlp.EncodeToBytes(&contractAccount)
+	stateLeafRLP, _ := rlp.EncodeToBytes([]interface{}{shared.HexToCompact([]byte{4, 5, 6, 16}), accountRLP})
+	stateLeafBuilder := dageth.Type.TrieNode.NewBuilder()
+	if err := state_trie.DecodeBytes(stateLeafBuilder, stateLeafRLP); err != nil {
+		t.Fatalf("unable to decode state trie leaf: %v", err)
+	}
+
+	// the leaf value is expanded into an Account, so a traversal can continue from it without decoding it by hand
+	prog := traversal.Progress{Cfg: &traversal.Config{
+		LinkSystem:                     codecs.NewLinkSystem(store),
+		LinkTargetNodePrototypeChooser: codecs.NodePrototypeChooser,
+	}}
+	for pathStr, expected := range map[string][]byte{
+		"TrieLeafNode/Value/Account/CodeCID":                                 byteCode,
+		"TrieLeafNode/Value/Account/StorageRootCID/TrieLeafNode/Value/Bytes": storageVal,
+	} {
+		if err := prog.Focus(stateLeafBuilder.Build(), ipld.ParsePath(pathStr), func(_ traversal.Progress, n ipld.Node) error {
+			val, err := n.AsBytes()
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(val, expected) {
+				t.Errorf("%s (%x) does not match expected value (%x)", pathStr, val, expected)
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("unable to traverse %s from the state trie leaf: %v", pathStr, err)
+		}
+	}
+}
+
+// chainFixture is the consensus encoding of a mainnet shaped block of a DAG-ETH codec
+type chainFixture struct {
+	name  string
+	codec uint64
+	enc   []byte
+}
+
+func chainFixtures(tb testing.TB) []chainFixture {
+	mustRLP := func(val interface{}) []byte {
+		enc, err := rlp.EncodeToBytes(val)
+		if err != nil {
+			tb.Fatalf("unable to RLP encode chain fixture: %v", err)
+		}
+		return enc
+	}
+	// leaf builds a trie leaf node holding the value under an odd length path, as leaves deep in a trie have
+	leaf := func(val []byte) []byte {
+		return mustRLP([]interface{}{append([]byte{0x31}, shared.RandomBytes(30)...), val})
+	}
+	// fullBranch builds a branch node with all 16 children set, as nodes near the root of mainnet tries are
+	fullBranch := mustRLP(func() []interface{} {
+		fields := make([]interface{}, 17)
+		for i := 0; i < 16; i++ {
+			fields[i] = shared.RandomHash().Bytes()
+		}
+		fields[16] = []byte{}
+		return fields
+	}())
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		tb.Fatalf("unable to generate key: %v", err)
+	}
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	to := shared.RandomAddr()
+	txs := make(types.Transactions, 150)
+	for i := range txs {
+		txs[i] = types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			Nonce:     uint64(i),
+			GasTipCap: big.NewInt(2_000_000_000),
+			GasFeeCap: big.NewInt(40_000_000_000),
+			Gas:       65_000,
+			To:        &to,
+			Value:     big.NewInt(0),
+			// ERC-20 transfer(address,uint256) call data
+			Data: shared.RandomBytes(68),
+			AccessList: types.AccessList{
+				{Address: shared.RandomAddr(), StorageKeys: []common.Hash{shared.RandomHash(), shared.RandomHash()}},
+			},
+		})
+	}
+	txEnc, err := txs[0].MarshalBinary()
+	if err != nil {
+		tb.Fatalf("unable to encode transaction: %v", err)
+	}
+
+	newLogs := func(n int) []*types.Log {
+		logs := make([]*types.Log, n)
+		for i := range logs {
+			logs[i] = &types.Log{
+				Address: shared.RandomAddr(),
+				Topics:  []common.Hash{shared.RandomHash(), shared.RandomHash(), shared.RandomHash()},
+				Data:    shared.RandomBytes(32),
+			}
+		}
+		return logs
+	}
+	newReceipt := func(logs []*types.Log) *types.Receipt {
+		receipt := &types.Receipt{
+			Type:              types.DynamicFeeTxType,
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 12_345_678,
+			Logs:              logs,
+		}
+		receipt.Bloom = types.CreateBloom(receipt)
+		return receipt
+	}
+	receipt := newReceipt(newLogs(100))
+	rctEnc, err := receipt.MarshalBinary()
+	if err != nil {
+		tb.Fatalf("unable to encode receipt: %v", err)
+	}
+	rcts := make(types.Receipts, 150)
+	for i := range rcts {
+		rcts[i] = newReceipt(newLogs(3))
+	}
+	logEnc := mustRLP(receipt.Logs[0])
+
+	baseFee := big.NewInt(7_000_000_000)
+	blobGasUsed, excessBlobGas := uint64(393_216), uint64(0)
+	withdrawalsHash, parentBeaconRoot := shared.RandomHash(), shared.RandomHash()
+	newHeader := func() *types.Header {
+		return &types.Header{
+			ParentHash:       shared.RandomHash(),
+			UncleHash:        types.EmptyUncleHash,
+			Coinbase:         shared.RandomAddr(),
+			Root:             shared.RandomHash(),
+			TxHash:           shared.RandomHash(),
+			ReceiptHash:      shared.RandomHash(),
+			Difficulty:       big.NewInt(0),
+			Number:           big.NewInt(20_000_000),
+			GasLimit:         30_000_000,
+			GasUsed:          12_345_678,
+			Time:             1_717_000_000,
+			Extra:            shared.RandomBytes(32),
+			MixDigest:        shared.RandomHash(),
+			BaseFee:          baseFee,
+			WithdrawalsHash:  &withdrawalsHash,
+			BlobGasUsed:      &blobGasUsed,
+			ExcessBlobGas:    &excessBlobGas,
+			ParentBeaconRoot: &parentBeaconRoot,
+		}
+	}
+
+	withdrawalEnc := mustRLP(&types.Withdrawal{Index: 40_000_000, Validator: 1_000_000, Address: shared.RandomAddr(), Amount: 18_000_000})
+	accountEnc := mustRLP(mockAccount)
+	frames := make([]tx_trace.Frame, 50)
+	for i := range frames {
+		frames[i] = tx_trace.Frame{
+			Op:     vm.CALL,
+			From:   shared.RandomAddr(),
+			To:     shared.RandomAddr(),
+			Input:  shared.RandomBytes(68),
+			Output: shared.RandomBytes(32),
+			Gas:    100_000,
+			Cost:   2_600,
+			Value:  big.NewInt(0),
+		}
+	}
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{{1}},
+		Commitments: []kzg4844.Commitment{{0xc0, 1}},
+		Proofs:      []kzg4844.Proof{{0xc0, 2}},
+	}
+
+	return []chainFixture{
+		{"header", header.MultiCodecType, mustRLP(newHeader())},
+		{"uncles", uncles.MultiCodecType, mustRLP([]*types.Header{newHeader(), newHeader()})},
+		{"tx", tx.MultiCodecType, txEnc},
+		{"tx_list", tx_list.MultiCodecType, mustRLP(txs)},
+		{"tx_trie/branch", tx_trie.MultiCodecType, fullBranch},
+		{"tx_trie/leaf", tx_trie.MultiCodecType, leaf(txEnc)},
+		{"rct", rct.MultiCodecType, rctEnc},
+		{"rct_list", rct_list.MultiCodecType, mustRLP(rcts)},
+		{"rct_trie/branch", rct_trie.MultiCodecType, fullBranch},
+		{"rct_trie/leaf", rct_trie.MultiCodecType, leaf(rctEnc)},
+		{"log", log.MultiCodecType, logEnc},
+		{"log_trie/leaf", log_trie.MultiCodecType, leaf(logEnc)},
+		{"state_account", account.MultiCodecType, accountEnc},
+		{"state_trie/branch", state_trie.MultiCodecType, fullBranch},
+		{"state_trie/leaf", state_trie.MultiCodecType, leaf(accountEnc)},
+		{"storage_trie/branch", storage_trie.MultiCodecType, fullBranch},
+		{"storage_trie/leaf", storage_trie.MultiCodecType, leaf(mustRLP(shared.RandomHash().Bytes()))},
+		{"withdrawal", withdrawal.MultiCodecType, withdrawalEnc},
+		{"withdrawal_trie/leaf", withdrawal_trie.MultiCodecType, leaf(withdrawalEnc)},
+		{"tx_trace", tx_trace.MultiCodecType, mustRLP(tx_trace.TxTrace{
+			TxHashes:  []common.Hash{txs[0].Hash()},
+			StateRoot: shared.RandomHash(),
+			Result:    shared.RandomBytes(32),
+			Frames:    frames,
+			Gas:       1_100_000,
+		})},
+		{"blob_sidecar", blob_sidecar.MultiCodecType, mustRLP(sidecar)},
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	for _, fixture := range chainFixtures(b) {
+		fixture := fixture
+		np := testutil.NodePrototype(b, testutil.KeccakLink(b, fixture.codec, crypto.Keccak256()))
+		b.Run(fixture.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(fixture.enc)))
+			for i := 0; i < b.N; i++ {
+				if err := codecs.DecodeByCodec(np.NewBuilder(), bytes.NewReader(fixture.enc), fixture.codec); err != nil {
+					b.Fatalf("unable to decode %s: %v", fixture.name, err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	for _, fixture := range chainFixtures(b) {
+		fixture := fixture
+		nb := testutil.NodePrototype(b, testutil.KeccakLink(b, fixture.codec, crypto.Keccak256())).NewBuilder()
+		if err := codecs.DecodeByCodec(nb, bytes.NewReader(fixture.enc), fixture.codec); err != nil {
+			b.Fatalf("unable to decode %s: %v", fixture.name, err)
+		}
+		node := nb.Build()
+		encoder, err := multicodec.LookupEncoder(fixture.codec)
+		if err != nil {
+			b.Fatalf("no encoder registered for %s: %v", fixture.name, err)
+		}
+		b.Run(fixture.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(fixture.enc)))
+			for i := 0; i < b.N; i++ {
+				if err := encoder(node, io.Discard); err != nil {
+					b.Fatalf("unable to encode %s: %v", fixture.name, err)
+				}
+			}
+		})
+	}
+}
+
+// fuzzDecode seeds the fuzzer with the chain fixtures of the codec and checks that decoding arbitrary input
+// never panics, and that whatever decodes encodes into bytes that decode and encode back to the same bytes
+func fuzzDecode(f *testing.F, codec uint64, seeds ...[]byte) {
+	for _, fixture := range chainFixtures(f) {
+		if fixture.codec == codec {
+			f.Add(fixture.enc)
+		}
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	np := testutil.NodePrototype(f, testutil.KeccakLink(f, codec, crypto.Keccak256()))
+	encoder, err := multicodec.LookupEncoder(codec)
+	if err != nil {
+		f.Fatalf("no encoder registered for multicodec type %#x: %v", codec, err)
+	}
+	roundTrip := func(t *testing.T, data []byte) ([]byte, error) {
+		nb := np.NewBuilder()
+		if err := codecs.DecodeByCodec(nb, bytes.NewReader(data), codec); err != nil {
+			return nil, err
+		}
+		buf := new(bytes.Buffer)
+		if err := encoder(nb.Build(), buf); err != nil {
+			t.Fatalf("unable to encode decoded input %x: %v", data, err)
+		}
+		return buf.Bytes(), nil
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		enc, err := roundTrip(t, data)
+		if err != nil {
+			return
+		}
+		reenc, err := roundTrip(t, enc)
+		if err != nil {
+			t.Fatalf("unable to decode encoding %x of decoded input %x: %v", enc, data, err)
+		}
+		if !bytes.Equal(reenc, enc) {
+			t.Fatalf("encoding %x of decoded input %x is not stable, it re-encodes to %x", enc, data, reenc)
+		}
+	})
+}
+
+func FuzzDecodeHeader(f *testing.F) {
+	blockRLP, err := os.ReadFile("../header/block1_rlp")
+	if err != nil {
+		f.Fatalf("unable to read mainnet block fixture: %v", err)
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(blockRLP, block); err != nil {
+		f.Fatalf("unable to decode mainnet block fixture: %v", err)
+	}
+	headerRLP, err := rlp.EncodeToBytes(block.Header())
+	if err != nil {
+		f.Fatalf("unable to encode mainnet header fixture: %v", err)
+	}
+	fuzzDecode(f, header.MultiCodecType, headerRLP)
+}
+
+func FuzzDecodeUncles(f *testing.F)          { fuzzDecode(f, uncles.MultiCodecType) }
+func FuzzDecodeTx(f *testing.F)              { fuzzDecode(f, tx.MultiCodecType) }
+func FuzzDecodeTxList(f *testing.F)          { fuzzDecode(f, tx_list.MultiCodecType) }
+func FuzzDecodeTxTrie(f *testing.F)          { fuzzDecode(f, tx_trie.MultiCodecType) }
+func FuzzDecodeReceipt(f *testing.F)         { fuzzDecode(f, rct.MultiCodecType) }
+func FuzzDecodeReceiptList(f *testing.F)     { fuzzDecode(f, rct_list.MultiCodecType) }
+func FuzzDecodeReceiptTrie(f *testing.F)     { fuzzDecode(f, rct_trie.MultiCodecType) }
+func FuzzDecodeLog(f *testing.F)             { fuzzDecode(f, log.MultiCodecType) }
+func FuzzDecodeLogTrie(f *testing.F)         { fuzzDecode(f, log_trie.MultiCodecType) }
+func FuzzDecodeStateAccount(f *testing.F)    { fuzzDecode(f, account.MultiCodecType) }
+func FuzzDecodeStateTrie(f *testing.F)       { fuzzDecode(f, state_trie.MultiCodecType) }
+func FuzzDecodeStorageTrie(f *testing.F)     { fuzzDecode(f, storage_trie.MultiCodecType) }
+func FuzzDecodeWithdrawal(f *testing.F)      { fuzzDecode(f, withdrawal.MultiCodecType) }
+func FuzzDecodeWithdrawalTrie(f *testing.F)  { fuzzDecode(f, withdrawal_trie.MultiCodecType) }
+func FuzzDecodeTxTrace(f *testing.F)         { fuzzDecode(f, tx_trace.MultiCodecType) }
+func FuzzDecodeBlobSidecar(f *testing.F)     { fuzzDecode(f, blob_sidecar.MultiCodecType) }
+func FuzzDecodeSnapshotAccount(f *testing.F) { fuzzDecode(f, snapshot.MultiCodecType) }
@@ -0,0 +1,95 @@
+package codecs
+
+import (
+	"io"
+
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal"
+
+	// registers the raw codec used by the CodeCID links of state accounts
+	_ "github.com/ipld/go-ipld-prime/codec/raw"
+	// registers the KECCAK_256 hasher used by every dag-eth codec
+	_ "github.com/multiformats/go-multihash/register/sha3"
+
+	"github.com/vulcanize/go-codec-dageth/beacon_block"
+	"github.com/vulcanize/go-codec-dageth/beacon_block_body"
+	"github.com/vulcanize/go-codec-dageth/beacon_state"
+	"github.com/vulcanize/go-codec-dageth/blob_sidecar"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/log_trie"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/rct_list"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/request"
+	"github.com/vulcanize/go-codec-dageth/request_list"
+	snapshot "github.com/vulcanize/go-codec-dageth/snapshot_account"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/tx_list"
+	"github.com/vulcanize/go-codec-dageth/tx_trace"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+// Storage is the block storage a LinkSystem reads from and writes to, e.g. go-ipld-prime's storage.Memory
+type Storage interface {
+	OpenRead(lnkCtx ipld.LinkContext, lnk ipld.Link) (io.Reader, error)
+	OpenWrite(lnkCtx ipld.LinkContext) (io.Writer, ipld.BlockWriteCommitter, error)
+}
+
+// NewLinkSystem returns an ipld.LinkSystem backed by the provided storage, with every dag-eth
+// encoder and decoder, the raw codec for contract bytecode, and the KECCAK_256 hasher available.
+// The LinkSystem does not carry a node prototype chooser, use NodePrototypeChooser in the traversal.Config
+// to load links into the dag-eth schema types.
+func NewLinkSystem(store Storage) ipld.LinkSystem {
+	lsys := cidlink.DefaultLinkSystem()
+	lsys.StorageReadOpener = store.OpenRead
+	lsys.StorageWriteOpener = store.OpenWrite
+	return lsys
+}
+
+// NodePrototypeChooser selects the dag-eth schema type for links with a dag-eth multicodec code,
+// and falls back to basicnode.Prototype.Any for any other link.
+var NodePrototypeChooser = AddSupportToChooser(func(ipld.Link, ipld.LinkContext) (ipld.NodePrototype, error) {
+	return basicnode.Prototype.Any, nil
+})
+
+// AddSupportToChooser takes an existing node prototype chooser and subs in
+// the dag-eth schema types for every dag-eth multicodec code.
+func AddSupportToChooser(existing traversal.LinkTargetNodePrototypeChooser) traversal.LinkTargetNodePrototypeChooser {
+	for _, addSupport := range []func(traversal.LinkTargetNodePrototypeChooser) traversal.LinkTargetNodePrototypeChooser{
+		header.AddSupportToChooser,
+		uncles.AddSupportToChooser,
+		tx_trie.AddSupportToChooser,
+		tx.AddSupportToChooser,
+		rct_trie.AddSupportToChooser,
+		rct.AddSupportToChooser,
+		state_trie.AddSupportToChooser,
+		account.AddSupportToChooser,
+		storage_trie.AddSupportToChooser,
+		log_trie.AddSupportToChooser,
+		log.AddSupportToChooser,
+		tx_trace.AddSupportToChooser,
+		tx_list.AddSupportToChooser,
+		rct_list.AddSupportToChooser,
+		blob_sidecar.AddSupportToChooser,
+		withdrawal_trie.AddSupportToChooser,
+		withdrawal.AddSupportToChooser,
+		request.AddSupportToChooser,
+		request_list.AddSupportToChooser,
+		snapshot.AddSupportToChooser,
+		beacon_block.AddSupportToChooser,
+		beacon_block_body.AddSupportToChooser,
+		beacon_state.AddSupportToChooser,
+	} {
+		existing = addSupport(existing)
+	}
+	return existing
+}
@@ -0,0 +1,89 @@
+package codecs
+
+import (
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/multicodec"
+
+	"github.com/vulcanize/go-codec-dageth/beacon_block"
+	"github.com/vulcanize/go-codec-dageth/beacon_block_body"
+	"github.com/vulcanize/go-codec-dageth/beacon_state"
+	"github.com/vulcanize/go-codec-dageth/blob_sidecar"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/log_trie"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/rct_list"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/request"
+	"github.com/vulcanize/go-codec-dageth/request_list"
+	snapshot "github.com/vulcanize/go-codec-dageth/snapshot_account"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/tx_list"
+	"github.com/vulcanize/go-codec-dageth/tx_trace"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+// Codec is a DAG-ETH codec: its multicodec code, the name of its package, and its decoder and encoder
+type Codec struct {
+	Code   uint64
+	Name   string
+	Decode ipld.Decoder
+	Encode ipld.Encoder
+}
+
+// Codecs lists every DAG-ETH codec, in the order of their multicodec codes
+var Codecs = []Codec{
+	{header.MultiCodecType, "header", header.Decode, header.Encode},
+	{uncles.MultiCodecType, "uncles", uncles.Decode, uncles.Encode},
+	{tx_trie.MultiCodecType, "tx_trie", tx_trie.Decode, tx_trie.Encode},
+	{tx.MultiCodecType, "tx", tx.Decode, tx.Encode},
+	{rct_trie.MultiCodecType, "rct_trie", rct_trie.Decode, rct_trie.Encode},
+	{rct.MultiCodecType, "rct", rct.Decode, rct.Encode},
+	{state_trie.MultiCodecType, "state_trie", state_trie.Decode, state_trie.Encode},
+	{account.MultiCodecType, "state_account", account.Decode, account.Encode},
+	{storage_trie.MultiCodecType, "storage_trie", storage_trie.Decode, storage_trie.Encode},
+	{log_trie.MultiCodecType, "log_trie", log_trie.Decode, log_trie.Encode},
+	{log.MultiCodecType, "log", log.Decode, log.Encode},
+	{tx_trace.MultiCodecType, "tx_trace", tx_trace.Decode, tx_trace.Encode},
+	{tx_list.MultiCodecType, "tx_list", tx_list.Decode, tx_list.Encode},
+	{rct_list.MultiCodecType, "rct_list", rct_list.Decode, rct_list.Encode},
+	{blob_sidecar.MultiCodecType, "blob_sidecar", blob_sidecar.Decode, blob_sidecar.Encode},
+	{withdrawal_trie.MultiCodecType, "withdrawal_trie", withdrawal_trie.Decode, withdrawal_trie.Encode},
+	{withdrawal.MultiCodecType, "withdrawal", withdrawal.Decode, withdrawal.Encode},
+	{request.MultiCodecType, "request", request.Decode, request.Encode},
+	{request_list.MultiCodecType, "request_list", request_list.Decode, request_list.Encode},
+	{snapshot.MultiCodecType, "snapshot_account", snapshot.Decode, snapshot.Encode},
+	{beacon_block.MultiCodecType, "beacon_block", beacon_block.Decode, beacon_block.Encode},
+	{beacon_block_body.MultiCodecType, "beacon_block_body", beacon_block_body.Decode, beacon_block_body.Encode},
+	{beacon_state.MultiCodecType, "beacon_state", beacon_state.Decode, beacon_state.Encode},
+}
+
+var codecsByCode = func() map[uint64]Codec {
+	m := make(map[uint64]Codec, len(Codecs))
+	for _, c := range Codecs {
+		m[c.Code] = c
+	}
+	return m
+}()
+
+// Lookup returns the DAG-ETH codec of the multicodec code, and whether the code is a DAG-ETH codec
+func Lookup(code uint64) (Codec, bool) {
+	c, ok := codecsByCode[code]
+	return c, ok
+}
+
+// RegisterAll registers the decoder and encoder of every DAG-ETH codec into the registry.
+// The codec packages register themselves into the global go-ipld-prime registry when imported, embedders using
+// private registries opt in with this instead.
+func RegisterAll(reg *multicodec.Registry) {
+	for _, c := range Codecs {
+		reg.RegisterDecoder(c.Code, c.Decode)
+		reg.RegisterEncoder(c.Code, c.Encode)
+	}
+}
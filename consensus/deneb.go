@@ -0,0 +1,326 @@
+/*
+Package consensus defines the SSZ types of the Ethereum consensus layer containers, as of the Deneb fork with the
+mainnet preset, for the beacon_block, beacon_block_body and beacon_state codecs, along with the Electra execution
+requests whose serializations are the data of the EIP-7685 requests of the request codec.
+
+The roots a container holds of other DAG-ETH blocks are typed as ssz.Root, so they decode into links: beacon block
+roots into beacon block CIDs, beacon state roots into beacon state CIDs, and the execution block hashes and trie
+roots of execution payloads into the keccak256 CIDs of the execution layer blocks they commit to. Roots of data that
+is not addressed as a block of its own, e.g. the deposit root, stay bytes.
+*/
+package consensus
+
+import (
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/ssz"
+)
+
+const (
+	beaconBlockMulticodec     = uint64(0x01a0) // Proposed
+	beaconBlockBodyMulticodec = uint64(0x01a1) // Proposed
+	beaconStateMulticodec     = uint64(0x01a2) // Proposed
+)
+
+// Mainnet preset values of the Deneb containers
+const (
+	MaxValidatorsPerCommittee  = 2048
+	MaxProposerSlashings       = 16
+	MaxAttesterSlashings       = 2
+	MaxAttestations            = 128
+	MaxDeposits                = 16
+	MaxVoluntaryExits          = 16
+	MaxBLSToExecutionChanges   = 16
+	MaxBlobCommitmentsPerBlock = 4096
+	MaxWithdrawalsPerPayload   = 16
+	MaxBytesPerTransaction     = 1 << 30
+	MaxTransactionsPerPayload  = 1 << 20
+	BytesPerLogsBloom          = 256
+	MaxExtraDataBytes          = 32
+	DepositContractTreeDepth   = 32
+	SyncCommitteeSize          = 512
+	SlotsPerHistoricalRoot     = 8192
+	HistoricalRootsLimit       = 1 << 24
+	EpochsPerEth1VotingPeriod  = 64
+	SlotsPerEpoch              = 32
+	ValidatorRegistryLimit     = 1 << 40
+	EpochsPerHistoricalVector  = 65536
+	EpochsPerSlashingsVector   = 8192
+	JustificationBitsLength    = 4
+	eth1DataVotesLimit         = EpochsPerEth1VotingPeriod * SlotsPerEpoch
+	depositProofLength         = DepositContractTreeDepth + 1
+	bytesPerBLSPubkey          = 48
+	bytesPerBLSSignature       = 96
+	bytesPerExecutionAddress   = 20
+	bytesPerVersion            = 4
+	bytesPerKZGCommitment      = 48
+	bytesPerRoot               = 32
+	bytesPerParticipationFlags = 1
+	bytesPerUint64             = 8
+	bytesPerUint256            = 32
+)
+
+var (
+	uint64Type    = ssz.Uint{Bytes: bytesPerUint64}
+	uint256Type   = ssz.Uint{Bytes: bytesPerUint256}
+	bytes4Type    = ssz.ByteVector{Length: bytesPerVersion}
+	bytes20Type   = ssz.ByteVector{Length: bytesPerExecutionAddress}
+	bytes32Type   = ssz.ByteVector{Length: bytesPerRoot}
+	pubkeyType    = ssz.ByteVector{Length: bytesPerBLSPubkey}
+	signatureType = ssz.ByteVector{Length: bytesPerBLSSignature}
+
+	beaconBlockRoot = ssz.Root{Codec: beaconBlockMulticodec, MultiHash: ssz.MultiHashType}
+	beaconBodyRoot  = ssz.Root{Codec: beaconBlockBodyMulticodec, MultiHash: ssz.MultiHashType}
+	beaconStateRoot = ssz.Root{Codec: beaconStateMulticodec, MultiHash: ssz.MultiHashType}
+	ethHeaderRoot   = ssz.Root{Codec: cid.EthBlock, MultiHash: multihash.KECCAK_256}
+	ethStateRoot    = ssz.Root{Codec: cid.EthStateTrie, MultiHash: multihash.KECCAK_256}
+	ethRctRoot      = ssz.Root{Codec: cid.EthTxReceiptTrie, MultiHash: multihash.KECCAK_256}
+)
+
+// Fork is the phase0 Fork container
+var Fork = ssz.Container{Fields: []ssz.Field{
+	{Name: "PreviousVersion", Type: bytes4Type},
+	{Name: "CurrentVersion", Type: bytes4Type},
+	{Name: "Epoch", Type: uint64Type},
+}}
+
+// Checkpoint is the phase0 Checkpoint container
+var Checkpoint = ssz.Container{Fields: []ssz.Field{
+	{Name: "Epoch", Type: uint64Type},
+	{Name: "RootCID", Type: beaconBlockRoot},
+}}
+
+// BeaconBlockHeader is the phase0 BeaconBlockHeader container, whose root is the root of the block it summarizes
+var BeaconBlockHeader = ssz.Container{Fields: []ssz.Field{
+	{Name: "Slot", Type: uint64Type},
+	{Name: "ProposerIndex", Type: uint64Type},
+	{Name: "ParentRootCID", Type: beaconBlockRoot},
+	{Name: "StateRootCID", Type: beaconStateRoot},
+	{Name: "BodyRootCID", Type: beaconBodyRoot},
+}}
+
+// SignedBeaconBlockHeader is the phase0 SignedBeaconBlockHeader container
+var SignedBeaconBlockHeader = ssz.Container{Fields: []ssz.Field{
+	{Name: "Message", Type: BeaconBlockHeader},
+	{Name: "Signature", Type: signatureType},
+}}
+
+// Eth1Data is the phase0 Eth1Data container
+var Eth1Data = ssz.Container{Fields: []ssz.Field{
+	{Name: "DepositRoot", Type: bytes32Type},
+	{Name: "DepositCount", Type: uint64Type},
+	{Name: "BlockHashCID", Type: ethHeaderRoot},
+}}
+
+// AttestationData is the phase0 AttestationData container
+var AttestationData = ssz.Container{Fields: []ssz.Field{
+	{Name: "Slot", Type: uint64Type},
+	{Name: "Index", Type: uint64Type},
+	{Name: "BeaconBlockRootCID", Type: beaconBlockRoot},
+	{Name: "Source", Type: Checkpoint},
+	{Name: "Target", Type: Checkpoint},
+}}
+
+// IndexedAttestation is the phase0 IndexedAttestation container
+var IndexedAttestation = ssz.Container{Fields: []ssz.Field{
+	{Name: "AttestingIndices", Type: ssz.List{Elem: uint64Type, Limit: MaxValidatorsPerCommittee}},
+	{Name: "Data", Type: AttestationData},
+	{Name: "Signature", Type: signatureType},
+}}
+
+// ProposerSlashing is the phase0 ProposerSlashing container
+var ProposerSlashing = ssz.Container{Fields: []ssz.Field{
+	{Name: "SignedHeader1", Type: SignedBeaconBlockHeader},
+	{Name: "SignedHeader2", Type: SignedBeaconBlockHeader},
+}}
+
+// AttesterSlashing is the phase0 AttesterSlashing container
+var AttesterSlashing = ssz.Container{Fields: []ssz.Field{
+	{Name: "Attestation1", Type: IndexedAttestation},
+	{Name: "Attestation2", Type: IndexedAttestation},
+}}
+
+// Attestation is the phase0 Attestation container
+var Attestation = ssz.Container{Fields: []ssz.Field{
+	{Name: "AggregationBits", Type: ssz.Bitlist{Limit: MaxValidatorsPerCommittee}},
+	{Name: "Data", Type: AttestationData},
+	{Name: "Signature", Type: signatureType},
+}}
+
+// DepositData is the phase0 DepositData container
+var DepositData = ssz.Container{Fields: []ssz.Field{
+	{Name: "Pubkey", Type: pubkeyType},
+	{Name: "WithdrawalCredentials", Type: bytes32Type},
+	{Name: "Amount", Type: uint64Type},
+	{Name: "Signature", Type: signatureType},
+}}
+
+// Deposit is the phase0 Deposit container
+var Deposit = ssz.Container{Fields: []ssz.Field{
+	{Name: "Proof", Type: ssz.Vector{Elem: bytes32Type, Length: depositProofLength}},
+	{Name: "Data", Type: DepositData},
+}}
+
+// VoluntaryExit is the phase0 VoluntaryExit container
+var VoluntaryExit = ssz.Container{Fields: []ssz.Field{
+	{Name: "Epoch", Type: uint64Type},
+	{Name: "ValidatorIndex", Type: uint64Type},
+}}
+
+// SignedVoluntaryExit is the phase0 SignedVoluntaryExit container
+var SignedVoluntaryExit = ssz.Container{Fields: []ssz.Field{
+	{Name: "Message", Type: VoluntaryExit},
+	{Name: "Signature", Type: signatureType},
+}}
+
+// SyncAggregate is the altair SyncAggregate container
+var SyncAggregate = ssz.Container{Fields: []ssz.Field{
+	{Name: "SyncCommitteeBits", Type: ssz.Bitvector{Length: SyncCommitteeSize}},
+	{Name: "SyncCommitteeSignature", Type: signatureType},
+}}
+
+// Withdrawal is the capella Withdrawal container
+var Withdrawal = ssz.Container{Fields: []ssz.Field{
+	{Name: "Index", Type: uint64Type},
+	{Name: "ValidatorIndex", Type: uint64Type},
+	{Name: "Address", Type: bytes20Type},
+	{Name: "Amount", Type: uint64Type},
+}}
+
+// ExecutionPayload is the deneb ExecutionPayload container
+var ExecutionPayload = ssz.Container{Fields: []ssz.Field{
+	{Name: "ParentHashCID", Type: ethHeaderRoot},
+	{Name: "FeeRecipient", Type: bytes20Type},
+	{Name: "StateRootCID", Type: ethStateRoot},
+	{Name: "ReceiptsRootCID", Type: ethRctRoot},
+	{Name: "LogsBloom", Type: ssz.ByteVector{Length: BytesPerLogsBloom}},
+	{Name: "PrevRandao", Type: bytes32Type},
+	{Name: "BlockNumber", Type: uint64Type},
+	{Name: "GasLimit", Type: uint64Type},
+	{Name: "GasUsed", Type: uint64Type},
+	{Name: "Timestamp", Type: uint64Type},
+	{Name: "ExtraData", Type: ssz.ByteList{Limit: MaxExtraDataBytes}},
+	{Name: "BaseFeePerGas", Type: uint256Type},
+	{Name: "BlockHashCID", Type: ethHeaderRoot},
+	{Name: "Transactions", Type: ssz.List{Elem: ssz.ByteList{Limit: MaxBytesPerTransaction}, Limit: MaxTransactionsPerPayload}},
+	{Name: "Withdrawals", Type: ssz.List{Elem: Withdrawal, Limit: MaxWithdrawalsPerPayload}},
+	{Name: "BlobGasUsed", Type: uint64Type},
+	{Name: "ExcessBlobGas", Type: uint64Type},
+}}
+
+// ExecutionPayloadHeader is the deneb ExecutionPayloadHeader container
+var ExecutionPayloadHeader = ssz.Container{Fields: []ssz.Field{
+	{Name: "ParentHashCID", Type: ethHeaderRoot},
+	{Name: "FeeRecipient", Type: bytes20Type},
+	{Name: "StateRootCID", Type: ethStateRoot},
+	{Name: "ReceiptsRootCID", Type: ethRctRoot},
+	{Name: "LogsBloom", Type: ssz.ByteVector{Length: BytesPerLogsBloom}},
+	{Name: "PrevRandao", Type: bytes32Type},
+	{Name: "BlockNumber", Type: uint64Type},
+	{Name: "GasLimit", Type: uint64Type},
+	{Name: "GasUsed", Type: uint64Type},
+	{Name: "Timestamp", Type: uint64Type},
+	{Name: "ExtraData", Type: ssz.ByteList{Limit: MaxExtraDataBytes}},
+	{Name: "BaseFeePerGas", Type: uint256Type},
+	{Name: "BlockHashCID", Type: ethHeaderRoot},
+	{Name: "TransactionsRoot", Type: bytes32Type},
+	{Name: "WithdrawalsRoot", Type: bytes32Type},
+	{Name: "BlobGasUsed", Type: uint64Type},
+	{Name: "ExcessBlobGas", Type: uint64Type},
+}}
+
+// BLSToExecutionChange is the capella BLSToExecutionChange container
+var BLSToExecutionChange = ssz.Container{Fields: []ssz.Field{
+	{Name: "ValidatorIndex", Type: uint64Type},
+	{Name: "FromBLSPubkey", Type: pubkeyType},
+	{Name: "ToExecutionAddress", Type: bytes20Type},
+}}
+
+// SignedBLSToExecutionChange is the capella SignedBLSToExecutionChange container
+var SignedBLSToExecutionChange = ssz.Container{Fields: []ssz.Field{
+	{Name: "Message", Type: BLSToExecutionChange},
+	{Name: "Signature", Type: signatureType},
+}}
+
+// BeaconBlockBody is the deneb BeaconBlockBody container
+var BeaconBlockBody = ssz.Container{Fields: []ssz.Field{
+	{Name: "RandaoReveal", Type: signatureType},
+	{Name: "Eth1Data", Type: Eth1Data},
+	{Name: "Graffiti", Type: bytes32Type},
+	{Name: "ProposerSlashings", Type: ssz.List{Elem: ProposerSlashing, Limit: MaxProposerSlashings}},
+	{Name: "AttesterSlashings", Type: ssz.List{Elem: AttesterSlashing, Limit: MaxAttesterSlashings}},
+	{Name: "Attestations", Type: ssz.List{Elem: Attestation, Limit: MaxAttestations}},
+	{Name: "Deposits", Type: ssz.List{Elem: Deposit, Limit: MaxDeposits}},
+	{Name: "VoluntaryExits", Type: ssz.List{Elem: SignedVoluntaryExit, Limit: MaxVoluntaryExits}},
+	{Name: "SyncAggregate", Type: SyncAggregate},
+	{Name: "ExecutionPayload", Type: ExecutionPayload},
+	{Name: "BLSToExecutionChanges", Type: ssz.List{Elem: SignedBLSToExecutionChange, Limit: MaxBLSToExecutionChanges}},
+	{Name: "BlobKZGCommitments", Type: ssz.List{Elem: ssz.ByteVector{Length: bytesPerKZGCommitment}, Limit: MaxBlobCommitmentsPerBlock}},
+}}
+
+// BeaconBlock is the phase0 BeaconBlock container with the deneb BeaconBlockBody. Its root is the root of the
+// BeaconBlockHeader with the root of its body, which is what the ParentBeaconRootCID of an execution header links to.
+var BeaconBlock = ssz.Container{Fields: []ssz.Field{
+	{Name: "Slot", Type: uint64Type},
+	{Name: "ProposerIndex", Type: uint64Type},
+	{Name: "ParentRootCID", Type: beaconBlockRoot},
+	{Name: "StateRootCID", Type: beaconStateRoot},
+	{Name: "Body", Type: BeaconBlockBody},
+}}
+
+// Validator is the phase0 Validator container
+var Validator = ssz.Container{Fields: []ssz.Field{
+	{Name: "Pubkey", Type: pubkeyType},
+	{Name: "WithdrawalCredentials", Type: bytes32Type},
+	{Name: "EffectiveBalance", Type: uint64Type},
+	{Name: "Slashed", Type: ssz.Bool{}},
+	{Name: "ActivationEligibilityEpoch", Type: uint64Type},
+	{Name: "ActivationEpoch", Type: uint64Type},
+	{Name: "ExitEpoch", Type: uint64Type},
+	{Name: "WithdrawableEpoch", Type: uint64Type},
+}}
+
+// SyncCommittee is the altair SyncCommittee container
+var SyncCommittee = ssz.Container{Fields: []ssz.Field{
+	{Name: "Pubkeys", Type: ssz.Vector{Elem: pubkeyType, Length: SyncCommitteeSize}},
+	{Name: "AggregatePubkey", Type: pubkeyType},
+}}
+
+// HistoricalSummary is the capella HistoricalSummary container
+var HistoricalSummary = ssz.Container{Fields: []ssz.Field{
+	{Name: "BlockSummaryRoot", Type: bytes32Type},
+	{Name: "StateSummaryRoot", Type: bytes32Type},
+}}
+
+// BeaconState is the deneb BeaconState container
+var BeaconState = ssz.Container{Fields: []ssz.Field{
+	{Name: "GenesisTime", Type: uint64Type},
+	{Name: "GenesisValidatorsRoot", Type: bytes32Type},
+	{Name: "Slot", Type: uint64Type},
+	{Name: "Fork", Type: Fork},
+	{Name: "LatestBlockHeader", Type: BeaconBlockHeader},
+	{Name: "BlockRootCIDs", Type: ssz.Vector{Elem: beaconBlockRoot, Length: SlotsPerHistoricalRoot}},
+	{Name: "StateRootCIDs", Type: ssz.Vector{Elem: beaconStateRoot, Length: SlotsPerHistoricalRoot}},
+	{Name: "HistoricalRoots", Type: ssz.List{Elem: bytes32Type, Limit: HistoricalRootsLimit}},
+	{Name: "Eth1Data", Type: Eth1Data},
+	{Name: "Eth1DataVotes", Type: ssz.List{Elem: Eth1Data, Limit: eth1DataVotesLimit}},
+	{Name: "Eth1DepositIndex", Type: uint64Type},
+	{Name: "Validators", Type: ssz.List{Elem: Validator, Limit: ValidatorRegistryLimit}},
+	{Name: "Balances", Type: ssz.List{Elem: uint64Type, Limit: ValidatorRegistryLimit}},
+	{Name: "RandaoMixes", Type: ssz.Vector{Elem: bytes32Type, Length: EpochsPerHistoricalVector}},
+	{Name: "Slashings", Type: ssz.Vector{Elem: uint64Type, Length: EpochsPerSlashingsVector}},
+	{Name: "PreviousEpochParticipation", Type: ssz.List{Elem: ssz.Uint{Bytes: bytesPerParticipationFlags}, Limit: ValidatorRegistryLimit}},
+	{Name: "CurrentEpochParticipation", Type: ssz.List{Elem: ssz.Uint{Bytes: bytesPerParticipationFlags}, Limit: ValidatorRegistryLimit}},
+	{Name: "JustificationBits", Type: ssz.Bitvector{Length: JustificationBitsLength}},
+	{Name: "PreviousJustifiedCheckpoint", Type: Checkpoint},
+	{Name: "CurrentJustifiedCheckpoint", Type: Checkpoint},
+	{Name: "FinalizedCheckpoint", Type: Checkpoint},
+	{Name: "InactivityScores", Type: ssz.List{Elem: uint64Type, Limit: ValidatorRegistryLimit}},
+	{Name: "CurrentSyncCommittee", Type: SyncCommittee},
+	{Name: "NextSyncCommittee", Type: SyncCommittee},
+	{Name: "LatestExecutionPayloadHeader", Type: ExecutionPayloadHeader},
+	{Name: "NextWithdrawalIndex", Type: uint64Type},
+	{Name: "NextWithdrawalValidatorIndex", Type: uint64Type},
+	{Name: "HistoricalSummaries", Type: ssz.List{Elem: HistoricalSummary, Limit: HistoricalRootsLimit}},
+}}
@@ -0,0 +1,43 @@
+package consensus
+
+import (
+	"github.com/vulcanize/go-codec-dageth/ssz"
+)
+
+// Mainnet preset values of the Electra execution requests
+const (
+	MaxDepositRequestsPerPayload       = 8192
+	MaxWithdrawalRequestsPerPayload    = 16
+	MaxConsolidationRequestsPerPayload = 2
+)
+
+// DepositRequest is the electra DepositRequest container
+var DepositRequest = ssz.Container{Fields: []ssz.Field{
+	{Name: "Pubkey", Type: pubkeyType},
+	{Name: "WithdrawalCredentials", Type: bytes32Type},
+	{Name: "Amount", Type: uint64Type},
+	{Name: "Signature", Type: signatureType},
+	{Name: "Index", Type: uint64Type},
+}}
+
+// WithdrawalRequest is the electra WithdrawalRequest container
+var WithdrawalRequest = ssz.Container{Fields: []ssz.Field{
+	{Name: "SourceAddress", Type: bytes20Type},
+	{Name: "ValidatorPubkey", Type: pubkeyType},
+	{Name: "Amount", Type: uint64Type},
+}}
+
+// ConsolidationRequest is the electra ConsolidationRequest container
+var ConsolidationRequest = ssz.Container{Fields: []ssz.Field{
+	{Name: "SourceAddress", Type: bytes20Type},
+	{Name: "SourcePubkey", Type: pubkeyType},
+	{Name: "TargetPubkey", Type: pubkeyType},
+}}
+
+// DepositRequests, WithdrawalRequests and ConsolidationRequests are the request lists of the electra
+// ExecutionRequests container, whose serializations are the data of the EIP-7685 requests of their type
+var (
+	DepositRequests       = ssz.List{Elem: DepositRequest, Limit: MaxDepositRequestsPerPayload}
+	WithdrawalRequests    = ssz.List{Elem: WithdrawalRequest, Limit: MaxWithdrawalRequestsPerPayload}
+	ConsolidationRequests = ssz.List{Elem: ConsolidationRequest, Limit: MaxConsolidationRequestsPerPayload}
+)
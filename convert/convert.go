@@ -0,0 +1,150 @@
+/*
+Package convert converts between go-ethereum types and DAG-ETH IPLD nodes, and derives the CIDs of go-ethereum
+types, so applications already holding go-ethereum structs do not need to round trip them through RLP by hand.
+*/
+package convert
+
+import (
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/tx"
+)
+
+// FromHeader converts a go-ethereum Header into a Header IPLD node
+func FromHeader(h *types.Header) (ipld.Node, error) {
+	nb := dageth.Type.Header.NewBuilder()
+	if err := header.DecodeHeader(nb, *h); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
+}
+
+// ToHeader converts a Header IPLD node into a go-ethereum Header
+func ToHeader(node ipld.Node) (*types.Header, error) {
+	h := new(types.Header)
+	if err := header.EncodeHeader(h, node); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// HeaderCID returns the CID of the Header IPLD of a go-ethereum Header
+func HeaderCID(h *types.Header) (cid.Cid, error) {
+	return shared.Keccak256ToCid(header.MultiCodecType, h.Hash().Bytes())
+}
+
+// FromTransaction converts a go-ethereum Transaction into a Transaction IPLD node
+func FromTransaction(t *types.Transaction) (ipld.Node, error) {
+	nb := dageth.Type.Transaction.NewBuilder()
+	if err := tx.DecodeTx(nb, t); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
+}
+
+// ToTransaction converts a Transaction IPLD node into a go-ethereum Transaction
+func ToTransaction(node ipld.Node) (*types.Transaction, error) {
+	t := new(types.Transaction)
+	if err := tx.EncodeTx(t, node); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// TransactionCID returns the CID of the Transaction IPLD of a go-ethereum Transaction
+func TransactionCID(t *types.Transaction) (cid.Cid, error) {
+	return shared.Keccak256ToCid(tx.MultiCodecType, t.Hash().Bytes())
+}
+
+// FromReceipt converts a go-ethereum Receipt into a Receipt IPLD node.
+// Only the consensus fields of the Receipt are carried over.
+func FromReceipt(r *types.Receipt) (ipld.Node, error) {
+	nb := dageth.Type.Receipt.NewBuilder()
+	if err := rct.DecodeReceipt(nb, *r); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
+}
+
+// ToReceipt converts a Receipt IPLD node into a go-ethereum Receipt with its consensus fields set
+func ToReceipt(node ipld.Node) (*types.Receipt, error) {
+	r := new(types.Receipt)
+	if err := rct.EncodeReceipt(r, node); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReceiptCID returns the CID of the Receipt IPLD of a go-ethereum Receipt
+func ReceiptCID(r *types.Receipt) (cid.Cid, error) {
+	enc, err := r.MarshalBinary()
+	if err != nil {
+		return cid.Cid{}, err
+	}
+	return shared.Keccak256ToCid(rct.MultiCodecType, crypto.Keccak256(enc))
+}
+
+// FromLog converts a go-ethereum Log into a Log IPLD node.
+// Only the consensus fields of the Log are carried over.
+func FromLog(l *types.Log) (ipld.Node, error) {
+	nb := dageth.Type.Log.NewBuilder()
+	if err := log.DecodeLog(nb, *l); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
+}
+
+// ToLog converts a Log IPLD node into a go-ethereum Log with its consensus fields set
+func ToLog(node ipld.Node) (*types.Log, error) {
+	l := new(types.Log)
+	if err := log.EncodeLog(l, node); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// LogCID returns the CID of the Log IPLD of a go-ethereum Log
+func LogCID(l *types.Log) (cid.Cid, error) {
+	enc, err := rlp.EncodeToBytes(l)
+	if err != nil {
+		return cid.Cid{}, err
+	}
+	return shared.Keccak256ToCid(log.MultiCodecType, crypto.Keccak256(enc))
+}
+
+// FromAccount converts a go-ethereum StateAccount into an Account IPLD node
+func FromAccount(a *types.StateAccount) (ipld.Node, error) {
+	nb := dageth.Type.Account.NewBuilder()
+	if err := account.DecodeAccount(nb, *a); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
+}
+
+// ToAccount converts an Account IPLD node into a go-ethereum StateAccount
+func ToAccount(node ipld.Node) (*types.StateAccount, error) {
+	a := new(types.StateAccount)
+	if err := account.EncodeAccount(a, node); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// AccountCID returns the CID of the Account IPLD of a go-ethereum StateAccount
+func AccountCID(a *types.StateAccount) (cid.Cid, error) {
+	enc, err := rlp.EncodeToBytes(a)
+	if err != nil {
+		return cid.Cid{}, err
+	}
+	return shared.Keccak256ToCid(account.MultiCodecType, crypto.Keccak256(enc))
+}
@@ -0,0 +1,195 @@
+package convert_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/convert"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/tx"
+)
+
+var (
+	mockLog = &types.Log{
+		Address: shared.RandomAddr(),
+		Topics:  []common.Hash{shared.RandomHash(), shared.RandomHash()},
+		Data:    shared.RandomBytes(64),
+	}
+	mockHeader = &types.Header{
+		ParentHash:  shared.RandomHash(),
+		UncleHash:   types.EmptyUncleHash,
+		Coinbase:    shared.RandomAddr(),
+		Root:        shared.RandomHash(),
+		TxHash:      types.EmptyTxsHash,
+		ReceiptHash: types.EmptyReceiptsHash,
+		Difficulty:  big.NewInt(1000),
+		Number:      big.NewInt(12000000),
+		GasLimit:    30000000,
+		GasUsed:     21000,
+		Time:        1620000000,
+		Extra:       []byte("extra"),
+		BaseFee:     big.NewInt(7),
+	}
+	mockAccount = &types.StateAccount{
+		Nonce:    5,
+		Balance:  uint256.NewInt(1000000000),
+		Root:     types.EmptyRootHash,
+		CodeHash: crypto.Keccak256([]byte{0x60}),
+	}
+)
+
+// expectCID checks the CID derived by the convert package against the CID of the node's encoding
+func expectCID(t *testing.T, c cid.Cid, codec uint64, encode func(ipld.Node, []byte) ([]byte, error), node ipld.Node) {
+	enc, err := encode(node, nil)
+	if err != nil {
+		t.Fatalf("unable to encode node: %v", err)
+	}
+	expected := testutil.KeccakCid(t, codec, crypto.Keccak256(enc))
+	if !c.Equals(expected) {
+		t.Errorf("CID (%s) does not match the CID of the encoded node (%s)", c.String(), expected.String())
+	}
+}
+
+func appendEncoder(f func([]byte, ipld.Node) ([]byte, error)) func(ipld.Node, []byte) ([]byte, error) {
+	return func(n ipld.Node, enc []byte) ([]byte, error) {
+		return f(enc, n)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	node, err := convert.FromHeader(mockHeader)
+	if err != nil {
+		t.Fatalf("unable to convert header: %v", err)
+	}
+	h, err := convert.ToHeader(node)
+	if err != nil {
+		t.Fatalf("unable to convert header node: %v", err)
+	}
+	if h.Hash() != mockHeader.Hash() {
+		t.Errorf("converted header hash (%s) does not match expected hash (%s)", h.Hash().Hex(), mockHeader.Hash().Hex())
+	}
+	headerCID, err := convert.HeaderCID(mockHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectCID(t, headerCID, header.MultiCodecType, appendEncoder(header.AppendEncode), node)
+}
+
+func TestTransaction(t *testing.T) {
+	txs := []*types.Transaction{
+		types.NewTransaction(1, shared.RandomAddr(), big.NewInt(10), 21000, big.NewInt(100), []byte{1}),
+		types.NewTx(&types.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			Nonce:     2,
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(100),
+			Gas:       50000,
+			To:        nil,
+			Value:     big.NewInt(0),
+			Data:      []byte{0x60, 0x80},
+			AccessList: types.AccessList{
+				{Address: shared.RandomAddr(), StorageKeys: []common.Hash{shared.RandomHash()}},
+			},
+		}),
+	}
+	for _, trx := range txs {
+		node, err := convert.FromTransaction(trx)
+		if err != nil {
+			t.Fatalf("unable to convert transaction: %v", err)
+		}
+		converted, err := convert.ToTransaction(node)
+		if err != nil {
+			t.Fatalf("unable to convert transaction node: %v", err)
+		}
+		if converted.Hash() != trx.Hash() {
+			t.Errorf("converted transaction hash (%s) does not match expected hash (%s)", converted.Hash().Hex(), trx.Hash().Hex())
+		}
+		txCID, err := convert.TransactionCID(trx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectCID(t, txCID, tx.MultiCodecType, appendEncoder(tx.AppendEncode), node)
+	}
+}
+
+func TestReceiptAndLog(t *testing.T) {
+	receipt := &types.Receipt{
+		Type:              types.DynamicFeeTxType,
+		Status:            types.ReceiptStatusSuccessful,
+		CumulativeGasUsed: 50000,
+		Logs:              []*types.Log{mockLog},
+	}
+	receipt.Bloom = types.CreateBloom(receipt)
+	node, err := convert.FromReceipt(receipt)
+	if err != nil {
+		t.Fatalf("unable to convert receipt: %v", err)
+	}
+	converted, err := convert.ToReceipt(node)
+	if err != nil {
+		t.Fatalf("unable to convert receipt node: %v", err)
+	}
+	expectedEnc, _ := receipt.MarshalBinary()
+	enc, _ := converted.MarshalBinary()
+	if !bytes.Equal(enc, expectedEnc) {
+		t.Errorf("converted receipt (%x) does not match expected receipt (%x)", enc, expectedEnc)
+	}
+	rctCID, err := convert.ReceiptCID(receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectCID(t, rctCID, rct.MultiCodecType, appendEncoder(rct.AppendEncode), node)
+
+	logNode, err := convert.FromLog(mockLog)
+	if err != nil {
+		t.Fatalf("unable to convert log: %v", err)
+	}
+	convertedLog, err := convert.ToLog(logNode)
+	if err != nil {
+		t.Fatalf("unable to convert log node: %v", err)
+	}
+	expectedEnc, _ = rlp.EncodeToBytes(mockLog)
+	enc, _ = rlp.EncodeToBytes(convertedLog)
+	if !bytes.Equal(enc, expectedEnc) {
+		t.Errorf("converted log (%x) does not match expected log (%x)", enc, expectedEnc)
+	}
+	logCID, err := convert.LogCID(mockLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectCID(t, logCID, log.MultiCodecType, appendEncoder(log.AppendEncode), logNode)
+}
+
+func TestAccount(t *testing.T) {
+	node, err := convert.FromAccount(mockAccount)
+	if err != nil {
+		t.Fatalf("unable to convert account: %v", err)
+	}
+	converted, err := convert.ToAccount(node)
+	if err != nil {
+		t.Fatalf("unable to convert account node: %v", err)
+	}
+	expectedEnc, _ := rlp.EncodeToBytes(mockAccount)
+	enc, _ := rlp.EncodeToBytes(converted)
+	if !bytes.Equal(enc, expectedEnc) {
+		t.Errorf("converted account (%x) does not match expected account (%x)", enc, expectedEnc)
+	}
+	acctCID, err := convert.AccountCID(mockAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectCID(t, acctCID, account.MultiCodecType, appendEncoder(account.AppendEncode), node)
+}
@@ -0,0 +1,211 @@
+/*
+Package diff compares two DAG-ETH tries, such as the state tries of consecutive blocks, directly on their IPLD nodes.
+Only the subtries whose links differ between the two tries are loaded, so the cost of a diff scales with the
+size of the change rather than the size of the tries.
+*/
+package diff
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/keys"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// Change is a key whose value differs between the old and the new trie.
+// Old and New are the Value unions of the key in each trie; Old is nil for added keys, and New is nil for removed keys.
+type Change struct {
+	Key []byte
+	Old ipld.Node
+	New ipld.Node
+}
+
+// Diff walks the tries referenced by oldRoot and newRoot, loading their nodes through the LinkSystem, and calls fn
+// with every changed key in ascending key order. Subtries linked by the same CID in both tries are skipped without
+// being loaded. Either root can be the empty trie root, whose node does not need to be stored.
+// Diff stops at the first error returned by fn, and returns it.
+func Diff(lsys ipld.LinkSystem, oldRoot, newRoot ipld.Link, fn func(Change) error) error {
+	d := &differ{lsys: lsys, fn: fn}
+	return d.diff(rootCursor(oldRoot), rootCursor(newRoot), nil)
+}
+
+// cursor is a position within a trie node; extension and leaf nodes span as many positions as their
+// partial path holds nibbles, of which consumed have been walked past
+type cursor struct {
+	// link references the stored node, it is nil for nodes embedded in their parent
+	link ipld.Link
+	// node is the TrieNode, which is loaded lazily for stored nodes
+	node     ipld.Node
+	consumed int
+}
+
+func rootCursor(root ipld.Link) *cursor {
+	if cl, ok := root.(cidlink.Link); ok && bytes.Equal(cl.Hash()[2:], types.EmptyRootHash.Bytes()) {
+		return nil
+	}
+	return &cursor{link: root}
+}
+
+// sameAs returns whether both cursors are at the same position of the same stored node, so everything under them matches
+func (c *cursor) sameAs(other *cursor) bool {
+	return c.link != nil && other.link != nil && c.link == other.link && c.consumed == other.consumed
+}
+
+// children returns the value held at the position of the cursor, and the cursors one nibble further down
+func (d *differ) children(c *cursor) (ipld.Node, [16]*cursor, error) {
+	var children [16]*cursor
+	if c == nil {
+		return nil, children, nil
+	}
+	if c.node == nil {
+		node, err := d.lsys.Load(ipld.LinkContext{}, c.link, dageth.Type.TrieNode)
+		if err != nil {
+			return nil, children, err
+		}
+		c.node = node
+	}
+	n, kind, err := trie.NodeAndKind(c.node)
+	if err != nil {
+		return nil, children, err
+	}
+	switch kind {
+	case trie.BRANCH_NODE:
+		for i := range children {
+			child, err := n.LookupByString(fmt.Sprintf("Child%X", i))
+			if err != nil {
+				return nil, children, err
+			}
+			if child.IsNull() {
+				continue
+			}
+			if children[i], err = childCursor(child); err != nil {
+				return nil, children, err
+			}
+		}
+		val, err := valueOf(n)
+		return val, children, err
+	case trie.EXTENSION_NODE:
+		partialPath, err := partialPathOf(n)
+		if err != nil {
+			return nil, children, err
+		}
+		if c.consumed >= len(partialPath) {
+			return nil, children, fmt.Errorf("extension node has an empty partial path")
+		}
+		next := &cursor{link: c.link, node: c.node, consumed: c.consumed + 1}
+		// the extension ends where its child begins, so a stored child can be compared by its link
+		if next.consumed == len(partialPath) {
+			child, err := n.LookupByString("Child")
+			if err != nil {
+				return nil, children, err
+			}
+			next, err = childCursor(child)
+			if err != nil {
+				return nil, children, err
+			}
+		}
+		children[partialPath[c.consumed]] = next
+		return nil, children, nil
+	case trie.LEAF_NODE:
+		partialPath, err := partialPathOf(n)
+		if err != nil {
+			return nil, children, err
+		}
+		if c.consumed < len(partialPath) {
+			children[partialPath[c.consumed]] = &cursor{link: c.link, node: c.node, consumed: c.consumed + 1}
+			return nil, children, nil
+		}
+		val, err := valueOf(n)
+		return val, children, err
+	default:
+		return nil, children, fmt.Errorf("unrecognized trie node type %s", kind.String())
+	}
+}
+
+// childCursor returns the cursor at the start of the node the Child union of a branch or extension holds, a link to
+// the stored node, or the node itself when it is smaller than 32 bytes and so included directly in its parent
+func childCursor(child ipld.Node) (*cursor, error) {
+	if linkNode, err := child.LookupByString("Link"); err == nil {
+		lnk, err := linkNode.AsLink()
+		if err != nil {
+			return nil, err
+		}
+		return &cursor{link: lnk}, nil
+	}
+	embedded, err := child.LookupByString("TrieNode")
+	if err != nil {
+		return nil, fmt.Errorf("trie node child needs to be a link or a trie node: %v", err)
+	}
+	return &cursor{node: embedded}, nil
+}
+
+type differ struct {
+	lsys ipld.LinkSystem
+	fn   func(Change) error
+}
+
+// diff compares the subtries under the cursors, which are both at the nibble path
+func (d *differ) diff(oldCursor, newCursor *cursor, path []byte) error {
+	if oldCursor == nil && newCursor == nil {
+		return nil
+	}
+	if oldCursor != nil && newCursor != nil && oldCursor.sameAs(newCursor) {
+		return nil
+	}
+	oldVal, oldChildren, err := d.children(oldCursor)
+	if err != nil {
+		return err
+	}
+	newVal, newChildren, err := d.children(newCursor)
+	if err != nil {
+		return err
+	}
+	if (oldVal != nil || newVal != nil) && (oldVal == nil || newVal == nil || !ipld.DeepEqual(oldVal, newVal)) {
+		key, err := keys.NibblesToKeybytes(path)
+		if err != nil {
+			return err
+		}
+		if err := d.fn(Change{Key: key, Old: oldVal, New: newVal}); err != nil {
+			return err
+		}
+	}
+	for i := range oldChildren {
+		if err := d.diff(oldChildren[i], newChildren[i], append(path[:len(path):len(path)], byte(i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func valueOf(node ipld.Node) (ipld.Node, error) {
+	val, err := node.LookupByString("Value")
+	if err != nil {
+		return nil, err
+	}
+	if val.IsNull() {
+		return nil, nil
+	}
+	return val, nil
+}
+
+func partialPathOf(node ipld.Node) ([]byte, error) {
+	ppNode, err := node.LookupByString("PartialPath")
+	if err != nil {
+		return nil, err
+	}
+	pp, err := ppNode.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	// leaf partial paths carry the terminator flag in the hex representation
+	if len(pp) > 0 && pp[len(pp)-1] == 16 {
+		pp = pp[:len(pp)-1]
+	}
+	return pp, nil
+}
@@ -0,0 +1,195 @@
+package diff_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/diff"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+func accountRLP(nonce uint64) []byte {
+	enc, _ := rlp.EncodeToBytes(&types.StateAccount{
+		Nonce:    nonce,
+		Balance:  uint256.NewInt(nonce),
+		Root:     types.EmptyRootHash,
+		CodeHash: types.EmptyCodeHash.Bytes(),
+	})
+	return enc
+}
+
+func nonceOf(t *testing.T, val ipld.Node) uint64 {
+	nonceNode, err := val.LookupByString("Account")
+	if err == nil {
+		nonceNode, err = nonceNode.LookupByString("Nonce")
+	}
+	if err != nil {
+		t.Fatalf("changed value is not an account: %v", err)
+	}
+	b, _ := nonceNode.AsBytes()
+	return new(big.Int).SetBytes(b).Uint64()
+}
+
+// collect returns the changes between the tries, checking they arrive in ascending key order,
+// along with the number of blocks loaded to find them
+func collect(t *testing.T, store *storage.Memory, oldRoot, newRoot ipld.Link) ([]diff.Change, int) {
+	lsys := codecs.NewLinkSystem(store)
+	loads := 0
+	lsys.StorageReadOpener = func(lnkCtx ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+		loads++
+		return store.OpenRead(lnkCtx, lnk)
+	}
+	var changes []diff.Change
+	if err := diff.Diff(lsys, oldRoot, newRoot, func(c diff.Change) error {
+		if len(changes) > 0 && bytes.Compare(changes[len(changes)-1].Key, c.Key) >= 0 {
+			t.Errorf("change %x does not follow change %x in key order", c.Key, changes[len(changes)-1].Key)
+		}
+		changes = append(changes, c)
+		return nil
+	}); err != nil {
+		t.Fatalf("unable to diff tries: %v", err)
+	}
+	return changes, loads
+}
+
+func TestDiffStateTries(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	oldKVs := make(map[string][]byte)
+	for i := uint64(0); i < 1000; i++ {
+		oldKVs[string(crypto.Keccak256(common.BigToAddress(new(big.Int).SetUint64(i)).Bytes()))] = accountRLP(i)
+	}
+	newKVs := make(map[string][]byte)
+	for k, v := range oldKVs {
+		newKVs[k] = v
+	}
+	type expectedChange struct{ old, new uint64 }
+	expected := make(map[string]expectedChange)
+	for _, i := range []uint64{3, 50, 777} {
+		key := string(crypto.Keccak256(common.BigToAddress(new(big.Int).SetUint64(i)).Bytes()))
+		newKVs[key] = accountRLP(i + 10000)
+		expected[key] = expectedChange{i, i + 10000}
+	}
+	for _, i := range []uint64{4, 999} {
+		key := string(crypto.Keccak256(common.BigToAddress(new(big.Int).SetUint64(i)).Bytes()))
+		delete(newKVs, key)
+		expected[key] = expectedChange{i, 0}
+	}
+	for _, i := range []uint64{20000, 20001} {
+		key := string(crypto.Keccak256(common.BigToAddress(new(big.Int).SetUint64(i)).Bytes()))
+		newKVs[key] = accountRLP(i)
+		expected[key] = expectedChange{0, i}
+	}
+	oldRoot := testutil.CommitTrie(t, lsys, state_trie.MultiCodecType, oldKVs)
+	newRoot := testutil.CommitTrie(t, lsys, state_trie.MultiCodecType, newKVs)
+
+	changes, loads := collect(t, store, oldRoot, newRoot)
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d", len(expected), len(changes))
+	}
+	for _, c := range changes {
+		exp, ok := expected[string(c.Key)]
+		if !ok {
+			t.Errorf("unexpected change of key %x", c.Key)
+			continue
+		}
+		if (c.Old == nil) != (exp.old == 0) || (c.New == nil) != (exp.new == 0) {
+			t.Errorf("change of key %x has the wrong values present", c.Key)
+			continue
+		}
+		if c.Old != nil && nonceOf(t, c.Old) != exp.old {
+			t.Errorf("old nonce of key %x does not match expected nonce %d", c.Key, exp.old)
+		}
+		if c.New != nil && nonceOf(t, c.New) != exp.new {
+			t.Errorf("new nonce of key %x does not match expected nonce %d", c.Key, exp.new)
+		}
+	}
+	// only the nodes along the changed paths are loaded
+	if loads > 4*len(expected)*2 {
+		t.Errorf("expected the diff to load at most %d nodes, loaded %d of %d", 4*len(expected)*2, loads, len(store.Bag))
+	}
+
+	if changes, loads := collect(t, store, oldRoot, oldRoot); len(changes) != 0 || loads != 0 {
+		t.Errorf("expected no changes and no loads diffing a trie against itself, got %d changes and %d loads", len(changes), loads)
+	}
+
+	emptyRoot := testutil.KeccakLink(t, state_trie.MultiCodecType, types.EmptyRootHash.Bytes())
+	changes, _ = collect(t, store, emptyRoot, newRoot)
+	if len(changes) != len(newKVs) {
+		t.Errorf("expected every one of the %d keys to be added to the empty trie, got %d changes", len(newKVs), len(changes))
+	}
+	for _, c := range changes {
+		if c.Old != nil || c.New == nil {
+			t.Errorf("expected key %x to be added", c.Key)
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	if err := diff.Diff(lsys, oldRoot, newRoot, func(diff.Change) error {
+		calls++
+		return stop
+	}); err != stop || calls != 1 {
+		t.Errorf("expected the diff to stop at the first callback error, got %v after %d calls", err, calls)
+	}
+}
+
+func TestDiffStorageTries(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	slotKey := func(i int64) string { return string(crypto.Keccak256(common.BigToHash(big.NewInt(i)).Bytes())) }
+	slotVal := func(v string) []byte {
+		enc, _ := rlp.EncodeToBytes([]byte(v))
+		return enc
+	}
+	// small values keep leaves embedded in their parent branches
+	oldKVs := make(map[string][]byte)
+	for i := int64(0); i < 40; i++ {
+		oldKVs[slotKey(i)] = slotVal(fmt.Sprint(i))
+	}
+	newKVs := make(map[string][]byte)
+	for k, v := range oldKVs {
+		newKVs[k] = v
+	}
+	newKVs[slotKey(7)] = slotVal("changed")
+	delete(newKVs, slotKey(8))
+	newKVs[slotKey(100)] = slotVal("added")
+
+	changes, _ := collect(t, store, testutil.CommitTrie(t, lsys, storage_trie.MultiCodecType, oldKVs), testutil.CommitTrie(t, lsys, storage_trie.MultiCodecType, newKVs))
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(changes))
+	}
+	for _, c := range changes {
+		switch string(c.Key) {
+		case slotKey(7):
+			if c.Old == nil || c.New == nil {
+				t.Error("expected slot 7 to be changed")
+			}
+		case slotKey(8):
+			if c.Old == nil || c.New != nil {
+				t.Error("expected slot 8 to be removed")
+			}
+		case slotKey(100):
+			if c.Old != nil || c.New == nil {
+				t.Error("expected slot 100 to be added")
+			}
+		default:
+			t.Errorf("unexpected change of key %x", c.Key)
+		}
+	}
+}
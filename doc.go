@@ -5,7 +5,8 @@ go-ipld-prime (https://github.com/ipld/go-ipld-prime/).
 
 Use the Decode() and Encode() functions directly, or import one of the packages to have their codec
 registered into the go-ipld-prime multicodec registry and available from the
-cidlink.DefaultLinkSystem.
+cidlink.DefaultLinkSystem. Every codec package also provides DecodeWithOptions and EncodeWithOptions, taking its
+DecodeOptions and EncodeOptions, and DecodeVerified, which checks the input hashes to the expected CID first.
 
 Nodes encoded with theses codecs _must_ conform to the DAG-ETH spec. Specifically,
 they should have the non-optional fields shown in the DAG-ETH [schemas](https://github.com/ipld/ipld/tree/master/specs/codecs/dag-eth):
@@ -0,0 +1,152 @@
+package ethash
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	datasetInitBytes   = 1 << 30 // bytes in the dataset at genesis
+	datasetGrowthBytes = 1 << 23 // dataset growth per epoch
+	cacheInitBytes     = 1 << 24 // bytes in the cache at genesis
+	cacheGrowthBytes   = 1 << 17 // cache growth per epoch
+	epochLength        = 30000   // blocks per epoch
+	mixBytes           = 128     // width of the mix
+	hashBytes          = 64      // hash length in bytes
+	hashWords          = 16      // number of 32 bit ints in a hash
+	datasetParents     = 256     // number of parents of each dataset element
+	cacheRounds        = 3       // number of rounds in cache production
+	loopAccesses       = 64      // number of accesses in the hashimoto loop
+)
+
+// cacheSize returns the size of the verification cache of the epoch, the largest prime number of hashes below its
+// linearly growing bound
+func cacheSize(epoch uint64) uint64 {
+	size := cacheInitBytes + cacheGrowthBytes*epoch - hashBytes
+	for !new(big.Int).SetUint64(size / hashBytes).ProbablyPrime(1) {
+		size -= 2 * hashBytes
+	}
+	return size
+}
+
+// datasetSize returns the size of the full dataset of the epoch, the largest prime number of mixes below its
+// linearly growing bound
+func datasetSize(epoch uint64) uint64 {
+	size := datasetInitBytes + datasetGrowthBytes*epoch - mixBytes
+	for !new(big.Int).SetUint64(size / mixBytes).ProbablyPrime(1) {
+		size -= 2 * mixBytes
+	}
+	return size
+}
+
+// seedHash returns the seed of the epoch, keccak256 applied once per epoch to 32 zero bytes
+func seedHash(epoch uint64) []byte {
+	seed := make([]byte, 32)
+	for i := uint64(0); i < epoch; i++ {
+		seed = crypto.Keccak256(seed)
+	}
+	return seed
+}
+
+// generateCache generates the verification cache of the epoch: a sequence of keccak512 hashes chained from the
+// seed, mixed by cacheRounds rounds of the RandMemoHash algorithm, as little endian words
+func generateCache(epoch uint64) []uint32 {
+	size := cacheSize(epoch)
+	rows := int(size / hashBytes)
+	cache := make([]byte, size)
+	copy(cache, crypto.Keccak512(seedHash(epoch)))
+	for offset := uint64(hashBytes); offset < size; offset += hashBytes {
+		copy(cache[offset:], crypto.Keccak512(cache[offset-hashBytes:offset]))
+	}
+	temp := make([]byte, hashBytes)
+	for i := 0; i < cacheRounds; i++ {
+		for j := 0; j < rows; j++ {
+			srcOff := ((j - 1 + rows) % rows) * hashBytes
+			dstOff := j * hashBytes
+			xorOff := int(binary.LittleEndian.Uint32(cache[dstOff:])%uint32(rows)) * hashBytes
+			for k := range temp {
+				temp[k] = cache[srcOff+k] ^ cache[xorOff+k]
+			}
+			copy(cache[dstOff:], crypto.Keccak512(temp))
+		}
+	}
+	words := make([]uint32, size/4)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(cache[i*4:])
+	}
+	return words
+}
+
+// fnv is the FNV-1 inspired mixing function of ethash, which multiplies before xoring unlike FNV-1 itself
+func fnv(a, b uint32) uint32 {
+	return a*0x01000193 ^ b
+}
+
+// fnvHash mixes the data into the mix with fnv
+func fnvHash(mix []uint32, data []uint32) {
+	for i := 0; i < len(mix); i++ {
+		mix[i] = mix[i]*0x01000193 ^ data[i]
+	}
+}
+
+// datasetItem computes the dataset item at the index from the cache, as little endian words
+func datasetItem(cache []uint32, index uint32) []uint32 {
+	rows := uint32(len(cache) / hashWords)
+	mix := make([]byte, hashBytes)
+	binary.LittleEndian.PutUint32(mix, cache[(index%rows)*hashWords]^index)
+	for i := 1; i < hashWords; i++ {
+		binary.LittleEndian.PutUint32(mix[i*4:], cache[(index%rows)*hashWords+uint32(i)])
+	}
+	mix = crypto.Keccak512(mix)
+	intMix := make([]uint32, hashWords)
+	for i := range intMix {
+		intMix[i] = binary.LittleEndian.Uint32(mix[i*4:])
+	}
+	for i := uint32(0); i < datasetParents; i++ {
+		parent := fnv(index^i, intMix[i%16]) % rows
+		fnvHash(intMix, cache[parent*hashWords:])
+	}
+	for i, val := range intMix {
+		binary.LittleEndian.PutUint32(mix[i*4:], val)
+	}
+	mix = crypto.Keccak512(mix)
+	for i := range intMix {
+		intMix[i] = binary.LittleEndian.Uint32(mix[i*4:])
+	}
+	return intMix
+}
+
+// hashimotoLight aggregates the dataset items the seal hash and nonce select, computing each from the cache,
+// and returns the mix digest and the PoW result
+func hashimotoLight(size uint64, cache []uint32, hash []byte, nonce uint64) ([]byte, []byte) {
+	rows := uint32(size / mixBytes)
+	seed := make([]byte, 40)
+	copy(seed, hash)
+	binary.LittleEndian.PutUint64(seed[32:], nonce)
+	seed = crypto.Keccak512(seed)
+	seedHead := binary.LittleEndian.Uint32(seed)
+
+	mix := make([]uint32, mixBytes/4)
+	for i := range mix {
+		mix[i] = binary.LittleEndian.Uint32(seed[i%16*4:])
+	}
+	temp := make([]uint32, len(mix))
+	for i := 0; i < loopAccesses; i++ {
+		parent := fnv(uint32(i)^seedHead, mix[i%len(mix)]) % rows
+		for j := uint32(0); j < mixBytes/hashBytes; j++ {
+			copy(temp[j*hashWords:], datasetItem(cache, 2*parent+j))
+		}
+		fnvHash(mix, temp)
+	}
+	for i := 0; i < len(mix); i += 4 {
+		mix[i/4] = fnv(fnv(fnv(mix[i], mix[i+1]), mix[i+2]), mix[i+3])
+	}
+	mix = mix[:len(mix)/4]
+	digest := make([]byte, 32)
+	for i, val := range mix {
+		binary.LittleEndian.PutUint32(digest[i*4:], val)
+	}
+	return digest, crypto.Keccak256(append(seed, digest...))
+}
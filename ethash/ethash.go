@@ -0,0 +1,147 @@
+/*
+Package ethash verifies the proof-of-work seals of pre-merge headers, the MixDigest and Nonce of a header against its
+Difficulty, so archival pipelines can detect forged historical headers.
+
+Verification uses the light ethash algorithm, which computes the dataset items it needs from the verification cache
+of the epoch of the header instead of the full dataset. Generating a cache takes around a second and 16MB or more of
+memory, so a Verifier generates the cache of an epoch lazily, on the first header of that epoch it verifies, and keeps
+the caches of the most recently used epochs.
+*/
+package ethash
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/convert"
+)
+
+// defaultCaches is the number of epoch caches a Verifier keeps when none is given
+const defaultCaches = 3
+
+// two256 is the bound the PoW result of a header times its difficulty has to stay within
+var two256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// Verifier verifies ethash seals, generating the verification cache of each epoch on first use.
+// It is safe for concurrent use.
+type Verifier struct {
+	maxCaches int
+
+	mu     sync.Mutex
+	caches map[uint64]*epochCache
+	// recent holds the epochs of the kept caches, least recently used first
+	recent []uint64
+}
+
+// epochCache is the verification cache of an epoch, generated once by the first verification that needs it
+type epochCache struct {
+	once  sync.Once
+	cache []uint32
+}
+
+// NewVerifier returns a Verifier that keeps the caches of up to maxCaches epochs, or 3 if maxCaches is not positive
+func NewVerifier(maxCaches int) *Verifier {
+	if maxCaches <= 0 {
+		maxCaches = defaultCaches
+	}
+	return &Verifier{maxCaches: maxCaches, caches: make(map[uint64]*epochCache)}
+}
+
+var (
+	defaultVerifier     *Verifier
+	defaultVerifierOnce sync.Once
+)
+
+// VerifySeal checks the proof-of-work seal of the Header node with a Verifier shared across the package level calls,
+// which is only initialized on first use
+func VerifySeal(header ipld.Node) error {
+	defaultVerifierOnce.Do(func() { defaultVerifier = NewVerifier(defaultCaches) })
+	return defaultVerifier.VerifySeal(header)
+}
+
+// VerifySeal checks that the MixDigest of the Header node is the ethash mix digest of its seal hash and nonce,
+// and that the resulting PoW value meets its Difficulty. Post-merge headers, without a difficulty, are rejected.
+func (v *Verifier) VerifySeal(header ipld.Node) error {
+	h, err := convert.ToHeader(header)
+	if err != nil {
+		return err
+	}
+	return v.VerifyHeader(h)
+}
+
+// VerifyHeader is like VerifySeal, for a go-ethereum header
+func (v *Verifier) VerifyHeader(h *types.Header) error {
+	if h.Difficulty == nil || h.Difficulty.Sign() <= 0 {
+		return fmt.Errorf("header %d has no difficulty, it is not sealed with proof-of-work", h.Number)
+	}
+	number := h.Number.Uint64()
+	epoch := number / epochLength
+	digest, result := hashimotoLight(datasetSize(epoch), v.cache(epoch), SealHash(h).Bytes(), h.Nonce.Uint64())
+	if !bytes.Equal(h.MixDigest.Bytes(), digest) {
+		return fmt.Errorf("header %d mix digest %x does not match the ethash mix digest %x", number, h.MixDigest, digest)
+	}
+	target := new(big.Int).Div(two256, h.Difficulty)
+	if new(big.Int).SetBytes(result).Cmp(target) > 0 {
+		return fmt.Errorf("header %d proof-of-work does not meet its difficulty %d", number, h.Difficulty)
+	}
+	return nil
+}
+
+// cache returns the verification cache of the epoch, generating it if it is not kept yet
+func (v *Verifier) cache(epoch uint64) []uint32 {
+	v.mu.Lock()
+	c, ok := v.caches[epoch]
+	if !ok {
+		c = new(epochCache)
+		v.caches[epoch] = c
+		if len(v.recent) == v.maxCaches {
+			delete(v.caches, v.recent[0])
+			v.recent = v.recent[1:]
+		}
+	} else {
+		for i, e := range v.recent {
+			if e == epoch {
+				v.recent = append(v.recent[:i], v.recent[i+1:]...)
+				break
+			}
+		}
+	}
+	v.recent = append(v.recent, epoch)
+	v.mu.Unlock()
+	// the cache is generated outside of the lock, so verifications of other epochs are not held up
+	c.once.Do(func() { c.cache = generateCache(epoch) })
+	return c.cache
+}
+
+// SealHash returns the hash of the header the proof-of-work is computed over, the hash of the header without its
+// MixDigest and Nonce
+func SealHash(h *types.Header) common.Hash {
+	enc := []interface{}{
+		h.ParentHash,
+		h.UncleHash,
+		h.Coinbase,
+		h.Root,
+		h.TxHash,
+		h.ReceiptHash,
+		h.Bloom,
+		h.Difficulty,
+		h.Number,
+		h.GasLimit,
+		h.GasUsed,
+		h.Time,
+		h.Extra,
+	}
+	if h.BaseFee != nil {
+		enc = append(enc, h.BaseFee)
+	}
+	rlpEnc, _ := rlp.EncodeToBytes(enc)
+	return crypto.Keccak256Hash(rlpEnc)
+}
@@ -0,0 +1,60 @@
+package ethash_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/vulcanize/go-codec-dageth/convert"
+	"github.com/vulcanize/go-codec-dageth/ethash"
+)
+
+// mainnetBlock1 is the header of mainnet block 1, the first block mined with ethash
+func mainnetBlock1() *types.Header {
+	return &types.Header{
+		ParentHash:  common.HexToHash("0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"),
+		UncleHash:   types.EmptyUncleHash,
+		Coinbase:    common.HexToAddress("0x05a56e2d52c817161883f50c441c3228cfe54d9f"),
+		Root:        common.HexToHash("0xd67e4d450343046425ae4271474353857ab860dbc0a1dde64b41b5cd3a532bf3"),
+		TxHash:      types.EmptyTxsHash,
+		ReceiptHash: types.EmptyReceiptsHash,
+		Difficulty:  big.NewInt(17171480576),
+		Number:      big.NewInt(1),
+		GasLimit:    5000,
+		Time:        1438269988,
+		Extra:       common.FromHex("0x476574682f76312e302e302f6c696e75782f676f312e342e32"),
+		MixDigest:   common.HexToHash("0x969b900de27b6ac6a67742365dd65f55a0526c41fd18e1b16f1a1215c2e66f59"),
+		Nonce:       types.EncodeNonce(0x539bd4979fef1ec4),
+	}
+}
+
+func TestVerifySeal(t *testing.T) {
+	h := mainnetBlock1()
+	if expected := common.HexToHash("0x88e96d4537bea4d9c05d12549907b32561d3bf31f45aae734cdc119f13406cb6"); h.Hash() != expected {
+		t.Fatalf("mock header hashes to %x, not the mainnet block 1 hash %x", h.Hash(), expected)
+	}
+	node, err := convert.FromHeader(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ethash.VerifySeal(node); err != nil {
+		t.Fatalf("unable to verify the seal of mainnet block 1: %v", err)
+	}
+
+	v := ethash.NewVerifier(1)
+	for name, forge := range map[string]func(h *types.Header){
+		"changed nonce":      func(h *types.Header) { h.Nonce = types.EncodeNonce(h.Nonce.Uint64() + 1) },
+		"changed mix digest": func(h *types.Header) { h.MixDigest[0]++ },
+		"changed field":      func(h *types.Header) { h.GasLimit++ },
+		"higher difficulty":  func(h *types.Header) { h.Difficulty = new(big.Int).Lsh(h.Difficulty, 32) },
+		"no difficulty":      func(h *types.Header) { h.Difficulty = new(big.Int) },
+	} {
+		forged := mainnetBlock1()
+		forge(forged)
+		if err := v.VerifyHeader(forged); err == nil {
+			t.Errorf("expected the seal of a header with a %s to be rejected", name)
+		}
+	}
+}
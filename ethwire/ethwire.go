@@ -0,0 +1,179 @@
+/*
+Package ethwire serves requests of the eth devp2p wire protocol out of the DAG-ETH blocks reachable through a
+LinkSystem, so an IPLD archive can back a devp2p responder. Requested keccak256 hashes are mapped onto the CIDs of
+the DAG-ETH codecs that can be keyed by them, e.g. trie nodes onto eth-state-trie and eth-storage-trie CIDs and block
+hashes onto eth-block CIDs, whose receipt trie leads to the eth-tx-receipt blocks.
+Pending transactions of the eth/68 transaction pool messages convert into the same eth-tx nodes and CIDs as mined
+transactions, so mempool observers can content-address and deduplicate them.
+*/
+package ethwire
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/proof"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+)
+
+const (
+	// softResponseLimit is the target size of responses, as in go-ethereum
+	softResponseLimit = 2 * 1024 * 1024
+	// maxNodeDataServe is the number of state trie nodes and contract codes served per GetNodeData request
+	maxNodeDataServe = 1024
+	// maxReceiptsServe is the number of block receipt lists served per GetReceipts request
+	maxReceiptsServe = 1024
+)
+
+// GetNodeDataRequest is the eth/66 request for the state trie nodes and contract code with the given hashes.
+// The message was dropped from eth/67 on, and go-ethereum no longer defines it.
+type GetNodeDataRequest []common.Hash
+
+// GetNodeDataPacket is a GetNodeDataRequest with its request ID
+type GetNodeDataPacket struct {
+	RequestId uint64
+	GetNodeDataRequest
+}
+
+// NodeDataResponse is the eth/66 response holding the requested state trie nodes and contract code
+type NodeDataResponse [][]byte
+
+// NodeDataPacket is a NodeDataResponse with the ID of the request it answers
+type NodeDataPacket struct {
+	RequestId uint64
+	NodeDataResponse
+}
+
+// nodeDataCodecs are the codecs of the blocks a GetNodeData hash can refer to, in the order they are tried
+var nodeDataCodecs = []uint64{state_trie.MultiCodecType, storage_trie.MultiCodecType, cid.Raw}
+
+// NodeData answers the request with the state and storage trie nodes and contract codes that hash to the requested
+// hashes, in the order they were requested in. Like go-ethereum, hashes that cannot be loaded are left out, and the
+// response ends once it reaches the soft response limit.
+func NodeData(lsys ipld.LinkSystem, req *GetNodeDataPacket) (*NodeDataPacket, error) {
+	res := &NodeDataPacket{RequestId: req.RequestId}
+	var size int
+	for lookups, hash := range req.GetNodeDataRequest {
+		if size >= softResponseLimit || len(res.NodeDataResponse) >= maxNodeDataServe || lookups >= 2*maxNodeDataServe {
+			break
+		}
+		for _, codec := range nodeDataCodecs {
+			if data, err := loadBlock(lsys, codec, hash); err == nil {
+				res.NodeDataResponse = append(res.NodeDataResponse, data)
+				size += len(data)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// Receipts answers the request with the receipts of the blocks with the requested hashes, in the order they were
+// requested in, each list RLP encoded the way the Receipts message carries it. The receipts are read from the
+// receipt trie the header of the block commits to. Like go-ethereum, blocks whose receipts cannot be loaded are left
+// out, and the response ends once it reaches the soft response limit.
+func Receipts(lsys ipld.LinkSystem, req *eth.GetReceiptsPacket) (*eth.ReceiptsRLPPacket, error) {
+	res := &eth.ReceiptsRLPPacket{RequestId: req.RequestId}
+	var size int
+	for lookups, hash := range req.GetReceiptsRequest {
+		if size >= softResponseLimit || len(res.ReceiptsRLPResponse) >= maxReceiptsServe || lookups >= 2*maxReceiptsServe {
+			break
+		}
+		encoded, err := blockReceipts(lsys, hash)
+		if err != nil {
+			continue
+		}
+		res.ReceiptsRLPResponse = append(res.ReceiptsRLPResponse, encoded)
+		size += len(encoded)
+	}
+	return res, nil
+}
+
+// blockReceipts returns the RLP encoded receipt list of the block with the hash
+func blockReceipts(lsys ipld.LinkSystem, hash common.Hash) (rlp.RawValue, error) {
+	headerRLP, err := loadBlock(lsys, header.MultiCodecType, hash)
+	if err != nil {
+		return nil, err
+	}
+	h := new(types.Header)
+	if err := rlp.DecodeBytes(headerRLP, h); err != nil {
+		return nil, fmt.Errorf("invalid header %x: %v", hash, err)
+	}
+	if h.ReceiptHash == types.EmptyRootHash {
+		return rlp.EncodeToBytes([]*types.Receipt{})
+	}
+	rootCID, err := shared.Keccak256ToCid(rct_trie.MultiCodecType, h.ReceiptHash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	rp, err := proof.GenerateRangeProof(lsys, cidlink.Link{Cid: rootCID}, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	// the receipt trie is keyed by the RLP encoded index, whose byte order is not the index order
+	indices := make([]uint64, len(rp.Keys))
+	for i, key := range rp.Keys {
+		if err := rlp.DecodeBytes(key, &indices[i]); err != nil {
+			return nil, fmt.Errorf("invalid receipt trie key %x: %v", key, err)
+		}
+	}
+	order := make([]int, len(rp.Keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return indices[order[i]] < indices[order[j]] })
+	// the leaves hold the consensus encoding of the receipts, which is a list for legacy receipts
+	// and a typed envelope for the others, the latter being wrapped into a byte string in the list
+	list := make([]rlp.RawValue, len(order))
+	for i, j := range order {
+		if indices[j] != uint64(i) {
+			return nil, fmt.Errorf("receipt trie %x is missing receipt %d", h.ReceiptHash, i)
+		}
+		leaf := rp.Values[j]
+		if len(leaf) > 0 && leaf[0] >= 0xc0 {
+			list[i] = leaf
+			continue
+		}
+		if list[i], err = rlp.EncodeToBytes(leaf); err != nil {
+			return nil, err
+		}
+	}
+	return rlp.EncodeToBytes(list)
+}
+
+// loadBlock reads the block with the keccak256 CID of its multicodec through the LinkSystem, and checks its hash
+func loadBlock(lsys ipld.LinkSystem, codec uint64, hash common.Hash) ([]byte, error) {
+	if lsys.StorageReadOpener == nil {
+		return nil, fmt.Errorf("no storage configured for reading")
+	}
+	c, err := shared.Keccak256ToCid(codec, hash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	r, err := lsys.StorageReadOpener(ipld.LinkContext{}, cidlink.Link{Cid: c})
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if crypto.Keccak256Hash(data) != hash {
+		return nil, fmt.Errorf("block %s does not match its hash", c.String())
+	}
+	return data, nil
+}
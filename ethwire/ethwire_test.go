@@ -0,0 +1,240 @@
+package ethwire_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/blob_sidecar"
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/convert"
+	"github.com/vulcanize/go-codec-dageth/ethwire"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+func TestNodeData(t *testing.T) {
+	g := testutil.NewGenerator(1)
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	accounts := make([][]byte, 32)
+	for i := range accounts {
+		accounts[i], _ = rlp.EncodeToBytes(g.Account())
+	}
+	nodes := g.TrieNodes(accounts)
+	for _, node := range nodes {
+		store.Bag[testutil.KeccakLink(t, state_trie.MultiCodecType, crypto.Keccak256(node))] = node
+	}
+	code := g.Bytes(100)
+	store.Bag[testutil.KeccakLink(t, cid.Raw, crypto.Keccak256(code))] = code
+
+	req := &ethwire.GetNodeDataPacket{
+		RequestId:          7,
+		GetNodeDataRequest: ethwire.GetNodeDataRequest{crypto.Keccak256Hash(nodes[0]), g.Hash(), crypto.Keccak256Hash(code), crypto.Keccak256Hash(nodes[1])},
+	}
+	res, err := ethwire.NodeData(codecs.NewLinkSystem(store), req)
+	if err != nil {
+		t.Fatalf("unable to serve node data: %v", err)
+	}
+	expected := [][]byte{nodes[0], code, nodes[1]}
+	if res.RequestId != 7 || len(res.NodeDataResponse) != len(expected) {
+		t.Fatalf("expected a response to request 7 with %d entries, got %d for %d", len(expected), len(res.NodeDataResponse), res.RequestId)
+	}
+	for i, data := range res.NodeDataResponse {
+		if !bytes.Equal(data, expected[i]) {
+			t.Errorf("node data entry %d does not match", i)
+		}
+	}
+
+	// the response round trips through the eth/66 encoding
+	enc, err := rlp.EncodeToBytes(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(ethwire.NodeDataPacket)
+	if err := rlp.DecodeBytes(enc, decoded); err != nil || len(decoded.NodeDataResponse) != len(expected) {
+		t.Errorf("unable to decode the response (%v)", err)
+	}
+}
+
+func TestReceipts(t *testing.T) {
+	g := testutil.NewGenerator(2)
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	var hashes []common.Hash
+	var expected [][]byte
+	for i, txCount := range []int{200, 0, 3} {
+		h := g.Header()
+		h.Number.SetUint64(uint64(i))
+		txs := g.Transactions(txCount)
+		receipts := g.Receipts(txs)
+		body := &types.Body{Transactions: txs}
+		if h.WithdrawalsHash != nil {
+			body.Withdrawals = g.Withdrawals(2)
+		}
+		blk := types.NewBlock(h, body, receipts, gethtrie.NewStackTrie(nil))
+		if _, err := block.PackBlock(blk, receipts, lsys); err != nil {
+			t.Fatalf("unable to pack block %d: %v", i, err)
+		}
+		enc, err := rlp.EncodeToBytes(receipts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, blk.Hash())
+		expected = append(expected, enc)
+	}
+
+	req := &eth.GetReceiptsPacket{RequestId: 3, GetReceiptsRequest: eth.GetReceiptsRequest{hashes[0], g.Hash(), hashes[1], hashes[2]}}
+	res, err := ethwire.Receipts(lsys, req)
+	if err != nil {
+		t.Fatalf("unable to serve receipts: %v", err)
+	}
+	if res.RequestId != 3 || len(res.ReceiptsRLPResponse) != len(expected) {
+		t.Fatalf("expected a response to request 3 with %d receipt lists, got %d for %d", len(expected), len(res.ReceiptsRLPResponse), res.RequestId)
+	}
+	for i, enc := range res.ReceiptsRLPResponse {
+		if !bytes.Equal(enc, expected[i]) {
+			t.Errorf("receipts of block %d do not match their encoding", i)
+		}
+	}
+
+	// the response decodes into the receipts packet of the eth protocol
+	enc, err := rlp.EncodeToBytes(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(eth.ReceiptsPacket)
+	if err := rlp.DecodeBytes(enc, decoded); err != nil {
+		t.Fatalf("unable to decode the response: %v", err)
+	}
+	if len(decoded.ReceiptsResponse[0]) != 200 || len(decoded.ReceiptsResponse[1]) != 0 || len(decoded.ReceiptsResponse[2]) != 3 {
+		t.Errorf("unexpected receipt counts in the decoded response")
+	}
+}
+
+func TestPooledTransactions(t *testing.T) {
+	g := testutil.NewGenerator(3)
+	var txs types.Transactions
+	for _, txType := range []uint8{types.LegacyTxType, types.AccessListTxType, types.DynamicFeeTxType, types.BlobTxType, types.SetCodeTxType} {
+		t := g.Transaction(txType)
+		if txType == types.BlobTxType {
+			t = t.WithBlobTxSidecar(g.BlobSidecar())
+		}
+		txs = append(txs, t)
+	}
+	var res eth.PooledTransactionsRLPResponse
+	for _, t := range txs {
+		enc, _ := rlp.EncodeToBytes(t)
+		res = append(res, enc)
+	}
+	pooled, err := ethwire.DecodePooledTransactions(res)
+	if err != nil {
+		t.Fatalf("unable to decode pooled transactions: %v", err)
+	}
+	for i, p := range pooled {
+		// the pooled transaction has the CID of the mined one
+		expected, err := convert.TransactionCID(txs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.Cid.Equals(expected) {
+			t.Errorf("pooled transaction %d has CID %s, expected %s", i, p.Cid, expected)
+		}
+		if p.Type != txs[i].Type() || uint64(p.Size) != txs[i].Size() {
+			t.Errorf("pooled transaction %d has type %d and size %d, expected %d and %d", i, p.Type, p.Size, txs[i].Type(), txs[i].Size())
+		}
+		if hasSidecar := p.Sidecar != nil; hasSidecar != (txs[i].Type() == types.BlobTxType) {
+			t.Errorf("pooled transaction %d of type %d has a sidecar: %v", i, p.Type, hasSidecar)
+		}
+	}
+	sidecarRLP, _ := rlp.EncodeToBytes(txs[3].BlobTxSidecar())
+	if expected := testutil.KeccakCid(t, blob_sidecar.MultiCodecType, crypto.Keccak256(sidecarRLP)); !pooled[3].SidecarCid.Equals(expected) {
+		t.Errorf("blob sidecar has CID %s, expected %s", pooled[3].SidecarCid, expected)
+	}
+
+	// the transactions encode back into the response, which go-ethereum decodes
+	packet, err := ethwire.PooledTransactions(5, pooled)
+	if err != nil {
+		t.Fatalf("unable to encode pooled transactions: %v", err)
+	}
+	for i, enc := range packet.PooledTransactionsRLPResponse {
+		if !bytes.Equal(enc, res[i]) {
+			t.Errorf("pooled transaction %d does not encode back into its entry of the response", i)
+		}
+	}
+	enc, err := rlp.EncodeToBytes(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(eth.PooledTransactionsPacket)
+	if err := rlp.DecodeBytes(enc, decoded); err != nil {
+		t.Fatalf("unable to decode the response: %v", err)
+	}
+	if decoded.RequestId != 5 || len(decoded.PooledTransactionsResponse) != len(txs) || decoded.PooledTransactionsResponse[3].BlobTxSidecar() == nil {
+		t.Errorf("unexpected decoded response")
+	}
+	if _, err := ethwire.DecodePooledTransaction(mustRLP(t, txs[3].WithoutBlobTxSidecar())); err == nil {
+		t.Error("expected an error decoding a pooled blob transaction without its sidecar")
+	}
+
+	// the announcement matches the one go-ethereum makes
+	ann, err := ethwire.Announce(pooled)
+	if err != nil {
+		t.Fatalf("unable to announce pooled transactions: %v", err)
+	}
+	for i, tx := range txs {
+		if ann.Types[i] != tx.Type() || ann.Sizes[i] != uint32(tx.Size()) || ann.Hashes[i] != tx.Hash() {
+			t.Errorf("announcement of transaction %d does not match", i)
+		}
+	}
+	if err := ethwire.VerifyAnnounced(ann, pooled); err != nil {
+		t.Errorf("expected the transactions to match their announcement: %v", err)
+	}
+	ann.Sizes[2]++
+	if err := ethwire.VerifyAnnounced(ann, pooled); err == nil {
+		t.Error("expected an error for a transaction delivered with another size than announced")
+	}
+	ann.Sizes[2]--
+	unannounced, err := convert.TransactionCID(g.Transaction(types.LegacyTxType))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ethwire.VerifyAnnounced(ann, append(pooled, &ethwire.PooledTransaction{Cid: unannounced})); err == nil {
+		t.Error("expected an error for a transaction that was not announced")
+	}
+
+	// announced transactions already held, and repeated announcements, are not requested
+	ann.Types, ann.Sizes, ann.Hashes = append(ann.Types, ann.Types[1]), append(ann.Sizes, ann.Sizes[1]), append(ann.Hashes, ann.Hashes[1])
+	held, err := convert.TransactionCID(txs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := ethwire.RequestAnnounced(9, ann, func(c cid.Cid) bool { return c.Equals(held) })
+	if err != nil {
+		t.Fatalf("unable to request announced transactions: %v", err)
+	}
+	if req.RequestId != 9 || len(req.GetPooledTransactionsRequest) != 4 || req.GetPooledTransactionsRequest[0] != txs[1].Hash() {
+		t.Errorf("expected a request for transactions 1 to 4, got %v", req.GetPooledTransactionsRequest)
+	}
+	ann.Sizes = ann.Sizes[:1]
+	if _, err := ethwire.AnnouncedCIDs(ann); err == nil {
+		t.Error("expected an error for an announcement with fewer sizes than hashes")
+	}
+}
+
+func mustRLP(t *testing.T, tx *types.Transaction) []byte {
+	enc, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
@@ -0,0 +1,207 @@
+package ethwire
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/blob_sidecar"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/tx"
+)
+
+// PooledTransaction is a pending transaction as carried by the PooledTransactions message, split into DAG-ETH nodes
+type PooledTransaction struct {
+	// Tx is the Transaction node, which holds the consensus encoding the transaction is mined with
+	Tx ipld.Node
+	// Cid is the eth-tx CID of the transaction, the same CID it has in the transaction trie once mined
+	Cid cid.Cid
+	// Sidecar is the BlobSidecar node of a blob transaction, nil for the other transaction types
+	Sidecar ipld.Node
+	// SidecarCid is the eth-blob-sidecar CID of the Sidecar, cid.Undef without one
+	SidecarCid cid.Cid
+	// Type is the transaction type, as announced by NewPooledTransactionHashes
+	Type byte
+	// Size is the size of the network encoding of the transaction, sidecar included, as announced
+	Size uint32
+}
+
+// DecodePooledTransaction decodes a transaction entry of the eth/68 PooledTransactions message. Entries hold the
+// network encoding of the transactions, which is the consensus encoding for every transaction type but blob
+// transactions, whose network encoding wraps the transaction together with its blobs, commitments and proofs, with
+// the encodings of typed transactions wrapped into RLP byte strings.
+func DecodePooledTransaction(enc []byte) (*PooledTransaction, error) {
+	t := new(types.Transaction)
+	if err := rlp.DecodeBytes(enc, t); err != nil {
+		return nil, err
+	}
+	if t.Type() == types.BlobTxType && t.BlobTxSidecar() == nil {
+		return nil, fmt.Errorf("blob transaction %s is missing its sidecar", t.Hash().Hex())
+	}
+	nb := dageth.Type.Transaction.NewBuilder()
+	if err := tx.DecodeTx(nb, t.WithoutBlobTxSidecar()); err != nil {
+		return nil, err
+	}
+	c, err := shared.Keccak256ToCid(tx.MultiCodecType, t.Hash().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	p := &PooledTransaction{
+		Tx:   nb.Build(),
+		Cid:  c,
+		Type: t.Type(),
+		Size: uint32(t.Size()),
+	}
+	if sidecar := t.BlobTxSidecar(); sidecar != nil {
+		sidecarRLP, err := rlp.EncodeToBytes(sidecar)
+		if err != nil {
+			return nil, err
+		}
+		sb := dageth.Type.BlobSidecar.NewBuilder()
+		if err := blob_sidecar.DecodeSidecar(sb, sidecar); err != nil {
+			return nil, err
+		}
+		p.Sidecar = sb.Build()
+		if p.SidecarCid, err = shared.Keccak256ToCid(blob_sidecar.MultiCodecType, crypto.Keccak256(sidecarRLP)); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// DecodePooledTransactions decodes the transactions of a PooledTransactions response, in the order of the response
+func DecodePooledTransactions(res eth.PooledTransactionsRLPResponse) ([]*PooledTransaction, error) {
+	txs := make([]*PooledTransaction, len(res))
+	for i, enc := range res {
+		p, err := DecodePooledTransaction(enc)
+		if err != nil {
+			return nil, fmt.Errorf("pooled transaction %d: %v", i, err)
+		}
+		txs[i] = p
+	}
+	return txs, nil
+}
+
+// EncodePooledTransaction encodes the Transaction node, and the BlobSidecar node of a blob transaction, into an entry
+// of the PooledTransactions message
+func EncodePooledTransaction(txNode, sidecarNode ipld.Node) ([]byte, error) {
+	t := new(types.Transaction)
+	if err := tx.EncodeTx(t, txNode); err != nil {
+		return nil, err
+	}
+	if t.Type() != types.BlobTxType {
+		if sidecarNode != nil {
+			return nil, fmt.Errorf("transaction of type %d cannot carry a blob sidecar", t.Type())
+		}
+		return rlp.EncodeToBytes(t)
+	}
+	if sidecarNode == nil {
+		return nil, fmt.Errorf("blob transaction %s is missing its sidecar", t.Hash().Hex())
+	}
+	sidecar := new(types.BlobTxSidecar)
+	if err := blob_sidecar.EncodeSidecar(sidecar, sidecarNode); err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(t.WithBlobTxSidecar(sidecar))
+}
+
+// PooledTransactions answers a GetPooledTransactions request with the transactions, encoded the way the
+// PooledTransactions message carries them, in the order they are given in
+func PooledTransactions(requestID uint64, txs []*PooledTransaction) (*eth.PooledTransactionsRLPPacket, error) {
+	res := &eth.PooledTransactionsRLPPacket{RequestId: requestID}
+	for i, p := range txs {
+		enc, err := EncodePooledTransaction(p.Tx, p.Sidecar)
+		if err != nil {
+			return nil, fmt.Errorf("pooled transaction %d: %v", i, err)
+		}
+		res.PooledTransactionsRLPResponse = append(res.PooledTransactionsRLPResponse, enc)
+	}
+	return res, nil
+}
+
+// Announce builds the eth/68 NewPooledTransactionHashes announcement of the transactions
+func Announce(txs []*PooledTransaction) (*eth.NewPooledTransactionHashesPacket, error) {
+	ann := &eth.NewPooledTransactionHashesPacket{
+		Types:  make([]byte, len(txs)),
+		Sizes:  make([]uint32, len(txs)),
+		Hashes: make([]common.Hash, len(txs)),
+	}
+	for i, p := range txs {
+		hash, err := tx.Hash(p.Tx)
+		if err != nil {
+			return nil, fmt.Errorf("pooled transaction %d: %v", i, err)
+		}
+		ann.Types[i], ann.Sizes[i], ann.Hashes[i] = p.Type, p.Size, hash
+	}
+	return ann, nil
+}
+
+// AnnouncedCIDs maps the hashes of a NewPooledTransactionHashes announcement onto the eth-tx CIDs of the
+// transactions, returning an error if the types, sizes and hashes of the announcement are not of the same length
+func AnnouncedCIDs(ann *eth.NewPooledTransactionHashesPacket) ([]cid.Cid, error) {
+	if len(ann.Types) != len(ann.Hashes) || len(ann.Sizes) != len(ann.Hashes) {
+		return nil, fmt.Errorf("announcement has %d types and %d sizes for %d hashes", len(ann.Types), len(ann.Sizes), len(ann.Hashes))
+	}
+	cids := make([]cid.Cid, len(ann.Hashes))
+	for i, hash := range ann.Hashes {
+		c, err := shared.Keccak256ToCid(tx.MultiCodecType, hash.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		cids[i] = c
+	}
+	return cids, nil
+}
+
+// RequestAnnounced builds the GetPooledTransactions request for the transactions of the announcement whose CIDs the
+// has callback does not know, e.g. as pending or already mined transactions of a blockstore, leaving out the
+// transactions announced more than once
+func RequestAnnounced(requestID uint64, ann *eth.NewPooledTransactionHashesPacket, has func(cid.Cid) bool) (*eth.GetPooledTransactionsPacket, error) {
+	cids, err := AnnouncedCIDs(ann)
+	if err != nil {
+		return nil, err
+	}
+	req := &eth.GetPooledTransactionsPacket{RequestId: requestID}
+	seen := make(map[cid.Cid]bool, len(cids))
+	for i, c := range cids {
+		if seen[c] || has(c) {
+			continue
+		}
+		seen[c] = true
+		req.GetPooledTransactionsRequest = append(req.GetPooledTransactionsRequest, ann.Hashes[i])
+	}
+	return req, nil
+}
+
+// VerifyAnnounced checks that every transaction was announced, and with the type and size it was delivered with,
+// as go-ethereum does before accepting the transactions it fetched in response to an announcement
+func VerifyAnnounced(ann *eth.NewPooledTransactionHashesPacket, txs []*PooledTransaction) error {
+	cids, err := AnnouncedCIDs(ann)
+	if err != nil {
+		return err
+	}
+	announced := make(map[cid.Cid]int, len(cids))
+	for i, c := range cids {
+		announced[c] = i
+	}
+	for _, p := range txs {
+		i, ok := announced[p.Cid]
+		if !ok {
+			return fmt.Errorf("transaction %s was not announced", p.Cid.String())
+		}
+		if ann.Types[i] != p.Type {
+			return fmt.Errorf("transaction %s was announced with type %d, got type %d", p.Cid.String(), ann.Types[i], p.Type)
+		}
+		if ann.Sizes[i] != p.Size {
+			return fmt.Errorf("transaction %s was announced with size %d, got size %d", p.Cid.String(), ann.Sizes[i], p.Size)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	"github.com/vulcanize/go-codec-dageth/helpers"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// ExportAccess writes the minimal witness of the access set under stateRoot, as loaded through the LinkSystem, into
+// a CARv2 archive with stateRoot as its root: the state trie nodes on the path to the account of every address of
+// the access list, the contract code of each account, and the storage trie nodes on the paths to its storage keys.
+// The paths of accounts and slots that do not exist end at the nodes proving them absent.
+// Blocks are written in the order of the access list, each path from its root down, and blocks shared by several
+// paths are written once, so the same access list always produces the same archive.
+func ExportAccess(w io.WriteSeeker, lsys ipld.LinkSystem, stateRoot ipld.Link, access types.AccessList) error {
+	cl, ok := stateRoot.(cidlink.Link)
+	if !ok {
+		return fmt.Errorf("expected a cidlink.Link, got %T", stateRoot)
+	}
+	if codec := cl.Prefix().Codec; codec != state_trie.MultiCodecType {
+		return fmt.Errorf("expected a state trie root, got a link with codec %#x", codec)
+	}
+	cw, err := NewCARWriter(w, []cid.Cid{cl.Cid})
+	if err != nil {
+		return err
+	}
+	for _, tuple := range access {
+		val, err := putPath(cw, lsys, cl.Cid, helpers.AddressToNibbles(tuple.Address))
+		if err != nil {
+			return fmt.Errorf("account %s: %v", tuple.Address.Hex(), err)
+		}
+		if val == nil {
+			continue
+		}
+		account, err := val.LookupByString(trie.STATE_VALUE.String())
+		if err != nil {
+			return fmt.Errorf("account %s: %v", tuple.Address.Hex(), err)
+		}
+		code, err := linkedCid(account, "CodeCID")
+		if err != nil {
+			return fmt.Errorf("account %s: %v", tuple.Address.Hex(), err)
+		}
+		if !isEmptyCode(code) {
+			data, err := readBlock(lsys, code)
+			if err != nil {
+				return fmt.Errorf("code of account %s: %v", tuple.Address.Hex(), err)
+			}
+			if _, err := cw.Put(code, data); err != nil {
+				return err
+			}
+		}
+		storageRoot, err := linkedCid(account, "StorageRootCID")
+		if err != nil {
+			return fmt.Errorf("account %s: %v", tuple.Address.Hex(), err)
+		}
+		if isEmptyRoot(storageRoot) {
+			continue
+		}
+		for _, slot := range tuple.StorageKeys {
+			if _, err := putPath(cw, lsys, storageRoot, helpers.StorageSlotToNibbles(slot)); err != nil {
+				return fmt.Errorf("slot %s of account %s: %v", slot.Hex(), tuple.Address.Hex(), err)
+			}
+		}
+	}
+	return cw.Close()
+}
+
+// putPath puts the trie nodes from the root along the nibble path into the archive, and returns the Value the path
+// ends at, or nil if the trie does not hold it
+func putPath(cw *CARWriter, lsys ipld.LinkSystem, root cid.Cid, nibbles []byte) (ipld.Node, error) {
+	c, remaining := root, nibbles
+	for {
+		node, err := putBlock(cw, lsys, c)
+		if err != nil {
+			return nil, err
+		}
+		val, next, rest, err := helpers.Step(node, remaining)
+		if err != nil || next == nil {
+			return val, err
+		}
+		cl, ok := next.(cidlink.Link)
+		if !ok {
+			return nil, fmt.Errorf("expected a cidlink.Link, got %T", next)
+		}
+		c, remaining = cl.Cid, rest
+	}
+}
+
+// linkedCid returns the CID the link of the field of the node references
+func linkedCid(node ipld.Node, field string) (cid.Cid, error) {
+	linkNode, err := node.LookupByString(field)
+	if err != nil {
+		return cid.Undef, err
+	}
+	lnk, err := linkNode.AsLink()
+	if err != nil {
+		return cid.Undef, err
+	}
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return cid.Undef, fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+	}
+	return cl.Cid, nil
+}
@@ -0,0 +1,172 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/log_trie"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+// blockLinks are the header links followed into the DAG of a block, in the order they are exported
+var blockLinks = []string{"TxRootCID", "RctRootCID", "UnclesCID", "WithdrawalsRootCID"}
+
+// blockCodecs are the codecs of the IPLD blocks that make up the DAG of a block below its header.
+// Links to other headers and to state tries are not followed.
+var blockCodecs = map[uint64]bool{
+	tx_trie.MultiCodecType:         true,
+	rct_trie.MultiCodecType:        true,
+	log_trie.MultiCodecType:        true,
+	uncles.MultiCodecType:          true,
+	withdrawal_trie.MultiCodecType: true,
+}
+
+// ExportBlocks writes the DAGs of the blocks whose headers are referenced by headerLinks, as loaded through the
+// LinkSystem, into a CARv2 archive with the header CIDs as its roots.
+// For each header in turn the archive holds the header, the transaction trie, the receipt trie along with the log
+// tries linked from its receipts, the uncles and the withdrawal trie, each trie walked depth first in child order,
+// so the same blocks always produce the same archive.
+func ExportBlocks(w io.WriteSeeker, lsys ipld.LinkSystem, headerLinks []ipld.Link) error {
+	roots := make([]cid.Cid, len(headerLinks))
+	for i, lnk := range headerLinks {
+		cl, ok := lnk.(cidlink.Link)
+		if !ok {
+			return fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+		}
+		roots[i] = cl.Cid
+	}
+	cw, err := NewCARWriter(w, roots)
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		headerNode, err := putBlock(cw, lsys, root)
+		if err != nil {
+			return err
+		}
+		for _, field := range blockLinks {
+			linkNode, err := headerNode.LookupByString(field)
+			if err != nil {
+				return err
+			}
+			if linkNode.IsNull() {
+				continue
+			}
+			lnk, err := linkNode.AsLink()
+			if err != nil {
+				return err
+			}
+			if err := walkBlocks(cw, lsys, lnk.(cidlink.Link).Cid); err != nil {
+				return err
+			}
+		}
+	}
+	return cw.Close()
+}
+
+// walkBlocks puts the block and then, depth first, every block it links to that belongs to the DAG of a block
+func walkBlocks(cw *CARWriter, lsys ipld.LinkSystem, c cid.Cid) error {
+	if !blockCodecs[c.Prefix().Codec] || cw.Has(c) || isEmptyRoot(c) {
+		return nil
+	}
+	node, err := putBlock(cw, lsys, c)
+	if err != nil {
+		return err
+	}
+	for _, lnk := range collectLinks(node, nil) {
+		if err := walkBlocks(cw, lsys, lnk.(cidlink.Link).Cid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putBlock reads the block through the LinkSystem, verifies it against its CID, puts it into the archive,
+// and returns it decoded
+func putBlock(cw *CARWriter, lsys ipld.LinkSystem, c cid.Cid) (ipld.Node, error) {
+	data, err := readBlock(lsys, c)
+	if err != nil {
+		return nil, err
+	}
+	lnk := cidlink.Link{Cid: c}
+	np, err := codecs.NodePrototypeChooser(lnk, ipld.LinkContext{})
+	if err != nil {
+		return nil, err
+	}
+	nb := np.NewBuilder()
+	if err := codecs.DecodeByCodec(nb, bytes.NewReader(data), c.Prefix().Codec); err != nil {
+		return nil, fmt.Errorf("unable to decode block %s: %v", c.String(), err)
+	}
+	if _, err := cw.Put(c, data); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
+}
+
+func readBlock(lsys ipld.LinkSystem, c cid.Cid) ([]byte, error) {
+	if lsys.StorageReadOpener == nil {
+		return nil, fmt.Errorf("no storage configured for reading")
+	}
+	r, err := lsys.StorageReadOpener(ipld.LinkContext{}, cidlink.Link{Cid: c})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read block %s: %v", c.String(), err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := c.Prefix().Sum(data)
+	if err != nil {
+		return nil, err
+	}
+	if !sum.Equals(c) {
+		return nil, fmt.Errorf("block %s does not match its CID", c.String())
+	}
+	return data, nil
+}
+
+// collectLinks appends every link found in the node, in iteration order, to links
+func collectLinks(node ipld.Node, links []ipld.Link) []ipld.Link {
+	switch node.Kind() {
+	case ipld.Kind_Link:
+		lnk, err := node.AsLink()
+		if err == nil {
+			links = append(links, lnk)
+		}
+	case ipld.Kind_Map:
+		for it := node.MapIterator(); !it.Done(); {
+			_, v, err := it.Next()
+			if err != nil {
+				break
+			}
+			links = collectLinks(v, links)
+		}
+	case ipld.Kind_List:
+		for it := node.ListIterator(); !it.Done(); {
+			_, v, err := it.Next()
+			if err != nil {
+				break
+			}
+			links = collectLinks(v, links)
+		}
+	}
+	return links
+}
+
+// isEmptyRoot returns whether the CID references the root of an empty trie, which is never stored
+func isEmptyRoot(c cid.Cid) bool {
+	return shared.HasHash(c, multihash.KECCAK_256, types.EmptyRootHash.Bytes())
+}
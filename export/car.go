@@ -0,0 +1,150 @@
+/*
+Package export writes DAG-ETH IPLD blocks into CAR archives, so Ethereum data can be distributed through
+Filecoin and IPFS pinning services, and imports them back into a LinkSystem. It also verifies the CAR responses of
+IPFS trustless gateways to header, path and account queries, for light clients that do not trust the gateway.
+ExportState dumps a whole state trie, and ExportAccess the minimal witness of an access list.
+*/
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/fluent"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+)
+
+// CARv2Pragma is the fixed prefix that identifies a CARv2 archive
+var CARv2Pragma = []byte{0x0a, 0xa1, 0x67, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x02}
+
+const (
+	// carV2HeaderSize is the size of the CARv2 header that follows the pragma:
+	// 16 bytes of characteristics, and the data offset, data size and index offset as little endian uint64s
+	carV2HeaderSize = 40
+	// carV2DataOffset is where the inner CARv1 payload starts, right after the 11 byte pragma and the header
+	carV2DataOffset = 11 + carV2HeaderSize
+)
+
+// CARWriter streams blocks into a CARv2 archive that wraps a CARv1 payload, with no index.
+// Each CID is written once, in the order it is first put, so the same sequence of puts always produces the same
+// archive. It is safe for concurrent use.
+type CARWriter struct {
+	mu       sync.Mutex
+	w        io.WriteSeeker
+	dataSize uint64
+	seen     map[cid.Cid]struct{}
+	closed   bool
+}
+
+// NewCARWriter writes the CARv2 pragma and header, and the CARv1 header with the given roots, to w
+func NewCARWriter(w io.WriteSeeker, roots []cid.Cid) (*CARWriter, error) {
+	if _, err := w.Write(CARv2Pragma); err != nil {
+		return nil, err
+	}
+	// the data size is patched in when the writer is closed
+	if _, err := w.Write(make([]byte, carV2HeaderSize)); err != nil {
+		return nil, err
+	}
+	header, err := encodeCARv1Header(roots)
+	if err != nil {
+		return nil, err
+	}
+	cw := &CARWriter{w: w, seen: make(map[cid.Cid]struct{})}
+	if err := cw.writeSection(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Put writes the block to the archive, unless a block with the same CID has already been written.
+// It returns whether the block was written.
+func (cw *CARWriter) Put(c cid.Cid, data []byte) (bool, error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if cw.closed {
+		return false, fmt.Errorf("CAR writer is closed")
+	}
+	if _, ok := cw.seen[c]; ok {
+		return false, nil
+	}
+	cw.seen[c] = struct{}{}
+	return true, cw.writeSection(c.Bytes(), data)
+}
+
+// Has returns whether a block with the CID has been written to the archive
+func (cw *CARWriter) Has(c cid.Cid) bool {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	_, ok := cw.seen[c]
+	return ok
+}
+
+// Close completes the CARv2 header with the size of the CARv1 payload, and leaves w positioned at the end of
+// the archive. It does not close w.
+func (cw *CARWriter) Close() error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if cw.closed {
+		return nil
+	}
+	cw.closed = true
+	header := make([]byte, carV2HeaderSize)
+	binary.LittleEndian.PutUint64(header[16:], carV2DataOffset)
+	binary.LittleEndian.PutUint64(header[24:], cw.dataSize)
+	if _, err := cw.w.Seek(int64(len(CARv2Pragma)), io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := cw.w.Write(header); err != nil {
+		return err
+	}
+	_, err := cw.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// writeSection writes the parts prefixed by the varint of their total length
+func (cw *CARWriter) writeSection(parts ...[]byte) error {
+	var length int
+	for _, part := range parts {
+		length += len(part)
+	}
+	prefix := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(prefix, uint64(length))
+	if _, err := cw.w.Write(prefix[:n]); err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if _, err := cw.w.Write(part); err != nil {
+			return err
+		}
+	}
+	cw.dataSize += uint64(n + length)
+	return nil
+}
+
+// encodeCARv1Header encodes the DAG-CBOR {roots, version} header of a CARv1 payload
+func encodeCARv1Header(roots []cid.Cid) ([]byte, error) {
+	header, err := fluent.Build(basicnode.Prototype.Map, func(na fluent.NodeAssembler) {
+		na.CreateMap(2, func(ma fluent.MapAssembler) {
+			ma.AssembleEntry("roots").CreateList(int64(len(roots)), func(la fluent.ListAssembler) {
+				for _, root := range roots {
+					la.AssembleValue().AssignLink(cidlink.Link{Cid: root})
+				}
+			})
+			ma.AssembleEntry("version").AssignInt(1)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := dagcbor.Encode(header, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,534 @@
+package export_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/export"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/helpers"
+	"github.com/vulcanize/go-codec-dageth/log_trie"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+type carBlock struct {
+	cid  cid.Cid
+	data []byte
+}
+
+// readCAR parses a CARv2 archive without an index, returning its roots and blocks in order
+func readCAR(t *testing.T, data []byte) ([]cid.Cid, []carBlock) {
+	if !bytes.HasPrefix(data, export.CARv2Pragma) {
+		t.Fatal("archive does not start with the CARv2 pragma")
+	}
+	header := data[len(export.CARv2Pragma) : len(export.CARv2Pragma)+40]
+	dataOffset := binary.LittleEndian.Uint64(header[16:])
+	dataSize := binary.LittleEndian.Uint64(header[24:])
+	if indexOffset := binary.LittleEndian.Uint64(header[32:]); indexOffset != 0 {
+		t.Fatalf("expected no index, got index offset %d", indexOffset)
+	}
+	if dataOffset+dataSize != uint64(len(data)) {
+		t.Fatalf("CARv1 payload (%d+%d) does not span the rest of the archive (%d)", dataOffset, dataSize, len(data))
+	}
+	r := bufio.NewReader(bytes.NewReader(data[dataOffset:]))
+	readSection := func() []byte {
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil
+		}
+		section := make([]byte, length)
+		if _, err := io.ReadFull(r, section); err != nil {
+			t.Fatalf("unable to read section: %v", err)
+		}
+		return section
+	}
+
+	nb := basicnode.Prototype.Map.NewBuilder()
+	if err := dagcbor.Decode(nb, bytes.NewReader(readSection())); err != nil {
+		t.Fatalf("unable to decode CARv1 header: %v", err)
+	}
+	var roots []cid.Cid
+	rootsNode, _ := nb.Build().LookupByString("roots")
+	for it := rootsNode.ListIterator(); !it.Done(); {
+		_, n, _ := it.Next()
+		lnk, _ := n.AsLink()
+		roots = append(roots, lnk.(cidlink.Link).Cid)
+	}
+
+	var blocks []carBlock
+	for section := readSection(); section != nil; section = readSection() {
+		n, c, err := cid.CidFromBytes(section)
+		if err != nil {
+			t.Fatalf("unable to read block CID: %v", err)
+		}
+		blocks = append(blocks, carBlock{cid: c, data: section[n:]})
+	}
+	return roots, blocks
+}
+
+func mockBlock(parent common.Hash, number int64, txCount int) (*types.Block, types.Receipts) {
+	txs := make(types.Transactions, txCount)
+	receipts := make(types.Receipts, txCount)
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), shared.RandomAddr(), big.NewInt(1), 21000, big.NewInt(1), nil)
+		receipts[i] = &types.Receipt{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(i+1) * 21000,
+			Logs:              []*types.Log{{Address: shared.RandomAddr(), Topics: []common.Hash{shared.RandomHash()}, Data: []byte{1}}},
+		}
+		receipts[i].Bloom = types.CreateBloom(receipts[i])
+	}
+	h := &types.Header{ParentHash: parent, Number: big.NewInt(number), Difficulty: big.NewInt(1), GasLimit: 30000000, Root: shared.RandomHash()}
+	body := &types.Body{Transactions: txs, Withdrawals: []*types.Withdrawal{{Index: uint64(number), Validator: 1, Amount: 32}}}
+	return types.NewBlock(h, body, receipts, gethtrie.NewStackTrie(nil)), receipts
+}
+
+func exportToBytes(t *testing.T, lsys ipld.LinkSystem, headers []ipld.Link) []byte {
+	f, err := ioutil.TempFile(t.TempDir(), "blocks.car")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := export.ExportBlocks(f, lsys, headers); err != nil {
+		t.Fatalf("unable to export blocks: %v", err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestExportBlocks(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	var headers []ipld.Link
+	parent := common.Hash{}
+	for number, txCount := range []int{20, 0, 5} {
+		blk, receipts := mockBlock(parent, int64(number), txCount)
+		headerLink, err := block.PackBlock(blk, receipts, lsys)
+		if err != nil {
+			t.Fatalf("unable to pack block: %v", err)
+		}
+		headers = append(headers, headerLink)
+		parent = blk.Hash()
+	}
+
+	data := exportToBytes(t, lsys, headers)
+	roots, blocks := readCAR(t, data)
+	if len(roots) != len(headers) {
+		t.Fatalf("expected %d roots, got %d", len(headers), len(roots))
+	}
+	for i, root := range roots {
+		if !root.Equals(headers[i].(cidlink.Link).Cid) {
+			t.Errorf("root %d (%s) does not match header %s", i, root.String(), headers[i].String())
+		}
+	}
+
+	// the archive holds exactly the headers and the blocks of their DAGs, each once
+	expected := make(map[cid.Cid]bool)
+	for lnk := range store.Bag {
+		switch c := lnk.(cidlink.Link).Cid; c.Prefix().Codec {
+		case header.MultiCodecType, uncles.MultiCodecType, tx_trie.MultiCodecType, rct_trie.MultiCodecType,
+			log_trie.MultiCodecType, withdrawal_trie.MultiCodecType:
+			expected[c] = true
+		}
+	}
+	seen := make(map[cid.Cid]bool)
+	for _, blk := range blocks {
+		if seen[blk.cid] {
+			t.Errorf("block %s is in the archive more than once", blk.cid.String())
+		}
+		seen[blk.cid] = true
+		if !expected[blk.cid] {
+			t.Errorf("unexpected block %s in the archive", blk.cid.String())
+		}
+		if !bytes.Equal(blk.data, store.Bag[cidlink.Link{Cid: blk.cid}]) {
+			t.Errorf("block %s does not match the stored block", blk.cid.String())
+		}
+	}
+	if len(seen) != len(expected) {
+		t.Errorf("archive holds %d blocks, expected %d", len(seen), len(expected))
+	}
+	if !blocks[0].cid.Equals(roots[0]) {
+		t.Error("expected the archive to start with the first header")
+	}
+
+	if !bytes.Equal(exportToBytes(t, lsys, headers), data) {
+		t.Error("exporting the same blocks twice produced different archives")
+	}
+}
+
+func TestImportCAR(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	var headers []ipld.Link
+	parent := shared.RandomHash()
+	for number, txCount := range []int{3, 0, 7} {
+		blk, receipts := mockBlock(parent, int64(number), txCount)
+		headerLink, err := block.PackBlock(blk, receipts, lsys)
+		if err != nil {
+			t.Fatalf("unable to pack block: %v", err)
+		}
+		headers = append(headers, headerLink)
+		parent = blk.Hash()
+	}
+	data := exportToBytes(t, lsys, headers)
+	_, blocks := readCAR(t, data)
+
+	// the state tries and the parent of the first header are not in the archive
+	opts := export.ImportOptions{AllowMissing: export.AllowMissingCodecs(header.MultiCodecType, state_trie.MultiCodecType)}
+	imported := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	summary, err := opts.ImportCAR(bytes.NewReader(data), codecs.NewLinkSystem(imported))
+	if err != nil {
+		t.Fatalf("unable to import archive: %v", err)
+	}
+	if summary.Blocks != len(blocks) || len(imported.Bag) != len(blocks) {
+		t.Errorf("expected %d blocks to be imported, summary has %d and the store %d", len(blocks), summary.Blocks, len(imported.Bag))
+	}
+	if summary.Codecs[header.MultiCodecType] != len(headers) || len(summary.Roots) != len(headers) {
+		t.Errorf("expected %d headers and roots, got %d and %d", len(headers), summary.Codecs[header.MultiCodecType], len(summary.Roots))
+	}
+	if len(summary.Missing) != len(headers)+1 {
+		t.Errorf("expected %d missing links, got %d", len(headers)+1, len(summary.Missing))
+	}
+	for _, blk := range blocks {
+		if !bytes.Equal(imported.Bag[cidlink.Link{Cid: blk.cid}], blk.data) {
+			t.Errorf("imported block %s does not match the exported block", blk.cid.String())
+		}
+	}
+
+	// the CARv1 payload on its own imports the same way
+	dataOffset := binary.LittleEndian.Uint64(data[len(export.CARv2Pragma)+16:])
+	summary, err = opts.ImportCAR(bytes.NewReader(data[dataOffset:]), codecs.NewLinkSystem(&storage.Memory{Bag: make(map[ipld.Link][]byte)}))
+	if err != nil || summary.Blocks != len(blocks) {
+		t.Errorf("unable to import the CARv1 payload: %v", err)
+	}
+
+	if _, err := export.ImportCAR(bytes.NewReader(data), codecs.NewLinkSystem(&storage.Memory{Bag: make(map[ipld.Link][]byte)})); err == nil {
+		t.Error("expected an error importing an archive with missing links by default")
+	}
+	tampered := append([]byte{}, data...)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := opts.ImportCAR(bytes.NewReader(tampered), codecs.NewLinkSystem(&storage.Memory{Bag: make(map[ipld.Link][]byte)})); err == nil {
+		t.Error("expected an error importing an archive with a tampered block")
+	}
+}
+
+func TestExportState(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	accounts := make(map[string][]byte)
+	// every third account shares the same storage trie and code
+	sharedStorage := testutil.BuildTrie(t, store, storage_trie.MultiCodecType, map[string][]byte{
+		string(crypto.Keccak256([]byte{1})): {0x01},
+		string(crypto.Keccak256([]byte{2})): {0x02},
+	})
+	sharedCode := []byte{0x60, 0x00, 0x60, 0x00, 0xfd}
+	store.Bag[testutil.KeccakLink(t, cid.Raw, crypto.Keccak256(sharedCode))] = sharedCode
+	for i := int64(1); i <= 200; i++ {
+		acct := &types.StateAccount{
+			Nonce:    uint64(i),
+			Balance:  uint256.NewInt(uint64(i)),
+			Root:     types.EmptyRootHash,
+			CodeHash: types.EmptyCodeHash.Bytes(),
+		}
+		switch {
+		case i%3 == 0:
+			acct.Root, acct.CodeHash = sharedStorage, crypto.Keccak256(sharedCode)
+		case i%10 == 1:
+			slots := map[string][]byte{string(crypto.Keccak256(big.NewInt(i).Bytes())): big.NewInt(i).Bytes()}
+			acct.Root = testutil.BuildTrie(t, store, storage_trie.MultiCodecType, slots)
+			code := append([]byte{0x60, byte(i)}, sharedCode...)
+			acct.CodeHash = crypto.Keccak256(code)
+			store.Bag[testutil.KeccakLink(t, cid.Raw, acct.CodeHash)] = code
+		}
+		enc, _ := rlp.EncodeToBytes(acct)
+		accounts[string(crypto.Keccak256(common.BigToAddress(big.NewInt(i)).Bytes()))] = enc
+	}
+	stateStore := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	stateRoot := testutil.KeccakLink(t, state_trie.MultiCodecType, testutil.BuildTrie(t, stateStore, state_trie.MultiCodecType, accounts).Bytes())
+	for lnk, data := range stateStore.Bag {
+		store.Bag[lnk] = data
+	}
+	lsys := codecs.NewLinkSystem(store)
+
+	snapshot := func(opts export.SnapshotOptions) ([]cid.Cid, []carBlock) {
+		f, err := ioutil.TempFile(t.TempDir(), "state.car")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := opts.ExportState(f, lsys, stateRoot); err != nil {
+			t.Fatalf("unable to export state: %v", err)
+		}
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return readCAR(t, data)
+	}
+
+	roots, blocks := snapshot(export.SnapshotOptions{Workers: 4})
+	if len(roots) != 1 || !roots[0].Equals(stateRoot.Cid) {
+		t.Errorf("expected the state root as the only root, got %v", roots)
+	}
+	if len(blocks) != len(stateStore.Bag) {
+		t.Errorf("expected %d state trie blocks, got %d", len(stateStore.Bag), len(blocks))
+	}
+	for _, blk := range blocks {
+		if blk.cid.Prefix().Codec != state_trie.MultiCodecType {
+			t.Errorf("unexpected block %s in a state trie only snapshot", blk.cid.String())
+		}
+	}
+
+	_, blocks = snapshot(export.SnapshotOptions{Workers: 8, Storage: true, Code: true})
+	if len(blocks) != len(store.Bag) {
+		t.Errorf("expected every one of the %d stored blocks exactly once, got %d", len(store.Bag), len(blocks))
+	}
+	seen := make(map[cid.Cid]bool)
+	for _, blk := range blocks {
+		if seen[blk.cid] {
+			t.Errorf("block %s is written more than once", blk.cid.String())
+		}
+		seen[blk.cid] = true
+		if !bytes.Equal(store.Bag[cidlink.Link{Cid: blk.cid}], blk.data) {
+			t.Errorf("exported block %s does not match the stored block", blk.cid.String())
+		}
+	}
+
+	delete(store.Bag, testutil.KeccakLink(t, storage_trie.MultiCodecType, sharedStorage.Bytes()))
+	f, err := ioutil.TempFile(t.TempDir(), "state.car")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := (export.SnapshotOptions{Storage: true}).ExportState(f, lsys, stateRoot); err == nil {
+		t.Error("expected an error exporting a state trie with a missing storage trie")
+	}
+}
+
+// gatewayCAR writes the blocks into an archive in the given order, as a trustless gateway would respond
+func gatewayCAR(t *testing.T, root cid.Cid, blocks []carBlock) []byte {
+	f, err := ioutil.TempFile(t.TempDir(), "gateway.car")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cw, err := export.NewCARWriter(f, []cid.Cid{root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, blk := range blocks {
+		if _, err := cw.Put(blk.cid, blk.data); err != nil {
+			t.Fatalf("unable to write block: %v", err)
+		}
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestVerifyGateway(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	accounts := make(map[string][]byte)
+	for i := int64(1); i <= 100; i++ {
+		acct := &types.StateAccount{Nonce: uint64(i), Balance: uint256.NewInt(1), Root: types.EmptyRootHash, CodeHash: types.EmptyCodeHash.Bytes()}
+		enc, _ := rlp.EncodeToBytes(acct)
+		accounts[string(crypto.Keccak256(common.BigToAddress(big.NewInt(i)).Bytes()))] = enc
+	}
+	stateRoot := testutil.KeccakCid(t, state_trie.MultiCodecType, testutil.BuildTrie(t, store, state_trie.MultiCodecType, accounts).Bytes())
+	h := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1), Root: common.BytesToHash(stateRoot.Hash()[2:])}
+	headerEnc, _ := rlp.EncodeToBytes(h)
+	headerCID := testutil.KeccakCid(t, header.MultiCodecType, crypto.Keccak256(headerEnc))
+	store.Bag[cidlink.Link{Cid: headerCID}] = headerEnc
+	lsys := codecs.NewLinkSystem(store)
+
+	// the blocks on the path from the header to an account
+	pathBlocks := func(address common.Address) []carBlock {
+		blocks := []carBlock{{headerCID, headerEnc}}
+		lnk, remaining := ipld.Link(cidlink.Link{Cid: stateRoot}), helpers.AddressToNibbles(address)
+		for lnk != nil {
+			c := lnk.(cidlink.Link).Cid
+			blocks = append(blocks, carBlock{c, store.Bag[lnk]})
+			node, err := lsys.Load(ipld.LinkContext{}, lnk, dageth.Type.TrieNode)
+			if err != nil {
+				t.Fatalf("unable to load trie node: %v", err)
+			}
+			if _, lnk, remaining, err = helpers.Step(node, remaining); err != nil {
+				t.Fatalf("unable to step through trie node: %v", err)
+			}
+		}
+		return blocks
+	}
+	address := common.BigToAddress(big.NewInt(7))
+	blocks := pathBlocks(address)
+
+	account, err := export.VerifyGatewayAccount(bytes.NewReader(gatewayCAR(t, headerCID, blocks)), headerCID, address)
+	if err != nil {
+		t.Fatalf("unable to verify account response: %v", err)
+	}
+	nonceNode, _ := account.LookupByString("Nonce")
+	if nonce, _ := nonceNode.AsBytes(); new(big.Int).SetBytes(nonce).Int64() != 7 {
+		t.Errorf("expected the account with nonce 7, got nonce %x", nonce)
+	}
+	// the same blocks answer the path query of the account, with repeated blocks skipped
+	accountPath, err := helpers.AccountPath(lsys, cidlink.Link{Cid: stateRoot}, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := ipld.ParsePath("StateRootCID").Join(accountPath)
+	repeated := append(append([]carBlock{}, blocks...), blocks[1])
+	node, err := export.VerifyGatewayPath(bytes.NewReader(gatewayCAR(t, headerCID, repeated)), headerCID, path)
+	if err != nil {
+		t.Fatalf("unable to verify path response: %v", err)
+	}
+	if nonceNode, _ := node.LookupByString("Nonce"); nonceNode == nil {
+		t.Error("expected the path to end at the account")
+	}
+	hdr, err := export.VerifyGatewayHeader(bytes.NewReader(gatewayCAR(t, headerCID, blocks[:1])), headerCID)
+	if err != nil {
+		t.Fatalf("unable to verify header response: %v", err)
+	}
+	if number, _ := hdr.FieldNumber().AsBytes(); new(big.Int).SetBytes(number).Int64() != 1 {
+		t.Errorf("expected header 1, got %x", number)
+	}
+
+	// the blocks proving an account absent answer with no account
+	missing := common.BigToAddress(big.NewInt(1000))
+	account, err = export.VerifyGatewayAccount(bytes.NewReader(gatewayCAR(t, headerCID, pathBlocks(missing))), headerCID, missing)
+	if err != nil || account != nil {
+		t.Errorf("expected no account and no error, got %v and %v", account, err)
+	}
+
+	invalid := map[string][]carBlock{
+		"out of order":   append([]carBlock{blocks[0], blocks[2], blocks[1]}, blocks[3:]...),
+		"truncated":      blocks[:len(blocks)-1],
+		"tampered":       append(append([]carBlock{}, blocks[:len(blocks)-1]...), carBlock{blocks[len(blocks)-1].cid, append([]byte{0xc0}, blocks[len(blocks)-1].data...)}),
+		"unrelated tail": append(append([]carBlock{}, blocks...), pathBlocks(common.BigToAddress(big.NewInt(50)))[len(blocks)-1:]...),
+		"wrong codec": append([]carBlock{blocks[0]}, carBlock{
+			testutil.KeccakCid(t, storage_trie.MultiCodecType, blocks[1].cid.Hash()[2:]), blocks[1].data,
+		}),
+	}
+	for name, blks := range invalid {
+		if _, err := export.VerifyGatewayAccount(bytes.NewReader(gatewayCAR(t, headerCID, blks)), headerCID, address); err == nil {
+			t.Errorf("%s: expected an error verifying the response", name)
+		}
+	}
+	if _, err := export.VerifyGatewayHeader(bytes.NewReader(gatewayCAR(t, stateRoot, blocks[:1])), headerCID); err == nil {
+		t.Error("expected an error verifying a response without the header among its roots")
+	}
+}
+
+func TestExportAccess(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	slots := []common.Hash{common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(2)), common.BigToHash(big.NewInt(3))}
+	storageRoot := testutil.BuildTrie(t, store, storage_trie.MultiCodecType, map[string][]byte{
+		string(crypto.Keccak256(slots[0].Bytes())): {0x01},
+		string(crypto.Keccak256(slots[1].Bytes())): {0x02},
+		string(crypto.Keccak256(slots[2].Bytes())): {0x03},
+	})
+	code := []byte{0x60, 0x00, 0x60, 0x00, 0xfd}
+	store.Bag[testutil.KeccakLink(t, cid.Raw, crypto.Keccak256(code))] = code
+	contract := common.BigToAddress(big.NewInt(7))
+	accounts := make(map[string][]byte)
+	for i := int64(1); i <= 100; i++ {
+		acct := &types.StateAccount{
+			Nonce:    uint64(i),
+			Balance:  uint256.NewInt(uint64(i)),
+			Root:     types.EmptyRootHash,
+			CodeHash: types.EmptyCodeHash.Bytes(),
+		}
+		addr := common.BigToAddress(big.NewInt(i))
+		if addr == contract {
+			acct.Root, acct.CodeHash = storageRoot, crypto.Keccak256(code)
+		}
+		enc, _ := rlp.EncodeToBytes(acct)
+		accounts[string(crypto.Keccak256(addr.Bytes()))] = enc
+	}
+	stateRoot := testutil.KeccakLink(t, state_trie.MultiCodecType, testutil.BuildTrie(t, store, state_trie.MultiCodecType, accounts).Bytes())
+	lsys := codecs.NewLinkSystem(store)
+
+	eoa, absent := common.BigToAddress(big.NewInt(42)), common.HexToAddress("0xdeadbeef")
+	access := types.AccessList{
+		{Address: contract, StorageKeys: []common.Hash{slots[0], slots[2]}},
+		{Address: eoa},
+		{Address: absent},
+	}
+	f, err := ioutil.TempFile(t.TempDir(), "access.car")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := export.ExportAccess(f, lsys, stateRoot, access); err != nil {
+		t.Fatalf("unable to export access set: %v", err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, blocks := readCAR(t, data)
+	if len(roots) != 1 || !roots[0].Equals(stateRoot.Cid) {
+		t.Errorf("expected the state root as the only root, got %v", roots)
+	}
+	if len(blocks) >= len(store.Bag) {
+		t.Errorf("expected a witness smaller than the %d stored blocks, got %d blocks", len(store.Bag), len(blocks))
+	}
+
+	// the witness alone resolves the accounts and slots of the access set, and proves the absent account absent
+	witness := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	for _, blk := range blocks {
+		witness.Bag[cidlink.Link{Cid: blk.cid}] = blk.data
+	}
+	wlsys := codecs.NewLinkSystem(witness)
+	for _, slot := range access[0].StorageKeys {
+		if _, err := helpers.StoragePath(wlsys, stateRoot, contract, slot); err != nil {
+			t.Errorf("unable to resolve slot %s from the witness: %v", slot.Hex(), err)
+		}
+	}
+	if _, err := helpers.StoragePath(wlsys, stateRoot, contract, slots[1]); err == nil {
+		t.Error("expected a slot outside of the access set not to resolve from the witness")
+	}
+	if _, err := helpers.AccountPath(wlsys, stateRoot, eoa); err != nil {
+		t.Errorf("unable to resolve account %s from the witness: %v", eoa.Hex(), err)
+	}
+	if _, err := helpers.AccountPath(wlsys, stateRoot, absent); err == nil {
+		t.Error("expected the absent account not to resolve from the witness")
+	}
+	if _, ok := witness.Bag[testutil.KeccakLink(t, cid.Raw, crypto.Keccak256(code))]; !ok {
+		t.Error("expected the code of the contract in the witness")
+	}
+}
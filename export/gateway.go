@@ -0,0 +1,276 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/multiformats/go-multihash"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/helpers"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// GatewayOptions can be used to customize the verification of CAR responses from trustless gateways.
+// The zero value requires every block to carry a keccak256 CID.
+type GatewayOptions struct {
+	// LinkHashes overrides the multihash type blocks are expected to carry, for chains that do not hash with
+	// keccak256; it should match the DecodeOptions of the codecs the blocks were built with
+	LinkHashes shared.LinkHashes
+}
+
+// VerifyGatewayPath verifies a CAR response from a trustless gateway to a path query, e.g.
+// /ipfs/<root>/StateRootCID/TrieBranchNode/Child3/Link, with the default GatewayOptions
+func VerifyGatewayPath(r io.Reader, root cid.Cid, path ipld.Path) (ipld.Node, error) {
+	return GatewayOptions{}.VerifyGatewayPath(r, root, path)
+}
+
+// VerifyGatewayHeader verifies a CAR response from a trustless gateway to a query for the header, with the
+// default GatewayOptions
+func VerifyGatewayHeader(r io.Reader, root cid.Cid) (dageth.Header, error) {
+	return GatewayOptions{}.VerifyGatewayHeader(r, root)
+}
+
+// VerifyGatewayAccount verifies a CAR response from a trustless gateway to a query for the state of the account,
+// with the default GatewayOptions
+func VerifyGatewayAccount(r io.Reader, root cid.Cid, address common.Address) (ipld.Node, error) {
+	return GatewayOptions{}.VerifyGatewayAccount(r, root, address)
+}
+
+// VerifyGatewayPath reads the CAR response of a trustless gateway to a query for the path under the root, and
+// returns the node at the end of the path, which is the block it links to if it ends at a link.
+// The archive must list the root among its roots, and hold the blocks the path traverses in order, starting with
+// the root block. It can go on with blocks of the subgraph under the node, as returned for dag-scope=entity or
+// dag-scope=all, each of which must be linked from a block before it, and it can repeat blocks already read.
+// Every block must decode with the DAG-ETH codec of its CID, raw blocks aside, and hash to that CID.
+func (opts GatewayOptions) VerifyGatewayPath(r io.Reader, root cid.Cid, path ipld.Path) (ipld.Node, error) {
+	gs, err := opts.newGatewayStream(r, root)
+	if err != nil {
+		return nil, err
+	}
+	node, err := gs.next(root)
+	if err != nil {
+		return nil, err
+	}
+	segments := path.Segments()
+	for i, segment := range segments {
+		if node, err = node.LookupBySegment(segment); err != nil {
+			return nil, fmt.Errorf("path segment %d (%s): %v", i, segment.String(), err)
+		}
+		if node.Kind() != ipld.Kind_Link {
+			continue
+		}
+		lnk, err := node.AsLink()
+		if err != nil {
+			return nil, err
+		}
+		if node, err = gs.nextLink(lnk); err != nil {
+			return nil, fmt.Errorf("path segment %d (%s): %v", i, segment.String(), err)
+		}
+	}
+	return node, gs.finish(node)
+}
+
+// VerifyGatewayHeader is like VerifyGatewayPath, for the empty path under the CID of a header
+func (opts GatewayOptions) VerifyGatewayHeader(r io.Reader, root cid.Cid) (dageth.Header, error) {
+	if codec := root.Prefix().Codec; codec != header.MultiCodecType {
+		return nil, fmt.Errorf("CID %s has multicodec type %#x, not a header", root.String(), codec)
+	}
+	node, err := opts.VerifyGatewayPath(r, root, ipld.Path{})
+	if err != nil {
+		return nil, err
+	}
+	return node.(dageth.Header), nil
+}
+
+// VerifyGatewayAccount reads the CAR response of a trustless gateway to a query for the state of the account under
+// the root, which is either the CID of a header or of a state trie root node, and returns the Account node, or nil
+// if the blocks prove that the state trie does not hold the account.
+// The archive must hold, in order, the root block, the state trie root node if the root is a header, and the trie
+// nodes on the path to the account. Any further blocks must belong to the subgraph of the account, e.g. its storage
+// trie, as for VerifyGatewayPath.
+func (opts GatewayOptions) VerifyGatewayAccount(r io.Reader, root cid.Cid, address common.Address) (ipld.Node, error) {
+	gs, err := opts.newGatewayStream(r, root)
+	if err != nil {
+		return nil, err
+	}
+	node, err := gs.next(root)
+	if err != nil {
+		return nil, err
+	}
+	if root.Prefix().Codec == header.MultiCodecType {
+		stateRootNode, err := node.LookupByString("StateRootCID")
+		if err != nil {
+			return nil, err
+		}
+		stateRoot, err := stateRootNode.AsLink()
+		if err != nil {
+			return nil, err
+		}
+		if isEmptyRoot(stateRoot.(cidlink.Link).Cid) {
+			return nil, gs.finish(nil)
+		}
+		if node, err = gs.nextLink(stateRoot); err != nil {
+			return nil, err
+		}
+	}
+	remaining := helpers.AddressToNibbles(address)
+	for depth := 0; ; depth++ {
+		val, next, rest, err := helpers.Step(node, remaining)
+		if err != nil {
+			return nil, fmt.Errorf("state trie node at depth %d: %v", depth, err)
+		}
+		if val != nil {
+			account, err := val.LookupByString(trie.STATE_VALUE.String())
+			if err != nil {
+				return nil, fmt.Errorf("state trie value at depth %d: %v", depth, err)
+			}
+			return account, gs.finish(account)
+		}
+		if next == nil {
+			return nil, gs.finish(nil)
+		}
+		if node, err = gs.nextLink(next); err != nil {
+			return nil, fmt.Errorf("state trie node at depth %d: %v", depth+1, err)
+		}
+		remaining = rest
+	}
+}
+
+// gatewayStream reads the blocks of a gateway response in order, verifying each of them
+type gatewayStream struct {
+	opts    GatewayOptions
+	payload *bufio.Reader
+	// read holds the decoded blocks read so far, as gateways can repeat blocks
+	read map[cid.Cid]ipld.Node
+}
+
+func (opts GatewayOptions) newGatewayStream(r io.Reader, root cid.Cid) (*gatewayStream, error) {
+	payload, err := carPayload(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	roots, err := readCARv1Header(payload)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, c := range roots {
+		found = found || c.Equals(root)
+	}
+	if !found {
+		return nil, fmt.Errorf("root %s is not among the roots of the archive", root.String())
+	}
+	return &gatewayStream{opts: opts, payload: payload, read: make(map[cid.Cid]ipld.Node)}, nil
+}
+
+func (gs *gatewayStream) nextLink(lnk ipld.Link) (ipld.Node, error) {
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return nil, fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+	}
+	return gs.next(cl.Cid)
+}
+
+// next returns the decoded block with the expected CID, which must be the next block of the archive that has not
+// been read before
+func (gs *gatewayStream) next(expected cid.Cid) (ipld.Node, error) {
+	if node, ok := gs.read[expected]; ok {
+		return node, nil
+	}
+	for {
+		c, node, repeated, err := gs.readBlock()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive ends before block %s", expected.String())
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c.Equals(expected) {
+			return node, nil
+		}
+		if !repeated {
+			return nil, fmt.Errorf("expected block %s, found block %s", expected.String(), c.String())
+		}
+	}
+}
+
+// finish reads the blocks left in the archive, which must belong to the subgraph of the node
+func (gs *gatewayStream) finish(node ipld.Node) error {
+	reachable := make(map[cid.Cid]bool)
+	addLinks := func(node ipld.Node) {
+		for _, lnk := range collectLinks(node, nil) {
+			if cl, ok := lnk.(cidlink.Link); ok {
+				reachable[cl.Cid] = true
+			}
+		}
+	}
+	if node != nil {
+		addLinks(node)
+	}
+	for {
+		c, block, repeated, err := gs.readBlock()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if repeated {
+			continue
+		}
+		if !reachable[c] {
+			return fmt.Errorf("block %s is not linked from the blocks before it", c.String())
+		}
+		addLinks(block)
+	}
+}
+
+// readBlock reads, verifies and decodes the next block of the archive, and reports whether it was read before
+func (gs *gatewayStream) readBlock() (cid.Cid, ipld.Node, bool, error) {
+	section, err := readSection(gs.payload)
+	if err != nil {
+		return cid.Undef, nil, false, err
+	}
+	n, c, err := cid.CidFromBytes(section)
+	if err != nil {
+		return cid.Undef, nil, false, fmt.Errorf("invalid block CID: %v", err)
+	}
+	if node, ok := gs.read[c]; ok {
+		return c, node, true, nil
+	}
+	data := section[n:]
+	prefix := c.Prefix()
+	if mhType := gs.opts.LinkHashes.Type(multihash.KECCAK_256); prefix.MhType != mhType {
+		return cid.Undef, nil, false, fmt.Errorf("block %s has multihash type %#x, not %#x", c.String(), prefix.MhType, mhType)
+	}
+	var node ipld.Node
+	if prefix.Codec == cid.Raw {
+		if err := shared.VerifyCID(data, c, cid.Raw); err != nil {
+			return cid.Undef, nil, false, err
+		}
+		node = basicnode.NewBytes(data)
+	} else {
+		np, err := codecs.NodePrototypeChooser(cidlink.Link{Cid: c}, ipld.LinkContext{})
+		if err != nil {
+			return cid.Undef, nil, false, err
+		}
+		nb := np.NewBuilder()
+		if err := codecs.DecodeVerified(nb, bytes.NewReader(data), c); err != nil {
+			return cid.Undef, nil, false, fmt.Errorf("invalid block %s: %v", c.String(), err)
+		}
+		node = nb.Build()
+	}
+	gs.read[c] = node
+	return c, node, false, nil
+}
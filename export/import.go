@@ -0,0 +1,246 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+)
+
+// ImportOptions can be used to customize the behavior of CAR imports.
+// The zero value requires every link of every block to resolve within the archive.
+type ImportOptions struct {
+	// AllowMissing reports whether a link from the block with CID from to a block that is not in the archive is
+	// acceptable, e.g. the parent link of the first header of a block range.
+	// Links to the roots of empty tries are always acceptable, as those roots are never stored.
+	AllowMissing func(from cid.Cid, to ipld.Link) bool
+}
+
+// AllowMissingCodecs returns an AllowMissing func that accepts missing links to blocks of the given codecs
+func AllowMissingCodecs(codecs ...uint64) func(cid.Cid, ipld.Link) bool {
+	allowed := make(map[uint64]bool, len(codecs))
+	for _, codec := range codecs {
+		allowed[codec] = true
+	}
+	return func(_ cid.Cid, to ipld.Link) bool {
+		cl, ok := to.(cidlink.Link)
+		return ok && allowed[cl.Prefix().Codec]
+	}
+}
+
+// ImportSummary describes the contents of an imported CAR archive
+type ImportSummary struct {
+	Roots []cid.Cid
+	// Blocks is the number of blocks imported, and Bytes their total size
+	Blocks int
+	Bytes  uint64
+	// Codecs counts the imported blocks by their multicodec
+	Codecs map[uint64]int
+	// Missing lists the links to blocks outside the archive that were allowed by the ImportOptions
+	Missing []MissingLink
+}
+
+// MissingLink is a link from a block in an archive to a block that is not in the archive
+type MissingLink struct {
+	From cid.Cid
+	To   ipld.Link
+}
+
+// ImportCAR imports a CARv1 or CARv2 archive of DAG-ETH blocks into the LinkSystem, with the default ImportOptions
+func ImportCAR(r io.Reader, lsys ipld.LinkSystem) (*ImportSummary, error) {
+	return ImportOptions{}.ImportCAR(r, lsys)
+}
+
+// ImportCAR reads a CARv1 or CARv2 archive, verifies every block against the hash in its CID, decodes it with the
+// DAG-ETH codec of its CID (raw blocks, such as contract code, are taken as they are), and writes it through the
+// LinkSystem. Once every block is read it checks that the roots are in the archive and that every link resolves
+// within the archive or is allowed to be missing.
+// Blocks are written as they are read, so the blocks read before an error is found remain written.
+func (opts ImportOptions) ImportCAR(r io.Reader, lsys ipld.LinkSystem) (*ImportSummary, error) {
+	if lsys.StorageWriteOpener == nil {
+		return nil, fmt.Errorf("no storage configured for writing")
+	}
+	br := bufio.NewReader(r)
+	payload, err := carPayload(br)
+	if err != nil {
+		return nil, err
+	}
+	summary := &ImportSummary{Codecs: make(map[uint64]int)}
+	if summary.Roots, err = readCARv1Header(payload); err != nil {
+		return nil, err
+	}
+
+	imported := make(map[cid.Cid]bool)
+	type blockLinks struct {
+		from  cid.Cid
+		links []ipld.Link
+	}
+	var pending []blockLinks
+	for {
+		section, err := readSection(payload)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return summary, err
+		}
+		n, c, err := cid.CidFromBytes(section)
+		if err != nil {
+			return summary, fmt.Errorf("invalid block CID: %v", err)
+		}
+		data := section[n:]
+		sum, err := c.Prefix().Sum(data)
+		if err != nil {
+			return summary, err
+		}
+		if !sum.Equals(c) {
+			return summary, fmt.Errorf("block %s does not match its CID", c.String())
+		}
+		links, err := decodeLinks(c, data)
+		if err != nil {
+			return summary, err
+		}
+		if err := writeBlock(lsys, c, data); err != nil {
+			return summary, err
+		}
+		if !imported[c] {
+			imported[c] = true
+			summary.Blocks++
+			summary.Bytes += uint64(len(data))
+			summary.Codecs[c.Prefix().Codec]++
+		}
+		if len(links) > 0 {
+			pending = append(pending, blockLinks{from: c, links: links})
+		}
+	}
+
+	for _, root := range summary.Roots {
+		if !imported[root] {
+			return summary, fmt.Errorf("root %s is not in the archive", root.String())
+		}
+	}
+	for _, bl := range pending {
+		for _, lnk := range bl.links {
+			cl, ok := lnk.(cidlink.Link)
+			if !ok {
+				return summary, fmt.Errorf("block %s has a link of unexpected type %T", bl.from.String(), lnk)
+			}
+			if imported[cl.Cid] || isEmptyRoot(cl.Cid) {
+				continue
+			}
+			if opts.AllowMissing == nil || !opts.AllowMissing(bl.from, lnk) {
+				return summary, fmt.Errorf("block %s links to %s, which is not in the archive", bl.from.String(), cl.String())
+			}
+			summary.Missing = append(summary.Missing, MissingLink{From: bl.from, To: lnk})
+		}
+	}
+	return summary, nil
+}
+
+// carPayload returns a reader over the CARv1 payload of a CARv1 or CARv2 archive
+func carPayload(br *bufio.Reader) (*bufio.Reader, error) {
+	prefix, err := br.Peek(len(CARv2Pragma))
+	if err != nil || !bytes.Equal(prefix, CARv2Pragma) {
+		// anything that is not a CARv2 archive is read as a CARv1 payload
+		return br, nil
+	}
+	header := make([]byte, len(CARv2Pragma)+carV2HeaderSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("invalid CARv2 header: %v", err)
+	}
+	dataOffset := binary.LittleEndian.Uint64(header[len(CARv2Pragma)+16:])
+	dataSize := binary.LittleEndian.Uint64(header[len(CARv2Pragma)+24:])
+	if dataOffset < uint64(len(header)) {
+		return nil, fmt.Errorf("invalid CARv2 data offset %d", dataOffset)
+	}
+	if _, err := io.CopyN(io.Discard, br, int64(dataOffset)-int64(len(header))); err != nil {
+		return nil, fmt.Errorf("invalid CARv2 data offset %d: %v", dataOffset, err)
+	}
+	return bufio.NewReader(io.LimitReader(br, int64(dataSize))), nil
+}
+
+func readCARv1Header(r *bufio.Reader) ([]cid.Cid, error) {
+	section, err := readSection(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CARv1 header: %v", err)
+	}
+	nb := basicnode.Prototype.Map.NewBuilder()
+	if err := dagcbor.Decode(nb, bytes.NewReader(section)); err != nil {
+		return nil, fmt.Errorf("invalid CARv1 header: %v", err)
+	}
+	header := nb.Build()
+	versionNode, err := header.LookupByString("version")
+	if err != nil {
+		return nil, fmt.Errorf("invalid CARv1 header: %v", err)
+	}
+	if version, err := versionNode.AsInt(); err != nil || version != 1 {
+		return nil, fmt.Errorf("unsupported CAR payload version %v", versionNode)
+	}
+	rootsNode, err := header.LookupByString("roots")
+	if err != nil {
+		return nil, fmt.Errorf("invalid CARv1 header: %v", err)
+	}
+	var roots []cid.Cid
+	for it := rootsNode.ListIterator(); it != nil && !it.Done(); {
+		_, n, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		lnk, err := n.AsLink()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CARv1 root: %v", err)
+		}
+		roots = append(roots, lnk.(cidlink.Link).Cid)
+	}
+	return roots, nil
+}
+
+// readSection reads a varint length prefixed section, returning io.EOF at the end of the payload
+func readSection(r *bufio.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	section := make([]byte, length)
+	if _, err := io.ReadFull(r, section); err != nil {
+		return nil, fmt.Errorf("truncated CAR section: %v", err)
+	}
+	return section, nil
+}
+
+// decodeLinks decodes the block with the DAG-ETH codec of its CID, and returns the links it holds
+func decodeLinks(c cid.Cid, data []byte) ([]ipld.Link, error) {
+	if c.Prefix().Codec == cid.Raw {
+		return nil, nil
+	}
+	lnk := cidlink.Link{Cid: c}
+	np, err := codecs.NodePrototypeChooser(lnk, ipld.LinkContext{})
+	if err != nil {
+		return nil, err
+	}
+	nb := np.NewBuilder()
+	if err := codecs.DecodeByCodec(nb, bytes.NewReader(data), c.Prefix().Codec); err != nil {
+		return nil, fmt.Errorf("unable to decode block %s: %v", c.String(), err)
+	}
+	return collectLinks(nb.Build(), nil), nil
+}
+
+func writeBlock(lsys ipld.LinkSystem, c cid.Cid, data []byte) error {
+	w, commit, err := lsys.StorageWriteOpener(ipld.LinkContext{})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return commit(cidlink.Link{Cid: c})
+}
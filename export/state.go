@@ -0,0 +1,187 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/metrics"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+)
+
+// SnapshotOptions can be used to customize what a state snapshot holds and how it is walked.
+// The zero value exports only the state trie, with one worker per CPU.
+type SnapshotOptions struct {
+	// Workers is the number of blocks read and decoded concurrently, defaulting to the number of CPUs
+	Workers int
+	// Storage includes the storage trie of every account
+	Storage bool
+	// Code includes the contract code of every account
+	Code bool
+	// Metrics, if set, receives the blocks read by the export and the depth it reaches below the state root
+	Metrics metrics.Recorder
+}
+
+// ExportState writes the state trie rooted at stateRoot, as loaded through the LinkSystem, into a CARv2 archive
+// with the default SnapshotOptions
+func ExportState(w io.WriteSeeker, lsys ipld.LinkSystem, stateRoot ipld.Link) error {
+	return SnapshotOptions{}.ExportState(w, lsys, stateRoot)
+}
+
+// ExportState writes the state trie rooted at stateRoot, as loaded through the LinkSystem, into a CARv2 archive
+// with stateRoot as its root. Blocks are read, verified and decoded by a bounded pool of workers fanning out over
+// the trie, and streamed into the archive as they are done, so unlike ExportBlocks the block order is not
+// deterministic. Every block is read and written once, however many accounts share it.
+func (opts SnapshotOptions) ExportState(w io.WriteSeeker, lsys ipld.LinkSystem, stateRoot ipld.Link) error {
+	cl, ok := stateRoot.(cidlink.Link)
+	if !ok {
+		return fmt.Errorf("expected a cidlink.Link, got %T", stateRoot)
+	}
+	if codec := cl.Prefix().Codec; codec != state_trie.MultiCodecType {
+		return fmt.Errorf("expected a state trie root, got a link with codec %#x", codec)
+	}
+	cw, err := NewCARWriter(w, []cid.Cid{cl.Cid})
+	if err != nil {
+		return err
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	s := &snapshotter{
+		opts:    opts,
+		lsys:    metrics.Instrument(lsys, opts.Metrics),
+		rec:     metrics.OrNop(opts.Metrics),
+		cw:      cw,
+		claimed: make(map[cid.Cid]bool),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	s.push(cl.Cid, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work()
+		}()
+	}
+	wg.Wait()
+	if s.err != nil {
+		return s.err
+	}
+	return cw.Close()
+}
+
+// snapshotter shares the blocks left to export between its workers
+type snapshotter struct {
+	opts SnapshotOptions
+	lsys ipld.LinkSystem
+	rec  metrics.Recorder
+	cw   *CARWriter
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []queued
+	claimed map[cid.Cid]bool
+	active  int
+	err     error
+}
+
+// queued is a block left to export, at a depth below the state root
+type queued struct {
+	c     cid.Cid
+	depth int
+}
+
+// push queues the block, unless it has already been queued
+func (s *snapshotter) push(c cid.Cid, depth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.claimed[c] {
+		return
+	}
+	s.claimed[c] = true
+	s.queue = append(s.queue, queued{c: c, depth: depth})
+	s.cond.Signal()
+}
+
+// work exports queued blocks until none are left and no other worker can queue more, or an error occurs
+func (s *snapshotter) work() {
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && s.active > 0 && s.err == nil {
+			s.cond.Wait()
+		}
+		if len(s.queue) == 0 || s.err != nil {
+			s.cond.Broadcast()
+			s.mu.Unlock()
+			return
+		}
+		q := s.queue[len(s.queue)-1]
+		s.queue = s.queue[:len(s.queue)-1]
+		s.active++
+		s.mu.Unlock()
+
+		err := s.export(q.c, q.depth)
+
+		s.mu.Lock()
+		s.active--
+		if err != nil && s.err == nil {
+			s.err = err
+		}
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	}
+}
+
+// export writes the block into the archive and queues the blocks it links to that belong in the snapshot
+func (s *snapshotter) export(c cid.Cid, depth int) error {
+	data, err := readBlock(s.lsys, c)
+	if err != nil {
+		return err
+	}
+	links, err := decodeLinks(c, data)
+	if err != nil {
+		return err
+	}
+	s.rec.NodeDecoded(c.Prefix().Codec)
+	s.rec.DepthReached(depth)
+	if _, err := s.cw.Put(c, data); err != nil {
+		return err
+	}
+	for _, lnk := range links {
+		child := lnk.(cidlink.Link).Cid
+		if s.follow(child) {
+			s.push(child, depth+1)
+		}
+	}
+	return nil
+}
+
+// follow returns whether the linked block belongs in the snapshot
+func (s *snapshotter) follow(c cid.Cid) bool {
+	switch c.Prefix().Codec {
+	case state_trie.MultiCodecType:
+		return true
+	case storage_trie.MultiCodecType:
+		return s.opts.Storage && !isEmptyRoot(c)
+	case cid.Raw:
+		return s.opts.Code && !isEmptyCode(c)
+	default:
+		return false
+	}
+}
+
+// isEmptyCode returns whether the CID references the code of accounts without code, which is never stored
+func isEmptyCode(c cid.Cid) bool {
+	return shared.HasHash(c, multihash.KECCAK_256, types.EmptyCodeHash.Bytes())
+}
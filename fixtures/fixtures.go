@@ -0,0 +1,320 @@
+/*
+Package fixtures fetches real blocks from an Ethereum node over JSON-RPC and stores them as binary fixtures,
+so the test corpus can cover every fork boundary without hand-crafted hex strings.
+
+The node has to serve the debug_getRawHeader, debug_getRawBlock and debug_getRawReceipts methods, and
+eth_getProof for the state of the fetched blocks. Each block is stored in a directory named after its number:
+
+	<dir>/<number>/header.rlp             the RLP encoded header
+	<dir>/<number>/block.rlp              the RLP encoded block, holding its transactions, uncles and withdrawals
+	<dir>/<number>/receipts.rlp           the RLP list of the consensus encodings of its receipts
+	<dir>/<number>/proof_<address>.rlp    the RLP encoded Proof of an account, in lower case hex without 0x
+*/
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	headerFile   = "header.rlp"
+	blockFile    = "block.rlp"
+	receiptsFile = "receipts.rlp"
+	proofPrefix  = "proof_"
+)
+
+// Block holds the consensus encodings of a block and its receipts, and proofs of accounts in its post state
+type Block struct {
+	Number uint64
+	// Header is the RLP encoded header
+	Header []byte
+	// Block is the RLP encoded block
+	Block []byte
+	// Receipts are the consensus encodings of the receipts of the block
+	Receipts [][]byte
+	// Proofs are the proofs of accounts, and some of their storage slots, in the state after the block
+	Proofs []Proof
+}
+
+// Proof is an eth_getProof proof of an account and some of its storage slots
+type Proof struct {
+	Address common.Address
+	// AccountProof are the state trie nodes on the path from the state root to the account
+	AccountProof [][]byte
+	// StorageKeys are the proven storage slots
+	StorageKeys []common.Hash
+	// StorageProofs are the storage trie nodes on the path from the storage root to each of the slots
+	StorageProofs [][][]byte
+}
+
+// GethBlock decodes the block into a go-ethereum Block
+func (b *Block) GethBlock() (*types.Block, error) {
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(b.Block, block); err != nil {
+		return nil, fmt.Errorf("unable to decode block %d: %v", b.Number, err)
+	}
+	return block, nil
+}
+
+// Transactions returns the consensus encodings of the transactions of the block
+func (b *Block) Transactions() ([][]byte, error) {
+	block, err := b.GethBlock()
+	if err != nil {
+		return nil, err
+	}
+	txs := make([][]byte, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		if txs[i], err = tx.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return txs, nil
+}
+
+// Uncles returns the RLP list of the uncle headers of the block
+func (b *Block) Uncles() ([]byte, error) {
+	block, err := b.GethBlock()
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(block.Uncles())
+}
+
+// Withdrawals returns the RLP encodings of the withdrawals of the block, which are nil before Shanghai
+func (b *Block) Withdrawals() ([][]byte, error) {
+	block, err := b.GethBlock()
+	if err != nil {
+		return nil, err
+	}
+	var withdrawals [][]byte
+	for _, w := range block.Withdrawals() {
+		enc, err := rlp.EncodeToBytes(w)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, enc)
+	}
+	return withdrawals, nil
+}
+
+// FetchOptions can be used to customize which proofs are fetched along with each block.
+// The zero value fetches the proof of the coinbase account of each block.
+type FetchOptions struct {
+	// Accounts are the accounts whose proofs are fetched in addition to the coinbase
+	Accounts []common.Address
+	// StorageKeys are the storage slots proven for each account
+	StorageKeys []common.Hash
+}
+
+// Fetch fetches the block with the number, its receipts and the proof of its coinbase from the node
+func Fetch(ctx context.Context, client *rpc.Client, number uint64) (*Block, error) {
+	return FetchOptions{}.Fetch(ctx, client, number)
+}
+
+// Download fetches the blocks with the numbers from the node at the RPC URL, and writes them into the directory
+func Download(ctx context.Context, rpcURL, dir string, numbers ...uint64) error {
+	return FetchOptions{}.Download(ctx, rpcURL, dir, numbers...)
+}
+
+// Fetch is like the package level Fetch, but uses the provided options
+func (opts FetchOptions) Fetch(ctx context.Context, client *rpc.Client, number uint64) (*Block, error) {
+	blockNr := hexutil.EncodeUint64(number)
+	b := &Block{Number: number}
+	var header, block hexutil.Bytes
+	if err := client.CallContext(ctx, &header, "debug_getRawHeader", blockNr); err != nil {
+		return nil, fmt.Errorf("unable to fetch header %d: %v", number, err)
+	}
+	if err := client.CallContext(ctx, &block, "debug_getRawBlock", blockNr); err != nil {
+		return nil, fmt.Errorf("unable to fetch block %d: %v", number, err)
+	}
+	var receipts []hexutil.Bytes
+	if err := client.CallContext(ctx, &receipts, "debug_getRawReceipts", blockNr); err != nil {
+		return nil, fmt.Errorf("unable to fetch receipts of block %d: %v", number, err)
+	}
+	b.Header, b.Block = header, block
+	for _, receipt := range receipts {
+		b.Receipts = append(b.Receipts, receipt)
+	}
+
+	gethHeader := new(types.Header)
+	if err := rlp.DecodeBytes(b.Header, gethHeader); err != nil {
+		return nil, fmt.Errorf("unable to decode header %d: %v", number, err)
+	}
+	accounts := append([]common.Address{gethHeader.Coinbase}, opts.Accounts...)
+	seen := make(map[common.Address]bool, len(accounts))
+	for _, addr := range accounts {
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		proof, err := opts.fetchProof(ctx, client, addr, blockNr)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch proof of %s at block %d: %v", addr.Hex(), number, err)
+		}
+		b.Proofs = append(b.Proofs, *proof)
+	}
+	return b, nil
+}
+
+// proofResult is the part of the eth_getProof result that is stored
+type proofResult struct {
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	StorageProof []struct {
+		Key   string          `json:"key"`
+		Proof []hexutil.Bytes `json:"proof"`
+	} `json:"storageProof"`
+}
+
+func (opts FetchOptions) fetchProof(ctx context.Context, client *rpc.Client, addr common.Address, blockNr string) (*Proof, error) {
+	keys := make([]string, len(opts.StorageKeys))
+	for i, key := range opts.StorageKeys {
+		keys[i] = key.Hex()
+	}
+	var res proofResult
+	if err := client.CallContext(ctx, &res, "eth_getProof", addr, keys, blockNr); err != nil {
+		return nil, err
+	}
+	proof := &Proof{Address: addr, StorageKeys: opts.StorageKeys}
+	for _, node := range res.AccountProof {
+		proof.AccountProof = append(proof.AccountProof, node)
+	}
+	if len(res.StorageProof) != len(opts.StorageKeys) {
+		return nil, fmt.Errorf("expected %d storage proofs, got %d", len(opts.StorageKeys), len(res.StorageProof))
+	}
+	for _, storageProof := range res.StorageProof {
+		nodes := make([][]byte, len(storageProof.Proof))
+		for i, node := range storageProof.Proof {
+			nodes[i] = node
+		}
+		proof.StorageProofs = append(proof.StorageProofs, nodes)
+	}
+	return proof, nil
+}
+
+// Download is like the package level Download, but uses the provided options
+func (opts FetchOptions) Download(ctx context.Context, rpcURL, dir string, numbers ...uint64) error {
+	client, err := rpc.DialContext(ctx, rpcURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	for _, number := range numbers {
+		block, err := opts.Fetch(ctx, client, number)
+		if err != nil {
+			return err
+		}
+		if err := Write(dir, block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write writes the block into its directory within dir
+func Write(dir string, block *Block) error {
+	blockDir := filepath.Join(dir, strconv.FormatUint(block.Number, 10))
+	if err := os.MkdirAll(blockDir, 0755); err != nil {
+		return err
+	}
+	receipts, err := rlp.EncodeToBytes(block.Receipts)
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		headerFile:   block.Header,
+		blockFile:    block.Block,
+		receiptsFile: receipts,
+	}
+	for _, proof := range block.Proofs {
+		enc, err := rlp.EncodeToBytes(proof)
+		if err != nil {
+			return err
+		}
+		files[proofFileName(proof.Address)] = enc
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(blockDir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads the block with the number from its directory within dir
+func Load(dir string, number uint64) (*Block, error) {
+	blockDir := filepath.Join(dir, strconv.FormatUint(number, 10))
+	block := &Block{Number: number}
+	var err error
+	if block.Header, err = os.ReadFile(filepath.Join(blockDir, headerFile)); err != nil {
+		return nil, err
+	}
+	if block.Block, err = os.ReadFile(filepath.Join(blockDir, blockFile)); err != nil {
+		return nil, err
+	}
+	receipts, err := os.ReadFile(filepath.Join(blockDir, receiptsFile))
+	if err != nil {
+		return nil, err
+	}
+	if err := rlp.DecodeBytes(receipts, &block.Receipts); err != nil {
+		return nil, fmt.Errorf("unable to decode receipts of block %d: %v", number, err)
+	}
+	entries, err := os.ReadDir(blockDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), proofPrefix) {
+			continue
+		}
+		enc, err := os.ReadFile(filepath.Join(blockDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var proof Proof
+		if err := rlp.DecodeBytes(enc, &proof); err != nil {
+			return nil, fmt.Errorf("unable to decode %s of block %d: %v", entry.Name(), number, err)
+		}
+		block.Proofs = append(block.Proofs, proof)
+	}
+	return block, nil
+}
+
+// LoadAll reads every block in dir, in ascending block number order
+func LoadAll(dir string) ([]*Block, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var numbers []uint64
+	for _, entry := range entries {
+		number, err := strconv.ParseUint(entry.Name(), 10, 64)
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	blocks := make([]*Block, len(numbers))
+	for i, number := range numbers {
+		if blocks[i], err = Load(dir, number); err != nil {
+			return nil, err
+		}
+	}
+	return blocks, nil
+}
+
+func proofFileName(addr common.Address) string {
+	return proofPrefix + strings.ToLower(strings.TrimPrefix(addr.Hex(), "0x")) + ".rlp"
+}
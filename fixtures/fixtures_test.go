@@ -0,0 +1,230 @@
+package fixtures_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+
+	"github.com/vulcanize/go-codec-dageth/fixtures"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+)
+
+// mockNode serves the blocks, receipts and proofs generated for it over the debug and eth namespaces
+type mockNode struct {
+	blocks   map[uint64]*types.Block
+	receipts map[uint64]types.Receipts
+	proof    [][]byte
+}
+
+type debugAPI struct{ node *mockNode }
+
+func (api *debugAPI) GetRawHeader(blockNr hexutil.Uint64) (hexutil.Bytes, error) {
+	block, err := api.node.block(blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(block.Header())
+}
+
+func (api *debugAPI) GetRawBlock(blockNr hexutil.Uint64) (hexutil.Bytes, error) {
+	block, err := api.node.block(blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(block)
+}
+
+func (api *debugAPI) GetRawReceipts(blockNr hexutil.Uint64) ([]hexutil.Bytes, error) {
+	if _, err := api.node.block(blockNr); err != nil {
+		return nil, err
+	}
+	var encs []hexutil.Bytes
+	for _, receipt := range api.node.receipts[uint64(blockNr)] {
+		enc, err := receipt.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		encs = append(encs, enc)
+	}
+	return encs, nil
+}
+
+type storageProof struct {
+	Key   string          `json:"key"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+type accountProof struct {
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	StorageProof []storageProof  `json:"storageProof"`
+}
+
+type ethAPI struct{ node *mockNode }
+
+func (api *ethAPI) GetProof(addr common.Address, keys []string, blockNr hexutil.Uint64) (*accountProof, error) {
+	if _, err := api.node.block(blockNr); err != nil {
+		return nil, err
+	}
+	res := &accountProof{StorageProof: make([]storageProof, len(keys))}
+	for _, node := range api.node.proof {
+		res.AccountProof = append(res.AccountProof, node)
+		for i, key := range keys {
+			res.StorageProof[i].Key = key
+			res.StorageProof[i].Proof = append(res.StorageProof[i].Proof, node)
+		}
+	}
+	return res, nil
+}
+
+func (n *mockNode) block(blockNr hexutil.Uint64) (*types.Block, error) {
+	block, ok := n.blocks[uint64(blockNr)]
+	if !ok {
+		return nil, fmt.Errorf("block %d not found", blockNr)
+	}
+	return block, nil
+}
+
+func newMockNode(t *testing.T, numbers ...uint64) (*mockNode, string) {
+	g := testutil.NewGenerator(1)
+	node := &mockNode{blocks: make(map[uint64]*types.Block), receipts: make(map[uint64]types.Receipts)}
+	for _, number := range numbers {
+		header := g.Header()
+		header.Number.SetUint64(number)
+		txs := g.Transactions(4)
+		body := &types.Body{Transactions: txs, Uncles: g.Headers(1)}
+		if header.WithdrawalsHash != nil {
+			body.Withdrawals = g.Withdrawals(2)
+		}
+		node.receipts[number] = g.Receipts(txs)
+		node.blocks[number] = types.NewBlock(header, body, node.receipts[number], gethtrie.NewStackTrie(nil))
+	}
+	accounts := make([][]byte, 16)
+	for i := range accounts {
+		accounts[i], _ = rlp.EncodeToBytes(g.Account())
+	}
+	node.proof = g.TrieNodes(accounts)
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("debug", &debugAPI{node}); err != nil {
+		t.Fatalf("unable to register debug API: %v", err)
+	}
+	if err := srv.RegisterName("eth", &ethAPI{node}); err != nil {
+		t.Fatalf("unable to register eth API: %v", err)
+	}
+	httpSrv := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		httpSrv.Close()
+		srv.Stop()
+	})
+	return node, httpSrv.URL
+}
+
+func TestDownloadAndLoad(t *testing.T) {
+	node, url := newMockNode(t, 7, 3)
+	dir := t.TempDir()
+	extra := common.HexToAddress("0x00000000219ab540356cbb839cbe05303d7705fa")
+	opts := fixtures.FetchOptions{
+		Accounts:    []common.Address{extra},
+		StorageKeys: []common.Hash{common.HexToHash("0x01")},
+	}
+	if err := opts.Download(context.Background(), url, dir, 7, 3); err != nil {
+		t.Fatalf("unable to download fixtures: %v", err)
+	}
+	if err := fixtures.Download(context.Background(), url, dir, 8); err == nil {
+		t.Error("expected downloading a block the node does not have to fail")
+	}
+
+	blocks, err := fixtures.LoadAll(dir)
+	if err != nil {
+		t.Fatalf("unable to load fixtures: %v", err)
+	}
+	if len(blocks) != 2 || blocks[0].Number != 3 || blocks[1].Number != 7 {
+		t.Fatalf("expected fixtures of blocks 3 and 7 in order, got %d blocks", len(blocks))
+	}
+	for _, block := range blocks {
+		expected := node.blocks[block.Number]
+		gethBlock, err := block.GethBlock()
+		if err != nil {
+			t.Fatalf("unable to decode block %d fixture: %v", block.Number, err)
+		}
+		if gethBlock.Hash() != expected.Hash() {
+			t.Errorf("block %d fixture hash (%s) does not match expected hash (%s)", block.Number, gethBlock.Hash().Hex(), expected.Hash().Hex())
+		}
+		if err := testutil.RoundTrip(header.MultiCodecType, block.Header); err != nil {
+			t.Errorf("block %d header fixture does not round trip: %v", block.Number, err)
+		}
+		unclesRLP, err := block.Uncles()
+		if err != nil {
+			t.Fatalf("unable to get block %d uncles: %v", block.Number, err)
+		}
+		if err := testutil.RoundTrip(uncles.MultiCodecType, unclesRLP); err != nil {
+			t.Errorf("block %d uncles fixture does not round trip: %v", block.Number, err)
+		}
+		txs, err := block.Transactions()
+		if err != nil {
+			t.Fatalf("unable to get block %d transactions: %v", block.Number, err)
+		}
+		if len(txs) != len(expected.Transactions()) || len(block.Receipts) != len(txs) {
+			t.Fatalf("block %d fixture has %d transactions and %d receipts, expected %d", block.Number, len(txs), len(block.Receipts), len(expected.Transactions()))
+		}
+		for i := range txs {
+			if err := testutil.RoundTrip(tx.MultiCodecType, txs[i]); err != nil {
+				t.Errorf("block %d transaction %d fixture does not round trip: %v", block.Number, i, err)
+			}
+			if err := testutil.RoundTrip(rct.MultiCodecType, block.Receipts[i]); err != nil {
+				t.Errorf("block %d receipt %d fixture does not round trip: %v", block.Number, i, err)
+			}
+		}
+		receipts := make(types.Receipts, len(block.Receipts))
+		for i, enc := range block.Receipts {
+			receipts[i] = new(types.Receipt)
+			if err := receipts[i].UnmarshalBinary(enc); err != nil {
+				t.Fatalf("unable to decode block %d receipt %d fixture: %v", block.Number, i, err)
+			}
+		}
+		if types.DeriveSha(receipts, gethtrie.NewStackTrie(nil)) != gethBlock.ReceiptHash() {
+			t.Errorf("block %d receipts fixture does not match the receipt root", block.Number)
+		}
+		withdrawals, err := block.Withdrawals()
+		if err != nil {
+			t.Fatalf("unable to get block %d withdrawals: %v", block.Number, err)
+		}
+		if len(withdrawals) != len(expected.Withdrawals()) {
+			t.Errorf("block %d fixture has %d withdrawals, expected %d", block.Number, len(withdrawals), len(expected.Withdrawals()))
+		}
+
+		if len(block.Proofs) != 2 {
+			t.Fatalf("expected proofs of the coinbase and the extra account in block %d fixture, got %d", block.Number, len(block.Proofs))
+		}
+		for _, proof := range block.Proofs {
+			if proof.Address != expected.Coinbase() && proof.Address != extra {
+				t.Errorf("block %d fixture has a proof of unexpected account %s", block.Number, proof.Address.Hex())
+			}
+			if len(proof.AccountProof) != len(node.proof) || len(proof.StorageProofs) != 1 || len(proof.StorageKeys) != 1 {
+				t.Fatalf("block %d fixture proof of %s does not hold the served nodes", block.Number, proof.Address.Hex())
+			}
+			for i, node := range proof.AccountProof {
+				if !bytes.Equal(node, proof.StorageProofs[0][i]) {
+					t.Errorf("block %d fixture storage proof node %d does not match the served node", block.Number, i)
+				}
+				if err := testutil.RoundTrip(state_trie.MultiCodecType, node); err != nil {
+					t.Errorf("block %d fixture account proof node %d does not round trip: %v", block.Number, i, err)
+				}
+			}
+		}
+	}
+}
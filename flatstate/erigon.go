@@ -0,0 +1,117 @@
+package flatstate
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// Erigon account fields, flagged in the first byte of its storage encoding
+const (
+	erigonNonce       = 1
+	erigonBalance     = 2
+	erigonIncarnation = 4
+	erigonCodeHash    = 8
+)
+
+// erigonIncarnationLength is the length of the incarnation in the keys of Erigon storage slots
+const erigonIncarnationLength = 8
+
+// decodeErigonAccount decodes an account of Erigon's PlainState or HashedAccounts tables, in Erigon's storage
+// encoding: a byte flagging the fields that are set, followed by each of them as a length byte and big endian
+// bytes, in the order nonce, balance, incarnation and code hash. The storage root is not part of it.
+func decodeErigonAccount(key, value []byte) (*account, error) {
+	hash, err := addressHash(key)
+	if err != nil {
+		return nil, err
+	}
+	acct := &account{hash: hash, balance: new(uint256.Int), codeHash: types.EmptyCodeHash}
+	if len(value) == 0 {
+		return acct, nil
+	}
+	fields, rest := value[0], value[1:]
+	field := func(name string, max int) ([]byte, error) {
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("erigon account %x is missing its %s", key, name)
+		}
+		length := int(rest[0])
+		if length > max || len(rest) < 1+length {
+			return nil, fmt.Errorf("erigon account %x has an invalid %s length %d", key, name, length)
+		}
+		b := rest[1 : 1+length]
+		rest = rest[1+length:]
+		return b, nil
+	}
+	if fields&erigonNonce != 0 {
+		b, err := field("nonce", 8)
+		if err != nil {
+			return nil, err
+		}
+		acct.nonce = new(uint256.Int).SetBytes(b).Uint64()
+	}
+	if fields&erigonBalance != 0 {
+		b, err := field("balance", 32)
+		if err != nil {
+			return nil, err
+		}
+		acct.balance.SetBytes(b)
+	}
+	if fields&erigonIncarnation != 0 {
+		b, err := field("incarnation", 8)
+		if err != nil {
+			return nil, err
+		}
+		acct.incarnation = new(uint256.Int).SetBytes(b).Uint64()
+	}
+	if fields&erigonCodeHash != 0 {
+		b, err := field("code hash", common.HashLength)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != common.HashLength {
+			return nil, fmt.Errorf("erigon account %x has a %d byte code hash", key, len(b))
+		}
+		acct.codeHash = common.BytesToHash(b)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("erigon account %x has %d trailing bytes", key, len(rest))
+	}
+	return acct, nil
+}
+
+// decodeErigonSlot decodes a storage slot of Erigon's PlainState or HashedStorage tables, keyed by the address or
+// its hash, the incarnation of the account, and the slot or its hash, with the value stripped of leading zeros
+func decodeErigonSlot(key, value []byte) (*slot, error) {
+	var addrLength int
+	switch len(key) {
+	case common.AddressLength + erigonIncarnationLength + common.HashLength:
+		addrLength = common.AddressLength
+	case common.HashLength + erigonIncarnationLength + common.HashLength:
+		addrLength = common.HashLength
+	default:
+		return nil, fmt.Errorf("erigon storage key %x is neither plain nor hashed", key)
+	}
+	addrHash, err := addressHash(key[:addrLength])
+	if err != nil {
+		return nil, err
+	}
+	slotKey := key[addrLength+erigonIncarnationLength:]
+	keyHash := common.BytesToHash(slotKey)
+	if addrLength == common.AddressLength {
+		keyHash = crypto.Keccak256Hash(slotKey)
+	}
+	v, err := slotValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return &slot{
+		addrHash:    addrHash,
+		incarnation: binary.BigEndian.Uint64(key[addrLength : addrLength+erigonIncarnationLength]),
+		keyHash:     keyHash,
+		value:       v,
+	}, nil
+}
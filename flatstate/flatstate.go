@@ -0,0 +1,340 @@
+/*
+Package flatstate rebuilds the state trie out of the flat state tables of Erigon and Reth, which keep the latest
+accounts and storage slots keyed by address or hash instead of in a trie. The accounts and storage of a table dump
+are re-encoded into state and storage trie nodes with the trie builder and stored through a LinkSystem, and the link
+to the state root is returned, to be checked against the StateRootCID of the header the dump was taken at.
+*/
+package flatstate
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/trie"
+	"github.com/vulcanize/go-codec-dageth/util"
+)
+
+// Format is the layout of the tables of a flat state dump
+type Format int
+
+const (
+	// ErigonPlainState is Erigon's PlainState table, which holds both the accounts, keyed by address, and the
+	// storage slots, keyed by address, incarnation and slot; the two are told apart by the length of their keys,
+	// so the storage table can be nil
+	ErigonPlainState Format = iota
+	// ErigonHashedState is Erigon's HashedAccounts table, keyed by address hash, along with its HashedStorage
+	// table, keyed by address hash, incarnation and slot hash
+	ErigonHashedState
+	// RethPlainState is Reth's PlainAccountState table, keyed by address, along with its PlainStorageState table,
+	// keyed by address with a storage entry per slot
+	RethPlainState
+	// RethHashedState is Reth's HashedAccounts table, keyed by address hash, along with its HashedStorages table,
+	// keyed by address hash with a storage entry per slot hash
+	RethHashedState
+)
+
+// String returns the name of the format
+func (f Format) String() string {
+	switch f {
+	case ErigonPlainState:
+		return "erigon-plain"
+	case ErigonHashedState:
+		return "erigon-hashed"
+	case RethPlainState:
+		return "reth-plain"
+	case RethHashedState:
+		return "reth-hashed"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(f))
+	}
+}
+
+// hashed returns whether the tables of the format are keyed by hash, and so iterate in the order of the trie
+func (f Format) hashed() bool {
+	return f == ErigonHashedState || f == RethHashedState
+}
+
+// Table iterates the entries of a dumped table in the order of their keys, dupsort tables yielding an entry per
+// value. An ethdb.Iterator over a database the dump was loaded into satisfies it.
+type Table interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Error() error
+}
+
+// Entry is a key/value pair of a table
+type Entry struct {
+	Key, Value []byte
+}
+
+// sliceTable is a Table over entries held in memory
+type sliceTable struct {
+	entries []Entry
+	pos     int
+}
+
+// NewSliceTable returns a Table over the entries, which need to be in the order of their keys
+func NewSliceTable(entries []Entry) Table {
+	return &sliceTable{entries: entries, pos: -1}
+}
+
+func (t *sliceTable) Next() bool {
+	if t.pos < len(t.entries) {
+		t.pos++
+	}
+	return t.pos < len(t.entries)
+}
+
+func (t *sliceTable) Key() []byte   { return t.entries[t.pos].Key }
+func (t *sliceTable) Value() []byte { return t.entries[t.pos].Value }
+func (t *sliceTable) Error() error  { return nil }
+
+// account is a flat state account, keyed by the hash of its address
+type account struct {
+	hash        common.Hash
+	nonce       uint64
+	balance     *uint256.Int
+	codeHash    common.Hash
+	incarnation uint64
+}
+
+// slot is a flat state storage slot, keyed by the hashes of its account's address and of its key
+type slot struct {
+	addrHash    common.Hash
+	incarnation uint64
+	keyHash     common.Hash
+	value       []byte
+}
+
+// Build rebuilds the state trie from the account and storage tables of a dump in the format, stores its nodes
+// through the LinkSystem, and returns the link to its root.
+// Hashed tables are streamed, as they iterate in the order of the trie; plain tables are read into memory to be
+// sorted by hash first. Storage slots of accounts missing from the account table, or of earlier incarnations of
+// an Erigon account, are skipped.
+func Build(lsys ipld.LinkSystem, format Format, accounts, storage Table) (ipld.Link, error) {
+	nextAccount, nextSlot, err := sources(format, accounts, storage)
+	if err != nil {
+		return nil, err
+	}
+	stateBuilder := trie.NewBuilder(lsys, state_trie.MultiCodecType)
+	storageBuilder := trie.NewBuilder(lsys, storage_trie.MultiCodecType)
+	pending, err := nextSlot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		acct, err := nextAccount()
+		if err != nil {
+			return nil, err
+		}
+		if acct == nil {
+			break
+		}
+		for pending != nil && bytes.Compare(pending.addrHash[:], acct.hash[:]) <= 0 {
+			// zero slots are not part of the trie
+			if pending.addrHash == acct.hash && pending.incarnation == acct.incarnation && len(pending.value) > 0 {
+				value, err := rlp.EncodeToBytes(pending.value)
+				if err != nil {
+					return nil, err
+				}
+				if err := storageBuilder.Update(pending.keyHash[:], value); err != nil {
+					return nil, fmt.Errorf("invalid storage of account %x: %v", acct.hash, err)
+				}
+			}
+			if pending, err = nextSlot(); err != nil {
+				return nil, err
+			}
+		}
+		storageRoot, err := storageBuilder.Commit()
+		if err != nil {
+			return nil, err
+		}
+		root, err := util.LinkToKeccak256(storageRoot)
+		if err != nil {
+			return nil, err
+		}
+		enc, err := rlp.EncodeToBytes(&types.StateAccount{
+			Nonce:    acct.nonce,
+			Balance:  acct.balance,
+			Root:     root,
+			CodeHash: acct.codeHash.Bytes(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := stateBuilder.Update(acct.hash[:], enc); err != nil {
+			return nil, fmt.Errorf("invalid account %x: %v", acct.hash, err)
+		}
+	}
+	return stateBuilder.Commit()
+}
+
+// Verify is like Build, but returns an error if the rebuilt state root is not the expected one, e.g. the state
+// root of the header the dump was taken at
+func Verify(lsys ipld.LinkSystem, format Format, accounts, storage Table, expected common.Hash) (ipld.Link, error) {
+	lnk, err := Build(lsys, format, accounts, storage)
+	if err != nil {
+		return nil, err
+	}
+	root, err := util.LinkToKeccak256(lnk)
+	if err != nil {
+		return nil, err
+	}
+	if root != expected {
+		return nil, fmt.Errorf("%s state dump rebuilds into state root %x, expected %x", format, root, expected)
+	}
+	return lnk, nil
+}
+
+// decoder decodes the accounts and storage slots of the tables of a format
+type decoder struct {
+	account func(key, value []byte) (*account, error)
+	slot    func(key, value []byte) (*slot, error)
+}
+
+// sources returns functions yielding the accounts and storage slots of the tables in the order of their hashes,
+// and nil once they are exhausted
+func sources(format Format, accounts, storage Table) (func() (*account, error), func() (*slot, error), error) {
+	var dec decoder
+	switch format {
+	case ErigonPlainState, ErigonHashedState:
+		dec = decoder{account: decodeErigonAccount, slot: decodeErigonSlot}
+	case RethPlainState:
+		dec = decoder{account: decodeRethAccount, slot: decodeRethPlainSlot}
+	case RethHashedState:
+		dec = decoder{account: decodeRethAccount, slot: decodeRethHashedSlot}
+	default:
+		return nil, nil, fmt.Errorf("unrecognized flat state format %s", format)
+	}
+	if !format.hashed() {
+		accts, slots, err := readPlain(format, accounts, storage, dec)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func() (*account, error) {
+				if len(accts) == 0 {
+					return nil, nil
+				}
+				acct := accts[0]
+				accts = accts[1:]
+				return acct, nil
+			}, func() (*slot, error) {
+				if len(slots) == 0 {
+					return nil, nil
+				}
+				s := slots[0]
+				slots = slots[1:]
+				return s, nil
+			}, nil
+	}
+	return func() (*account, error) {
+			key, value, err := next(accounts)
+			if key == nil || err != nil {
+				return nil, err
+			}
+			return dec.account(key, value)
+		}, func() (*slot, error) {
+			key, value, err := next(storage)
+			if key == nil || err != nil {
+				return nil, err
+			}
+			return dec.slot(key, value)
+		}, nil
+}
+
+// next returns the key and value of the next entry of the table, or a nil key once it is exhausted
+func next(table Table) ([]byte, []byte, error) {
+	if table == nil {
+		return nil, nil, nil
+	}
+	if !table.Next() {
+		return nil, nil, table.Error()
+	}
+	return table.Key(), table.Value(), nil
+}
+
+// readPlain reads the accounts and storage slots of plain tables into memory, and sorts them by hash
+func readPlain(format Format, accounts, storage Table, dec decoder) ([]*account, []*slot, error) {
+	var accts []*account
+	var slots []*slot
+	for {
+		key, value, err := next(accounts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if key == nil {
+			break
+		}
+		// Erigon's PlainState holds the storage slots along with the accounts
+		if format == ErigonPlainState && len(key) != common.AddressLength {
+			s, err := dec.slot(key, value)
+			if err != nil {
+				return nil, nil, err
+			}
+			slots = append(slots, s)
+			continue
+		}
+		acct, err := dec.account(key, value)
+		if err != nil {
+			return nil, nil, err
+		}
+		accts = append(accts, acct)
+	}
+	for {
+		key, value, err := next(storage)
+		if err != nil {
+			return nil, nil, err
+		}
+		if key == nil {
+			break
+		}
+		s, err := dec.slot(key, value)
+		if err != nil {
+			return nil, nil, err
+		}
+		slots = append(slots, s)
+	}
+	sort.Slice(accts, func(i, j int) bool {
+		return bytes.Compare(accts[i].hash[:], accts[j].hash[:]) < 0
+	})
+	sort.Slice(slots, func(i, j int) bool {
+		if c := bytes.Compare(slots[i].addrHash[:], slots[j].addrHash[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(slots[i].keyHash[:], slots[j].keyHash[:]) < 0
+	})
+	return accts, slots, nil
+}
+
+// addressHash returns the hash of the account key of a table: the keccak256 hash of an address, or the key
+// itself if it is an address hash already
+func addressHash(key []byte) (common.Hash, error) {
+	switch len(key) {
+	case common.AddressLength:
+		return crypto.Keccak256Hash(key), nil
+	case common.HashLength:
+		return common.BytesToHash(key), nil
+	default:
+		return common.Hash{}, fmt.Errorf("account key %x is neither an address nor an address hash", key)
+	}
+}
+
+// slotValue returns the value of a storage slot with its leading zeros trimmed
+func slotValue(value []byte) ([]byte, error) {
+	value = common.TrimLeftZeroes(value)
+	if len(value) > common.HashLength {
+		return nil, fmt.Errorf("storage slot value %x is longer than %d bytes", value, common.HashLength)
+	}
+	return value, nil
+}
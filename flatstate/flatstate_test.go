@@ -0,0 +1,191 @@
+package flatstate_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/flatstate"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+type mockAccount struct {
+	address     common.Address
+	account     *types.StateAccount
+	incarnation uint64
+	storage     map[common.Hash]common.Hash
+}
+
+// mockState returns accounts with storage for the contracts, along with the state root geth derives for them
+func mockState(t *testing.T) ([]mockAccount, common.Hash) {
+	g := testutil.NewGenerator(7)
+	accounts := make([]mockAccount, 30)
+	stateKVs := make(map[common.Hash][]byte)
+	for i := range accounts {
+		acct := g.Account()
+		m := mockAccount{address: g.Address(), account: acct}
+		if !bytes.Equal(acct.CodeHash, types.EmptyCodeHash.Bytes()) {
+			m.incarnation = 1
+			m.storage = make(map[common.Hash]common.Hash)
+			storageKVs := make(map[common.Hash][]byte)
+			for j := 0; j < 5; j++ {
+				key, value := g.Hash(), common.BigToHash(g.Big(64))
+				m.storage[key] = value
+				storageKVs[crypto.Keccak256Hash(key[:])], _ = rlp.EncodeToBytes(common.TrimLeftZeroes(value[:]))
+			}
+			acct.Root = stackRoot(t, storageKVs)
+		}
+		stateKVs[crypto.Keccak256Hash(m.address[:])], _ = rlp.EncodeToBytes(acct)
+		accounts[i] = m
+	}
+	return accounts, stackRoot(t, stateKVs)
+}
+
+func stackRoot(t *testing.T, kvs map[common.Hash][]byte) common.Hash {
+	keys := make([]common.Hash, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	st := gethtrie.NewStackTrie(nil)
+	for _, k := range keys {
+		if err := st.Update(k[:], kvs[k]); err != nil {
+			t.Fatalf("unable to update trie: %v", err)
+		}
+	}
+	return st.Hash()
+}
+
+// erigonAccount encodes the account in Erigon's storage encoding
+func erigonAccount(m mockAccount) []byte {
+	enc := []byte{0}
+	field := func(flag byte, b []byte) {
+		enc[0] |= flag
+		enc = append(append(enc, byte(len(b))), b...)
+	}
+	if m.account.Nonce > 0 {
+		nonce := make([]byte, 8)
+		binary.BigEndian.PutUint64(nonce, m.account.Nonce)
+		field(1, common.TrimLeftZeroes(nonce))
+	}
+	if !m.account.Balance.IsZero() {
+		field(2, m.account.Balance.Bytes())
+	}
+	if m.incarnation > 0 {
+		field(4, []byte{byte(m.incarnation)})
+	}
+	if !bytes.Equal(m.account.CodeHash, types.EmptyCodeHash.Bytes()) {
+		field(8, m.account.CodeHash)
+	}
+	return enc
+}
+
+// rethAccount encodes the account in Reth's compact encoding
+func rethAccount(m mockAccount) []byte {
+	nonce := make([]byte, 8)
+	binary.BigEndian.PutUint64(nonce, m.account.Nonce)
+	nonce = common.TrimLeftZeroes(nonce)
+	balance := m.account.Balance.Bytes()
+	flags := uint16(len(nonce)) | uint16(len(balance))<<4
+	hasCode := !bytes.Equal(m.account.CodeHash, types.EmptyCodeHash.Bytes())
+	if hasCode {
+		flags |= 1 << 10
+	}
+	enc := binary.LittleEndian.AppendUint16(nil, flags)
+	enc = append(append(enc, nonce...), balance...)
+	if hasCode {
+		enc = append(enc, m.account.CodeHash...)
+	}
+	return enc
+}
+
+func sorted(entries []flatstate.Entry) flatstate.Table {
+	sort.SliceStable(entries, func(i, j int) bool { return bytes.Compare(entries[i].Key, entries[j].Key) < 0 })
+	return flatstate.NewSliceTable(entries)
+}
+
+func incarnation(i uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, i)
+}
+
+func TestBuild(t *testing.T) {
+	accounts, expected := mockState(t)
+	var erigonPlain, erigonAccounts, erigonStorage, rethPlainAccounts, rethPlainStorage, rethAccounts, rethStorage []flatstate.Entry
+	for _, m := range accounts {
+		addrHash := crypto.Keccak256(m.address[:])
+		erigonPlain = append(erigonPlain, flatstate.Entry{Key: m.address[:], Value: erigonAccount(m)})
+		erigonAccounts = append(erigonAccounts, flatstate.Entry{Key: addrHash, Value: erigonAccount(m)})
+		rethPlainAccounts = append(rethPlainAccounts, flatstate.Entry{Key: m.address[:], Value: rethAccount(m)})
+		rethAccounts = append(rethAccounts, flatstate.Entry{Key: addrHash, Value: rethAccount(m)})
+		if m.storage != nil {
+			// a slot left behind by an earlier incarnation of the contract
+			stale := append(append(append([]byte{}, m.address[:]...), incarnation(0)...), crypto.Keccak256([]byte("stale"))...)
+			erigonPlain = append(erigonPlain, flatstate.Entry{Key: stale, Value: []byte{1}})
+		}
+		for key, value := range m.storage {
+			keyHash := crypto.Keccak256(key[:])
+			trimmed := common.TrimLeftZeroes(value[:])
+			erigonPlain = append(erigonPlain, flatstate.Entry{
+				Key:   append(append(append([]byte{}, m.address[:]...), incarnation(m.incarnation)...), key[:]...),
+				Value: trimmed,
+			})
+			erigonStorage = append(erigonStorage, flatstate.Entry{
+				Key:   append(append(append([]byte{}, addrHash...), incarnation(m.incarnation)...), keyHash...),
+				Value: trimmed,
+			})
+			rethPlainStorage = append(rethPlainStorage, flatstate.Entry{
+				Key:   m.address[:],
+				Value: append(append([]byte{}, key[:]...), trimmed...),
+			})
+			rethStorage = append(rethStorage, flatstate.Entry{
+				Key:   addrHash,
+				Value: append(append([]byte{}, keyHash...), trimmed...),
+			})
+		}
+	}
+	sortDup := func(entries []flatstate.Entry) flatstate.Table {
+		sort.Slice(entries, func(i, j int) bool {
+			if c := bytes.Compare(entries[i].Key, entries[j].Key); c != 0 {
+				return c < 0
+			}
+			return bytes.Compare(entries[i].Value[:32], entries[j].Value[:32]) < 0
+		})
+		return flatstate.NewSliceTable(entries)
+	}
+
+	for _, test := range []struct {
+		format            flatstate.Format
+		accounts, storage flatstate.Table
+	}{
+		{flatstate.ErigonPlainState, sorted(erigonPlain), nil},
+		{flatstate.ErigonHashedState, sorted(erigonAccounts), sorted(erigonStorage)},
+		{flatstate.RethPlainState, sorted(rethPlainAccounts), sortDup(rethPlainStorage)},
+		{flatstate.RethHashedState, sorted(rethAccounts), sortDup(rethStorage)},
+	} {
+		store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+		lsys := codecs.NewLinkSystem(store)
+		root, err := flatstate.Verify(lsys, test.format, test.accounts, test.storage, expected)
+		if err != nil {
+			t.Fatalf("%s: unable to rebuild the state: %v", test.format, err)
+		}
+		if _, err := lsys.Load(ipld.LinkContext{}, root, dageth.Type.TrieNode); err != nil {
+			t.Errorf("%s: unable to load the rebuilt state root: %v", test.format, err)
+		}
+	}
+
+	lsys := codecs.NewLinkSystem(&storage.Memory{Bag: make(map[ipld.Link][]byte)})
+	if _, err := flatstate.Verify(lsys, flatstate.RethHashedState, sorted(rethAccounts), nil, expected); err == nil {
+		t.Error("expected an error verifying a state dump missing its storage")
+	}
+}
@@ -0,0 +1,79 @@
+package flatstate
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// rethAccountFlagsLength is the length of the bit flags that lead Reth's compact account encoding
+const rethAccountFlagsLength = 2
+
+// decodeRethAccount decodes an account of Reth's PlainAccountState or HashedAccounts tables, in Reth's compact
+// encoding: two little endian bytes of flags, holding the length of the nonce in their lowest four bits, the
+// length of the balance in the six bits above, and whether the account has a bytecode hash in the next bit,
+// followed by the big endian nonce and balance and the 32 byte bytecode hash if any
+func decodeRethAccount(key, value []byte) (*account, error) {
+	hash, err := addressHash(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) < rethAccountFlagsLength {
+		return nil, fmt.Errorf("reth account %x is shorter than its flags", key)
+	}
+	flags := binary.LittleEndian.Uint16(value)
+	nonceLength, balanceLength, hasCode := int(flags&0xf), int(flags>>4&0x3f), flags>>10&1 == 1
+	expected := rethAccountFlagsLength + nonceLength + balanceLength
+	if hasCode {
+		expected += common.HashLength
+	}
+	if nonceLength > 8 || balanceLength > 32 || len(value) != expected {
+		return nil, fmt.Errorf("reth account %x has an invalid length of %d bytes", key, len(value))
+	}
+	rest := value[rethAccountFlagsLength:]
+	acct := &account{
+		hash:     hash,
+		nonce:    new(uint256.Int).SetBytes(rest[:nonceLength]).Uint64(),
+		balance:  new(uint256.Int).SetBytes(rest[nonceLength : nonceLength+balanceLength]),
+		codeHash: types.EmptyCodeHash,
+	}
+	if hasCode {
+		acct.codeHash = common.BytesToHash(rest[nonceLength+balanceLength:])
+	}
+	return acct, nil
+}
+
+// decodeRethPlainSlot decodes a storage entry of Reth's PlainStorageState table, keyed by address
+func decodeRethPlainSlot(key, value []byte) (*slot, error) {
+	return decodeRethSlot(key, value, true)
+}
+
+// decodeRethHashedSlot decodes a storage entry of Reth's HashedStorages table, keyed by address hash
+func decodeRethHashedSlot(key, value []byte) (*slot, error) {
+	return decodeRethSlot(key, value, false)
+}
+
+// decodeRethSlot decodes a storage entry of Reth's storage tables, whose value is the 32 byte slot, or its hash,
+// followed by the big endian value of the slot stripped of leading zeros
+func decodeRethSlot(key, value []byte, plain bool) (*slot, error) {
+	addrHash, err := addressHash(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) < common.HashLength {
+		return nil, fmt.Errorf("reth storage entry %x of account %x is shorter than its key", value, key)
+	}
+	keyHash := common.BytesToHash(value[:common.HashLength])
+	if plain {
+		keyHash = crypto.Keccak256Hash(value[:common.HashLength])
+	}
+	v, err := slotValue(value[common.HashLength:])
+	if err != nil {
+		return nil, err
+	}
+	return &slot{addrHash: addrHash, keyHash: keyHash, value: v}, nil
+}
@@ -77,6 +77,11 @@ func accumulateChainTypes(ts *schema.TypeSystem) {
 			MixDigest Hash
 			Nonce Uint
 			BaseFee nullable BigInt
+			WithdrawalsRootCID nullable &TrieNode
+			BlobGasUsed nullable Uint
+			ExcessBlobGas nullable Uint
+			ParentBeaconRootCID nullable &BeaconBlock
+			RequestsCID nullable &Requests
 		}
 	*/
 	ts.Accumulate(schema.SpawnStruct("Header",
@@ -97,6 +102,11 @@ func accumulateChainTypes(ts *schema.TypeSystem) {
 			schema.SpawnStructField("MixDigest", "Hash", false, false),
 			schema.SpawnStructField("Nonce", "Uint", false, false),
 			schema.SpawnStructField("BaseFee", "BigInt", false, true),
+			schema.SpawnStructField("WithdrawalsRootCID", "Link", false, true),
+			schema.SpawnStructField("BlobGasUsed", "Uint", false, true),
+			schema.SpawnStructField("ExcessBlobGas", "Uint", false, true),
+			schema.SpawnStructField("ParentBeaconRootCID", "Link", false, true),
+			schema.SpawnStructField("RequestsCID", "Link", false, true),
 		},
 		schema.SpawnStructRepresentationMap(nil),
 	))
@@ -116,6 +126,20 @@ func accumulateChainTypes(ts *schema.TypeSystem) {
 
 		type AccessList [AccessElement]
 
+		type BlobHashes [Hash]
+
+		# An EIP-7702 authorization to set the code of the signing account to that of Address
+		type Authorization struct {
+			ChainID BigInt
+			Address Address
+			Nonce   Uint
+			YParity Uint
+			R       BigInt
+			S       BigInt
+		}
+
+		type AuthorizationList [Authorization]
+
 		type Transaction struct {
 			TxType       TxType
 			ChainID      nullable BigInt # null unless the transaction is an EIP-2930 or EIP-1559 transaction
@@ -128,11 +152,18 @@ func accumulateChainTypes(ts *schema.TypeSystem) {
 			Amount       BigInt
 			Data         Bytes
 			AccessList   nullable AccessList # null unless the transaction is an EIP-2930 or EIP-1559 transaction
+			MaxFeePerBlobGas    nullable BigInt # null unless the transaction is an EIP-4844 transaction
+			BlobVersionedHashes nullable BlobHashes # null unless the transaction is an EIP-4844 transaction
+			AuthorizationList   nullable AuthorizationList # null unless the transaction is an EIP-7702 transaction
 
 			# Signature values
 			V            BigInt
 			R            BigInt
 			S            BigInt
+
+			# The sender recovered from the signature, only present when decoded with tx.DecodeOptions{RecoverSender: true}
+			# It is derived rather than part of the consensus encoding, so it is ignored when encoding
+			From         optional Address
 		}
 
 		type Transactions [Transaction]
@@ -146,6 +177,19 @@ func accumulateChainTypes(ts *schema.TypeSystem) {
 		schema.SpawnStructRepresentationMap(nil),
 	))
 	ts.Accumulate(schema.SpawnList("AccessList", "AccessElement", false))
+	ts.Accumulate(schema.SpawnList("BlobHashes", "Hash", false))
+	ts.Accumulate(schema.SpawnStruct("Authorization",
+		[]schema.StructField{
+			schema.SpawnStructField("ChainID", "BigInt", false, false),
+			schema.SpawnStructField("Address", "Address", false, false),
+			schema.SpawnStructField("Nonce", "Uint", false, false),
+			schema.SpawnStructField("YParity", "Uint", false, false),
+			schema.SpawnStructField("R", "BigInt", false, false),
+			schema.SpawnStructField("S", "BigInt", false, false),
+		},
+		schema.SpawnStructRepresentationMap(nil),
+	))
+	ts.Accumulate(schema.SpawnList("AuthorizationList", "Authorization", false))
 	ts.Accumulate(schema.SpawnStruct("Transaction",
 		[]schema.StructField{
 			schema.SpawnStructField("TxType", "TxType", false, false),
@@ -159,14 +203,45 @@ func accumulateChainTypes(ts *schema.TypeSystem) {
 			schema.SpawnStructField("Amount", "BigInt", false, false),
 			schema.SpawnStructField("Data", "Bytes", false, false),
 			schema.SpawnStructField("AccessList", "AccessList", false, true),
+			schema.SpawnStructField("MaxFeePerBlobGas", "BigInt", false, true),
+			schema.SpawnStructField("BlobVersionedHashes", "BlobHashes", false, true),
+			schema.SpawnStructField("AuthorizationList", "AuthorizationList", false, true),
 			schema.SpawnStructField("V", "BigInt", false, false),
 			schema.SpawnStructField("R", "BigInt", false, false),
 			schema.SpawnStructField("S", "BigInt", false, false),
+			schema.SpawnStructField("From", "Address", true, false),
 		},
 		schema.SpawnStructRepresentationMap(nil),
 	))
 	ts.Accumulate(schema.SpawnList("Transactions", "Transaction", false))
 
+	/*
+		type Blobs [Bytes]
+
+		type KZGCommitments [Bytes]
+
+		type KZGProofs [Bytes]
+
+		# BlobSidecar carries the blobs of an EIP-4844 transaction, which are not part of the transaction itself
+		# The versioned hashes in the transaction's BlobVersionedHashes are derived from the Commitments
+		type BlobSidecar struct {
+			Blobs       Blobs
+			Commitments KZGCommitments
+			Proofs      KZGProofs
+		}
+	*/
+	ts.Accumulate(schema.SpawnList("Blobs", "Bytes", false))
+	ts.Accumulate(schema.SpawnList("KZGCommitments", "Bytes", false))
+	ts.Accumulate(schema.SpawnList("KZGProofs", "Bytes", false))
+	ts.Accumulate(schema.SpawnStruct("BlobSidecar",
+		[]schema.StructField{
+			schema.SpawnStructField("Blobs", "Blobs", false, false),
+			schema.SpawnStructField("Commitments", "KZGCommitments", false, false),
+			schema.SpawnStructField("Proofs", "KZGProofs", false, false),
+		},
+		schema.SpawnStructRepresentationMap(nil),
+	))
+
 	/*
 		type Topics [Hash]
 
@@ -190,11 +265,14 @@ func accumulateChainTypes(ts *schema.TypeSystem) {
 	ts.Accumulate(schema.SpawnList("Logs", "Log", false))
 
 	/*
+		# PostState and Status form a union: receipts from before the Byzantium fork (block 4,370,000)
+		# carry the intermediate state root in PostState, later receipts carry the Status instead
+		# Exactly one of the two is non-null
 		type Receipt struct {
 			TxType			  TxType
+			PostState		  nullable Hash
 			// We could make Status an enum
-			Status	          Uint   // nullable
-			PostState		  Hash   // nullable
+			Status	          nullable Uint
 			CumulativeGasUsed Uint
 			Bloom             Bloom
 			Logs 			  Logs
@@ -206,7 +284,7 @@ func accumulateChainTypes(ts *schema.TypeSystem) {
 	ts.Accumulate(schema.SpawnStruct("Receipt",
 		[]schema.StructField{
 			schema.SpawnStructField("TxType", "TxType", false, false),
-			schema.SpawnStructField("PostState", "Bytes", false, true),
+			schema.SpawnStructField("PostState", "Hash", false, true),
 			schema.SpawnStructField("Status", "Uint", false, true),
 			schema.SpawnStructField("CumulativeGasUsed", "Uint", false, false),
 			schema.SpawnStructField("Bloom", "Bloom", false, false),
@@ -216,6 +294,109 @@ func accumulateChainTypes(ts *schema.TypeSystem) {
 		schema.SpawnStructRepresentationMap(nil),
 	))
 	ts.Accumulate(schema.SpawnList("Receipts", "Receipt", false))
+
+	/*
+		# Withdrawal is an EIP-4895 validator withdrawal pushed from the beacon chain, Amount is denominated in Gwei
+		type Withdrawal struct {
+			Index          Uint
+			ValidatorIndex Uint
+			Address        Address
+			Amount         Uint
+		}
+
+		type Withdrawals [Withdrawal]
+	*/
+	ts.Accumulate(schema.SpawnStruct("Withdrawal",
+		[]schema.StructField{
+			schema.SpawnStructField("Index", "Uint", false, false),
+			schema.SpawnStructField("ValidatorIndex", "Uint", false, false),
+			schema.SpawnStructField("Address", "Address", false, false),
+			schema.SpawnStructField("Amount", "Uint", false, false),
+		},
+		schema.SpawnStructRepresentationMap(nil),
+	))
+	ts.Accumulate(schema.SpawnList("Withdrawals", "Withdrawal", false))
+
+	/*
+		# DepositRequest is an EIP-6110 validator deposit read from the deposit contract logs, Amount is denominated in Gwei
+		type DepositRequest struct {
+			Pubkey                Bytes
+			WithdrawalCredentials Hash
+			Amount                Uint
+			Signature             Bytes
+			Index                 Uint
+		}
+
+		type DepositRequests [DepositRequest]
+
+		# WithdrawalRequest is an EIP-7002 withdrawal triggered from the execution layer, Amount is denominated in Gwei
+		type WithdrawalRequest struct {
+			SourceAddress   Address
+			ValidatorPubkey Bytes
+			Amount          Uint
+		}
+
+		type WithdrawalRequests [WithdrawalRequest]
+
+		# ConsolidationRequest is an EIP-7251 consolidation of a validator into another one
+		type ConsolidationRequest struct {
+			SourceAddress Address
+			SourcePubkey  Bytes
+			TargetPubkey  Bytes
+		}
+
+		type ConsolidationRequests [ConsolidationRequest]
+
+		# Request is an EIP-7685 execution layer request, only the requests of its RequestType are non-null
+		type Request struct {
+			RequestType    Uint
+			Deposits       nullable DepositRequests
+			Withdrawals    nullable WithdrawalRequests
+			Consolidations nullable ConsolidationRequests
+		}
+
+		# Requests are the requests of a block, whose hashes hash to the header's requests hash
+		type Requests [&Request]
+	*/
+	ts.Accumulate(schema.SpawnStruct("DepositRequest",
+		[]schema.StructField{
+			schema.SpawnStructField("Pubkey", "Bytes", false, false),
+			schema.SpawnStructField("WithdrawalCredentials", "Hash", false, false),
+			schema.SpawnStructField("Amount", "Uint", false, false),
+			schema.SpawnStructField("Signature", "Bytes", false, false),
+			schema.SpawnStructField("Index", "Uint", false, false),
+		},
+		schema.SpawnStructRepresentationMap(nil),
+	))
+	ts.Accumulate(schema.SpawnList("DepositRequests", "DepositRequest", false))
+	ts.Accumulate(schema.SpawnStruct("WithdrawalRequest",
+		[]schema.StructField{
+			schema.SpawnStructField("SourceAddress", "Address", false, false),
+			schema.SpawnStructField("ValidatorPubkey", "Bytes", false, false),
+			schema.SpawnStructField("Amount", "Uint", false, false),
+		},
+		schema.SpawnStructRepresentationMap(nil),
+	))
+	ts.Accumulate(schema.SpawnList("WithdrawalRequests", "WithdrawalRequest", false))
+	ts.Accumulate(schema.SpawnStruct("ConsolidationRequest",
+		[]schema.StructField{
+			schema.SpawnStructField("SourceAddress", "Address", false, false),
+			schema.SpawnStructField("SourcePubkey", "Bytes", false, false),
+			schema.SpawnStructField("TargetPubkey", "Bytes", false, false),
+		},
+		schema.SpawnStructRepresentationMap(nil),
+	))
+	ts.Accumulate(schema.SpawnList("ConsolidationRequests", "ConsolidationRequest", false))
+	ts.Accumulate(schema.SpawnStruct("Request",
+		[]schema.StructField{
+			schema.SpawnStructField("RequestType", "Uint", false, false),
+			schema.SpawnStructField("Deposits", "DepositRequests", false, true),
+			schema.SpawnStructField("Withdrawals", "WithdrawalRequests", false, true),
+			schema.SpawnStructField("Consolidations", "ConsolidationRequests", false, true),
+		},
+		schema.SpawnStructRepresentationMap(nil),
+	))
+	ts.Accumulate(schema.SpawnList("Requests", "Link", false))
 }
 
 func accumulateStateDataStructures(ts *schema.TypeSystem) {
@@ -257,6 +438,7 @@ func accumulateStateDataStructures(ts *schema.TypeSystem) {
 			| Account "state"
 			| Bytes "storage"
 			| Log "log"
+			| Withdrawal "withdrawal"
 		} representation keyed
 
 		# Child union type used to handle the case where the node is stored directly in the parent node because it is smaller
@@ -283,13 +465,15 @@ func accumulateStateDataStructures(ts *schema.TypeSystem) {
 			"Account",
 			"Bytes",
 			"Log",
+			"Withdrawal",
 		},
 		schema.SpawnUnionRepresentationKeyed(map[string]schema.TypeName{
-			"tx":      "Transaction",
-			"rct":     "Receipt",
-			"state":   "Account",
-			"storage": "Bytes",
-			"log":     "Log",
+			"tx":         "Transaction",
+			"rct":        "Receipt",
+			"state":      "Account",
+			"storage":    "Bytes",
+			"log":        "Log",
+			"withdrawal": "Withdrawal",
 		}),
 	))
 	ts.Accumulate(schema.SpawnUnion("Child",
@@ -327,7 +511,7 @@ func accumulateStateDataStructures(ts *schema.TypeSystem) {
 	ts.Accumulate(schema.SpawnStruct("TrieExtensionNode",
 		[]schema.StructField{
 			schema.SpawnStructField("PartialPath", "Bytes", false, false),
-			schema.SpawnStructField("Child", "Link", false, false),
+			schema.SpawnStructField("Child", "Child", false, true),
 		},
 		schema.SpawnStructRepresentationMap(nil),
 	))
@@ -441,20 +625,46 @@ func accumulateConvenienceTypes(ts *schema.TypeSystem) {
 		   # CID link to the header at this block
 		   # This CID is composed of the KECCAK_256 multihash of the RLP encoded header and the EthHeader codec (0x90)
 		   # Note that the header contains references to the uncles and tx, receipt, and state tries at this height
-		   HeaderCID       &Header
+		   Header       &Header
 		   # CID link to the list of transactions at this block
 		   # This CID is composed of the KECCAK_256 multihash of the RLP encoded list of transactions and the EthTxList codec (0x9c)
-		   TransactionsCID &Transactions
+		   Transactions &Transactions
 		   # CID link to the list of receipts at this block
 		   # This CID is composed of the KECCAK_256 multihash of the RLP encoded list of receipts and the EthTxReceiptList codec (0x9d)
-		   ReceiptsCID     &Receipts
+		   Receipts     &Receipts
 		}
 	*/
 	ts.Accumulate(schema.SpawnStruct("Block",
 		[]schema.StructField{
-			schema.SpawnStructField("HeaderCID", "Link", false, false),
-			schema.SpawnStructField("TransactionsCID", "Link", false, false),
-			schema.SpawnStructField("ReceiptsCID", "Link", false, false),
+			schema.SpawnStructField("Header", "Link", false, false),
+			schema.SpawnStructField("Transactions", "Link", false, false),
+			schema.SpawnStructField("Receipts", "Link", false, false),
+		},
+		schema.SpawnStructRepresentationMap(nil),
+	))
+
+	/*
+		# CliqueExtra is the Extra field of a clique (proof-of-authority) header split into its parts
+		type CliqueExtra struct {
+		   # Vanity is the 32 bytes of signer vanity data leading the field
+		   Vanity Bytes
+		   # Signers is the list of authorized signers, only present in the headers of checkpoint blocks
+		   Signers nullable Addresses
+		   # Seal is the 65 byte signature of the header by its signer, closing the field
+		   Seal Bytes
+		   # Signer is the address recovered from the seal
+		   Signer Address
+		}
+
+		type Addresses [Address]
+	*/
+	ts.Accumulate(schema.SpawnList("Addresses", "Address", false))
+	ts.Accumulate(schema.SpawnStruct("CliqueExtra",
+		[]schema.StructField{
+			schema.SpawnStructField("Vanity", "Bytes", false, false),
+			schema.SpawnStructField("Signers", "Addresses", false, true),
+			schema.SpawnStructField("Seal", "Bytes", false, false),
+			schema.SpawnStructField("Signer", "Address", false, false),
 		},
 		schema.SpawnStructRepresentationMap(nil),
 	))
@@ -0,0 +1,8 @@
+/*
+Package header is the eth-block codec of execution layer headers.
+
+Headers are decoded with the fields of the forks present in their encoding, or, with a profile.Profile in the
+DecodeOptions, with exactly the fields of the forks active at their block. LinkHashes builds the links of a header
+with another multihash type, e.g. shared.LinkHashes{multihash.KECCAK_256: multihash.SHA2_256}.
+*/
+package header
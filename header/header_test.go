@@ -4,17 +4,24 @@ import (
 	"bytes"
 	"encoding/binary"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
 	"github.com/multiformats/go-multihash"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
 	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/profile"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/testutil"
 )
 
 var (
@@ -40,6 +47,12 @@ type Header struct {
 	Extra Bytes
 	MixDigest Hash
 	Nonce Uint
+	BaseFee nullable BigInt # null unless the header is from the London fork or later
+	WithdrawalsRootCID nullable &WithdrawalTrieNode # null unless the header is from the Shanghai fork or later
+	BlobGasUsed nullable Uint # null unless the header is from the Cancun fork or later
+	ExcessBlobGas nullable Uint # null unless the header is from the Cancun fork or later
+	ParentBeaconRootCID nullable &BeaconBlock # null unless the header is from the Cancun fork or later
+	RequestsCID nullable &Requests # null unless the header is from the Prague fork or later
 }
 */
 
@@ -56,6 +69,8 @@ func TestHeaderCodec(t *testing.T) {
 	testHeaderDecode(t)
 	testHeaderNodeContents(t)
 	testHeaderEncode(t)
+	testHeaderDecodeVerified(t)
+	testHeaderOptions(t)
 }
 
 func testHeaderDecode(t *testing.T) {
@@ -67,6 +82,90 @@ func testHeaderDecode(t *testing.T) {
 	headerNode = headerBuilder.Build()
 }
 
+func testHeaderDecodeVerified(t *testing.T) {
+	expected := testutil.KeccakCid(t, header.MultiCodecType, crypto.Keccak256(headerRLP))
+	headerBuilder := dageth.Type.Header.NewBuilder()
+	if err := header.DecodeVerified(headerBuilder, bytes.NewReader(headerRLP), expected); err != nil {
+		t.Fatalf("unable to decode header against its CID: %v", err)
+	}
+	if !ipld.DeepEqual(headerBuilder.Build(), headerNode) {
+		t.Error("header decoded against its CID does not match the decoded header")
+	}
+	tampered := append([]byte{}, headerRLP...)
+	tampered[len(tampered)-1] ^= 0x01
+	if err := header.DecodeVerified(dageth.Type.Header.NewBuilder(), bytes.NewReader(tampered), expected); err == nil {
+		t.Error("expected an error decoding a header that does not hash to the expected CID")
+	}
+	wrongCodec := testutil.KeccakCid(t, header.MultiCodecType+1, crypto.Keccak256(headerRLP))
+	if err := header.DecodeVerified(dageth.Type.Header.NewBuilder(), bytes.NewReader(headerRLP), wrongCodec); err == nil {
+		t.Error("expected an error decoding a header against a CID with another multicodec type")
+	}
+}
+
+func testHeaderOptions(t *testing.T) {
+	linkCodecs := shared.LinkCodecs{cid.EthBlock: cid.DagCBOR}
+	headerBuilder := dageth.Type.Header.NewBuilder()
+	if err := header.DecodeBytesWithOptions(headerBuilder, headerRLP, header.DecodeOptions{LinkCodecs: linkCodecs}); err != nil {
+		t.Fatalf("unable to decode header with options: %v", err)
+	}
+	node := headerBuilder.Build()
+	parentNode, err := node.LookupByString("ParentCID")
+	if err != nil {
+		t.Fatalf("header is missing ParentCID: %v", err)
+	}
+	parentLink, err := parentNode.AsLink()
+	if err != nil {
+		t.Fatalf("header ParentCID is not a link: %v", err)
+	}
+	if codec := parentLink.(cidlink.Link).Prefix().Codec; codec != cid.DagCBOR {
+		t.Errorf("header ParentCID multicodec type (%#x) does not match the override (%#x)", codec, cid.DagCBOR)
+	}
+	stateRootNode, err := node.LookupByString("StateRootCID")
+	if err != nil {
+		t.Fatalf("header is missing StateRootCID: %v", err)
+	}
+	stateRootLink, err := stateRootNode.AsLink()
+	if err != nil {
+		t.Fatalf("header StateRootCID is not a link: %v", err)
+	}
+	if codec := stateRootLink.(cidlink.Link).Prefix().Codec; codec != cid.EthStateTrie {
+		t.Errorf("header StateRootCID multicodec type (%#x) should not be overridden", codec)
+	}
+
+	if err := header.EncodeWithOptions(node, new(bytes.Buffer), header.EncodeOptions{Strict: true}); err == nil {
+		t.Error("expected an error strictly encoding a header with an unexpected ParentCID multicodec type")
+	}
+	enc, err := header.AppendEncodeWithOptions(nil, node, header.EncodeOptions{Strict: true, LinkCodecs: linkCodecs})
+	if err != nil {
+		t.Fatalf("unable to strictly encode header with the decoding link codecs: %v", err)
+	}
+	if !bytes.Equal(enc, headerRLP) {
+		t.Errorf("header encoding (%x) does not match the expected RLP encoding (%x)", enc, headerRLP)
+	}
+
+	linkHashes := shared.LinkHashes{multihash.KECCAK_256: multihash.SHA2_256}
+	headerBuilder = dageth.Type.Header.NewBuilder()
+	if err := header.DecodeBytesWithOptions(headerBuilder, headerRLP, header.DecodeOptions{LinkHashes: linkHashes}); err != nil {
+		t.Fatalf("unable to decode header with link hashes: %v", err)
+	}
+	node = headerBuilder.Build()
+	parentNode, _ = node.LookupByString("ParentCID")
+	parentLink, _ = parentNode.AsLink()
+	if prefix := parentLink.(cidlink.Link).Prefix(); prefix.MhType != multihash.SHA2_256 || prefix.Codec != cid.EthBlock {
+		t.Errorf("header ParentCID multihash type (%#x) does not match the override (%#x)", prefix.MhType, multihash.SHA2_256)
+	}
+	if err := header.EncodeWithOptions(node, new(bytes.Buffer), header.EncodeOptions{Strict: true}); err == nil {
+		t.Error("expected an error strictly encoding a header with an unexpected ParentCID multihash type")
+	}
+	enc, err = header.AppendEncodeWithOptions(nil, node, header.EncodeOptions{Strict: true, LinkHashes: linkHashes})
+	if err != nil {
+		t.Fatalf("unable to strictly encode header with the decoding link hashes: %v", err)
+	}
+	if !bytes.Equal(enc, headerRLP) {
+		t.Errorf("header encoding (%x) does not match the expected RLP encoding (%x)", enc, headerRLP)
+	}
+}
+
 func testHeaderNodeContents(t *testing.T) {
 	parentNode, err := headerNode.LookupByString("ParentCID")
 	if err != nil {
@@ -296,6 +395,14 @@ func testHeaderNodeContents(t *testing.T) {
 	if nonce != gethHeader.Nonce.Uint64() {
 		t.Errorf("header nonce (%d) does not match expected nonce (%d)", nonce, gethHeader.Nonce.Uint64())
 	}
+
+	baseFeeNode, err := headerNode.LookupByString("BaseFee")
+	if err != nil {
+		t.Fatalf("header is missing BaseFee: %v", err)
+	}
+	if !baseFeeNode.IsNull() {
+		t.Errorf("pre-London header BaseFee should be null")
+	}
 }
 
 func testHeaderEncode(t *testing.T) {
@@ -317,6 +424,205 @@ func testHeaderEncode(t *testing.T) {
 	}
 }
 
+func TestLondonHeader(t *testing.T) {
+	block, _, err := loadBlockFromRLPFile("./block1_rlp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	londonHeader := block.Header()
+	londonHeader.BaseFee = big.NewInt(1000000000)
+	londonHeaderNode := testHeaderRoundTrip(t, "London", londonHeader)
+
+	baseFeeNode, err := londonHeaderNode.LookupByString("BaseFee")
+	if err != nil {
+		t.Fatalf("header is missing BaseFee: %v", err)
+	}
+	baseFeeBytes, err := baseFeeNode.AsBytes()
+	if err != nil {
+		t.Fatalf("header BaseFee should be of type Bytes: %v", err)
+	}
+	if !bytes.Equal(baseFeeBytes, londonHeader.BaseFee.Bytes()) {
+		t.Errorf("header base fee (%x) does not match expected base fee (%x)", baseFeeBytes, londonHeader.BaseFee.Bytes())
+	}
+	withdrawalsNode, err := londonHeaderNode.LookupByString("WithdrawalsRootCID")
+	if err != nil {
+		t.Fatalf("header is missing WithdrawalsRootCID: %v", err)
+	}
+	if !withdrawalsNode.IsNull() {
+		t.Errorf("pre-Shanghai header WithdrawalsRootCID should be null")
+	}
+}
+
+func TestShanghaiHeader(t *testing.T) {
+	block, _, err := loadBlockFromRLPFile("./block1_rlp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shanghaiHeader := block.Header()
+	shanghaiHeader.BaseFee = big.NewInt(1000000000)
+	withdrawalsHash := crypto.Keccak256Hash([]byte("withdrawals"))
+	shanghaiHeader.WithdrawalsHash = &withdrawalsHash
+	shanghaiHeaderNode := testHeaderRoundTrip(t, "Shanghai", shanghaiHeader)
+
+	withdrawalsNode, err := shanghaiHeaderNode.LookupByString("WithdrawalsRootCID")
+	if err != nil {
+		t.Fatalf("header is missing WithdrawalsRootCID: %v", err)
+	}
+	withdrawalsLink, err := withdrawalsNode.AsLink()
+	if err != nil {
+		t.Fatalf("header WithdrawalsRootCID is not a link: %v", err)
+	}
+	withdrawalsCIDLink, ok := withdrawalsLink.(cidlink.Link)
+	if !ok {
+		t.Fatalf("header WithdrawalsRootCID is not a CID")
+	}
+	if codec := withdrawalsCIDLink.Cid.Prefix().Codec; codec != 0x9f {
+		t.Errorf("header WithdrawalsRootCID codec (%#x) should be the withdrawal trie codec (0x9f)", codec)
+	}
+	decodedWithdrawalsMh, err := multihash.Decode(withdrawalsCIDLink.Hash())
+	if err != nil {
+		t.Fatalf("header WithdrawalsRootCID could not be decoded into multihash: %v", err)
+	}
+	if !bytes.Equal(decodedWithdrawalsMh.Digest, withdrawalsHash.Bytes()) {
+		t.Errorf("header withdrawals root hash (%x) does not match expected hash (%x)", decodedWithdrawalsMh.Digest, withdrawalsHash.Bytes())
+	}
+}
+
+func TestCancunHeader(t *testing.T) {
+	block, _, err := loadBlockFromRLPFile("./block1_rlp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancunHeader := block.Header()
+	cancunHeader.BaseFee = big.NewInt(1000000000)
+	withdrawalsHash := crypto.Keccak256Hash([]byte("withdrawals"))
+	cancunHeader.WithdrawalsHash = &withdrawalsHash
+	blobGasUsed, excessBlobGas := uint64(393216), uint64(0)
+	cancunHeader.BlobGasUsed = &blobGasUsed
+	cancunHeader.ExcessBlobGas = &excessBlobGas
+	parentBeaconRoot := crypto.Keccak256Hash([]byte("beacon"))
+	cancunHeader.ParentBeaconRoot = &parentBeaconRoot
+	cancunHeaderNode := testHeaderRoundTrip(t, "Cancun", cancunHeader)
+
+	for key, expected := range map[string]uint64{
+		"BlobGasUsed":   blobGasUsed,
+		"ExcessBlobGas": excessBlobGas,
+	} {
+		n, err := cancunHeaderNode.LookupByString(key)
+		if err != nil {
+			t.Fatalf("header is missing %s: %v", key, err)
+		}
+		nBytes, err := n.AsBytes()
+		if err != nil {
+			t.Fatalf("header %s should be of type Bytes: %v", key, err)
+		}
+		if val := binary.BigEndian.Uint64(nBytes); val != expected {
+			t.Errorf("header %s (%d) does not match expected value (%d)", key, val, expected)
+		}
+	}
+
+	beaconNode, err := cancunHeaderNode.LookupByString("ParentBeaconRootCID")
+	if err != nil {
+		t.Fatalf("header is missing ParentBeaconRootCID: %v", err)
+	}
+	beaconLink, err := beaconNode.AsLink()
+	if err != nil {
+		t.Fatalf("header ParentBeaconRootCID is not a link: %v", err)
+	}
+	beaconCIDLink, ok := beaconLink.(cidlink.Link)
+	if !ok {
+		t.Fatalf("header ParentBeaconRootCID is not a CID")
+	}
+	decodedBeaconMh, err := multihash.Decode(beaconCIDLink.Hash())
+	if err != nil {
+		t.Fatalf("header ParentBeaconRootCID could not be decoded into multihash: %v", err)
+	}
+	if !bytes.Equal(decodedBeaconMh.Digest, parentBeaconRoot.Bytes()) {
+		t.Errorf("header parent beacon root (%x) does not match expected root (%x)", decodedBeaconMh.Digest, parentBeaconRoot.Bytes())
+	}
+}
+
+func TestPragueHeader(t *testing.T) {
+	block, _, err := loadBlockFromRLPFile("./block1_rlp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pragueHeader := block.Header()
+	pragueHeader.BaseFee = big.NewInt(1000000000)
+	withdrawalsHash := crypto.Keccak256Hash([]byte("withdrawals"))
+	pragueHeader.WithdrawalsHash = &withdrawalsHash
+	blobGasUsed, excessBlobGas := uint64(393216), uint64(0)
+	pragueHeader.BlobGasUsed = &blobGasUsed
+	pragueHeader.ExcessBlobGas = &excessBlobGas
+	parentBeaconRoot := crypto.Keccak256Hash([]byte("beacon"))
+	pragueHeader.ParentBeaconRoot = &parentBeaconRoot
+	requestsHash := types.EmptyRequestsHash
+	pragueHeader.RequestsHash = &requestsHash
+	pragueHeaderNode := testHeaderRoundTrip(t, "Prague", pragueHeader)
+
+	requestsNode, err := pragueHeaderNode.LookupByString("RequestsCID")
+	if err != nil {
+		t.Fatalf("header is missing RequestsCID: %v", err)
+	}
+	requestsLink, err := requestsNode.AsLink()
+	if err != nil {
+		t.Fatalf("header RequestsCID is not a link: %v", err)
+	}
+	requestsCIDLink, ok := requestsLink.(cidlink.Link)
+	if !ok {
+		t.Fatalf("header RequestsCID is not a CID")
+	}
+	if codec := requestsCIDLink.Cid.Prefix().Codec; codec != 0xf4 {
+		t.Errorf("header RequestsCID codec (%#x) should be the request list codec (0xf4)", codec)
+	}
+	decodedRequestsMh, err := multihash.Decode(requestsCIDLink.Hash())
+	if err != nil {
+		t.Fatalf("header RequestsCID could not be decoded into multihash: %v", err)
+	}
+	if decodedRequestsMh.Code != multihash.SHA2_256 {
+		t.Errorf("header RequestsCID multihash type (%#x) should be sha2-256", decodedRequestsMh.Code)
+	}
+	if !bytes.Equal(decodedRequestsMh.Digest, requestsHash.Bytes()) {
+		t.Errorf("header requests hash (%x) does not match expected hash (%x)", decodedRequestsMh.Digest, requestsHash.Bytes())
+	}
+
+	cancunHeader := types.CopyHeader(pragueHeader)
+	cancunHeader.RequestsHash = nil
+	cancunRequestsNode, err := testHeaderRoundTrip(t, "Cancun", cancunHeader).LookupByString("RequestsCID")
+	if err != nil {
+		t.Fatalf("header is missing RequestsCID: %v", err)
+	}
+	if !cancunRequestsNode.IsNull() {
+		t.Errorf("pre-Prague header RequestsCID should be null")
+	}
+}
+
+// testHeaderRoundTrip decodes the RLP of the provided header, checks that the node encodes back to the same
+// RLP and keccak hash, and returns the node
+func testHeaderRoundTrip(t *testing.T, fork string, gethHeader *types.Header) ipld.Node {
+	forkHeaderRLP, err := rlp.EncodeToBytes(gethHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerBuilder := dageth.Type.Header.NewBuilder()
+	if err := header.DecodeBytes(headerBuilder, forkHeaderRLP); err != nil {
+		t.Fatalf("unable to decode %s header into an IPLD node: %v", fork, err)
+	}
+	forkHeaderNode := headerBuilder.Build()
+
+	encodedHeaderBytes, err := header.AppendEncode(nil, forkHeaderNode)
+	if err != nil {
+		t.Fatalf("unable to encode %s header: %v", fork, err)
+	}
+	if !bytes.Equal(encodedHeaderBytes, forkHeaderRLP) {
+		t.Errorf("%s header encoding (%x) does not match the expected RLP encoding (%x)", fork, encodedHeaderBytes, forkHeaderRLP)
+	}
+	if hash := crypto.Keccak256Hash(encodedHeaderBytes); hash != gethHeader.Hash() {
+		t.Errorf("%s header encoding hash (%s) does not match the expected header hash (%s)", fork, hash.Hex(), gethHeader.Hash().Hex())
+	}
+	return forkHeaderNode
+}
+
 func loadBlockFromRLPFile(filename string) (*types.Block, []byte, error) {
 	f, err := os.Open(filename)
 	if err != nil {
@@ -330,3 +636,119 @@ func loadBlockFromRLPFile(filename string) (*types.Block, []byte, error) {
 	block := new(types.Block)
 	return block, blockRLP, rlp.DecodeBytes(blockRLP, block)
 }
+
+func TestHeaderProfile(t *testing.T) {
+	block, _, err := loadBlockFromRLPFile("./block1_rlp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	frontierRLP, err := rlp.EncodeToBytes(block.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainnet := header.DecodeOptions{Profile: profile.Mainnet}
+	headerBuilder := dageth.Type.Header.NewBuilder()
+	if err := mainnet.DecodeBytes(headerBuilder, frontierRLP); err != nil {
+		t.Fatalf("unable to decode a frontier header with the mainnet profile: %v", err)
+	}
+	frontierNode := headerBuilder.Build()
+
+	// a custom chain that activates London from genesis
+	londonHeader := block.Header()
+	londonHeader.BaseFee = big.NewInt(1000000000)
+	londonRLP, err := rlp.EncodeToBytes(londonHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mainnet.DecodeBytes(dageth.Type.Header.NewBuilder(), londonRLP); err == nil {
+		t.Error("expected an error decoding a header carrying a BaseFee before London with the mainnet profile")
+	}
+	custom := &profile.Profile{Name: "custom", LondonBlock: big.NewInt(0)}
+	headerBuilder = dageth.Type.Header.NewBuilder()
+	if err := (header.DecodeOptions{Profile: custom}).DecodeBytes(headerBuilder, londonRLP); err != nil {
+		t.Fatalf("unable to decode a London header with a profile activating London at genesis: %v", err)
+	}
+	if _, err := (header.EncodeOptions{Profile: custom}).AppendEncode(nil, frontierNode); err == nil {
+		t.Error("expected an error encoding a header without a BaseFee with a profile activating London at genesis")
+	}
+	if _, err := (header.EncodeOptions{Profile: custom}).AppendEncode(nil, headerBuilder.Build()); err != nil {
+		t.Errorf("unable to encode a London header with a profile activating London at genesis: %v", err)
+	}
+}
+
+func TestNumericForms(t *testing.T) {
+	// Uint fields shorter than 8 bytes and BigInt fields with leading zeros encode like their canonical forms
+	node := withBytes(t, headerNode, "GasLimit", []byte{0x01, 0x02})
+	node = withBytes(t, node, "Number", append([]byte{0, 0}, gethHeader.Number.Bytes()...))
+	expected := *gethHeader
+	expected.GasLimit = 0x0102
+	expectedRLP, _ := rlp.EncodeToBytes(&expected)
+	enc, err := header.AppendEncode(nil, node)
+	if err != nil {
+		t.Fatalf("unable to encode header with non-canonical numeric fields: %v", err)
+	}
+	if !bytes.Equal(enc, expectedRLP) {
+		t.Errorf("header encoding (%x) does not match the expected RLP encoding (%x)", enc, expectedRLP)
+	}
+	// but are rejected when encoding strictly, as they would give another node the same CID
+	for field, b := range map[string][]byte{
+		"GasLimit": {0x01, 0x02},
+		"Time":     make([]byte, 9),
+		"Number":   append([]byte{0}, gethHeader.Number.Bytes()...),
+	} {
+		if _, err := header.AppendEncodeWithOptions(nil, withBytes(t, headerNode, field, b), header.EncodeOptions{Strict: true}); err == nil {
+			t.Errorf("expected an error strictly encoding a header with a non-canonical %s", field)
+		}
+	}
+	if _, err := header.AppendEncodeWithOptions(nil, headerNode, header.EncodeOptions{Strict: true}); err != nil {
+		t.Errorf("unable to strictly encode a decoded header: %v", err)
+	}
+	// values overflowing 64 bits are rejected either way
+	if _, err := header.AppendEncode(nil, withBytes(t, headerNode, "GasUsed", []byte{1, 0, 0, 0, 0, 0, 0, 0, 0})); err == nil {
+		t.Error("expected an error encoding a header with a GasUsed overflowing 64 bits")
+	}
+}
+
+// withBytes copies the map node with the named field set to the bytes
+func withBytes(t *testing.T, node ipld.Node, field string, b []byte) ipld.Node {
+	nb := basicnode.Prototype.Map.NewBuilder()
+	ma, err := nb.BeginMap(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for it := node.MapIterator(); !it.Done(); {
+		k, v, err := it.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.IsAbsent() {
+			continue
+		}
+		key, _ := k.AsString()
+		if key == field {
+			v = basicnode.NewBytes(b)
+		}
+		if err := ma.AssembleKey().AssignString(key); err != nil {
+			t.Fatal(err)
+		}
+		if err := ma.AssembleValue().AssignNode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ma.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	return nb.Build()
+}
+
+func TestFixedLengthFields(t *testing.T) {
+	// hashes and addresses are not padded or cropped into shape, as that would give other nodes the same CID
+	for field, b := range map[string][]byte{
+		"Coinbase":  gethHeader.Coinbase.Bytes()[1:],
+		"MixDigest": append(gethHeader.MixDigest.Bytes(), 0),
+	} {
+		if _, err := header.AppendEncode(nil, withBytes(t, headerNode, field, b)); err == nil {
+			t.Errorf("expected an error encoding a header with a %d byte %s", len(b), field)
+		}
+	}
+}
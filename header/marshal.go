@@ -1,44 +1,84 @@
 package header
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
-	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	"github.com/multiformats/go-multihash"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/profile"
 	"github.com/vulcanize/go-codec-dageth/shared"
 )
 
+// EncodeOptions can be used to customize the behavior of header encoding.
+// The zero value is the default behavior used by the registered codec.
+type EncodeOptions struct {
+	// Strict causes the encoder to reject links that do not carry the multicodec and multihash types
+	// the header decoder builds them with, and numeric fields not in their canonical form, see shared.CheckNumerics
+	Strict bool
+	// LinkCodecs overrides the multicodec types Strict expects links to carry,
+	// it should match the DecodeOptions the node was decoded with
+	LinkCodecs shared.LinkCodecs
+	// LinkHashes overrides the multihash types Strict expects links to carry,
+	// it should match the DecodeOptions the node was decoded with
+	LinkHashes shared.LinkHashes
+	// Profile causes the encoder to reject headers that do not carry exactly the fields of the forks the profile
+	// activates at their block
+	Profile *profile.Profile
+}
+
 // Encode provides an IPLD codec encode interface for eth header IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code 0x90 when this package is invoked via init.
 func Encode(node ipld.Node, w io.Writer) error {
-	// 1KiB can be allocated on the stack, and covers most small nodes
-	// without having to grow the buffer and cause allocations.
-	enc := make([]byte, 0, 1024)
-
-	enc, err := AppendEncode(enc, node)
-	if err != nil {
-		return err
-	}
-	_, err = w.Write(enc)
-	return err
+	return EncodeOptions{}.Encode(node, w)
 }
 
 // AppendEncode is like Encode, but it uses a destination buffer directly.
 // This means less copying of bytes, and if the destination has enough capacity,
 // fewer allocations.
 func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return EncodeOptions{}.AppendEncode(enc, inNode)
+}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// EncodeHeader packs the node into the provided go-ethereum Header
+func EncodeHeader(header *types.Header, inNode ipld.Node) error {
+	return EncodeOptions{}.EncodeHeader(header, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	header := new(types.Header)
+	if err := cfg.EncodeHeader(header, node); err != nil {
+		return err
+	}
+	if err := rlp.Encode(w, header); err != nil {
+		return fmt.Errorf("invalid DAG-ETH Header form (unable to RLP encode header: %v)", err)
+	}
+	return nil
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	header := new(types.Header)
-	if err := EncodeHeader(header, inNode); err != nil {
+	if err := cfg.EncodeHeader(header, inNode); err != nil {
 		return enc, err
 	}
 	wbs := shared.NewWriteableByteSlice(&enc)
@@ -48,42 +88,65 @@ func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	return enc, nil
 }
 
-// EncodeHeader packs the node into the provided go-ethereum Header
-func EncodeHeader(header *types.Header, inNode ipld.Node) error {
+// EncodeHeader is like the package level EncodeHeader, but uses the provided options
+func (cfg EncodeOptions) EncodeHeader(header *types.Header, inNode ipld.Node) error {
 	// Wrap in a typed node for some basic schema form checking
 	builder := dageth.Type.Header.NewBuilder()
 	if err := builder.AssignNode(inNode); err != nil {
 		return err
 	}
 	node := builder.Build()
+	if cfg.Strict {
+		if err := shared.CheckNumerics(node); err != nil {
+			return fmt.Errorf("invalid DAG-ETH Header form (%v)", err)
+		}
+	}
 	for _, pFunc := range requiredPackFuncs {
-		if err := pFunc(header, node); err != nil {
+		if err := pFunc(cfg, header, node); err != nil {
 			return fmt.Errorf("invalid DAG-ETH Header form (%v)", err)
 		}
 	}
+	if cfg.Profile != nil {
+		if err := cfg.Profile.CheckHeader(header); err != nil {
+			return fmt.Errorf("invalid DAG-ETH Header form for chain %s (%v)", cfg.Profile.Name, err)
+		}
+	}
 	return nil
 }
 
-var requiredPackFuncs = []func(*types.Header, ipld.Node) error{
-	packParentCID,
-	packUnclesCID,
-	packCoinbase,
-	packStateRootCID,
-	packTxRootCID,
-	packRctRootCID,
-	packBloom,
-	packDifficulty,
-	packNumber,
-	packGasLimit,
-	packGasUsed,
-	packTime,
-	packExtra,
-	packMixDigest,
-	packNonce,
-	packBaseFee,
-}
-
-func packNonce(header *types.Header, node ipld.Node) error {
+// checkLink verifies that a link carries the multicodec and multihash types it is built with, when encoding strictly
+func (cfg EncodeOptions) checkLink(link cidlink.Link, codec, mhType uint64) error {
+	if !cfg.Strict {
+		return nil
+	}
+	return shared.CheckLink(link.Cid, cfg.LinkCodecs.Codec(codec), cfg.LinkHashes.Type(mhType))
+}
+
+var requiredPackFuncs = []func(EncodeOptions, *types.Header, ipld.Node) error{
+	EncodeOptions.packParentCID,
+	EncodeOptions.packUnclesCID,
+	EncodeOptions.packCoinbase,
+	EncodeOptions.packStateRootCID,
+	EncodeOptions.packTxRootCID,
+	EncodeOptions.packRctRootCID,
+	EncodeOptions.packBloom,
+	EncodeOptions.packDifficulty,
+	EncodeOptions.packNumber,
+	EncodeOptions.packGasLimit,
+	EncodeOptions.packGasUsed,
+	EncodeOptions.packTime,
+	EncodeOptions.packExtra,
+	EncodeOptions.packMixDigest,
+	EncodeOptions.packNonce,
+	EncodeOptions.packBaseFee,
+	EncodeOptions.packWithdrawalsRootCID,
+	EncodeOptions.packBlobGasUsed,
+	EncodeOptions.packExcessBlobGas,
+	EncodeOptions.packParentBeaconRootCID,
+	EncodeOptions.packRequestsCID,
+}
+
+func (cfg EncodeOptions) packNonce(header *types.Header, node ipld.Node) error {
 	n, err := node.LookupByString("Nonce")
 	if err != nil {
 		return err
@@ -99,7 +162,7 @@ func packNonce(header *types.Header, node ipld.Node) error {
 	return nil
 }
 
-func packMixDigest(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packMixDigest(header *types.Header, node ipld.Node) error {
 	md, err := node.LookupByString("MixDigest")
 	if err != nil {
 		return err
@@ -108,11 +171,14 @@ func packMixDigest(header *types.Header, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	header.MixDigest = common.BytesToHash(mdBytes)
+	header.MixDigest, err = shared.BytesToHash(mdBytes)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
-func packExtra(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packExtra(header *types.Header, node ipld.Node) error {
 	e, err := node.LookupByString("Extra")
 	if err != nil {
 		return err
@@ -125,7 +191,7 @@ func packExtra(header *types.Header, node ipld.Node) error {
 	return nil
 }
 
-func packTime(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packTime(header *types.Header, node ipld.Node) error {
 	t, err := node.LookupByString("Time")
 	if err != nil {
 		return err
@@ -134,11 +200,14 @@ func packTime(header *types.Header, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	header.Time = binary.BigEndian.Uint64(tBytes)
+	header.Time, err = shared.BytesToUint64(tBytes)
+	if err != nil {
+		return fmt.Errorf("Time %v", err)
+	}
 	return nil
 }
 
-func packGasUsed(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packGasUsed(header *types.Header, node ipld.Node) error {
 	gu, err := node.LookupByString("GasUsed")
 	if err != nil {
 		return err
@@ -147,11 +216,14 @@ func packGasUsed(header *types.Header, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	header.GasUsed = binary.BigEndian.Uint64(guBytes)
+	header.GasUsed, err = shared.BytesToUint64(guBytes)
+	if err != nil {
+		return fmt.Errorf("GasUsed %v", err)
+	}
 	return nil
 }
 
-func packGasLimit(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packGasLimit(header *types.Header, node ipld.Node) error {
 	gl, err := node.LookupByString("GasLimit")
 	if err != nil {
 		return err
@@ -160,11 +232,14 @@ func packGasLimit(header *types.Header, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	header.GasLimit = binary.BigEndian.Uint64(glBytes)
+	header.GasLimit, err = shared.BytesToUint64(glBytes)
+	if err != nil {
+		return fmt.Errorf("GasLimit %v", err)
+	}
 	return nil
 }
 
-func packNumber(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packNumber(header *types.Header, node ipld.Node) error {
 	num, err := node.LookupByString("Number")
 	if err != nil {
 		return err
@@ -173,11 +248,11 @@ func packNumber(header *types.Header, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	header.Number = new(big.Int).SetBytes(numBytes)
+	header.Number = shared.BytesToBig(numBytes)
 	return nil
 }
 
-func packDifficulty(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packDifficulty(header *types.Header, node ipld.Node) error {
 	diff, err := node.LookupByString("Difficulty")
 	if err != nil {
 		return err
@@ -186,11 +261,11 @@ func packDifficulty(header *types.Header, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	header.Difficulty = new(big.Int).SetBytes(diffBytes)
+	header.Difficulty = shared.BytesToBig(diffBytes)
 	return nil
 }
 
-func packBloom(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packBloom(header *types.Header, node ipld.Node) error {
 	blm, err := node.LookupByString("Bloom")
 	if err != nil {
 		return err
@@ -207,7 +282,7 @@ func packBloom(header *types.Header, node ipld.Node) error {
 	return nil
 }
 
-func packRctRootCID(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packRctRootCID(header *types.Header, node ipld.Node) error {
 	rctCID, err := node.LookupByString("RctRootCID")
 	if err != nil {
 		return err
@@ -220,6 +295,9 @@ func packRctRootCID(header *types.Header, node ipld.Node) error {
 	if !ok {
 		return fmt.Errorf("header must have a RctRootCID")
 	}
+	if err := cfg.checkLink(rctCIDLink, cid.EthTxReceiptTrie, MultiHashType); err != nil {
+		return err
+	}
 	rctMh := rctCIDLink.Hash()
 	decodedRctMh, err := multihash.Decode(rctMh)
 	if err != nil {
@@ -229,7 +307,7 @@ func packRctRootCID(header *types.Header, node ipld.Node) error {
 	return nil
 }
 
-func packTxRootCID(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packTxRootCID(header *types.Header, node ipld.Node) error {
 	txCID, err := node.LookupByString("TxRootCID")
 	if err != nil {
 		return err
@@ -242,6 +320,9 @@ func packTxRootCID(header *types.Header, node ipld.Node) error {
 	if !ok {
 		return fmt.Errorf("header must have a TxRootCID")
 	}
+	if err := cfg.checkLink(txCIDLink, cid.EthTxTrie, MultiHashType); err != nil {
+		return err
+	}
 	txMh := txCIDLink.Hash()
 	decodedTxMh, err := multihash.Decode(txMh)
 	if err != nil {
@@ -251,7 +332,7 @@ func packTxRootCID(header *types.Header, node ipld.Node) error {
 	return nil
 }
 
-func packStateRootCID(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packStateRootCID(header *types.Header, node ipld.Node) error {
 	srCID, err := node.LookupByString("StateRootCID")
 	if err != nil {
 		return err
@@ -264,6 +345,9 @@ func packStateRootCID(header *types.Header, node ipld.Node) error {
 	if !ok {
 		return fmt.Errorf("header must have a StateRootCID")
 	}
+	if err := cfg.checkLink(srCIDLink, cid.EthStateTrie, MultiHashType); err != nil {
+		return err
+	}
 	srMh := srCIDLink.Hash()
 	decodedSrMh, err := multihash.Decode(srMh)
 	if err != nil {
@@ -273,7 +357,7 @@ func packStateRootCID(header *types.Header, node ipld.Node) error {
 	return nil
 }
 
-func packCoinbase(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packCoinbase(header *types.Header, node ipld.Node) error {
 	coinbase, err := node.LookupByString("Coinbase")
 	if err != nil {
 		return err
@@ -282,11 +366,14 @@ func packCoinbase(header *types.Header, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	header.Coinbase = common.BytesToAddress(coinbaseBytes)
+	header.Coinbase, err = shared.BytesToAddress(coinbaseBytes)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
-func packUnclesCID(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packUnclesCID(header *types.Header, node ipld.Node) error {
 	uncleCID, err := node.LookupByString("UnclesCID")
 	if err != nil {
 		return err
@@ -299,6 +386,9 @@ func packUnclesCID(header *types.Header, node ipld.Node) error {
 	if !ok {
 		return fmt.Errorf("header must have an UnclesCID")
 	}
+	if err := cfg.checkLink(unclesCIDLink, cid.EthBlockList, MultiHashType); err != nil {
+		return err
+	}
 	unclesMh := unclesCIDLink.Hash()
 	decodedUnclesMh, err := multihash.Decode(unclesMh)
 	if err != nil {
@@ -308,7 +398,7 @@ func packUnclesCID(header *types.Header, node ipld.Node) error {
 	return nil
 }
 
-func packParentCID(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packParentCID(header *types.Header, node ipld.Node) error {
 	parentCID, err := node.LookupByString("ParentCID")
 	if err != nil {
 		return err
@@ -321,6 +411,9 @@ func packParentCID(header *types.Header, node ipld.Node) error {
 	if !ok {
 		return fmt.Errorf("header must have a ParentCID")
 	}
+	if err := cfg.checkLink(parentCIDLink, cid.EthBlock, MultiHashType); err != nil {
+		return err
+	}
 	parentMh := parentCIDLink.Hash()
 	decodedParentMh, err := multihash.Decode(parentMh)
 	if err != nil {
@@ -330,7 +423,7 @@ func packParentCID(header *types.Header, node ipld.Node) error {
 	return nil
 }
 
-func packBaseFee(header *types.Header, node ipld.Node) error {
+func (cfg EncodeOptions) packBaseFee(header *types.Header, node ipld.Node) error {
 	baseFeeNode, err := node.LookupByString("BaseFee")
 	if err != nil {
 		return err
@@ -342,6 +435,130 @@ func packBaseFee(header *types.Header, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	header.BaseFee = new(big.Int).SetBytes(baseFeeBytes)
+	header.BaseFee = shared.BytesToBig(baseFeeBytes)
+	return nil
+}
+
+func (cfg EncodeOptions) packWithdrawalsRootCID(header *types.Header, node ipld.Node) error {
+	withdrawalsCID, err := node.LookupByString("WithdrawalsRootCID")
+	if err != nil {
+		return err
+	}
+	if withdrawalsCID.IsNull() {
+		return nil
+	}
+	withdrawalsLink, err := withdrawalsCID.AsLink()
+	if err != nil {
+		return err
+	}
+	withdrawalsCIDLink, ok := withdrawalsLink.(cidlink.Link)
+	if !ok {
+		return fmt.Errorf("header WithdrawalsRootCID must be a CID")
+	}
+	if err := cfg.checkLink(withdrawalsCIDLink, withdrawalTrieMulticodec, MultiHashType); err != nil {
+		return err
+	}
+	decodedWithdrawalsMh, err := multihash.Decode(withdrawalsCIDLink.Hash())
+	if err != nil {
+		return fmt.Errorf("unable to decode WithdrawalsRootCID multihash: %v", err)
+	}
+	withdrawalsHash := common.BytesToHash(decodedWithdrawalsMh.Digest)
+	header.WithdrawalsHash = &withdrawalsHash
+	return nil
+}
+
+func (cfg EncodeOptions) packBlobGasUsed(header *types.Header, node ipld.Node) error {
+	bgu, err := node.LookupByString("BlobGasUsed")
+	if err != nil {
+		return err
+	}
+	if bgu.IsNull() {
+		return nil
+	}
+	bguBytes, err := bgu.AsBytes()
+	if err != nil {
+		return err
+	}
+	blobGasUsed, err := shared.BytesToUint64(bguBytes)
+	if err != nil {
+		return fmt.Errorf("BlobGasUsed %v", err)
+	}
+	header.BlobGasUsed = &blobGasUsed
+	return nil
+}
+
+func (cfg EncodeOptions) packExcessBlobGas(header *types.Header, node ipld.Node) error {
+	ebg, err := node.LookupByString("ExcessBlobGas")
+	if err != nil {
+		return err
+	}
+	if ebg.IsNull() {
+		return nil
+	}
+	ebgBytes, err := ebg.AsBytes()
+	if err != nil {
+		return err
+	}
+	excessBlobGas, err := shared.BytesToUint64(ebgBytes)
+	if err != nil {
+		return fmt.Errorf("ExcessBlobGas %v", err)
+	}
+	header.ExcessBlobGas = &excessBlobGas
+	return nil
+}
+
+func (cfg EncodeOptions) packParentBeaconRootCID(header *types.Header, node ipld.Node) error {
+	beaconCID, err := node.LookupByString("ParentBeaconRootCID")
+	if err != nil {
+		return err
+	}
+	if beaconCID.IsNull() {
+		return nil
+	}
+	beaconLink, err := beaconCID.AsLink()
+	if err != nil {
+		return err
+	}
+	beaconCIDLink, ok := beaconLink.(cidlink.Link)
+	if !ok {
+		return fmt.Errorf("header ParentBeaconRootCID must be a CID")
+	}
+	if err := cfg.checkLink(beaconCIDLink, beaconBlockMulticodec, sszSHA256MultiHash); err != nil {
+		return err
+	}
+	decodedBeaconMh, err := multihash.Decode(beaconCIDLink.Hash())
+	if err != nil {
+		return fmt.Errorf("unable to decode ParentBeaconRootCID multihash: %v", err)
+	}
+	parentBeaconRoot := common.BytesToHash(decodedBeaconMh.Digest)
+	header.ParentBeaconRoot = &parentBeaconRoot
+	return nil
+}
+
+func (cfg EncodeOptions) packRequestsCID(header *types.Header, node ipld.Node) error {
+	requestsCID, err := node.LookupByString("RequestsCID")
+	if err != nil {
+		return err
+	}
+	if requestsCID.IsNull() {
+		return nil
+	}
+	requestsLink, err := requestsCID.AsLink()
+	if err != nil {
+		return err
+	}
+	requestsCIDLink, ok := requestsLink.(cidlink.Link)
+	if !ok {
+		return fmt.Errorf("header RequestsCID must be a CID")
+	}
+	if err := cfg.checkLink(requestsCIDLink, requestListMulticodec, multihash.SHA2_256); err != nil {
+		return err
+	}
+	decodedRequestsMh, err := multihash.Decode(requestsCIDLink.Hash())
+	if err != nil {
+		return fmt.Errorf("unable to decode RequestsCID multihash: %v", err)
+	}
+	requestsHash := common.BytesToHash(decodedRequestsMh.Digest)
+	header.RequestsHash = &requestsHash
 	return nil
 }
@@ -1,7 +1,6 @@
 package header
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,12 +11,77 @@ import (
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/profile"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+const (
+	withdrawalTrieMulticodec = uint64(0x9f)   // Proposed
+	beaconBlockMulticodec    = uint64(0x01a0) // Proposed
+	requestListMulticodec    = uint64(0xf4)   // Proposed
+	// sszSHA256MultiHash identifies an SSZ hash tree root using SHA2-256, which is how beacon block roots are computed
+	sszSHA256MultiHash = uint64(0xb502)
 )
 
+// DecodeOptions can be used to customize the behavior of header decoding.
+// The zero value is the default behavior used by the registered codec.
+type DecodeOptions struct {
+	// LinkCodecs overrides the multicodec types of the links to the parent, the uncles, the tries and the
+	// parent beacon block and the requests of the header
+	LinkCodecs shared.LinkCodecs
+	// LinkHashes overrides the multihash types of the links of the header, e.g. for chains that do not hash
+	// with keccak256
+	LinkHashes shared.LinkHashes
+	// Profile causes the decoder to reject headers that do not carry exactly the fields of the forks the profile
+	// activates at their block, instead of taking whichever trailing fields are present
+	Profile *profile.Profile
+}
+
 // Decode provides an IPLD codec decode interface for eth header IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code 0x90 when this package is invoked via init.
 func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return DecodeOptions{}.Decode(na, in)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeOptions{}.DecodeBytes(na, src)
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// DecodeHeader unpacks a go-ethereum Header into a NodeAssembler
+func DecodeHeader(na ipld.NodeAssembler, header types.Header) error {
+	return DecodeOptions{}.DecodeHeader(na, header)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
 	var src []byte
 	if buf, ok := in.(interface{ Bytes() []byte }); ok {
 		src = buf.Bytes()
@@ -28,54 +92,62 @@ func Decode(na ipld.NodeAssembler, in io.Reader) error {
 			return err
 		}
 	}
-	return DecodeBytes(na, src)
+	return cfg.DecodeBytes(na, src)
 }
 
-// DecodeBytes is like Decode, but it uses an input buffer directly.
-// Decode will grab or read all the bytes from an io.Reader anyway, so this can
-// save having to copy the bytes or create a bytes.Buffer.
-func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
 	var header types.Header
 	if err := rlp.DecodeBytes(src, &header); err != nil {
 		return err
 	}
-	return DecodeHeader(na, header)
+	return cfg.DecodeHeader(na, header)
 }
 
-// DecodeHeader unpacks a go-ethereum Header into a NodeAssembler
-func DecodeHeader(na ipld.NodeAssembler, header types.Header) error {
-	ma, err := na.BeginMap(15)
+// DecodeHeader is like the package level DecodeHeader, but uses the provided options
+func (cfg DecodeOptions) DecodeHeader(na ipld.NodeAssembler, header types.Header) error {
+	if cfg.Profile != nil {
+		if err := cfg.Profile.CheckHeader(&header); err != nil {
+			return fmt.Errorf("invalid DAG-ETH Header binary for chain %s (%v)", cfg.Profile.Name, err)
+		}
+	}
+	ma, err := na.BeginMap(21)
 	if err != nil {
 		return err
 	}
 	for _, upFunc := range requiredUnpackFuncs {
-		if err := upFunc(ma, header); err != nil {
+		if err := upFunc(cfg, ma, header); err != nil {
 			return fmt.Errorf("invalid DAG-ETH Header binary (%v)", err)
 		}
 	}
 	return ma.Finish()
 }
 
-var requiredUnpackFuncs = []func(ipld.MapAssembler, types.Header) error{
-	unpackParentCID,
-	unpackUnclesCID,
-	unpackCoinbase,
-	unpackStateRootCID,
-	unpackTxRootCID,
-	unpackRctRootCID,
-	unpackBloom,
-	unpackDifficulty,
-	unpackNumber,
-	unpackGasLimit,
-	unpackGasUsed,
-	unpackTime,
-	unpackExtra,
-	unpackMixDigest,
-	unpackNonce,
-	unpackBaseFee,
-}
-
-func unpackNonce(ma ipld.MapAssembler, header types.Header) error {
+var requiredUnpackFuncs = []func(DecodeOptions, ipld.MapAssembler, types.Header) error{
+	DecodeOptions.unpackParentCID,
+	DecodeOptions.unpackUnclesCID,
+	DecodeOptions.unpackCoinbase,
+	DecodeOptions.unpackStateRootCID,
+	DecodeOptions.unpackTxRootCID,
+	DecodeOptions.unpackRctRootCID,
+	DecodeOptions.unpackBloom,
+	DecodeOptions.unpackDifficulty,
+	DecodeOptions.unpackNumber,
+	DecodeOptions.unpackGasLimit,
+	DecodeOptions.unpackGasUsed,
+	DecodeOptions.unpackTime,
+	DecodeOptions.unpackExtra,
+	DecodeOptions.unpackMixDigest,
+	DecodeOptions.unpackNonce,
+	DecodeOptions.unpackBaseFee,
+	DecodeOptions.unpackWithdrawalsRootCID,
+	DecodeOptions.unpackBlobGasUsed,
+	DecodeOptions.unpackExcessBlobGas,
+	DecodeOptions.unpackParentBeaconRootCID,
+	DecodeOptions.unpackRequestsCID,
+}
+
+func (cfg DecodeOptions) unpackNonce(ma ipld.MapAssembler, header types.Header) error {
 	if err := ma.AssembleKey().AssignString("Nonce"); err != nil {
 		return err
 	}
@@ -85,7 +157,7 @@ func unpackNonce(ma ipld.MapAssembler, header types.Header) error {
 	return nil
 }
 
-func unpackMixDigest(ma ipld.MapAssembler, header types.Header) error {
+func (cfg DecodeOptions) unpackMixDigest(ma ipld.MapAssembler, header types.Header) error {
 	if err := ma.AssembleKey().AssignString("MixDigest"); err != nil {
 		return err
 	}
@@ -95,41 +167,38 @@ func unpackMixDigest(ma ipld.MapAssembler, header types.Header) error {
 	return nil
 }
 
-func unpackExtra(ma ipld.MapAssembler, header types.Header) error {
+func (cfg DecodeOptions) unpackExtra(ma ipld.MapAssembler, header types.Header) error {
 	if err := ma.AssembleKey().AssignString("Extra"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes(header.Extra)
 }
 
-func unpackTime(ma ipld.MapAssembler, header types.Header) error {
-	timeBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(timeBytes, header.Time)
+func (cfg DecodeOptions) unpackTime(ma ipld.MapAssembler, header types.Header) error {
+	timeBytes := shared.Uint64ToBytes(header.Time)
 	if err := ma.AssembleKey().AssignString("Time"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes(timeBytes)
 }
 
-func unpackGasUsed(ma ipld.MapAssembler, header types.Header) error {
-	gasUsedBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(gasUsedBytes, header.GasUsed)
+func (cfg DecodeOptions) unpackGasUsed(ma ipld.MapAssembler, header types.Header) error {
+	gasUsedBytes := shared.Uint64ToBytes(header.GasUsed)
 	if err := ma.AssembleKey().AssignString("GasUsed"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes(gasUsedBytes)
 }
 
-func unpackGasLimit(ma ipld.MapAssembler, header types.Header) error {
-	gasLimitBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(gasLimitBytes, header.GasLimit)
+func (cfg DecodeOptions) unpackGasLimit(ma ipld.MapAssembler, header types.Header) error {
+	gasLimitBytes := shared.Uint64ToBytes(header.GasLimit)
 	if err := ma.AssembleKey().AssignString("GasLimit"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes(gasLimitBytes)
 }
 
-func unpackNumber(ma ipld.MapAssembler, header types.Header) error {
+func (cfg DecodeOptions) unpackNumber(ma ipld.MapAssembler, header types.Header) error {
 	if header.Number == nil {
 		return fmt.Errorf("header cannot have `nil` Number")
 	}
@@ -139,7 +208,7 @@ func unpackNumber(ma ipld.MapAssembler, header types.Header) error {
 	return ma.AssembleValue().AssignBytes(header.Number.Bytes())
 }
 
-func unpackDifficulty(ma ipld.MapAssembler, header types.Header) error {
+func (cfg DecodeOptions) unpackDifficulty(ma ipld.MapAssembler, header types.Header) error {
 	if header.Difficulty == nil {
 		return fmt.Errorf("header cannot have `nil` Difficulty")
 	}
@@ -149,19 +218,18 @@ func unpackDifficulty(ma ipld.MapAssembler, header types.Header) error {
 	return ma.AssembleValue().AssignBytes(header.Difficulty.Bytes())
 }
 
-func unpackBloom(ma ipld.MapAssembler, header types.Header) error {
+func (cfg DecodeOptions) unpackBloom(ma ipld.MapAssembler, header types.Header) error {
 	if err := ma.AssembleKey().AssignString("Bloom"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes(header.Bloom.Bytes())
 }
 
-func unpackRctRootCID(ma ipld.MapAssembler, header types.Header) error {
-	rctMh, err := multihash.Encode(header.ReceiptHash.Bytes(), MultiHashType)
+func (cfg DecodeOptions) unpackRctRootCID(ma ipld.MapAssembler, header types.Header) error {
+	rctCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(cid.EthTxReceiptTrie), cfg.LinkHashes.Type(MultiHashType), header.ReceiptHash.Bytes())
 	if err != nil {
 		return err
 	}
-	rctCID := cid.NewCidV1(cid.EthTxReceiptTrie, rctMh)
 	rctLinkCID := cidlink.Link{Cid: rctCID}
 	if err := ma.AssembleKey().AssignString("RctRootCID"); err != nil {
 		return err
@@ -169,12 +237,11 @@ func unpackRctRootCID(ma ipld.MapAssembler, header types.Header) error {
 	return ma.AssembleValue().AssignLink(rctLinkCID)
 }
 
-func unpackTxRootCID(ma ipld.MapAssembler, header types.Header) error {
-	txMh, err := multihash.Encode(header.TxHash.Bytes(), MultiHashType)
+func (cfg DecodeOptions) unpackTxRootCID(ma ipld.MapAssembler, header types.Header) error {
+	txCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(cid.EthTxTrie), cfg.LinkHashes.Type(MultiHashType), header.TxHash.Bytes())
 	if err != nil {
 		return err
 	}
-	txCID := cid.NewCidV1(cid.EthTxTrie, txMh)
 	txLinkCID := cidlink.Link{Cid: txCID}
 	if err := ma.AssembleKey().AssignString("TxRootCID"); err != nil {
 		return err
@@ -182,12 +249,11 @@ func unpackTxRootCID(ma ipld.MapAssembler, header types.Header) error {
 	return ma.AssembleValue().AssignLink(txLinkCID)
 }
 
-func unpackStateRootCID(ma ipld.MapAssembler, header types.Header) error {
-	srMh, err := multihash.Encode(header.Root.Bytes(), MultiHashType)
+func (cfg DecodeOptions) unpackStateRootCID(ma ipld.MapAssembler, header types.Header) error {
+	srCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(cid.EthStateTrie), cfg.LinkHashes.Type(MultiHashType), header.Root.Bytes())
 	if err != nil {
 		return err
 	}
-	srCID := cid.NewCidV1(cid.EthStateTrie, srMh)
 	srLinkCID := cidlink.Link{Cid: srCID}
 	if err := ma.AssembleKey().AssignString("StateRootCID"); err != nil {
 		return err
@@ -195,19 +261,18 @@ func unpackStateRootCID(ma ipld.MapAssembler, header types.Header) error {
 	return ma.AssembleValue().AssignLink(srLinkCID)
 }
 
-func unpackCoinbase(ma ipld.MapAssembler, header types.Header) error {
+func (cfg DecodeOptions) unpackCoinbase(ma ipld.MapAssembler, header types.Header) error {
 	if err := ma.AssembleKey().AssignString("Coinbase"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes(header.Coinbase.Bytes())
 }
 
-func unpackUnclesCID(ma ipld.MapAssembler, header types.Header) error {
-	unclesMh, err := multihash.Encode(header.UncleHash.Bytes(), MultiHashType)
+func (cfg DecodeOptions) unpackUnclesCID(ma ipld.MapAssembler, header types.Header) error {
+	unclesCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(cid.EthBlockList), cfg.LinkHashes.Type(MultiHashType), header.UncleHash.Bytes())
 	if err != nil {
 		return err
 	}
-	unclesCID := cid.NewCidV1(cid.EthBlockList, unclesMh)
 	unclesLinkCID := cidlink.Link{Cid: unclesCID}
 	if err := ma.AssembleKey().AssignString("UnclesCID"); err != nil {
 		return err
@@ -215,12 +280,11 @@ func unpackUnclesCID(ma ipld.MapAssembler, header types.Header) error {
 	return ma.AssembleValue().AssignLink(unclesLinkCID)
 }
 
-func unpackParentCID(ma ipld.MapAssembler, header types.Header) error {
-	parentMh, err := multihash.Encode(header.ParentHash.Bytes(), MultiHashType)
+func (cfg DecodeOptions) unpackParentCID(ma ipld.MapAssembler, header types.Header) error {
+	parentCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(cid.EthBlock), cfg.LinkHashes.Type(MultiHashType), header.ParentHash.Bytes())
 	if err != nil {
 		return err
 	}
-	parentCID := cid.NewCidV1(cid.EthBlock, parentMh)
 	parentLinkCID := cidlink.Link{Cid: parentCID}
 	if err := ma.AssembleKey().AssignString("ParentCID"); err != nil {
 		return err
@@ -228,7 +292,7 @@ func unpackParentCID(ma ipld.MapAssembler, header types.Header) error {
 	return ma.AssembleValue().AssignLink(parentLinkCID)
 }
 
-func unpackBaseFee(ma ipld.MapAssembler, header types.Header) error {
+func (cfg DecodeOptions) unpackBaseFee(ma ipld.MapAssembler, header types.Header) error {
 	if err := ma.AssembleKey().AssignString("BaseFee"); err != nil {
 		return err
 	}
@@ -237,3 +301,67 @@ func unpackBaseFee(ma ipld.MapAssembler, header types.Header) error {
 	}
 	return ma.AssembleValue().AssignBytes(header.BaseFee.Bytes())
 }
+
+func (cfg DecodeOptions) unpackWithdrawalsRootCID(ma ipld.MapAssembler, header types.Header) error {
+	if err := ma.AssembleKey().AssignString("WithdrawalsRootCID"); err != nil {
+		return err
+	}
+	if header.WithdrawalsHash == nil {
+		return ma.AssembleValue().AssignNull()
+	}
+	withdrawalsCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(withdrawalTrieMulticodec), cfg.LinkHashes.Type(MultiHashType), header.WithdrawalsHash.Bytes())
+	if err != nil {
+		return err
+	}
+	return ma.AssembleValue().AssignLink(cidlink.Link{Cid: withdrawalsCID})
+}
+
+func (cfg DecodeOptions) unpackBlobGasUsed(ma ipld.MapAssembler, header types.Header) error {
+	if err := ma.AssembleKey().AssignString("BlobGasUsed"); err != nil {
+		return err
+	}
+	if header.BlobGasUsed == nil {
+		return ma.AssembleValue().AssignNull()
+	}
+	blobGasUsedBytes := shared.Uint64ToBytes(*header.BlobGasUsed)
+	return ma.AssembleValue().AssignBytes(blobGasUsedBytes)
+}
+
+func (cfg DecodeOptions) unpackExcessBlobGas(ma ipld.MapAssembler, header types.Header) error {
+	if err := ma.AssembleKey().AssignString("ExcessBlobGas"); err != nil {
+		return err
+	}
+	if header.ExcessBlobGas == nil {
+		return ma.AssembleValue().AssignNull()
+	}
+	excessBlobGasBytes := shared.Uint64ToBytes(*header.ExcessBlobGas)
+	return ma.AssembleValue().AssignBytes(excessBlobGasBytes)
+}
+
+func (cfg DecodeOptions) unpackParentBeaconRootCID(ma ipld.MapAssembler, header types.Header) error {
+	if err := ma.AssembleKey().AssignString("ParentBeaconRootCID"); err != nil {
+		return err
+	}
+	if header.ParentBeaconRoot == nil {
+		return ma.AssembleValue().AssignNull()
+	}
+	beaconCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(beaconBlockMulticodec), cfg.LinkHashes.Type(sszSHA256MultiHash), header.ParentBeaconRoot.Bytes())
+	if err != nil {
+		return err
+	}
+	return ma.AssembleValue().AssignLink(cidlink.Link{Cid: beaconCID})
+}
+
+func (cfg DecodeOptions) unpackRequestsCID(ma ipld.MapAssembler, header types.Header) error {
+	if err := ma.AssembleKey().AssignString("RequestsCID"); err != nil {
+		return err
+	}
+	if header.RequestsHash == nil {
+		return ma.AssembleValue().AssignNull()
+	}
+	requestsCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(requestListMulticodec), cfg.LinkHashes.Type(multihash.SHA2_256), header.RequestsHash.Bytes())
+	if err != nil {
+		return err
+	}
+	return ma.AssembleValue().AssignLink(cidlink.Link{Cid: requestsCID})
+}
@@ -0,0 +1,309 @@
+/*
+Package helpers translates Ethereum keys, such as account addresses and storage slots, into paths
+through the DAG-ETH trie node structure, so a specific account or slot can be fetched by walking
+links from a state root.
+*/
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipld/go-ipld-prime"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/keys"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// KeyToNibbles converts a trie key into its nibble path, one nibble per byte
+func KeyToNibbles(key []byte) []byte {
+	return keys.KeybytesToNibbles(key)
+}
+
+// AddressToNibbles returns the state trie nibble path of the account at the address,
+// which is the nibble path of the keccak256 hash of the address
+func AddressToNibbles(address common.Address) []byte {
+	return KeyToNibbles(shared.AddressToLeafKey(address))
+}
+
+// StorageSlotToNibbles returns the storage trie nibble path of the slot,
+// which is the nibble path of the keccak256 hash of the 32 byte slot key
+func StorageSlotToNibbles(slot common.Hash) []byte {
+	return KeyToNibbles(crypto.Keccak256(slot.Bytes()))
+}
+
+// TriePath walks the trie from the node referenced by root along the nibble path, loading linked nodes
+// through the LinkSystem, and returns the ipld.Path from that root node to the Value of the node at the
+// end of the nibble path, e.g. TrieBranchNode/Child3/Link/TrieLeafNode/Value.
+// Partial paths are expected in the default hex representation.
+// It returns an error if the trie does not contain a value at the nibble path.
+func TriePath(lsys ipld.LinkSystem, root ipld.Link, nibbles []byte) (ipld.Path, error) {
+	node, err := loadTrieNode(lsys, root)
+	if err != nil {
+		return ipld.Path{}, err
+	}
+	var path ipld.Path
+	remaining := nibbles
+	for {
+		n, kind, err := trie.NodeAndKind(node)
+		if err != nil {
+			return ipld.Path{}, err
+		}
+		path = path.AppendSegmentString(kind.String())
+		switch kind {
+		case trie.BRANCH_NODE:
+			if len(remaining) == 0 {
+				valNode, err := n.LookupByString("Value")
+				if err != nil {
+					return ipld.Path{}, err
+				}
+				if valNode.IsNull() {
+					return ipld.Path{}, fmt.Errorf("trie has no value at path %x", nibbles)
+				}
+				return path.AppendSegmentString("Value"), nil
+			}
+			childKey := fmt.Sprintf("Child%X", remaining[0])
+			remaining = remaining[1:]
+			child, err := n.LookupByString(childKey)
+			if err != nil {
+				return ipld.Path{}, err
+			}
+			if child.IsNull() {
+				return ipld.Path{}, fmt.Errorf("trie has no value at path %x", nibbles)
+			}
+			path = path.AppendSegmentString(childKey)
+			if linkNode, err := child.LookupByString("Link"); err == nil {
+				path = path.AppendSegmentString("Link")
+				if node, err = loadLinkedTrieNode(lsys, linkNode); err != nil {
+					return ipld.Path{}, err
+				}
+				continue
+			}
+			// nodes smaller than 32 bytes are included directly in their parent branch
+			if node, err = child.LookupByString("TrieNode"); err != nil {
+				return ipld.Path{}, fmt.Errorf("branch node child needs to be a link or a trie node: %v", err)
+			}
+			path = path.AppendSegmentString("TrieNode")
+		case trie.EXTENSION_NODE:
+			partialPath, err := partialPathNibbles(n)
+			if err != nil {
+				return ipld.Path{}, err
+			}
+			if !keys.HasPrefix(remaining, partialPath) {
+				return ipld.Path{}, fmt.Errorf("trie has no value at path %x", nibbles)
+			}
+			remaining = remaining[len(partialPath):]
+			child, err := n.LookupByString("Child")
+			if err != nil {
+				return ipld.Path{}, err
+			}
+			path = path.AppendSegmentString("Child")
+			if linkNode, err := child.LookupByString("Link"); err == nil {
+				path = path.AppendSegmentString("Link")
+				if node, err = loadLinkedTrieNode(lsys, linkNode); err != nil {
+					return ipld.Path{}, err
+				}
+				continue
+			}
+			// nodes smaller than 32 bytes are included directly in their parent
+			if node, err = child.LookupByString("TrieNode"); err != nil {
+				return ipld.Path{}, fmt.Errorf("extension node child needs to be a link or a trie node: %v", err)
+			}
+			path = path.AppendSegmentString("TrieNode")
+		case trie.LEAF_NODE:
+			partialPath, err := partialPathNibbles(n)
+			if err != nil {
+				return ipld.Path{}, err
+			}
+			if keys.Compare(remaining, partialPath) != 0 {
+				return ipld.Path{}, fmt.Errorf("trie has no value at path %x", nibbles)
+			}
+			return path.AppendSegmentString("Value"), nil
+		default:
+			return ipld.Path{}, fmt.Errorf("unrecognized trie node type %s", kind.String())
+		}
+	}
+}
+
+// AccountPath returns the ipld.Path from the state trie root node referenced by stateRoot to the
+// Account of the address, e.g. for use after the StateRootCID segment of a Header
+func AccountPath(lsys ipld.LinkSystem, stateRoot ipld.Link, address common.Address) (ipld.Path, error) {
+	path, err := TriePath(lsys, stateRoot, AddressToNibbles(address))
+	if err != nil {
+		return ipld.Path{}, err
+	}
+	return path.AppendSegmentString(trie.STATE_VALUE.String()), nil
+}
+
+// StoragePath returns the ipld.Path from the state trie root node referenced by stateRoot to the
+// value of the slot in the storage trie of the account at the address
+func StoragePath(lsys ipld.LinkSystem, stateRoot ipld.Link, address common.Address, slot common.Hash) (ipld.Path, error) {
+	accountPath, err := AccountPath(lsys, stateRoot, address)
+	if err != nil {
+		return ipld.Path{}, err
+	}
+	storageRoot, err := resolveStorageRoot(lsys, stateRoot, accountPath)
+	if err != nil {
+		return ipld.Path{}, err
+	}
+	storagePath, err := TriePath(lsys, storageRoot, StorageSlotToNibbles(slot))
+	if err != nil {
+		return ipld.Path{}, err
+	}
+	return accountPath.AppendSegmentString("StorageRootCID").
+		Join(storagePath).
+		AppendSegmentString(trie.STORAGE_VALUE.String()), nil
+}
+
+// PathSelector returns a selector that explores exactly the fields of the path and matches the node at its end,
+// so the path can be used where a selector is expected, e.g. to fetch the path and its proof over the network
+func PathSelector(path ipld.Path) builder.SelectorSpec {
+	ssb := builder.NewSelectorSpecBuilder(basicnode.Prototype.Any)
+	spec := ssb.Matcher()
+	segments := path.Segments()
+	for i := len(segments) - 1; i >= 0; i-- {
+		field, next := segments[i].String(), spec
+		spec = ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+			efsb.Insert(field, next)
+		})
+	}
+	return spec
+}
+
+func resolveStorageRoot(lsys ipld.LinkSystem, stateRoot ipld.Link, accountPath ipld.Path) (ipld.Link, error) {
+	node, err := loadTrieNode(lsys, stateRoot)
+	if err != nil {
+		return nil, err
+	}
+	for _, segment := range accountPath.Segments() {
+		if node, err = node.LookupBySegment(segment); err != nil {
+			return nil, err
+		}
+		if node.Kind() == ipld.Kind_Link {
+			if node, err = loadLinkedTrieNode(lsys, node); err != nil {
+				return nil, err
+			}
+		}
+	}
+	storageRootNode, err := node.LookupByString("StorageRootCID")
+	if err != nil {
+		return nil, err
+	}
+	return storageRootNode.AsLink()
+}
+
+func loadLinkedTrieNode(lsys ipld.LinkSystem, linkNode ipld.Node) (ipld.Node, error) {
+	lnk, err := linkNode.AsLink()
+	if err != nil {
+		return nil, err
+	}
+	return loadTrieNode(lsys, lnk)
+}
+
+func loadTrieNode(lsys ipld.LinkSystem, lnk ipld.Link) (ipld.Node, error) {
+	return lsys.Load(ipld.LinkContext{}, lnk, dageth.Type.TrieNode)
+}
+
+// Step follows the remaining nibbles through the trie node, and any nodes embedded in it.
+// It returns either the Value the nibbles end at, or the link to the next node to resolve together
+// with the nibbles still remaining after it. Both are nil when the trie does not hold the nibbles.
+func Step(node ipld.Node, remaining []byte) (ipld.Node, ipld.Link, []byte, error) {
+	for {
+		n, kind, err := trie.NodeAndKind(node)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		switch kind {
+		case trie.BRANCH_NODE:
+			if len(remaining) == 0 {
+				val, err := valueOf(n)
+				return val, nil, nil, err
+			}
+			child, err := n.LookupByString(fmt.Sprintf("Child%X", remaining[0]))
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			remaining = remaining[1:]
+			if child.IsNull() {
+				return nil, nil, nil, nil
+			}
+			if linkNode, err := child.LookupByString("Link"); err == nil {
+				lnk, err := linkNode.AsLink()
+				return nil, lnk, remaining, err
+			}
+			// nodes smaller than 32 bytes are included directly in their parent branch
+			if node, err = child.LookupByString("TrieNode"); err != nil {
+				return nil, nil, nil, fmt.Errorf("branch node child needs to be a link or a trie node: %v", err)
+			}
+		case trie.EXTENSION_NODE:
+			partialPath, err := partialPathNibbles(n)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if !keys.HasPrefix(remaining, partialPath) {
+				return nil, nil, nil, nil
+			}
+			remaining = remaining[len(partialPath):]
+			child, err := n.LookupByString("Child")
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if linkNode, err := child.LookupByString("Link"); err == nil {
+				lnk, err := linkNode.AsLink()
+				return nil, lnk, remaining, err
+			}
+			// nodes smaller than 32 bytes are included directly in their parent
+			if node, err = child.LookupByString("TrieNode"); err != nil {
+				return nil, nil, nil, fmt.Errorf("extension node child needs to be a link or a trie node: %v", err)
+			}
+		case trie.LEAF_NODE:
+			partialPath, err := partialPathNibbles(n)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if keys.Compare(remaining, partialPath) != 0 {
+				return nil, nil, nil, nil
+			}
+			val, err := valueOf(n)
+			return val, nil, nil, err
+		default:
+			return nil, nil, nil, fmt.Errorf("unrecognized trie node type %s", kind.String())
+		}
+	}
+}
+
+func valueOf(node ipld.Node) (ipld.Node, error) {
+	val, err := node.LookupByString("Value")
+	if err != nil {
+		return nil, err
+	}
+	if val.IsNull() {
+		return nil, nil
+	}
+	return val, nil
+}
+
+// partialPathNibbles returns the nibbles of the partial path of the extension or leaf node, without the terminator
+// flag leaf paths carry in the default hex representation
+func partialPathNibbles(node ipld.Node) ([]byte, error) {
+	ppNode, err := node.LookupByString("PartialPath")
+	if err != nil {
+		return nil, err
+	}
+	path, err := ppNode.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(path) > 0 && path[len(path)-1] == 16 {
+		path = path[:len(path)-1]
+	}
+	if err := keys.CheckNibbles(path); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
@@ -0,0 +1,204 @@
+package helpers_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+	"github.com/ipld/go-ipld-prime/traversal"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/helpers"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+var (
+	mockContract = common.HexToAddress("0x00000000000000000000000000000000000000c0")
+	mockSlots    = map[common.Hash][]byte{
+		common.BigToHash(big.NewInt(0)): {0x01},
+		common.BigToHash(big.NewInt(1)): {0x02, 0x03},
+		common.BigToHash(big.NewInt(2)): {0x04, 0x05, 0x06},
+	}
+)
+
+func buildStateTrie(t *testing.T, store *storage.Memory) (ipld.Link, map[common.Address]*types.StateAccount) {
+	storageKVs := make(map[string][]byte, len(mockSlots))
+	for slot, val := range mockSlots {
+		valRLP, _ := rlp.EncodeToBytes(val)
+		storageKVs[string(crypto.Keccak256(slot.Bytes()))] = valRLP
+	}
+	storageRoot := testutil.BuildTrieLink(t, store, storage_trie.MultiCodecType, storageKVs)
+
+	accounts := make(map[common.Address]*types.StateAccount)
+	stateKVs := make(map[string][]byte)
+	for i := int64(1); i <= 32; i++ {
+		addr := common.BigToAddress(big.NewInt(i))
+		acct := &types.StateAccount{
+			Nonce:    uint64(i),
+			Balance:  uint256.NewInt(uint64(i) * 1000),
+			Root:     types.EmptyRootHash,
+			CodeHash: types.EmptyCodeHash.Bytes(),
+		}
+		accounts[addr] = acct
+	}
+	accounts[mockContract] = &types.StateAccount{
+		Nonce:    1,
+		Balance:  uint256.NewInt(0),
+		Root:     common.BytesToHash(storageRoot.(cidlink.Link).Hash()[2:]),
+		CodeHash: types.EmptyCodeHash.Bytes(),
+	}
+	for addr, acct := range accounts {
+		acctRLP, _ := rlp.EncodeToBytes(acct)
+		stateKVs[string(shared.AddressToLeafKey(addr))] = acctRLP
+	}
+	return testutil.BuildTrieLink(t, store, state_trie.MultiCodecType, stateKVs), accounts
+}
+
+func TestAccountPath(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	stateRoot, accounts := buildStateTrie(t, store)
+	lsys := codecs.NewLinkSystem(store)
+	rootNode, err := lsys.Load(ipld.LinkContext{}, stateRoot, dageth.Type.TrieNode)
+	if err != nil {
+		t.Fatalf("unable to load state root: %v", err)
+	}
+	prog := traversal.Progress{Cfg: &traversal.Config{
+		LinkSystem:                     lsys,
+		LinkTargetNodePrototypeChooser: codecs.NodePrototypeChooser,
+	}}
+	for addr, acct := range accounts {
+		path, err := helpers.AccountPath(lsys, stateRoot, addr)
+		if err != nil {
+			t.Fatalf("unable to resolve account path for %s: %v", addr.Hex(), err)
+		}
+		if err := prog.Focus(rootNode, path.AppendSegmentString("Nonce"), func(_ traversal.Progress, n ipld.Node) error {
+			nonceBytes, err := n.AsBytes()
+			if err != nil {
+				return err
+			}
+			if new(big.Int).SetBytes(nonceBytes).Uint64() != acct.Nonce {
+				t.Errorf("account %s nonce (%x) does not match expected nonce (%d)", addr.Hex(), nonceBytes, acct.Nonce)
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("unable to traverse %s: %v", path.String(), err)
+		}
+	}
+
+	if _, err := helpers.AccountPath(lsys, stateRoot, common.HexToAddress("0xdeadbeef")); err == nil {
+		t.Error("expected an error resolving the path of an account that is not in the trie")
+	}
+}
+
+func TestStoragePath(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	stateRoot, _ := buildStateTrie(t, store)
+	lsys := codecs.NewLinkSystem(store)
+	rootNode, err := lsys.Load(ipld.LinkContext{}, stateRoot, dageth.Type.TrieNode)
+	if err != nil {
+		t.Fatalf("unable to load state root: %v", err)
+	}
+	prog := traversal.Progress{Cfg: &traversal.Config{
+		LinkSystem:                     lsys,
+		LinkTargetNodePrototypeChooser: codecs.NodePrototypeChooser,
+	}}
+	for slot, val := range mockSlots {
+		path, err := helpers.StoragePath(lsys, stateRoot, mockContract, slot)
+		if err != nil {
+			t.Fatalf("unable to resolve storage path for slot %s: %v", slot.Hex(), err)
+		}
+		expected, _ := rlp.EncodeToBytes(val)
+		check := func(_ traversal.Progress, n ipld.Node) error {
+			storageVal, err := n.AsBytes()
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(storageVal, expected) {
+				t.Errorf("slot %s value (%x) does not match expected value (%x)", slot.Hex(), storageVal, expected)
+			}
+			return nil
+		}
+		if err := prog.Focus(rootNode, path, check); err != nil {
+			t.Fatalf("unable to traverse %s: %v", path.String(), err)
+		}
+
+		// the selector matches only the node at the end of the path
+		sel, err := helpers.PathSelector(path).Selector()
+		if err != nil {
+			t.Fatalf("unable to compile path selector: %v", err)
+		}
+		matched := 0
+		if err := prog.WalkMatching(rootNode, sel, func(p traversal.Progress, n ipld.Node) error {
+			matched++
+			return check(p, n)
+		}); err != nil {
+			t.Fatalf("unable to walk path selector: %v", err)
+		}
+		if matched != 1 {
+			t.Errorf("expected the path selector to match 1 node, got %d", matched)
+		}
+	}
+
+	if _, err := helpers.StoragePath(lsys, stateRoot, mockContract, common.BigToHash(big.NewInt(3))); err == nil {
+		t.Error("expected an error resolving the path of a slot that is not in the trie")
+	}
+}
+
+func TestTriePathEmbeddedNodes(t *testing.T) {
+	// the keys share all but their last nibbles, so the nodes under the root branch are small enough to be
+	// included directly in their parent: an extension over a branch over two leaves, and a leaf
+	kvs := map[string][]byte{
+		string(common.Hex2Bytes("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa121")): {0x01},
+		string(common.Hex2Bytes("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa122")): {0x02},
+		string(common.Hex2Bytes("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa345")): {0x03},
+	}
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	root := testutil.BuildTrieLink(t, store, storage_trie.MultiCodecType, kvs)
+	lsys := codecs.NewLinkSystem(store)
+	rootNode, err := lsys.Load(ipld.LinkContext{}, root, dageth.Type.TrieNode)
+	if err != nil {
+		t.Fatalf("unable to load storage root: %v", err)
+	}
+	prog := traversal.Progress{Cfg: &traversal.Config{
+		LinkSystem:                     lsys,
+		LinkTargetNodePrototypeChooser: codecs.NodePrototypeChooser,
+	}}
+	for key, val := range kvs {
+		path, err := helpers.TriePath(lsys, root, helpers.KeyToNibbles([]byte(key)))
+		if err != nil {
+			t.Fatalf("unable to resolve trie path for key %x: %v", key, err)
+		}
+		if err := prog.Focus(rootNode, path.AppendSegmentString("Bytes"), func(_ traversal.Progress, n ipld.Node) error {
+			storageVal, err := n.AsBytes()
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(storageVal, val) {
+				t.Errorf("key %x value (%x) does not match expected value (%x)", key, storageVal, val)
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("unable to traverse %s: %v", path.String(), err)
+		}
+	}
+	expected := "TrieExtensionNode/Child/Link/TrieBranchNode/Child1/TrieNode/TrieExtensionNode/Child/TrieNode/TrieBranchNode/Child2/TrieNode/TrieLeafNode/Value"
+	path, err := helpers.TriePath(lsys, root, helpers.KeyToNibbles(common.Hex2Bytes("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa122")))
+	if err != nil {
+		t.Fatalf("unable to resolve trie path: %v", err)
+	}
+	if path.String() != expected {
+		t.Errorf("trie path (%s) does not match expected path (%s)", path.String(), expected)
+	}
+}
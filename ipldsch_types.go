@@ -20,82 +20,120 @@ var _ ipld.Node = nil // suppress errors when this dependency is not referenced
 var Type typeSlab
 
 type typeSlab struct {
-	AccessElement           _AccessElement__Prototype
-	AccessElement__Repr     _AccessElement__ReprPrototype
-	AccessList              _AccessList__Prototype
-	AccessList__Repr        _AccessList__ReprPrototype
-	Account                 _Account__Prototype
-	Account__Repr           _Account__ReprPrototype
-	Address                 _Address__Prototype
-	Address__Repr           _Address__ReprPrototype
-	Balance                 _Balance__Prototype
-	Balance__Repr           _Balance__ReprPrototype
-	BigInt                  _BigInt__Prototype
-	BigInt__Repr            _BigInt__ReprPrototype
-	Block                   _Block__Prototype
-	Block__Repr             _Block__ReprPrototype
-	Bloom                   _Bloom__Prototype
-	Bloom__Repr             _Bloom__ReprPrototype
-	Bool                    _Bool__Prototype
-	Bool__Repr              _Bool__ReprPrototype
-	ByteCode                _ByteCode__Prototype
-	ByteCode__Repr          _ByteCode__ReprPrototype
-	Bytes                   _Bytes__Prototype
-	Bytes__Repr             _Bytes__ReprPrototype
-	Child                   _Child__Prototype
-	Child__Repr             _Child__ReprPrototype
-	Frame                   _Frame__Prototype
-	Frame__Repr             _Frame__ReprPrototype
-	FrameList               _FrameList__Prototype
-	FrameList__Repr         _FrameList__ReprPrototype
-	Hash                    _Hash__Prototype
-	Hash__Repr              _Hash__ReprPrototype
-	Header                  _Header__Prototype
-	Header__Repr            _Header__ReprPrototype
-	Link                    _Link__Prototype
-	Link__Repr              _Link__ReprPrototype
-	Log                     _Log__Prototype
-	Log__Repr               _Log__ReprPrototype
-	Logs                    _Logs__Prototype
-	Logs__Repr              _Logs__ReprPrototype
-	OpCode                  _OpCode__Prototype
-	OpCode__Repr            _OpCode__ReprPrototype
-	Receipt                 _Receipt__Prototype
-	Receipt__Repr           _Receipt__ReprPrototype
-	Receipts                _Receipts__Prototype
-	Receipts__Repr          _Receipts__ReprPrototype
-	StorageKeys             _StorageKeys__Prototype
-	StorageKeys__Repr       _StorageKeys__ReprPrototype
-	String                  _String__Prototype
-	String__Repr            _String__ReprPrototype
-	Time                    _Time__Prototype
-	Time__Repr              _Time__ReprPrototype
-	Topics                  _Topics__Prototype
-	Topics__Repr            _Topics__ReprPrototype
-	Transaction             _Transaction__Prototype
-	Transaction__Repr       _Transaction__ReprPrototype
-	Transactions            _Transactions__Prototype
-	Transactions__Repr      _Transactions__ReprPrototype
-	TrieBranchNode          _TrieBranchNode__Prototype
-	TrieBranchNode__Repr    _TrieBranchNode__ReprPrototype
-	TrieExtensionNode       _TrieExtensionNode__Prototype
-	TrieExtensionNode__Repr _TrieExtensionNode__ReprPrototype
-	TrieLeafNode            _TrieLeafNode__Prototype
-	TrieLeafNode__Repr      _TrieLeafNode__ReprPrototype
-	TrieNode                _TrieNode__Prototype
-	TrieNode__Repr          _TrieNode__ReprPrototype
-	TxCIDList               _TxCIDList__Prototype
-	TxCIDList__Repr         _TxCIDList__ReprPrototype
-	TxTrace                 _TxTrace__Prototype
-	TxTrace__Repr           _TxTrace__ReprPrototype
-	TxType                  _TxType__Prototype
-	TxType__Repr            _TxType__ReprPrototype
-	Uint                    _Uint__Prototype
-	Uint__Repr              _Uint__ReprPrototype
-	Uncles                  _Uncles__Prototype
-	Uncles__Repr            _Uncles__ReprPrototype
-	Value                   _Value__Prototype
-	Value__Repr             _Value__ReprPrototype
+	AccessElement               _AccessElement__Prototype
+	AccessElement__Repr         _AccessElement__ReprPrototype
+	AccessList                  _AccessList__Prototype
+	AccessList__Repr            _AccessList__ReprPrototype
+	Account                     _Account__Prototype
+	Account__Repr               _Account__ReprPrototype
+	Address                     _Address__Prototype
+	Address__Repr               _Address__ReprPrototype
+	Addresses                   _Addresses__Prototype
+	Addresses__Repr             _Addresses__ReprPrototype
+	Authorization               _Authorization__Prototype
+	Authorization__Repr         _Authorization__ReprPrototype
+	AuthorizationList           _AuthorizationList__Prototype
+	AuthorizationList__Repr     _AuthorizationList__ReprPrototype
+	Balance                     _Balance__Prototype
+	Balance__Repr               _Balance__ReprPrototype
+	BigInt                      _BigInt__Prototype
+	BigInt__Repr                _BigInt__ReprPrototype
+	BlobHashes                  _BlobHashes__Prototype
+	BlobHashes__Repr            _BlobHashes__ReprPrototype
+	BlobSidecar                 _BlobSidecar__Prototype
+	BlobSidecar__Repr           _BlobSidecar__ReprPrototype
+	Blobs                       _Blobs__Prototype
+	Blobs__Repr                 _Blobs__ReprPrototype
+	Block                       _Block__Prototype
+	Block__Repr                 _Block__ReprPrototype
+	Bloom                       _Bloom__Prototype
+	Bloom__Repr                 _Bloom__ReprPrototype
+	Bool                        _Bool__Prototype
+	Bool__Repr                  _Bool__ReprPrototype
+	ByteCode                    _ByteCode__Prototype
+	ByteCode__Repr              _ByteCode__ReprPrototype
+	Bytes                       _Bytes__Prototype
+	Bytes__Repr                 _Bytes__ReprPrototype
+	Child                       _Child__Prototype
+	Child__Repr                 _Child__ReprPrototype
+	CliqueExtra                 _CliqueExtra__Prototype
+	CliqueExtra__Repr           _CliqueExtra__ReprPrototype
+	ConsolidationRequest        _ConsolidationRequest__Prototype
+	ConsolidationRequest__Repr  _ConsolidationRequest__ReprPrototype
+	ConsolidationRequests       _ConsolidationRequests__Prototype
+	ConsolidationRequests__Repr _ConsolidationRequests__ReprPrototype
+	DepositRequest              _DepositRequest__Prototype
+	DepositRequest__Repr        _DepositRequest__ReprPrototype
+	DepositRequests             _DepositRequests__Prototype
+	DepositRequests__Repr       _DepositRequests__ReprPrototype
+	Frame                       _Frame__Prototype
+	Frame__Repr                 _Frame__ReprPrototype
+	FrameList                   _FrameList__Prototype
+	FrameList__Repr             _FrameList__ReprPrototype
+	Hash                        _Hash__Prototype
+	Hash__Repr                  _Hash__ReprPrototype
+	Header                      _Header__Prototype
+	Header__Repr                _Header__ReprPrototype
+	KZGCommitments              _KZGCommitments__Prototype
+	KZGCommitments__Repr        _KZGCommitments__ReprPrototype
+	KZGProofs                   _KZGProofs__Prototype
+	KZGProofs__Repr             _KZGProofs__ReprPrototype
+	Link                        _Link__Prototype
+	Link__Repr                  _Link__ReprPrototype
+	Log                         _Log__Prototype
+	Log__Repr                   _Log__ReprPrototype
+	Logs                        _Logs__Prototype
+	Logs__Repr                  _Logs__ReprPrototype
+	OpCode                      _OpCode__Prototype
+	OpCode__Repr                _OpCode__ReprPrototype
+	Receipt                     _Receipt__Prototype
+	Receipt__Repr               _Receipt__ReprPrototype
+	Receipts                    _Receipts__Prototype
+	Receipts__Repr              _Receipts__ReprPrototype
+	Request                     _Request__Prototype
+	Request__Repr               _Request__ReprPrototype
+	Requests                    _Requests__Prototype
+	Requests__Repr              _Requests__ReprPrototype
+	StorageKeys                 _StorageKeys__Prototype
+	StorageKeys__Repr           _StorageKeys__ReprPrototype
+	String                      _String__Prototype
+	String__Repr                _String__ReprPrototype
+	Time                        _Time__Prototype
+	Time__Repr                  _Time__ReprPrototype
+	Topics                      _Topics__Prototype
+	Topics__Repr                _Topics__ReprPrototype
+	Transaction                 _Transaction__Prototype
+	Transaction__Repr           _Transaction__ReprPrototype
+	Transactions                _Transactions__Prototype
+	Transactions__Repr          _Transactions__ReprPrototype
+	TrieBranchNode              _TrieBranchNode__Prototype
+	TrieBranchNode__Repr        _TrieBranchNode__ReprPrototype
+	TrieExtensionNode           _TrieExtensionNode__Prototype
+	TrieExtensionNode__Repr     _TrieExtensionNode__ReprPrototype
+	TrieLeafNode                _TrieLeafNode__Prototype
+	TrieLeafNode__Repr          _TrieLeafNode__ReprPrototype
+	TrieNode                    _TrieNode__Prototype
+	TrieNode__Repr              _TrieNode__ReprPrototype
+	TxCIDList                   _TxCIDList__Prototype
+	TxCIDList__Repr             _TxCIDList__ReprPrototype
+	TxTrace                     _TxTrace__Prototype
+	TxTrace__Repr               _TxTrace__ReprPrototype
+	TxType                      _TxType__Prototype
+	TxType__Repr                _TxType__ReprPrototype
+	Uint                        _Uint__Prototype
+	Uint__Repr                  _Uint__ReprPrototype
+	Uncles                      _Uncles__Prototype
+	Uncles__Repr                _Uncles__ReprPrototype
+	Value                       _Value__Prototype
+	Value__Repr                 _Value__ReprPrototype
+	Withdrawal                  _Withdrawal__Prototype
+	Withdrawal__Repr            _Withdrawal__ReprPrototype
+	WithdrawalRequest           _WithdrawalRequest__Prototype
+	WithdrawalRequest__Repr     _WithdrawalRequest__ReprPrototype
+	WithdrawalRequests          _WithdrawalRequests__Prototype
+	WithdrawalRequests__Repr    _WithdrawalRequests__ReprPrototype
+	Withdrawals                 _Withdrawals__Prototype
+	Withdrawals__Repr           _Withdrawals__ReprPrototype
 }
 
 // --- type definitions follow ---
@@ -126,6 +164,29 @@ type _Account struct {
 type Address = *_Address
 type _Address struct{ x []byte }
 
+// Addresses matches the IPLD Schema type "Addresses".  It has list kind.
+type Addresses = *_Addresses
+type _Addresses struct {
+	x []_Address
+}
+
+// Authorization matches the IPLD Schema type "Authorization".  It has Struct type-kind, and may be interrogated like map kind.
+type Authorization = *_Authorization
+type _Authorization struct {
+	ChainID _BigInt
+	Address _Address
+	Nonce   _Uint
+	YParity _Uint
+	R       _BigInt
+	S       _BigInt
+}
+
+// AuthorizationList matches the IPLD Schema type "AuthorizationList".  It has list kind.
+type AuthorizationList = *_AuthorizationList
+type _AuthorizationList struct {
+	x []_Authorization
+}
+
 // Balance matches the IPLD Schema type "Balance".  It has bytes kind.
 type Balance = *_Balance
 type _Balance struct{ x []byte }
@@ -134,6 +195,26 @@ type _Balance struct{ x []byte }
 type BigInt = *_BigInt
 type _BigInt struct{ x []byte }
 
+// BlobHashes matches the IPLD Schema type "BlobHashes".  It has list kind.
+type BlobHashes = *_BlobHashes
+type _BlobHashes struct {
+	x []_Hash
+}
+
+// BlobSidecar matches the IPLD Schema type "BlobSidecar".  It has Struct type-kind, and may be interrogated like map kind.
+type BlobSidecar = *_BlobSidecar
+type _BlobSidecar struct {
+	Blobs       _Blobs
+	Commitments _KZGCommitments
+	Proofs      _KZGProofs
+}
+
+// Blobs matches the IPLD Schema type "Blobs".  It has list kind.
+type Blobs = *_Blobs
+type _Blobs struct {
+	x []_Bytes
+}
+
 // Block matches the IPLD Schema type "Block".  It has Struct type-kind, and may be interrogated like map kind.
 type Block = *_Block
 type _Block struct {
@@ -173,6 +254,45 @@ type _Child__iface interface {
 func (_Link) _Child__member()     {}
 func (_TrieNode) _Child__member() {}
 
+// CliqueExtra matches the IPLD Schema type "CliqueExtra".  It has Struct type-kind, and may be interrogated like map kind.
+type CliqueExtra = *_CliqueExtra
+type _CliqueExtra struct {
+	Vanity  _Bytes
+	Signers _Addresses__Maybe
+	Seal    _Bytes
+	Signer  _Address
+}
+
+// ConsolidationRequest matches the IPLD Schema type "ConsolidationRequest".  It has Struct type-kind, and may be interrogated like map kind.
+type ConsolidationRequest = *_ConsolidationRequest
+type _ConsolidationRequest struct {
+	SourceAddress _Address
+	SourcePubkey  _Bytes
+	TargetPubkey  _Bytes
+}
+
+// ConsolidationRequests matches the IPLD Schema type "ConsolidationRequests".  It has list kind.
+type ConsolidationRequests = *_ConsolidationRequests
+type _ConsolidationRequests struct {
+	x []_ConsolidationRequest
+}
+
+// DepositRequest matches the IPLD Schema type "DepositRequest".  It has Struct type-kind, and may be interrogated like map kind.
+type DepositRequest = *_DepositRequest
+type _DepositRequest struct {
+	Pubkey                _Bytes
+	WithdrawalCredentials _Hash
+	Amount                _Uint
+	Signature             _Bytes
+	Index                 _Uint
+}
+
+// DepositRequests matches the IPLD Schema type "DepositRequests".  It has list kind.
+type DepositRequests = *_DepositRequests
+type _DepositRequests struct {
+	x []_DepositRequest
+}
+
 // Frame matches the IPLD Schema type "Frame".  It has Struct type-kind, and may be interrogated like map kind.
 type Frame = *_Frame
 type _Frame struct {
@@ -199,22 +319,39 @@ type _Hash struct{ x []byte }
 // Header matches the IPLD Schema type "Header".  It has Struct type-kind, and may be interrogated like map kind.
 type Header = *_Header
 type _Header struct {
-	ParentCID    _Link
-	UnclesCID    _Link
-	Coinbase     _Address
-	StateRootCID _Link
-	TxRootCID    _Link
-	RctRootCID   _Link
-	Bloom        _Bloom
-	Difficulty   _BigInt
-	Number       _BigInt
-	GasLimit     _Uint
-	GasUsed      _Uint
-	Time         _Time
-	Extra        _Bytes
-	MixDigest    _Hash
-	Nonce        _Uint
-	BaseFee      _BigInt__Maybe
+	ParentCID           _Link
+	UnclesCID           _Link
+	Coinbase            _Address
+	StateRootCID        _Link
+	TxRootCID           _Link
+	RctRootCID          _Link
+	Bloom               _Bloom
+	Difficulty          _BigInt
+	Number              _BigInt
+	GasLimit            _Uint
+	GasUsed             _Uint
+	Time                _Time
+	Extra               _Bytes
+	MixDigest           _Hash
+	Nonce               _Uint
+	BaseFee             _BigInt__Maybe
+	WithdrawalsRootCID  _Link__Maybe
+	BlobGasUsed         _Uint__Maybe
+	ExcessBlobGas       _Uint__Maybe
+	ParentBeaconRootCID _Link__Maybe
+	RequestsCID         _Link__Maybe
+}
+
+// KZGCommitments matches the IPLD Schema type "KZGCommitments".  It has list kind.
+type KZGCommitments = *_KZGCommitments
+type _KZGCommitments struct {
+	x []_Bytes
+}
+
+// KZGProofs matches the IPLD Schema type "KZGProofs".  It has list kind.
+type KZGProofs = *_KZGProofs
+type _KZGProofs struct {
+	x []_Bytes
 }
 
 // Link matches the IPLD Schema type "Link".  It has link kind.
@@ -243,7 +380,7 @@ type _OpCode struct{ x []byte }
 type Receipt = *_Receipt
 type _Receipt struct {
 	TxType            _TxType
-	PostState         _Bytes__Maybe
+	PostState         _Hash__Maybe
 	Status            _Uint__Maybe
 	CumulativeGasUsed _Uint
 	Bloom             _Bloom
@@ -257,6 +394,21 @@ type _Receipts struct {
 	x []_Receipt
 }
 
+// Request matches the IPLD Schema type "Request".  It has Struct type-kind, and may be interrogated like map kind.
+type Request = *_Request
+type _Request struct {
+	RequestType    _Uint
+	Deposits       _DepositRequests__Maybe
+	Withdrawals    _WithdrawalRequests__Maybe
+	Consolidations _ConsolidationRequests__Maybe
+}
+
+// Requests matches the IPLD Schema type "Requests".  It has list kind.
+type Requests = *_Requests
+type _Requests struct {
+	x []_Link
+}
+
 // StorageKeys matches the IPLD Schema type "StorageKeys".  It has list kind.
 type StorageKeys = *_StorageKeys
 type _StorageKeys struct {
@@ -280,20 +432,24 @@ type _Topics struct {
 // Transaction matches the IPLD Schema type "Transaction".  It has Struct type-kind, and may be interrogated like map kind.
 type Transaction = *_Transaction
 type _Transaction struct {
-	TxType       _TxType
-	ChainID      _BigInt__Maybe
-	AccountNonce _Uint
-	GasPrice     _BigInt__Maybe
-	GasTipCap    _BigInt__Maybe
-	GasFeeCap    _BigInt__Maybe
-	GasLimit     _Uint
-	Recipient    _Address__Maybe
-	Amount       _BigInt
-	Data         _Bytes
-	AccessList   _AccessList__Maybe
-	V            _BigInt
-	R            _BigInt
-	S            _BigInt
+	TxType              _TxType
+	ChainID             _BigInt__Maybe
+	AccountNonce        _Uint
+	GasPrice            _BigInt__Maybe
+	GasTipCap           _BigInt__Maybe
+	GasFeeCap           _BigInt__Maybe
+	GasLimit            _Uint
+	Recipient           _Address__Maybe
+	Amount              _BigInt
+	Data                _Bytes
+	AccessList          _AccessList__Maybe
+	MaxFeePerBlobGas    _BigInt__Maybe
+	BlobVersionedHashes _BlobHashes__Maybe
+	AuthorizationList   _AuthorizationList__Maybe
+	V                   _BigInt
+	R                   _BigInt
+	S                   _BigInt
+	From                _Address__Maybe
 }
 
 // Transactions matches the IPLD Schema type "Transactions".  It has list kind.
@@ -328,7 +484,7 @@ type _TrieBranchNode struct {
 type TrieExtensionNode = *_TrieExtensionNode
 type _TrieExtensionNode struct {
 	PartialPath _Bytes
-	Child       _Link
+	Child       _Child__Maybe
 }
 
 // TrieLeafNode matches the IPLD Schema type "TrieLeafNode".  It has Struct type-kind, and may be interrogated like map kind.
@@ -396,6 +552,7 @@ type _Value struct {
 	x3  _Account
 	x4  _Bytes
 	x5  _Log
+	x6  _Withdrawal
 }
 type _Value__iface interface {
 	_Value__member()
@@ -406,3 +563,33 @@ func (_Receipt) _Value__member()     {}
 func (_Account) _Value__member()     {}
 func (_Bytes) _Value__member()       {}
 func (_Log) _Value__member()         {}
+func (_Withdrawal) _Value__member()  {}
+
+// Withdrawal matches the IPLD Schema type "Withdrawal".  It has Struct type-kind, and may be interrogated like map kind.
+type Withdrawal = *_Withdrawal
+type _Withdrawal struct {
+	Index          _Uint
+	ValidatorIndex _Uint
+	Address        _Address
+	Amount         _Uint
+}
+
+// WithdrawalRequest matches the IPLD Schema type "WithdrawalRequest".  It has Struct type-kind, and may be interrogated like map kind.
+type WithdrawalRequest = *_WithdrawalRequest
+type _WithdrawalRequest struct {
+	SourceAddress   _Address
+	ValidatorPubkey _Bytes
+	Amount          _Uint
+}
+
+// WithdrawalRequests matches the IPLD Schema type "WithdrawalRequests".  It has list kind.
+type WithdrawalRequests = *_WithdrawalRequests
+type _WithdrawalRequests struct {
+	x []_WithdrawalRequest
+}
+
+// Withdrawals matches the IPLD Schema type "Withdrawals".  It has list kind.
+type Withdrawals = *_Withdrawals
+type _Withdrawals struct {
+	x []_Withdrawal
+}
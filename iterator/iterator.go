@@ -0,0 +1,230 @@
+/*
+Package iterator walks the leaves of a DAG-ETH trie depth first, in key order, loading trie nodes through a
+LinkSystem only as the walk reaches them. The nibble path of the last leaf is exposed as a cursor, so a long
+running iteration, e.g. over a full state trie, can be checkpointed and resumed later from the same root.
+*/
+package iterator
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/keys"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// Iterator walks the leaves of a trie in key order
+type Iterator struct {
+	lsys  ipld.LinkSystem
+	stack []pending
+	// after holds the nibble path the walk resumes after, until the first leaf past it is reached
+	after  []byte
+	cursor []byte
+
+	fetched bool
+	path    []byte
+	value   ipld.Node
+	err     error
+}
+
+// pending is a trie node yet to be walked, either embedded in its parent or behind a link
+type pending struct {
+	node ipld.Node
+	lnk  ipld.Link
+	path []byte
+}
+
+// New returns an Iterator over every leaf of the trie referenced by root
+func New(lsys ipld.LinkSystem, root ipld.Link) *Iterator {
+	return &Iterator{lsys: lsys, stack: []pending{{lnk: root}}}
+}
+
+// Resume returns an Iterator over the leaves of the trie referenced by root whose nibble path sorts after the cursor,
+// as returned by Cursor. Subtries that sort entirely before the cursor are skipped without being loaded.
+func Resume(lsys ipld.LinkSystem, root ipld.Link, cursor []byte) (*Iterator, error) {
+	for i, nibble := range cursor {
+		if nibble > 0x0f {
+			return nil, fmt.Errorf("cursor byte %d (%#x) is not a nibble", i, nibble)
+		}
+	}
+	it := New(lsys, root)
+	it.after = append([]byte{}, cursor...)
+	it.cursor = it.after
+	return it, nil
+}
+
+// Done returns whether every leaf has been returned by Next.
+// It loads trie nodes up to the next leaf, and returns false if that fails so the error is returned by Next.
+func (it *Iterator) Done() bool {
+	it.fetch()
+	return it.path == nil && it.err == nil
+}
+
+// Next returns the key of the next leaf and the member of its Value union, e.g. the Account of a state trie leaf.
+// It returns ipld.ErrIteratorOverread once every leaf has been returned.
+func (it *Iterator) Next() ([]byte, ipld.Node, error) {
+	it.fetch()
+	if it.err != nil {
+		return nil, nil, it.err
+	}
+	if it.path == nil {
+		return nil, nil, ipld.ErrIteratorOverread{}
+	}
+	path, value := it.path, it.value
+	it.fetched, it.path, it.value = false, nil, nil
+	it.cursor = path
+	key, err := keys.NibblesToKeybytes(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, value, nil
+}
+
+// Cursor returns the nibble path of the leaf last returned by Next, or the cursor the Iterator was resumed from
+// if Next has not returned a leaf since. Resuming from it continues the walk with the leaf after it.
+func (it *Iterator) Cursor() []byte {
+	return append([]byte{}, it.cursor...)
+}
+
+// fetch advances the walk to the next leaf, unless it already holds one
+func (it *Iterator) fetch() {
+	if it.fetched || it.err != nil {
+		return
+	}
+	it.fetched = true
+	for len(it.stack) > 0 {
+		p := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+		node := p.node
+		if node == nil {
+			if node, it.err = it.lsys.Load(ipld.LinkContext{}, p.lnk, dageth.Type.TrieNode); it.err != nil {
+				return
+			}
+		}
+		var val ipld.Node
+		var valPath []byte
+		if val, valPath, it.err = it.push(node, p.path); it.err != nil {
+			return
+		}
+		if val == nil {
+			continue
+		}
+		if it.after != nil {
+			if bytes.Compare(valPath, it.after) <= 0 {
+				continue
+			}
+			// leaves are reached in key order, so every leaf from here on sorts after the cursor
+			it.after = nil
+		}
+		it.path = valPath
+		it.value, _, it.err = trie.ValueAndKind(val)
+		return
+	}
+}
+
+// skip returns whether every path under the subtrie at the path sorts before the cursor being resumed after
+func (it *Iterator) skip(path []byte) bool {
+	if it.after == nil {
+		return false
+	}
+	if len(path) > len(it.after) {
+		return bytes.Compare(path[:len(it.after)], it.after) < 0
+	}
+	return bytes.Compare(path, it.after[:len(path)]) < 0
+}
+
+// push stacks the children of the trie node so that they are walked in key order,
+// and returns the value the node holds, if any, along with the full path of that value
+func (it *Iterator) push(node ipld.Node, path []byte) (ipld.Node, []byte, error) {
+	n, kind, err := trie.NodeAndKind(node)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch kind {
+	case trie.BRANCH_NODE:
+		for i := 15; i >= 0; i-- {
+			childPath := append(append([]byte{}, path...), byte(i))
+			if it.skip(childPath) {
+				break
+			}
+			child, err := n.LookupByString(fmt.Sprintf("Child%X", i))
+			if err != nil {
+				return nil, nil, err
+			}
+			if child.IsNull() {
+				continue
+			}
+			if linkNode, err := child.LookupByString("Link"); err == nil {
+				lnk, err := linkNode.AsLink()
+				if err != nil {
+					return nil, nil, err
+				}
+				it.stack = append(it.stack, pending{lnk: lnk, path: childPath})
+				continue
+			}
+			// nodes smaller than 32 bytes are included directly in their parent branch
+			embedded, err := child.LookupByString("TrieNode")
+			if err != nil {
+				return nil, nil, fmt.Errorf("branch node child needs to be a link or a trie node: %v", err)
+			}
+			it.stack = append(it.stack, pending{node: embedded, path: childPath})
+		}
+		val, err := n.LookupByString("Value")
+		if err != nil || val.IsNull() {
+			return nil, nil, err
+		}
+		return val, path, nil
+	case trie.EXTENSION_NODE:
+		partialPath, err := partialPathOf(n)
+		if err != nil {
+			return nil, nil, err
+		}
+		childPath := append(append([]byte{}, path...), partialPath...)
+		if it.skip(childPath) {
+			return nil, nil, nil
+		}
+		child, err := n.LookupByString("Child")
+		if err != nil {
+			return nil, nil, err
+		}
+		if linkNode, err := child.LookupByString("Link"); err == nil {
+			lnk, err := linkNode.AsLink()
+			if err != nil {
+				return nil, nil, err
+			}
+			it.stack = append(it.stack, pending{lnk: lnk, path: childPath})
+			return nil, nil, nil
+		}
+		// nodes smaller than 32 bytes are included directly in their parent
+		embedded, err := child.LookupByString("TrieNode")
+		if err != nil {
+			return nil, nil, fmt.Errorf("extension node child needs to be a link or a trie node: %v", err)
+		}
+		it.stack = append(it.stack, pending{node: embedded, path: childPath})
+		return nil, nil, nil
+	case trie.LEAF_NODE:
+		partialPath, err := partialPathOf(n)
+		if err != nil {
+			return nil, nil, err
+		}
+		// leaf partial paths carry the terminator flag in the hex representation
+		if len(partialPath) > 0 && partialPath[len(partialPath)-1] == 16 {
+			partialPath = partialPath[:len(partialPath)-1]
+		}
+		val, err := n.LookupByString("Value")
+		return val, append(append([]byte{}, path...), partialPath...), err
+	default:
+		return nil, nil, fmt.Errorf("unrecognized trie node type %s", kind.String())
+	}
+}
+
+func partialPathOf(node ipld.Node) ([]byte, error) {
+	ppNode, err := node.LookupByString("PartialPath")
+	if err != nil {
+		return nil, err
+	}
+	return ppNode.AsBytes()
+}
@@ -0,0 +1,125 @@
+package iterator_test
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/helpers"
+	"github.com/vulcanize/go-codec-dageth/iterator"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+// drain returns the keys left to the iterator
+func drain(t *testing.T, it *iterator.Iterator) []string {
+	var keys []string
+	for !it.Done() {
+		key, val, err := it.Next()
+		if err != nil {
+			t.Fatalf("unable to iterate trie: %v", err)
+		}
+		if _, err := val.AsBytes(); err != nil {
+			t.Fatalf("iterated value is not a storage value: %v", err)
+		}
+		keys = append(keys, string(key))
+	}
+	if _, _, err := it.Next(); err == nil {
+		t.Error("expected an error iterating past the last leaf")
+	}
+	return keys
+}
+
+func TestIterator(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	// short values leave some leaves embedded in their parent branches
+	kvs := make(map[string][]byte, 600)
+	keys := make([]string, 0, 600)
+	for i := 0; i < 600; i++ {
+		k := string(shared.RandomHash().Bytes())
+		kvs[k], _ = rlp.EncodeToBytes(shared.RandomBytes(1 + i%40))
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	root := testutil.CommitTrie(t, lsys, storage_trie.MultiCodecType, kvs)
+
+	it := iterator.New(lsys, root)
+	if cursor := it.Cursor(); len(cursor) != 0 {
+		t.Errorf("expected an empty cursor before the first leaf, got %x", cursor)
+	}
+	var seen []string
+	var cursor []byte
+	for len(seen) < 250 {
+		key, _, err := it.Next()
+		if err != nil {
+			t.Fatalf("unable to iterate trie: %v", err)
+		}
+		seen = append(seen, string(key))
+		cursor = it.Cursor()
+	}
+	if !bytes.Equal(cursor, helpers.KeyToNibbles([]byte(seen[len(seen)-1]))) {
+		t.Errorf("cursor (%x) is not the nibble path of the last key (%x)", cursor, seen[len(seen)-1])
+	}
+	seen = append(seen, drain(t, it)...)
+	if len(seen) != len(keys) {
+		t.Fatalf("expected %d keys, got %d", len(keys), len(seen))
+	}
+	for i := range keys {
+		if seen[i] != keys[i] {
+			t.Fatalf("key %d (%x) does not match expected key (%x)", i, seen[i], keys[i])
+		}
+	}
+
+	// resuming from the checkpoint continues with the leaf after it, skipping the subtries before it
+	loads := 0
+	countingLsys := codecs.NewLinkSystem(store)
+	countingLsys.StorageReadOpener = func(lnkCtx ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+		loads++
+		return store.OpenRead(lnkCtx, lnk)
+	}
+	resumed, err := iterator.Resume(countingLsys, root, cursor)
+	if err != nil {
+		t.Fatalf("unable to resume iterator: %v", err)
+	}
+	if rest := drain(t, resumed); len(rest) != len(keys)-250 || rest[0] != keys[250] {
+		t.Errorf("expected the resumed iterator to return the %d keys after the cursor", len(keys)-250)
+	}
+	full := 0
+	countingLsys.StorageReadOpener = func(lnkCtx ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+		full++
+		return store.OpenRead(lnkCtx, lnk)
+	}
+	drain(t, iterator.New(countingLsys, root))
+	if loads >= full {
+		t.Errorf("expected the resumed iterator to load fewer nodes (%d) than a full iteration (%d)", loads, full)
+	}
+
+	// a partial nibble path sorts before the leaves under it, so the resumed iterator includes them
+	prefix := helpers.KeyToNibbles([]byte(keys[300]))[:2]
+	resumed, err = iterator.Resume(lsys, root, prefix)
+	if err != nil {
+		t.Fatalf("unable to resume iterator: %v", err)
+	}
+	rest := drain(t, resumed)
+	expected := 0
+	for _, k := range keys {
+		if bytes.Compare(helpers.KeyToNibbles([]byte(k)), prefix) > 0 {
+			expected++
+		}
+	}
+	if len(rest) != expected {
+		t.Errorf("expected %d keys after cursor %x, got %d", expected, prefix, len(rest))
+	}
+
+	if _, err := iterator.Resume(lsys, root, []byte{0x01, 0x10}); err == nil {
+		t.Error("expected an error resuming from a cursor that is not a nibble path")
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Package keys converts between the representations of the keys and paths of Ethereum Merkle Patricia tries: trie
+keys as bytes, nibble paths holding one nibble per byte, and the hex-prefix (compact) encoding extension and leaf
+nodes carry their partial paths in, and compares nibble paths by their prefixes, for consumers navigating tries.
+*/
+package keys
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrEmptyCompact is returned for compact paths without the byte holding their hex prefix flag
+	ErrEmptyCompact = errors.New("empty compact path")
+	// ErrInvalidFlag is returned for compact paths whose hex prefix flag is not one of 0 to 3
+	ErrInvalidFlag = errors.New("invalid hex prefix flag")
+	// ErrNonZeroPadding is returned for compact paths of an even number of nibbles whose padding nibble is set
+	ErrNonZeroPadding = errors.New("non-zero hex prefix padding nibble")
+	// ErrInvalidNibble is returned for nibble paths holding a byte greater than 0x0f
+	ErrInvalidNibble = errors.New("invalid nibble")
+	// ErrOddLength is returned for nibble paths of an odd length that are converted into keys
+	ErrOddLength = errors.New("odd length nibble path")
+)
+
+const (
+	// flagLeaf is set in the hex prefix flag of the partial paths of leaf nodes
+	flagLeaf = 2
+	// flagOdd is set in the hex prefix flag of partial paths of an odd number of nibbles
+	flagOdd = 1
+)
+
+// KeybytesToNibbles splits the trie key into its nibble path, the high nibble of each byte first
+func KeybytesToNibbles(key []byte) []byte {
+	nibbles := make([]byte, len(key)*2)
+	for i, b := range key {
+		nibbles[i*2] = b >> 4
+		nibbles[i*2+1] = b & 0x0f
+	}
+	return nibbles
+}
+
+// NibblesToKeybytes packs the nibble path back into the trie key, it returns an error for paths of an odd length
+// or holding bytes that are not nibbles
+func NibblesToKeybytes(nibbles []byte) ([]byte, error) {
+	if len(nibbles)%2 != 0 {
+		return nil, fmt.Errorf("%w: %d nibbles", ErrOddLength, len(nibbles))
+	}
+	if err := CheckNibbles(nibbles); err != nil {
+		return nil, err
+	}
+	key := make([]byte, len(nibbles)/2)
+	for i := range key {
+		key[i] = nibbles[2*i]<<4 | nibbles[2*i+1]
+	}
+	return key, nil
+}
+
+// NibblesToCompact encodes the partial path of a leaf or extension node into its compact encoding: the hex prefix
+// flag, whether the node is a leaf and whether the path has an odd number of nibbles, in the high nibble of the
+// first byte, followed by the nibbles, the first of an odd length path in the low nibble of the first byte
+func NibblesToCompact(nibbles []byte, leaf bool) ([]byte, error) {
+	if err := CheckNibbles(nibbles); err != nil {
+		return nil, err
+	}
+	var flag byte
+	if leaf {
+		flag |= flagLeaf
+	}
+	compact := make([]byte, len(nibbles)/2+1)
+	if len(nibbles)%2 == 1 {
+		flag |= flagOdd
+		compact[0] = nibbles[0]
+		nibbles = nibbles[1:]
+	}
+	compact[0] |= flag << 4
+	for i := 0; i < len(nibbles); i += 2 {
+		compact[i/2+1] = nibbles[i]<<4 | nibbles[i+1]
+	}
+	return compact, nil
+}
+
+// CompactToNibbles decodes the compact encoding of a partial path into its nibbles, and reports whether it is the
+// path of a leaf node. It returns an error wrapping ErrEmptyCompact, ErrInvalidFlag or ErrNonZeroPadding for input
+// that is not a compact path.
+func CompactToNibbles(compact []byte) ([]byte, bool, error) {
+	if err := CheckCompact(compact); err != nil {
+		return nil, false, err
+	}
+	flag := compact[0] >> 4
+	nibbles := make([]byte, 0, len(compact)*2-1)
+	if flag&flagOdd != 0 {
+		nibbles = append(nibbles, compact[0]&0x0f)
+	}
+	for _, b := range compact[1:] {
+		nibbles = append(nibbles, b>>4, b&0x0f)
+	}
+	return nibbles, flag&flagLeaf != 0, nil
+}
+
+// CheckCompact checks that the input is the compact encoding of a partial path: that it holds the hex prefix flag,
+// that the flag is one of 0 to 3, and that the padding nibble of paths of an even number of nibbles is zero
+func CheckCompact(compact []byte) error {
+	if len(compact) == 0 {
+		return ErrEmptyCompact
+	}
+	flag := compact[0] >> 4
+	if flag > flagLeaf|flagOdd {
+		return fmt.Errorf("%w %d", ErrInvalidFlag, flag)
+	}
+	if flag&flagOdd == 0 && compact[0]&0x0f != 0 {
+		return fmt.Errorf("%w %#x", ErrNonZeroPadding, compact[0]&0x0f)
+	}
+	return nil
+}
+
+// CheckNibbles checks that every byte of the path holds a single nibble
+func CheckNibbles(nibbles []byte) error {
+	for i, b := range nibbles {
+		if b > 0x0f {
+			return fmt.Errorf("%w %#x at %d", ErrInvalidNibble, b, i)
+		}
+	}
+	return nil
+}
+
+// HasPrefix reports whether the nibble path begins with the prefix
+func HasPrefix(nibbles, prefix []byte) bool {
+	return bytes.HasPrefix(nibbles, prefix)
+}
+
+// CommonPrefixLength returns the number of leading nibbles the nibble paths share
+func CommonPrefixLength(a, b []byte) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// Compare orders nibble paths the way the leaves of a trie are ordered, returning -1 if a sorts before b, 1 if it
+// sorts after b and 0 if they are equal; a path sorts before the paths it is a prefix of
+func Compare(a, b []byte) int {
+	return bytes.Compare(a, b)
+}
@@ -0,0 +1,128 @@
+package keys_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/vulcanize/go-codec-dageth/keys"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+func TestCompact(t *testing.T) {
+	// the examples of the hex-prefix encoding in the yellow paper and the go-ethereum trie
+	for _, test := range []struct {
+		nibbles []byte
+		leaf    bool
+		compact []byte
+	}{
+		{[]byte{}, false, []byte{0x00}},
+		{[]byte{}, true, []byte{0x20}},
+		{[]byte{1, 2, 3, 4, 5}, false, []byte{0x11, 0x23, 0x45}},
+		{[]byte{0, 1, 2, 3, 4, 5}, false, []byte{0x00, 0x01, 0x23, 0x45}},
+		{[]byte{0, 15, 1, 12, 11, 8}, true, []byte{0x20, 0x0f, 0x1c, 0xb8}},
+		{[]byte{15, 1, 12, 11, 8}, true, []byte{0x3f, 0x1c, 0xb8}},
+	} {
+		compact, err := keys.NibblesToCompact(test.nibbles, test.leaf)
+		if err != nil {
+			t.Fatalf("unable to encode %x: %v", test.nibbles, err)
+		}
+		if !bytes.Equal(compact, test.compact) {
+			t.Errorf("expected %x to encode into %x, got %x", test.nibbles, test.compact, compact)
+		}
+		nibbles, leaf, err := keys.CompactToNibbles(test.compact)
+		if err != nil {
+			t.Fatalf("unable to decode %x: %v", test.compact, err)
+		}
+		if !bytes.Equal(nibbles, test.nibbles) || leaf != test.leaf {
+			t.Errorf("expected %x to decode into %x (leaf %v), got %x (leaf %v)", test.compact, test.nibbles, test.leaf, nibbles, leaf)
+		}
+	}
+}
+
+func TestCompactMatchesHex(t *testing.T) {
+	// the nibble paths convert like the hex paths used by the codecs, which terminate leaf paths with 16
+	for i := 0; i < 64; i++ {
+		nibbles := keys.KeybytesToNibbles(shared.RandomBytes(1 + i%33))[i%2:]
+		for _, leaf := range []bool{false, true} {
+			hex := append([]byte{}, nibbles...)
+			if leaf {
+				hex = append(hex, 16)
+			}
+			compact, err := keys.NibblesToCompact(nibbles, leaf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := shared.HexToCompact(hex); !bytes.Equal(compact, expected) {
+				t.Fatalf("expected %x to encode into %x, got %x", hex, expected, compact)
+			}
+			if expected := shared.CompactToHex(compact); !bytes.Equal(expected, hex) {
+				t.Fatalf("expected %x to decode into %x, got %x", compact, hex, expected)
+			}
+		}
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	for _, test := range []struct {
+		compact []byte
+		err     error
+	}{
+		{nil, keys.ErrEmptyCompact},
+		{[]byte{}, keys.ErrEmptyCompact},
+		{[]byte{0x40}, keys.ErrInvalidFlag},
+		{[]byte{0xf1, 0x23}, keys.ErrInvalidFlag},
+		{[]byte{0x01, 0x23}, keys.ErrNonZeroPadding},
+		{[]byte{0x2a}, keys.ErrNonZeroPadding},
+	} {
+		if _, _, err := keys.CompactToNibbles(test.compact); !errors.Is(err, test.err) {
+			t.Errorf("expected decoding %x to fail with %v, got %v", test.compact, test.err, err)
+		}
+	}
+	if _, err := keys.NibblesToCompact([]byte{1, 16}, true); !errors.Is(err, keys.ErrInvalidNibble) {
+		t.Errorf("expected encoding a terminated hex path to fail with %v, got %v", keys.ErrInvalidNibble, err)
+	}
+	if _, err := keys.NibblesToKeybytes([]byte{1, 2, 3}); !errors.Is(err, keys.ErrOddLength) {
+		t.Errorf("expected packing an odd length path to fail with %v, got %v", keys.ErrOddLength, err)
+	}
+	if _, err := keys.NibblesToKeybytes([]byte{1, 0x20}); !errors.Is(err, keys.ErrInvalidNibble) {
+		t.Errorf("expected packing an invalid nibble to fail with %v, got %v", keys.ErrInvalidNibble, err)
+	}
+}
+
+func TestKeybytes(t *testing.T) {
+	key := []byte{0x12, 0xab, 0x0f}
+	nibbles := keys.KeybytesToNibbles(key)
+	if !bytes.Equal(nibbles, []byte{1, 2, 10, 11, 0, 15}) {
+		t.Errorf("unexpected nibbles %x", nibbles)
+	}
+	back, err := keys.NibblesToKeybytes(nibbles)
+	if err != nil || !bytes.Equal(back, key) {
+		t.Errorf("expected %x to pack back into %x, got %x and %v", nibbles, key, back, err)
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	a, b := []byte{1, 2, 3, 4}, []byte{1, 2, 5}
+	if n := keys.CommonPrefixLength(a, b); n != 2 {
+		t.Errorf("expected a common prefix of 2 nibbles, got %d", n)
+	}
+	if !keys.HasPrefix(a, a[:3]) || keys.HasPrefix(a[:3], a) || !keys.HasPrefix(a, nil) {
+		t.Error("unexpected prefix checks")
+	}
+	for _, test := range []struct {
+		a, b []byte
+		cmp  int
+	}{
+		{a, b, -1},
+		{b, a, 1},
+		{a[:2], a, -1},
+		{a, a[:2], 1},
+		{a, append([]byte{}, a...), 0},
+		{nil, nil, 0},
+	} {
+		if cmp := keys.Compare(test.a, test.b); cmp != test.cmp {
+			t.Errorf("expected comparing %x with %x to give %d, got %d", test.a, test.b, test.cmp, cmp)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+/*
+Package log is the eth-log codec of receipt logs.
+
+Logs decode into a typed Address and a Topics list of Hashes. MatchEvent and MatchABIEvent match log nodes against
+event signatures, whose IDs EventID computes, and UnpackEvent unpacks the arguments of an abi.Event from them.
+*/
+package log
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipld/go-ipld-prime"
+)
+
+// EventID returns the first topic of the logs of the event with the canonical signature,
+// e.g. "Transfer(address,address,uint256)"
+func EventID(signature string) common.Hash {
+	return crypto.Keccak256Hash([]byte(signature))
+}
+
+// MatchEvent returns whether the log node was emitted for the event with the ID, i.e. whether its first topic is the ID
+func MatchEvent(node ipld.Node, eventID common.Hash) (bool, error) {
+	topicsNode, err := node.LookupByString("Topics")
+	if err != nil {
+		return false, fmt.Errorf("log is missing a Topics node: %v", err)
+	}
+	if topicsNode.Length() == 0 {
+		return false, nil
+	}
+	topicNode, err := topicsNode.LookupByIndex(0)
+	if err != nil {
+		return false, err
+	}
+	topic, err := topicNode.AsBytes()
+	if err != nil {
+		return false, err
+	}
+	return common.BytesToHash(topic) == eventID, nil
+}
+
+// MatchABIEvent is like MatchEvent, for an event of a contract ABI and from the contract address, if not zero.
+// Anonymous events carry no ID topic, so they cannot be matched.
+func MatchABIEvent(node ipld.Node, event abi.Event, address common.Address) (bool, error) {
+	if event.Anonymous {
+		return false, fmt.Errorf("anonymous event %s has no ID topic to match", event.Name)
+	}
+	if address != (common.Address{}) {
+		addrNode, err := node.LookupByString("Address")
+		if err != nil {
+			return false, fmt.Errorf("log is missing an Address node: %v", err)
+		}
+		addr, err := addrNode.AsBytes()
+		if err != nil {
+			return false, err
+		}
+		if common.BytesToAddress(addr) != address {
+			return false, nil
+		}
+	}
+	return MatchEvent(node, event.ID)
+}
+
+// UnpackEvent unpacks the arguments of the event from the topics and data of the log node, by argument name.
+// Indexed arguments of dynamic types are unpacked as the hash of their value, as they are carried in the topics.
+func UnpackEvent(node ipld.Node, event abi.Event) (map[string]interface{}, error) {
+	log := new(types.Log)
+	if err := EncodeLog(log, node); err != nil {
+		return nil, err
+	}
+	topics := log.Topics
+	if !event.Anonymous {
+		if len(topics) == 0 || topics[0] != event.ID {
+			return nil, fmt.Errorf("log was not emitted for event %s", event.Sig)
+		}
+		topics = topics[1:]
+	}
+	out := make(map[string]interface{})
+	if err := event.Inputs.UnpackIntoMap(out, log.Data); err != nil {
+		return nil, fmt.Errorf("unable to unpack %s data: %v", event.Name, err)
+	}
+	var indexed abi.Arguments
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(out, indexed, topics); err != nil {
+		return nil, fmt.Errorf("unable to unpack %s topics: %v", event.Name, err)
+	}
+	return out, nil
+}
@@ -2,8 +2,11 @@ package log_test
 
 import (
 	"bytes"
+	"math/big"
+	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -109,3 +112,56 @@ func testLogEncoding(t *testing.T) {
 		t.Errorf("log encoding (%x) does not match the expected consensus encoding (%x)", logBytes, logEncoding)
 	}
 }
+
+const transferABI = `[{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"}]`
+
+func TestLogEvents(t *testing.T) {
+	contractABI, err := abi.JSON(strings.NewReader(transferABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer := contractABI.Events["Transfer"]
+	if id := log.EventID("Transfer(address,address,uint256)"); id != transfer.ID {
+		t.Fatalf("Transfer event ID %x does not match the ABI event ID %x", id, transfer.ID)
+	}
+	token := common.HexToAddress("0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48")
+	from, to := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	data, err := transfer.Inputs.NonIndexed().Pack(big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transferLog := &types.Log{
+		Address: token,
+		Topics:  []common.Hash{transfer.ID, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:    data,
+	}
+	enc, err := rlp.EncodeToBytes(transferLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb := dageth.Type.Log.NewBuilder()
+	if err := log.DecodeBytes(nb, enc); err != nil {
+		t.Fatalf("unable to decode transfer log: %v", err)
+	}
+	node := nb.Build()
+
+	if ok, err := log.MatchABIEvent(node, transfer, token); err != nil || !ok {
+		t.Errorf("expected the transfer log to match the Transfer event of the token (%v)", err)
+	}
+	if ok, _ := log.MatchABIEvent(node, transfer, from); ok {
+		t.Error("expected the transfer log not to match the Transfer event of another contract")
+	}
+	if ok, _ := log.MatchEvent(logNode, transfer.ID); ok {
+		t.Error("expected the mock log not to match the Transfer event")
+	}
+	args, err := log.UnpackEvent(node, transfer)
+	if err != nil {
+		t.Fatalf("unable to unpack transfer log: %v", err)
+	}
+	if args["from"] != from || args["to"] != to || args["value"].(*big.Int).Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("unexpected Transfer arguments %v", args)
+	}
+	if _, err := log.UnpackEvent(logNode, transfer); err == nil {
+		t.Error("expected unpacking the mock log as a Transfer event to fail")
+	}
+}
@@ -17,16 +17,7 @@ import (
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code TBD when this package is invoked via init.
 func Encode(node ipld.Node, w io.Writer) error {
-	// 1KiB can be allocated on the stack, and covers most small nodes
-	// without having to grow the buffer and cause allocations.
-	enc := make([]byte, 0, 1024)
-
-	enc, err := AppendEncode(enc, node)
-	if err != nil {
-		return err
-	}
-	_, err = w.Write(enc)
-	return err
+	return shared.WriteEncoded(w, node, AppendEncode)
 }
 
 // AppendEncode is like Encode, but it uses a destination buffer directly.
@@ -44,6 +35,30 @@ func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	return enc, nil
 }
 
+// EncodeOptions can be used to customize the behavior of log encoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type EncodeOptions struct{}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return Encode(node, w)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return AppendEncode(enc, inNode)
+}
+
 // EncodeLog packs the node into the go-ethereum Log
 func EncodeLog(log *types.Log, inNode ipld.Node) error {
 	// Wrap in a typed node for some basic schema form checking
@@ -75,7 +90,10 @@ func packAddress(log *types.Log, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	log.Address = common.BytesToAddress(addrBytes)
+	log.Address, err = shared.BytesToAddress(addrBytes)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -95,7 +113,10 @@ func packTopics(log *types.Log, node ipld.Node) error {
 		if err != nil {
 			return err
 		}
-		topics[topicIndex] = common.BytesToHash(topicBytes)
+		topics[topicIndex], err = shared.BytesToHash(topicBytes)
+		if err != nil {
+			return err
+		}
 	}
 	log.Topics = topics
 	return nil
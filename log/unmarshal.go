@@ -7,7 +7,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
 )
 
 // Decode provides an IPLD codec decode interface for eth log IPLDs.
@@ -38,6 +41,43 @@ func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
 	return DecodeLog(na, *log)
 }
 
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeOptions can be used to customize the behavior of log decoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type DecodeOptions struct{}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return Decode(na, in)
+}
+
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeBytes(na, src)
+}
+
 // DecodeLog unpacks a go-ethereum Log into the NodeAssembler
 func DecodeLog(na ipld.NodeAssembler, log types.Log) error {
 	ma, err := na.BeginMap(3)
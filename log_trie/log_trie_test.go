@@ -290,7 +290,11 @@ func verifyExtensionNodeContents(t *testing.T) {
 	if err != nil {
 		t.Fatalf("log trie extension node missing Child: %v", err)
 	}
-	childLink, err := childNode.AsLink()
+	childLinkNode, err := childNode.LookupByString("Link")
+	if err != nil {
+		t.Fatalf("log trie extension node Child should be of type Link: %v", err)
+	}
+	childLink, err := childLinkNode.AsLink()
 	if err != nil {
 		t.Fatalf("log trie extension node Child should be of kind Link: %v", err)
 	}
@@ -23,3 +23,13 @@ func Encode(node ipld.Node, w io.Writer) error {
 func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	return dageth_trie.AppendEncode(enc, inNode)
 }
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts dageth_trie.EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts dageth_trie.EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
@@ -3,8 +3,10 @@ package log_trie
 import (
 	"io"
 
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 
+	"github.com/vulcanize/go-codec-dageth/shared"
 	dageth_trie "github.com/vulcanize/go-codec-dageth/trie"
 )
 
@@ -23,3 +25,26 @@ func Decode(na ipld.NodeAssembler, in io.Reader) error {
 func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
 	return dageth_trie.DecodeTrieNodeBytes(na, src, MultiCodecType)
 }
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts dageth_trie.DecodeOptions) error {
+	return opts.DecodeTrieNode(na, in, MultiCodecType)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts dageth_trie.DecodeOptions) error {
+	return opts.DecodeTrieNodeBytes(na, src, MultiCodecType)
+}
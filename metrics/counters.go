@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+)
+
+// Counters is a Recorder keeping running totals of what is reported to it, which it serves over HTTP in the
+// Prometheus text exposition format, so a Prometheus server can scrape the progress of an operation
+type Counters struct {
+	bytes    atomic.Uint64
+	links    atomic.Uint64
+	maxDepth atomic.Int64
+
+	mu    sync.Mutex
+	nodes map[uint64]uint64
+}
+
+// Stats are the totals of a Counters
+type Stats struct {
+	// Nodes are the numbers of blocks decoded, by multicodec type
+	Nodes    map[uint64]uint64
+	Bytes    uint64
+	Links    uint64
+	MaxDepth int
+}
+
+// NewCounters returns Counters starting from zero
+func NewCounters() *Counters {
+	return &Counters{nodes: make(map[uint64]uint64)}
+}
+
+func (c *Counters) NodeDecoded(codec uint64) {
+	c.mu.Lock()
+	c.nodes[codec]++
+	c.mu.Unlock()
+}
+
+func (c *Counters) BytesRead(n int) {
+	c.bytes.Add(uint64(n))
+}
+
+func (c *Counters) LinkResolved(ipld.Link) {
+	c.links.Add(1)
+}
+
+func (c *Counters) DepthReached(depth int) {
+	for {
+		max := c.maxDepth.Load()
+		if int64(depth) <= max || c.maxDepth.CompareAndSwap(max, int64(depth)) {
+			return
+		}
+	}
+}
+
+// Stats returns the current totals
+func (c *Counters) Stats() Stats {
+	c.mu.Lock()
+	nodes := make(map[uint64]uint64, len(c.nodes))
+	for codec, n := range c.nodes {
+		nodes[codec] = n
+	}
+	c.mu.Unlock()
+	return Stats{Nodes: nodes, Bytes: c.bytes.Load(), Links: c.links.Load(), MaxDepth: int(c.maxDepth.Load())}
+}
+
+// WritePrometheus writes the current totals in the Prometheus text exposition format, with the nodes decoded
+// labeled by the name of their codec
+func (c *Counters) WritePrometheus(w io.Writer) error {
+	stats := c.Stats()
+	codecTypes := make([]uint64, 0, len(stats.Nodes))
+	for codec := range stats.Nodes {
+		codecTypes = append(codecTypes, codec)
+	}
+	sort.Slice(codecTypes, func(i, j int) bool { return codecTypes[i] < codecTypes[j] })
+	if _, err := fmt.Fprint(w, "# HELP dageth_nodes_decoded_total Blocks decoded, by codec.\n# TYPE dageth_nodes_decoded_total counter\n"); err != nil {
+		return err
+	}
+	for _, codec := range codecTypes {
+		name := fmt.Sprintf("%#x", codec)
+		if c, ok := codecs.Lookup(codec); ok {
+			name = c.Name
+		}
+		if _, err := fmt.Fprintf(w, "dageth_nodes_decoded_total{codec=%q} %d\n", name, stats.Nodes[codec]); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "# HELP dageth_bytes_read_total Bytes read from storage.\n# TYPE dageth_bytes_read_total counter\ndageth_bytes_read_total %d\n"+
+		"# HELP dageth_links_resolved_total Links opened in storage.\n# TYPE dageth_links_resolved_total counter\ndageth_links_resolved_total %d\n"+
+		"# HELP dageth_depth_reached Deepest block reached below the root, in links followed.\n# TYPE dageth_depth_reached gauge\ndageth_depth_reached %d\n",
+		stats.Bytes, stats.Links, stats.MaxDepth)
+	return err
+}
+
+// ServeHTTP serves the current totals in the Prometheus text exposition format
+func (c *Counters) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := c.WritePrometheus(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Package metrics instruments long-running operations over the DAG, such as state exports, walks and proof
+generation, which report the nodes they decode, the bytes they read, the links they resolve and the depth they
+reach into a Recorder, so operators can follow their progress. Counters is a Recorder that serves its totals in the
+Prometheus text exposition format.
+*/
+package metrics
+
+import (
+	"io"
+
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+)
+
+// Recorder receives the progress of an operation. Operations may report from several goroutines at once, so
+// implementations need to be safe for concurrent use.
+type Recorder interface {
+	// NodeDecoded is called for every block decoded, with its multicodec type
+	NodeDecoded(codec uint64)
+	// BytesRead is called with the number of bytes read from storage
+	BytesRead(n int)
+	// LinkResolved is called for every link opened in storage
+	LinkResolved(lnk ipld.Link)
+	// DepthReached is called with the depth of a block below the root of the operation, in links followed
+	DepthReached(depth int)
+}
+
+// Nop is a Recorder that discards everything reported to it
+type Nop struct{}
+
+func (Nop) NodeDecoded(uint64)     {}
+func (Nop) BytesRead(int)          {}
+func (Nop) LinkResolved(ipld.Link) {}
+func (Nop) DepthReached(int)       {}
+
+// OrNop returns the Recorder, or Nop if it is nil, so the options of operations can leave it unset
+func OrNop(rec Recorder) Recorder {
+	if rec == nil {
+		return Nop{}
+	}
+	return rec
+}
+
+// Instrument returns a copy of the LinkSystem that reports the links it opens and the bytes it reads from
+// storage, along with the blocks it decodes, into the Recorder
+func Instrument(lsys ipld.LinkSystem, rec Recorder) ipld.LinkSystem {
+	if rec == nil {
+		return lsys
+	}
+	if read := lsys.StorageReadOpener; read != nil {
+		lsys.StorageReadOpener = func(ctx ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+			r, err := read(ctx, lnk)
+			if err != nil {
+				return nil, err
+			}
+			rec.LinkResolved(lnk)
+			return &countingReader{r: r, rec: rec}, nil
+		}
+	}
+	if choose := lsys.DecoderChooser; choose != nil {
+		lsys.DecoderChooser = func(lnk ipld.Link) (ipld.Decoder, error) {
+			decode, err := choose(lnk)
+			if err != nil {
+				return nil, err
+			}
+			return func(na ipld.NodeAssembler, r io.Reader) error {
+				if err := decode(na, r); err != nil {
+					return err
+				}
+				if cl, ok := lnk.(cidlink.Link); ok {
+					rec.NodeDecoded(cl.Cid.Prefix().Codec)
+				}
+				return nil
+			}, nil
+		}
+	}
+	return lsys
+}
+
+// countingReader reports the bytes read through it
+type countingReader struct {
+	r   io.Reader
+	rec Recorder
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	if n > 0 {
+		cr.rec.BytesRead(n)
+	}
+	return n, err
+}
@@ -0,0 +1,80 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/metrics"
+	"github.com/vulcanize/go-codec-dageth/proof"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/walk"
+)
+
+func TestCounters(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	root, keys := testutil.BuildStorageTrie(t, lsys, 300)
+	var size uint64
+	for _, data := range store.Bag {
+		size += uint64(len(data))
+	}
+
+	counters := metrics.NewCounters()
+	maxDepth := 0
+	if err := (walk.Options{Metrics: counters}).Walk(lsys, root, func(ipld.Link, ipld.Node) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("unable to walk: %v", err)
+	}
+	stats := counters.Stats()
+	blocks := uint64(len(store.Bag))
+	if stats.Links != blocks || stats.Bytes != size || stats.Nodes[storage_trie.MultiCodecType] != blocks {
+		t.Errorf("expected the walk to report %d blocks of %d bytes, got %+v", blocks, size, stats)
+	}
+	for _, key := range keys {
+		p, err := proof.GenerateProof(lsys, root, []byte(key))
+		if err != nil {
+			t.Fatalf("unable to generate proof: %v", err)
+		}
+		if len(p)-1 > maxDepth {
+			maxDepth = len(p) - 1
+		}
+	}
+	if stats.MaxDepth != maxDepth {
+		t.Errorf("expected the walk to reach depth %d, got %d", maxDepth, stats.MaxDepth)
+	}
+
+	// a proof reports the nodes on its path
+	counters = metrics.NewCounters()
+	p, err := (proof.GenerateOptions{Metrics: counters}).GenerateProof(lsys, root, crypto.Keccak256([]byte("missing")))
+	if err != nil {
+		t.Fatalf("unable to generate proof: %v", err)
+	}
+	if stats := counters.Stats(); stats.Links != uint64(len(p)) || stats.MaxDepth != len(p)-1 {
+		t.Errorf("expected a proof of %d nodes to report as many links, got %+v", len(p), stats)
+	}
+
+	rec := httptest.NewRecorder()
+	counters.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE dageth_nodes_decoded_total counter",
+		`dageth_nodes_decoded_total{codec="storage_trie"} `,
+		"dageth_links_resolved_total ",
+		"# TYPE dageth_depth_reached gauge",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected the exposition to contain %q, got:\n%s", line, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+}
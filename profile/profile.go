@@ -0,0 +1,168 @@
+/*
+Package profile describes the fork schedules of the chains the codecs decode blocks of, so the field layouts of a
+header or receipt can be chosen from the forks active at its block rather than inferred from the number of fields it
+carries, which is ambiguous for custom chains that append fields of their own or skip forks.
+*/
+package profile
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Profile is the fork schedule of a chain, the block numbers and timestamps at which the forks that change the
+// layouts of headers and receipts activate. A nil fork block or time means the fork is not scheduled.
+type Profile struct {
+	Name    string
+	ChainID *big.Int
+	Genesis common.Hash
+
+	ByzantiumBlock *big.Int // receipts carry a Status instead of a PostState
+	BerlinBlock    *big.Int // EIP-2718 typed receipts
+	LondonBlock    *big.Int // headers carry a BaseFee, dynamic fee receipts
+
+	ShanghaiTime *uint64 // headers carry a WithdrawalsRootCID
+	CancunTime   *uint64 // headers carry the blob gas fields and the ParentBeaconRootCID, blob receipts
+	PragueTime   *uint64 // headers carry a RequestsCID, set code receipts
+}
+
+var (
+	// Mainnet is the profile of the Ethereum mainnet
+	Mainnet = Custom("mainnet", params.MainnetGenesisHash, params.MainnetChainConfig)
+	// Sepolia is the profile of the Sepolia testnet
+	Sepolia = Custom("sepolia", params.SepoliaGenesisHash, params.SepoliaChainConfig)
+	// Gnosis is the profile of the Gnosis chain
+	Gnosis = &Profile{
+		Name:           "gnosis",
+		ChainID:        big.NewInt(100),
+		Genesis:        common.HexToHash("0x4f1dd23188aab3a76b463e4af801b52b1248ef073c648cbdc4c9333d3da79756"),
+		ByzantiumBlock: big.NewInt(0),
+		BerlinBlock:    big.NewInt(16_101_500),
+		LondonBlock:    big.NewInt(19_040_000),
+		ShanghaiTime:   newUint64(1690889660),
+		CancunTime:     newUint64(1710181820),
+		PragueTime:     newUint64(1746612311),
+	}
+)
+
+func newUint64(v uint64) *uint64 {
+	return &v
+}
+
+// Custom returns the profile of a chain from its go-ethereum chain config, e.g. one read from its genesis file
+func Custom(name string, genesis common.Hash, config *params.ChainConfig) *Profile {
+	return &Profile{
+		Name:           name,
+		ChainID:        config.ChainID,
+		Genesis:        genesis,
+		ByzantiumBlock: config.ByzantiumBlock,
+		BerlinBlock:    config.BerlinBlock,
+		LondonBlock:    config.LondonBlock,
+		ShanghaiTime:   config.ShanghaiTime,
+		CancunTime:     config.CancunTime,
+		PragueTime:     config.PragueTime,
+	}
+}
+
+// Rules are the forks active at a block, which determine the layouts of its header and receipts
+type Rules struct {
+	Byzantium, Berlin, London, Shanghai, Cancun, Prague bool
+}
+
+// Rules returns the forks active at the block of the given number and timestamp
+func (p *Profile) Rules(number *big.Int, time uint64) Rules {
+	return Rules{
+		Byzantium: isBlockForked(p.ByzantiumBlock, number),
+		Berlin:    isBlockForked(p.BerlinBlock, number),
+		London:    isBlockForked(p.LondonBlock, number),
+		Shanghai:  isTimestampForked(p.ShanghaiTime, time),
+		Cancun:    isTimestampForked(p.CancunTime, time),
+		Prague:    isTimestampForked(p.PragueTime, time),
+	}
+}
+
+// HeaderRules returns the forks active at the block of the header
+func (p *Profile) HeaderRules(header *types.Header) Rules {
+	return p.Rules(header.Number, header.Time)
+}
+
+// CheckHeader returns an error if the header does not carry exactly the fields of the forks active at its block
+func (p *Profile) CheckHeader(header *types.Header) error {
+	if header.Number == nil {
+		return fmt.Errorf("header cannot have `nil` Number")
+	}
+	return p.HeaderRules(header).CheckHeader(header)
+}
+
+func isBlockForked(fork, number *big.Int) bool {
+	return fork != nil && number != nil && fork.Cmp(number) <= 0
+}
+
+func isTimestampForked(fork *uint64, time uint64) bool {
+	return fork != nil && *fork <= time
+}
+
+// CheckHeader returns an error if the header does not carry exactly the fields of the forks of the rules
+func (r Rules) CheckHeader(header *types.Header) error {
+	if err := checkField("BaseFee", "london", r.London, header.BaseFee != nil); err != nil {
+		return err
+	}
+	if err := checkField("WithdrawalsRootCID", "shanghai", r.Shanghai, header.WithdrawalsHash != nil); err != nil {
+		return err
+	}
+	if err := checkField("BlobGasUsed", "cancun", r.Cancun, header.BlobGasUsed != nil); err != nil {
+		return err
+	}
+	if err := checkField("ExcessBlobGas", "cancun", r.Cancun, header.ExcessBlobGas != nil); err != nil {
+		return err
+	}
+	if err := checkField("ParentBeaconRootCID", "cancun", r.Cancun, header.ParentBeaconRoot != nil); err != nil {
+		return err
+	}
+	return checkField("RequestsCID", "prague", r.Prague, header.RequestsHash != nil)
+}
+
+func checkField(field, fork string, active, present bool) error {
+	switch {
+	case active && !present:
+		return fmt.Errorf("header is missing the %s field of the %s fork", field, fork)
+	case !active && present:
+		return fmt.Errorf("header carries a %s field but the %s fork is not active", field, fork)
+	}
+	return nil
+}
+
+// CheckReceipt returns an error if the receipt does not have the layout of the forks of the rules: a PostState
+// before Byzantium and a Status after it, and a TxType whose fork is active
+func (r Rules) CheckReceipt(receipt *types.Receipt) error {
+	if r.Byzantium && len(receipt.PostState) > 0 {
+		return fmt.Errorf("receipt carries a PostState but the byzantium fork is active")
+	}
+	if !r.Byzantium && len(receipt.PostState) == 0 {
+		return fmt.Errorf("receipt carries a Status but the byzantium fork is not active")
+	}
+	var active bool
+	switch receipt.Type {
+	case types.LegacyTxType:
+		active = true
+	case types.AccessListTxType:
+		active = r.Berlin
+	case types.DynamicFeeTxType:
+		active = r.London
+	case types.BlobTxType:
+		active = r.Cancun
+	case types.SetCodeTxType:
+		active = r.Prague
+	default:
+		// types no Ethereum fork introduces are left to the custom chains defining them
+		active = true
+	}
+	if !active {
+		return fmt.Errorf("receipt TxType %d is not active at this block", receipt.Type)
+	}
+	return nil
+}
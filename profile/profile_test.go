@@ -0,0 +1,74 @@
+package profile_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/vulcanize/go-codec-dageth/profile"
+)
+
+func TestRules(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		profile  *profile.Profile
+		number   int64
+		time     uint64
+		expected profile.Rules
+	}{
+		{"mainnet frontier", profile.Mainnet, 1, 1438269988, profile.Rules{}},
+		{"mainnet byzantium", profile.Mainnet, 4_370_000, 1508131331, profile.Rules{Byzantium: true}},
+		{"mainnet london", profile.Mainnet, 12_965_000, 1628166822, profile.Rules{Byzantium: true, Berlin: true, London: true}},
+		{"mainnet cancun", profile.Mainnet, 19_426_587, 1710338135, profile.Rules{Byzantium: true, Berlin: true, London: true, Shanghai: true, Cancun: true}},
+		{"sepolia genesis", profile.Sepolia, 0, 1633267481, profile.Rules{Byzantium: true, Berlin: true, London: true}},
+		{"gnosis pre-london", profile.Gnosis, 19_039_999, 1635000000, profile.Rules{Byzantium: true, Berlin: true}},
+		{"gnosis prague", profile.Gnosis, 40_000_000, 1746612311, profile.Rules{Byzantium: true, Berlin: true, London: true, Shanghai: true, Cancun: true, Prague: true}},
+	} {
+		if rules := test.profile.Rules(big.NewInt(test.number), test.time); rules != test.expected {
+			t.Errorf("%s: expected rules %+v, got %+v", test.name, test.expected, rules)
+		}
+	}
+}
+
+func TestCheckHeader(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(12_965_000), Time: 1628166822, Difficulty: big.NewInt(1)}
+	if err := profile.Mainnet.CheckHeader(header); err == nil {
+		t.Error("expected an error checking a London header without a BaseFee")
+	}
+	header.BaseFee = big.NewInt(1000000000)
+	if err := profile.Mainnet.CheckHeader(header); err != nil {
+		t.Errorf("unable to check a London header: %v", err)
+	}
+	header.WithdrawalsHash = &types.EmptyWithdrawalsHash
+	if err := profile.Mainnet.CheckHeader(header); err == nil {
+		t.Error("expected an error checking a London header carrying a WithdrawalsRootCID")
+	}
+}
+
+func TestCheckReceipt(t *testing.T) {
+	frontier := profile.Mainnet.Rules(big.NewInt(1), 1438269988)
+	london := profile.Mainnet.Rules(big.NewInt(12_965_000), 1628166822)
+	postState := &types.Receipt{Type: types.LegacyTxType, PostState: common.HexToHash("0x01").Bytes()}
+	status := &types.Receipt{Type: types.LegacyTxType, Status: types.ReceiptStatusSuccessful}
+	dynamicFee := &types.Receipt{Type: types.DynamicFeeTxType, Status: types.ReceiptStatusSuccessful}
+	blob := &types.Receipt{Type: types.BlobTxType, Status: types.ReceiptStatusSuccessful}
+	for _, test := range []struct {
+		name    string
+		rules   profile.Rules
+		receipt *types.Receipt
+		valid   bool
+	}{
+		{"frontier post state", frontier, postState, true},
+		{"frontier status", frontier, status, false},
+		{"london post state", london, postState, false},
+		{"london status", london, status, true},
+		{"london dynamic fee", london, dynamicFee, true},
+		{"london blob", london, blob, false},
+	} {
+		if err := test.rules.CheckReceipt(test.receipt); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %t, got error %v", test.name, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Package projection converts DAG-ETH nodes into dag-json and dag-cbor, and back, so Ethereum data can be inspected
+with generic IPLD tooling and served through gateways that do not know the DAG-ETH codecs.
+
+A projection holds the data model of the node: the same map keys as the schema types, bytes as bytes, and links
+as links, so the projection of a block still links to the CIDs of the DAG-ETH blocks it references. Absent optional
+fields are left out of the projection rather than written as null. Re-encoding a projection with the DAG-ETH codec
+of the block it was projected from gives back the original block bytes.
+*/
+package projection
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+const (
+	// DAGJSON is the multicodec code of dag-json
+	DAGJSON uint64 = 0x0129
+	// DAGCBOR is the multicodec code of dag-cbor
+	DAGCBOR uint64 = cid.DagCBOR
+)
+
+// Encode writes the projection of the decoded DAG-ETH node in the format, DAGJSON or DAGCBOR
+func Encode(node ipld.Node, format uint64, w io.Writer) error {
+	encode, err := encoder(format)
+	if err != nil {
+		return err
+	}
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := copyPresent(nb, node); err != nil {
+		return err
+	}
+	return encode(nb.Build(), w)
+}
+
+// Decode decodes a projection in the format, DAGJSON or DAGCBOR, into the assembler
+func Decode(na ipld.NodeAssembler, format uint64, r io.Reader) error {
+	switch format {
+	case DAGJSON:
+		return dagjson.Decode(na, r)
+	case DAGCBOR:
+		return dagcbor.Decode(na, r)
+	}
+	return fmt.Errorf("multicodec type %#x is not a projection format", format)
+}
+
+// Project decodes the block with the DAG-ETH codec of the multicodec code, and returns its projection in the format
+func Project(data []byte, codec, format uint64) ([]byte, error) {
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := codecs.DecodeByCodec(nb, bytes.NewReader(data), codec); err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := Encode(nb.Build(), format, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ProjectLink loads the block the link references through the LinkSystem, and returns its projection in the format
+func ProjectLink(lsys ipld.LinkSystem, lnk ipld.Link, format uint64) ([]byte, error) {
+	node, err := lsys.Load(ipld.LinkContext{}, lnk, basicnode.Prototype.Any)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := Encode(node, format, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unproject decodes the projection in the format, and re-encodes it with the DAG-ETH codec of the multicodec code
+func Unproject(data []byte, format, codec uint64) ([]byte, error) {
+	c, ok := codecs.Lookup(codec)
+	if !ok {
+		return nil, fmt.Errorf("multicodec type %#x is not a dag-eth codec", codec)
+	}
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := Decode(nb, format, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	// copy into the schema type of the codec, entry by entry, as the generated assemblers of the unions nesting trie
+	// nodes cannot take a basic node whole, nor be decoded into by the generic codecs
+	// the chooser only looks at the multicodec type of the link, so any hash will do
+	lnkCID, err := shared.Keccak256ToCid(codec, common.Hash{}.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	proto, err := codecs.NodePrototypeChooser(cidlink.Link{Cid: lnkCID}, ipld.LinkContext{})
+	if err != nil {
+		return nil, err
+	}
+	tb := proto.NewBuilder()
+	if err := copyPresent(tb, nb.Build()); err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := c.Encode(tb.Build(), buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnprojectVerified is like Unproject, re-encoding with the DAG-ETH codec of the expected CID, but also checks that
+// the re-encoded block hashes to that CID
+func UnprojectVerified(data []byte, format uint64, expected cid.Cid) ([]byte, error) {
+	enc, err := Unproject(data, format, expected.Prefix().Codec)
+	if err != nil {
+		return nil, err
+	}
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := codecs.DecodeVerified(nb, bytes.NewReader(enc), expected); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+func encoder(format uint64) (ipld.Encoder, error) {
+	switch format {
+	case DAGJSON:
+		return dagjson.Encode, nil
+	case DAGCBOR:
+		return dagcbor.Encode, nil
+	}
+	return nil, fmt.Errorf("multicodec type %#x is not a projection format", format)
+}
+
+// copyPresent copies the node into the assembler, leaving out the map entries of absent optional fields, which the
+// generic codecs would otherwise write as null, and assembling maps key by key
+func copyPresent(na ipld.NodeAssembler, node ipld.Node) error {
+	switch node.Kind() {
+	case ipld.Kind_Map:
+		ma, err := na.BeginMap(node.Length())
+		if err != nil {
+			return err
+		}
+		for it := node.MapIterator(); !it.Done(); {
+			k, v, err := it.Next()
+			if err != nil {
+				return err
+			}
+			if v.IsAbsent() {
+				continue
+			}
+			key, err := k.AsString()
+			if err != nil {
+				return err
+			}
+			if err := ma.AssembleKey().AssignString(key); err != nil {
+				return err
+			}
+			if err := copyPresent(ma.AssembleValue(), v); err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+		}
+		return ma.Finish()
+	case ipld.Kind_List:
+		la, err := na.BeginList(node.Length())
+		if err != nil {
+			return err
+		}
+		for it := node.ListIterator(); !it.Done(); {
+			i, v, err := it.Next()
+			if err != nil {
+				return err
+			}
+			if err := copyPresent(la.AssembleValue(), v); err != nil {
+				return fmt.Errorf("[%d]: %v", i, err)
+			}
+		}
+		return la.Finish()
+	default:
+		return na.AssignNode(node)
+	}
+}
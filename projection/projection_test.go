@@ -0,0 +1,112 @@
+package projection_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/projection"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/tx"
+)
+
+func TestRoundTrip(t *testing.T) {
+	g := testutil.NewGenerator(31)
+	for _, sample := range g.Samples() {
+		for _, format := range []uint64{projection.DAGJSON, projection.DAGCBOR} {
+			projected, err := projection.Project(sample.Data, sample.Codec, format)
+			if err != nil {
+				t.Fatalf("%s: unable to project into %#x: %v", sample.Name, format, err)
+			}
+			enc, err := projection.Unproject(projected, format, sample.Codec)
+			if err != nil {
+				t.Fatalf("%s: unable to unproject from %#x: %v", sample.Name, format, err)
+			}
+			if !bytes.Equal(enc, sample.Data) {
+				t.Errorf("%s: re-encoding the %#x projection does not give back the block", sample.Name, format)
+			}
+		}
+	}
+}
+
+func TestLinksPreserved(t *testing.T) {
+	g := testutil.NewGenerator(32)
+	h := g.Header()
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	enc, err := rlp.EncodeToBytes(h)
+	if err != nil {
+		t.Fatalf("unable to encode header: %v", err)
+	}
+	c, _ := shared.RawToCid(header.MultiCodecType, enc)
+	lnk := cidlink.Link{Cid: c}
+	store.Bag[lnk] = enc
+
+	projected, err := projection.ProjectLink(lsys, lnk, projection.DAGJSON)
+	if err != nil {
+		t.Fatalf("unable to project header: %v", err)
+	}
+	parent := testutil.KeccakCid(t, header.MultiCodecType, h.ParentHash.Bytes())
+	if !strings.Contains(string(projected), `"/": "`+parent.String()+`"`) {
+		t.Errorf("expected the projection to link to the parent %s, got %s", parent, projected)
+	}
+
+	// a generic decoder sees the link as a link
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := projection.Decode(nb, projection.DAGJSON, bytes.NewReader(projected)); err != nil {
+		t.Fatalf("unable to decode projection: %v", err)
+	}
+	parentNode, err := nb.Build().LookupByString("ParentCID")
+	if err != nil {
+		t.Fatalf("unable to look up parent: %v", err)
+	}
+	if parentLnk, err := parentNode.AsLink(); err != nil || parentLnk.String() != parent.String() {
+		t.Errorf("expected parent link %s, got %v (%v)", parent, parentLnk, err)
+	}
+
+	if _, err := projection.UnprojectVerified(projected, projection.DAGJSON, c); err != nil {
+		t.Errorf("unable to verify unprojected header: %v", err)
+	}
+	other := testutil.KeccakCid(t, header.MultiCodecType, g.Hash().Bytes())
+	if _, err := projection.UnprojectVerified(projected, projection.DAGJSON, other); err == nil {
+		t.Error("expected an error verifying against the CID of another header")
+	}
+}
+
+func TestAbsentFields(t *testing.T) {
+	g := testutil.NewGenerator(33)
+	transaction := g.Transaction(2)
+	enc, _ := transaction.MarshalBinary()
+	proto, _ := codecs.NodePrototypeChooser(testutil.KeccakLink(t, tx.MultiCodecType, g.Hash().Bytes()), ipld.LinkContext{})
+	builder := proto.NewBuilder()
+	if err := tx.Decode(builder, bytes.NewReader(enc)); err != nil {
+		t.Fatalf("unable to decode transaction: %v", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := projection.Encode(builder.Build(), projection.DAGJSON, buf); err != nil {
+		t.Fatalf("unable to project transaction: %v", err)
+	}
+	if strings.Contains(buf.String(), `"From"`) {
+		t.Errorf("expected the absent From field to be left out, got %s", buf.String())
+	}
+	reenc, err := projection.Unproject(buf.Bytes(), projection.DAGJSON, tx.MultiCodecType)
+	if err != nil {
+		t.Fatalf("unable to unproject transaction: %v", err)
+	}
+	if !bytes.Equal(reenc, enc) {
+		t.Error("re-encoding the projection of a typed node does not give back the transaction")
+	}
+
+	if err := projection.Encode(builder.Build(), 0x55, buf); err == nil {
+		t.Error("expected an error projecting into a format other than dag-json or dag-cbor")
+	}
+}
@@ -0,0 +1,96 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/helpers"
+	"github.com/vulcanize/go-codec-dageth/metrics"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// Node is a trie node of a proof, along with the link it is stored under
+type Node struct {
+	Link ipld.Link
+	RLP  []byte
+}
+
+// GenerateOptions can be used to customize proof generation.
+// The zero value is the default behavior used by GenerateProof, GenerateRangeProof and GenerateSizedRangeProof.
+type GenerateOptions struct {
+	// Metrics, if set, receives the trie nodes loaded to generate the proof and the depth reached below the root
+	Metrics metrics.Recorder
+}
+
+// GenerateProof walks the trie from the root through the LinkSystem along the key, and returns the trie nodes
+// on the path in root-to-leaf order, which together prove the value at the key or the absence of the key.
+// The key is the trie key, e.g. keccak256(address) for the state trie and keccak256(slot) for a storage trie.
+// Nodes embedded in their parent branch are part of the parent's RLP, so they are not returned separately.
+func GenerateProof(lsys ipld.LinkSystem, root ipld.Link, key []byte) ([]Node, error) {
+	return GenerateOptions{}.GenerateProof(lsys, root, key)
+}
+
+// GenerateProof is like the package level GenerateProof, but uses the provided options
+func (opts GenerateOptions) GenerateProof(lsys ipld.LinkSystem, root ipld.Link, key []byte) ([]Node, error) {
+	lsys = metrics.Instrument(lsys, opts.Metrics)
+	rec := metrics.OrNop(opts.Metrics)
+	codec, _, err := linkToHash(root)
+	if err != nil {
+		return nil, err
+	}
+	var proof []Node
+	remaining := helpers.KeyToNibbles(key)
+	for lnk := root; lnk != nil; {
+		enc, err := loadRaw(lsys, lnk)
+		if err != nil {
+			return nil, err
+		}
+		proof = append(proof, Node{Link: lnk, RLP: enc})
+		nb := dageth.Type.TrieNode.NewBuilder()
+		if err := trie.DecodeTrieNodeBytes(nb, enc, codec); err != nil {
+			return nil, fmt.Errorf("invalid trie node %s (%v)", lnk.String(), err)
+		}
+		rec.NodeDecoded(codec)
+		rec.DepthReached(len(proof) - 1)
+		if _, lnk, remaining, err = helpers.Step(nb.Build(), remaining); err != nil {
+			return nil, err
+		}
+	}
+	return proof, nil
+}
+
+// Encodings returns the RLP encodings of the proof nodes, in the form returned by eth_getProof
+func Encodings(proof []Node) [][]byte {
+	encs := make([][]byte, len(proof))
+	for i, node := range proof {
+		encs[i] = node.RLP
+	}
+	return encs
+}
+
+func loadRaw(lsys ipld.LinkSystem, lnk ipld.Link) ([]byte, error) {
+	_, hash, err := linkToHash(lnk)
+	if err != nil {
+		return nil, err
+	}
+	if lsys.StorageReadOpener == nil {
+		return nil, fmt.Errorf("no storage configured for reading")
+	}
+	r, err := lsys.StorageReadOpener(ipld.LinkContext{}, lnk)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(crypto.Keccak256(enc), hash) {
+		return nil, fmt.Errorf("trie node %s does not match its hash", lnk.String())
+	}
+	return enc, nil
+}
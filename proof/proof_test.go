@@ -0,0 +1,424 @@
+package proof_test
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/holiman/uint256"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/proof"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+// proofList collects the nodes written by gethtrie.Trie.Prove, in the order eth_getProof returns them
+type proofList [][]byte
+
+func (l *proofList) Put(_ []byte, value []byte) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func (l *proofList) Delete([]byte) error {
+	panic("not supported")
+}
+
+func newTrie() *gethtrie.Trie {
+	return gethtrie.NewEmpty(triedb.NewDatabase(rawdb.NewMemoryDatabase(), nil))
+}
+
+func prove(t *testing.T, tr *gethtrie.Trie, key []byte) [][]byte {
+	var p proofList
+	if err := tr.Prove(key, &p); err != nil {
+		t.Fatalf("unable to generate proof: %v", err)
+	}
+	return p
+}
+
+func mockAccounts(n int64) map[common.Address]*types.StateAccount {
+	accounts := make(map[common.Address]*types.StateAccount, n)
+	for i := int64(1); i <= n; i++ {
+		accounts[common.BigToAddress(big.NewInt(i))] = &types.StateAccount{
+			Nonce:    uint64(i),
+			Balance:  uint256.NewInt(uint64(i) * 1000),
+			Root:     types.EmptyRootHash,
+			CodeHash: types.EmptyCodeHash.Bytes(),
+		}
+	}
+	return accounts
+}
+
+func TestVerifyStateProof(t *testing.T) {
+	accounts := mockAccounts(64)
+	tr := newTrie()
+	for addr, acct := range accounts {
+		enc, _ := rlp.EncodeToBytes(acct)
+		tr.MustUpdate(shared.AddressToLeafKey(addr), enc)
+	}
+	root := testutil.KeccakLink(t, state_trie.MultiCodecType, tr.Hash().Bytes())
+
+	for addr, acct := range accounts {
+		key := shared.AddressToLeafKey(addr)
+		val, err := proof.Verify(root, key, prove(t, tr, key))
+		if err != nil {
+			t.Fatalf("unable to verify proof for %s: %v", addr.Hex(), err)
+		}
+		if val == nil {
+			t.Fatalf("expected proof for %s to prove inclusion", addr.Hex())
+		}
+		nonceNode, err := val.LookupByString("Account")
+		if err == nil {
+			nonceNode, err = nonceNode.LookupByString("Nonce")
+		}
+		if err != nil {
+			t.Fatalf("unable to look up account nonce: %v", err)
+		}
+		nonceBytes, _ := nonceNode.AsBytes()
+		if new(big.Int).SetBytes(nonceBytes).Uint64() != acct.Nonce {
+			t.Errorf("account %s nonce (%x) does not match expected nonce (%d)", addr.Hex(), nonceBytes, acct.Nonce)
+		}
+	}
+
+	// the proof of an absent key ends at the node where its path diverges
+	absent := shared.AddressToLeafKey(common.HexToAddress("0xdeadbeef"))
+	val, err := proof.Verify(root, absent, prove(t, tr, absent))
+	if err != nil {
+		t.Fatalf("unable to verify exclusion proof: %v", err)
+	}
+	if val != nil {
+		t.Errorf("expected exclusion proof to return no value, got %v", val)
+	}
+}
+
+func TestVerifyStorageProof(t *testing.T) {
+	tr := newTrie()
+	slots := make(map[common.Hash][]byte)
+	for i := int64(0); i < 16; i++ {
+		slot := common.BigToHash(big.NewInt(i))
+		enc, _ := rlp.EncodeToBytes(big.NewInt(i + 100).Bytes())
+		slots[slot] = enc
+		tr.MustUpdate(crypto.Keccak256(slot.Bytes()), enc)
+	}
+	root := testutil.KeccakLink(t, storage_trie.MultiCodecType, tr.Hash().Bytes())
+	for slot, expected := range slots {
+		key := crypto.Keccak256(slot.Bytes())
+		p := prove(t, tr, key)
+
+		// the proof can be given as decoded TrieNode IPLDs as well as raw blocks
+		nodes := make([]ipld.Node, len(p))
+		for i, enc := range p {
+			nb := dageth.Type.TrieNode.NewBuilder()
+			if err := storage_trie.DecodeBytes(nb, enc); err != nil {
+				t.Fatalf("unable to decode proof node: %v", err)
+			}
+			nodes[i] = nb.Build()
+		}
+		val, err := proof.VerifyNodes(root, key, nodes)
+		if err != nil {
+			t.Fatalf("unable to verify proof for slot %s: %v", slot.Hex(), err)
+		}
+		bytesNode, err := val.LookupByString("Bytes")
+		if err != nil {
+			t.Fatalf("unable to look up storage value: %v", err)
+		}
+		storageVal, _ := bytesNode.AsBytes()
+		if !bytes.Equal(storageVal, expected) {
+			t.Errorf("slot %s value (%x) does not match expected value (%x)", slot.Hex(), storageVal, expected)
+		}
+	}
+}
+
+func TestVerifyInvalidProof(t *testing.T) {
+	accounts := mockAccounts(16)
+	tr := newTrie()
+	for addr, acct := range accounts {
+		enc, _ := rlp.EncodeToBytes(acct)
+		tr.MustUpdate(shared.AddressToLeafKey(addr), enc)
+	}
+	root := testutil.KeccakLink(t, state_trie.MultiCodecType, tr.Hash().Bytes())
+	key := shared.AddressToLeafKey(common.BigToAddress(big.NewInt(1)))
+	p := prove(t, tr, key)
+
+	if _, err := proof.Verify(root, key, p[:len(p)-1]); err == nil {
+		t.Error("expected an error verifying a proof with a missing node")
+	}
+	tampered := make([][]byte, len(p))
+	copy(tampered, p)
+	tampered[len(p)-1] = append(common.CopyBytes(p[len(p)-1][:len(p[len(p)-1])-1]), 0x01)
+	if _, err := proof.Verify(root, key, tampered); err == nil {
+		t.Error("expected an error verifying a proof with a tampered node")
+	}
+
+	// proof nodes are decoded strictly, so a leaf whose partial path sets the padding nibble does not prove its value
+	paddedLeaf, _ := rlp.EncodeToBytes([]interface{}{append([]byte{0x25}, key...), []byte{0x01}})
+	paddedRoot := testutil.KeccakLink(t, storage_trie.MultiCodecType, crypto.Keccak256(paddedLeaf))
+	if _, err := proof.Verify(paddedRoot, key, [][]byte{paddedLeaf}); err == nil {
+		t.Error("expected an error verifying a proof with a non-canonical node")
+	}
+}
+
+func TestVerifyEmptyTrie(t *testing.T) {
+	root := testutil.KeccakLink(t, storage_trie.MultiCodecType, types.EmptyRootHash.Bytes())
+	key := crypto.Keccak256(common.Hash{}.Bytes())
+	val, err := proof.Verify(root, key, nil)
+	if err != nil {
+		t.Fatalf("unable to verify the absence of a key from the empty trie: %v", err)
+	}
+	if val != nil {
+		t.Errorf("expected the empty trie proof to show the key absent, got %v", val)
+	}
+}
+
+func TestGenerateProof(t *testing.T) {
+	accounts := mockAccounts(64)
+	tr := newTrie()
+	kvs := make(map[string][]byte, len(accounts))
+	for addr, acct := range accounts {
+		enc, _ := rlp.EncodeToBytes(acct)
+		kvs[string(shared.AddressToLeafKey(addr))] = enc
+		tr.MustUpdate(shared.AddressToLeafKey(addr), enc)
+	}
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	testutil.BuildTrie(t, store, state_trie.MultiCodecType, kvs)
+	lsys := codecs.NewLinkSystem(store)
+	root := testutil.KeccakLink(t, state_trie.MultiCodecType, tr.Hash().Bytes())
+
+	keys := [][]byte{shared.AddressToLeafKey(common.HexToAddress("0xdeadbeef"))}
+	for addr := range accounts {
+		keys = append(keys, shared.AddressToLeafKey(addr))
+	}
+	for _, key := range keys {
+		p, err := proof.GenerateProof(lsys, root, key)
+		if err != nil {
+			t.Fatalf("unable to generate proof for key %x: %v", key, err)
+		}
+		// the proof matches the one go-ethereum serves for eth_getProof
+		expected := prove(t, tr, key)
+		if len(p) != len(expected) {
+			t.Fatalf("proof for key %x has %d nodes, expected %d", key, len(p), len(expected))
+		}
+		for i, node := range p {
+			if !bytes.Equal(node.RLP, expected[i]) {
+				t.Errorf("proof node %d for key %x (%x) does not match expected node (%x)", i, key, node.RLP, expected[i])
+			}
+			if _, ok := store.Bag[node.Link]; !ok {
+				t.Errorf("proof node %d link %s is not in the store", i, node.Link.String())
+			}
+		}
+		if _, err := proof.Verify(root, key, proof.Encodings(p)); err != nil {
+			t.Errorf("unable to verify generated proof for key %x: %v", key, err)
+		}
+	}
+
+	missingRoot := testutil.KeccakLink(t, state_trie.MultiCodecType, crypto.Keccak256([]byte("missing")))
+	if _, err := proof.GenerateProof(lsys, missingRoot, keys[0]); err == nil {
+		t.Error("expected an error generating a proof from a root that is not in the store")
+	}
+}
+
+func TestRangeProof(t *testing.T) {
+	accounts := mockAccounts(200)
+	kvs := make(map[string][]byte, len(accounts))
+	for addr, acct := range accounts {
+		enc, _ := rlp.EncodeToBytes(acct)
+		kvs[string(shared.AddressToLeafKey(addr))] = enc
+	}
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	testutil.BuildTrie(t, store, state_trie.MultiCodecType, kvs)
+	tr := newTrie()
+	for k, v := range kvs {
+		tr.MustUpdate([]byte(k), v)
+	}
+	root := testutil.KeccakLink(t, state_trie.MultiCodecType, tr.Hash().Bytes())
+	lsys := codecs.NewLinkSystem(store)
+
+	// sync the trie range by range into a new store, as a snap sync would
+	synced := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	syncedLsys := codecs.NewLinkSystem(synced)
+	var keys [][]byte
+	var origin []byte
+	for ranges := 0; ; ranges++ {
+		rp, err := proof.GenerateRangeProof(lsys, root, origin, nil, 50)
+		if err != nil {
+			t.Fatalf("unable to generate range proof: %v", err)
+		}
+		hasMore, err := rp.Store(syncedLsys, root)
+		if err != nil {
+			t.Fatalf("unable to verify and store range proof: %v", err)
+		}
+		keys = append(keys, rp.Keys...)
+		if !hasMore {
+			if ranges != 3 {
+				t.Errorf("expected 4 ranges of at most 50 leaves, got %d", ranges+1)
+			}
+			break
+		}
+		next := new(big.Int).Add(new(big.Int).SetBytes(rp.Keys[len(rp.Keys)-1]), big.NewInt(1))
+		origin = common.BigToHash(next).Bytes()
+	}
+	if len(keys) != len(kvs) {
+		t.Fatalf("ranges hold %d keys, expected %d", len(keys), len(kvs))
+	}
+	for i, key := range keys {
+		if _, ok := kvs[string(key)]; !ok {
+			t.Errorf("range key %x is not in the trie", key)
+		}
+		if i > 0 && bytes.Compare(keys[i-1], key) >= 0 {
+			t.Errorf("range keys are not sorted at index %d", i)
+		}
+	}
+	for lnk := range store.Bag {
+		if _, ok := synced.Bag[lnk]; !ok {
+			t.Errorf("trie node %s was not synced", lnk.String())
+		}
+	}
+
+	// a range with a leaf left out of it does not verify
+	rp, err := proof.GenerateRangeProof(lsys, root, nil, nil, 50)
+	if err != nil {
+		t.Fatalf("unable to generate range proof: %v", err)
+	}
+	rp.Keys = append(rp.Keys[:10:10], rp.Keys[11:]...)
+	rp.Values = append(rp.Values[:10:10], rp.Values[11:]...)
+	if _, err := rp.Verify(root); err == nil {
+		t.Error("expected an error verifying a range with a missing leaf")
+	}
+}
+
+func TestRangeProofLimit(t *testing.T) {
+	tr := newTrie()
+	kvs := make(map[string][]byte)
+	for i := int64(0); i < 64; i++ {
+		enc, _ := rlp.EncodeToBytes(big.NewInt(i + 1).Bytes())
+		key := crypto.Keccak256(common.BigToHash(big.NewInt(i)).Bytes())
+		kvs[string(key)] = enc
+		tr.MustUpdate(key, enc)
+	}
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	testutil.BuildTrie(t, store, storage_trie.MultiCodecType, kvs)
+	root := testutil.KeccakLink(t, storage_trie.MultiCodecType, tr.Hash().Bytes())
+
+	// the range ends with the first leaf at or past the limit
+	limit := common.HexToHash("0x8000000000000000000000000000000000000000000000000000000000000000").Bytes()
+	rp, err := proof.GenerateRangeProof(codecs.NewLinkSystem(store), root, nil, limit, 0)
+	if err != nil {
+		t.Fatalf("unable to generate range proof: %v", err)
+	}
+	if len(rp.Keys) < 2 || bytes.Compare(rp.Keys[len(rp.Keys)-1], limit) < 0 || bytes.Compare(rp.Keys[len(rp.Keys)-2], limit) >= 0 {
+		t.Errorf("expected the range to end at the first key past %x", limit)
+	}
+	hasMore, err := rp.Verify(root)
+	if err != nil {
+		t.Fatalf("unable to verify range proof: %v", err)
+	}
+	if !hasMore {
+		t.Error("expected more leaves to the right of the range")
+	}
+
+	// a range of every leaf needs no proof
+	rp, err = proof.GenerateRangeProof(codecs.NewLinkSystem(store), root, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("unable to generate range proof: %v", err)
+	}
+	if len(rp.Keys) != len(kvs) || len(rp.Proof) != 0 {
+		t.Errorf("expected a proofless range of %d leaves, got %d leaves and %d proof nodes", len(kvs), len(rp.Keys), len(rp.Proof))
+	}
+	if hasMore, err = rp.Verify(root); err != nil || hasMore {
+		t.Errorf("expected the complete range to verify with no more leaves (%v)", err)
+	}
+
+	// a sized range ends with the leaf that reaches the byte limit
+	rp, err = proof.GenerateSizedRangeProof(codecs.NewLinkSystem(store), root, nil, nil, 100)
+	if err != nil {
+		t.Fatalf("unable to generate range proof: %v", err)
+	}
+	var size int
+	for i, key := range rp.Keys {
+		if size >= 100 {
+			t.Fatalf("expected the range to end once it holds 100 bytes, got %d leaves", len(rp.Keys))
+		}
+		size += len(key) + len(rp.Values[i])
+	}
+	if size < 100 {
+		t.Errorf("expected the range to hold at least 100 bytes, got %d", size)
+	}
+	if hasMore, err = rp.Verify(root); err != nil || !hasMore {
+		t.Errorf("expected the sized range to verify with more leaves (%v)", err)
+	}
+}
+
+func TestVerifySubtrie(t *testing.T) {
+	accounts := mockAccounts(64)
+	kvs := make(map[string][]byte, len(accounts))
+	for addr, acct := range accounts {
+		enc, _ := rlp.EncodeToBytes(acct)
+		kvs[string(shared.AddressToLeafKey(addr))] = enc
+	}
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	testutil.BuildTrie(t, store, state_trie.MultiCodecType, kvs)
+	lsys := codecs.NewLinkSystem(store)
+
+	// find the root and the nibble path of every stored node
+	paths := make(map[ipld.Link][]byte)
+	keys := make([]string, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	st := gethtrie.NewStackTrie(func(path []byte, hash common.Hash, _ []byte) {
+		paths[testutil.KeccakLink(t, state_trie.MultiCodecType, hash.Bytes())] = common.CopyBytes(path)
+	})
+	for _, k := range keys {
+		st.Update([]byte(k), kvs[k])
+	}
+	root := testutil.KeccakCid(t, state_trie.MultiCodecType, st.Hash().Bytes())
+	if err := proof.VerifySubtrie(root, lsys); err != nil {
+		t.Fatalf("expected the complete trie to verify: %v", err)
+	}
+
+	var deep ipld.Link
+	for lnk, path := range paths {
+		if deep == nil || len(path) > len(paths[deep]) {
+			deep = lnk
+		}
+	}
+	// the subtrie under any node verifies on its own
+	if err := proof.VerifySubtrie(deep.(cidlink.Link).Cid, lsys); err != nil {
+		t.Errorf("expected the subtrie at %x to verify: %v", paths[deep], err)
+	}
+
+	original := store.Bag[deep]
+	tampered := append([]byte{}, original...)
+	tampered[len(tampered)-1]++
+	store.Bag[deep] = tampered
+	err := proof.VerifySubtrie(root, lsys)
+	var se *proof.SubtrieError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected a SubtrieError for a tampered node, got %v", err)
+	}
+	if !bytes.Equal(se.Path, paths[deep]) || !se.Cid.Equals(deep.(cidlink.Link).Cid) {
+		t.Errorf("expected the divergence at %x, got %x", paths[deep], se.Path)
+	}
+
+	delete(store.Bag, deep)
+	if err := proof.VerifySubtrie(root, lsys); !errors.As(err, &se) || !bytes.Equal(se.Path, paths[deep]) {
+		t.Errorf("expected a SubtrieError at %x for a missing node, got %v", paths[deep], err)
+	}
+}
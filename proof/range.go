@@ -0,0 +1,440 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	"github.com/vulcanize/go-codec-dageth/helpers"
+	"github.com/vulcanize/go-codec-dageth/keys"
+	"github.com/vulcanize/go-codec-dageth/metrics"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// RangeProof is a contiguous range of trie leaves, sorted by key, together with the Merkle proofs of its left
+// and right boundaries, as served in the snap/1 AccountRange and StorageRanges messages.
+// Values are the leaf values as stored in the trie, so accounts received in the snap slim format need to be
+// converted with types.FullAccountRLP first.
+type RangeProof struct {
+	// Origin is the key the range was requested from, which the left boundary proof proves
+	Origin []byte
+	Keys   [][]byte
+	Values [][]byte
+	// Proof holds the RLP encoded trie nodes of the left and right boundary proofs.
+	// It is empty when the range holds every leaf of the trie.
+	Proof [][]byte
+}
+
+// Verify verifies that the range holds every leaf of the trie referenced by root between the Origin and the last key.
+// It returns whether the trie holds more leaves to the right of the range.
+func (rp RangeProof) Verify(root ipld.Link) (bool, error) {
+	_, rootHash, err := linkToHash(root)
+	if err != nil {
+		return false, err
+	}
+	// a range holding every leaf of the trie is verified without a proof
+	var proofDB ethdb.KeyValueReader
+	if len(rp.Proof) > 0 {
+		db := memorydb.New()
+		for _, enc := range rp.Proof {
+			if err := db.Put(crypto.Keccak256(enc), enc); err != nil {
+				return false, err
+			}
+		}
+		proofDB = db
+	}
+	origin := rp.Origin
+	if origin == nil {
+		origin = make([]byte, common.HashLength)
+	}
+	return gethtrie.VerifyRangeProof(common.BytesToHash(rootHash), origin, rp.Keys, rp.Values, proofDB)
+}
+
+// Store verifies the range against the trie root, and then writes the trie nodes it proves through the LinkSystem.
+// Those are the nodes of the boundary proofs and the subtries between the boundaries, which are rebuilt from the leaves.
+// It returns whether the trie holds more leaves to the right of the range.
+func (rp RangeProof) Store(lsys ipld.LinkSystem, root ipld.Link) (bool, error) {
+	hasMore, err := rp.Verify(root)
+	if err != nil {
+		return false, err
+	}
+	codec, rootHash, err := linkToHash(root)
+	if err != nil {
+		return false, err
+	}
+	leaves := make([][]byte, len(rp.Keys))
+	for i, key := range rp.Keys {
+		leaves[i] = helpers.KeyToNibbles(key)
+	}
+	b := &subtrieBuilder{nibbles: leaves, values: rp.Values}
+	if len(rp.Proof) == 0 {
+		if len(leaves) > 0 {
+			// the root is hashed whatever its size
+			b.nodes = append(b.nodes, b.build(0, len(leaves), 0))
+		}
+	} else {
+		proofNodes := make(map[string][]byte, len(rp.Proof))
+		for _, enc := range rp.Proof {
+			proofNodes[string(crypto.Keccak256(enc))] = enc
+			b.nodes = append(b.nodes, enc)
+		}
+		if err := b.fillProof(proofNodes, rootHash, nil); err != nil {
+			return false, err
+		}
+	}
+	for _, enc := range b.nodes {
+		if err := storeRaw(lsys, codec, enc); err != nil {
+			return false, err
+		}
+	}
+	return hasMore, nil
+}
+
+// subtrieBuilder rebuilds the trie nodes below a range proof from the sorted leaves of the range
+type subtrieBuilder struct {
+	nibbles [][]byte
+	values  [][]byte
+	nodes   [][]byte
+}
+
+// fillProof walks the proof from the node with the given hash, and rebuilds every subtrie that the proof
+// references but does not include, keeping those that hash to their reference
+func (b *subtrieBuilder) fillProof(proofNodes map[string][]byte, hash, path []byte) error {
+	enc, ok := proofNodes[string(hash)]
+	if !ok {
+		start, end := b.leavesWithPrefix(path)
+		if start == end {
+			return nil
+		}
+		nodes := b.nodes
+		if enc = b.build(start, end, len(path)); !bytes.Equal(crypto.Keccak256(enc), hash) {
+			// the subtrie is on a boundary, so it holds leaves outside of the range
+			b.nodes = nodes
+			return nil
+		}
+		b.nodes = append(b.nodes, enc)
+		return nil
+	}
+	var fields []interface{}
+	if err := rlp.DecodeBytes(enc, &fields); err != nil {
+		return fmt.Errorf("invalid proof node %x (%v)", hash, err)
+	}
+	return b.fillProofFields(proofNodes, fields, path)
+}
+
+func (b *subtrieBuilder) fillProofFields(proofNodes map[string][]byte, fields []interface{}, path []byte) error {
+	var children []interface{}
+	var childPaths [][]byte
+	switch len(fields) {
+	case 17:
+		for i := 0; i < 16; i++ {
+			children = append(children, fields[i])
+			childPaths = append(childPaths, append(append([]byte{}, path...), byte(i)))
+		}
+	case 2:
+		compact, ok := fields[0].([]byte)
+		if !ok {
+			return fmt.Errorf("invalid partial path type %T", fields[0])
+		}
+		nibbles, leaf, err := keys.CompactToNibbles(compact)
+		if err != nil {
+			return fmt.Errorf("invalid partial path %x (%v)", compact, err)
+		}
+		if leaf {
+			return nil
+		}
+		children = append(children, fields[1])
+		childPaths = append(childPaths, append(append([]byte{}, path...), nibbles...))
+	default:
+		return fmt.Errorf("trie node needs 2 or 17 elements, got %d", len(fields))
+	}
+	for i, child := range children {
+		switch c := child.(type) {
+		case []interface{}:
+			if err := b.fillProofFields(proofNodes, c, childPaths[i]); err != nil {
+				return err
+			}
+		case []byte:
+			if len(c) == common.HashLength {
+				if err := b.fillProof(proofNodes, c, childPaths[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// leavesWithPrefix returns the index range of the leaves whose path starts with the prefix
+func (b *subtrieBuilder) leavesWithPrefix(prefix []byte) (int, int) {
+	start := sort.Search(len(b.nibbles), func(i int) bool {
+		return bytes.Compare(b.nibbles[i], prefix) >= 0
+	})
+	end := start
+	for end < len(b.nibbles) && bytes.HasPrefix(b.nibbles[end], prefix) {
+		end++
+	}
+	return start, end
+}
+
+// build returns the RLP encoding of the trie node at the given depth that holds the leaves start to end,
+// collecting the encodings of the hashed nodes below it
+func (b *subtrieBuilder) build(start, end, depth int) []byte {
+	if end-start == 1 {
+		enc, _ := rlp.EncodeToBytes([]interface{}{
+			shared.HexToCompact(append(append([]byte{}, b.nibbles[start][depth:]...), 16)),
+			b.values[start],
+		})
+		return enc
+	}
+	// the leaves are sorted, so their common prefix is that of the first and last leaf
+	first, last := b.nibbles[start][depth:], b.nibbles[end-1][depth:]
+	prefixLen := keys.CommonPrefixLength(first, last)
+	if prefixLen > 0 {
+		enc, _ := rlp.EncodeToBytes([]interface{}{
+			shared.HexToCompact(first[:prefixLen]),
+			b.ref(b.build(start, end, depth+prefixLen)),
+		})
+		return enc
+	}
+	branch := make([]interface{}, 17)
+	for i := range branch {
+		branch[i] = []byte{}
+	}
+	for i := start; i < end; {
+		if len(b.nibbles[i]) == depth {
+			branch[16] = b.values[i]
+			i++
+			continue
+		}
+		j := i
+		for j < end && b.nibbles[j][depth] == b.nibbles[i][depth] {
+			j++
+		}
+		branch[b.nibbles[i][depth]] = b.ref(b.build(i, j, depth+1))
+		i = j
+	}
+	enc, _ := rlp.EncodeToBytes(branch)
+	return enc
+}
+
+// ref returns how a child node is referenced from its parent: nodes smaller than 32 bytes are included directly
+func (b *subtrieBuilder) ref(enc []byte) interface{} {
+	if len(enc) < common.HashLength {
+		return rlp.RawValue(enc)
+	}
+	b.nodes = append(b.nodes, enc)
+	return crypto.Keccak256(enc)
+}
+
+// GenerateRangeProof walks the trie from the root through the LinkSystem, and returns the range of leaves starting
+// from the origin key, along with its boundary proofs. Like the snap/1 protocol, the range ends after max leaves,
+// or after the first leaf at or past the limit key; a nil limit does not bound the range, nor does a max of 0.
+func GenerateRangeProof(lsys ipld.LinkSystem, root ipld.Link, origin, limit []byte, max int) (RangeProof, error) {
+	return GenerateOptions{}.GenerateRangeProof(lsys, root, origin, limit, max)
+}
+
+// GenerateRangeProof is like the package level GenerateRangeProof, but uses the provided options
+func (opts GenerateOptions) GenerateRangeProof(lsys ipld.LinkSystem, root ipld.Link, origin, limit []byte, max int) (RangeProof, error) {
+	return opts.generateRangeProof(lsys, root, origin, limit, max, 0)
+}
+
+// GenerateSizedRangeProof is like GenerateRangeProof, but instead of a number of leaves the range ends after the
+// first leaf that brings the size of its keys and values to maxBytes, like the soft byte limit of snap/1 requests.
+func GenerateSizedRangeProof(lsys ipld.LinkSystem, root ipld.Link, origin, limit []byte, maxBytes uint64) (RangeProof, error) {
+	return GenerateOptions{}.GenerateSizedRangeProof(lsys, root, origin, limit, maxBytes)
+}
+
+// GenerateSizedRangeProof is like the package level GenerateSizedRangeProof, but uses the provided options
+func (opts GenerateOptions) GenerateSizedRangeProof(lsys ipld.LinkSystem, root ipld.Link, origin, limit []byte, maxBytes uint64) (RangeProof, error) {
+	return opts.generateRangeProof(lsys, root, origin, limit, 0, maxBytes)
+}
+
+func (opts GenerateOptions) generateRangeProof(lsys ipld.LinkSystem, root ipld.Link, origin, limit []byte, max int, maxBytes uint64) (RangeProof, error) {
+	codec, _, err := linkToHash(root)
+	if err != nil {
+		return RangeProof{}, err
+	}
+	rp := RangeProof{Origin: origin}
+	it := &rangeIterator{
+		lsys:     metrics.Instrument(lsys, opts.Metrics),
+		rec:      metrics.OrNop(opts.Metrics),
+		codec:    codec,
+		origin:   helpers.KeyToNibbles(origin),
+		limit:    limit,
+		max:      max,
+		maxBytes: maxBytes,
+	}
+	if err := it.walk(root, nil); err != nil && err != errRangeDone {
+		return RangeProof{}, err
+	}
+	rp.Keys, rp.Values = it.keys, it.values
+	if origin == nil && !it.more {
+		// the range holds every leaf of the trie, so no proof is needed
+		return rp, nil
+	}
+	if origin == nil {
+		origin = make([]byte, common.HashLength)
+	}
+	seen := make(map[string]bool)
+	proveKeys := [][]byte{origin}
+	if len(rp.Keys) > 0 {
+		proveKeys = append(proveKeys, rp.Keys[len(rp.Keys)-1])
+	}
+	for _, key := range proveKeys {
+		p, err := opts.GenerateProof(lsys, root, key)
+		if err != nil {
+			return RangeProof{}, err
+		}
+		for _, node := range p {
+			if !seen[node.Link.String()] {
+				seen[node.Link.String()] = true
+				rp.Proof = append(rp.Proof, node.RLP)
+			}
+		}
+	}
+	return rp, nil
+}
+
+var errRangeDone = fmt.Errorf("range complete")
+
+// rangeIterator collects trie leaves in key order by walking the RLP of the trie nodes
+type rangeIterator struct {
+	lsys     ipld.LinkSystem
+	rec      metrics.Recorder
+	codec    uint64
+	origin   []byte
+	limit    []byte
+	max      int
+	maxBytes uint64
+
+	keys   [][]byte
+	values [][]byte
+	size   uint64
+	more   bool
+	// depth is the number of links followed from the root to the node being walked
+	depth int
+}
+
+func (it *rangeIterator) walk(lnk ipld.Link, path []byte) error {
+	enc, err := loadRaw(it.lsys, lnk)
+	if err != nil {
+		return err
+	}
+	var fields []interface{}
+	if err := rlp.DecodeBytes(enc, &fields); err != nil {
+		return fmt.Errorf("invalid trie node %s (%v)", lnk.String(), err)
+	}
+	it.rec.NodeDecoded(it.codec)
+	it.rec.DepthReached(it.depth)
+	it.depth++
+	defer func() { it.depth-- }()
+	return it.walkFields(fields, path)
+}
+
+func (it *rangeIterator) walkChild(child interface{}, path []byte) error {
+	switch c := child.(type) {
+	case []interface{}:
+		return it.walkFields(c, path)
+	case []byte:
+		if len(c) == 0 {
+			return nil
+		}
+		if len(c) != common.HashLength {
+			return fmt.Errorf("invalid trie node reference %x", c)
+		}
+		childCID, err := shared.Keccak256ToCid(it.codec, c)
+		if err != nil {
+			return err
+		}
+		return it.walk(cidlink.Link{Cid: childCID}, path)
+	default:
+		return fmt.Errorf("unexpected trie node element type %T", child)
+	}
+}
+
+func (it *rangeIterator) walkFields(fields []interface{}, path []byte) error {
+	// skip subtries that lie entirely to the left of the origin
+	if n := len(path); n <= len(it.origin) && bytes.Compare(path, it.origin[:n]) < 0 {
+		return nil
+	}
+	switch len(fields) {
+	case 17:
+		for i := 0; i < 16; i++ {
+			if err := it.walkChild(fields[i], append(append([]byte{}, path...), byte(i))); err != nil {
+				return err
+			}
+		}
+		if val, ok := fields[16].([]byte); ok && len(val) > 0 {
+			return it.add(path, val)
+		}
+		return nil
+	case 2:
+		compact, ok := fields[0].([]byte)
+		if !ok {
+			return fmt.Errorf("invalid partial path type %T", fields[0])
+		}
+		nibbles, leaf, err := keys.CompactToNibbles(compact)
+		if err != nil {
+			return fmt.Errorf("invalid partial path %x (%v)", compact, err)
+		}
+		full := append(append([]byte{}, path...), nibbles...)
+		if leaf {
+			val, ok := fields[1].([]byte)
+			if !ok {
+				return fmt.Errorf("invalid leaf value type %T", fields[1])
+			}
+			return it.add(full, val)
+		}
+		return it.walkChild(fields[1], full)
+	default:
+		return fmt.Errorf("trie node needs 2 or 17 elements, got %d", len(fields))
+	}
+}
+
+func (it *rangeIterator) add(path, val []byte) error {
+	if bytes.Compare(path, it.origin) < 0 {
+		return nil
+	}
+	key, err := keys.NibblesToKeybytes(path)
+	if err != nil {
+		return err
+	}
+	if (it.max > 0 && len(it.keys) >= it.max) ||
+		(it.maxBytes > 0 && it.size >= it.maxBytes) ||
+		(it.limit != nil && len(it.keys) > 0 && bytes.Compare(it.keys[len(it.keys)-1], it.limit) >= 0) {
+		it.more = true
+		return errRangeDone
+	}
+	it.keys = append(it.keys, key)
+	it.values = append(it.values, val)
+	it.size += uint64(len(key) + len(val))
+	return nil
+}
+
+func storeRaw(lsys ipld.LinkSystem, codec uint64, enc []byte) error {
+	if lsys.StorageWriteOpener == nil {
+		return fmt.Errorf("no storage configured for writing")
+	}
+	w, commit, err := lsys.StorageWriteOpener(ipld.LinkContext{})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(enc); err != nil {
+		return err
+	}
+	c, err := shared.Keccak256ToCid(codec, crypto.Keccak256(enc))
+	if err != nil {
+		return err
+	}
+	return commit(cidlink.Link{Cid: c})
+}
@@ -0,0 +1,146 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// SubtrieError is returned by VerifySubtrie for the first trie node that diverges from the link referencing it
+type SubtrieError struct {
+	// Path is the nibble path of the node from the root of the subtrie, one nibble per byte
+	Path []byte
+	// Cid is the CID the node is referenced by
+	Cid cid.Cid
+	// Err is the divergence, e.g. the node hashing to another digest, or it missing from the storage
+	Err error
+}
+
+func (e *SubtrieError) Error() string {
+	return fmt.Sprintf("trie node %s at nibble path %x: %v", e.Cid.String(), e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *SubtrieError) Unwrap() error {
+	return e.Err
+}
+
+// VerifySubtrie reads every trie node reachable from the trie node the root CID references through the storage of
+// the LinkSystem, e.g. the state root of a partial archive, and checks that the keccak256 hash of the payload of each
+// node matches the link of its parent, and that the node decodes with the trie codec of that link.
+// It returns a SubtrieError for the first node that diverges, visiting children in nibble order, so a subtrie that
+// verifies is complete and recomputes to the root. Nodes embedded in their parent branch are checked as part of it.
+func VerifySubtrie(root cid.Cid, lsys ipld.LinkSystem) error {
+	return verifySubtrie(lsys, root, nil)
+}
+
+func verifySubtrie(lsys ipld.LinkSystem, c cid.Cid, path []byte) error {
+	fail := func(err error) error {
+		return &SubtrieError{Path: path, Cid: c, Err: err}
+	}
+	codec, expected, err := linkToHash(cidlink.Link{Cid: c})
+	if err != nil {
+		return fail(err)
+	}
+	if lsys.StorageReadOpener == nil {
+		return fail(fmt.Errorf("no storage configured for reading"))
+	}
+	r, err := lsys.StorageReadOpener(ipld.LinkContext{}, cidlink.Link{Cid: c})
+	if err != nil {
+		return fail(err)
+	}
+	enc, err := io.ReadAll(r)
+	if err != nil {
+		return fail(err)
+	}
+	if actual := crypto.Keccak256(enc); !bytes.Equal(actual, expected) {
+		return fail(fmt.Errorf("payload hashes to %x, not %x", actual, expected))
+	}
+	nb := dageth.Type.TrieNode.NewBuilder()
+	if err := trie.DecodeTrieNodeBytes(nb, enc, codec); err != nil {
+		return fail(err)
+	}
+	return verifyChildren(lsys, nb.Build(), path, fail)
+}
+
+// verifyChildren verifies the subtries the children of the node link to, failing with the error of the node for
+// problems within the node itself
+func verifyChildren(lsys ipld.LinkSystem, node ipld.Node, path []byte, fail func(error) error) error {
+	n, kind, err := trie.NodeAndKind(node)
+	if err != nil {
+		return fail(err)
+	}
+	switch kind {
+	case trie.BRANCH_NODE:
+		for i := 0; i < 16; i++ {
+			child, err := n.LookupByString(fmt.Sprintf("Child%X", i))
+			if err != nil {
+				return fail(err)
+			}
+			if child.IsNull() {
+				continue
+			}
+			childPath := append(path[:len(path):len(path)], byte(i))
+			if linkNode, err := child.LookupByString("Link"); err == nil {
+				if err := verifyLink(lsys, linkNode, childPath, fail); err != nil {
+					return err
+				}
+				continue
+			}
+			embedded, err := child.LookupByString("TrieNode")
+			if err != nil {
+				return fail(fmt.Errorf("branch node child needs to be a link or a trie node: %v", err))
+			}
+			if err := verifyChildren(lsys, embedded, childPath, fail); err != nil {
+				return err
+			}
+		}
+		return nil
+	case trie.EXTENSION_NODE:
+		ppNode, err := n.LookupByString("PartialPath")
+		if err != nil {
+			return fail(err)
+		}
+		partialPath, err := ppNode.AsBytes()
+		if err != nil {
+			return fail(err)
+		}
+		child, err := n.LookupByString("Child")
+		if err != nil {
+			return fail(err)
+		}
+		childPath := append(path[:len(path):len(path)], partialPath...)
+		if linkNode, err := child.LookupByString("Link"); err == nil {
+			return verifyLink(lsys, linkNode, childPath, fail)
+		}
+		embedded, err := child.LookupByString("TrieNode")
+		if err != nil {
+			return fail(fmt.Errorf("extension node child needs to be a link or a trie node: %v", err))
+		}
+		return verifyChildren(lsys, embedded, childPath, fail)
+	case trie.LEAF_NODE:
+		return nil
+	default:
+		return fail(fmt.Errorf("unrecognized trie node type %s", kind.String()))
+	}
+}
+
+func verifyLink(lsys ipld.LinkSystem, linkNode ipld.Node, path []byte, fail func(error) error) error {
+	lnk, err := linkNode.AsLink()
+	if err != nil {
+		return fail(err)
+	}
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return fail(fmt.Errorf("expected a cidlink.Link, got %T", lnk))
+	}
+	return verifySubtrie(lsys, cl.Cid, path)
+}
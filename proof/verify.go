@@ -0,0 +1,93 @@
+/*
+Package proof verifies and generates Merkle proofs over DAG-ETH trie nodes, such as the
+accountProof and storageProof lists returned by eth_getProof.
+RangeProof covers the boundary-proven leaf ranges of the snap/1 protocol, and VerifySubtrie rehashes every trie
+node reachable from a root, for partial archives.
+*/
+package proof
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/helpers"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// Verify verifies the Merkle proof of the key against the trie root referenced by root, and returns the Value
+// stored at the key, or nil if the proof shows that the trie does not contain the key.
+// The proof is the list of RLP encoded trie nodes on the path from the root to the key, in the order returned
+// in the accountProof and storageProof fields of an eth_getProof response.
+// The key is the trie key, e.g. keccak256(address) for the state trie and keccak256(slot) for a storage trie.
+// The multicodec of the root CID determines which DAG-ETH trie codec the nodes are decoded with, strictly.
+// An empty trie contains no key, so a proof against the empty root hash needs no nodes.
+func Verify(root ipld.Link, key []byte, proof [][]byte) (ipld.Node, error) {
+	codec, rootHash, err := linkToHash(root)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(rootHash, types.EmptyRootHash.Bytes()) {
+		return nil, nil
+	}
+	proofNodes := make(map[string][]byte, len(proof))
+	for _, enc := range proof {
+		proofNodes[string(crypto.Keccak256(enc))] = enc
+	}
+	remaining := helpers.KeyToNibbles(key)
+	hash := rootHash
+	for i := 0; ; i++ {
+		enc, ok := proofNodes[string(hash)]
+		if !ok {
+			return nil, fmt.Errorf("proof is missing trie node %x at depth %d", hash, i)
+		}
+		nb := dageth.Type.TrieNode.NewBuilder()
+		if err := (trie.DecodeOptions{Strict: true}).DecodeTrieNodeBytes(nb, enc, codec); err != nil {
+			return nil, fmt.Errorf("invalid proof node %x (%v)", hash, err)
+		}
+		val, next, rest, err := helpers.Step(nb.Build(), remaining)
+		if err != nil || next == nil {
+			return val, err
+		}
+		remaining = rest
+		if _, hash, err = linkToHash(next); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// VerifyNodes is like Verify, but takes the proof as TrieNode IPLDs.
+// The nodes are re-encoded to verify they hash to the links that reference them.
+func VerifyNodes(root ipld.Link, key []byte, proof []ipld.Node) (ipld.Node, error) {
+	encs := make([][]byte, len(proof))
+	for i, node := range proof {
+		enc, err := trie.AppendEncode(nil, node)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof node at index %d (%v)", i, err)
+		}
+		encs[i] = enc
+	}
+	return Verify(root, key, encs)
+}
+
+// linkToHash returns the multicodec and keccak256 digest of a DAG-ETH trie node link
+func linkToHash(lnk ipld.Link) (uint64, []byte, error) {
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return 0, nil, fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+	}
+	decoded, err := multihash.Decode(cl.Hash())
+	if err != nil {
+		return 0, nil, err
+	}
+	if decoded.Code != multihash.KECCAK_256 {
+		return 0, nil, fmt.Errorf("expected a keccak-256 multihash, got %#x", decoded.Code)
+	}
+	return cl.Prefix().Codec, decoded.Digest, nil
+}
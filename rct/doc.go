@@ -0,0 +1,7 @@
+/*
+Package rct is the eth-tx-receipt codec of receipts.
+
+With profile.Rules in the DecodeOptions or EncodeOptions, receipts are checked against the layout of the forks active
+at their block rather than inferred from the fields they carry.
+*/
+package rct
@@ -2,7 +2,6 @@ package rct
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"io"
 
@@ -10,44 +9,114 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/profile"
 	"github.com/vulcanize/go-codec-dageth/shared"
 )
 
+// EncodeOptions can be used to customize the behavior of receipt encoding.
+// The zero value is the default behavior used by the registered codec.
+type EncodeOptions struct {
+	// Strict causes the encoder to reject receipts whose LogRootCID, which is not part of the consensus encoding,
+	// does not reference the log trie built from the receipt's logs, and numeric fields not in their canonical form,
+	// see shared.CheckNumerics
+	Strict bool
+	// LinkCodecs overrides the multicodec type Strict expects the LogRootCID to carry,
+	// it should match the DecodeOptions the node was decoded with
+	LinkCodecs shared.LinkCodecs
+	// LinkHashes overrides the multihash types Strict expects links to carry,
+	// it should match the DecodeOptions the node was decoded with
+	LinkHashes shared.LinkHashes
+	// Rules, the forks active at the block of the receipt, causes the encoder to reject receipts that do not have
+	// the layout of those forks
+	Rules *profile.Rules
+}
+
 // Encode provides an IPLD codec encode interface for eth receipt IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code 0x95 when this package is invoked via init.
 func Encode(node ipld.Node, w io.Writer) error {
-	// 1KiB can be allocated on the stack, and covers most small nodes
-	// without having to grow the buffer and cause allocations.
-	enc := make([]byte, 0, 1024)
-
-	enc, err := AppendEncode(enc, node)
-	if err != nil {
-		return err
-	}
-	_, err = w.Write(enc)
-	return err
+	return EncodeOptions{}.Encode(node, w)
 }
 
 // AppendEncode is like Encode, but it uses a destination buffer directly.
 // This means less copying of bytes, and if the destination has enough capacity,
 // fewer allocations.
 func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return EncodeOptions{}.AppendEncode(enc, inNode)
+}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options.
+// It streams the receipt to w, its logs one by one, see shared.StreamRLP.
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	item, err := cfg.StreamItem(node)
+	if err != nil {
+		return err
+	}
+	if err := shared.StreamRLP(w, item); err != nil {
+		return fmt.Errorf("invalid DAG-ETH Receipt form (%v)", err)
+	}
+	return nil
+}
+
+// StreamItem packs the node into the item shared.StreamRLP writes the consensus encoding of the receipt with,
+// a shared.Typed payload for typed receipts and a shared.List otherwise, listing the logs one by one
+func (cfg EncodeOptions) StreamItem(inNode ipld.Node) (interface{}, error) {
+	rct := new(receiptRLP)
+	txType, err := cfg.packReceiptRLP(rct, inNode)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode receiptRLP (%v)", err)
+	}
+	if err := cfg.checkRules(txType, rct); err != nil {
+		return nil, err
+	}
+	logs := make(shared.List, len(rct.Logs))
+	for i, l := range rct.Logs {
+		logs[i] = l
+	}
+	fields := shared.List{rct.PostStateOrStatus, rct.CumulativeGasUsed, rct.Bloom, logs}
+	switch {
+	case txType == types.LegacyTxType:
+		return fields, nil
+	case isTypedReceipt(txType):
+		return shared.Typed{Type: txType, Fields: fields}, nil
+	default:
+		return nil, fmt.Errorf("invalid DAG-ETH Receipt form (unrecognized TxType %d)", txType)
+	}
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	rct := new(receiptRLP)
-	txType, err := packReceiptRLP(rct, inNode)
+	txType, err := cfg.packReceiptRLP(rct, inNode)
 	if err != nil {
 		return enc, fmt.Errorf("unable to encode receiptRLP (%v)", err)
 	}
+	if err := cfg.checkRules(txType, rct); err != nil {
+		return enc, err
+	}
 	wbs := shared.NewWriteableByteSlice(&enc)
-	switch txType {
-	case types.LegacyTxType:
+	switch {
+	case txType == types.LegacyTxType:
 		if err := rlp.Encode(wbs, rct); err != nil {
 			return enc, fmt.Errorf("invalid DAG-ETH Receipt form (%v)", err)
 		}
 		return enc, nil
-	case types.AccessListTxType, types.DynamicFeeTxType:
+	case isTypedReceipt(txType):
 		enc = append(enc, txType)
 		if err := rlp.Encode(wbs, rct); err != nil {
 			return enc, fmt.Errorf("invalid DAG-ETH Receipt form (%v)", err)
@@ -67,11 +136,49 @@ var (
 
 // EncodeReceipt packs the node into the go-ethereum Receipt
 func EncodeReceipt(receipt *types.Receipt, inNode ipld.Node) error {
+	return EncodeOptions{}.EncodeReceipt(receipt, inNode)
+}
+
+// EncodeReceipt is like the package level EncodeReceipt, but uses the provided options
+func (cfg EncodeOptions) EncodeReceipt(receipt *types.Receipt, inNode ipld.Node) error {
 	rct := new(receiptRLP)
-	txType, err := packReceiptRLP(rct, inNode)
+	txType, err := cfg.packReceiptRLP(rct, inNode)
 	if err != nil {
 		return fmt.Errorf("unable to pack receiptRLP struct: %v", err)
 	}
+	if err := setReceiptFields(receipt, txType, rct); err != nil {
+		return err
+	}
+	if cfg.Rules != nil {
+		if err := cfg.Rules.CheckReceipt(receipt); err != nil {
+			return fmt.Errorf("invalid DAG-ETH Receipt form (%v)", err)
+		}
+	}
+	return nil
+}
+
+// checkRules checks the receipt payload against the Rules, if any
+func (cfg EncodeOptions) checkRules(txType uint8, rct *receiptRLP) error {
+	if cfg.Rules == nil {
+		return nil
+	}
+	var receipt types.Receipt
+	if err := setReceiptFields(&receipt, txType, rct); err != nil {
+		return err
+	}
+	if err := cfg.Rules.CheckReceipt(&receipt); err != nil {
+		return fmt.Errorf("invalid DAG-ETH Receipt form (%v)", err)
+	}
+	return nil
+}
+
+// isTypedReceipt returns true if the TxType is an EIP-2718 type, which prefixes the receipt's RLP payload
+func isTypedReceipt(txType uint8) bool {
+	return txType != types.LegacyTxType && txType <= 0x7f
+}
+
+// setReceiptFields sets the consensus fields of the go-ethereum Receipt from the TxType and the receipt payload
+func setReceiptFields(receipt *types.Receipt, txType uint8, rct *receiptRLP) error {
 	receipt.Type = txType
 	receipt.Bloom = rct.Bloom
 	receipt.CumulativeGasUsed = rct.CumulativeGasUsed
@@ -89,7 +196,7 @@ func EncodeReceipt(receipt *types.Receipt, inNode ipld.Node) error {
 	return nil
 }
 
-func packReceiptRLP(rct *receiptRLP, inNode ipld.Node) (uint8, error) {
+func (cfg EncodeOptions) packReceiptRLP(rct *receiptRLP, inNode ipld.Node) (uint8, error) {
 	// Wrap in a typed node for some basic schema form checking
 	builder := dageth.Type.Receipt.NewBuilder()
 	if err := builder.AssignNode(inNode); err != nil {
@@ -105,9 +212,48 @@ func packReceiptRLP(rct *receiptRLP, inNode ipld.Node) (uint8, error) {
 			return 0, fmt.Errorf("invalid DAG-ETH Receipt form (%v)", err)
 		}
 	}
+	if cfg.Strict {
+		if err := shared.CheckNumerics(node); err != nil {
+			return 0, fmt.Errorf("invalid DAG-ETH Receipt form (%v)", err)
+		}
+		if err := cfg.checkLogRootCID(rct, node); err != nil {
+			return 0, fmt.Errorf("invalid DAG-ETH Receipt form (%v)", err)
+		}
+	}
 	return txType, nil
 }
 
+// checkLogRootCID verifies that the LogRootCID of the receipt node references the log trie built from its logs
+func (cfg EncodeOptions) checkLogRootCID(rct *receiptRLP, node ipld.Node) error {
+	lrNode, err := node.LookupByString("LogRootCID")
+	if err != nil {
+		return err
+	}
+	lrLink, err := lrNode.AsLink()
+	if err != nil {
+		return err
+	}
+	lrCIDLink, ok := lrLink.(cidlink.Link)
+	if !ok {
+		return fmt.Errorf("receipt LogRootCID must be a CID")
+	}
+	if err := shared.CheckLink(lrCIDLink.Cid, cfg.LinkCodecs.Codec(logTrieMulticodec), cfg.LinkHashes.Type(log.MultiHashType)); err != nil {
+		return err
+	}
+	logTrieRoot, err := processLogs(rct.Logs)
+	if err != nil {
+		return err
+	}
+	decodedLrMh, err := multihash.Decode(lrCIDLink.Hash())
+	if err != nil {
+		return fmt.Errorf("unable to decode LogRootCID multihash: %v", err)
+	}
+	if !bytes.Equal(decodedLrMh.Digest, logTrieRoot) {
+		return fmt.Errorf("receipt LogRootCID %s does not reference the log trie of its logs (%x)", lrCIDLink.String(), logTrieRoot)
+	}
+	return nil
+}
+
 // the consensus struct for a receipt is not an exported type from go-ethereum
 // so until types.Receipt has a MarshalBinary method we will pack and RLP encode a custom struct
 type receiptRLP struct {
@@ -124,24 +270,31 @@ var requiredPackFuncs = []func(*receiptRLP, ipld.Node) error{
 	packLogs,
 }
 
+// packPostStateOrStatus packs whichever of PostState (pre-Byzantium) or Status (Byzantium and later) is set,
+// a receipt node must have exactly one of the two
 func packPostStateOrStatus(rct *receiptRLP, node ipld.Node) error {
 	psNode, err := node.LookupByString("PostState")
 	if err != nil {
 		return fmt.Errorf("receipt is missing a PostState node: %v", err)
 	}
+	sNode, err := node.LookupByString("Status")
+	if err != nil {
+		return fmt.Errorf("receipt is missing a Status node: %v", err)
+	}
 	if !psNode.IsNull() {
+		if !sNode.IsNull() {
+			return fmt.Errorf("receipt Node cannot have both PostState and Status")
+		}
 		psBytes, err := psNode.AsBytes()
 		if err != nil {
 			return fmt.Errorf("receipt PostState should be of type Bytes")
 		}
+		if len(psBytes) != len(common.Hash{}) {
+			return fmt.Errorf("receipt PostState should be a %d byte state root, got %d bytes", len(common.Hash{}), len(psBytes))
+		}
 		rct.PostStateOrStatus = psBytes
 		return nil
 	}
-
-	sNode, err := node.LookupByString("Status")
-	if err != nil {
-		return fmt.Errorf("receipt is missing a Status node: %v", err)
-	}
 	if sNode.IsNull() {
 		return fmt.Errorf("receipt Node must have either PostState or Status")
 	}
@@ -155,7 +308,7 @@ func packPostStateOrStatus(rct *receiptRLP, node ipld.Node) error {
 	case bytes.Equal(sBytes, receiptStatusSuccessful):
 		rct.PostStateOrStatus = receiptStatusSuccessfulRLP
 	default:
-		return fmt.Errorf("unrecognized tx type")
+		return fmt.Errorf("unrecognized receipt Status %x", sBytes)
 	}
 	return nil
 }
@@ -169,7 +322,10 @@ func packCumulativeGasUsed(rct *receiptRLP, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	rct.CumulativeGasUsed = binary.BigEndian.Uint64(cguBytes)
+	rct.CumulativeGasUsed, err = shared.BytesToUint64(cguBytes)
+	if err != nil {
+		return fmt.Errorf("CumulativeGasUsed %v", err)
+	}
 	return nil
 }
 
@@ -206,6 +362,10 @@ func packLogs(rct *receiptRLP, node ipld.Node) error {
 		if err != nil {
 			return err
 		}
+		addr, err := shared.BytesToAddress(addrBytes)
+		if err != nil {
+			return err
+		}
 		topicsNode, err := logNode.LookupByString("Topics")
 		if err != nil {
 			return fmt.Errorf("receipt log is missing a Topics node: %v", err)
@@ -221,7 +381,10 @@ func packLogs(rct *receiptRLP, node ipld.Node) error {
 			if err != nil {
 				return err
 			}
-			topics[topicIndex] = common.BytesToHash(topicBytes)
+			topics[topicIndex], err = shared.BytesToHash(topicBytes)
+			if err != nil {
+				return err
+			}
 		}
 		dataNode, err := logNode.LookupByString("Data")
 		if err != nil {
@@ -232,7 +395,7 @@ func packLogs(rct *receiptRLP, node ipld.Node) error {
 			return err
 		}
 		logs[logIndex] = &types.Log{
-			Address: common.BytesToAddress(addrBytes),
+			Address: addr,
 			Topics:  topics,
 			Data:    data,
 		}
@@ -168,3 +168,219 @@ func testReceiptEncoding(t *testing.T) {
 		t.Errorf("dynamic fee receipt encoding (%x) does not match the expected consensus encoding (%x)", dfRctBytes, dfReceiptConsensusEnc)
 	}
 }
+
+func TestTypedReceiptEnvelopes(t *testing.T) {
+	dfEnc, err := dynamicFeeReceipt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal dynamic fee receipt binary: %v", err)
+	}
+	// every typed receipt shares the same payload, so swapping the type byte yields a valid envelope of that type
+	for _, txType := range []uint8{types.BlobTxType, types.SetCodeTxType, 0x7f} {
+		typedEnc := append([]byte{txType}, dfEnc[1:]...)
+		rctBuilder := dageth.Type.Receipt.NewBuilder()
+		if err := rct.Decode(rctBuilder, bytes.NewReader(typedEnc)); err != nil {
+			t.Fatalf("unable to decode type %d receipt into an IPLD node: %v", txType, err)
+		}
+		rctNode := rctBuilder.Build()
+		rctType, err := shared.GetTxType(rctNode)
+		if err != nil {
+			t.Fatalf("type %d receipt is missing TxType: %v", txType, err)
+		}
+		if rctType != txType {
+			t.Errorf("receipt TxType (%d) does not match expected TxType (%d)", rctType, txType)
+		}
+		rctWriter := new(bytes.Buffer)
+		if err := rct.Encode(rctNode, rctWriter); err != nil {
+			t.Fatalf("unable to encode type %d receipt into writer: %v", txType, err)
+		}
+		if !bytes.Equal(rctWriter.Bytes(), typedEnc) {
+			t.Errorf("type %d receipt encoding (%x) does not match the expected consensus encoding (%x)", txType, rctWriter.Bytes(), typedEnc)
+		}
+	}
+
+	lEnc, err := legacyReceipt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal legacy receipt binary: %v", err)
+	}
+	if err := rct.Decode(dageth.Type.Receipt.NewBuilder(), bytes.NewReader(append([]byte{types.LegacyTxType}, lEnc...))); err == nil {
+		t.Error("expected decoding a legacy receipt wrapped in a typed envelope to fail")
+	}
+}
+
+func TestPreByzantiumReceipt(t *testing.T) {
+	// receipts from before the Byzantium fork carry the intermediate state root instead of a status
+	preByzantiumReceipt := &types.Receipt{
+		PostState:         crypto.Keccak256([]byte("intermediate state root")),
+		CumulativeGasUsed: 21000,
+		Logs:              legacyReceipt.Logs,
+		Type:              types.LegacyTxType,
+	}
+	preByzantiumReceipt.Bloom = types.CreateBloom(preByzantiumReceipt)
+	rctEnc, err := preByzantiumReceipt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal pre-Byzantium receipt binary: %v", err)
+	}
+	rctBuilder := dageth.Type.Receipt.NewBuilder()
+	if err := rct.Decode(rctBuilder, bytes.NewReader(rctEnc)); err != nil {
+		t.Fatalf("unable to decode pre-Byzantium receipt into an IPLD node: %v", err)
+	}
+	rctNode := rctBuilder.Build()
+	statusNode, err := rctNode.LookupByString("Status")
+	if err != nil {
+		t.Fatalf("receipt is missing Status: %v", err)
+	}
+	if !statusNode.IsNull() {
+		t.Errorf("pre-Byzantium receipt Status should be null")
+	}
+	postStateNode, err := rctNode.LookupByString("PostState")
+	if err != nil {
+		t.Fatalf("receipt is missing PostState: %v", err)
+	}
+	postStateBy, err := postStateNode.AsBytes()
+	if err != nil {
+		t.Fatalf("receipt PostState should be of type Bytes: %v", err)
+	}
+	if !bytes.Equal(postStateBy, preByzantiumReceipt.PostState) {
+		t.Errorf("receipt post state (%x) does not match expected post state (%x)", postStateBy, preByzantiumReceipt.PostState)
+	}
+
+	rctWriter := new(bytes.Buffer)
+	if err := rct.Encode(rctNode, rctWriter); err != nil {
+		t.Fatalf("unable to encode pre-Byzantium receipt into writer: %v", err)
+	}
+	if !bytes.Equal(rctWriter.Bytes(), rctEnc) {
+		t.Errorf("pre-Byzantium receipt encoding (%x) does not match the expected consensus encoding (%x)", rctWriter.Bytes(), rctEnc)
+	}
+
+	// a receipt node carrying both a PostState and a Status is ambiguous
+	ambiguousRctBuilder := dageth.Type.Receipt.NewBuilder()
+	ma, err := ambiguousRctBuilder.BeginMap(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctIt := rctNode.MapIterator()
+	for !rctIt.Done() {
+		k, v, err := rctIt.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ma.AssembleKey().AssignNode(k); err != nil {
+			t.Fatal(err)
+		}
+		if key, _ := k.AsString(); key == "Status" {
+			if err := ma.AssembleValue().AssignBytes([]byte{0, 0, 0, 0, 0, 0, 0, 1}); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := ma.AssembleValue().AssignNode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ma.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rct.Encode(ambiguousRctBuilder.Build(), new(bytes.Buffer)); err == nil {
+		t.Error("expected encoding a receipt with both PostState and Status to fail")
+	}
+}
+
+func TestReceiptBloomVerification(t *testing.T) {
+	verifyingDecoder := rct.DecodeOptions{VerifyBloom: true}
+	validReceipt := &types.Receipt{
+		Status:            types.ReceiptStatusSuccessful,
+		CumulativeGasUsed: 1,
+		Logs:              dynamicFeeReceipt.Logs,
+		Type:              types.DynamicFeeTxType,
+	}
+	validReceipt.Bloom = types.CreateBloom(validReceipt)
+	validEnc, err := validReceipt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal receipt binary: %v", err)
+	}
+	if err := verifyingDecoder.Decode(dageth.Type.Receipt.NewBuilder(), bytes.NewReader(validEnc)); err != nil {
+		t.Fatalf("unable to decode receipt with a valid bloom: %v", err)
+	}
+
+	tamperedReceipt := *validReceipt
+	tamperedReceipt.Bloom = types.BytesToBloom(crypto.Keccak256([]byte("tampered")))
+	tamperedEnc, err := tamperedReceipt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal receipt binary: %v", err)
+	}
+	if err := verifyingDecoder.DecodeBytes(dageth.Type.Receipt.NewBuilder(), tamperedEnc); err == nil {
+		t.Error("expected decoding a receipt with a tampered bloom to fail when verifying the bloom")
+	}
+	// the bloom is not verified by default
+	if err := rct.DecodeBytes(dageth.Type.Receipt.NewBuilder(), tamperedEnc); err != nil {
+		t.Errorf("unable to decode receipt with a tampered bloom without verification: %v", err)
+	}
+}
+
+func TestReceiptStrictEncoding(t *testing.T) {
+	enc, err := dynamicFeeReceipt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal receipt binary: %v", err)
+	}
+	rctBuilder := dageth.Type.Receipt.NewBuilder()
+	if err := rct.DecodeBytes(rctBuilder, enc); err != nil {
+		t.Fatalf("unable to decode receipt into an IPLD node: %v", err)
+	}
+	rctNode := rctBuilder.Build()
+	strictEncoder := rct.EncodeOptions{Strict: true}
+	strictEnc, err := strictEncoder.AppendEncode(nil, rctNode)
+	if err != nil {
+		t.Fatalf("unable to strictly encode receipt: %v", err)
+	}
+	if !bytes.Equal(strictEnc, enc) {
+		t.Errorf("receipt encoding (%x) does not match the expected consensus encoding (%x)", strictEnc, enc)
+	}
+
+	// swap in the LogRootCID of a receipt without logs
+	emptyReceipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 1, Type: types.DynamicFeeTxType}
+	emptyEnc, err := emptyReceipt.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal receipt binary: %v", err)
+	}
+	emptyBuilder := dageth.Type.Receipt.NewBuilder()
+	if err := rct.DecodeBytes(emptyBuilder, emptyEnc); err != nil {
+		t.Fatalf("unable to decode receipt into an IPLD node: %v", err)
+	}
+	emptyLogRoot, err := emptyBuilder.Build().LookupByString("LogRootCID")
+	if err != nil {
+		t.Fatalf("receipt is missing LogRootCID: %v", err)
+	}
+	swappedBuilder := dageth.Type.Receipt.NewBuilder()
+	ma, err := swappedBuilder.BeginMap(rctNode.Length())
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := rctNode.MapIterator()
+	for !it.Done() {
+		k, v, err := it.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, _ := k.AsString()
+		if key == "LogRootCID" {
+			v = emptyLogRoot
+		}
+		if err := ma.AssembleKey().AssignString(key); err != nil {
+			t.Fatal(err)
+		}
+		if err := ma.AssembleValue().AssignNode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ma.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	swappedNode := swappedBuilder.Build()
+	if err := rct.EncodeWithOptions(swappedNode, new(bytes.Buffer), strictEncoder); err == nil {
+		t.Error("expected an error strictly encoding a receipt whose LogRootCID does not match its logs")
+	}
+	// the LogRootCID is not checked by default
+	if _, err := rct.AppendEncode(nil, swappedNode); err != nil {
+		t.Errorf("unable to encode receipt with a mismatched LogRootCID without strictness: %v", err)
+	}
+}
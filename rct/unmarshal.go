@@ -1,7 +1,6 @@
 package rct
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,17 +11,76 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
 	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
-	"github.com/multiformats/go-multihash"
 	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/profile"
+	"github.com/vulcanize/go-codec-dageth/shared"
 )
 
+// logTrieMulticodec is the codec of the log trie referenced by LogRootCID,
+// declared here as the log_trie package depends on this one through the trie package
+const logTrieMulticodec = uint64(0x99) // Proposed
+
+// DecodeOptions can be used to customize the behavior of receipt decoding.
+// The zero value is the default behavior used by the registered codec.
+type DecodeOptions struct {
+	// VerifyBloom causes the decoder to recompute the logs bloom from the decoded logs and
+	// reject receipts whose Bloom does not match it, catching corrupted or tampered payloads
+	VerifyBloom bool
+	// LinkCodecs overrides the multicodec type of the link to the log trie of the receipt
+	LinkCodecs shared.LinkCodecs
+	// LinkHashes overrides the multihash type of the link to the log trie of the receipt
+	LinkHashes shared.LinkHashes
+	// Rules, the forks active at the block of the receipt, causes the decoder to reject receipts that do not have
+	// the layout of those forks, e.g. a Status before Byzantium, instead of inferring it from the encoding
+	Rules *profile.Rules
+}
+
 // Decode provides an IPLD codec decode interface for eth receipt IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code 0x95 when this package is invoked via init.
 func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return DecodeOptions{}.Decode(na, in)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+// A payload starting with a byte in [0x01, 0x7f] is an EIP-2718 typed receipt envelope, anything else is a legacy
+// RLP list. Every receipt type shares the same inner payload, so types this codec does not know about yet are
+// decoded too; either way the resulting node carries the TxType.
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeOptions{}.DecodeBytes(na, src)
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
 	var src []byte
 	if buf, ok := in.(interface{ Bytes() []byte }); ok {
 		src = buf.Bytes()
@@ -33,52 +91,90 @@ func Decode(na ipld.NodeAssembler, in io.Reader) error {
 			return err
 		}
 	}
-	return DecodeBytes(na, src)
+	return cfg.DecodeBytes(na, src)
 }
 
-// DecodeBytes is like Decode, but it uses an input buffer directly.
-// Decode will grab or read all the bytes from an io.Reader anyway, so this can
-// save having to copy the bytes or create a bytes.Buffer.
-func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
-	var rct types.Receipt
-	if err := rct.UnmarshalBinary(src); err != nil {
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	if len(src) == 0 {
+		return fmt.Errorf("invalid DAG-ETH Receipt binary (empty input)")
+	}
+	txType, payload := uint8(types.LegacyTxType), src
+	if src[0] <= 0x7f {
+		if src[0] == types.LegacyTxType {
+			return fmt.Errorf("invalid DAG-ETH Receipt binary (legacy receipts cannot be wrapped in a typed envelope)")
+		}
+		txType, payload = src[0], src[1:]
+	}
+	rct := new(receiptRLP)
+	if err := rlp.DecodeBytes(payload, rct); err != nil {
+		return err
+	}
+	var receipt types.Receipt
+	if err := setReceiptFields(&receipt, txType, rct); err != nil {
 		return err
 	}
-	return DecodeReceipt(na, rct)
+	return cfg.DecodeReceipt(na, receipt)
+}
+
+// VerifyBloom recomputes the logs bloom from the receipt's logs and returns an error if it does not match the receipt's Bloom
+func VerifyBloom(receipt *types.Receipt) error {
+	if expected := types.CreateBloom(receipt); expected != receipt.Bloom {
+		return fmt.Errorf("receipt Bloom (%x) does not match the bloom derived from its logs (%x)", receipt.Bloom.Bytes(), expected.Bytes())
+	}
+	return nil
 }
 
 // DecodeReceipt unpacks a go-ethereum Receipt into the NodeAssembler
 func DecodeReceipt(na ipld.NodeAssembler, receipt types.Receipt) error {
+	return DecodeOptions{}.DecodeReceipt(na, receipt)
+}
+
+// DecodeReceipt is like the package level DecodeReceipt, but uses the provided options
+func (cfg DecodeOptions) DecodeReceipt(na ipld.NodeAssembler, receipt types.Receipt) error {
+	if cfg.VerifyBloom {
+		if err := VerifyBloom(&receipt); err != nil {
+			return fmt.Errorf("invalid DAG-ETH Receipt binary (%v)", err)
+		}
+	}
+	if cfg.Rules != nil {
+		if err := cfg.Rules.CheckReceipt(&receipt); err != nil {
+			return fmt.Errorf("invalid DAG-ETH Receipt binary (%v)", err)
+		}
+	}
 	ma, err := na.BeginMap(5)
 	if err != nil {
 		return err
 	}
 	for _, upFunc := range requiredUnpackFuncs {
-		if err := upFunc(ma, receipt); err != nil {
+		if err := upFunc(cfg, ma, receipt); err != nil {
 			return fmt.Errorf("invalid DAG-ETH Receipt binary (%v)", err)
 		}
 	}
 	return ma.Finish()
 }
 
-var requiredUnpackFuncs = []func(ipld.MapAssembler, types.Receipt) error{
-	unpackTxType,
-	unpackPostStateOrStatus,
-	unpackCumulativeGasUsed,
-	unpackBloom,
-	unpackLogs,
-	unpackLogRootCID,
+var requiredUnpackFuncs = []func(DecodeOptions, ipld.MapAssembler, types.Receipt) error{
+	DecodeOptions.unpackTxType,
+	DecodeOptions.unpackPostStateOrStatus,
+	DecodeOptions.unpackCumulativeGasUsed,
+	DecodeOptions.unpackBloom,
+	DecodeOptions.unpackLogs,
+	DecodeOptions.unpackLogRootCID,
 }
 
-func unpackTxType(ma ipld.MapAssembler, rct types.Receipt) error {
+func (cfg DecodeOptions) unpackTxType(ma ipld.MapAssembler, rct types.Receipt) error {
 	if err := ma.AssembleKey().AssignString("TxType"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes([]byte{rct.Type})
 }
 
-func unpackPostStateOrStatus(ma ipld.MapAssembler, rct types.Receipt) error {
+func (cfg DecodeOptions) unpackPostStateOrStatus(ma ipld.MapAssembler, rct types.Receipt) error {
 	if len(rct.PostState) > 0 {
+		if len(rct.PostState) != len(common.Hash{}) {
+			return fmt.Errorf("receipt PostState should be a %d byte state root, got %d bytes", len(common.Hash{}), len(rct.PostState))
+		}
 		if err := ma.AssembleKey().AssignString("PostState"); err != nil {
 			return err
 		}
@@ -112,23 +208,22 @@ func unpackPostStateOrStatus(ma ipld.MapAssembler, rct types.Receipt) error {
 	return ma.AssembleValue().AssignNull()
 }
 
-func unpackCumulativeGasUsed(ma ipld.MapAssembler, rct types.Receipt) error {
-	cguBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(cguBytes, rct.CumulativeGasUsed)
+func (cfg DecodeOptions) unpackCumulativeGasUsed(ma ipld.MapAssembler, rct types.Receipt) error {
+	cguBytes := shared.Uint64ToBytes(rct.CumulativeGasUsed)
 	if err := ma.AssembleKey().AssignString("CumulativeGasUsed"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes(cguBytes)
 }
 
-func unpackBloom(ma ipld.MapAssembler, rct types.Receipt) error {
+func (cfg DecodeOptions) unpackBloom(ma ipld.MapAssembler, rct types.Receipt) error {
 	if err := ma.AssembleKey().AssignString("Bloom"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes(rct.Bloom.Bytes())
 }
 
-func unpackLogs(ma ipld.MapAssembler, rct types.Receipt) error {
+func (cfg DecodeOptions) unpackLogs(ma ipld.MapAssembler, rct types.Receipt) error {
 	if err := ma.AssembleKey().AssignString("Logs"); err != nil {
 		return err
 	}
@@ -175,16 +270,15 @@ func unpackLogs(ma ipld.MapAssembler, rct types.Receipt) error {
 	return la.Finish()
 }
 
-func unpackLogRootCID(ma ipld.MapAssembler, rct types.Receipt) error {
+func (cfg DecodeOptions) unpackLogRootCID(ma ipld.MapAssembler, rct types.Receipt) error {
 	logTrieRoot, err := processLogs(rct.Logs)
 	if err != nil {
 		return err
 	}
-	logMh, err := multihash.Encode(logTrieRoot, log.MultiHashType)
+	logCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(logTrieMulticodec), cfg.LinkHashes.Type(log.MultiHashType), logTrieRoot)
 	if err != nil {
 		return err
 	}
-	logCID := cid.NewCidV1(log.MultiCodecType, logMh)
 	logLinkCID := cidlink.Link{Cid: logCID}
 	if err := ma.AssembleKey().AssignString("LogRootCID"); err != nil {
 		return err
@@ -213,20 +307,16 @@ func processLogs(logs []*types.Log) ([]byte, error) {
 // It contributes to the creation of the trie node objects.
 type localTrie struct {
 	DB     ethdb.Database
-	trieDB *trie.Database
+	trieDB *triedb.Database
 	trie   *trie.Trie
 }
 
 // newlocalTrie initializes and returns a localTrie object
 func newlocalTrie() *localTrie {
-	var err error
 	lt := &localTrie{}
 	lt.DB = rawdb.NewMemoryDatabase()
-	lt.trieDB = trie.NewDatabase(lt.DB)
-	lt.trie, err = trie.New(common.Hash{}, lt.trieDB)
-	if err != nil {
-		panic(err)
-	}
+	lt.trieDB = triedb.NewDatabase(lt.DB, nil)
+	lt.trie = trie.NewEmpty(lt.trieDB)
 	return lt
 }
 
@@ -237,7 +327,7 @@ func (lt *localTrie) add(idx int, rawdata []byte) error {
 	if err != nil {
 		panic(err)
 	}
-	return lt.trie.TryUpdate(key, rawdata)
+	return lt.trie.Update(key, rawdata)
 }
 
 // rootHash returns the computed trie root.
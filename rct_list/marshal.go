@@ -13,28 +13,78 @@ import (
 	"github.com/vulcanize/go-codec-dageth/shared"
 )
 
+// EncodeOptions can be used to customize the behavior of receipt list encoding.
+// The zero value is the default behavior used by the registered codec.
+type EncodeOptions struct {
+	// Receipt customizes how the receipts of the list are encoded
+	Receipt dageth_rct.EncodeOptions
+}
+
 // Encode provides an IPLD codec encode interface for eth receipt list IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code (tbd) when this package is invoked via init.
 func Encode(node ipld.Node, w io.Writer) error {
-	// 1KiB can be allocated on the stack, and covers most small nodes
-	// without having to grow the buffer and cause allocations.
-	enc := make([]byte, 0, 1024)
-
-	enc, err := AppendEncode(enc, node)
-	if err != nil {
-		return err
-	}
-	_, err = w.Write(enc)
-	return err
+	return EncodeOptions{}.Encode(node, w)
 }
 
 // AppendEncode is like Encode, but it uses a destination buffer directly.
 // This means less copying of bytes, and if the destination has enough capacity,
 // fewer allocations.
 func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return EncodeOptions{}.AppendEncode(enc, inNode)
+}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// EncodeRcts packs the node into a go-ethereum Receipts
+func EncodeRcts(rcts *[]*types.Receipt, inNode ipld.Node) error {
+	return EncodeOptions{}.EncodeRcts(rcts, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options.
+// It streams the list to w receipt by receipt, and the logs of each receipt one by one, see shared.StreamRLP.
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	// Wrap in a typed node for some basic schema form checking
+	builder := dageth.Type.Receipts.NewBuilder()
+	if err := builder.AssignNode(node); err != nil {
+		return err
+	}
+	rctsNode := builder.Build()
+	items := make(shared.List, 0, rctsNode.Length())
+	rctsIt := rctsNode.ListIterator()
+	for !rctsIt.Done() {
+		_, rctNode, err := rctsIt.Next()
+		if err != nil {
+			return err
+		}
+		item, err := cfg.Receipt.StreamItem(rctNode)
+		if err != nil {
+			return fmt.Errorf("invalid DAG-ETH Receipts form (%v)", err)
+		}
+		// typed receipts are listed as byte strings
+		if _, ok := item.(shared.Typed); ok {
+			item = shared.Wrapped{Item: item}
+		}
+		items = append(items, item)
+	}
+	if err := shared.StreamRLP(w, items); err != nil {
+		return fmt.Errorf("invalid DAG-ETH Receipts form (unable to RLP encode receipts: %v)", err)
+	}
+	return nil
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	rcts := make([]*types.Receipt, 0, inNode.Length())
-	if err := EncodeRcts(&rcts, inNode); err != nil {
+	if err := cfg.EncodeRcts(&rcts, inNode); err != nil {
 		return enc, err
 	}
 	wbs := shared.NewWriteableByteSlice(&enc)
@@ -44,8 +94,8 @@ func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	return enc, nil
 }
 
-// EncodeRcts packs the node into a go-ethereum Receipts
-func EncodeRcts(rcts *[]*types.Receipt, inNode ipld.Node) error {
+// EncodeRcts is like the package level EncodeRcts, but uses the provided options
+func (cfg EncodeOptions) EncodeRcts(rcts *[]*types.Receipt, inNode ipld.Node) error {
 	// Wrap in a typed node for some basic schema form checking
 	builder := dageth.Type.Receipts.NewBuilder()
 	if err := builder.AssignNode(inNode); err != nil {
@@ -59,7 +109,7 @@ func EncodeRcts(rcts *[]*types.Receipt, inNode ipld.Node) error {
 			return err
 		}
 		rct := new(types.Receipt)
-		if err := dageth_rct.EncodeReceipt(rct, rctNode); err != nil {
+		if err := cfg.Receipt.EncodeReceipt(rct, rctNode); err != nil {
 			return fmt.Errorf("invalid DAG-ETH Receipts form (%v)", err)
 		}
 		*rcts = append(*rcts, rct)
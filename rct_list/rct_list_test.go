@@ -2,6 +2,7 @@ package rct_list_test
 
 import (
 	"bytes"
+	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -134,3 +135,64 @@ func testReceiptsEncode(t *testing.T) {
 		t.Errorf("receipt list encoding (%x) does not match the expected RLP encoding (%x)", encodedReceiptsBytes, receiptsRLP)
 	}
 }
+
+// writeRecorder records the largest write made to it
+type writeRecorder struct {
+	bytes.Buffer
+	largest int
+}
+
+func (w *writeRecorder) Write(b []byte) (int, error) {
+	if len(b) > w.largest {
+		w.largest = len(b)
+	}
+	return w.Buffer.Write(b)
+}
+
+func TestStreamingEncode(t *testing.T) {
+	// receipts emitting many logs, in both the legacy and typed layouts
+	var logs []*types.Log
+	for i := 0; i < 1000; i++ {
+		logs = append(logs, &types.Log{
+			Address: common.BytesToAddress([]byte{byte(i)}),
+			Topics:  []common.Hash{common.BigToHash(big.NewInt(int64(i)))},
+			Data:    bytes.Repeat([]byte{byte(i)}, 64),
+		})
+	}
+	receipts := types.Receipts{
+		{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, Logs: logs, Type: types.LegacyTxType},
+		{Status: types.ReceiptStatusFailed, CumulativeGasUsed: 1 << 40, Logs: logs, Type: types.DynamicFeeTxType},
+		{PostState: mockHash, CumulativeGasUsed: 0x7f, Type: types.AccessListTxType},
+	}
+	for _, receipt := range receipts {
+		receipt.Bloom = types.CreateBloom(receipt)
+	}
+	expected, err := rlp.EncodeToBytes(receipts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb := dageth.Type.Receipts.NewBuilder()
+	if err := rct_list.Decode(nb, bytes.NewReader(expected)); err != nil {
+		t.Fatalf("unable to decode receipts: %v", err)
+	}
+	node := nb.Build()
+
+	w := new(writeRecorder)
+	if err := rct_list.Encode(node, w); err != nil {
+		t.Fatalf("unable to encode receipts: %v", err)
+	}
+	if !bytes.Equal(w.Bytes(), expected) {
+		t.Fatal("streamed receipt list encoding does not match the expected RLP encoding")
+	}
+	// the receipts are written log by log, rather than in one piece
+	if w.largest > len(types.Bloom{})+8 {
+		t.Errorf("expected no write larger than a bloom, got a write of %d bytes out of %d", w.largest, len(expected))
+	}
+	enc, err := rct_list.AppendEncode(nil, node)
+	if err != nil {
+		t.Fatalf("unable to encode receipts: %v", err)
+	}
+	if !bytes.Equal(enc, expected) {
+		t.Error("buffered receipt list encoding does not match the expected RLP encoding")
+	}
+}
@@ -7,15 +7,64 @@ import (
 
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 
 	dageth_rct "github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/shared"
 )
 
+// DecodeOptions can be used to customize the behavior of receipt list decoding.
+// The zero value is the default behavior used by the registered codec.
+type DecodeOptions struct {
+	// Receipt customizes how the receipts of the list are decoded
+	Receipt dageth_rct.DecodeOptions
+}
+
 // Decode provides an IPLD codec decode interface for eth receipt list IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code tbd when this package is invoked via init.
 func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return DecodeOptions{}.Decode(na, in)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeOptions{}.DecodeBytes(na, src)
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// DecodeRcts unpacks a list of go-ethereum Receipts into the NodeAssembler
+func DecodeRcts(na ipld.NodeAssembler, rcts []*types.Receipt) error {
+	return DecodeOptions{}.DecodeRcts(na, rcts)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
 	var src []byte
 	if buf, ok := in.(interface{ Bytes() []byte }); ok {
 		src = buf.Bytes()
@@ -26,23 +75,21 @@ func Decode(na ipld.NodeAssembler, in io.Reader) error {
 			return err
 		}
 	}
-	return DecodeBytes(na, src)
+	return cfg.DecodeBytes(na, src)
 }
 
-// DecodeBytes is like Decode, but it uses an input buffer directly.
-// Decode will grab or read all the bytes from an io.Reader anyway, so this can
-// save having to copy the bytes or create a bytes.Buffer.
-func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
 	var rcts []*types.Receipt
 	if err := rlp.DecodeBytes(src, &rcts); err != nil {
 		return err
 	}
 
-	return DecodeRcts(na, rcts)
+	return cfg.DecodeRcts(na, rcts)
 }
 
-// DecodeRcts unpacks a list of go-ethereum Receipts into the NodeAssembler
-func DecodeRcts(na ipld.NodeAssembler, rcts []*types.Receipt) error {
+// DecodeRcts is like the package level DecodeRcts, but uses the provided options
+func (cfg DecodeOptions) DecodeRcts(na ipld.NodeAssembler, rcts []*types.Receipt) error {
 	la, err := na.BeginList(int64(len(rcts)))
 	if err != nil {
 		return err
@@ -50,7 +97,7 @@ func DecodeRcts(na ipld.NodeAssembler, rcts []*types.Receipt) error {
 	for i, rct := range rcts {
 		// node := dageth.Type.Receipt.NewBuilder()
 		node := la.ValuePrototype(int64(i)).NewBuilder()
-		if err := dageth_rct.DecodeReceipt(node, *rct); err != nil {
+		if err := cfg.Receipt.DecodeReceipt(node, *rct); err != nil {
 			return fmt.Errorf("invalid DAG-ETH Receipts binary (%v)", err)
 		}
 		if err := la.AssembleValue().AssignNode(node.Build()); err != nil {
@@ -17,6 +17,7 @@ import (
 	"github.com/multiformats/go-multihash"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/rct"
 	"github.com/vulcanize/go-codec-dageth/rct_trie"
 	"github.com/vulcanize/go-codec-dageth/shared"
 	"github.com/vulcanize/go-codec-dageth/trie"
@@ -344,7 +345,11 @@ func verifyExtensionNodeContents(t *testing.T) {
 	if err != nil {
 		t.Fatalf("receipt trie extension node missing Child: %v", err)
 	}
-	childLink, err := childNode.AsLink()
+	childLinkNode, err := childNode.LookupByString("Link")
+	if err != nil {
+		t.Fatalf("receipt trie extension node Child should be of type Link: %v", err)
+	}
+	childLink, err := childLinkNode.AsLink()
 	if err != nil {
 		t.Fatalf("receipt trie extension node Child should be of kind Link: %v", err)
 	}
@@ -616,3 +621,16 @@ func testReceiptTrieEncode(t *testing.T) {
 		t.Errorf("receipt trie leaf node encoding (%x) does not match the expected consenus encoding (%x)", encodedLeafBytesALReceipt, mockLeafNodeRLPALReceipt)
 	}
 }
+
+func TestReceiptTrieBloomVerification(t *testing.T) {
+	// the mock legacy receipt has logs but an empty bloom
+	opts := trie.DecodeOptions{Receipt: rct.DecodeOptions{VerifyBloom: true}}
+	if err := rct_trie.DecodeWithOptions(dageth.Type.TrieNode.NewBuilder(), bytes.NewReader(mockLeafNodeRLPLegacyReceipt), opts); err == nil {
+		t.Error("expected decoding a receipt trie leaf with a mismatched bloom to fail when verifying receipt blooms")
+	}
+
+	// branch and extension nodes carry no receipt to verify
+	if err := rct_trie.DecodeWithOptions(dageth.Type.TrieNode.NewBuilder(), bytes.NewReader(mockExtensionNodeRLP), opts); err != nil {
+		t.Errorf("unable to decode receipt trie extension node while verifying receipt blooms: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package request
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/ssz"
+)
+
+// Encode provides an IPLD codec encode interface for eth request IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0xf3 when this package is invoked via init.
+func Encode(node ipld.Node, w io.Writer) error {
+	return shared.WriteEncoded(w, node, AppendEncode)
+}
+
+// AppendEncode is like Encode, but it uses a destination buffer directly.
+// This means less copying of bytes, and if the destination has enough capacity,
+// fewer allocations.
+func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	// Wrap in a typed node for some basic schema form checking
+	builder := dageth.Type.Request.NewBuilder()
+	if err := builder.AssignNode(inNode); err != nil {
+		return nil, err
+	}
+	node := builder.Build()
+	enc, err := appendRequest(enc, node)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DAG-ETH Request form (%v)", err)
+	}
+	return enc, nil
+}
+
+func appendRequest(enc []byte, node ipld.Node) ([]byte, error) {
+	typeNode, err := node.LookupByString("RequestType")
+	if err != nil {
+		return nil, fmt.Errorf("request is missing a RequestType node: %v", err)
+	}
+	typeBytes, err := typeNode.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(typeBytes) != 1 {
+		return nil, fmt.Errorf("request must have a 1 byte RequestType, got %d bytes", len(typeBytes))
+	}
+	requestType := typeBytes[0]
+	if int(requestType) >= len(requestLists) {
+		return nil, fmt.Errorf("unknown request type %#x", requestType)
+	}
+	enc = append(enc, requestType)
+	for i, requests := range requestLists {
+		listNode, err := node.LookupByString(requests.field)
+		if err != nil {
+			return nil, fmt.Errorf("request is missing a %s node: %v", requests.field, err)
+		}
+		if i != int(requestType) {
+			if !listNode.IsNull() {
+				return nil, fmt.Errorf("request of type %#x must have null %s", requestType, requests.field)
+			}
+			continue
+		}
+		if listNode.IsNull() {
+			return nil, fmt.Errorf("request of type %#x is missing its %s", requestType, requests.field)
+		}
+		if enc, err = ssz.AppendEncode(requests.list, enc, listNode); err != nil {
+			return nil, err
+		}
+	}
+	return enc, nil
+}
+
+// EncodeOptions can be used to customize the behavior of request encoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type EncodeOptions struct{}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return Encode(node, w)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return AppendEncode(enc, inNode)
+}
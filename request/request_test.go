@@ -0,0 +1,150 @@
+package request_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	"github.com/multiformats/go-multihash"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/consensus"
+	"github.com/vulcanize/go-codec-dageth/request"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+/* IPLD Schema
+type DepositRequest struct {
+	Pubkey                Bytes
+	WithdrawalCredentials Hash
+	Amount                Uint
+	Signature             Bytes
+	Index                 Uint
+}
+
+type WithdrawalRequest struct {
+	SourceAddress   Address
+	ValidatorPubkey Bytes
+	Amount          Uint
+}
+
+type ConsolidationRequest struct {
+	SourceAddress Address
+	SourcePubkey  Bytes
+	TargetPubkey  Bytes
+}
+
+type Request struct {
+	RequestType    Uint
+	Deposits       nullable DepositRequests
+	Withdrawals    nullable WithdrawalRequests
+	Consolidations nullable ConsolidationRequests
+}
+*/
+
+func TestDepositRequest(t *testing.T) {
+	pubkey, credentials, signature := shared.RandomBytes(48), shared.RandomHash(), shared.RandomBytes(96)
+	// the amount and index of a deposit are little-endian in the request data, and big-endian in the node
+	amount, index := make([]byte, 8), make([]byte, 8)
+	binary.LittleEndian.PutUint64(amount, 32_000_000_000)
+	binary.LittleEndian.PutUint64(index, 1_500_000)
+	var data []byte
+	data = append(data, request.DepositRequestType)
+	data = append(data, pubkey...)
+	data = append(data, credentials.Bytes()...)
+	data = append(data, amount...)
+	data = append(data, signature...)
+	data = append(data, index...)
+
+	nb := dageth.Type.Request.NewBuilder()
+	if err := request.DecodeBytes(nb, data); err != nil {
+		t.Fatalf("unable to decode deposit request: %v", err)
+	}
+	requestNode := nb.Build()
+	for _, field := range []string{"Withdrawals", "Consolidations"} {
+		if n, err := requestNode.LookupByString(field); err != nil || !n.IsNull() {
+			t.Errorf("deposit request %s should be null", field)
+		}
+	}
+	depositsNode, err := requestNode.LookupByString("Deposits")
+	if err != nil {
+		t.Fatalf("deposit request is missing Deposits: %v", err)
+	}
+	if depositsNode.Length() != 1 {
+		t.Fatalf("expected 1 deposit, got %d", depositsNode.Length())
+	}
+	depositNode, _ := depositsNode.LookupByIndex(0)
+	pubkeyNode, _ := depositNode.LookupByString("Pubkey")
+	if pk, _ := pubkeyNode.AsBytes(); !bytes.Equal(pk, pubkey) {
+		t.Errorf("deposit pubkey (%x) does not match expected pubkey (%x)", pk, pubkey)
+	}
+	amountNode, _ := depositNode.LookupByString("Amount")
+	if amt, _ := amountNode.AsBytes(); binary.BigEndian.Uint64(amt) != 32_000_000_000 {
+		t.Errorf("deposit amount (%x) does not match the big-endian expected amount", amt)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := request.Encode(requestNode, buf); err != nil {
+		t.Fatalf("unable to encode deposit request: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("deposit request encoding (%x) does not match the input (%x)", buf.Bytes(), data)
+	}
+
+	// requests are linked by their sha256 hash, which is what the requests hash of the header hashes
+	mh, _ := multihash.Sum(data, multihash.SHA2_256, -1)
+	if err := request.DecodeVerified(dageth.Type.Request.NewBuilder(), bytes.NewReader(data), cid.NewCidV1(request.MultiCodecType, mh)); err != nil {
+		t.Errorf("unable to decode deposit request against its CID: %v", err)
+	}
+}
+
+func TestRequestTypes(t *testing.T) {
+	g := testutil.NewGenerator(1)
+	for n := 0; n < 8; n++ {
+		for i, data := range g.Requests() {
+			nb := dageth.Type.Request.NewBuilder()
+			if err := request.DecodeBytes(nb, data); err != nil {
+				t.Fatalf("unable to decode request of type %d: %v", i, err)
+			}
+			enc, err := request.AppendEncode(nil, nb.Build())
+			if err != nil {
+				t.Fatalf("unable to encode request of type %d: %v", i, err)
+			}
+			if !bytes.Equal(enc, data) {
+				t.Errorf("request of type %d encoding (%x) does not match the input (%x)", i, enc, data)
+			}
+		}
+	}
+}
+
+func TestInvalidRequests(t *testing.T) {
+	withdrawal := append([]byte{request.WithdrawalRequestType}, testutil.NewGenerator(2).SSZ(consensus.WithdrawalRequest)...)
+	for name, data := range map[string][]byte{
+		"empty request":              {},
+		"request of an unknown type": {0x03},
+		"partial withdrawal request": withdrawal[:len(withdrawal)-1],
+		"too many consolidations":    append([]byte{request.ConsolidationRequestType}, make([]byte, 3*116)...),
+	} {
+		if err := request.DecodeBytes(dageth.Type.Request.NewBuilder(), data); err == nil {
+			t.Errorf("expected decoding the %s to fail", name)
+		}
+	}
+
+	// the list of the request type has to be the only list of the request
+	node, err := qp.BuildMap(dageth.Type.Request, 4, func(ma ipld.MapAssembler) {
+		qp.MapEntry(ma, "RequestType", qp.Bytes([]byte{request.ConsolidationRequestType}))
+		qp.MapEntry(ma, "Deposits", qp.List(0, func(ipld.ListAssembler) {}))
+		qp.MapEntry(ma, "Withdrawals", qp.Null())
+		qp.MapEntry(ma, "Consolidations", qp.Null())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := request.AppendEncode(nil, node); err == nil {
+		t.Error("expected encoding a consolidation request holding deposits to fail")
+	}
+}
@@ -0,0 +1,124 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/consensus"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/ssz"
+)
+
+// The EIP-7685 request types
+const (
+	DepositRequestType       = byte(0x00) // EIP-6110
+	WithdrawalRequestType    = byte(0x01) // EIP-7002
+	ConsolidationRequestType = byte(0x02) // EIP-7251
+)
+
+// requestLists are the Request fields holding the requests of each request type, indexed by the type, along with
+// the SSZ list type whose serialization is the data of requests of that type
+var requestLists = []struct {
+	field string
+	list  ssz.List
+}{
+	DepositRequestType:       {"Deposits", consensus.DepositRequests},
+	WithdrawalRequestType:    {"Withdrawals", consensus.WithdrawalRequests},
+	ConsolidationRequestType: {"Consolidations", consensus.ConsolidationRequests},
+}
+
+// Decode provides an IPLD codec decode interface for eth request IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0xf3 when this package is invoked via init.
+func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	var src []byte
+	if buf, ok := in.(interface{ Bytes() []byte }); ok {
+		src = buf.Bytes()
+	} else {
+		var err error
+		src, err = ioutil.ReadAll(in)
+		if err != nil {
+			return err
+		}
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	if len(src) == 0 {
+		return fmt.Errorf("invalid DAG-ETH Request binary (request has no type)")
+	}
+	requestType := src[0]
+	if int(requestType) >= len(requestLists) {
+		return fmt.Errorf("invalid DAG-ETH Request binary (unknown request type %#x)", requestType)
+	}
+	ma, err := na.BeginMap(int64(1 + len(requestLists)))
+	if err != nil {
+		return err
+	}
+	if err := ma.AssembleKey().AssignString("RequestType"); err != nil {
+		return err
+	}
+	if err := ma.AssembleValue().AssignBytes([]byte{requestType}); err != nil {
+		return err
+	}
+	for i, requests := range requestLists {
+		if err := ma.AssembleKey().AssignString(requests.field); err != nil {
+			return err
+		}
+		if i != int(requestType) {
+			if err := ma.AssembleValue().AssignNull(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := ssz.Decode(requests.list, ma.AssembleValue(), src[1:]); err != nil {
+			return fmt.Errorf("invalid DAG-ETH Request binary (%v)", err)
+		}
+	}
+	return ma.Finish()
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeOptions can be used to customize the behavior of request decoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type DecodeOptions struct{}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return Decode(na, in)
+}
+
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeBytes(na, src)
+}
@@ -0,0 +1,97 @@
+package request_list
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/request"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+const sha256Length = 32
+
+// EncodeOptions can be used to customize the behavior of request list encoding.
+// The zero value is the default behavior used by the registered codec.
+type EncodeOptions struct {
+	// Strict causes the encoder to reject links that do not carry the multicodec type
+	// the request list decoder builds them with
+	Strict bool
+	// LinkCodecs overrides the multicodec type Strict expects links to carry,
+	// it should match the DecodeOptions the node was decoded with
+	LinkCodecs shared.LinkCodecs
+	// LinkHashes overrides the multihash types Strict expects links to carry,
+	// it should match the DecodeOptions the node was decoded with
+	LinkHashes shared.LinkHashes
+}
+
+// Encode provides an IPLD codec encode interface for eth request list IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0xf4 when this package is invoked via init.
+func Encode(node ipld.Node, w io.Writer) error {
+	return EncodeOptions{}.Encode(node, w)
+}
+
+// AppendEncode is like Encode, but it uses a destination buffer directly.
+// This means less copying of bytes, and if the destination has enough capacity,
+// fewer allocations.
+func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return EncodeOptions{}.AppendEncode(enc, inNode)
+}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return shared.WriteEncoded(w, node, cfg.AppendEncode)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	// Wrap in a typed node for some basic schema form checking
+	builder := dageth.Type.Requests.NewBuilder()
+	if err := builder.AssignNode(inNode); err != nil {
+		return nil, err
+	}
+	node := builder.Build()
+	for itr := node.ListIterator(); !itr.Done(); {
+		i, requestNode, err := itr.Next()
+		if err != nil {
+			return nil, err
+		}
+		requestLink, err := requestNode.AsLink()
+		if err != nil {
+			return nil, err
+		}
+		requestCIDLink, ok := requestLink.(cidlink.Link)
+		if !ok {
+			return nil, fmt.Errorf("invalid DAG-ETH Requests form (request %d must be a CID)", i)
+		}
+		if cfg.Strict {
+			if err := shared.CheckLink(requestCIDLink.Cid, cfg.LinkCodecs.Codec(request.MultiCodecType), cfg.LinkHashes.Type(request.MultiHashType)); err != nil {
+				return nil, fmt.Errorf("invalid DAG-ETH Requests form (%v)", err)
+			}
+		}
+		decodedRequestMh, err := multihash.Decode(requestCIDLink.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("invalid DAG-ETH Requests form (unable to decode request %d multihash: %v)", i, err)
+		}
+		if decodedRequestMh.Code != multihash.SHA2_256 || len(decodedRequestMh.Digest) != sha256Length {
+			return nil, fmt.Errorf("invalid DAG-ETH Requests form (request %d must link to a sha2-256 hash)", i)
+		}
+		enc = append(enc, decodedRequestMh.Digest...)
+	}
+	return enc, nil
+}
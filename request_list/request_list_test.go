@@ -0,0 +1,116 @@
+package request_list_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/request"
+	"github.com/vulcanize/go-codec-dageth/request_list"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+/* IPLD Schema
+type Requests [&Request]
+*/
+
+func TestRequestListCodec(t *testing.T) {
+	requests := testutil.NewGenerator(1).Requests()
+	var listData []byte
+	var requestCIDs []cid.Cid
+	for _, req := range requests {
+		if len(req) <= 1 {
+			continue
+		}
+		requestHash := sha256.Sum256(req)
+		listData = append(listData, requestHash[:]...)
+		mh, _ := multihash.Sum(req, multihash.SHA2_256, -1)
+		requestCIDs = append(requestCIDs, cid.NewCidV1(request.MultiCodecType, mh))
+	}
+
+	nb := dageth.Type.Requests.NewBuilder()
+	if err := request_list.DecodeBytes(nb, listData); err != nil {
+		t.Fatalf("unable to decode request list: %v", err)
+	}
+	listNode := nb.Build()
+	if listNode.Length() != int64(len(requestCIDs)) {
+		t.Fatalf("expected %d requests, got %d", len(requestCIDs), listNode.Length())
+	}
+	for i, expected := range requestCIDs {
+		n, _ := listNode.LookupByIndex(int64(i))
+		if lnk, err := n.AsLink(); err != nil || lnk != (cidlink.Link{Cid: expected}) {
+			t.Errorf("expected request %d to link to %s, got %v (%v)", i, expected.String(), lnk, err)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := request_list.Encode(listNode, buf); err != nil {
+		t.Fatalf("unable to encode request list: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), listData) {
+		t.Errorf("request list encoding (%x) does not match the input (%x)", buf.Bytes(), listData)
+	}
+
+	// the request list hashes to the requests hash of the header, so the header RequestsCID links to it
+	requestsHash := types.CalcRequestsHash(requests)
+	mh, _ := multihash.Encode(requestsHash.Bytes(), multihash.SHA2_256)
+	listCID := cid.NewCidV1(request_list.MultiCodecType, mh)
+	if err := request_list.DecodeVerified(dageth.Type.Requests.NewBuilder(), bytes.NewReader(listData), listCID); err != nil {
+		t.Errorf("unable to decode request list against the CID of the requests hash: %v", err)
+	}
+}
+
+func TestRequestListLinks(t *testing.T) {
+	if err := request_list.DecodeBytes(dageth.Type.Requests.NewBuilder(), make([]byte, 33)); err == nil {
+		t.Error("expected decoding a request list with a partial hash to fail")
+	}
+
+	// only sha256 hashes can be encoded, and strict encoding also checks the multicodec type of the links
+	hash := shared.RandomHash()
+	keccakNode := requestList(t, testutil.KeccakCid(t, request.MultiCodecType, hash.Bytes()))
+	if _, err := request_list.AppendEncode(nil, keccakNode); err == nil {
+		t.Error("expected encoding a link with a keccak256 multihash to fail")
+	}
+	mh, _ := multihash.Encode(hash.Bytes(), multihash.SHA2_256)
+	txNode := requestList(t, cid.NewCidV1(cid.EthTx, mh))
+	if _, err := request_list.AppendEncode(nil, txNode); err != nil {
+		t.Errorf("unable to encode a link to another codec: %v", err)
+	}
+	if _, err := request_list.AppendEncodeWithOptions(nil, txNode, request_list.EncodeOptions{Strict: true}); err == nil {
+		t.Error("expected strictly encoding a link to another codec to fail")
+	}
+
+	// links can be built with an overridden multicodec type
+	opts := request_list.DecodeOptions{LinkCodecs: shared.LinkCodecs{request.MultiCodecType: cid.Raw}}
+	nb := dageth.Type.Requests.NewBuilder()
+	if err := request_list.DecodeBytesWithOptions(nb, hash.Bytes(), opts); err != nil {
+		t.Fatal(err)
+	}
+	n, _ := nb.Build().LookupByIndex(0)
+	if lnk, _ := n.AsLink(); lnk.(cidlink.Link).Cid.Prefix().Codec != cid.Raw {
+		t.Errorf("expected the request link to carry the overridden raw multicodec type, got %s", lnk.String())
+	}
+}
+
+func requestList(t *testing.T, c cid.Cid) ipld.Node {
+	nb := dageth.Type.Requests.NewBuilder()
+	la, err := nb.BeginList(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := la.AssembleValue().AssignLink(cidlink.Link{Cid: c}); err != nil {
+		t.Fatal(err)
+	}
+	if err := la.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	return nb.Build()
+}
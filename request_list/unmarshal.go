@@ -0,0 +1,99 @@
+package request_list
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/request"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// DecodeOptions can be used to customize the behavior of request list decoding.
+// The zero value is the default behavior used by the registered codec.
+type DecodeOptions struct {
+	// LinkCodecs overrides the multicodec type of the links to the requests
+	LinkCodecs shared.LinkCodecs
+	// LinkHashes overrides the multihash type of the links to the requests
+	LinkHashes shared.LinkHashes
+}
+
+// Decode provides an IPLD codec decode interface for eth request list IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0xf4 when this package is invoked via init.
+func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return DecodeOptions{}.Decode(na, in)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeOptions{}.DecodeBytes(na, src)
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
+	var src []byte
+	if buf, ok := in.(interface{ Bytes() []byte }); ok {
+		src = buf.Bytes()
+	} else {
+		var err error
+		src, err = ioutil.ReadAll(in)
+		if err != nil {
+			return err
+		}
+	}
+	return cfg.DecodeBytes(na, src)
+}
+
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options.
+// The block is the concatenation of the sha256 hashes of the non-empty requests of a block, which is what the
+// EIP-7685 requests hash of the header hashes, so each hash decodes into a link to its request.
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	if len(src)%sha256Length != 0 {
+		return fmt.Errorf("invalid DAG-ETH Requests binary (length %d is not a multiple of %d)", len(src), sha256Length)
+	}
+	la, err := na.BeginList(int64(len(src) / sha256Length))
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(src); i += sha256Length {
+		requestCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(request.MultiCodecType), cfg.LinkHashes.Type(multihash.SHA2_256), src[i:i+sha256Length])
+		if err != nil {
+			return err
+		}
+		if err := la.AssembleValue().AssignLink(cidlink.Link{Cid: requestCID}); err != nil {
+			return err
+		}
+	}
+	return la.Finish()
+}
@@ -0,0 +1,268 @@
+/*
+Package selectors provides prebuilt IPLD selectors for common Ethereum queries over DAG-ETH,
+which spell out the keyed unions of the trie node structure so they don't have to be composed by hand.
+*/
+package selectors
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal/selector"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/helpers"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+var ssb = builder.NewSelectorSpecBuilder(basicnode.Prototype.Any)
+
+var branchChildren = []string{
+	"Child0", "Child1", "Child2", "Child3", "Child4", "Child5", "Child6", "Child7",
+	"Child8", "Child9", "ChildA", "ChildB", "ChildC", "ChildD", "ChildE", "ChildF",
+}
+
+// TrieLeaves returns a selector that explores every node of the trie it is applied to, following links
+// and embedded nodes alike, and applies the value selector to the Value union of every node holding one
+func TrieLeaves(value builder.SelectorSpec) builder.SelectorSpec {
+	edge := ssb.ExploreRecursiveEdge()
+	child := ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+		efsb.Insert("Link", edge)
+		efsb.Insert("TrieNode", edge)
+	})
+	return ssb.ExploreRecursive(selector.RecursionLimitNone(), ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+		efsb.Insert(trie.BRANCH_NODE.String(), ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+			for _, field := range branchChildren {
+				efsb.Insert(field, child)
+			}
+			efsb.Insert("Value", value)
+		}))
+		efsb.Insert(trie.EXTENSION_NODE.String(), ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+			efsb.Insert("Child", child)
+		}))
+		efsb.Insert(trie.LEAF_NODE.String(), ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+			efsb.Insert("Value", value)
+		}))
+	}))
+}
+
+// HeaderWithOmmers returns a selector that matches the header it is applied to and every one of its ommer headers
+func HeaderWithOmmers() builder.SelectorSpec {
+	return ssb.ExploreUnion(ssb.Matcher(), ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+		efsb.Insert("UnclesCID", ssb.ExploreAll(ssb.Matcher()))
+	}))
+}
+
+// BlockTransactions returns a selector that, applied to a header, walks the whole transaction trie
+// of the block and matches every transaction in it
+func BlockTransactions() builder.SelectorSpec {
+	return ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+		efsb.Insert("TxRootCID", TrieLeaves(ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+			efsb.Insert(trie.TX_VALUE.String(), ssb.Matcher())
+		})))
+	})
+}
+
+// AccountWithStorage returns a selector that, applied to the state trie root node referenced by stateRoot,
+// explores the path to the account at the address, matches the account, and walks its whole storage trie
+// matching every slot value. The path is resolved through the LinkSystem, since the partial paths of the
+// extension nodes along it can't be known up front.
+func AccountWithStorage(lsys ipld.LinkSystem, stateRoot ipld.Link, address common.Address) (builder.SelectorSpec, error) {
+	path, err := helpers.AccountPath(lsys, stateRoot, address)
+	if err != nil {
+		return nil, err
+	}
+	storageRoot, err := storageRootOf(lsys, stateRoot, address)
+	if err != nil {
+		return nil, err
+	}
+	account := ssb.Matcher()
+	// the empty storage trie has no root node to load
+	if !bytes.Equal(storageRoot.Hash()[2:], types.EmptyRootHash.Bytes()) {
+		account = ssb.ExploreUnion(ssb.Matcher(), ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+			efsb.Insert("StorageRootCID", TrieLeaves(ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+				efsb.Insert(trie.STORAGE_VALUE.String(), ssb.Matcher())
+			})))
+		}))
+	}
+	return explorePath(path, account), nil
+}
+
+// StateSubtree returns a selector that, applied to the state trie root node referenced by stateRoot,
+// explores the path to the smallest subtrie holding every account whose nibble path starts with the prefix,
+// and walks that subtrie matching every account in it. The path is resolved through the LinkSystem.
+// It returns an error if the trie holds no account under the prefix.
+func StateSubtree(lsys ipld.LinkSystem, stateRoot ipld.Link, prefix []byte) (builder.SelectorSpec, error) {
+	path, err := subtriePath(lsys, stateRoot, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return explorePath(path, TrieLeaves(ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+		efsb.Insert(trie.STATE_VALUE.String(), ssb.Matcher())
+	}))), nil
+}
+
+// explorePath returns a selector that explores exactly the fields of the path and applies next at its end
+func explorePath(path ipld.Path, next builder.SelectorSpec) builder.SelectorSpec {
+	spec := next
+	segments := path.Segments()
+	for i := len(segments) - 1; i >= 0; i-- {
+		field, next := segments[i].String(), spec
+		spec = ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+			efsb.Insert(field, next)
+		})
+	}
+	return spec
+}
+
+// subtriePath walks the trie along the prefix and returns the path to the TrieNode under which every key
+// starting with the prefix is held
+func subtriePath(lsys ipld.LinkSystem, root ipld.Link, prefix []byte) (ipld.Path, error) {
+	node, err := loadTrieNode(lsys, root)
+	if err != nil {
+		return ipld.Path{}, err
+	}
+	var path ipld.Path
+	remaining := prefix
+	for len(remaining) > 0 {
+		n, kind, err := trie.NodeAndKind(node)
+		if err != nil {
+			return ipld.Path{}, err
+		}
+		switch kind {
+		case trie.BRANCH_NODE:
+			field := fmt.Sprintf("Child%X", remaining[0])
+			child, err := n.LookupByString(field)
+			if err != nil {
+				return ipld.Path{}, err
+			}
+			if child.IsNull() {
+				return ipld.Path{}, fmt.Errorf("trie has no value under prefix %x", prefix)
+			}
+			remaining = remaining[1:]
+			path = path.AppendSegmentString(kind.String()).AppendSegmentString(field)
+			if linkNode, err := child.LookupByString("Link"); err == nil {
+				path = path.AppendSegmentString("Link")
+				if node, err = loadLinkedTrieNode(lsys, linkNode); err != nil {
+					return ipld.Path{}, err
+				}
+				continue
+			}
+			// nodes smaller than 32 bytes are included directly in their parent branch
+			if node, err = child.LookupByString("TrieNode"); err != nil {
+				return ipld.Path{}, fmt.Errorf("branch node child needs to be a link or a trie node: %v", err)
+			}
+			path = path.AppendSegmentString("TrieNode")
+		case trie.EXTENSION_NODE:
+			partialPath, err := partialPathOf(n)
+			if err != nil {
+				return ipld.Path{}, err
+			}
+			// the prefix ends within the partial path, so the whole extension is under it
+			if bytes.HasPrefix(partialPath, remaining) {
+				return path, nil
+			}
+			if !bytes.HasPrefix(remaining, partialPath) {
+				return ipld.Path{}, fmt.Errorf("trie has no value under prefix %x", prefix)
+			}
+			remaining = remaining[len(partialPath):]
+			child, err := n.LookupByString("Child")
+			if err != nil {
+				return ipld.Path{}, err
+			}
+			path = path.AppendSegmentString(kind.String()).AppendSegmentString("Child")
+			if linkNode, err := child.LookupByString("Link"); err == nil {
+				path = path.AppendSegmentString("Link")
+				if node, err = loadLinkedTrieNode(lsys, linkNode); err != nil {
+					return ipld.Path{}, err
+				}
+				continue
+			}
+			// nodes smaller than 32 bytes are included directly in their parent
+			if node, err = child.LookupByString("TrieNode"); err != nil {
+				return ipld.Path{}, fmt.Errorf("extension node child needs to be a link or a trie node: %v", err)
+			}
+			path = path.AppendSegmentString("TrieNode")
+		case trie.LEAF_NODE:
+			partialPath, err := partialPathOf(n)
+			if err != nil {
+				return ipld.Path{}, err
+			}
+			if !bytes.HasPrefix(partialPath, remaining) {
+				return ipld.Path{}, fmt.Errorf("trie has no value under prefix %x", prefix)
+			}
+			return path, nil
+		default:
+			return ipld.Path{}, fmt.Errorf("unrecognized trie node type %s", kind.String())
+		}
+	}
+	return path, nil
+}
+
+// storageRootOf returns the StorageRootCID of the account at the address
+func storageRootOf(lsys ipld.LinkSystem, stateRoot ipld.Link, address common.Address) (cidlink.Link, error) {
+	lnk, remaining := stateRoot, helpers.AddressToNibbles(address)
+	for {
+		node, err := loadTrieNode(lsys, lnk)
+		if err != nil {
+			return cidlink.Link{}, err
+		}
+		val, next, rest, err := helpers.Step(node, remaining)
+		if err != nil {
+			return cidlink.Link{}, err
+		}
+		if val != nil {
+			accountNode, err := val.LookupByString(trie.STATE_VALUE.String())
+			if err != nil {
+				return cidlink.Link{}, err
+			}
+			storageRootNode, err := accountNode.LookupByString("StorageRootCID")
+			if err != nil {
+				return cidlink.Link{}, err
+			}
+			storageRoot, err := storageRootNode.AsLink()
+			if err != nil {
+				return cidlink.Link{}, err
+			}
+			return storageRoot.(cidlink.Link), nil
+		}
+		if next == nil {
+			return cidlink.Link{}, fmt.Errorf("state trie has no account at address %s", address.Hex())
+		}
+		lnk, remaining = next, rest
+	}
+}
+
+func loadLinkedTrieNode(lsys ipld.LinkSystem, linkNode ipld.Node) (ipld.Node, error) {
+	lnk, err := linkNode.AsLink()
+	if err != nil {
+		return nil, err
+	}
+	return loadTrieNode(lsys, lnk)
+}
+
+func loadTrieNode(lsys ipld.LinkSystem, lnk ipld.Link) (ipld.Node, error) {
+	return lsys.Load(ipld.LinkContext{}, lnk, dageth.Type.TrieNode)
+}
+
+func partialPathOf(node ipld.Node) ([]byte, error) {
+	ppNode, err := node.LookupByString("PartialPath")
+	if err != nil {
+		return nil, err
+	}
+	pp, err := ppNode.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	// leaf partial paths carry the terminator flag in the hex representation
+	if len(pp) > 0 && pp[len(pp)-1] == 16 {
+		pp = pp[:len(pp)-1]
+	}
+	return pp, nil
+}
@@ -0,0 +1,196 @@
+package selectors_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+	"github.com/ipld/go-ipld-prime/traversal"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/helpers"
+	"github.com/vulcanize/go-codec-dageth/selectors"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+var mockContract = common.BigToAddress(big.NewInt(7))
+
+// buildStateTrie stores a state trie of 100 accounts, where only mockContract has storage,
+// and returns a link to its root together with the nibble paths of the accounts
+func buildStateTrie(t *testing.T, store *storage.Memory, slots int) (ipld.Link, [][]byte) {
+	storageKVs := make(map[string][]byte, slots)
+	for i := 0; i < slots; i++ {
+		valRLP, _ := rlp.EncodeToBytes([]byte{byte(i + 1)})
+		storageKVs[string(crypto.Keccak256(common.BigToHash(big.NewInt(int64(i))).Bytes()))] = valRLP
+	}
+	storageRoot := testutil.BuildTrie(t, store, storage_trie.MultiCodecType, storageKVs)
+	kvs := make(map[string][]byte)
+	var paths [][]byte
+	for i := int64(1); i <= 100; i++ {
+		addr := common.BigToAddress(big.NewInt(i))
+		acct := &types.StateAccount{
+			Nonce:    uint64(i),
+			Balance:  uint256.NewInt(uint64(i)),
+			Root:     types.EmptyRootHash,
+			CodeHash: types.EmptyCodeHash.Bytes(),
+		}
+		if addr == mockContract {
+			acct.Root = storageRoot
+		}
+		enc, _ := rlp.EncodeToBytes(acct)
+		kvs[string(crypto.Keccak256(addr.Bytes()))] = enc
+		paths = append(paths, helpers.AddressToNibbles(addr))
+	}
+	root := testutil.BuildTrie(t, store, state_trie.MultiCodecType, kvs)
+	return testutil.KeccakLink(t, state_trie.MultiCodecType, root.Bytes()), paths
+}
+
+// walkMatching applies the selector to the node and returns the matched nodes
+func walkMatching(t *testing.T, lsys ipld.LinkSystem, node ipld.Node, spec builder.SelectorSpec) []ipld.Node {
+	sel, err := spec.Selector()
+	if err != nil {
+		t.Fatalf("unable to compile selector: %v", err)
+	}
+	prog := traversal.Progress{Cfg: &traversal.Config{
+		LinkSystem:                     lsys,
+		LinkTargetNodePrototypeChooser: codecs.NodePrototypeChooser,
+	}}
+	var matched []ipld.Node
+	if err := prog.WalkMatching(node, sel, func(_ traversal.Progress, n ipld.Node) error {
+		matched = append(matched, n)
+		return nil
+	}); err != nil {
+		t.Fatalf("unable to walk selector: %v", err)
+	}
+	return matched
+}
+
+func TestBlockSelectors(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	txs := make(types.Transactions, 40)
+	receipts := make(types.Receipts, len(txs))
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), shared.RandomAddr(), big.NewInt(1), 21000, big.NewInt(1), nil)
+		receipts[i] = &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: uint64(i+1) * 21000}
+	}
+	uncles := []*types.Header{
+		{Number: big.NewInt(9), Difficulty: big.NewInt(1), Coinbase: shared.RandomAddr()},
+		{Number: big.NewInt(9), Difficulty: big.NewInt(2), Coinbase: shared.RandomAddr()},
+	}
+	h := &types.Header{Number: big.NewInt(10), Difficulty: big.NewInt(1), GasLimit: 30000000, Root: shared.RandomHash()}
+	blk := types.NewBlock(h, &types.Body{Transactions: txs, Uncles: uncles}, receipts, gethtrie.NewStackTrie(nil))
+	headerLink, err := block.PackBlock(blk, receipts, lsys)
+	if err != nil {
+		t.Fatalf("unable to pack block: %v", err)
+	}
+	headerNode, err := lsys.Load(ipld.LinkContext{}, headerLink, dageth.Type.Header)
+	if err != nil {
+		t.Fatalf("unable to load header: %v", err)
+	}
+
+	headers := walkMatching(t, lsys, headerNode, selectors.HeaderWithOmmers())
+	if len(headers) != 1+len(uncles) {
+		t.Fatalf("expected the header and %d ommers to be matched, got %d nodes", len(uncles), len(headers))
+	}
+	for i, uncle := range uncles {
+		coinbase, err := headers[i+1].LookupByString("Coinbase")
+		if err != nil {
+			t.Fatalf("unable to look up ommer coinbase: %v", err)
+		}
+		if b, _ := coinbase.AsBytes(); !bytes.Equal(b, uncle.Coinbase.Bytes()) {
+			t.Errorf("ommer %d coinbase (%x) does not match expected coinbase (%x)", i, b, uncle.Coinbase.Bytes())
+		}
+	}
+
+	matched := walkMatching(t, lsys, headerNode, selectors.BlockTransactions())
+	if len(matched) != len(txs) {
+		t.Fatalf("expected %d transactions to be matched, got %d", len(txs), len(matched))
+	}
+	nonces := make(map[uint64]bool)
+	for _, n := range matched {
+		nonceNode, err := n.LookupByString("AccountNonce")
+		if err != nil {
+			t.Fatalf("matched node is not a transaction: %v", err)
+		}
+		b, _ := nonceNode.AsBytes()
+		nonces[new(big.Int).SetBytes(b).Uint64()] = true
+	}
+	if len(nonces) != len(txs) {
+		t.Errorf("expected %d distinct transactions, got %d", len(txs), len(nonces))
+	}
+}
+
+func TestStateSelectors(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	stateRoot, paths := buildStateTrie(t, store, 20)
+	rootNode, err := lsys.Load(ipld.LinkContext{}, stateRoot, dageth.Type.TrieNode)
+	if err != nil {
+		t.Fatalf("unable to load state root: %v", err)
+	}
+
+	spec, err := selectors.AccountWithStorage(lsys, stateRoot, mockContract)
+	if err != nil {
+		t.Fatalf("unable to build account selector: %v", err)
+	}
+	if matched := walkMatching(t, lsys, rootNode, spec); len(matched) != 1+20 {
+		t.Errorf("expected the account and its 20 slots to be matched, got %d nodes", len(matched))
+	}
+	// accounts without storage match on their own
+	spec, err = selectors.AccountWithStorage(lsys, stateRoot, common.BigToAddress(big.NewInt(8)))
+	if err != nil {
+		t.Fatalf("unable to build account selector: %v", err)
+	}
+	matched := walkMatching(t, lsys, rootNode, spec)
+	if len(matched) != 1 {
+		t.Fatalf("expected only the account to be matched, got %d nodes", len(matched))
+	}
+	if nonce, err := matched[0].LookupByString("Nonce"); err != nil {
+		t.Errorf("matched node is not an account: %v", err)
+	} else if b, _ := nonce.AsBytes(); new(big.Int).SetBytes(b).Uint64() != 8 {
+		t.Errorf("matched account nonce (%x) does not match expected nonce 8", b)
+	}
+	if _, err := selectors.AccountWithStorage(lsys, stateRoot, common.BigToAddress(big.NewInt(101))); err == nil {
+		t.Error("expected an error building a selector for an account that is not in the trie")
+	}
+
+	for _, prefix := range [][]byte{nil, {0x3}, paths[0][:2], paths[1][:3], paths[2]} {
+		expected := 0
+		for _, path := range paths {
+			if bytes.HasPrefix(path, prefix) {
+				expected++
+			}
+		}
+		spec, err := selectors.StateSubtree(lsys, stateRoot, prefix)
+		if err != nil {
+			t.Fatalf("unable to build subtree selector for prefix %x: %v", prefix, err)
+		}
+		if matched := walkMatching(t, lsys, rootNode, spec); len(matched) != expected {
+			t.Errorf("expected %d accounts under prefix %x, got %d", expected, prefix, len(matched))
+		}
+	}
+	missing := append(append([]byte{}, paths[0][:8]...), (paths[0][8]+1)%16)
+	for _, path := range paths {
+		if bytes.HasPrefix(path, missing) {
+			t.Fatal("mock prefix is not missing from the trie")
+		}
+	}
+	if _, err := selectors.StateSubtree(lsys, stateRoot, missing); err == nil {
+		t.Error("expected an error building a subtree selector for a prefix that is not in the trie")
+	}
+}
@@ -0,0 +1,12 @@
+/*
+Package shared holds the helpers the DAG-ETH codecs share.
+
+Numeric fields are held as big-endian bytes, Uint fields 8 bytes wide and BigInt fields minimal. Uint64Field,
+BigField and Uint256Field read them back as uint64, *big.Int and *uint256.Int, accepting shorter or zero padded
+forms, while CheckNumerics rejects every non-canonical form for the Strict encoders.
+
+StreamRLP writes lists item by item, so the Encode functions of the codecs do not build whole encodings in memory.
+LinkCodecs and LinkHashes override the multicodec and multihash types of the links the codecs build, e.g. for
+experimental chains that do not hash with keccak256, with HashToCid in place of Keccak256ToCid.
+*/
+package shared
@@ -0,0 +1,172 @@
+package shared
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/holiman/uint256"
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+)
+
+// UintLength is the length of the canonical form of Uint fields, which hold uint64 values in fixed width big-endian
+const UintLength = 8
+
+// Uint64ToBytes returns the canonical form of a Uint field, the value in 8 byte big-endian
+func Uint64ToBytes(v uint64) []byte {
+	b := make([]byte, UintLength)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// BytesToUint64 reads the big-endian bytes of a Uint field. Forms shorter than the canonical 8 bytes, and longer
+// forms padded with leading zeros, are read as the value they hold, while values overflowing 64 bits are an error.
+func BytesToUint64(b []byte) (uint64, error) {
+	b = trimLeadingZeros(b)
+	if len(b) > UintLength {
+		return 0, fmt.Errorf("value %#x overflows 64 bits", b)
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// BytesToBig reads the big-endian bytes of a BigInt field, ignoring leading zeros
+func BytesToBig(b []byte) *big.Int {
+	return new(big.Int).SetBytes(b)
+}
+
+// BytesToUint256 reads the big-endian bytes of a BigInt field that holds a 256 bit value, ignoring leading zeros,
+// and returns an error for values overflowing 256 bits
+func BytesToUint256(b []byte) (*uint256.Int, error) {
+	b = trimLeadingZeros(b)
+	if len(b) > 32 {
+		return nil, fmt.Errorf("value %#x overflows 256 bits", b)
+	}
+	return new(uint256.Int).SetBytes(b), nil
+}
+
+// Uint64Field reads the named Uint field of the node
+func Uint64Field(node ipld.Node, field string) (uint64, error) {
+	b, err := bytesField(node, field)
+	if err != nil {
+		return 0, err
+	}
+	v, err := BytesToUint64(b)
+	if err != nil {
+		return 0, fmt.Errorf("%s %v", field, err)
+	}
+	return v, nil
+}
+
+// BigField reads the named BigInt field of the node
+func BigField(node ipld.Node, field string) (*big.Int, error) {
+	b, err := bytesField(node, field)
+	if err != nil {
+		return nil, err
+	}
+	return BytesToBig(b), nil
+}
+
+// Uint256Field reads the named BigInt field of the node as a 256 bit value
+func Uint256Field(node ipld.Node, field string) (*uint256.Int, error) {
+	b, err := bytesField(node, field)
+	if err != nil {
+		return nil, err
+	}
+	v, err := BytesToUint256(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s %v", field, err)
+	}
+	return v, nil
+}
+
+// CheckNumerics checks that every numeric field the typed node holds, in its own fields and in the fields of the
+// structures it nests, e.g. the authorizations of a transaction, is in its canonical form: Uints and Times 8 bytes
+// wide, and BigInts and Balances without leading zeros. Non-canonical forms encode to the same RLP as the canonical
+// ones, so they would give different nodes the same CID; the codecs reject them when encoding strictly.
+func CheckNumerics(node ipld.Node) error {
+	return checkNumerics(node, "")
+}
+
+func checkNumerics(node ipld.Node, path string) error {
+	switch n := node.(type) {
+	case dageth.Uint:
+		return checkUint(n.Bytes(), path)
+	case dageth.Time:
+		return checkUint(n.Bytes(), path)
+	case dageth.BigInt:
+		return checkBigInt(n.Bytes(), path)
+	case dageth.Balance:
+		return checkBigInt(n.Bytes(), path)
+	}
+	switch node.Kind() {
+	case ipld.Kind_Map:
+		it := node.MapIterator()
+		for !it.Done() {
+			k, v, err := it.Next()
+			if err != nil {
+				return err
+			}
+			key, err := k.AsString()
+			if err != nil {
+				return err
+			}
+			if err := checkNumerics(v, joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case ipld.Kind_List:
+		it := node.ListIterator()
+		for !it.Done() {
+			i, v, err := it.Next()
+			if err != nil {
+				return err
+			}
+			if err := checkNumerics(v, joinPath(path, fmt.Sprint(i))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkUint(b []byte, path string) error {
+	if len(b) != UintLength {
+		return fmt.Errorf("%s is %d bytes, not %d", path, len(b), UintLength)
+	}
+	return nil
+}
+
+func checkBigInt(b []byte, path string) error {
+	if len(b) > 0 && b[0] == 0 {
+		return fmt.Errorf("%s has leading zeros", path)
+	}
+	return nil
+}
+
+func joinPath(path, segment string) string {
+	if path == "" {
+		return segment
+	}
+	return path + "/" + segment
+}
+
+func bytesField(node ipld.Node, field string) ([]byte, error) {
+	fieldNode, err := node.LookupByString(field)
+	if err != nil {
+		return nil, err
+	}
+	return fieldNode.AsBytes()
+}
+
+func trimLeadingZeros(b []byte) []byte {
+	for len(b) > 0 && b[0] == 0 {
+		b = b[1:]
+	}
+	return b
+}
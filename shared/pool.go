@@ -0,0 +1,78 @@
+package shared
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+)
+
+// maxPooledBufferSize bounds the capacity of the buffers kept by the pool,
+// so that encoding one large block does not pin a large buffer for good
+const maxPooledBufferSize = 64 << 10
+
+var (
+	bufferPool = sync.Pool{
+		New: func() interface{} {
+			// 1KiB covers most nodes without having to grow the buffer
+			buf := make([]byte, 0, 1024)
+			return &buf
+		},
+	}
+	keccakPool = sync.Pool{
+		New: func() interface{} {
+			return crypto.NewKeccakState()
+		},
+	}
+)
+
+// GetBuffer returns an empty scratch buffer from the pool, it should be handed back with PutBuffer
+// once its contents are no longer used
+func GetBuffer() *[]byte {
+	return bufferPool.Get().(*[]byte)
+}
+
+// PutBuffer returns a buffer obtained with GetBuffer to the pool, the buffer must not be used afterwards
+func PutBuffer(buf *[]byte) {
+	if cap(*buf) > maxPooledBufferSize {
+		return
+	}
+	*buf = (*buf)[:0]
+	bufferPool.Put(buf)
+}
+
+// WriteEncoded encodes the node with the appendEncode function of a codec into a pooled buffer and writes
+// the encoding to w, it is used by the Encode functions of the codecs
+func WriteEncoded(w io.Writer, node ipld.Node, appendEncode func([]byte, ipld.Node) ([]byte, error)) error {
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+	enc, err := appendEncode(*buf, node)
+	if err != nil {
+		return err
+	}
+	*buf = enc
+	_, err = w.Write(enc)
+	return err
+}
+
+// hashToCid builds the CID of a hash of the multihash type in a pooled buffer, so that the CID string
+// is the only allocation
+func hashToCid(codec, mhType uint64, h []byte) (cid.Cid, error) {
+	if len(h) != common.HashLength {
+		return cid.Undef, fmt.Errorf("hash of %d bytes, expected %d", len(h), common.HashLength)
+	}
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+	enc := binary.AppendUvarint((*buf)[:0], 1)
+	enc = binary.AppendUvarint(enc, codec)
+	enc = binary.AppendUvarint(enc, mhType)
+	enc = binary.AppendUvarint(enc, uint64(len(h)))
+	enc = append(enc, h...)
+	*buf = enc
+	return cid.Cast(enc)
+}
@@ -0,0 +1,163 @@
+package shared
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// List is an RLP list that StreamRLP writes item by item, rather than encoding it as a whole before writing it
+type List []interface{}
+
+// Typed is an EIP-2718 typed payload, the type byte followed by the RLP list of its fields, as the consensus
+// encoding of typed transactions and receipts is
+type Typed struct {
+	Type   byte
+	Fields List
+}
+
+// Wrapped wraps the encoding of its item into an RLP byte string, as blocks and tries hold typed payloads.
+// The item should encode to more than a single byte, as Lists and Typed payloads do.
+type Wrapped struct {
+	Item interface{}
+}
+
+// StreamRLP writes the RLP encoding of the item to w, writing Lists, Typed payloads and Wrapped items, at any
+// depth, item by item: the length prefix of each is computed ahead by sizing every item once, so that only one item
+// of another type is held in memory at a time, instead of the whole encoding. Items of other types are encoded by
+// rlp.Encode. It makes many small writes, so w should be buffered when writes are costly.
+func StreamRLP(w io.Writer, item interface{}) error {
+	sized, err := sizeItem(item)
+	if err != nil {
+		return err
+	}
+	return sized.stream(w)
+}
+
+// sizedItem is an item of StreamRLP together with the length of its encoding, and the sized items of a List or the
+// sized item a Typed payload or Wrapped item holds
+type sizedItem struct {
+	item interface{}
+	// size is the length of the encoding of the item
+	size uint64
+	// payload is the length of the payload behind the header of a List or Wrapped item
+	payload uint64
+	items   []sizedItem
+}
+
+// sizeItem returns the item with the length of its encoding, encoding items of types other than Lists, Typed
+// payloads, Wrapped items, byte slices and uint64s into a byteCounter to size them
+func sizeItem(item interface{}) (sizedItem, error) {
+	sized := sizedItem{item: item}
+	switch it := item.(type) {
+	case List:
+		sized.items = make([]sizedItem, len(it))
+		for i, child := range it {
+			childSized, err := sizeItem(child)
+			if err != nil {
+				return sizedItem{}, err
+			}
+			sized.items[i] = childSized
+			sized.payload += childSized.size
+		}
+		sized.size = headerSize(sized.payload) + sized.payload
+	case Typed:
+		fields, err := sizeItem(it.Fields)
+		if err != nil {
+			return sizedItem{}, err
+		}
+		sized.items = []sizedItem{fields}
+		sized.size = 1 + fields.size
+	case Wrapped:
+		inner, err := sizeItem(it.Item)
+		if err != nil {
+			return sizedItem{}, err
+		}
+		sized.items = []sizedItem{inner}
+		sized.payload = inner.size
+		sized.size = headerSize(inner.size) + inner.size
+	case []byte:
+		sized.size = headerSize(uint64(len(it))) + uint64(len(it))
+		if len(it) == 1 && it[0] < 0x80 {
+			sized.size = 1
+		}
+	case uint64:
+		sized.size = 1
+		if it >= 0x80 {
+			sized.size += uint64(8 - leadingZeroBytes(it))
+		}
+	default:
+		var counter byteCounter
+		if err := rlp.Encode(&counter, item); err != nil {
+			return sizedItem{}, err
+		}
+		sized.size = uint64(counter)
+	}
+	return sized, nil
+}
+
+// stream writes the encoding of the sized item to w, with the headers of Lists and Wrapped items of the payload
+// lengths computed by sizeItem
+func (s sizedItem) stream(w io.Writer) error {
+	switch it := s.item.(type) {
+	case List:
+		if _, err := w.Write(appendHeader(nil, 0xc0, s.payload)); err != nil {
+			return err
+		}
+		for _, child := range s.items {
+			if err := child.stream(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Typed:
+		if _, err := w.Write([]byte{it.Type}); err != nil {
+			return err
+		}
+		return s.items[0].stream(w)
+	case Wrapped:
+		if _, err := w.Write(appendHeader(nil, 0x80, s.payload)); err != nil {
+			return err
+		}
+		return s.items[0].stream(w)
+	default:
+		return rlp.Encode(w, s.item)
+	}
+}
+
+// headerSize returns the length of the RLP header of a byte string or list of the size
+func headerSize(size uint64) uint64 {
+	if size < 56 {
+		return 1
+	}
+	return 1 + uint64(8-leadingZeroBytes(size))
+}
+
+// appendHeader appends the RLP header of a byte string, at offset 0x80, or list, at offset 0xc0, of the size
+func appendHeader(buf []byte, offset byte, size uint64) []byte {
+	if size < 56 {
+		return append(buf, offset+byte(size))
+	}
+	n := 8 - leadingZeroBytes(size)
+	var sizeBytes [8]byte
+	binary.BigEndian.PutUint64(sizeBytes[:], size)
+	buf = append(buf, offset+55+byte(n))
+	return append(buf, sizeBytes[8-n:]...)
+}
+
+func leadingZeroBytes(v uint64) int {
+	n := 0
+	for n < 8 && v>>(56-8*n)&0xff == 0 {
+		n++
+	}
+	return n
+}
+
+// byteCounter is an io.Writer that counts the bytes written to it
+type byteCounter uint64
+
+func (c *byteCounter) Write(b []byte) (int, error) {
+	*c += byteCounter(len(b))
+	return len(b), nil
+}
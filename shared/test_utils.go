@@ -166,12 +166,18 @@ func verifySharedTxContent(t *testing.T, txNode ipld.Node, tx *types.Transaction
 	if err != nil {
 		t.Fatalf("transaction missing Recipient: %v", err)
 	}
-	recipientBytes, err := recipientNode.AsBytes()
-	if err != nil {
-		t.Fatalf("transaction Recipient should be of type Bytes: %v", err)
-	}
-	if !bytes.Equal(recipientBytes, tx.To().Bytes()) {
-		t.Errorf("transaction recipient (%x) does not match expected recipient (%x)", recipientBytes, tx.To().Bytes())
+	if tx.To() == nil {
+		if !recipientNode.IsNull() {
+			t.Errorf("contract creation transaction Recipient should be null")
+		}
+	} else {
+		recipientBytes, err := recipientNode.AsBytes()
+		if err != nil {
+			t.Fatalf("transaction Recipient should be of type Bytes: %v", err)
+		}
+		if !bytes.Equal(recipientBytes, tx.To().Bytes()) {
+			t.Errorf("transaction recipient (%x) does not match expected recipient (%x)", recipientBytes, tx.To().Bytes())
+		}
 	}
 
 	gasLimitNode, err := txNode.LookupByString("GasLimit")
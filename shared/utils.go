@@ -1,15 +1,20 @@
 package shared
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-
-	"github.com/multiformats/go-multihash"
+	"io"
+	"io/ioutil"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/keys"
 )
 
 var evenLeafFlag = []byte{byte(2) << 4}
@@ -17,26 +22,105 @@ var evenLeafFlag = []byte{byte(2) << 4}
 // RawToCid takes the desired codec and a slice of bytes
 // and returns the proper cid of the object.
 func RawToCid(codec uint64, rawdata []byte) (cid.Cid, error) {
-	c, err := cid.Prefix{
-		Codec:    codec,
-		Version:  1,
-		MhType:   multihash.KECCAK_256,
-		MhLength: -1,
-	}.Sum(rawdata)
-	if err != nil {
-		return cid.Cid{}, err
+	var h [32]byte
+	hasher := keccakPool.Get().(crypto.KeccakState)
+	hasher.Reset()
+	hasher.Write(rawdata)
+	hasher.Read(h[:])
+	keccakPool.Put(hasher)
+	return hashToCid(codec, multihash.KECCAK_256, h[:])
+}
+
+// Keccak256ToCid returns the CIDv1 of the multicodec type carrying the keccak256 hash.
+// It returns an error if the hash is not 32 bytes long, or the codec does not fit in a CID.
+func Keccak256ToCid(codec uint64, h []byte) (cid.Cid, error) {
+	return hashToCid(codec, multihash.KECCAK_256, h)
+}
+
+// HashToCid is like Keccak256ToCid, but takes a hash of the given multihash type, for chains that do not
+// hash with keccak256. It returns an error if the hash is not 32 bytes long, or if the codec or multihash type,
+// which may come from LinkCodecs and LinkHashes, do not fit in a CID.
+func HashToCid(codec, mhType uint64, h []byte) (cid.Cid, error) {
+	return hashToCid(codec, mhType, h)
+}
+
+// ReadAll returns the bytes of the input, taking them directly from buffers that expose them
+func ReadAll(in io.Reader) ([]byte, error) {
+	if buf, ok := in.(interface{ Bytes() []byte }); ok {
+		return buf.Bytes(), nil
 	}
-	return c, nil
+	return ioutil.ReadAll(in)
 }
 
-// Keccak256ToCid takes a keccak256 hash and returns its cid based on the codec given.
-func Keccak256ToCid(codec uint64, h []byte) cid.Cid {
-	buf, err := multihash.Encode(h, multihash.KECCAK_256)
+// VerifyCID checks that the expected CID carries the multicodec code of the codec decoding the block,
+// and that the block hashes to the multihash of the expected CID
+func VerifyCID(data []byte, expected cid.Cid, codec uint64) error {
+	prefix := expected.Prefix()
+	if prefix.Codec != codec {
+		return fmt.Errorf("expected CID %s has multicodec type %#x, not %#x", expected.String(), prefix.Codec, codec)
+	}
+	actual, err := prefix.Sum(data)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if !actual.Equals(expected) {
+		return fmt.Errorf("block hashes to CID %s, not the expected CID %s", actual.String(), expected.String())
+	}
+	return nil
+}
+
+// LinkCodecs overrides the multicodec types of the CID links built by the codecs, it maps the multicodec type a link
+// is built with by default to the multicodec type to use instead
+type LinkCodecs map[uint64]uint64
+
+// Codec returns the multicodec type to use for a link that is built with the provided multicodec type by default
+func (lc LinkCodecs) Codec(codec uint64) uint64 {
+	if override, ok := lc[codec]; ok {
+		return override
+	}
+	return codec
+}
+
+// LinkHashes overrides the multihash types of the CID links built by the codecs, for chains that hash with another
+// function than keccak256, e.g. sha2-256 on test networks. It maps the multihash type a link is built with by default
+// to the multihash type to use instead; the digests the links carry are the hashes of the encoding as is.
+type LinkHashes map[uint64]uint64
+
+// Type returns the multihash type to use for a link that is built with the provided multihash type by default
+func (lh LinkHashes) Type(mhType uint64) uint64 {
+	if override, ok := lh[mhType]; ok {
+		return override
+	}
+	return mhType
+}
+
+// CheckLink verifies that a CID carries the expected multicodec type, and a multihash of the expected type
+// holding a 32 byte digest as every hash referenced by eth IPLDs does
+func CheckLink(c cid.Cid, codec, mhType uint64) error {
+	prefix := c.Prefix()
+	if prefix.Codec != codec {
+		return fmt.Errorf("CID %s has multicodec type %#x, not %#x", c.String(), prefix.Codec, codec)
 	}
+	if prefix.MhType != mhType {
+		return fmt.Errorf("CID %s has multihash type %#x, not %#x", c.String(), prefix.MhType, mhType)
+	}
+	if prefix.MhLength != 32 {
+		return fmt.Errorf("CID %s has a %d byte digest, not 32", c.String(), prefix.MhLength)
+	}
+	return nil
+}
 
-	return cid.NewCidV1(codec, multihash.Multihash(buf))
+// HasHash returns whether the CID carries the hash as a multihash of the type, whatever its multicodec type
+func HasHash(c cid.Cid, mhType uint64, h []byte) bool {
+	if !c.Defined() {
+		return false
+	}
+	prefix := c.Prefix()
+	if prefix.MhType != mhType || prefix.MhLength != len(h) {
+		return false
+	}
+	mh := c.Hash()
+	return bytes.Equal(mh[len(mh)-len(h):], h)
 }
 
 // AddressToLeafKey hashes an returns an address
@@ -67,6 +151,24 @@ func GetTxType(node ipld.Node) (uint8, error) {
 	return tyBytes[0], nil
 }
 
+// BytesToAddress reads the bytes of an Address field, which need to be exactly 20 bytes: unlike
+// common.BytesToAddress, it does not pad or crop other lengths into an address, which would encode nodes with
+// different field values into the same bytes
+func BytesToAddress(b []byte) (common.Address, error) {
+	if len(b) != common.AddressLength {
+		return common.Address{}, fmt.Errorf("address %#x is %d bytes, not %d", b, len(b), common.AddressLength)
+	}
+	return common.BytesToAddress(b), nil
+}
+
+// BytesToHash is like BytesToAddress, but reads the 32 bytes of a Hash field
+func BytesToHash(b []byte) (common.Hash, error) {
+	if len(b) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("hash %#x is %d bytes, not %d", b, len(b), common.HashLength)
+	}
+	return common.BytesToHash(b), nil
+}
+
 type WriteableByteSlice struct {
 	enc *[]byte
 }
@@ -80,64 +182,42 @@ func (w WriteableByteSlice) Write(b []byte) (int, error) {
 	return len(b), nil
 }
 
-// HexToCompact converts a hex path to the compact encoded format
+// HexToCompact converts a hex path, terminated by 16 for leaf nodes, to the compact encoded format.
+// It returns nil if the path holds bytes other than nibbles before the terminator.
 func HexToCompact(hex []byte) []byte {
-	return hexToCompact(hex)
-}
-
-func hexToCompact(hex []byte) []byte {
-	terminator := byte(0)
-	if hasTerm(hex) {
-		terminator = 1
+	leaf := hasTerm(hex)
+	if leaf {
 		hex = hex[:len(hex)-1]
 	}
-	buf := make([]byte, len(hex)/2+1)
-	buf[0] = terminator << 5 // the flag byte
-	if len(hex)&1 == 1 {
-		buf[0] |= 1 << 4 // odd flag
-		buf[0] |= hex[0] // first nibble is contained in the first byte
-		hex = hex[1:]
-	}
-	decodeNibbles(hex, buf[1:])
-	return buf
-}
-
-func decodeNibbles(nibbles []byte, bytes []byte) {
-	for bi, ni := 0, 0; ni < len(nibbles); bi, ni = bi+1, ni+2 {
-		bytes[bi] = nibbles[ni]<<4 | nibbles[ni+1]
+	compact, err := keys.NibblesToCompact(hex, leaf)
+	if err != nil {
+		return nil
 	}
+	return compact
 }
 
-// CompactToHex converts a compact encoded path to hex format
+// CompactToHex converts a compact encoded path to hex format, terminated by 16 for leaf nodes. Like go-ethereum, it
+// ignores the padding nibble of paths of an even number of nibbles. It returns nil for an empty path or a path whose
+// hex prefix flag is not one of 0 to 3.
 func CompactToHex(compact []byte) []byte {
-	return compactToHex(compact)
-}
-
-func compactToHex(compact []byte) []byte {
-	if len(compact) == 0 {
-		return compact
+	nibbles, leaf, err := keys.CompactToNibbles(compact)
+	if errors.Is(err, keys.ErrNonZeroPadding) {
+		nibbles, leaf, err = keys.CompactToNibbles(append([]byte{compact[0] & 0xf0}, compact[1:]...))
 	}
-	base := keybytesToHex(compact)
-	// delete terminator flag
-	if base[0] < 2 {
-		base = base[:len(base)-1]
+	if err != nil {
+		return nil
 	}
-	// apply odd flag
-	chop := 2 - base[0]&1
-	return base[chop:]
-}
-
-func keybytesToHex(str []byte) []byte {
-	l := len(str)*2 + 1
-	var nibbles = make([]byte, l)
-	for i, b := range str {
-		nibbles[i*2] = b / 16
-		nibbles[i*2+1] = b % 16
+	if leaf {
+		nibbles = append(nibbles, 16)
 	}
-	nibbles[l-1] = 16
 	return nibbles
 }
 
+// KeyToHex converts a trie key to its hex path, terminated by the leaf flag
+func KeyToHex(key []byte) []byte {
+	return append(keys.KeybytesToNibbles(key), 16)
+}
+
 // hasTerm returns whether a hex key has the terminator flag.
 func hasTerm(s []byte) bool {
 	return len(s) > 0 && s[len(s)-1] == 16
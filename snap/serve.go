@@ -0,0 +1,233 @@
+package snap
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethsnap "github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/keys"
+	"github.com/vulcanize/go-codec-dageth/proof"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/util"
+)
+
+const (
+	// softResponseLimit caps the byte limit of requests, as go-ethereum does
+	softResponseLimit = 2 * 1024 * 1024
+	// maxCodeLookups is the number of contract codes served per request
+	maxCodeLookups = 1024
+	// maxTrieNodeLookups is the number of trie nodes served per request
+	maxTrieNodeLookups = 1024
+)
+
+// AccountRange answers the request with the accounts of the state trie from its origin, up to its limit hash or
+// byte limit, along with the boundary proofs of the range, from the trie nodes reachable through the LinkSystem
+func AccountRange(lsys ipld.LinkSystem, req *gethsnap.GetAccountRangePacket) (*gethsnap.AccountRangePacket, error) {
+	stateRoot, err := stateRootLink(req.Root)
+	if err != nil {
+		return nil, err
+	}
+	rp, err := proof.GenerateSizedRangeProof(lsys, stateRoot, req.Origin.Bytes(), req.Limit.Bytes(), responseBytes(req.Bytes))
+	if err != nil {
+		return nil, err
+	}
+	res := &gethsnap.AccountRangePacket{
+		ID:       req.ID,
+		Accounts: make([]*gethsnap.AccountData, len(rp.Keys)),
+		Proof:    rp.Proof,
+	}
+	for i, key := range rp.Keys {
+		acct := new(types.StateAccount)
+		if err := rlp.DecodeBytes(rp.Values[i], acct); err != nil {
+			return nil, fmt.Errorf("invalid account %x: %v", key, err)
+		}
+		res.Accounts[i] = &gethsnap.AccountData{
+			Hash: common.BytesToHash(key),
+			Body: types.SlimAccountRLP(*acct),
+		}
+	}
+	return res, nil
+}
+
+// StorageRanges answers the request with the slots of the storage tries of the requested accounts, from the
+// trie nodes reachable through the LinkSystem. The origin and limit of the request apply to the first account.
+// Like go-ethereum, the response ends with the first range that does not hold a whole storage trie, and carries
+// the boundary proofs of that range; accounts with an empty storage trie get no range.
+func StorageRanges(lsys ipld.LinkSystem, req *gethsnap.GetStorageRangesPacket) (*gethsnap.StorageRangesPacket, error) {
+	maxBytes := responseBytes(req.Bytes)
+	res := &gethsnap.StorageRangesPacket{ID: req.ID}
+	var size uint64
+	for i, account := range req.Accounts {
+		if size >= maxBytes {
+			break
+		}
+		var origin, limit []byte
+		if i == 0 {
+			origin, limit = req.Origin, req.Limit
+			// a range from the zero hash needs no left boundary proof
+			if common.BytesToHash(origin) == (common.Hash{}) {
+				origin = nil
+			}
+		}
+		root, err := storageRoot(lsys, req.Root, account)
+		if err != nil {
+			return nil, err
+		}
+		if hash, err := util.LinkToKeccak256(root); err != nil {
+			return nil, err
+		} else if hash == types.EmptyRootHash {
+			continue
+		}
+		rp, err := proof.GenerateSizedRangeProof(lsys, root, origin, limit, maxBytes-size)
+		if err != nil {
+			return nil, err
+		}
+		slots := make([]*gethsnap.StorageData, len(rp.Keys))
+		for j, key := range rp.Keys {
+			slots[j] = &gethsnap.StorageData{Hash: common.BytesToHash(key), Body: rp.Values[j]}
+			size += uint64(common.HashLength + len(rp.Values[j]))
+		}
+		if len(slots) > 0 {
+			res.Slots = append(res.Slots, slots)
+		}
+		if len(rp.Proof) > 0 {
+			res.Proof = rp.Proof
+			break
+		}
+	}
+	return res, nil
+}
+
+// ByteCodes answers the request with the contract codes the LinkSystem holds as raw blocks, in the order they
+// were requested in. Codes that cannot be loaded are left out of the response.
+func ByteCodes(lsys ipld.LinkSystem, req *gethsnap.GetByteCodesPacket) (*gethsnap.ByteCodesPacket, error) {
+	maxBytes := responseBytes(req.Bytes)
+	hashes := req.Hashes
+	if len(hashes) > maxCodeLookups {
+		hashes = hashes[:maxCodeLookups]
+	}
+	res := &gethsnap.ByteCodesPacket{ID: req.ID}
+	var size uint64
+	for _, hash := range hashes {
+		if hash == types.EmptyCodeHash {
+			res.Codes = append(res.Codes, []byte{})
+		} else if code, err := loadBlock(lsys, cid.Raw, hash); err == nil {
+			res.Codes = append(res.Codes, code)
+			size += uint64(len(code))
+		}
+		if size > maxBytes {
+			break
+		}
+	}
+	return res, nil
+}
+
+// TrieNodes answers the request with the trie nodes at the requested paths, resolved from the state root of the
+// request through the LinkSystem. Like go-ethereum, the response ends before the first path that cannot be resolved.
+func TrieNodes(lsys ipld.LinkSystem, req *gethsnap.GetTrieNodesPacket) (*gethsnap.TrieNodesPacket, error) {
+	maxBytes := responseBytes(req.Bytes)
+	res := &gethsnap.TrieNodesPacket{ID: req.ID}
+	var size uint64
+	add := func(node []byte) bool {
+		res.Nodes = append(res.Nodes, node)
+		size += uint64(len(node))
+		return size <= maxBytes && len(res.Nodes) < maxTrieNodeLookups
+	}
+	for _, set := range req.Paths {
+		switch len(set) {
+		case 0:
+			continue
+		case 1:
+			node, err := nodeAt(lsys, state_trie.MultiCodecType, req.Root, set[0])
+			if err != nil || !add(node) {
+				return res, nil
+			}
+		default:
+			root, err := storageRoot(lsys, req.Root, common.BytesToHash(set[0]))
+			if err != nil {
+				return res, nil
+			}
+			rootHash, err := util.LinkToKeccak256(root)
+			if err != nil {
+				return nil, err
+			}
+			for _, path := range set[1:] {
+				node, err := nodeAt(lsys, storage_trie.MultiCodecType, rootHash, path)
+				if err != nil || !add(node) {
+					return res, nil
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// nodeAt returns the trie node stored at the compact encoded path below the root.
+// Nodes embedded in their parent are not stored on their own, so they are not found.
+func nodeAt(lsys ipld.LinkSystem, codec uint64, root common.Hash, compact []byte) ([]byte, error) {
+	// the empty path is that of the root, as go-ethereum reads it
+	var path []byte
+	if len(compact) > 0 {
+		var err error
+		if path, _, err = keys.CompactToNibbles(compact); err != nil {
+			return nil, fmt.Errorf("invalid trie node path %x (%v)", compact, err)
+		}
+	}
+	hash := root
+	for {
+		enc, err := loadBlock(lsys, codec, hash)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return enc, nil
+		}
+		var fields []interface{}
+		if err := rlp.DecodeBytes(enc, &fields); err != nil {
+			return nil, fmt.Errorf("invalid trie node %x (%v)", hash, err)
+		}
+		var child interface{}
+		switch len(fields) {
+		case 17:
+			child, path = fields[path[0]], path[1:]
+		case 2:
+			partial, ok := fields[0].([]byte)
+			if !ok {
+				return nil, fmt.Errorf("invalid partial path type %T", fields[0])
+			}
+			nibbles, leaf, err := keys.CompactToNibbles(partial)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trie node %x (%v)", hash, err)
+			}
+			if leaf || !keys.HasPrefix(path, nibbles) {
+				return nil, fmt.Errorf("no trie node stored at path %x below %x", compact, root)
+			}
+			child, path = fields[1], path[len(nibbles):]
+		default:
+			return nil, fmt.Errorf("trie node needs 2 or 17 elements, got %d", len(fields))
+		}
+		ref, ok := child.([]byte)
+		if !ok || len(ref) != common.HashLength {
+			return nil, fmt.Errorf("no trie node stored at path %x below %x", compact, root)
+		}
+		hash = common.BytesToHash(ref)
+	}
+}
+
+// responseBytes returns the byte limit of a request, capped at the soft response limit.
+// A limit of 0 still serves a single item, as in go-ethereum.
+func responseBytes(requested uint64) uint64 {
+	switch {
+	case requested > softResponseLimit:
+		return softResponseLimit
+	case requested == 0:
+		return 1
+	}
+	return requested
+}
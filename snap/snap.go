@@ -0,0 +1,239 @@
+/*
+Package snap converts the responses of the snap/1 state sync protocol into verified DAG-ETH IPLD blocks, so a snap
+sync client can store the state it downloads as content-addressed blocks, and serves snap/1 requests out of the
+blocks reachable through a LinkSystem, so an IPLD store can back a snap server.
+
+Every response is checked against the request it answers before anything is written: account and storage ranges
+with their boundary proofs against the state and storage roots, contract code against the requested code hashes,
+and trie nodes against the hashes the client expects at the requested paths.
+*/
+package snap
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethsnap "github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	"github.com/vulcanize/go-codec-dageth/proof"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// StoreAccountRange verifies the accounts and boundary proofs of the response against the state root and origin
+// of the request, and writes the state trie nodes they prove through the LinkSystem.
+// It returns whether the state trie holds more accounts to the right of the range.
+func StoreAccountRange(lsys ipld.LinkSystem, req *gethsnap.GetAccountRangePacket, res *gethsnap.AccountRangePacket) (bool, error) {
+	hashes, accounts, err := res.Unpack()
+	if err != nil {
+		return false, err
+	}
+	rp := proof.RangeProof{
+		Origin: req.Origin.Bytes(),
+		Keys:   hashesToKeys(hashes),
+		Values: accounts,
+		Proof:  res.Proof,
+	}
+	stateRoot, err := stateRootLink(req.Root)
+	if err != nil {
+		return false, err
+	}
+	hasMore, err := rp.Store(lsys, stateRoot)
+	if err != nil {
+		return false, fmt.Errorf("invalid account range from %x: %v", req.Origin, err)
+	}
+	return hasMore, nil
+}
+
+// StoreStorageRanges verifies the slots of the response against the storage roots of the requested accounts,
+// and writes the storage trie nodes they prove through the LinkSystem.
+// The storage roots are resolved through the state trie of the request root, so the account range holding the
+// accounts has to be stored first. Every range but the last one has to hold the whole storage trie of its account,
+// and the last one is verified with the boundary proofs of the response, from the origin of the request if it is
+// the only range. It returns whether the storage trie of the last account holds more slots to the right of the range.
+func StoreStorageRanges(lsys ipld.LinkSystem, req *gethsnap.GetStorageRangesPacket, res *gethsnap.StorageRangesPacket) (bool, error) {
+	if len(res.Slots) > len(req.Accounts) {
+		return false, fmt.Errorf("storage ranges response holds %d ranges for %d requested accounts", len(res.Slots), len(req.Accounts))
+	}
+	hashes, slots := res.Unpack()
+	var hasMore bool
+	for i := range hashes {
+		account := req.Accounts[i]
+		root, err := storageRoot(lsys, req.Root, account)
+		if err != nil {
+			return false, err
+		}
+		rp := proof.RangeProof{Keys: hashesToKeys(hashes[i]), Values: slots[i]}
+		if i == len(hashes)-1 {
+			rp.Proof = res.Proof
+			if i == 0 {
+				rp.Origin = req.Origin
+			}
+		}
+		if hasMore, err = rp.Store(lsys, root); err != nil {
+			return false, fmt.Errorf("invalid storage range of account %x: %v", account, err)
+		}
+	}
+	return hasMore, nil
+}
+
+// StoreByteCodes verifies that each contract code of the response hashes to one of the requested code hashes, in
+// the order they were requested in, and writes it through the LinkSystem as a raw block.
+// It returns the code hashes that were served, the response can leave out any of them.
+func StoreByteCodes(lsys ipld.LinkSystem, req *gethsnap.GetByteCodesPacket, res *gethsnap.ByteCodesPacket) ([]common.Hash, error) {
+	served := make([]common.Hash, 0, len(res.Codes))
+	next := 0
+	for _, code := range res.Codes {
+		hash := crypto.Keccak256Hash(code)
+		for next < len(req.Hashes) && req.Hashes[next] != hash {
+			next++
+		}
+		if next == len(req.Hashes) {
+			return nil, fmt.Errorf("byte codes response holds unrequested code %x", hash)
+		}
+		next++
+		if err := storeBlock(lsys, cid.Raw, hash, code); err != nil {
+			return nil, err
+		}
+		served = append(served, hash)
+	}
+	return served, nil
+}
+
+// StoreTrieNodes verifies the trie nodes of the response against the hashes the client expects at the requested
+// paths, and writes them through the LinkSystem as state or storage trie nodes depending on their path set.
+// The hashes follow the paths of the request in order, flattened over the path sets, the way the nodes of the
+// response do. Nodes the response leaves empty are skipped.
+func StoreTrieNodes(lsys ipld.LinkSystem, req *gethsnap.GetTrieNodesPacket, hashes []common.Hash, res *gethsnap.TrieNodesPacket) error {
+	codecs := pathCodecs(req.Paths)
+	if len(hashes) != len(codecs) {
+		return fmt.Errorf("expected a hash for each of the %d requested paths, got %d", len(codecs), len(hashes))
+	}
+	if len(res.Nodes) > len(codecs) {
+		return fmt.Errorf("trie nodes response holds %d nodes for %d requested paths", len(res.Nodes), len(codecs))
+	}
+	for i, node := range res.Nodes {
+		if len(node) == 0 {
+			continue
+		}
+		if hash := crypto.Keccak256Hash(node); hash != hashes[i] {
+			return fmt.Errorf("trie node %d hashes to %x, expected %x", i, hash, hashes[i])
+		}
+		if err := storeBlock(lsys, codecs[i], hashes[i], node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pathCodecs returns the trie multicodec of each requested path, flattened over the path sets: a set of a single
+// path addresses a state trie node, and the paths after the account hash of a longer set address storage trie nodes
+func pathCodecs(paths []gethsnap.TrieNodePathSet) []uint64 {
+	var codecs []uint64
+	for _, set := range paths {
+		switch len(set) {
+		case 0:
+		case 1:
+			codecs = append(codecs, state_trie.MultiCodecType)
+		default:
+			for range set[1:] {
+				codecs = append(codecs, storage_trie.MultiCodecType)
+			}
+		}
+	}
+	return codecs
+}
+
+// storageRoot resolves the link to the storage trie root of the account through the state trie
+func storageRoot(lsys ipld.LinkSystem, root common.Hash, account common.Hash) (ipld.Link, error) {
+	stateRoot, err := stateRootLink(root)
+	if err != nil {
+		return nil, err
+	}
+	p, err := proof.GenerateProof(lsys, stateRoot, account.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve account %x: %v", account, err)
+	}
+	val, err := proof.Verify(stateRoot, account.Bytes(), proof.Encodings(p))
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve account %x: %v", account, err)
+	}
+	if val == nil {
+		return nil, fmt.Errorf("account %x is not in state %s", account, stateRoot.String())
+	}
+	acct, err := val.LookupByString(trie.STATE_VALUE.String())
+	if err != nil {
+		return nil, err
+	}
+	rootNode, err := acct.LookupByString("StorageRootCID")
+	if err != nil {
+		return nil, err
+	}
+	return rootNode.AsLink()
+}
+
+// storeBlock writes the block through the LinkSystem under the keccak256 CID of its multicodec
+func storeBlock(lsys ipld.LinkSystem, codec uint64, hash common.Hash, data []byte) error {
+	if lsys.StorageWriteOpener == nil {
+		return fmt.Errorf("no storage configured for writing")
+	}
+	w, commit, err := lsys.StorageWriteOpener(ipld.LinkContext{})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	c, err := shared.Keccak256ToCid(codec, hash.Bytes())
+	if err != nil {
+		return err
+	}
+	return commit(cidlink.Link{Cid: c})
+}
+
+// loadBlock reads the block with the keccak256 CID of its multicodec through the LinkSystem, and checks its hash
+func loadBlock(lsys ipld.LinkSystem, codec uint64, hash common.Hash) ([]byte, error) {
+	if lsys.StorageReadOpener == nil {
+		return nil, fmt.Errorf("no storage configured for reading")
+	}
+	c, err := shared.Keccak256ToCid(codec, hash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	r, err := lsys.StorageReadOpener(ipld.LinkContext{}, cidlink.Link{Cid: c})
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(crypto.Keccak256(data), hash.Bytes()) {
+		return nil, fmt.Errorf("block %s does not match its hash", c.String())
+	}
+	return data, nil
+}
+
+func stateRootLink(root common.Hash) (ipld.Link, error) {
+	c, err := shared.Keccak256ToCid(state_trie.MultiCodecType, root.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return cidlink.Link{Cid: c}, nil
+}
+
+func hashesToKeys(hashes []common.Hash) [][]byte {
+	keys := make([][]byte, len(hashes))
+	for i, hash := range hashes {
+		keys[i] = hash.Bytes()
+	}
+	return keys
+}
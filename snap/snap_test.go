@@ -0,0 +1,257 @@
+package snap_test
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethsnap "github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/snap"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// mockState stores a state trie of n accounts, every tenth of them with code and a storage trie of 100 slots,
+// and returns the state root along with the hashes of the accounts with storage and of their code
+func mockState(t *testing.T, store *storage.Memory, n int) (common.Hash, []common.Hash, []common.Hash) {
+	g := testutil.NewGenerator(1)
+	lsys := codecs.NewLinkSystem(store)
+	hashes := make([]common.Hash, n)
+	for i := range hashes {
+		hashes[i] = g.Hash()
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+	var withStorage, codeHashes []common.Hash
+	state := trie.NewBuilder(lsys, state_trie.MultiCodecType)
+	for i, hash := range hashes {
+		acct := g.Account()
+		if i%10 == 0 {
+			slots := make([]common.Hash, 100)
+			for j := range slots {
+				slots[j] = g.Hash()
+			}
+			sort.Slice(slots, func(i, j int) bool { return bytes.Compare(slots[i][:], slots[j][:]) < 0 })
+			st := trie.NewBuilder(lsys, storage_trie.MultiCodecType)
+			for _, slot := range slots {
+				val, _ := rlp.EncodeToBytes(g.Bytes(1 + int(slot[0])%32))
+				if err := st.Update(slot[:], val); err != nil {
+					t.Fatal(err)
+				}
+			}
+			root, err := st.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			code := g.Bytes(200)
+			store.Bag[testutil.KeccakLink(t, cid.Raw, crypto.Keccak256(code))] = code
+			acct.Root = common.BytesToHash(root.(cidlink.Link).Hash()[2:])
+			acct.CodeHash = crypto.Keccak256(code)
+			withStorage = append(withStorage, hash)
+			codeHashes = append(codeHashes, common.BytesToHash(acct.CodeHash))
+		}
+		enc, _ := rlp.EncodeToBytes(acct)
+		if err := state.Update(hash[:], enc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := state.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return common.BytesToHash(root.(cidlink.Link).Hash()[2:]), withStorage, codeHashes
+}
+
+func next(hash common.Hash) common.Hash {
+	return common.BigToHash(new(big.Int).Add(hash.Big(), big.NewInt(1)))
+}
+
+func TestSync(t *testing.T) {
+	server := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	root, withStorage, codeHashes := mockState(t, server, 300)
+	serverLsys := codecs.NewLinkSystem(server)
+	client := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	clientLsys := codecs.NewLinkSystem(client)
+
+	// sync the accounts range by range
+	var ranges int
+	for origin := (common.Hash{}); ; ranges++ {
+		req := &gethsnap.GetAccountRangePacket{ID: uint64(ranges), Root: root, Origin: origin, Limit: common.MaxHash, Bytes: 4000}
+		res, err := snap.AccountRange(serverLsys, req)
+		if err != nil {
+			t.Fatalf("unable to serve account range: %v", err)
+		}
+		if res.ID != req.ID || len(res.Accounts) == 0 {
+			t.Fatalf("expected a response to request %d with accounts, got %d accounts for %d", req.ID, len(res.Accounts), res.ID)
+		}
+		hasMore, err := snap.StoreAccountRange(clientLsys, req, res)
+		if err != nil {
+			t.Fatalf("unable to store account range: %v", err)
+		}
+		if !hasMore {
+			break
+		}
+		origin = next(res.Accounts[len(res.Accounts)-1].Hash)
+	}
+	if ranges == 0 {
+		t.Error("expected the accounts to be synced over several ranges")
+	}
+
+	// then the storage of the accounts with storage, continuing large ranges from their last slot
+	for accounts := withStorage; len(accounts) > 0; {
+		req := &gethsnap.GetStorageRangesPacket{Root: root, Accounts: accounts, Bytes: 3000}
+		for {
+			res, err := snap.StorageRanges(serverLsys, req)
+			if err != nil {
+				t.Fatalf("unable to serve storage ranges: %v", err)
+			}
+			hasMore, err := snap.StoreStorageRanges(clientLsys, req, res)
+			if err != nil {
+				t.Fatalf("unable to store storage ranges: %v", err)
+			}
+			last := res.Slots[len(res.Slots)-1]
+			if !hasMore {
+				accounts = req.Accounts[len(res.Slots):]
+				break
+			}
+			origin := next(last[len(last)-1].Hash)
+			req = &gethsnap.GetStorageRangesPacket{Root: root, Accounts: req.Accounts[len(res.Slots)-1:], Origin: origin[:], Bytes: 3000}
+		}
+	}
+
+	// and the code
+	codeReq := &gethsnap.GetByteCodesPacket{Hashes: codeHashes, Bytes: 1000}
+	codeRes, err := snap.ByteCodes(serverLsys, codeReq)
+	if err != nil {
+		t.Fatalf("unable to serve byte codes: %v", err)
+	}
+	if len(codeRes.Codes) == 0 || len(codeRes.Codes) == len(codeHashes) {
+		t.Errorf("expected the byte limit to cut the codes short, got %d of %d", len(codeRes.Codes), len(codeHashes))
+	}
+	for len(codeReq.Hashes) > 0 {
+		if codeRes, err = snap.ByteCodes(serverLsys, codeReq); err != nil {
+			t.Fatalf("unable to serve byte codes: %v", err)
+		}
+		served, err := snap.StoreByteCodes(clientLsys, codeReq, codeRes)
+		if err != nil {
+			t.Fatalf("unable to store byte codes: %v", err)
+		}
+		codeReq.Hashes = codeReq.Hashes[len(served):]
+	}
+
+	for lnk := range server.Bag {
+		if _, ok := client.Bag[lnk]; !ok {
+			t.Errorf("block %s was not synced", lnk.String())
+		}
+	}
+	if len(client.Bag) != len(server.Bag) {
+		t.Errorf("expected the client to hold the %d blocks of the server, got %d", len(server.Bag), len(client.Bag))
+	}
+}
+
+func TestTrieNodes(t *testing.T) {
+	server := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	root, withStorage, _ := mockState(t, server, 50)
+	serverLsys := codecs.NewLinkSystem(server)
+
+	req := &gethsnap.GetTrieNodesPacket{
+		Root: root,
+		Paths: []gethsnap.TrieNodePathSet{
+			{{}},
+			{withStorage[0][:], {}, shared.HexToCompact([]byte{0})},
+			{shared.HexToCompact([]byte{0xf})},
+		},
+		Bytes: 1 << 20,
+	}
+	res, err := snap.TrieNodes(serverLsys, req)
+	if err != nil {
+		t.Fatalf("unable to serve trie nodes: %v", err)
+	}
+	if len(res.Nodes) != 4 {
+		t.Fatalf("expected 4 trie nodes, got %d", len(res.Nodes))
+	}
+	hashes := make([]common.Hash, len(res.Nodes))
+	for i, node := range res.Nodes {
+		hashes[i] = crypto.Keccak256Hash(node)
+	}
+	if hashes[0] != root {
+		t.Errorf("expected the node at the empty path to be the state root %x, got %x", root, hashes[0])
+	}
+
+	client := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	clientLsys := codecs.NewLinkSystem(client)
+	if err := snap.StoreTrieNodes(clientLsys, req, hashes, res); err != nil {
+		t.Fatalf("unable to store trie nodes: %v", err)
+	}
+	trieCodecs := []uint64{state_trie.MultiCodecType, storage_trie.MultiCodecType, storage_trie.MultiCodecType, state_trie.MultiCodecType}
+	for i, hash := range hashes {
+		if _, ok := client.Bag[testutil.KeccakLink(t, trieCodecs[i], hash[:])]; !ok {
+			t.Errorf("expected trie node %d to be stored as %#x", i, trieCodecs[i])
+		}
+	}
+
+	// a response that does not match the expected hashes is rejected
+	hashes[1], hashes[2] = hashes[2], hashes[1]
+	if err := snap.StoreTrieNodes(clientLsys, req, hashes, res); err == nil {
+		t.Error("expected storing trie nodes that do not match their hashes to fail")
+	}
+
+	// the response ends before the first path that cannot be resolved
+	req.Paths = append([]gethsnap.TrieNodePathSet{{shared.HexToCompact([]byte{1, 2, 3, 4, 5, 6, 7, 8})}}, req.Paths...)
+	if res, err = snap.TrieNodes(serverLsys, req); err != nil || len(res.Nodes) != 0 {
+		t.Errorf("expected an empty response for an unresolvable path, got %d nodes (%v)", len(res.Nodes), err)
+	}
+	// as it does before a path that is not a compact path
+	req.Paths[0] = gethsnap.TrieNodePathSet{{0x4f}}
+	if res, err = snap.TrieNodes(serverLsys, req); err != nil || len(res.Nodes) != 0 {
+		t.Errorf("expected an empty response for a malformed path, got %d nodes (%v)", len(res.Nodes), err)
+	}
+}
+
+func TestRejectInvalidResponses(t *testing.T) {
+	server := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	root, withStorage, codeHashes := mockState(t, server, 50)
+	serverLsys := codecs.NewLinkSystem(server)
+	clientLsys := codecs.NewLinkSystem(&storage.Memory{Bag: make(map[ipld.Link][]byte)})
+
+	accountReq := &gethsnap.GetAccountRangePacket{Root: root, Limit: common.MaxHash, Bytes: 1000}
+	accountRes, err := snap.AccountRange(serverLsys, accountReq)
+	if err != nil {
+		t.Fatalf("unable to serve account range: %v", err)
+	}
+	accountRes.Accounts = append(accountRes.Accounts[:1:1], accountRes.Accounts[2:]...)
+	if _, err := snap.StoreAccountRange(clientLsys, accountReq, accountRes); err == nil {
+		t.Error("expected storing an account range with a missing account to fail")
+	}
+	// the accounts are not stored, so the storage roots cannot be resolved
+	storageReq := &gethsnap.GetStorageRangesPacket{Root: root, Accounts: withStorage[:1], Bytes: 1 << 20}
+	storageRes, err := snap.StorageRanges(serverLsys, storageReq)
+	if err != nil {
+		t.Fatalf("unable to serve storage ranges: %v", err)
+	}
+	if _, err := snap.StoreStorageRanges(clientLsys, storageReq, storageRes); err == nil {
+		t.Error("expected storing storage ranges of unknown accounts to fail")
+	}
+	// a slot that was changed does not verify
+	storageRes.Slots[0][5].Body = []byte{0x01}
+	if _, err := snap.StoreStorageRanges(serverLsys, storageReq, storageRes); err == nil {
+		t.Error("expected storing a changed slot to fail")
+	}
+
+	codeReq := &gethsnap.GetByteCodesPacket{Hashes: codeHashes[1:], Bytes: 1 << 20}
+	if _, err := snap.StoreByteCodes(clientLsys, codeReq, &gethsnap.ByteCodesPacket{Codes: [][]byte{{0x60, 0x00}}}); err == nil {
+		t.Error("expected storing unrequested code to fail")
+	}
+}
@@ -0,0 +1,67 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+)
+
+// EncodeOptions can be used to customize the behavior of snapshot account encoding.
+// The zero value is the default behavior used by the registered codec.
+type EncodeOptions struct {
+	// Account customizes how the links of the Account node are checked, as for the state account codec
+	Account account.EncodeOptions
+}
+
+// Encode provides an IPLD codec encode interface for eth snapshot account IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0xf5 when this package is invoked via init.
+func Encode(node ipld.Node, w io.Writer) error {
+	return EncodeOptions{}.Encode(node, w)
+}
+
+// AppendEncode is like Encode, but it uses a destination buffer directly.
+// This means less copying of bytes, and if the destination has enough capacity,
+// fewer allocations.
+func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return EncodeOptions{}.AppendEncode(enc, inNode)
+}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return shared.WriteEncoded(w, node, cfg.AppendEncode)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	acct := new(types.StateAccount)
+	if err := cfg.Account.EncodeAccount(acct, inNode); err != nil {
+		return enc, err
+	}
+	return append(enc, types.SlimAccountRLP(*acct)...), nil
+}
+
+// ToSlim converts the consensus RLP of an account, the value of its state trie leaf, into a slim snapshot account
+func ToSlim(full []byte) ([]byte, error) {
+	acct := new(types.StateAccount)
+	if err := rlp.DecodeBytes(full, acct); err != nil {
+		return nil, fmt.Errorf("invalid account RLP (%v)", err)
+	}
+	return types.SlimAccountRLP(*acct), nil
+}
@@ -0,0 +1,33 @@
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// EncodeSlot returns the snapshot encoding of a storage slot value, the RLP of the value with its leading zeros
+// trimmed, which is also the value of the slot's storage trie leaf
+func EncodeSlot(value common.Hash) []byte {
+	enc, _ := rlp.EncodeToBytes(common.TrimLeftZeroes(value[:]))
+	return enc
+}
+
+// DecodeSlot decodes the snapshot encoding of a storage slot back into its 32 byte value
+func DecodeSlot(enc []byte) (common.Hash, error) {
+	content, rest, err := rlp.SplitString(enc)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid snapshot storage slot (%v)", err)
+	}
+	if len(rest) != 0 {
+		return common.Hash{}, fmt.Errorf("invalid snapshot storage slot (%d trailing bytes)", len(rest))
+	}
+	if len(content) > common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid snapshot storage slot (%d byte value)", len(content))
+	}
+	if len(content) > 0 && content[0] == 0 {
+		return common.Hash{}, fmt.Errorf("invalid snapshot storage slot (value has leading zeros)")
+	}
+	return common.BytesToHash(content), nil
+}
@@ -0,0 +1,97 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	snapshot "github.com/vulcanize/go-codec-dageth/snapshot_account"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+)
+
+var (
+	emptyAccount = types.StateAccount{
+		Nonce:    1,
+		Balance:  uint256.NewInt(1000000000),
+		Root:     types.EmptyRootHash,
+		CodeHash: types.EmptyCodeHash.Bytes(),
+	}
+	contractAccount = types.StateAccount{
+		Nonce:    7,
+		Balance:  uint256.NewInt(42),
+		Root:     crypto.Keccak256Hash([]byte("storage")),
+		CodeHash: crypto.Keccak256([]byte("code")),
+	}
+)
+
+func TestSnapshotAccountCodec(t *testing.T) {
+	for name, acct := range map[string]types.StateAccount{"empty": emptyAccount, "contract": contractAccount} {
+		slim := types.SlimAccountRLP(acct)
+		full, err := rlp.EncodeToBytes(&acct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshotBuilder := dageth.Type.Account.NewBuilder()
+		if err := snapshot.DecodeBytes(snapshotBuilder, slim); err != nil {
+			t.Fatalf("%s: unable to decode slim account: %v", name, err)
+		}
+		snapshotNode := snapshotBuilder.Build()
+		// the slim account decodes into the same node as the trie-derived account
+		accountBuilder := dageth.Type.Account.NewBuilder()
+		if err := account.DecodeBytes(accountBuilder, full); err != nil {
+			t.Fatalf("%s: unable to decode full account: %v", name, err)
+		}
+		if !ipld.DeepEqual(snapshotNode, accountBuilder.Build()) {
+			t.Errorf("%s: slim account node does not match the full account node", name)
+		}
+		enc, err := snapshot.AppendEncode(nil, snapshotNode)
+		if err != nil {
+			t.Fatalf("%s: unable to encode slim account: %v", name, err)
+		}
+		if !bytes.Equal(enc, slim) {
+			t.Errorf("%s: slim account encoding (%x) does not match the expected encoding (%x)", name, enc, slim)
+		}
+		if converted, err := snapshot.ToFull(slim); err != nil || !bytes.Equal(converted, full) {
+			t.Errorf("%s: slim account converts to %x (%v), expected %x", name, converted, err, full)
+		}
+		if converted, err := snapshot.ToSlim(full); err != nil || !bytes.Equal(converted, slim) {
+			t.Errorf("%s: full account converts to %x (%v), expected %x", name, converted, err, slim)
+		}
+	}
+
+	// a slim account spelling out the empty storage root would not encode back into the same bytes
+	nonCanonical, err := rlp.EncodeToBytes(&types.SlimAccount{
+		Nonce:   emptyAccount.Nonce,
+		Balance: emptyAccount.Balance,
+		Root:    types.EmptyRootHash.Bytes(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshot.DecodeBytes(dageth.Type.Account.NewBuilder(), nonCanonical); err == nil {
+		t.Error("expected an error decoding a non-canonical slim account")
+	}
+}
+
+func TestSnapshotSlot(t *testing.T) {
+	for _, value := range []common.Hash{{}, common.HexToHash("0x01"), crypto.Keccak256Hash([]byte("slot"))} {
+		enc := snapshot.EncodeSlot(value)
+		decoded, err := snapshot.DecodeSlot(enc)
+		if err != nil {
+			t.Fatalf("unable to decode slot %x: %v", enc, err)
+		}
+		if decoded != value {
+			t.Errorf("slot decoded into %s, expected %s", decoded.Hex(), value.Hex())
+		}
+	}
+	if _, err := snapshot.DecodeSlot([]byte{0x82, 0x00, 0x01}); err == nil {
+		t.Error("expected an error decoding a slot with leading zeros")
+	}
+}
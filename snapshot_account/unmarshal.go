@@ -0,0 +1,102 @@
+/*
+Package snapshot is the codec of the accounts of go-ethereum's snapshot, the flat state database keyed by account
+hash, which stores accounts in the "slim" RLP encoding that leaves out the storage root and code hash of accounts
+without storage or code.
+
+Slim accounts decode into the same Account node as the eth-account-snapshot codec of the state trie leaves, with the
+storage root and code hash of empty accounts filled in, so flat-state databases can be content-addressed and their
+accounts compared with the ones derived from the state trie. ToFull and ToSlim convert between the two encodings.
+*/
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+)
+
+// DecodeOptions can be used to customize the behavior of snapshot account decoding.
+// The zero value is the default behavior used by the registered codec.
+type DecodeOptions struct {
+	// Account customizes the links of the decoded Account node, as for the state account codec
+	Account account.DecodeOptions
+}
+
+// Decode provides an IPLD codec decode interface for eth snapshot account IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0xf5 when this package is invoked via init.
+func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return DecodeOptions{}.Decode(na, in)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeOptions{}.DecodeBytes(na, src)
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
+	var src []byte
+	if buf, ok := in.(interface{ Bytes() []byte }); ok {
+		src = buf.Bytes()
+	} else {
+		var err error
+		src, err = ioutil.ReadAll(in)
+		if err != nil {
+			return err
+		}
+	}
+	return cfg.DecodeBytes(na, src)
+}
+
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options.
+// Slim accounts that spell out the empty storage root or code hash are rejected, as they would not encode back
+// into the bytes they were decoded from.
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	acct, err := types.FullAccount(src)
+	if err != nil {
+		return fmt.Errorf("invalid DAG-ETH Snapshot Account binary (%v)", err)
+	}
+	if !bytes.Equal(types.SlimAccountRLP(*acct), src) {
+		return fmt.Errorf("invalid DAG-ETH Snapshot Account binary (non-canonical slim encoding)")
+	}
+	return cfg.Account.DecodeAccount(na, *acct)
+}
+
+// ToFull converts a slim snapshot account into the consensus RLP of the account, the value of its state trie leaf
+func ToFull(slim []byte) ([]byte, error) {
+	return types.FullAccountRLP(slim)
+}
@@ -0,0 +1,454 @@
+package ssz
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// BytesPerOffset is the size of the offsets that locate variable-size values in a serialization
+const BytesPerOffset = 4
+
+func (t Uint) decode(na ipld.NodeAssembler, src []byte) error {
+	if len(src) != t.Bytes {
+		return fmt.Errorf("expected %d bytes for uint%d, got %d", t.Bytes, t.Bytes*8, len(src))
+	}
+	// SSZ integers are little-endian, the DAG-ETH form is big-endian
+	return na.AssignBytes(reverse(src))
+}
+
+func (t Uint) encode(enc []byte, node ipld.Node) ([]byte, error) {
+	b, err := node.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > t.Bytes {
+		return nil, fmt.Errorf("expected at most %d bytes for uint%d, got %d", t.Bytes, t.Bytes*8, len(b))
+	}
+	enc = append(enc, reverse(b)...)
+	return append(enc, make([]byte, t.Bytes-len(b))...), nil
+}
+
+func (Bool) decode(na ipld.NodeAssembler, src []byte) error {
+	if len(src) != 1 || src[0] > 1 {
+		return fmt.Errorf("invalid boolean %x", src)
+	}
+	return na.AssignBool(src[0] == 1)
+}
+
+func (Bool) encode(enc []byte, node ipld.Node) ([]byte, error) {
+	b, err := node.AsBool()
+	if err != nil {
+		return nil, err
+	}
+	if b {
+		return append(enc, 1), nil
+	}
+	return append(enc, 0), nil
+}
+
+func (t ByteVector) decode(na ipld.NodeAssembler, src []byte) error {
+	if len(src) != t.Length {
+		return fmt.Errorf("expected %d bytes, got %d", t.Length, len(src))
+	}
+	return na.AssignBytes(copyBytes(src))
+}
+
+func (t ByteVector) encode(enc []byte, node ipld.Node) ([]byte, error) {
+	b, err := node.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != t.Length {
+		return nil, fmt.Errorf("expected %d bytes, got %d", t.Length, len(b))
+	}
+	return append(enc, b...), nil
+}
+
+func (t ByteList) decode(na ipld.NodeAssembler, src []byte) error {
+	if uint64(len(src)) > t.Limit {
+		return fmt.Errorf("expected at most %d bytes, got %d", t.Limit, len(src))
+	}
+	return na.AssignBytes(copyBytes(src))
+}
+
+func (t ByteList) encode(enc []byte, node ipld.Node) ([]byte, error) {
+	b, err := node.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(b)) > t.Limit {
+		return nil, fmt.Errorf("expected at most %d bytes, got %d", t.Limit, len(b))
+	}
+	return append(enc, b...), nil
+}
+
+func (t Bitvector) decode(na ipld.NodeAssembler, src []byte) error {
+	if err := t.check(src); err != nil {
+		return err
+	}
+	return na.AssignBytes(copyBytes(src))
+}
+
+func (t Bitvector) encode(enc []byte, node ipld.Node) ([]byte, error) {
+	b, err := node.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.check(b); err != nil {
+		return nil, err
+	}
+	return append(enc, b...), nil
+}
+
+// check checks the size of the bitvector, and that the bits past its length are unset
+func (t Bitvector) check(b []byte) error {
+	if len(b) != t.Size() {
+		return fmt.Errorf("expected %d bytes for %d bits, got %d", t.Size(), t.Length, len(b))
+	}
+	if t.Length%8 != 0 && b[len(b)-1]>>(t.Length%8) != 0 {
+		return fmt.Errorf("bitvector of %d bits has bits set past its length", t.Length)
+	}
+	return nil
+}
+
+func (t Bitlist) decode(na ipld.NodeAssembler, src []byte) error {
+	if _, err := t.length(src); err != nil {
+		return err
+	}
+	return na.AssignBytes(copyBytes(src))
+}
+
+func (t Bitlist) encode(enc []byte, node ipld.Node) ([]byte, error) {
+	b, err := node.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.length(b); err != nil {
+		return nil, err
+	}
+	return append(enc, b...), nil
+}
+
+// length returns the number of bits of the bitlist, which ends with the highest set bit of its last byte
+func (t Bitlist) length(b []byte) (uint64, error) {
+	if len(b) == 0 || b[len(b)-1] == 0 {
+		return 0, fmt.Errorf("bitlist is missing its length delimiting bit")
+	}
+	last := b[len(b)-1]
+	length := uint64(len(b)-1) * 8
+	for last > 1 {
+		last >>= 1
+		length++
+	}
+	if length > t.Limit {
+		return 0, fmt.Errorf("expected at most %d bits, got %d", t.Limit, length)
+	}
+	return length, nil
+}
+
+func (t Root) decode(na ipld.NodeAssembler, src []byte) error {
+	if len(src) != BytesPerChunk {
+		return fmt.Errorf("expected a %d byte root, got %d bytes", BytesPerChunk, len(src))
+	}
+	c, err := shared.HashToCid(t.Codec, t.MultiHash, src)
+	if err != nil {
+		return err
+	}
+	return na.AssignLink(cidlink.Link{Cid: c})
+}
+
+func (t Root) encode(enc []byte, node ipld.Node) ([]byte, error) {
+	lnk, err := node.AsLink()
+	if err != nil {
+		return nil, err
+	}
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return nil, fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+	}
+	if cl.Cid.Prefix().Codec != t.Codec {
+		return nil, fmt.Errorf("expected a link with codec %#x, got %#x", t.Codec, cl.Cid.Prefix().Codec)
+	}
+	mh, err := multihash.Decode(cl.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if mh.Code != t.MultiHash || len(mh.Digest) != BytesPerChunk {
+		return nil, fmt.Errorf("expected a %d byte %#x multihash, got %d bytes of %#x", BytesPerChunk, t.MultiHash, len(mh.Digest), mh.Code)
+	}
+	return append(enc, mh.Digest...), nil
+}
+
+func (t Vector) decode(na ipld.NodeAssembler, src []byte) error {
+	elems, err := t.split(src)
+	if err != nil {
+		return err
+	}
+	return decodeElems(t.Elem, na, elems)
+}
+
+func (t Vector) encode(enc []byte, node ipld.Node) ([]byte, error) {
+	if node.Length() != int64(t.Length) {
+		return nil, fmt.Errorf("expected %d elements, got %d", t.Length, node.Length())
+	}
+	return encodeElems(t.Elem, enc, node)
+}
+
+// split splits the serialization of the vector into its elements
+func (t Vector) split(src []byte) ([][]byte, error) {
+	if size := t.Elem.Size(); size > 0 {
+		if len(src) != t.Length*size {
+			return nil, fmt.Errorf("expected %d bytes for %d elements, got %d", t.Length*size, t.Length, len(src))
+		}
+		return splitFixed(src, size), nil
+	}
+	return splitOffsets(src, t.Length)
+}
+
+func (t List) decode(na ipld.NodeAssembler, src []byte) error {
+	elems, err := t.split(src)
+	if err != nil {
+		return err
+	}
+	return decodeElems(t.Elem, na, elems)
+}
+
+func (t List) encode(enc []byte, node ipld.Node) ([]byte, error) {
+	if uint64(node.Length()) > t.Limit {
+		return nil, fmt.Errorf("expected at most %d elements, got %d", t.Limit, node.Length())
+	}
+	return encodeElems(t.Elem, enc, node)
+}
+
+// split splits the serialization of the list into its elements
+func (t List) split(src []byte) ([][]byte, error) {
+	var elems [][]byte
+	if size := t.Elem.Size(); size > 0 {
+		if len(src)%size != 0 {
+			return nil, fmt.Errorf("list of %d byte elements has %d bytes", size, len(src))
+		}
+		elems = splitFixed(src, size)
+	} else if len(src) > 0 {
+		if len(src) < BytesPerOffset {
+			return nil, fmt.Errorf("list of %d bytes is too short for its first offset", len(src))
+		}
+		first := binary.LittleEndian.Uint32(src)
+		if first == 0 || first%BytesPerOffset != 0 {
+			return nil, fmt.Errorf("invalid first offset %d", first)
+		}
+		var err error
+		if elems, err = splitOffsets(src, int(first/BytesPerOffset)); err != nil {
+			return nil, err
+		}
+	}
+	if uint64(len(elems)) > t.Limit {
+		return nil, fmt.Errorf("expected at most %d elements, got %d", t.Limit, len(elems))
+	}
+	return elems, nil
+}
+
+func (t Container) decode(na ipld.NodeAssembler, src []byte) error {
+	fields, err := t.split(src)
+	if err != nil {
+		return err
+	}
+	ma, err := na.BeginMap(int64(len(t.Fields)))
+	if err != nil {
+		return err
+	}
+	for i, f := range t.Fields {
+		if err := ma.AssembleKey().AssignString(f.Name); err != nil {
+			return err
+		}
+		if err := f.Type.decode(ma.AssembleValue(), fields[i]); err != nil {
+			return fmt.Errorf("invalid %s: %v", f.Name, err)
+		}
+	}
+	return ma.Finish()
+}
+
+func (t Container) encode(enc []byte, node ipld.Node) ([]byte, error) {
+	fixedSize := 0
+	fields := make([][]byte, len(t.Fields))
+	for i, f := range t.Fields {
+		fieldNode, err := node.LookupByString(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if fields[i], err = f.Type.encode(nil, fieldNode); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", f.Name, err)
+		}
+		if size := f.Type.Size(); size > 0 {
+			fixedSize += size
+		} else {
+			fixedSize += BytesPerOffset
+		}
+	}
+	// the fixed-size fields are serialized in place, the variable-size ones by their offset after the fixed part
+	offset := fixedSize
+	for i, f := range t.Fields {
+		if f.Type.Size() > 0 {
+			enc = append(enc, fields[i]...)
+			continue
+		}
+		enc = binary.LittleEndian.AppendUint32(enc, uint32(offset))
+		offset += len(fields[i])
+	}
+	for i, f := range t.Fields {
+		if f.Type.Size() == 0 {
+			enc = append(enc, fields[i]...)
+		}
+	}
+	return enc, nil
+}
+
+// split splits the serialization of the container into its fields
+func (t Container) split(src []byte) ([][]byte, error) {
+	fields := make([][]byte, len(t.Fields))
+	var variable []int
+	var offsets []int
+	pos := 0
+	for i, f := range t.Fields {
+		size := f.Type.Size()
+		if size == 0 {
+			size = BytesPerOffset
+		}
+		if len(src) < pos+size {
+			return nil, fmt.Errorf("container of %d bytes is too short for %s", len(src), f.Name)
+		}
+		if f.Type.Size() > 0 {
+			fields[i] = src[pos : pos+size]
+		} else {
+			variable = append(variable, i)
+			offsets = append(offsets, int(binary.LittleEndian.Uint32(src[pos:])))
+		}
+		pos += size
+	}
+	if len(variable) == 0 {
+		if len(src) != pos {
+			return nil, fmt.Errorf("expected %d bytes, got %d", pos, len(src))
+		}
+		return fields, nil
+	}
+	if offsets[0] != pos {
+		return nil, fmt.Errorf("expected the first offset to be %d, got %d", pos, offsets[0])
+	}
+	offsets = append(offsets, len(src))
+	for j, i := range variable {
+		if offsets[j+1] < offsets[j] || offsets[j+1] > len(src) {
+			return nil, fmt.Errorf("invalid offset %d of %s", offsets[j+1], t.Fields[i].Name)
+		}
+		fields[i] = src[offsets[j]:offsets[j+1]]
+	}
+	return fields, nil
+}
+
+func decodeElems(t Type, na ipld.NodeAssembler, elems [][]byte) error {
+	la, err := na.BeginList(int64(len(elems)))
+	if err != nil {
+		return err
+	}
+	for i, elem := range elems {
+		if err := t.decode(la.AssembleValue(), elem); err != nil {
+			return fmt.Errorf("invalid element %d: %v", i, err)
+		}
+	}
+	return la.Finish()
+}
+
+func encodeElems(t Type, enc []byte, node ipld.Node) ([]byte, error) {
+	it := node.ListIterator()
+	if it == nil {
+		return nil, fmt.Errorf("expected a list, got %s", node.Kind())
+	}
+	if t.Size() > 0 {
+		for !it.Done() {
+			i, elem, err := it.Next()
+			if err != nil {
+				return nil, err
+			}
+			if enc, err = t.encode(enc, elem); err != nil {
+				return nil, fmt.Errorf("invalid element %d: %v", i, err)
+			}
+		}
+		return enc, nil
+	}
+	// variable-size elements are preceded by their offsets
+	var elems [][]byte
+	for !it.Done() {
+		i, elem, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		b, err := t.encode(nil, elem)
+		if err != nil {
+			return nil, fmt.Errorf("invalid element %d: %v", i, err)
+		}
+		elems = append(elems, b)
+	}
+	offset := len(elems) * BytesPerOffset
+	for _, elem := range elems {
+		enc = binary.LittleEndian.AppendUint32(enc, uint32(offset))
+		offset += len(elem)
+	}
+	for _, elem := range elems {
+		enc = append(enc, elem...)
+	}
+	return enc, nil
+}
+
+// splitFixed splits a serialization into elements of the size
+func splitFixed(src []byte, size int) [][]byte {
+	elems := make([][]byte, len(src)/size)
+	for i := range elems {
+		elems[i] = src[i*size : (i+1)*size]
+	}
+	return elems
+}
+
+// splitOffsets splits a serialization of n variable-size elements, which starts with their offsets
+func splitOffsets(src []byte, n int) ([][]byte, error) {
+	if n == 0 {
+		if len(src) != 0 {
+			return nil, fmt.Errorf("expected no bytes, got %d", len(src))
+		}
+		return nil, nil
+	}
+	if len(src) < n*BytesPerOffset {
+		return nil, fmt.Errorf("%d bytes are too short for %d offsets", len(src), n)
+	}
+	offsets := make([]int, n+1)
+	for i := 0; i < n; i++ {
+		offsets[i] = int(binary.LittleEndian.Uint32(src[i*BytesPerOffset:]))
+	}
+	offsets[n] = len(src)
+	if offsets[0] != n*BytesPerOffset {
+		return nil, fmt.Errorf("expected the first offset to be %d, got %d", n*BytesPerOffset, offsets[0])
+	}
+	elems := make([][]byte, n)
+	for i := range elems {
+		if offsets[i+1] < offsets[i] || offsets[i+1] > len(src) {
+			return nil, fmt.Errorf("invalid offset %d of element %d", offsets[i+1], i+1)
+		}
+		elems[i] = src[offsets[i]:offsets[i+1]]
+	}
+	return elems, nil
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i, c := range b {
+		r[len(b)-1-i] = c
+	}
+	return r
+}
+
+func copyBytes(b []byte) []byte {
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,219 @@
+package ssz
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// zeroHashes holds the roots of the all zero trees of each depth, which pad the chunks of merkleized values
+var zeroHashes [65][BytesPerChunk]byte
+
+func init() {
+	for i := 1; i < len(zeroHashes); i++ {
+		zeroHashes[i] = hashPair(zeroHashes[i-1], zeroHashes[i-1])
+	}
+}
+
+func (t Uint) hashTreeRoot(src []byte) ([BytesPerChunk]byte, error) {
+	if len(src) != t.Bytes {
+		return [BytesPerChunk]byte{}, fmt.Errorf("expected %d bytes for uint%d, got %d", t.Bytes, t.Bytes*8, len(src))
+	}
+	return merkleize(pack(src), 1)
+}
+
+func (Bool) hashTreeRoot(src []byte) ([BytesPerChunk]byte, error) {
+	if len(src) != 1 || src[0] > 1 {
+		return [BytesPerChunk]byte{}, fmt.Errorf("invalid boolean %x", src)
+	}
+	return merkleize(pack(src), 1)
+}
+
+func (t ByteVector) hashTreeRoot(src []byte) ([BytesPerChunk]byte, error) {
+	if len(src) != t.Length {
+		return [BytesPerChunk]byte{}, fmt.Errorf("expected %d bytes, got %d", t.Length, len(src))
+	}
+	return merkleize(pack(src), chunkCount(uint64(t.Length)))
+}
+
+func (t ByteList) hashTreeRoot(src []byte) ([BytesPerChunk]byte, error) {
+	if uint64(len(src)) > t.Limit {
+		return [BytesPerChunk]byte{}, fmt.Errorf("expected at most %d bytes, got %d", t.Limit, len(src))
+	}
+	root, err := merkleize(pack(src), chunkCount(t.Limit))
+	if err != nil {
+		return root, err
+	}
+	return mixInLength(root, uint64(len(src))), nil
+}
+
+func (t Bitvector) hashTreeRoot(src []byte) ([BytesPerChunk]byte, error) {
+	if err := t.check(src); err != nil {
+		return [BytesPerChunk]byte{}, err
+	}
+	return merkleize(pack(src), chunkCount((uint64(t.Length)+7)/8))
+}
+
+func (t Bitlist) hashTreeRoot(src []byte) ([BytesPerChunk]byte, error) {
+	length, err := t.length(src)
+	if err != nil {
+		return [BytesPerChunk]byte{}, err
+	}
+	// the length delimiting bit is left out of the merkleized bits
+	bitfield := copyBytes(src[:(length+7)/8])
+	if length%8 != 0 {
+		bitfield[len(bitfield)-1] &^= 1 << (length % 8)
+	}
+	root, err := merkleize(pack(bitfield), chunkCount((t.Limit+7)/8))
+	if err != nil {
+		return root, err
+	}
+	return mixInLength(root, length), nil
+}
+
+func (t Root) hashTreeRoot(src []byte) ([BytesPerChunk]byte, error) {
+	var root [BytesPerChunk]byte
+	if len(src) != BytesPerChunk {
+		return root, fmt.Errorf("expected a %d byte root, got %d bytes", BytesPerChunk, len(src))
+	}
+	copy(root[:], src)
+	return root, nil
+}
+
+func (t Vector) hashTreeRoot(src []byte) ([BytesPerChunk]byte, error) {
+	elems, err := t.split(src)
+	if err != nil {
+		return [BytesPerChunk]byte{}, err
+	}
+	if isBasic(t.Elem) {
+		if err := checkBasic(t.Elem, elems); err != nil {
+			return [BytesPerChunk]byte{}, err
+		}
+		return merkleize(pack(src), chunkCount(uint64(t.Length*t.Elem.Size())))
+	}
+	roots, err := elemRoots(t.Elem, elems)
+	if err != nil {
+		return [BytesPerChunk]byte{}, err
+	}
+	return merkleize(roots, uint64(t.Length))
+}
+
+func (t List) hashTreeRoot(src []byte) ([BytesPerChunk]byte, error) {
+	elems, err := t.split(src)
+	if err != nil {
+		return [BytesPerChunk]byte{}, err
+	}
+	var root [BytesPerChunk]byte
+	if isBasic(t.Elem) {
+		if err := checkBasic(t.Elem, elems); err != nil {
+			return root, err
+		}
+		root, err = merkleize(pack(src), chunkCount(t.Limit*uint64(t.Elem.Size())))
+	} else {
+		var roots [][BytesPerChunk]byte
+		if roots, err = elemRoots(t.Elem, elems); err != nil {
+			return root, err
+		}
+		root, err = merkleize(roots, t.Limit)
+	}
+	if err != nil {
+		return root, err
+	}
+	return mixInLength(root, uint64(len(elems))), nil
+}
+
+func (t Container) hashTreeRoot(src []byte) ([BytesPerChunk]byte, error) {
+	fields, err := t.split(src)
+	if err != nil {
+		return [BytesPerChunk]byte{}, err
+	}
+	roots := make([][BytesPerChunk]byte, len(t.Fields))
+	for i, f := range t.Fields {
+		if roots[i], err = f.Type.hashTreeRoot(fields[i]); err != nil {
+			return [BytesPerChunk]byte{}, fmt.Errorf("invalid %s: %v", f.Name, err)
+		}
+	}
+	return merkleize(roots, uint64(len(roots)))
+}
+
+// checkBasic checks the elements of a vector or list of basic values, which are merkleized without being split
+func checkBasic(t Type, elems [][]byte) error {
+	if _, ok := t.(Bool); !ok {
+		return nil
+	}
+	for i, elem := range elems {
+		if elem[0] > 1 {
+			return fmt.Errorf("invalid boolean %x of element %d", elem, i)
+		}
+	}
+	return nil
+}
+
+func elemRoots(t Type, elems [][]byte) ([][BytesPerChunk]byte, error) {
+	roots := make([][BytesPerChunk]byte, len(elems))
+	for i, elem := range elems {
+		var err error
+		if roots[i], err = t.hashTreeRoot(elem); err != nil {
+			return nil, fmt.Errorf("invalid element %d: %v", i, err)
+		}
+	}
+	return roots, nil
+}
+
+// pack splits the bytes into chunks, right padding the last one with zeros
+func pack(b []byte) [][BytesPerChunk]byte {
+	chunks := make([][BytesPerChunk]byte, (len(b)+BytesPerChunk-1)/BytesPerChunk)
+	for i := range chunks {
+		copy(chunks[i][:], b[i*BytesPerChunk:])
+	}
+	return chunks
+}
+
+// chunkCount returns the number of chunks the bytes are packed into
+func chunkCount(size uint64) uint64 {
+	return (size + BytesPerChunk - 1) / BytesPerChunk
+}
+
+// merkleize returns the root of the binary merkle tree of the chunks, padded with zero chunks to the next power of
+// two of the limit
+func merkleize(chunks [][BytesPerChunk]byte, limit uint64) ([BytesPerChunk]byte, error) {
+	if uint64(len(chunks)) > limit {
+		return [BytesPerChunk]byte{}, fmt.Errorf("%d chunks exceed the limit of %d", len(chunks), limit)
+	}
+	depth := 0
+	if limit > 1 {
+		depth = bits.Len64(limit - 1)
+	}
+	if len(chunks) == 0 {
+		return zeroHashes[depth], nil
+	}
+	layer := chunks
+	for d := 0; d < depth; d++ {
+		if len(layer)%2 == 1 {
+			layer = append(layer, zeroHashes[d])
+		}
+		next := make([][BytesPerChunk]byte, len(layer)/2)
+		for i := range next {
+			next[i] = hashPair(layer[2*i], layer[2*i+1])
+		}
+		layer = next
+	}
+	return layer[0], nil
+}
+
+// mixInLength mixes the length of a list into the root of its elements
+func mixInLength(root [BytesPerChunk]byte, length uint64) [BytesPerChunk]byte {
+	var chunk [BytesPerChunk]byte
+	binary.LittleEndian.PutUint64(chunk[:], length)
+	return hashPair(root, chunk)
+}
+
+func hashPair(a, b [BytesPerChunk]byte) [BytesPerChunk]byte {
+	h := sha256.New()
+	h.Write(a[:])
+	h.Write(b[:])
+	var root [BytesPerChunk]byte
+	copy(root[:], h.Sum(nil))
+	return root
+}
@@ -0,0 +1,165 @@
+package ssz_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/ssz"
+)
+
+var mockContainer = ssz.Container{Fields: []ssz.Field{
+	{Name: "Number", Type: ssz.Uint{Bytes: 8}},
+	{Name: "Flag", Type: ssz.Bool{}},
+	{Name: "Data", Type: ssz.ByteList{Limit: 64}},
+	{Name: "ParentCID", Type: ssz.Root{Codec: 0x01a0, MultiHash: ssz.MultiHashType}},
+	{Name: "Bits", Type: ssz.Bitlist{Limit: 16}},
+	{Name: "Items", Type: ssz.List{Elem: ssz.ByteList{Limit: 8}, Limit: 4}},
+}}
+
+func sum(chunks ...[]byte) []byte {
+	h := sha256.New()
+	for _, chunk := range chunks {
+		h.Write(chunk)
+	}
+	return h.Sum(nil)
+}
+
+func chunk(b ...byte) []byte {
+	return append(b, make([]byte, ssz.BytesPerChunk-len(b))...)
+}
+
+func length(n uint64) []byte {
+	c := chunk()
+	binary.LittleEndian.PutUint64(c, n)
+	return c
+}
+
+func TestHashTreeRoot(t *testing.T) {
+	zero := chunk()
+	tests := []struct {
+		name     string
+		typ      ssz.Type
+		src      []byte
+		expected []byte
+	}{
+		{"uint64", ssz.Uint{Bytes: 8}, []byte{1, 2, 0, 0, 0, 0, 0, 0}, chunk(1, 2)},
+		{"zero container", ssz.Container{Fields: []ssz.Field{{Name: "A", Type: ssz.Uint{Bytes: 8}}, {Name: "B", Type: ssz.Bool{}}}}, make([]byte, 9),
+			mustHex("f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a92759fb4b")},
+		// the root of the empty deposit tree of the deposit contract
+		{"empty list", ssz.List{Elem: ssz.ByteVector{Length: 32}, Limit: 1 << 32}, nil,
+			mustHex("d70a234731285c6804c2a4f56711ddb8c82c99740f207854891028af34e27e5e")},
+		{"byte list", ssz.ByteList{Limit: 64}, []byte("abc"), sum(sum(chunk('a', 'b', 'c'), zero), length(3))},
+		{"bitlist", ssz.Bitlist{Limit: 8}, []byte{0x0d}, sum(chunk(0x05), length(3))},
+		{"full bitlist", ssz.Bitlist{Limit: 8}, []byte{0xff, 0x01}, sum(chunk(0xff), length(8))},
+		{"bitvector", ssz.Bitvector{Length: 4}, []byte{0x0a}, chunk(0x0a)},
+		{"vector", ssz.Vector{Elem: ssz.ByteVector{Length: 32}, Length: 3}, bytes.Repeat([]byte{1}, 96),
+			sum(sum(bytes.Repeat([]byte{1}, 64)), sum(bytes.Repeat([]byte{1}, 32), zero))},
+		{"uint list", ssz.List{Elem: ssz.Uint{Bytes: 8}, Limit: 8}, []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0},
+			sum(sum(chunk(1, 0, 0, 0, 0, 0, 0, 0, 2), zero), length(2))},
+	}
+	for _, test := range tests {
+		root, err := ssz.HashTreeRoot(test.typ, test.src)
+		if err != nil {
+			t.Errorf("unable to compute the hash tree root of the %s: %v", test.name, err)
+			continue
+		}
+		if !bytes.Equal(root[:], test.expected) {
+			t.Errorf("expected the hash tree root of the %s to be %x, got %x", test.name, test.expected, root)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var src []byte
+	src = append(src, 7, 0, 0, 0, 0, 0, 0, 1) // Number
+	src = append(src, 1)                      // Flag
+	src = append(src, 53, 0, 0, 0)            // Data offset
+	src = append(src, bytes.Repeat([]byte{0xaa}, 32)...)
+	src = append(src, 56, 0, 0, 0)      // Bits offset
+	src = append(src, 58, 0, 0, 0)      // Items offset
+	src = append(src, 1, 2, 3)          // Data
+	src = append(src, 0xff, 0x03)       // Bits
+	src = append(src, 8, 0, 0, 0)       // first Items offset
+	src = append(src, 10, 0, 0, 0)      // second Items offset
+	src = append(src, 4, 5, 6, 7, 8, 9) // Items
+
+	nb := basicnode.Prototype.Map.NewBuilder()
+	if err := ssz.Decode(mockContainer, nb, src); err != nil {
+		t.Fatalf("unable to decode container: %v", err)
+	}
+	node := nb.Build()
+	numberNode, _ := node.LookupByString("Number")
+	if number, _ := numberNode.AsBytes(); !bytes.Equal(number, []byte{1, 0, 0, 0, 0, 0, 0, 7}) {
+		t.Errorf("expected Number to be big-endian 0x0100000000000007, got %x", number)
+	}
+	parentNode, _ := node.LookupByString("ParentCID")
+	parent, err := parentNode.AsLink()
+	if err != nil {
+		t.Fatalf("expected ParentCID to be a link: %v", err)
+	}
+	mh, _ := multihash.Encode(bytes.Repeat([]byte{0xaa}, 32), ssz.MultiHashType)
+	if expected := (cidlink.Link{Cid: cid.NewCidV1(0x01a0, mh)}); parent != expected {
+		t.Errorf("expected ParentCID %s, got %s", expected.String(), parent.String())
+	}
+	itemsNode, _ := node.LookupByString("Items")
+	if itemsNode.Length() != 2 {
+		t.Errorf("expected 2 Items, got %d", itemsNode.Length())
+	}
+
+	enc, err := ssz.AppendEncode(mockContainer, nil, node)
+	if err != nil {
+		t.Fatalf("unable to encode container: %v", err)
+	}
+	if !bytes.Equal(enc, src) {
+		t.Errorf("container encoding (%x) does not match the input (%x)", enc, src)
+	}
+	if _, err := ssz.HashTreeRoot(mockContainer, src); err != nil {
+		t.Errorf("unable to compute the hash tree root of the container: %v", err)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  ssz.Type
+		src  []byte
+	}{
+		{"short uint", ssz.Uint{Bytes: 8}, []byte{1, 2, 3}},
+		{"boolean", ssz.Bool{}, []byte{2}},
+		{"long byte list", ssz.ByteList{Limit: 2}, []byte{1, 2, 3}},
+		{"bitvector with bits past its length", ssz.Bitvector{Length: 4}, []byte{0x10}},
+		{"bitlist without delimiter", ssz.Bitlist{Limit: 8}, []byte{0x01, 0x00}},
+		{"long bitlist", ssz.Bitlist{Limit: 4}, []byte{0x20}},
+		{"long list", ssz.List{Elem: ssz.Uint{Bytes: 1}, Limit: 2}, []byte{1, 2, 3}},
+		{"partial list element", ssz.List{Elem: ssz.Uint{Bytes: 2}, Limit: 4}, []byte{1, 2, 3}},
+		{"list offset into the offsets", ssz.List{Elem: ssz.ByteList{Limit: 4}, Limit: 4}, []byte{8, 0, 0, 0, 4, 0, 0, 0}},
+		{"decreasing list offsets", ssz.List{Elem: ssz.ByteList{Limit: 4}, Limit: 4}, []byte{8, 0, 0, 0, 7, 0, 0, 0}},
+		{"container with a gap", mockContainer, append(append(append(make([]byte, 9), 54, 0, 0, 0), make([]byte, 32)...), 54, 0, 0, 0, 54, 0, 0, 0, 0)},
+		{"container with extra bytes", ssz.Container{Fields: []ssz.Field{{Name: "A", Type: ssz.Bool{}}}}, []byte{1, 0}},
+	}
+	for _, test := range tests {
+		nb := basicnode.Prototype.Any.NewBuilder()
+		if err := ssz.Decode(test.typ, nb, test.src); err == nil {
+			t.Errorf("expected decoding the %s to fail", test.name)
+		}
+		if _, err := ssz.HashTreeRoot(test.typ, test.src); err == nil {
+			t.Errorf("expected the hash tree root of the %s to fail", test.name)
+		}
+	}
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,190 @@
+/*
+Package ssz implements the SimpleSerialize encoding and hash tree root of the Ethereum consensus layer over IPLD
+nodes. SSZ is not self-describing, so every function takes a Type describing the SSZ type of the block, built out of
+the Uint, Bool, ByteVector, ByteList, Bitvector, Bitlist, Root, Vector, List and Container descriptors.
+
+Decoded nodes take the data model form of their type: containers are maps keyed by field name, vectors and lists are
+lists, unsigned integers are big-endian bytes of the width of the type like the Uint fields of the DAG-ETH schema,
+booleans are booleans, roots are links, and every other byte or bit sequence is bytes, bitlists keeping their
+length delimiting bit.
+*/
+package ssz
+
+import (
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+const (
+	// BytesPerChunk is the size of the chunks SSZ values are merkleized from
+	BytesPerChunk = 32
+	// MultiHashType identifies an SSZ hash tree root using SHA2-256, the multihash of the CIDs of SSZ blocks.
+	// It is not a hash of the block bytes, so the multihash registry has no hasher for it.
+	MultiHashType = uint64(0xb502)
+)
+
+// Type describes an SSZ type
+type Type interface {
+	// Size returns the serialized size of the type, or 0 if the type is variable-size
+	Size() int
+
+	decode(na ipld.NodeAssembler, src []byte) error
+	encode(enc []byte, node ipld.Node) ([]byte, error)
+	hashTreeRoot(src []byte) ([BytesPerChunk]byte, error)
+}
+
+// Uint is an unsigned integer of Bytes bytes, e.g. 8 for a uint64 or 32 for a uint256
+type Uint struct {
+	Bytes int
+}
+
+// Bool is a boolean
+type Bool struct{}
+
+// ByteVector is a fixed number of bytes, e.g. a BLS signature
+type ByteVector struct {
+	Length int
+}
+
+// ByteList is up to Limit bytes, e.g. a transaction
+type ByteList struct {
+	Limit uint64
+}
+
+// Bitvector is a fixed number of bits
+type Bitvector struct {
+	Length int
+}
+
+// Bitlist is up to Limit bits
+type Bitlist struct {
+	Limit uint64
+}
+
+// Root is a 32 byte root that refers to another block, e.g. a beacon block root or an execution block hash.
+// It is decoded into a link with the multicodec type and multihash type of the block it refers to.
+type Root struct {
+	Codec     uint64
+	MultiHash uint64
+}
+
+// Vector is a fixed number of elements of a type
+type Vector struct {
+	Elem   Type
+	Length int
+}
+
+// List is up to Limit elements of a type
+type List struct {
+	Elem  Type
+	Limit uint64
+}
+
+// Field is a named field of a Container
+type Field struct {
+	Name string
+	Type Type
+}
+
+// Container is an ordered set of fields
+type Container struct {
+	Fields []Field
+}
+
+// Size implements Type
+func (t Uint) Size() int { return t.Bytes }
+
+// Size implements Type
+func (Bool) Size() int { return 1 }
+
+// Size implements Type
+func (t ByteVector) Size() int { return t.Length }
+
+// Size implements Type
+func (ByteList) Size() int { return 0 }
+
+// Size implements Type
+func (t Bitvector) Size() int { return (t.Length + 7) / 8 }
+
+// Size implements Type
+func (Bitlist) Size() int { return 0 }
+
+// Size implements Type
+func (Root) Size() int { return BytesPerChunk }
+
+// Size implements Type
+func (t Vector) Size() int { return t.Length * t.Elem.Size() }
+
+// Size implements Type
+func (List) Size() int { return 0 }
+
+// Size implements Type
+func (t Container) Size() int {
+	size := 0
+	for _, f := range t.Fields {
+		fieldSize := f.Type.Size()
+		if fieldSize == 0 {
+			return 0
+		}
+		size += fieldSize
+	}
+	return size
+}
+
+// isBasic returns whether the type is a basic type, which are packed together into chunks in vectors and lists
+func isBasic(t Type) bool {
+	switch t.(type) {
+	case Uint, Bool:
+		return true
+	default:
+		return false
+	}
+}
+
+// Decode decodes the SSZ serialization of the type into the NodeAssembler
+func Decode(t Type, na ipld.NodeAssembler, src []byte) error {
+	return t.decode(na, src)
+}
+
+// AppendEncode appends the SSZ serialization of the node, which needs to have the form of the type, to enc
+func AppendEncode(t Type, enc []byte, node ipld.Node) ([]byte, error) {
+	return t.encode(enc, node)
+}
+
+// HashTreeRoot returns the hash tree root of the SSZ serialization of the type, after checking that it is well formed
+func HashTreeRoot(t Type, src []byte) ([BytesPerChunk]byte, error) {
+	return t.hashTreeRoot(src)
+}
+
+// RootToCid returns the CID with the multicodec type of the block the hash tree root is the root of
+func RootToCid(codec uint64, root [BytesPerChunk]byte) (cid.Cid, error) {
+	return shared.HashToCid(codec, MultiHashType, root[:])
+}
+
+// VerifyCID checks that the CID carries the multicodec type and the hash tree root of the SSZ serialization of the
+// type, like shared.VerifyCID does for blocks addressed by the hash of their bytes
+func VerifyCID(t Type, src []byte, expected cid.Cid, codec uint64) error {
+	prefix := expected.Prefix()
+	if prefix.Codec != codec {
+		return fmt.Errorf("expected CID %s has multicodec type %#x, not %#x", expected.String(), prefix.Codec, codec)
+	}
+	if prefix.MhType != MultiHashType {
+		return fmt.Errorf("expected CID %s has multihash type %#x, not %#x", expected.String(), prefix.MhType, MultiHashType)
+	}
+	root, err := HashTreeRoot(t, src)
+	if err != nil {
+		return err
+	}
+	actual, err := RootToCid(codec, root)
+	if err != nil {
+		return err
+	}
+	if !actual.Equals(expected) {
+		return fmt.Errorf("block hashes to CID %s, not the expected CID %s", actual.String(), expected.String())
+	}
+	return nil
+}
@@ -1,14 +1,13 @@
 package account
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
-	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	"github.com/multiformats/go-multihash"
@@ -17,27 +16,58 @@ import (
 	"github.com/vulcanize/go-codec-dageth/shared"
 )
 
+// EncodeOptions can be used to customize the behavior of account encoding.
+// The zero value is the default behavior used by the registered codec.
+type EncodeOptions struct {
+	// Strict causes the encoder to reject links that do not carry the multicodec and multihash types
+	// the account decoder builds them with, and numeric fields not in their canonical form, see shared.CheckNumerics
+	Strict bool
+	// LinkCodecs overrides the multicodec types Strict expects links to carry,
+	// it should match the DecodeOptions the node was decoded with
+	LinkCodecs shared.LinkCodecs
+	// LinkHashes overrides the multihash types Strict expects links to carry,
+	// it should match the DecodeOptions the node was decoded with
+	LinkHashes shared.LinkHashes
+}
+
 // Encode provides an IPLD codec encode interface for eth state account IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code 0x97 when this package is invoked via init.
 func Encode(node ipld.Node, w io.Writer) error {
-	// 1KiB can be allocated on the stack, and covers most small nodes
-	// without having to grow the buffer and cause allocations.
-	enc := make([]byte, 0, 1024)
-	enc, err := AppendEncode(enc, node)
-	if err != nil {
-		return err
-	}
-	_, err = w.Write(enc)
-	return err
+	return EncodeOptions{}.Encode(node, w)
 }
 
 // AppendEncode is like Encode, but it uses a destination buffer directly.
 // This means less copying of bytes, and if the destination has enough capacity,
 // fewer allocations.
 func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return EncodeOptions{}.AppendEncode(enc, inNode)
+}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// EncodeAccount packs the node into the provided go-ethereum Account
+func EncodeAccount(account *types.StateAccount, inNode ipld.Node) error {
+	return EncodeOptions{}.EncodeAccount(account, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return shared.WriteEncoded(w, node, cfg.AppendEncode)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	account := new(types.StateAccount)
-	if err := EncodeAccount(account, inNode); err != nil {
+	if err := cfg.EncodeAccount(account, inNode); err != nil {
 		return enc, err
 	}
 	wbs := shared.NewWriteableByteSlice(&enc)
@@ -47,30 +77,43 @@ func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	return enc, nil
 }
 
-// EncodeAccount packs the node into the provided go-ethereum Account
-func EncodeAccount(header *types.StateAccount, inNode ipld.Node) error {
+// EncodeAccount is like the package level EncodeAccount, but uses the provided options
+func (cfg EncodeOptions) EncodeAccount(account *types.StateAccount, inNode ipld.Node) error {
 	// Wrap in a typed node for some basic schema form checking
 	builder := dageth.Type.Account.NewBuilder()
 	if err := builder.AssignNode(inNode); err != nil {
 		return err
 	}
 	node := builder.Build()
+	if cfg.Strict {
+		if err := shared.CheckNumerics(node); err != nil {
+			return fmt.Errorf("invalid DAG-ETH Account form (%v)", err)
+		}
+	}
 	for _, pFunc := range requiredPackFuncs {
-		if err := pFunc(header, node); err != nil {
+		if err := pFunc(cfg, account, node); err != nil {
 			return fmt.Errorf("invalid DAG-ETH Account form (%v)", err)
 		}
 	}
 	return nil
 }
 
-var requiredPackFuncs = []func(*types.StateAccount, ipld.Node) error{
-	packNonce,
-	packBalance,
-	packStorageRootCID,
-	packCodeCID,
+// checkLink verifies that a link carries the multicodec and multihash types it is built with, when encoding strictly
+func (cfg EncodeOptions) checkLink(link cidlink.Link, codec uint64) error {
+	if !cfg.Strict {
+		return nil
+	}
+	return shared.CheckLink(link.Cid, cfg.LinkCodecs.Codec(codec), cfg.LinkHashes.Type(MultiHashType))
+}
+
+var requiredPackFuncs = []func(EncodeOptions, *types.StateAccount, ipld.Node) error{
+	EncodeOptions.packNonce,
+	EncodeOptions.packBalance,
+	EncodeOptions.packStorageRootCID,
+	EncodeOptions.packCodeCID,
 }
 
-func packNonce(account *types.StateAccount, node ipld.Node) error {
+func (cfg EncodeOptions) packNonce(account *types.StateAccount, node ipld.Node) error {
 	n, err := node.LookupByString("Nonce")
 	if err != nil {
 		return err
@@ -79,11 +122,14 @@ func packNonce(account *types.StateAccount, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	account.Nonce = binary.BigEndian.Uint64(nBytes)
+	account.Nonce, err = shared.BytesToUint64(nBytes)
+	if err != nil {
+		return fmt.Errorf("Nonce %v", err)
+	}
 	return nil
 }
 
-func packBalance(account *types.StateAccount, node ipld.Node) error {
+func (cfg EncodeOptions) packBalance(account *types.StateAccount, node ipld.Node) error {
 	b, err := node.LookupByString("Balance")
 	if err != nil {
 		return err
@@ -92,11 +138,14 @@ func packBalance(account *types.StateAccount, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	account.Balance = new(big.Int).SetBytes(bBytes)
+	account.Balance, err = shared.BytesToUint256(bBytes)
+	if err != nil {
+		return fmt.Errorf("Balance %v", err)
+	}
 	return nil
 }
 
-func packStorageRootCID(account *types.StateAccount, node ipld.Node) error {
+func (cfg EncodeOptions) packStorageRootCID(account *types.StateAccount, node ipld.Node) error {
 	srCID, err := node.LookupByString("StorageRootCID")
 	if err != nil {
 		return err
@@ -109,6 +158,9 @@ func packStorageRootCID(account *types.StateAccount, node ipld.Node) error {
 	if !ok {
 		return fmt.Errorf("account must have a StateRootCID")
 	}
+	if err := cfg.checkLink(srCIDLink, cid.EthStorageTrie); err != nil {
+		return err
+	}
 	srMh := srCIDLink.Hash()
 	decodedSrMh, err := multihash.Decode(srMh)
 	if err != nil {
@@ -118,7 +170,7 @@ func packStorageRootCID(account *types.StateAccount, node ipld.Node) error {
 	return nil
 }
 
-func packCodeCID(account *types.StateAccount, node ipld.Node) error {
+func (cfg EncodeOptions) packCodeCID(account *types.StateAccount, node ipld.Node) error {
 	cCID, err := node.LookupByString("CodeCID")
 	if err != nil {
 		return err
@@ -131,11 +183,17 @@ func packCodeCID(account *types.StateAccount, node ipld.Node) error {
 	if !ok {
 		return fmt.Errorf("account must have a CodeCID")
 	}
+	if err := cfg.checkLink(cCIDLink, cid.Raw); err != nil {
+		return err
+	}
 	cMh := cCIDLink.Hash()
 	decodedCMh, err := multihash.Decode(cMh)
 	if err != nil {
 		return fmt.Errorf("unable to decode CodeCID multihash: %v", err)
 	}
+	if len(decodedCMh.Digest) != common.HashLength {
+		return fmt.Errorf("account CodeCID digest is %d bytes, not %d", len(decodedCMh.Digest), common.HashLength)
+	}
 	account.CodeHash = decodedCMh.Digest
 	return nil
 }
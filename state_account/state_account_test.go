@@ -3,17 +3,19 @@ package account_test
 import (
 	"bytes"
 	"encoding/binary"
-	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	"github.com/multiformats/go-multihash"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/shared"
 	account "github.com/vulcanize/go-codec-dageth/state_account"
 )
 
@@ -23,7 +25,7 @@ var (
 	emptyCodeHash            = crypto.Keccak256Hash([]byte{}).Bytes()
 	mockAccount              = &types.StateAccount{
 		Root:     mockStateRoot,
-		Balance:  big.NewInt(1000000000),
+		Balance:  uint256.NewInt(1000000000),
 		CodeHash: emptyCodeHash,
 		Nonce:    1,
 	}
@@ -141,3 +143,66 @@ func testAccountEncode(t *testing.T) {
 		t.Errorf("state account encoding (%x) does not match the expected RLP encoding (%x)", encodedAccountBytes, accountRLP)
 	}
 }
+
+func TestAccountLinks(t *testing.T) {
+	accountRLP, err := rlp.EncodeToBytes(mockAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := func(opts account.DecodeOptions) (ipld.Node, cid.Cid, cid.Cid) {
+		nb := dageth.Type.Account.NewBuilder()
+		if err := opts.DecodeBytes(nb, accountRLP); err != nil {
+			t.Fatalf("unable to decode account: %v", err)
+		}
+		node := nb.Build()
+		srNode, _ := node.LookupByString("StorageRootCID")
+		srLink, _ := srNode.AsLink()
+		codeNode, _ := node.LookupByString("CodeCID")
+		codeLink, _ := codeNode.AsLink()
+		return node, srLink.(cidlink.Link).Cid, codeLink.(cidlink.Link).Cid
+	}
+
+	// the storage root links to a storage trie node, and the code hash to the raw bytecode
+	node, srCID, codeCID := links(account.DecodeOptions{})
+	if err := shared.CheckLink(srCID, cid.EthStorageTrie, multihash.KECCAK_256); err != nil {
+		t.Errorf("unexpected StorageRootCID: %v", err)
+	}
+	if err := shared.CheckLink(codeCID, cid.Raw, multihash.KECCAK_256); err != nil {
+		t.Errorf("unexpected CodeCID: %v", err)
+	}
+	if _, err := account.AppendEncodeWithOptions(nil, node, account.EncodeOptions{Strict: true}); err != nil {
+		t.Errorf("unable to strictly encode account: %v", err)
+	}
+
+	// consumers that address code and storage tries with other codecs keep their links
+	linkCodecs := shared.LinkCodecs{cid.EthStorageTrie: cid.Raw, cid.Raw: cid.EthAccountSnapshot}
+	node, srCID, codeCID = links(account.DecodeOptions{LinkCodecs: linkCodecs})
+	if srCID.Prefix().Codec != cid.Raw || codeCID.Prefix().Codec != cid.EthAccountSnapshot {
+		t.Errorf("expected LinkCodecs to override the link codecs, got %s and %s", srCID.String(), codeCID.String())
+	}
+	if _, err := account.AppendEncodeWithOptions(nil, node, account.EncodeOptions{Strict: true}); err == nil {
+		t.Error("expected strictly encoding overridden links without the LinkCodecs to fail")
+	}
+	enc, err := account.AppendEncodeWithOptions(nil, node, account.EncodeOptions{Strict: true, LinkCodecs: linkCodecs})
+	if err != nil {
+		t.Fatalf("unable to strictly encode account with the LinkCodecs: %v", err)
+	}
+	if !bytes.Equal(enc, accountRLP) {
+		t.Errorf("state account encoding (%x) does not match the expected RLP encoding (%x)", enc, accountRLP)
+	}
+}
+
+func TestCodeHashLength(t *testing.T) {
+	// accounts always carry the hash of their code, the empty code included, unlike the slim accounts of snapshots
+	for _, codeHash := range [][]byte{{}, emptyCodeHash[:31]} {
+		acct := *mockAccount
+		acct.CodeHash = codeHash
+		enc, err := rlp.EncodeToBytes(&acct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := account.DecodeBytes(dageth.Type.Account.NewBuilder(), enc); err == nil {
+			t.Errorf("expected an error decoding an account with a %d byte code hash", len(codeHash))
+		}
+	}
+}
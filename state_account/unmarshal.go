@@ -1,23 +1,74 @@
 package account
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
-	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
 )
 
+// DecodeOptions can be used to customize the behavior of account decoding.
+// The zero value is the default behavior used by the registered codec.
+type DecodeOptions struct {
+	// LinkCodecs overrides the multicodec types of the links to the storage trie and the code of the account,
+	// which are eth-storage-trie and raw by default so traversals continue from an account into both
+	LinkCodecs shared.LinkCodecs
+	// LinkHashes overrides the multihash types of the links to the storage trie and the code of the account
+	LinkHashes shared.LinkHashes
+}
+
 // Decode provides an IPLD codec decode interface for eth state account IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code 0x97 when this package is invoked via init.
 func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return DecodeOptions{}.Decode(na, in)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeOptions{}.DecodeBytes(na, src)
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// DecodeAccount unpacks a go-ethereum Account into a NodeAssembler
+func DecodeAccount(na ipld.NodeAssembler, account types.StateAccount) error {
+	return DecodeOptions{}.DecodeAccount(na, account)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
 	var src []byte
 	if buf, ok := in.(interface{ Bytes() []byte }); ok {
 		src = buf.Bytes()
@@ -28,54 +79,51 @@ func Decode(na ipld.NodeAssembler, in io.Reader) error {
 			return err
 		}
 	}
-	return DecodeBytes(na, src)
+	return cfg.DecodeBytes(na, src)
 }
 
-// DecodeBytes is like Decode, but it uses an input buffer directly.
-// Decode will grab or read all the bytes from an io.Reader anyway, so this can
-// save having to copy the bytes or create a bytes.Buffer.
-func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
 	var account types.StateAccount
 	if err := rlp.DecodeBytes(src, &account); err != nil {
 		return err
 	}
-	return DecodeAccount(na, account)
+	return cfg.DecodeAccount(na, account)
 }
 
-// DecodeAccount unpacks a go-ethereum Account into a NodeAssembler
-func DecodeAccount(na ipld.NodeAssembler, header types.StateAccount) error {
+// DecodeAccount is like the package level DecodeAccount, but uses the provided options
+func (cfg DecodeOptions) DecodeAccount(na ipld.NodeAssembler, account types.StateAccount) error {
 	ma, err := na.BeginMap(15)
 	if err != nil {
 		return err
 	}
 	for _, upFunc := range requiredUnpackFuncs {
-		if err := upFunc(ma, header); err != nil {
+		if err := upFunc(cfg, ma, account); err != nil {
 			return fmt.Errorf("invalid DAG-ETH Account binary (%v)", err)
 		}
 	}
 	return ma.Finish()
 }
 
-var requiredUnpackFuncs = []func(ipld.MapAssembler, types.StateAccount) error{
-	unpackNonce,
-	unpackBalance,
-	unpackStorageRootCID,
-	unpackCodeCID,
+var requiredUnpackFuncs = []func(DecodeOptions, ipld.MapAssembler, types.StateAccount) error{
+	DecodeOptions.unpackNonce,
+	DecodeOptions.unpackBalance,
+	DecodeOptions.unpackStorageRootCID,
+	DecodeOptions.unpackCodeCID,
 }
 
-func unpackNonce(ma ipld.MapAssembler, account types.StateAccount) error {
+func (cfg DecodeOptions) unpackNonce(ma ipld.MapAssembler, account types.StateAccount) error {
 	if err := ma.AssembleKey().AssignString("Nonce"); err != nil {
 		return err
 	}
-	nonceBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(nonceBytes, account.Nonce)
+	nonceBytes := shared.Uint64ToBytes(account.Nonce)
 	if err := ma.AssembleValue().AssignBytes(nonceBytes); err != nil {
 		return err
 	}
 	return nil
 }
 
-func unpackBalance(ma ipld.MapAssembler, account types.StateAccount) error {
+func (cfg DecodeOptions) unpackBalance(ma ipld.MapAssembler, account types.StateAccount) error {
 	if account.Balance == nil {
 		return fmt.Errorf("account balance cannot be null")
 	}
@@ -88,12 +136,11 @@ func unpackBalance(ma ipld.MapAssembler, account types.StateAccount) error {
 	return nil
 }
 
-func unpackStorageRootCID(ma ipld.MapAssembler, account types.StateAccount) error {
-	srMh, err := multihash.Encode(account.Root.Bytes(), MultiHashType)
+func (cfg DecodeOptions) unpackStorageRootCID(ma ipld.MapAssembler, account types.StateAccount) error {
+	srCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(cid.EthStorageTrie), cfg.LinkHashes.Type(MultiHashType), account.Root.Bytes())
 	if err != nil {
 		return err
 	}
-	srCID := cid.NewCidV1(cid.EthStorageTrie, srMh)
 	srLinkCID := cidlink.Link{Cid: srCID}
 	if err := ma.AssembleKey().AssignString("StorageRootCID"); err != nil {
 		return err
@@ -101,12 +148,15 @@ func unpackStorageRootCID(ma ipld.MapAssembler, account types.StateAccount) erro
 	return ma.AssembleValue().AssignLink(srLinkCID)
 }
 
-func unpackCodeCID(ma ipld.MapAssembler, account types.StateAccount) error {
-	cMh, err := multihash.Encode(account.CodeHash, MultiHashType)
+func (cfg DecodeOptions) unpackCodeCID(ma ipld.MapAssembler, account types.StateAccount) error {
+	// the slim accounts of snapshots leave the hash of empty code out, but state accounts always carry it
+	if len(account.CodeHash) != common.HashLength {
+		return fmt.Errorf("account CodeHash is %d bytes, not %d", len(account.CodeHash), common.HashLength)
+	}
+	cCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(cid.Raw), cfg.LinkHashes.Type(MultiHashType), account.CodeHash)
 	if err != nil {
 		return err
 	}
-	cCID := cid.NewCidV1(cid.Raw, cMh)
 	cLinkCID := cidlink.Link{Cid: cCID}
 	if err := ma.AssembleKey().AssignString("CodeCID"); err != nil {
 		return err
@@ -3,8 +3,8 @@ package state_trie_test
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
-	"math/big"
 	"strconv"
 	"strings"
 	"testing"
@@ -13,12 +13,15 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	"github.com/multiformats/go-multihash"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
 	"github.com/vulcanize/go-codec-dageth/shared"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
 	"github.com/vulcanize/go-codec-dageth/state_trie"
 	"github.com/vulcanize/go-codec-dageth/trie"
 )
@@ -26,11 +29,11 @@ import (
 var (
 	mockAccount = types.StateAccount{
 		Nonce:    2,
-		Balance:  big.NewInt(1337),
+		Balance:  uint256.NewInt(1337),
 		CodeHash: crypto.Keccak256([]byte{}),
 		Root:     common.HexToHash("0xaaea5efba4fd7b45d7ec03918ac5d8b31aa93b48986af0e6b591f0f087c80127"),
 	}
-	mockLeafVal, _             = rlp.EncodeToBytes(mockAccount)
+	mockLeafVal, _             = rlp.EncodeToBytes(&mockAccount)
 	mockLeafParitalPath        = common.Hex2Bytes("3114658a74d9cc9f7acf2c5cd696c3494d7c344d78bfec3add0d91ec4e8d1c45")
 	mockDecodedLeafPartialPath = shared.CompactToHex(mockLeafParitalPath)
 	mockLeafNode               = []interface{}{
@@ -70,6 +73,29 @@ var (
 	}
 	mockBranchNodeRLP, _ = rlp.EncodeToBytes(mockBranchNode)
 
+	// an account leaf always RLP encodes to more than 32 bytes, so this can't occur in a real state trie,
+	// but it exercises the codec's handling of leaf nodes included directly in their parent
+	mockBranchNodeWithLeafIncludedDirectly = []interface{}{
+		mockChild0,
+		[]byte{},
+		[]byte{},
+		[]byte{},
+		[]byte{},
+		mockChild5,
+		mockLeafNode,
+		[]byte{},
+		[]byte{},
+		[]byte{},
+		[]byte{},
+		[]byte{},
+		[]byte{},
+		[]byte{},
+		mockChildE,
+		[]byte{},
+		[]byte{},
+	}
+	mockBranchNodeWithLeafIncludedDirectlyRLP, _ = rlp.EncodeToBytes(mockBranchNodeWithLeafIncludedDirectly)
+
 	leafNode, extensionNode, branchNode ipld.Node
 )
 
@@ -289,7 +315,11 @@ func verifyExtensionNodeContents(t *testing.T) {
 	if err != nil {
 		t.Fatalf("state trie extension node missing Child: %v", err)
 	}
-	childLink, err := childNode.AsLink()
+	childLinkNode, err := childNode.LookupByString("Link")
+	if err != nil {
+		t.Fatalf("state trie extension node Child should be of type Link: %v", err)
+	}
+	childLink, err := childLinkNode.AsLink()
 	if err != nil {
 		t.Fatalf("state trie extension node Child should be of kind Link: %v", err)
 	}
@@ -406,6 +436,163 @@ func verifyLeafValue(valEnumNode ipld.Node, t *testing.T) {
 	}
 }
 
+func TestStateTrieDecodeOptions(t *testing.T) {
+	leafBuilder := dageth.Type.TrieNode.NewBuilder()
+	if err := state_trie.DecodeBytesWithOptions(leafBuilder, mockLeafNodeRLP, trie.DecodeOptions{RawValues: true}); err != nil {
+		t.Fatalf("unable to decode state trie leaf node with raw values: %v", err)
+	}
+	rawLeafNode := leafBuilder.Build()
+	leaf, err := rawLeafNode.LookupByString(trie.LEAF_NODE.String())
+	if err != nil {
+		t.Fatalf("unable to resolve TrieNode union to a leaf: %v", err)
+	}
+	valEnumNode, err := leaf.LookupByString("Value")
+	if err != nil {
+		t.Fatalf("state trie leaf node missing Value: %v", err)
+	}
+	rawValNode, err := valEnumNode.LookupByString(trie.STORAGE_VALUE.String())
+	if err != nil {
+		t.Fatalf("unable to resolve Value union to raw bytes: %v", err)
+	}
+	rawVal, err := rawValNode.AsBytes()
+	if err != nil {
+		t.Fatalf("raw leaf value should be of type Bytes: %v", err)
+	}
+	if !bytes.Equal(rawVal, mockLeafVal) {
+		t.Errorf("raw leaf value (%x) does not match the account RLP (%x)", rawVal, mockLeafVal)
+	}
+	leafWriter := new(bytes.Buffer)
+	if err := state_trie.Encode(rawLeafNode, leafWriter); err != nil {
+		t.Fatalf("unable to encode state trie leaf node with a raw value: %v", err)
+	}
+	if !bytes.Equal(leafWriter.Bytes(), mockLeafNodeRLP) {
+		t.Errorf("state trie leaf node encoding (%x) does not match the expected RLP encoding (%x)", leafWriter.Bytes(), mockLeafNodeRLP)
+	}
+
+	opts := trie.DecodeOptions{
+		LinkCodecs: shared.LinkCodecs{cid.EthStateTrie: cid.DagCBOR},
+		Account:    account.DecodeOptions{LinkCodecs: shared.LinkCodecs{cid.EthStorageTrie: cid.DagCBOR}},
+	}
+	extensionBuilder := dageth.Type.TrieNode.NewBuilder()
+	if err := state_trie.DecodeBytesWithOptions(extensionBuilder, mockExtensionNodeRLP, opts); err != nil {
+		t.Fatalf("unable to decode state trie extension node with link codecs: %v", err)
+	}
+	extension, err := extensionBuilder.Build().LookupByString(trie.EXTENSION_NODE.String())
+	if err != nil {
+		t.Fatalf("unable to resolve TrieNode union to an extension: %v", err)
+	}
+	childNode, err := extension.LookupByString("Child")
+	if err != nil {
+		t.Fatalf("state trie extension node missing Child: %v", err)
+	}
+	childLinkNode, err := childNode.LookupByString("Link")
+	if err != nil {
+		t.Fatalf("state trie extension node Child is not a link: %v", err)
+	}
+	childLink, err := childLinkNode.AsLink()
+	if err != nil {
+		t.Fatalf("state trie extension node Child is not a link: %v", err)
+	}
+	if codec := childLink.(cidlink.Link).Prefix().Codec; codec != cid.DagCBOR {
+		t.Errorf("extension node child multicodec type (%#x) does not match the override (%#x)", codec, cid.DagCBOR)
+	}
+
+	leafBuilder = dageth.Type.TrieNode.NewBuilder()
+	if err := state_trie.DecodeBytesWithOptions(leafBuilder, mockLeafNodeRLP, opts); err != nil {
+		t.Fatalf("unable to decode state trie leaf node with link codecs: %v", err)
+	}
+	leaf, err = leafBuilder.Build().LookupByString(trie.LEAF_NODE.String())
+	if err != nil {
+		t.Fatalf("unable to resolve TrieNode union to a leaf: %v", err)
+	}
+	accountNode, err := leaf.LookupByString("Value")
+	if err != nil {
+		t.Fatalf("state trie leaf node missing Value: %v", err)
+	}
+	accountNode, err = accountNode.LookupByString(trie.STATE_VALUE.String())
+	if err != nil {
+		t.Fatalf("unable to resolve Value union to a state account: %v", err)
+	}
+	srNode, err := accountNode.LookupByString("StorageRootCID")
+	if err != nil {
+		t.Fatalf("account is missing StorageRootCID: %v", err)
+	}
+	srLink, err := srNode.AsLink()
+	if err != nil {
+		t.Fatalf("account StorageRootCID is not a link: %v", err)
+	}
+	if codec := srLink.(cidlink.Link).Prefix().Codec; codec != cid.DagCBOR {
+		t.Errorf("account StorageRootCID multicodec type (%#x) does not match the override (%#x)", codec, cid.DagCBOR)
+	}
+}
+
+func TestStateTrieLinkCodec(t *testing.T) {
+	var paths [][]byte
+	opts := trie.DecodeOptions{
+		PartialPath: trie.PartialPathCompact,
+		LinkCodec: func(path []byte, kind trie.NodeKind) uint64 {
+			paths = append(paths, path)
+			if kind == trie.BRANCH_NODE && path[0] == 5 {
+				return cid.DagCBOR
+			}
+			return 0
+		},
+	}
+	branchBuilder := dageth.Type.TrieNode.NewBuilder()
+	if err := state_trie.DecodeBytesWithOptions(branchBuilder, mockBranchNodeRLP, opts); err != nil {
+		t.Fatalf("unable to decode state trie branch node with a link codec resolver: %v", err)
+	}
+	branch, err := branchBuilder.Build().LookupByString(trie.BRANCH_NODE.String())
+	if err != nil {
+		t.Fatalf("unable to resolve TrieNode union to a branch: %v", err)
+	}
+	for i, expected := range map[string]uint64{"Child0": cid.EthStateTrie, "Child5": cid.DagCBOR, "ChildE": cid.EthStateTrie} {
+		childNode, err := branch.LookupByString(i)
+		if err != nil {
+			t.Fatalf("state trie branch node missing %s: %v", i, err)
+		}
+		linkNode, err := childNode.LookupByString("Link")
+		if err != nil {
+			t.Fatalf("state trie branch node %s is not a link: %v", i, err)
+		}
+		childLink, _ := linkNode.AsLink()
+		if codec := childLink.(cidlink.Link).Prefix().Codec; codec != expected {
+			t.Errorf("branch node %s multicodec type (%#x) does not match the resolved type (%#x)", i, codec, expected)
+		}
+	}
+	if len(paths) != 3 {
+		t.Errorf("expected the resolver to be called for the 3 linked children, got %d calls", len(paths))
+	}
+
+	paths = nil
+	extensionBuilder := dageth.Type.TrieNode.NewBuilder()
+	if err := state_trie.DecodeBytesWithOptions(extensionBuilder, mockExtensionNodeRLP, opts); err != nil {
+		t.Fatalf("unable to decode state trie extension node with a link codec resolver: %v", err)
+	}
+	if len(paths) != 1 || !bytes.Equal(paths[0], mockDecodedExtensionPartialPath) {
+		t.Errorf("expected the resolver to be given the extension path nibbles %x, got %x", mockDecodedExtensionPartialPath, paths)
+	}
+
+	extensionBuilder = dageth.Type.TrieNode.NewBuilder()
+	hashOpts := trie.DecodeOptions{LinkHashes: shared.LinkHashes{multihash.KECCAK_256: multihash.SHA2_256}}
+	if err := state_trie.DecodeBytesWithOptions(extensionBuilder, mockExtensionNodeRLP, hashOpts); err != nil {
+		t.Fatalf("unable to decode state trie extension node with link hashes: %v", err)
+	}
+	extension, _ := extensionBuilder.Build().LookupByString(trie.EXTENSION_NODE.String())
+	childNode, _ := extension.LookupByString("Child")
+	childLinkNode, _ := childNode.LookupByString("Link")
+	childLink, _ := childLinkNode.AsLink()
+	if prefix := childLink.(cidlink.Link).Prefix(); prefix.MhType != multihash.SHA2_256 || prefix.Codec != cid.EthStateTrie {
+		t.Errorf("extension node child multihash type (%#x) does not match the override (%#x)", prefix.MhType, multihash.SHA2_256)
+	}
+
+	// a multihash type too large for the varints of a CID can't be linked to
+	badHashOpts := trie.DecodeOptions{LinkHashes: shared.LinkHashes{multihash.KECCAK_256: 1 << 63}}
+	if err := state_trie.DecodeBytesWithOptions(dageth.Type.TrieNode.NewBuilder(), mockExtensionNodeRLP, badHashOpts); err == nil {
+		t.Error("expected decoding with a multihash type override that does not fit in a CID to fail")
+	}
+}
+
 func testStateTrieEncode(t *testing.T) {
 	branchWriter := new(bytes.Buffer)
 	if err := state_trie.Encode(branchNode, branchWriter); err != nil {
@@ -416,6 +603,12 @@ func testStateTrieEncode(t *testing.T) {
 		t.Errorf("state trie branch node encoding (%x) does not match the expected RLP encoding (%x)", encodedBranchBytes, mockBranchNodeRLP)
 	}
 
+	// a state leaf carries an account, which never fits in the 32 bytes a branch can include directly
+	err := state_trie.DecodeBytes(dageth.Type.TrieNode.NewBuilder(), mockBranchNodeWithLeafIncludedDirectlyRLP)
+	if !errors.Is(err, trie.ErrInvalidEmbeddedNode) {
+		t.Errorf("expected decoding a state trie branch node with an embedded leaf of %d bytes to fail with %v, got %v", len(mockLeafNodeRLP), trie.ErrInvalidEmbeddedNode, err)
+	}
+
 	extensionWriter := new(bytes.Buffer)
 	if err := state_trie.Encode(extensionNode, extensionWriter); err != nil {
 		t.Fatalf("unable to encode state trie extension node into writer: %v", err)
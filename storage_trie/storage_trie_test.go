@@ -2,7 +2,9 @@ package storage_trie_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"testing"
@@ -12,11 +14,15 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal"
 	"github.com/multiformats/go-multihash"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/keys"
 	"github.com/vulcanize/go-codec-dageth/storage_trie"
 	"github.com/vulcanize/go-codec-dageth/trie"
 )
@@ -234,19 +240,19 @@ func verifyBranchNodeContents(t *testing.T) {
 
 	child5Node, err := branch.LookupByString("Child5")
 	if err != nil {
-		t.Fatalf("storage trie branch node missing Child5: %v", err)
+		t.Fatalf("storage trie branch node missing Child6: %v", err)
 	}
 	child5LinkNode, err := child5Node.LookupByString("Link")
 	if err != nil {
-		t.Fatalf("storage trie branch node Child5 should be of type Link: %v", err)
+		t.Fatalf("storage trie branch node Child6 should be of type Link: %v", err)
 	}
 	child5Link, err := child5LinkNode.AsLink()
 	if err != nil {
-		t.Fatalf("storage trie branch node Child5 should be of type Link: %v", err)
+		t.Fatalf("storage trie branch node Child6 should be of type Link: %v", err)
 	}
 	child5CIDLink, ok := child5Link.(cidlink.Link)
 	if !ok {
-		t.Fatalf("storage trie branch node Child5 should be a CID: %v", err)
+		t.Fatalf("storage trie branch node Child6 should be a CID: %v", err)
 	}
 	child5Mh := child5CIDLink.Hash()
 	decodedChild5Mh, err := multihash.Decode(child5Mh)
@@ -344,19 +350,19 @@ func verifyBranchNodeWithLeafIncludedDirectlyContents(t *testing.T) {
 
 	child5Node, err := branch.LookupByString("Child5")
 	if err != nil {
-		t.Fatalf("storage trie branch node missing Child5: %v", err)
+		t.Fatalf("storage trie branch node missing Child6: %v", err)
 	}
 	child5LinkNode, err := child5Node.LookupByString("Link")
 	if err != nil {
-		t.Fatalf("storage trie branch node Child5 should be of type Link: %v", err)
+		t.Fatalf("storage trie branch node Child6 should be of type Link: %v", err)
 	}
 	child5Link, err := child5LinkNode.AsLink()
 	if err != nil {
-		t.Fatalf("storage trie branch node Child5 should be of type Link: %v", err)
+		t.Fatalf("storage trie branch node Child6 should be of type Link: %v", err)
 	}
 	child5CIDLink, ok := child5Link.(cidlink.Link)
 	if !ok {
-		t.Fatalf("storage trie branch node Child5 should be a CID: %v", err)
+		t.Fatalf("storage trie branch node Child6 should be a CID: %v", err)
 	}
 	child5Mh := child5CIDLink.Hash()
 	decodedChild5Mh, err := multihash.Decode(child5Mh)
@@ -410,7 +416,7 @@ func verifyBranchNodeWithLeafIncludedDirectlyContents(t *testing.T) {
 
 	child6Node, err := branch.LookupByString("Child6")
 	if err != nil {
-		t.Fatalf("storage trie branch node missing Child5: %v", err)
+		t.Fatalf("storage trie branch node missing Child6: %v", err)
 	}
 	trieNodeNode, err := child6Node.LookupByString("TrieNode")
 	if err != nil {
@@ -472,7 +478,11 @@ func verifyExtensionNodeContents(t *testing.T) {
 	if err != nil {
 		t.Fatalf("storage trie extension node missing Child: %v", err)
 	}
-	childLink, err := childNode.AsLink()
+	childLinkNode, err := childNode.LookupByString("Link")
+	if err != nil {
+		t.Fatalf("storage trie extension node Child should be of type Link: %v", err)
+	}
+	childLink, err := childLinkNode.AsLink()
 	if err != nil {
 		t.Fatalf("storage trie extension node Child should be of kind Link: %v", err)
 	}
@@ -535,6 +545,15 @@ func testStorageTrieEncode(t *testing.T) {
 		t.Errorf("storage trie branch node encoding (%x) does not match the expected RLP encoding (%x)", encodedBranchBytes, mockBranchNodeRLP)
 	}
 
+	branch2Writer := new(bytes.Buffer)
+	if err := storage_trie.Encode(branchNodeWithLeafIncludedDirectly, branch2Writer); err != nil {
+		t.Fatalf("unable to encode storage trie branch node with embedded leaf into writer: %v", err)
+	}
+	encodedBranch2Bytes := branch2Writer.Bytes()
+	if !bytes.Equal(encodedBranch2Bytes, mockBranchNodeWithLeafIncludedDirectlyRLP) {
+		t.Errorf("storage trie branch node with embedded leaf encoding (%x) does not match the expected RLP encoding (%x)", encodedBranch2Bytes, mockBranchNodeWithLeafIncludedDirectlyRLP)
+	}
+
 	extensionWriter := new(bytes.Buffer)
 	if err := storage_trie.Encode(extensionNode, extensionWriter); err != nil {
 		t.Fatalf("unable to encode storage trie extension node into writer: %v", err)
@@ -553,3 +572,541 @@ func testStorageTrieEncode(t *testing.T) {
 		t.Errorf("storage trie leaf node encoding (%x) does not match the expected RLP encoding (%x)", encodedLeafBytes, mockLeafNodeRLP)
 	}
 }
+
+func TestStorageTrieStrictDecode(t *testing.T) {
+	strict := trie.DecodeOptions{Strict: true}
+	for name, nodeRLP := range map[string][]byte{
+		"branch":                    mockBranchNodeRLP,
+		"branch with leaf included": mockBranchNodeWithLeafIncludedDirectlyRLP,
+		"extension":                 mockExtensionNodeRLP,
+		"leaf":                      mockLeafNodeRLP,
+	} {
+		if err := strict.DecodeTrieNodeBytes(dageth.Type.TrieNode.NewBuilder(), nodeRLP, storage_trie.MultiCodecType); err != nil {
+			t.Errorf("unable to strictly decode well-formed storage trie %s node: %v", name, err)
+		}
+	}
+
+	// these are accepted by the default decoder when assembling into an untyped node, only strict mode rejects them
+	threeMemberNodeRLP, _ := rlp.EncodeToBytes([]interface{}{mockLeafParitalPath, mockLeafVal, []byte{}})
+	badPaddingNodeRLP, _ := rlp.EncodeToBytes([]interface{}{common.Hex2Bytes("2114658a74d9cc"), mockLeafVal})
+	for name, nodeRLP := range map[string][]byte{
+		"three member node":       threeMemberNodeRLP,
+		"non-zero padding nibble": badPaddingNodeRLP,
+	} {
+		if err := trie.DecodeTrieNodeBytes(basicnode.Prototype.Any.NewBuilder(), nodeRLP, storage_trie.MultiCodecType); err != nil {
+			t.Errorf("unable to decode malformed storage trie node (%s) without strict mode: %v", name, err)
+		}
+		if err := strict.DecodeTrieNodeBytes(basicnode.Prototype.Any.NewBuilder(), nodeRLP, storage_trie.MultiCodecType); err == nil {
+			t.Errorf("expected strict decoding of malformed storage trie node (%s) to fail", name)
+		}
+	}
+
+	// these are rejected with an error in either mode
+	badFlagNodeRLP, _ := rlp.EncodeToBytes([]interface{}{common.Hex2Bytes("4114658a74d9cc"), mockLeafVal})
+	listPathNodeRLP, _ := rlp.EncodeToBytes([]interface{}{[]interface{}{mockLeafParitalPath}, mockLeafVal})
+	shortExtensionNodeRLP, _ := rlp.EncodeToBytes([]interface{}{mockExtensionPartialPath, []byte{1, 2, 3}})
+	for name, nodeRLP := range map[string][]byte{
+		"unknown hex prefix":        badFlagNodeRLP,
+		"list as partial path":      listPathNodeRLP,
+		"extension with short link": shortExtensionNodeRLP,
+	} {
+		for _, decoder := range []trie.DecodeOptions{{}, strict} {
+			if err := decoder.DecodeTrieNodeBytes(dageth.Type.TrieNode.NewBuilder(), nodeRLP, storage_trie.MultiCodecType); err == nil {
+				t.Errorf("expected decoding of malformed storage trie node (%s) to fail", name)
+			}
+		}
+	}
+}
+
+func TestStorageTrieDecodeErrors(t *testing.T) {
+	// memberOffset returns the byte offset of the RLP list member at the index
+	memberOffset := func(nodeRLP []byte, index int) int {
+		content, _, _ := rlp.SplitList(nodeRLP)
+		offset := len(nodeRLP) - len(content)
+		for i := 0; i < index; i++ {
+			_, _, rest, _ := rlp.Split(content)
+			offset += len(content) - len(rest)
+			content = rest
+		}
+		return offset
+	}
+	branchFields := make([]interface{}, 17)
+	for i := range branchFields {
+		branchFields[i] = []byte{}
+	}
+	branchFields[5] = []byte{1, 2, 3}
+	badChildBranchNodeRLP, _ := rlp.EncodeToBytes(branchFields)
+	threeMemberNodeRLP, _ := rlp.EncodeToBytes([]interface{}{mockLeafParitalPath, mockLeafVal, []byte{}})
+	badPaddingNodeRLP, _ := rlp.EncodeToBytes([]interface{}{common.Hex2Bytes("2114658a74d9cc"), mockLeafVal})
+	badFlagNodeRLP, _ := rlp.EncodeToBytes([]interface{}{common.Hex2Bytes("4114658a74d9cc"), mockLeafVal})
+	shortExtensionNodeRLP, _ := rlp.EncodeToBytes([]interface{}{mockExtensionPartialPath, []byte{1, 2, 3}})
+	badValueNodeRLP, _ := rlp.EncodeToBytes([]interface{}{mockLeafParitalPath, []byte{0xc1, 0x01}})
+	branchFields[5] = []interface{}{[]byte{0x20}, []byte{0x01}, []byte{}}
+	threeMemberEmbeddedNodeRLP, _ := rlp.EncodeToBytes(branchFields)
+	branchFields[5] = []interface{}{[]byte{0x12}, []interface{}{common.Hex2Bytes("4114"), []byte{0x01}}}
+	badFlagEmbeddedNodeRLP, _ := rlp.EncodeToBytes(branchFields)
+	branchFields[5] = []interface{}{[]byte{0x20}, crypto.Keccak256(nil)}
+	oversizedEmbeddedNodeRLP, _ := rlp.EncodeToBytes(branchFields)
+
+	for _, test := range []struct {
+		name    string
+		opts    trie.DecodeOptions
+		nodeRLP []byte
+		kind    error
+		field   int
+	}{
+		{"invalid RLP", trie.DecodeOptions{}, []byte{0xc5, 0x01}, trie.ErrMalformedNode, -1},
+		{"three member node", trie.DecodeOptions{Strict: true}, threeMemberNodeRLP, trie.ErrMalformedNode, -1},
+		{"non-zero padding nibble", trie.DecodeOptions{Strict: true}, badPaddingNodeRLP, trie.ErrUnknownHexPrefix, 0},
+		{"unknown hex prefix", trie.DecodeOptions{}, badFlagNodeRLP, trie.ErrUnknownHexPrefix, 0},
+		{"extension with short link", trie.DecodeOptions{}, shortExtensionNodeRLP, trie.ErrUnexpectedChildLength, 1},
+		{"branch with short link", trie.DecodeOptions{}, badChildBranchNodeRLP, trie.ErrUnexpectedChildLength, 5},
+		{"leaf value that is not an RLP string", trie.DecodeOptions{UnwrapStorageValues: true}, badValueNodeRLP, trie.ErrInvalidValue, 1},
+		{"embedded three member node", trie.DecodeOptions{}, threeMemberEmbeddedNodeRLP, trie.ErrInvalidEmbeddedNode, 5},
+		{"embedded node of 32 bytes or more", trie.DecodeOptions{}, oversizedEmbeddedNodeRLP, trie.ErrInvalidEmbeddedNode, 5},
+		{"unknown hex prefix within nested embedded nodes", trie.DecodeOptions{}, badFlagEmbeddedNodeRLP, trie.ErrUnknownHexPrefix, 5},
+	} {
+		err := test.opts.DecodeTrieNodeBytes(basicnode.Prototype.Any.NewBuilder(), test.nodeRLP, storage_trie.MultiCodecType)
+		if !errors.Is(err, test.kind) {
+			t.Errorf("expected decoding of storage trie node (%s) to fail with %v, got %v", test.name, test.kind, err)
+			continue
+		}
+		var decodeErr *trie.DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Errorf("expected decoding of storage trie node (%s) to fail with a *trie.DecodeError, got %T", test.name, err)
+			continue
+		}
+		if decodeErr.Codec != storage_trie.MultiCodecType {
+			t.Errorf("storage trie node (%s) error codec (%#x) does not match the storage trie codec", test.name, decodeErr.Codec)
+		}
+		if decodeErr.Field != test.field {
+			t.Errorf("storage trie node (%s) error field (%d) does not match expected field (%d)", test.name, decodeErr.Field, test.field)
+		}
+		expectedOffset := -1
+		if test.field >= 0 {
+			expectedOffset = memberOffset(test.nodeRLP, test.field)
+		}
+		if decodeErr.Offset != expectedOffset {
+			t.Errorf("storage trie node (%s) error offset (%d) does not match expected offset (%d)", test.name, decodeErr.Offset, expectedOffset)
+		}
+	}
+}
+
+// plainReader hides the Bytes method of the wrapped reader so the streaming decode path is used
+type plainReader struct{ io.Reader }
+
+func TestStorageTrieStreamingDecode(t *testing.T) {
+	for name, nodeRLP := range map[string][]byte{
+		"branch":    mockBranchNodeRLP,
+		"extension": mockExtensionNodeRLP,
+		"leaf":      mockLeafNodeRLP,
+	} {
+		nb := dageth.Type.TrieNode.NewBuilder()
+		if err := storage_trie.Decode(nb, plainReader{bytes.NewReader(nodeRLP)}); err != nil {
+			t.Fatalf("unable to stream decode storage trie %s node: %v", name, err)
+		}
+		encoded := new(bytes.Buffer)
+		if err := storage_trie.Encode(nb.Build(), encoded); err != nil {
+			t.Fatalf("unable to encode storage trie %s node: %v", name, err)
+		}
+		if !bytes.Equal(encoded.Bytes(), nodeRLP) {
+			t.Errorf("stream decoded storage trie %s node RLP (%x) does not match expected RLP (%x)", name, encoded.Bytes(), nodeRLP)
+		}
+	}
+	trailing := append(append([]byte{}, mockLeafNodeRLP...), 0x80)
+	err := storage_trie.Decode(dageth.Type.TrieNode.NewBuilder(), plainReader{bytes.NewReader(trailing)})
+	if !errors.Is(err, trie.ErrMalformedNode) {
+		t.Errorf("expected stream decoding of storage trie node with trailing data to fail with %v, got %v", trie.ErrMalformedNode, err)
+	}
+}
+
+func TestStorageTrieStreamingMaxNodeSize(t *testing.T) {
+	// a list header claiming about a terabyte must be rejected before that length is allocated
+	oversized := common.Hex2Bytes("fc1000000000bc0ffffffff0010203")
+	err := storage_trie.Decode(dageth.Type.TrieNode.NewBuilder(), plainReader{bytes.NewReader(oversized)})
+	if !errors.Is(err, trie.ErrMalformedNode) {
+		t.Errorf("expected stream decoding of oversized storage trie node to fail with %v, got %v", trie.ErrMalformedNode, err)
+	}
+	opts := trie.DecodeOptions{MaxNodeSize: len(mockBranchNodeRLP) - 1}
+	err = storage_trie.DecodeWithOptions(dageth.Type.TrieNode.NewBuilder(), plainReader{bytes.NewReader(mockBranchNodeRLP)}, opts)
+	if !errors.Is(err, trie.ErrMalformedNode) {
+		t.Errorf("expected stream decoding of storage trie node above MaxNodeSize to fail with %v, got %v", trie.ErrMalformedNode, err)
+	}
+	opts.MaxNodeSize = len(mockBranchNodeRLP)
+	if err := storage_trie.DecodeWithOptions(dageth.Type.TrieNode.NewBuilder(), plainReader{bytes.NewReader(mockBranchNodeRLP)}, opts); err != nil {
+		t.Errorf("unable to stream decode storage trie node at MaxNodeSize: %v", err)
+	}
+}
+
+func TestStorageTrieBorrowBytes(t *testing.T) {
+	// leafBytes decodes a copy of the leaf node RLP, then overwrites that input and returns the bytes
+	// of the decoded leaf node, which only reflect the overwrite when they alias the input
+	leafBytes := func(opts trie.DecodeOptions) ([]byte, []byte) {
+		src := append([]byte{}, mockLeafNodeRLP...)
+		nb := dageth.Type.TrieNode.NewBuilder()
+		if err := storage_trie.DecodeWithOptions(nb, bytes.NewBuffer(src), opts); err != nil {
+			t.Fatalf("unable to decode storage trie leaf node: %v", err)
+		}
+		for i := range src {
+			src[i] = 0xff
+		}
+		leaf, err := nb.Build().LookupByString(trie.LEAF_NODE.String())
+		if err != nil {
+			t.Fatalf("storage trie leaf node missing enum key: %v", err)
+		}
+		pathNode, err := leaf.LookupByString("PartialPath")
+		if err != nil {
+			t.Fatalf("storage trie leaf node missing PartialPath: %v", err)
+		}
+		path, _ := pathNode.AsBytes()
+		valNode, err := traversal.Get(leaf, ipld.ParsePath("Value/"+trie.STORAGE_VALUE.String()))
+		if err != nil {
+			t.Fatalf("storage trie leaf node missing Value: %v", err)
+		}
+		val, _ := valNode.AsBytes()
+		return path, val
+	}
+
+	path, val := leafBytes(trie.DecodeOptions{PartialPath: trie.PartialPathCompact})
+	if !bytes.Equal(path, mockLeafParitalPath) || !bytes.Equal(val, mockLeafVal) {
+		t.Errorf("storage trie leaf node decoded without borrowing should not alias its input")
+	}
+	path, val = leafBytes(trie.DecodeOptions{PartialPath: trie.PartialPathCompact, BorrowBytes: true})
+	if !bytes.Equal(path, bytes.Repeat([]byte{0xff}, len(mockLeafParitalPath))) {
+		t.Errorf("storage trie leaf node partial path (%x) decoded with borrowing should alias its input", path)
+	}
+	if !bytes.Equal(val, bytes.Repeat([]byte{0xff}, len(mockLeafVal))) {
+		t.Errorf("storage trie leaf node value (%x) decoded with borrowing should alias its input", val)
+	}
+	_, val = leafBytes(trie.DecodeOptions{BorrowBytes: true, UnwrapStorageValues: true})
+	if !bytes.Equal(val, []byte{0xff, 0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("storage trie leaf slot value (%x) decoded with borrowing should alias its input", val)
+	}
+}
+
+func TestStorageTriePartialPathEncodings(t *testing.T) {
+	expectedLeafPaths := map[trie.PartialPathEncoding][]byte{
+		trie.PartialPathHex:     mockDecodedLeafPartialPath,
+		trie.PartialPathNibbles: mockDecodedLeafPartialPath[:len(mockDecodedLeafPartialPath)-1],
+		trie.PartialPathCompact: mockLeafParitalPath,
+	}
+	expectedExtensionPaths := map[trie.PartialPathEncoding][]byte{
+		trie.PartialPathHex:     mockDecodedExtensionPartialPath,
+		trie.PartialPathNibbles: mockDecodedExtensionPartialPath,
+		trie.PartialPathCompact: mockExtensionPartialPath,
+	}
+	for pathEncoding, expectedLeafPath := range expectedLeafPaths {
+		decoder := trie.DecodeOptions{PartialPath: pathEncoding}
+		encoder := trie.EncodeOptions{PartialPath: pathEncoding}
+		for _, nodeRLP := range [][]byte{mockLeafNodeRLP, mockExtensionNodeRLP, mockBranchNodeWithLeafIncludedDirectlyRLP} {
+			nb := dageth.Type.TrieNode.NewBuilder()
+			if err := storage_trie.DecodeBytesWithOptions(nb, nodeRLP, decoder); err != nil {
+				t.Fatalf("unable to decode storage trie node with %s partial paths: %v", pathEncoding, err)
+			}
+			node := nb.Build()
+			buf := new(bytes.Buffer)
+			if err := storage_trie.EncodeWithOptions(node, buf, encoder); err != nil {
+				t.Fatalf("unable to encode storage trie node with %s partial paths: %v", pathEncoding, err)
+			}
+			if !bytes.Equal(buf.Bytes(), nodeRLP) {
+				t.Errorf("storage trie node encoding with %s partial paths (%x) does not match the expected RLP encoding (%x)", pathEncoding, buf.Bytes(), nodeRLP)
+			}
+		}
+
+		leaf := decodeStorageTrieNode(t, decoder, mockLeafNodeRLP, trie.LEAF_NODE)
+		if path := partialPath(t, leaf); !bytes.Equal(path, expectedLeafPath) {
+			t.Errorf("storage trie leaf node %s partial path (%x) does not match expected partial path (%x)", pathEncoding, path, expectedLeafPath)
+		}
+		ext := decodeStorageTrieNode(t, decoder, mockExtensionNodeRLP, trie.EXTENSION_NODE)
+		if path := partialPath(t, ext); !bytes.Equal(path, expectedExtensionPaths[pathEncoding]) {
+			t.Errorf("storage trie extension node %s partial path (%x) does not match expected partial path (%x)", pathEncoding, path, expectedExtensionPaths[pathEncoding])
+		}
+	}
+}
+
+func TestStorageTriePartialPathMismatch(t *testing.T) {
+	pathEncodings := []trie.PartialPathEncoding{trie.PartialPathHex, trie.PartialPathNibbles, trie.PartialPathCompact}
+	for _, decodeWith := range pathEncodings {
+		nb := dageth.Type.TrieNode.NewBuilder()
+		if err := storage_trie.DecodeBytesWithOptions(nb, mockLeafNodeRLP, trie.DecodeOptions{PartialPath: decodeWith}); err != nil {
+			t.Fatalf("unable to decode storage trie leaf node with %s partial paths: %v", decodeWith, err)
+		}
+		node := nb.Build()
+		for _, encodeWith := range pathEncodings {
+			if encodeWith == decodeWith {
+				continue
+			}
+			if _, err := storage_trie.AppendEncodeWithOptions(nil, node, trie.EncodeOptions{PartialPath: encodeWith}); err == nil {
+				t.Errorf("expected encoding a storage trie leaf node decoded with %s partial paths as %s partial paths to fail", decodeWith, encodeWith)
+			}
+		}
+	}
+
+	// a leaf path in the compact representation is not a valid hex path, it must not silently re-encode
+	nb := dageth.Type.TrieNode.NewBuilder()
+	if err := storage_trie.DecodeBytesWithOptions(nb, common.Hex2Bytes("c482200105"), trie.DecodeOptions{PartialPath: trie.PartialPathCompact}); err != nil {
+		t.Fatalf("unable to decode storage trie leaf node with compact partial paths: %v", err)
+	}
+	if enc, err := storage_trie.AppendEncode(nil, nb.Build()); err == nil {
+		t.Errorf("expected encoding a compact partial path with the default options to fail; got %x", enc)
+	}
+}
+
+func TestStorageTrieUnwrapStorageValues(t *testing.T) {
+	decoder := trie.DecodeOptions{UnwrapStorageValues: true}
+	encoder := trie.EncodeOptions{UnwrapStorageValues: true}
+	for nodeRLP, expectedVal := range map[string][]byte{
+		string(mockLeafNodeRLP): {1, 2, 3, 4, 5},
+		// a leaf small enough to be included directly in its parent branch
+		string(mockBranchNodeWithLeafIncludedDirectlyRLP): {1},
+	} {
+		nb := dageth.Type.TrieNode.NewBuilder()
+		if err := storage_trie.DecodeBytesWithOptions(nb, []byte(nodeRLP), decoder); err != nil {
+			t.Fatalf("unable to decode storage trie node with unwrapped values: %v", err)
+		}
+		node := nb.Build()
+		leaf := node
+		if branch, err := node.LookupByString(trie.BRANCH_NODE.String()); err == nil {
+			child, err := branch.LookupByString("Child6")
+			if err != nil {
+				t.Fatalf("storage trie branch node missing Child6: %v", err)
+			}
+			if leaf, err = child.LookupByString("TrieNode"); err != nil {
+				t.Fatalf("storage trie branch node Child6 should be an included TrieNode: %v", err)
+			}
+		}
+		valNode, err := traversal.Get(leaf, ipld.ParsePath("TrieLeafNode/Value/Bytes"))
+		if err != nil {
+			t.Fatalf("unable to select storage trie leaf value: %v", err)
+		}
+		val, err := valNode.AsBytes()
+		if err != nil {
+			t.Fatalf("storage trie leaf value should be of type Bytes: %v", err)
+		}
+		if !bytes.Equal(val, expectedVal) {
+			t.Errorf("unwrapped storage trie leaf value (%x) does not match expected value (%x)", val, expectedVal)
+		}
+
+		buf := new(bytes.Buffer)
+		if err := storage_trie.EncodeWithOptions(node, buf, encoder); err != nil {
+			t.Fatalf("unable to encode storage trie node with unwrapped values: %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), []byte(nodeRLP)) {
+			t.Errorf("storage trie node encoding with unwrapped values (%x) does not match the expected RLP encoding (%x)", buf.Bytes(), nodeRLP)
+		}
+	}
+
+	// a leaf value that is not an RLP string cannot be unwrapped
+	listValNodeRLP, _ := rlp.EncodeToBytes([]interface{}{mockLeafParitalPath, []byte{0xc1, 0x01}})
+	if err := storage_trie.DecodeBytesWithOptions(dageth.Type.TrieNode.NewBuilder(), listValNodeRLP, decoder); err == nil {
+		t.Error("expected unwrapping a storage trie leaf value that is not an RLP string to fail")
+	}
+}
+
+func decodeStorageTrieNode(t *testing.T, decoder trie.DecodeOptions, nodeRLP []byte, kind trie.NodeKind) ipld.Node {
+	nb := dageth.Type.TrieNode.NewBuilder()
+	if err := decoder.DecodeTrieNodeBytes(nb, nodeRLP, storage_trie.MultiCodecType); err != nil {
+		t.Fatalf("unable to decode storage trie node: %v", err)
+	}
+	node, err := nb.Build().LookupByString(kind.String())
+	if err != nil {
+		t.Fatalf("unable to resolve TrieNode union to a %s: %v", kind, err)
+	}
+	return node
+}
+
+func partialPath(t *testing.T, node ipld.Node) []byte {
+	pathNode, err := node.LookupByString("PartialPath")
+	if err != nil {
+		t.Fatalf("storage trie node missing PartialPath: %v", err)
+	}
+	path, err := pathNode.AsBytes()
+	if err != nil {
+		t.Fatalf("storage trie node PartialPath should be of type Bytes: %v", err)
+	}
+	return path
+}
+
+func BenchmarkStorageTrieEncode(b *testing.B) {
+	nb := dageth.Type.TrieNode.NewBuilder()
+	if err := storage_trie.DecodeBytes(nb, mockBranchNodeRLP); err != nil {
+		b.Fatalf("unable to decode storage trie branch node: %v", err)
+	}
+	node := nb.Build()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := storage_trie.Encode(node, io.Discard); err != nil {
+			b.Fatalf("unable to encode storage trie branch node: %v", err)
+		}
+	}
+}
+
+func BenchmarkStorageTrieDecode(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := storage_trie.DecodeBytes(dageth.Type.TrieNode.NewBuilder(), mockBranchNodeRLP); err != nil {
+			b.Fatalf("unable to decode storage trie branch node: %v", err)
+		}
+	}
+}
+
+func TestStorageTrieAdversarialPartialPaths(t *testing.T) {
+	decoders := map[string]trie.DecodeOptions{
+		"default":   {},
+		"strict":    {Strict: true},
+		"nibbles":   {PartialPath: trie.PartialPathNibbles},
+		"compact":   {PartialPath: trie.PartialPathCompact, Strict: true},
+		"borrowing": {BorrowBytes: true},
+	}
+	for _, test := range []struct {
+		name    string
+		path    []byte
+		err     error
+		lenient bool
+	}{
+		{"empty path", []byte{}, keys.ErrEmptyCompact, false},
+		{"flag 4", []byte{0x40}, keys.ErrInvalidFlag, false},
+		{"flag 15", []byte{0xff, 0xff}, keys.ErrInvalidFlag, false},
+		{"ASCII letter flag", []byte("A123"), keys.ErrInvalidFlag, false},
+		{"even extension padding", []byte{0x0f, 0x12}, keys.ErrNonZeroPadding, true},
+		{"even leaf padding", []byte{0x21}, keys.ErrNonZeroPadding, true},
+	} {
+		vals := [][]byte{mockLeafVal, crypto.Keccak256(nil)}
+		if len(test.path) != 0 && test.path[0]>>4 == 0 {
+			// the child of an extension node is always a 32 byte hash
+			vals = vals[1:]
+		}
+		for _, val := range vals {
+			nodeRLP, _ := rlp.EncodeToBytes([]interface{}{test.path, val})
+			for name, decoder := range decoders {
+				err := decoder.DecodeTrieNodeBytes(basicnode.Prototype.Any.NewBuilder(), nodeRLP, storage_trie.MultiCodecType)
+				if test.lenient && !decoder.Strict {
+					if err != nil {
+						t.Errorf("expected the %s decoder to accept a partial path with a %s: %v", name, test.name, err)
+					}
+					continue
+				}
+				if !errors.Is(err, trie.ErrUnknownHexPrefix) || !errors.Is(err, test.err) {
+					t.Errorf("expected the %s decoder to reject a partial path with a %s with %v, got %v", name, test.name, test.err, err)
+					continue
+				}
+				var decodeErr *trie.DecodeError
+				if errors.As(err, &decodeErr) && decodeErr.Field != 0 {
+					t.Errorf("expected the %s of the %s decoder to be located at the partial path, got field %d", test.name, name, decodeErr.Field)
+				}
+			}
+		}
+	}
+
+	// every single byte partial path either decodes, and then into the node kind of its flag, or fails cleanly
+	for b := 0; b < 256; b++ {
+		nodeRLP, _ := rlp.EncodeToBytes([]interface{}{[]byte{byte(b)}, mockLeafVal})
+		nb := dageth.Type.TrieNode.NewBuilder()
+		err := trie.DecodeOptions{Strict: true}.DecodeTrieNodeBytes(nb, nodeRLP, storage_trie.MultiCodecType)
+		if b>>4 > 3 || b>>4&1 == 0 && b&0x0f != 0 {
+			if err == nil {
+				t.Errorf("expected strict decoding of partial path %#x to fail", b)
+			}
+			continue
+		}
+		if b>>4 < 2 {
+			// the value is not a valid child link, so extension nodes fail on it instead
+			if !errors.Is(err, trie.ErrUnexpectedChildLength) {
+				t.Errorf("expected partial path %#x to decode as an extension node, got %v", b, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unable to decode leaf node with partial path %#x: %v", b, err)
+			continue
+		}
+		if _, err := nb.Build().LookupByString(trie.LEAF_NODE.String()); err != nil {
+			t.Errorf("expected partial path %#x to decode into a leaf node: %v", b, err)
+		}
+	}
+}
+
+// embeddedNodeKeys are storage trie keys sharing all but their last nibbles, so that the nodes over them encode to
+// fewer than 32 bytes and are included directly in their parent: the first two keys differ only in their last
+// nibble and the third splits from them at nibble 61
+var embeddedNodeKeys = [][]byte{
+	common.Hex2Bytes("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa121"),
+	common.Hex2Bytes("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa122"),
+	common.Hex2Bytes("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa345"),
+}
+
+// embeddedNodeTrie returns the encodings of the nodes of the storage trie holding the slot value 1 under the keys
+// that are stored on their own, the root last
+func embeddedNodeTrie(t *testing.T, keys [][]byte) [][]byte {
+	var nodes [][]byte
+	st := gethtrie.NewStackTrie(func(_ []byte, _ common.Hash, blob []byte) {
+		nodes = append(nodes, common.CopyBytes(blob))
+	})
+	for _, key := range keys {
+		if err := st.Update(key, []byte{0x01}); err != nil {
+			t.Fatalf("unable to update storage trie: %v", err)
+		}
+	}
+	st.Hash()
+	return nodes
+}
+
+func TestStorageTrieEmbeddedNodes(t *testing.T) {
+	// the leaves of the two keys are embedded in a branch, itself embedded in the root extension
+	twoKeyNodes := embeddedNodeTrie(t, embeddedNodeKeys[:2])
+	// the branch at nibble 61 holds the leaf of the third key, and an extension embedding the branch over the
+	// leaves of the first two keys
+	threeKeyNodes := embeddedNodeTrie(t, embeddedNodeKeys)
+	for _, test := range []struct {
+		name    string
+		nodeRLP []byte
+		values  []string
+	}{
+		{
+			"extension with an embedded branch",
+			twoKeyNodes[len(twoKeyNodes)-1],
+			[]string{
+				"TrieExtensionNode/Child/TrieNode/TrieBranchNode/Child1/TrieNode/TrieLeafNode/Value/Bytes",
+				"TrieExtensionNode/Child/TrieNode/TrieBranchNode/Child2/TrieNode/TrieLeafNode/Value/Bytes",
+			},
+		},
+		{
+			"branch with an embedded extension and leaf",
+			threeKeyNodes[len(threeKeyNodes)-2],
+			[]string{
+				"TrieBranchNode/Child1/TrieNode/TrieExtensionNode/Child/TrieNode/TrieBranchNode/Child1/TrieNode/TrieLeafNode/Value/Bytes",
+				"TrieBranchNode/Child1/TrieNode/TrieExtensionNode/Child/TrieNode/TrieBranchNode/Child2/TrieNode/TrieLeafNode/Value/Bytes",
+				"TrieBranchNode/Child3/TrieNode/TrieLeafNode/Value/Bytes",
+			},
+		},
+	} {
+		for _, decoder := range []trie.DecodeOptions{{Strict: true}, {BorrowBytes: true}} {
+			nb := dageth.Type.TrieNode.NewBuilder()
+			if err := decoder.DecodeTrieNodeBytes(nb, test.nodeRLP, storage_trie.MultiCodecType); err != nil {
+				t.Fatalf("unable to decode storage trie node (%s): %v", test.name, err)
+			}
+			node := nb.Build()
+			for _, path := range test.values {
+				valNode, err := traversal.Get(node, ipld.ParsePath(path))
+				if err != nil {
+					t.Fatalf("storage trie node (%s) has no value at %s: %v", test.name, path, err)
+				}
+				val, err := valNode.AsBytes()
+				if err != nil {
+					t.Fatalf("storage trie node (%s) value at %s should be of type Bytes: %v", test.name, path, err)
+				}
+				if !bytes.Equal(val, []byte{0x01}) {
+					t.Errorf("storage trie node (%s) value at %s (%x) does not match expected value (01)", test.name, path, val)
+				}
+			}
+			enc := new(bytes.Buffer)
+			if err := storage_trie.Encode(node, enc); err != nil {
+				t.Fatalf("unable to encode storage trie node (%s): %v", test.name, err)
+			}
+			if !bytes.Equal(enc.Bytes(), test.nodeRLP) {
+				t.Errorf("storage trie node (%s) encoding (%x) does not match the original encoding (%x)", test.name, enc.Bytes(), test.nodeRLP)
+			}
+		}
+	}
+}
@@ -0,0 +1,495 @@
+/*
+Package testutil generates random but valid chain data, and the DAG-ETH blocks encoding it, for property-based
+testing of the codecs. RoundTrip checks that a block decodes and encodes back into the same bytes, so downstream
+projects can run it over Samples, or over blocks of their own, for every codec. FixtureSamples splits the blocks
+of the fixtures package into samples of every execution layer codec.
+*/
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+
+	"github.com/vulcanize/go-codec-dageth/consensus"
+	"github.com/vulcanize/go-codec-dageth/ssz"
+	"github.com/vulcanize/go-codec-dageth/tx_trace"
+)
+
+// TxTypes are the transaction (and receipt) types the generator produces
+var TxTypes = []uint8{
+	types.LegacyTxType,
+	types.AccessListTxType,
+	types.DynamicFeeTxType,
+	types.BlobTxType,
+	types.SetCodeTxType,
+}
+
+// Generator generates random but valid chain data. It is deterministic for a given seed,
+// so that a failure found with a seed can be reproduced with it.
+type Generator struct {
+	rand    *rand.Rand
+	key     *ecdsa.PrivateKey
+	signer  types.Signer
+	chainID *big.Int
+}
+
+// NewGenerator returns a Generator seeded with the seed, which signs the transactions
+// it generates with a key derived from the seed
+func NewGenerator(seed int64) *Generator {
+	g := &Generator{
+		rand:    rand.New(rand.NewSource(seed)),
+		chainID: big.NewInt(1),
+	}
+	g.signer = types.LatestSignerForChainID(g.chainID)
+	for g.key == nil {
+		g.key, _ = crypto.ToECDSA(g.Bytes(32))
+	}
+	return g
+}
+
+// Bytes returns n random bytes
+func (g *Generator) Bytes(n int) []byte {
+	b := make([]byte, n)
+	g.rand.Read(b)
+	return b
+}
+
+// Hash returns a random hash
+func (g *Generator) Hash() common.Hash {
+	return common.BytesToHash(g.Bytes(common.HashLength))
+}
+
+// Address returns a random address
+func (g *Generator) Address() common.Address {
+	return common.BytesToAddress(g.Bytes(common.AddressLength))
+}
+
+// Uint64 returns a random uint64 below max
+func (g *Generator) Uint64(max uint64) uint64 {
+	return uint64(g.rand.Int63n(int64(max)))
+}
+
+// Big returns a random big int of up to bits bits
+func (g *Generator) Big(bits int) *big.Int {
+	return new(big.Int).Rand(g.rand, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+}
+
+// Header returns a header of a random fork, from Frontier through Prague, carrying the fields of that fork
+func (g *Generator) Header() *types.Header {
+	header := &types.Header{
+		ParentHash:  g.Hash(),
+		UncleHash:   g.Hash(),
+		Coinbase:    g.Address(),
+		Root:        g.Hash(),
+		TxHash:      g.Hash(),
+		ReceiptHash: g.Hash(),
+		Bloom:       types.BytesToBloom(g.Bytes(types.BloomByteLength)),
+		Difficulty:  g.Big(64),
+		Number:      g.Big(32),
+		GasLimit:    g.Uint64(60_000_000),
+		GasUsed:     g.Uint64(30_000_000),
+		Time:        g.Uint64(1 << 40),
+		Extra:       g.Bytes(int(g.Uint64(33))),
+		MixDigest:   g.Hash(),
+		Nonce:       types.EncodeNonce(g.rand.Uint64()),
+	}
+	fork := g.rand.Intn(5)
+	if fork >= 1 {
+		header.BaseFee = g.Big(40)
+	}
+	if fork >= 2 {
+		header.Difficulty = new(big.Int)
+		header.Nonce = types.BlockNonce{}
+		withdrawalsHash := g.Hash()
+		header.WithdrawalsHash = &withdrawalsHash
+	}
+	if fork >= 3 {
+		blobGasUsed := g.Uint64(7) * params.BlobTxBlobGasPerBlob
+		excessBlobGas := g.Uint64(1 << 24)
+		parentBeaconRoot := g.Hash()
+		header.BlobGasUsed = &blobGasUsed
+		header.ExcessBlobGas = &excessBlobGas
+		header.ParentBeaconRoot = &parentBeaconRoot
+	}
+	if fork >= 4 {
+		requestsHash := g.Hash()
+		header.RequestsHash = &requestsHash
+	}
+	return header
+}
+
+// Headers returns n headers
+func (g *Generator) Headers(n int) []*types.Header {
+	headers := make([]*types.Header, n)
+	for i := range headers {
+		headers[i] = g.Header()
+	}
+	return headers
+}
+
+// accessList returns an access list of up to 3 entries
+func (g *Generator) accessList() types.AccessList {
+	accessList := make(types.AccessList, g.rand.Intn(4))
+	for i := range accessList {
+		accessList[i].Address = g.Address()
+		accessList[i].StorageKeys = make([]common.Hash, g.rand.Intn(4))
+		for j := range accessList[i].StorageKeys {
+			accessList[i].StorageKeys[j] = g.Hash()
+		}
+	}
+	return accessList
+}
+
+// Transaction returns a signed transaction of the type, legacy and access list transactions are
+// contract creations every so often
+func (g *Generator) Transaction(txType uint8) *types.Transaction {
+	var to *common.Address
+	if txType >= types.DynamicFeeTxType || g.rand.Intn(4) != 0 {
+		addr := g.Address()
+		to = &addr
+	}
+	data := g.Bytes(int(g.Uint64(256)))
+	var txData types.TxData
+	switch txType {
+	case types.LegacyTxType:
+		txData = &types.LegacyTx{Nonce: g.Uint64(1 << 20), GasPrice: g.Big(40), Gas: g.Uint64(1 << 24), To: to, Value: g.Big(80), Data: data}
+	case types.AccessListTxType:
+		txData = &types.AccessListTx{ChainID: g.chainID, Nonce: g.Uint64(1 << 20), GasPrice: g.Big(40), Gas: g.Uint64(1 << 24), To: to, Value: g.Big(80), Data: data, AccessList: g.accessList()}
+	case types.DynamicFeeTxType:
+		txData = &types.DynamicFeeTx{ChainID: g.chainID, Nonce: g.Uint64(1 << 20), GasTipCap: g.Big(32), GasFeeCap: g.Big(40), Gas: g.Uint64(1 << 24), To: to, Value: g.Big(80), Data: data, AccessList: g.accessList()}
+	case types.BlobTxType:
+		blobHashes := make([]common.Hash, 1+g.rand.Intn(6))
+		for i := range blobHashes {
+			blobHashes[i] = g.Hash()
+			blobHashes[i][0] = 0x01 // kzg4844 versioned hash version
+		}
+		txData = &types.BlobTx{ChainID: uint256.MustFromBig(g.chainID), Nonce: g.Uint64(1 << 20), GasTipCap: uint256.MustFromBig(g.Big(32)), GasFeeCap: uint256.MustFromBig(g.Big(40)), Gas: g.Uint64(1 << 24), To: *to, Value: uint256.MustFromBig(g.Big(80)), Data: data, AccessList: g.accessList(), BlobFeeCap: uint256.MustFromBig(g.Big(32)), BlobHashes: blobHashes}
+	case types.SetCodeTxType:
+		authList := make([]types.SetCodeAuthorization, 1+g.rand.Intn(3))
+		for i := range authList {
+			auth, err := types.SignSetCode(g.key, types.SetCodeAuthorization{ChainID: *uint256.MustFromBig(g.chainID), Address: g.Address(), Nonce: g.Uint64(1 << 20)})
+			if err != nil {
+				panic(err)
+			}
+			authList[i] = auth
+		}
+		txData = &types.SetCodeTx{ChainID: uint256.MustFromBig(g.chainID), Nonce: g.Uint64(1 << 20), GasTipCap: uint256.MustFromBig(g.Big(32)), GasFeeCap: uint256.MustFromBig(g.Big(40)), Gas: g.Uint64(1 << 24), To: *to, Value: uint256.MustFromBig(g.Big(80)), Data: data, AccessList: g.accessList(), AuthList: authList}
+	default:
+		panic("unsupported transaction type")
+	}
+	return types.MustSignNewTx(g.key, g.signer, txData)
+}
+
+// Transactions returns n transactions of random types
+func (g *Generator) Transactions(n int) types.Transactions {
+	txs := make(types.Transactions, n)
+	for i := range txs {
+		txs[i] = g.Transaction(TxTypes[g.rand.Intn(len(TxTypes))])
+	}
+	return txs
+}
+
+// Log returns a log with up to 4 topics
+func (g *Generator) Log() *types.Log {
+	topics := make([]common.Hash, g.rand.Intn(5))
+	for i := range topics {
+		topics[i] = g.Hash()
+	}
+	return &types.Log{
+		Address: g.Address(),
+		Topics:  topics,
+		Data:    g.Bytes(int(g.Uint64(128))),
+	}
+}
+
+// Logs returns n logs
+func (g *Generator) Logs(n int) []*types.Log {
+	logs := make([]*types.Log, n)
+	for i := range logs {
+		logs[i] = g.Log()
+	}
+	return logs
+}
+
+// Receipt returns a receipt of the type with up to 8 logs, legacy receipts carry a pre-Byzantium
+// post state root every so often
+func (g *Generator) Receipt(txType uint8) *types.Receipt {
+	receipt := &types.Receipt{
+		Type:              txType,
+		CumulativeGasUsed: g.Uint64(1 << 25),
+		Logs:              g.Logs(g.rand.Intn(9)),
+	}
+	if txType == types.LegacyTxType && g.rand.Intn(4) == 0 {
+		receipt.PostState = g.Hash().Bytes()
+	} else {
+		receipt.Status = uint64(g.rand.Intn(2))
+	}
+	receipt.Bloom = types.CreateBloom(receipt)
+	return receipt
+}
+
+// Receipts returns receipts matching the types of the transactions
+func (g *Generator) Receipts(txs types.Transactions) types.Receipts {
+	receipts := make(types.Receipts, len(txs))
+	for i, tx := range txs {
+		receipts[i] = g.Receipt(tx.Type())
+	}
+	return receipts
+}
+
+// Block returns a block of n transactions of random types along with their receipts, under a header of a random
+// fork before Prague whose transaction and receipt roots, logs bloom and withdrawals root commit to them
+func (g *Generator) Block(n int) (*types.Block, types.Receipts) {
+	txs := g.Transactions(n)
+	receipts := g.Receipts(txs)
+	header := g.Header()
+	header.RequestsHash = nil
+	header.Bloom = types.Bloom{}
+	body := &types.Body{Transactions: txs}
+	if header.WithdrawalsHash != nil {
+		body.Withdrawals = g.Withdrawals(g.rand.Intn(4))
+	}
+	return types.NewBlock(header, body, receipts, gethtrie.NewStackTrie(nil)), receipts
+}
+
+// Account returns a state account, which is an EOA without storage every so often
+func (g *Generator) Account() *types.StateAccount {
+	account := &types.StateAccount{
+		Nonce:    g.Uint64(1 << 20),
+		Balance:  uint256.MustFromBig(g.Big(96)),
+		Root:     types.EmptyRootHash,
+		CodeHash: types.EmptyCodeHash.Bytes(),
+	}
+	if g.rand.Intn(2) == 0 {
+		account.Root = g.Hash()
+		account.CodeHash = g.Hash().Bytes()
+	}
+	return account
+}
+
+// Withdrawal returns a withdrawal
+func (g *Generator) Withdrawal() *types.Withdrawal {
+	return &types.Withdrawal{
+		Index:     g.Uint64(1 << 32),
+		Validator: g.Uint64(1 << 24),
+		Address:   g.Address(),
+		Amount:    g.Uint64(1 << 40),
+	}
+}
+
+// Withdrawals returns n withdrawals
+func (g *Generator) Withdrawals(n int) []*types.Withdrawal {
+	withdrawals := make([]*types.Withdrawal, n)
+	for i := range withdrawals {
+		withdrawals[i] = g.Withdrawal()
+	}
+	return withdrawals
+}
+
+// Requests returns the EIP-7685 requests of a block, one of each type in type order, each the type byte followed
+// by the serialization of up to 4 requests of that type
+func (g *Generator) Requests() [][]byte {
+	lists := []ssz.Type{consensus.DepositRequests, consensus.WithdrawalRequests, consensus.ConsolidationRequests}
+	requests := make([][]byte, len(lists))
+	for i, list := range lists {
+		requests[i] = append([]byte{byte(i)}, g.SSZ(list)...)
+	}
+	return requests
+}
+
+// TxTrace returns a transaction trace of the transactions with up to 16 frames
+func (g *Generator) TxTrace(txs types.Transactions) *tx_trace.TxTrace {
+	trace := &tx_trace.TxTrace{
+		TxHashes:  make([]common.Hash, len(txs)),
+		StateRoot: g.Hash(),
+		Result:    g.Bytes(int(g.Uint64(64))),
+		Frames:    make([]tx_trace.Frame, g.rand.Intn(17)),
+		Gas:       g.Uint64(1 << 24),
+		Failed:    g.rand.Intn(2) == 0,
+	}
+	for i, tx := range txs {
+		trace.TxHashes[i] = tx.Hash()
+	}
+	ops := []vm.OpCode{vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL, vm.CREATE, vm.CREATE2}
+	for i := range trace.Frames {
+		trace.Frames[i] = tx_trace.Frame{
+			Op:     ops[g.rand.Intn(len(ops))],
+			From:   g.Address(),
+			To:     g.Address(),
+			Input:  g.Bytes(int(g.Uint64(128))),
+			Output: g.Bytes(int(g.Uint64(64))),
+			Gas:    g.Uint64(1 << 24),
+			Cost:   g.Uint64(1 << 16),
+			Value:  g.Big(64),
+		}
+	}
+	return trace
+}
+
+// BlobSidecar returns a sidecar of up to 2 blobs, only the start of each blob is random
+func (g *Generator) BlobSidecar() *types.BlobTxSidecar {
+	n := 1 + g.rand.Intn(2)
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       make([]kzg4844.Blob, n),
+		Commitments: make([]kzg4844.Commitment, n),
+		Proofs:      make([]kzg4844.Proof, n),
+	}
+	for i := 0; i < n; i++ {
+		g.rand.Read(sidecar.Blobs[i][:256])
+		g.rand.Read(sidecar.Commitments[i][:])
+		g.rand.Read(sidecar.Proofs[i][:])
+	}
+	return sidecar
+}
+
+// SSZ returns the SSZ serialization of a random value of the type. Lists hold up to 4 elements and byte lists and
+// bitlists up to 64 bytes and bits, so that the values of types with large limits stay small.
+func (g *Generator) SSZ(t ssz.Type) []byte {
+	switch t := t.(type) {
+	case ssz.Uint:
+		return g.Bytes(t.Bytes)
+	case ssz.Bool:
+		return []byte{byte(g.rand.Intn(2))}
+	case ssz.ByteVector:
+		return g.Bytes(t.Length)
+	case ssz.ByteList:
+		return g.Bytes(g.rand.Intn(int(min(t.Limit, 64)) + 1))
+	case ssz.Bitvector:
+		b := g.Bytes(t.Size())
+		if t.Length%8 != 0 {
+			b[len(b)-1] &= 1<<(t.Length%8) - 1
+		}
+		return b
+	case ssz.Bitlist:
+		n := g.rand.Intn(int(min(t.Limit, 64)) + 1)
+		b := g.Bytes(n/8 + 1)
+		// the length delimiting bit follows the n bits of the list
+		b[len(b)-1] &= 1<<(n%8) - 1
+		b[len(b)-1] |= 1 << (n % 8)
+		return b
+	case ssz.Root:
+		return g.Bytes(ssz.BytesPerChunk)
+	case ssz.Vector:
+		elems := make([]ssz.Type, t.Length)
+		for i := range elems {
+			elems[i] = t.Elem
+		}
+		return g.sszSequence(elems)
+	case ssz.List:
+		elems := make([]ssz.Type, g.rand.Intn(int(min(t.Limit, 4))+1))
+		for i := range elems {
+			elems[i] = t.Elem
+		}
+		return g.sszSequence(elems)
+	case ssz.Container:
+		elems := make([]ssz.Type, len(t.Fields))
+		for i, f := range t.Fields {
+			elems[i] = f.Type
+		}
+		return g.sszSequence(elems)
+	default:
+		panic(fmt.Sprintf("unknown SSZ type %T", t))
+	}
+}
+
+// sszSequence serializes random values of the types the way SSZ serializes the fields of a container and the
+// elements of vectors and lists, the variable-size values following the fixed-size part that holds their offsets
+func (g *Generator) sszSequence(elems []ssz.Type) []byte {
+	fixedSize := 0
+	values := make([][]byte, len(elems))
+	for i, t := range elems {
+		values[i] = g.SSZ(t)
+		if size := t.Size(); size > 0 {
+			fixedSize += size
+		} else {
+			fixedSize += ssz.BytesPerOffset
+		}
+	}
+	enc := make([]byte, 0, fixedSize)
+	offset := fixedSize
+	for i, t := range elems {
+		if t.Size() > 0 {
+			enc = append(enc, values[i]...)
+			continue
+		}
+		enc = binary.LittleEndian.AppendUint32(enc, uint32(offset))
+		offset += len(values[i])
+	}
+	for i, t := range elems {
+		if t.Size() == 0 {
+			enc = append(enc, values[i]...)
+		}
+	}
+	return enc
+}
+
+// TrieNodes builds a trie holding the values under random 32 byte keys, as state and storage tries hold
+// values under hashed keys, and returns the encodings of its nodes. Every other key differs from another key
+// only in the high nibble of its last byte, so that the trie also holds leaves embedded in their parent branch
+// when the values encode to fewer than 30 bytes. Values should encode to at least 6 bytes, as the parent branch
+// of two such leaves is embedded in turn otherwise, which no trie with hashed keys holds in practice.
+func (g *Generator) TrieNodes(values [][]byte) [][]byte {
+	keys := make([][]byte, len(values))
+	for i := range keys {
+		if i%2 == 1 {
+			keys[i] = append(append([]byte{}, keys[i-1][:31]...), keys[i-1][31]^byte(1+g.rand.Intn(15))<<4)
+			continue
+		}
+		keys[i] = g.Bytes(32)
+	}
+	return trieNodes(keys, values)
+}
+
+// ListTrieNodes builds a trie holding the values under their RLP encoded index, as transaction, receipt and
+// withdrawal tries do, and returns the encodings of its nodes
+func (g *Generator) ListTrieNodes(values [][]byte) [][]byte {
+	return listTrieNodes(values)
+}
+
+func listTrieNodes(values [][]byte) [][]byte {
+	keys := make([][]byte, len(values))
+	for i := range keys {
+		keys[i], _ = rlp.EncodeToBytes(uint(i))
+	}
+	return trieNodes(keys, values)
+}
+
+// trieNodes builds the trie of the key/value pairs and returns the encodings of the nodes that are referenced
+// by hash, the root last; nodes embedded in their parent are part of the parent's encoding
+func trieNodes(keys, values [][]byte) [][]byte {
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return string(keys[order[i]]) < string(keys[order[j]])
+	})
+	var nodes [][]byte
+	st := gethtrie.NewStackTrie(func(path []byte, hash common.Hash, blob []byte) {
+		nodes = append(nodes, common.CopyBytes(blob))
+	})
+	for i, idx := range order {
+		if i > 0 && string(keys[idx]) == string(keys[order[i-1]]) {
+			continue
+		}
+		if err := st.Update(keys[idx], values[idx]); err != nil {
+			panic(err)
+		}
+	}
+	st.Hash()
+	return nodes
+}
@@ -0,0 +1,116 @@
+package testutil
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/vulcanize/go-codec-dageth/fixtures"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/log_trie"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/rct_list"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/tx_list"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+// FixtureSamples splits a fixture block into samples of the codecs of execution layer data: its header, uncles,
+// transactions and receipts, their lists, logs and withdrawals, the nodes of the tries holding them, and the nodes
+// and accounts of its state proofs. Unlike generated samples these are encodings real clients produced, so they
+// check that the codecs encode nodes back into the exact bytes, and so the CIDs, the nodes were decoded from.
+func FixtureSamples(block *fixtures.Block) ([]Sample, error) {
+	var samples []Sample
+	add := func(name string, codec uint64, data []byte) {
+		samples = append(samples, Sample{Name: fmt.Sprintf("block %d %s", block.Number, name), Codec: codec, Data: data})
+	}
+	addNodes := func(name string, codec uint64, nodes [][]byte) {
+		for i, node := range nodes {
+			add(fmt.Sprintf("%s/%d", name, i), codec, node)
+		}
+	}
+
+	add("header", header.MultiCodecType, block.Header)
+	unclesEnc, err := block.Uncles()
+	if err != nil {
+		return nil, err
+	}
+	add("uncles", uncles.MultiCodecType, unclesEnc)
+
+	txEncs, err := block.Transactions()
+	if err != nil {
+		return nil, err
+	}
+	for i, txEnc := range txEncs {
+		add(fmt.Sprintf("tx/%d", i), tx.MultiCodecType, txEnc)
+	}
+	gethBlock, err := block.GethBlock()
+	if err != nil {
+		return nil, err
+	}
+	add("tx_list", tx_list.MultiCodecType, mustRLP(gethBlock.Transactions()))
+	addNodes("tx_trie", tx_trie.MultiCodecType, listTrieNodes(txEncs))
+
+	receipts := make(types.Receipts, len(block.Receipts))
+	var logEncs [][]byte
+	for i, rctEnc := range block.Receipts {
+		add(fmt.Sprintf("rct/%d", i), rct.MultiCodecType, rctEnc)
+		receipts[i] = new(types.Receipt)
+		if err := receipts[i].UnmarshalBinary(rctEnc); err != nil {
+			return nil, fmt.Errorf("unable to decode receipt %d of block %d: %v", i, block.Number, err)
+		}
+		for _, l := range receipts[i].Logs {
+			logEncs = append(logEncs, mustRLP(l))
+		}
+	}
+	add("rct_list", rct_list.MultiCodecType, mustRLP(receipts))
+	addNodes("rct_trie", rct_trie.MultiCodecType, listTrieNodes(block.Receipts))
+	for i, logEnc := range logEncs {
+		add(fmt.Sprintf("log/%d", i), log.MultiCodecType, logEnc)
+	}
+	addNodes("log_trie", log_trie.MultiCodecType, listTrieNodes(logEncs))
+
+	withdrawalEncs, err := block.Withdrawals()
+	if err != nil {
+		return nil, err
+	}
+	for i, withdrawalEnc := range withdrawalEncs {
+		add(fmt.Sprintf("withdrawal/%d", i), withdrawal.MultiCodecType, withdrawalEnc)
+	}
+	addNodes("withdrawal_trie", withdrawal_trie.MultiCodecType, listTrieNodes(withdrawalEncs))
+
+	for _, proof := range block.Proofs {
+		name := proof.Address.Hex()
+		addNodes("state_trie "+name, state_trie.MultiCodecType, proof.AccountProof)
+		if len(proof.AccountProof) > 0 {
+			if acct := leafValue(proof.AccountProof[len(proof.AccountProof)-1]); acct != nil {
+				add("state_account "+name, account.MultiCodecType, acct)
+			}
+		}
+		for i, storageProof := range proof.StorageProofs {
+			addNodes(fmt.Sprintf("storage_trie %s/%s", name, proof.StorageKeys[i].Hex()), storage_trie.MultiCodecType, storageProof)
+		}
+	}
+	return samples, nil
+}
+
+// leafValue returns the value a leaf node of a proof holds, nil if the node is not a leaf
+func leafValue(node []byte) []byte {
+	var elems [][]byte
+	if err := rlp.DecodeBytes(node, &elems); err != nil || len(elems) != 2 {
+		return nil
+	}
+	if len(elems[0]) == 0 || elems[0][0]>>4 < 2 {
+		return nil
+	}
+	return elems[1]
+}
@@ -0,0 +1,26 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// KeccakCid returns the CID of the multicodec type carrying the keccak256 hash, failing the test if it can't be built
+func KeccakCid(t testing.TB, codec uint64, hash []byte) cid.Cid {
+	t.Helper()
+	c, err := shared.Keccak256ToCid(codec, hash)
+	if err != nil {
+		t.Fatalf("unable to build the CID of hash %x: %v", hash, err)
+	}
+	return c
+}
+
+// KeccakLink is like KeccakCid, but returns a link
+func KeccakLink(t testing.TB, codec uint64, hash []byte) cidlink.Link {
+	t.Helper()
+	return cidlink.Link{Cid: KeccakCid(t, codec, hash)}
+}
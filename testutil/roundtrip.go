@@ -0,0 +1,176 @@
+package testutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/multicodec"
+
+	"github.com/vulcanize/go-codec-dageth/beacon_block"
+	"github.com/vulcanize/go-codec-dageth/beacon_block_body"
+	"github.com/vulcanize/go-codec-dageth/beacon_state"
+	"github.com/vulcanize/go-codec-dageth/blob_sidecar"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/consensus"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/log_trie"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/rct_list"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/request"
+	"github.com/vulcanize/go-codec-dageth/request_list"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/tx_list"
+	"github.com/vulcanize/go-codec-dageth/tx_trace"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+// Sample is the consensus encoding of a block of a DAG-ETH codec
+type Sample struct {
+	Name  string
+	Codec uint64
+	Data  []byte
+}
+
+// Samples generates samples of every DAG-ETH codec: a header, uncles, transactions and receipts of every type,
+// their lists, logs, accounts, withdrawals, a trace and a blob sidecar, the nodes of every kind of trie holding them,
+// execution requests and their list, and a beacon block, block body and state
+func (g *Generator) Samples() []Sample {
+	var samples []Sample
+	add := func(name string, codec uint64, data []byte) {
+		samples = append(samples, Sample{Name: name, Codec: codec, Data: data})
+	}
+	addNodes := func(name string, codec uint64, nodes [][]byte) {
+		for i, node := range nodes {
+			add(fmt.Sprintf("%s/%d", name, i), codec, node)
+		}
+	}
+
+	add("header", header.MultiCodecType, mustRLP(g.Header()))
+	add("uncles", uncles.MultiCodecType, mustRLP(g.Headers(g.rand.Intn(3))))
+
+	txs := g.Transactions(8 + g.rand.Intn(24))
+	for _, txType := range TxTypes {
+		txs = append(txs, g.Transaction(txType))
+	}
+	txEncs := make([][]byte, len(txs))
+	for i, transaction := range txs {
+		txEncs[i] = mustMarshal(transaction)
+		add(fmt.Sprintf("tx/%d", i), tx.MultiCodecType, txEncs[i])
+	}
+	add("tx_list", tx_list.MultiCodecType, mustRLP(txs))
+	addNodes("tx_trie", tx_trie.MultiCodecType, g.ListTrieNodes(txEncs))
+
+	receipts := g.Receipts(txs)
+	rctEncs := make([][]byte, len(receipts))
+	var logEncs [][]byte
+	for i, receipt := range receipts {
+		rctEncs[i] = mustMarshal(receipt)
+		add(fmt.Sprintf("rct/%d", i), rct.MultiCodecType, rctEncs[i])
+		for _, l := range receipt.Logs {
+			logEncs = append(logEncs, mustRLP(l))
+		}
+	}
+	add("rct_list", rct_list.MultiCodecType, mustRLP(receipts))
+	addNodes("rct_trie", rct_trie.MultiCodecType, g.ListTrieNodes(rctEncs))
+	for i, logEnc := range logEncs {
+		add(fmt.Sprintf("log/%d", i), log.MultiCodecType, logEnc)
+	}
+	addNodes("log_trie", log_trie.MultiCodecType, g.ListTrieNodes(logEncs))
+
+	accountEncs := make([][]byte, 8+g.rand.Intn(24))
+	for i := range accountEncs {
+		accountEncs[i] = mustRLP(g.Account())
+		add(fmt.Sprintf("state_account/%d", i), account.MultiCodecType, accountEncs[i])
+	}
+	addNodes("state_trie", state_trie.MultiCodecType, g.TrieNodes(accountEncs))
+	// slot values short enough for leaves to be embedded in their parent branch
+	slotEncs := make([][]byte, 8+g.rand.Intn(24))
+	for i := range slotEncs {
+		slotEncs[i] = mustRLP(g.Bytes(5 + g.rand.Intn(24)))
+	}
+	addNodes("storage_trie", storage_trie.MultiCodecType, g.TrieNodes(slotEncs))
+
+	withdrawalEncs := make([][]byte, 1+g.rand.Intn(16))
+	for i, w := range g.Withdrawals(len(withdrawalEncs)) {
+		withdrawalEncs[i] = mustRLP(w)
+		add(fmt.Sprintf("withdrawal/%d", i), withdrawal.MultiCodecType, withdrawalEncs[i])
+	}
+	addNodes("withdrawal_trie", withdrawal_trie.MultiCodecType, g.ListTrieNodes(withdrawalEncs))
+
+	var requestHashes []byte
+	for i, req := range g.Requests() {
+		add(fmt.Sprintf("request/%d", i), request.MultiCodecType, req)
+		if len(req) > 1 {
+			requestHash := sha256.Sum256(req)
+			requestHashes = append(requestHashes, requestHash[:]...)
+		}
+	}
+	add("request_list", request_list.MultiCodecType, requestHashes)
+
+	add("tx_trace", tx_trace.MultiCodecType, mustRLP(g.TxTrace(txs[:1+g.rand.Intn(3)])))
+	add("blob_sidecar", blob_sidecar.MultiCodecType, mustRLP(g.BlobSidecar()))
+
+	add("beacon_block", beacon_block.MultiCodecType, g.SSZ(consensus.BeaconBlock))
+	add("beacon_block_body", beacon_block_body.MultiCodecType, g.SSZ(consensus.BeaconBlockBody))
+	add("beacon_state", beacon_state.MultiCodecType, g.SSZ(consensus.BeaconState))
+	return samples
+}
+
+// RoundTrip decodes the data with the DAG-ETH codec of the multicodec type into the node prototype the codecs
+// package loads that type with, and checks that the node encodes back into the same bytes
+func RoundTrip(codec uint64, data []byte) error {
+	c, err := shared.Keccak256ToCid(codec, crypto.Keccak256(data))
+	if err != nil {
+		return err
+	}
+	np, err := codecs.NodePrototypeChooser(cidlink.Link{Cid: c}, ipld.LinkContext{})
+	if err != nil {
+		return err
+	}
+	nb := np.NewBuilder()
+	if err := codecs.DecodeByCodec(nb, bytes.NewReader(data), codec); err != nil {
+		return fmt.Errorf("unable to decode %x: %v", data, err)
+	}
+	encoder, err := multicodec.LookupEncoder(codec)
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := encoder(nb.Build(), buf); err != nil {
+		return fmt.Errorf("unable to encode decoded %x: %v", data, err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		return fmt.Errorf("decoded %x encodes into %x", data, buf.Bytes())
+	}
+	return nil
+}
+
+func mustRLP(val interface{}) []byte {
+	enc, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+func mustMarshal(val interface{ MarshalBinary() ([]byte, error) }) []byte {
+	enc, err := val.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
@@ -0,0 +1,98 @@
+package testutil_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/vulcanize/go-codec-dageth/fixtures"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+)
+
+func TestRoundTrip(t *testing.T) {
+	codecSamples := make(map[uint64]int)
+	for seed := int64(0); seed < 16; seed++ {
+		for _, sample := range testutil.NewGenerator(seed).Samples() {
+			codecSamples[sample.Codec]++
+			if err := testutil.RoundTrip(sample.Codec, sample.Data); err != nil {
+				t.Errorf("%s generated with seed %d does not round trip: %v", sample.Name, seed, err)
+			}
+		}
+	}
+	if len(codecSamples) != 22 {
+		t.Errorf("expected samples of 22 codecs, got samples of %d", len(codecSamples))
+	}
+}
+
+func TestGeneratorDeterminism(t *testing.T) {
+	first, second := testutil.NewGenerator(42).Samples(), testutil.NewGenerator(42).Samples()
+	if len(first) != len(second) {
+		t.Fatalf("generators with the same seed generated %d and %d samples", len(first), len(second))
+	}
+	for i := range first {
+		if first[i].Name != second[i].Name || string(first[i].Data) != string(second[i].Data) {
+			t.Fatalf("generators with the same seed generated different %s samples", first[i].Name)
+		}
+	}
+}
+
+func TestEmbeddedLeaves(t *testing.T) {
+	for _, sample := range testutil.NewGenerator(7).Samples() {
+		if sample.Codec != storage_trie.MultiCodecType {
+			continue
+		}
+		content, _, err := rlp.SplitList(sample.Data)
+		if err != nil {
+			t.Fatalf("generated %s is not an RLP list: %v", sample.Name, err)
+		}
+		for len(content) > 0 {
+			kind, _, rest, err := rlp.Split(content)
+			if err != nil {
+				t.Fatalf("generated %s has a malformed member: %v", sample.Name, err)
+			}
+			if kind == rlp.List {
+				return
+			}
+			content = rest
+		}
+	}
+	t.Error("expected a generated storage trie to hold a leaf embedded in its parent branch")
+}
+
+// TestGolden checks that the codecs encode the nodes decoded from real chain data back into the exact bytes they
+// were decoded from, so that their CIDs match: mainnet block 1, and the fixtures in the directory named by the
+// DAGETH_FIXTURES environment variable, e.g. as downloaded with fixtures.Download, when it is set
+func TestGolden(t *testing.T) {
+	enc, err := os.ReadFile("../header/block1_rlp")
+	if err != nil {
+		t.Fatalf("unable to read block 1: %v", err)
+	}
+	var block struct {
+		Header rlp.RawValue
+		Rest   []rlp.RawValue `rlp:"tail"`
+	}
+	if err := rlp.DecodeBytes(enc, &block); err != nil {
+		t.Fatalf("unable to decode block 1: %v", err)
+	}
+	blocks := []*fixtures.Block{{Number: 1, Header: block.Header, Block: enc}}
+	if dir := os.Getenv("DAGETH_FIXTURES"); dir != "" {
+		loaded, err := fixtures.LoadAll(dir)
+		if err != nil {
+			t.Fatalf("unable to load fixtures: %v", err)
+		}
+		blocks = append(blocks, loaded...)
+	}
+	for _, b := range blocks {
+		samples, err := testutil.FixtureSamples(b)
+		if err != nil {
+			t.Fatalf("unable to split block %d into samples: %v", b.Number, err)
+		}
+		for _, sample := range samples {
+			if err := testutil.RoundTrip(sample.Codec, sample.Data); err != nil {
+				t.Errorf("%s does not re-encode into its original bytes: %v", sample.Name, err)
+			}
+		}
+	}
+}
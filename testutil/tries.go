@@ -0,0 +1,91 @@
+package testutil
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// BuildTrie hashes the trie of the key-value pairs with go-ethereum's StackTrie, puts its nodes into the store as
+// blocks of the multicodec type, and returns its root hash
+func BuildTrie(t testing.TB, store *storage.Memory, codec uint64, kvs map[string][]byte) common.Hash {
+	t.Helper()
+	keys := make([]string, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	st := gethtrie.NewStackTrie(func(_ []byte, hash common.Hash, blob []byte) {
+		store.Bag[KeccakLink(t, codec, hash.Bytes())] = common.CopyBytes(blob)
+	})
+	for _, k := range keys {
+		if err := st.Update([]byte(k), kvs[k]); err != nil {
+			t.Fatalf("unable to update trie: %v", err)
+		}
+	}
+	return st.Hash()
+}
+
+// BuildTrieLink is like BuildTrie, but returns a link to the root node
+func BuildTrieLink(t testing.TB, store *storage.Memory, codec uint64, kvs map[string][]byte) ipld.Link {
+	t.Helper()
+	return KeccakLink(t, codec, BuildTrie(t, store, codec, kvs).Bytes())
+}
+
+// CommitTrie commits the key-value pairs into a trie with a trie.Builder storing its nodes through the link system as
+// blocks of the multicodec type, and returns a link to its root
+func CommitTrie(t testing.TB, lsys ipld.LinkSystem, codec uint64, kvs map[string][]byte) ipld.Link {
+	t.Helper()
+	keys := make([]string, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b := trie.NewBuilder(lsys, codec)
+	for _, k := range keys {
+		if err := b.Update([]byte(k), kvs[k]); err != nil {
+			t.Fatalf("unable to update trie: %v", err)
+		}
+	}
+	root, err := b.Commit()
+	if err != nil {
+		t.Fatalf("unable to commit trie: %v", err)
+	}
+	return root
+}
+
+// BuildStorageTrie commits a storage trie of n slots under random keys, the i-th key in key order holding the RLP
+// encoding of i+1, and returns a link to its root together with its keys in order
+func BuildStorageTrie(t testing.TB, lsys ipld.LinkSystem, n int) (ipld.Link, []string) {
+	t.Helper()
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = string(shared.RandomHash().Bytes())
+	}
+	sort.Strings(keys)
+	kvs := make(map[string][]byte, n)
+	for i, k := range keys {
+		kvs[k], _ = rlp.EncodeToBytes(uint64(i + 1))
+	}
+	return CommitTrie(t, lsys, storage_trie.MultiCodecType, kvs), keys
+}
+
+// NodePrototype returns the node prototype the DAG-ETH link systems load the block of the link with
+func NodePrototype(t testing.TB, lnk ipld.Link) ipld.NodePrototype {
+	t.Helper()
+	np, err := codecs.NodePrototypeChooser(lnk, ipld.LinkContext{})
+	if err != nil {
+		t.Fatalf("unable to choose the node prototype of link %s: %v", lnk, err)
+	}
+	return np
+}
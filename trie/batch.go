@@ -0,0 +1,156 @@
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// RawNode is the encoding of a trie node along with the CID it is referenced by, as the trie node sets of snap sync
+// and healing messages deliver them once mapped onto CIDs
+type RawNode struct {
+	Cid  cid.Cid
+	Data []byte
+}
+
+// BatchError is returned by DecodeBatch for the first node of the batch that can't be decoded
+type BatchError struct {
+	// Index is the index of the node within the batch
+	Index int
+	// Cid is the CID of the node
+	Cid cid.Cid
+	// Err is the failure, a *DecodeError for nodes that are not valid trie nodes
+	Err error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("trie node %d (%s): %v", e.Index, e.Cid.String(), e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BatchOptions can be used to customize the behavior of batch decoding.
+// The zero value decodes with the default DecodeOptions on one worker per CPU, without verifying the nodes.
+type BatchOptions struct {
+	// Decode customizes how each node is decoded
+	Decode DecodeOptions
+	// Workers is the number of nodes decoded concurrently, defaulting to the number of CPUs
+	Workers int
+	// Verify checks that each node hashes to its CID before decoding it
+	Verify bool
+}
+
+// DecodeBatch decodes the trie nodes with the default BatchOptions
+func DecodeBatch(nodes []RawNode) ([]ipld.Node, error) {
+	return BatchOptions{}.DecodeBatch(nodes)
+}
+
+// DecodeBatch decodes each trie node with the trie codec its CID carries, in parallel, and returns the nodes in the
+// order of the batch. Each worker reuses its node builder and RLP stream across the nodes it decodes, which saves
+// most of the per-node setup for batches of thousands of small nodes. It returns a *BatchError for the first node of
+// the batch that fails to verify or decode, and no nodes.
+func (opts BatchOptions) DecodeBatch(nodes []RawNode) ([]ipld.Node, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(nodes) {
+		workers = len(nodes)
+	}
+	decoded := make([]ipld.Node, len(nodes))
+	errs := make([]error, len(nodes))
+	var next int64 = -1
+	var failed int32
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d := newBatchDecoder(opts.Decode)
+			// nodes are claimed in batch order, so every node before a failing one is decoded before the
+			// workers stop, and the first failure reported is that of the batch
+			for atomic.LoadInt32(&failed) == 0 {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(nodes) {
+					return
+				}
+				if decoded[i], errs[i] = d.decode(nodes[i], opts.Verify); errs[i] != nil {
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, &BatchError{Index: i, Cid: nodes[i].Cid, Err: err}
+		}
+	}
+	return decoded, nil
+}
+
+// batchDecoder decodes the trie nodes of one worker, reusing its builder and stream from node to node
+type batchDecoder struct {
+	cfg DecodeOptions
+	nb  ipld.NodeBuilder
+	r   *bytes.Reader
+	s   *rlp.Stream
+}
+
+func newBatchDecoder(cfg DecodeOptions) *batchDecoder {
+	r := bytes.NewReader(nil)
+	return &batchDecoder{
+		cfg: cfg,
+		nb:  dageth.Type.TrieNode.NewBuilder(),
+		r:   r,
+		s:   rlp.NewStream(r, 0),
+	}
+}
+
+func (d *batchDecoder) decode(node RawNode, verify bool) (ipld.Node, error) {
+	codec := node.Cid.Prefix().Codec
+	if !isTrieCodec(codec) {
+		return nil, &DecodeError{Kind: ErrUnsupportedCodec, Codec: codec, Field: -1, Offset: -1,
+			Err: fmt.Errorf("multicodec type %#x is not an eth trie codec", codec)}
+	}
+	if verify {
+		if err := shared.VerifyCID(node.Data, node.Cid, codec); err != nil {
+			return nil, err
+		}
+	}
+	d.nb.Reset()
+	var err error
+	if d.cfg.BorrowBytes {
+		members, splitErr := splitMembers(node.Data)
+		err = d.cfg.decodeMembers(d.nb, members, splitErr, codec)
+	} else {
+		d.r.Reset(node.Data)
+		d.s.Reset(d.r, uint64(len(node.Data)))
+		err = d.cfg.decodeTrieNode(d.nb, d.s, codec)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.nb.Build(), nil
+}
+
+// isTrieCodec returns true for the multicodec types of the eth tries
+func isTrieCodec(codec uint64) bool {
+	switch codec {
+	case cid.EthTxTrie, cid.EthTxReceiptTrie, cid.EthStateTrie, cid.EthStorageTrie, logTrieMulticodec, withdrawalTrieMulticodec:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package trie_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+func batchNodes(t *testing.T) []trie.RawNode {
+	g := testutil.NewGenerator(11)
+	var nodes []trie.RawNode
+	add := func(codec uint64, encs [][]byte) {
+		for _, enc := range encs {
+			nodes = append(nodes, trie.RawNode{Cid: testutil.KeccakCid(t, codec, crypto.Keccak256(enc)), Data: enc})
+		}
+	}
+	accounts := make([][]byte, 300)
+	for i := range accounts {
+		accounts[i], _ = rlp.EncodeToBytes(g.Account())
+	}
+	add(state_trie.MultiCodecType, g.TrieNodes(accounts))
+	slots := make([][]byte, 300)
+	for i := range slots {
+		slots[i], _ = rlp.EncodeToBytes(g.Bytes(6 + i%26))
+	}
+	add(storage_trie.MultiCodecType, g.TrieNodes(slots))
+	return nodes
+}
+
+func TestDecodeBatch(t *testing.T) {
+	nodes := batchNodes(t)
+	for _, opts := range []trie.BatchOptions{
+		{},
+		{Workers: 1},
+		{Workers: 7, Verify: true},
+		{Decode: trie.DecodeOptions{BorrowBytes: true, Strict: true}},
+	} {
+		decoded, err := opts.DecodeBatch(nodes)
+		if err != nil {
+			t.Fatalf("unable to decode batch with %+v: %v", opts, err)
+		}
+		if len(decoded) != len(nodes) {
+			t.Fatalf("expected %d decoded nodes, got %d", len(nodes), len(decoded))
+		}
+		for i, node := range decoded {
+			enc, err := trie.AppendEncode(nil, node)
+			if err != nil {
+				t.Fatalf("unable to encode node %d: %v", i, err)
+			}
+			if !bytes.Equal(enc, nodes[i].Data) {
+				t.Fatalf("node %d of the batch decoded with %+v encodes into %x, not %x", i, opts, enc, nodes[i].Data)
+			}
+		}
+	}
+	if decoded, err := trie.DecodeBatch(nil); err != nil || len(decoded) != 0 {
+		t.Errorf("expected an empty batch to decode into no nodes, got %d nodes and %v", len(decoded), err)
+	}
+}
+
+func TestDecodeBatchErrors(t *testing.T) {
+	corrupt := func(i int, change func(*trie.RawNode)) []trie.RawNode {
+		nodes := batchNodes(t)
+		change(&nodes[i])
+		return nodes
+	}
+	for _, test := range []struct {
+		name   string
+		nodes  []trie.RawNode
+		opts   trie.BatchOptions
+		index  int
+		decode bool
+	}{
+		{"malformed node", corrupt(40, func(n *trie.RawNode) { n.Data = []byte{0x01} }), trie.BatchOptions{}, 40, true},
+		{"tampered node", corrupt(7, func(n *trie.RawNode) { n.Data = append([]byte{}, n.Data...); n.Data[len(n.Data)-1]++ }),
+			trie.BatchOptions{Verify: true}, 7, false},
+		{"other codec", corrupt(3, func(n *trie.RawNode) { n.Cid = cid.NewCidV1(cid.EthBlock, n.Cid.Hash()) }), trie.BatchOptions{}, 3, true},
+	} {
+		_, err := test.opts.DecodeBatch(test.nodes)
+		var be *trie.BatchError
+		if !errors.As(err, &be) || be.Index != test.index {
+			t.Errorf("expected the %s to fail the batch at node %d, got %v", test.name, test.index, err)
+			continue
+		}
+		var de *trie.DecodeError
+		if errors.As(err, &de) != test.decode {
+			t.Errorf("expected the %s to be reported as a DecodeError: %v, got %v", test.name, test.decode, err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package trie
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// Builder constructs a Merkle Patricia trie from key/value pairs inserted in ascending key order, similar to
+// go-ethereum's StackTrie. Every node is encoded and stored through the LinkSystem as a block of the trie's
+// multicodec as soon as no later key can change it, so only the nodes along the path of the last key are kept
+// in memory. Nodes whose encoding is shorter than 32 bytes are embedded in their parent instead of stored.
+type Builder struct {
+	lsys  ipld.LinkSystem
+	codec uint64
+	root  *builderNode
+	last  []byte
+}
+
+// builderNode is a trie node that is still being built, until ref is set
+type builderNode struct {
+	kind NodeKind
+	// path holds the partial path nibbles of extension and leaf nodes
+	path  []byte
+	value []byte
+	// children holds the children of branch nodes, and the child of extension nodes at index 0
+	children [16]*builderNode
+	// ref holds the keccak256 hash of the stored node, or its encoding if that is shorter than 32 bytes
+	ref []byte
+}
+
+// NewBuilder returns a Builder storing the nodes of the trie through the LinkSystem with the multicodec
+// of the trie, e.g. tx_trie.MultiCodecType
+func NewBuilder(lsys ipld.LinkSystem, codec uint64) *Builder {
+	return &Builder{lsys: lsys, codec: codec}
+}
+
+// Update inserts the key/value pair into the trie.
+// Keys need to be inserted in ascending order, none of them can be a prefix of another, and values can't be empty.
+func (b *Builder) Update(key, value []byte) error {
+	if len(value) == 0 {
+		return fmt.Errorf("trie builder can't insert an empty value for key %x", key)
+	}
+	if b.last != nil && bytes.Compare(key, b.last) <= 0 {
+		return fmt.Errorf("trie builder keys need to be inserted in ascending order, got %x after %x", key, b.last)
+	}
+	hex := shared.KeyToHex(key)
+	nibbles, value := hex[:len(hex)-1], common.CopyBytes(value)
+	if b.root == nil {
+		b.root = &builderNode{kind: LEAF_NODE, path: nibbles, value: value}
+	} else if err := b.insert(b.root, nibbles, value); err != nil {
+		return err
+	}
+	b.last = common.CopyBytes(key)
+	return nil
+}
+
+// Commit stores the remaining nodes, and returns the link to the root node of the trie.
+// The root node is stored even if its encoding is shorter than 32 bytes; the empty trie has no nodes to store,
+// so its root link references types.EmptyRootHash without a block behind it.
+// The Builder is reset afterwards, so it can be used to build another trie.
+func (b *Builder) Commit() (ipld.Link, error) {
+	root := b.root
+	b.root, b.last = nil, nil
+	if root == nil {
+		return b.link(types.EmptyRootHash.Bytes())
+	}
+	enc, err := b.encode(root)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := b.store(enc)
+	if err != nil {
+		return nil, err
+	}
+	return b.link(hash)
+}
+
+// link returns the link to the node of the trie with the hash
+func (b *Builder) link(hash []byte) (ipld.Link, error) {
+	c, err := shared.Keccak256ToCid(b.codec, hash)
+	if err != nil {
+		return nil, err
+	}
+	return cidlink.Link{Cid: c}, nil
+}
+
+func (b *Builder) insert(n *builderNode, key, value []byte) error {
+	switch n.kind {
+	case BRANCH_NODE:
+		if len(key) == 0 {
+			return fmt.Errorf("trie builder keys can't be prefixes of other keys")
+		}
+		// every key under the children before this one has been inserted, so the closest of them is done
+		for i := int(key[0]) - 1; i >= 0; i-- {
+			if child := n.children[i]; child != nil {
+				if err := b.hash(child); err != nil {
+					return err
+				}
+				break
+			}
+		}
+		if n.children[key[0]] == nil {
+			n.children[key[0]] = &builderNode{kind: LEAF_NODE, path: key[1:], value: value}
+			return nil
+		}
+		return b.insert(n.children[key[0]], key[1:], value)
+	case EXTENSION_NODE:
+		diff := commonPrefixLength(n.path, key)
+		if diff == len(n.path) {
+			return b.insert(n.children[0], key[diff:], value)
+		}
+		if diff == len(key) {
+			return fmt.Errorf("trie builder keys can't be prefixes of other keys")
+		}
+		// the key leaves the extension path, so what remains of the extension is done
+		rest := n.children[0]
+		if diff < len(n.path)-1 {
+			rest = &builderNode{kind: EXTENSION_NODE, path: n.path[diff+1:], children: [16]*builderNode{rest}}
+		}
+		if err := b.hash(rest); err != nil {
+			return err
+		}
+		branch := &builderNode{kind: BRANCH_NODE}
+		branch.children[n.path[diff]] = rest
+		branch.children[key[diff]] = &builderNode{kind: LEAF_NODE, path: key[diff+1:], value: value}
+		if diff == 0 {
+			*n = *branch
+			return nil
+		}
+		n.path, n.children[0] = n.path[:diff], branch
+		return nil
+	case LEAF_NODE:
+		diff := commonPrefixLength(n.path, key)
+		if diff == len(n.path) || diff == len(key) {
+			return fmt.Errorf("trie builder keys can't be prefixes of other keys")
+		}
+		prev := &builderNode{kind: LEAF_NODE, path: n.path[diff+1:], value: n.value}
+		if err := b.hash(prev); err != nil {
+			return err
+		}
+		branch := &builderNode{kind: BRANCH_NODE}
+		branch.children[n.path[diff]] = prev
+		branch.children[key[diff]] = &builderNode{kind: LEAF_NODE, path: key[diff+1:], value: value}
+		if diff == 0 {
+			*n = *branch
+			return nil
+		}
+		*n = builderNode{kind: EXTENSION_NODE, path: n.path[:diff], children: [16]*builderNode{branch}}
+		return nil
+	default:
+		return fmt.Errorf("unrecognized trie builder node type %s", n.kind.String())
+	}
+}
+
+// hash finishes the node, storing it unless it is embedded in its parent, and releases its contents
+func (b *Builder) hash(n *builderNode) error {
+	if n.ref != nil {
+		return nil
+	}
+	enc, err := b.encode(n)
+	if err != nil {
+		return err
+	}
+	if len(enc) < 32 {
+		n.ref = enc
+	} else if n.ref, err = b.store(enc); err != nil {
+		return err
+	}
+	n.path, n.value, n.children = nil, nil, [16]*builderNode{}
+	return nil
+}
+
+// encode returns the consensus encoding of the node, finishing any of its children that are still being built
+func (b *Builder) encode(n *builderNode) ([]byte, error) {
+	var fields []interface{}
+	switch n.kind {
+	case BRANCH_NODE:
+		fields = make([]interface{}, 17)
+		for i, child := range n.children {
+			if child == nil {
+				fields[i] = []byte{}
+				continue
+			}
+			if err := b.hash(child); err != nil {
+				return nil, err
+			}
+			fields[i] = childRef(child.ref)
+		}
+		fields[16] = []byte{}
+	case EXTENSION_NODE:
+		if err := b.hash(n.children[0]); err != nil {
+			return nil, err
+		}
+		fields = []interface{}{shared.HexToCompact(n.path), childRef(n.children[0].ref)}
+	case LEAF_NODE:
+		path := append(append(make([]byte, 0, len(n.path)+1), n.path...), 16)
+		fields = []interface{}{shared.HexToCompact(path), n.value}
+	default:
+		return nil, fmt.Errorf("unrecognized trie builder node type %s", n.kind.String())
+	}
+	return rlp.EncodeToBytes(fields)
+}
+
+// store writes the encoded node under the CID derived from its keccak256 hash, and returns that hash
+func (b *Builder) store(enc []byte) ([]byte, error) {
+	if b.lsys.StorageWriteOpener == nil {
+		return nil, fmt.Errorf("no storage configured for writing")
+	}
+	hash := crypto.Keccak256(enc)
+	w, commit, err := b.lsys.StorageWriteOpener(ipld.LinkContext{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(enc); err != nil {
+		return nil, err
+	}
+	lnk, err := b.link(hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := commit(lnk); err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+// childRef returns how a child node is referenced in the encoding of its parent: by hash, or embedded
+func childRef(ref []byte) interface{} {
+	if len(ref) < 32 {
+		return rlp.RawValue(ref)
+	}
+	return ref
+}
+
+func commonPrefixLength(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
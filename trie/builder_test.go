@@ -0,0 +1,122 @@
+package trie_test
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+func TestBuilder(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		codec uint64
+		kvs   map[string][]byte
+	}{
+		{"single leaf", storage_trie.MultiCodecType, map[string][]byte{string(shared.RandomBytes(32)): {0x01}}},
+		{"embedded nodes", storage_trie.MultiCodecType, map[string][]byte{
+			"\x01\x23": {0x01}, "\x01\x24": {0x02}, "\x01\x35": {0x03}, "\x11\x00": {0x04},
+		}},
+		{"hashed keys", state_trie.MultiCodecType, func() map[string][]byte {
+			kvs := make(map[string][]byte)
+			for i := 0; i < 500; i++ {
+				kvs[string(shared.RandomHash().Bytes())] = shared.RandomBytes(1 + i%80)
+			}
+			return kvs
+		}()},
+		{"shared prefixes", state_trie.MultiCodecType, func() map[string][]byte {
+			kvs := make(map[string][]byte)
+			prefix := shared.RandomBytes(20)
+			for i := 0; i < 200; i++ {
+				kvs[string(append(append([]byte{}, prefix[:i%20]...), shared.RandomBytes(32-i%20)...))] = shared.RandomBytes(40)
+			}
+			return kvs
+		}()},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			keys := make([]string, 0, len(test.kvs))
+			for k := range test.kvs {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			expectedNodes := make(map[ipld.Link][]byte)
+			st := gethtrie.NewStackTrie(func(_ []byte, hash common.Hash, blob []byte) {
+				expectedNodes[testutil.KeccakLink(t, test.codec, hash.Bytes())] = common.CopyBytes(blob)
+			})
+			store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+			lsys := codecs.NewLinkSystem(store)
+			b := trie.NewBuilder(lsys, test.codec)
+			for _, k := range keys {
+				st.Update([]byte(k), test.kvs[k])
+				if err := b.Update([]byte(k), test.kvs[k]); err != nil {
+					t.Fatalf("unable to update trie builder: %v", err)
+				}
+			}
+			root, err := b.Commit()
+			if err != nil {
+				t.Fatalf("unable to commit trie builder: %v", err)
+			}
+			expectedRoot := testutil.KeccakLink(t, test.codec, st.Hash().Bytes())
+			if root != expectedRoot {
+				t.Fatalf("trie builder root (%s) does not match stack trie root (%s)", root.String(), expectedRoot.String())
+			}
+			// the stack trie leaves a root shorter than 32 bytes to the caller
+			if _, ok := expectedNodes[expectedRoot]; !ok {
+				expectedNodes[expectedRoot] = store.Bag[root]
+			}
+			if len(store.Bag) != len(expectedNodes) {
+				t.Errorf("expected %d stored trie nodes, got %d", len(expectedNodes), len(store.Bag))
+			}
+			for lnk, blob := range expectedNodes {
+				if !bytes.Equal(store.Bag[lnk], blob) {
+					t.Errorf("stored trie node %s does not match stack trie node", lnk.String())
+				}
+			}
+			if _, err := lsys.Load(ipld.LinkContext{}, root, dageth.Type.TrieNode); err != nil {
+				t.Errorf("unable to load root node: %v", err)
+			}
+		})
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	lsys := codecs.NewLinkSystem(&storage.Memory{Bag: make(map[ipld.Link][]byte)})
+	b := trie.NewBuilder(lsys, storage_trie.MultiCodecType)
+	root, err := b.Commit()
+	if err != nil {
+		t.Fatalf("unable to commit empty trie builder: %v", err)
+	}
+	if !bytes.Equal(root.(cidlink.Link).Hash()[2:], types.EmptyRootHash.Bytes()) {
+		t.Errorf("empty trie root (%s) does not reference the empty root hash", root.String())
+	}
+
+	if err := b.Update([]byte{0x02}, nil); err == nil {
+		t.Error("expected an error inserting an empty value")
+	}
+	if err := b.Update([]byte{0x02}, []byte{0x01}); err != nil {
+		t.Fatalf("unable to update trie builder: %v", err)
+	}
+	if err := b.Update([]byte{0x01}, []byte{0x01}); err == nil {
+		t.Error("expected an error inserting a key out of order")
+	}
+	if err := b.Update([]byte{0x02}, []byte{0x01}); err == nil {
+		t.Error("expected an error inserting a duplicate key")
+	}
+	if err := b.Update([]byte{0x02, 0x03}, []byte{0x01}); err == nil {
+		t.Error("expected an error inserting a key extending a previous key")
+	}
+}
@@ -0,0 +1,12 @@
+/*
+Package trie decodes and encodes the Merkle Patricia trie nodes the trie codecs share.
+
+Branch and extension nodes reference their children with the Child union: a Link to the child block, or the child
+TrieNode itself when its encoding is shorter than 32 bytes. The LinkCodec hook of the DecodeOptions resolves the
+multicodec type of each child link from its nibble path. Nodes decoded from byte slices with BorrowBytes alias their
+input, and nodes streamed from readers are bounded by MaxNodeSize.
+
+DecodeBatch decodes the trie node sets of snap sync and healing messages in parallel, and Builder builds tries
+from sorted key/value pairs, storing each node through a LinkSystem as it completes.
+*/
+package trie
@@ -0,0 +1,92 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMalformedNode is the kind of DecodeError for nodes that are not RLP lists of the shape of a trie node
+	ErrMalformedNode = errors.New("malformed trie node")
+	// ErrUnexpectedChildLength is the kind of DecodeError for child references that are neither empty nor a 32 byte hash
+	ErrUnexpectedChildLength = errors.New("unexpected trie node child length")
+	// ErrUnknownHexPrefix is the kind of DecodeError for partial paths without a valid hex prefix flag, its Err
+	// wraps keys.ErrEmptyCompact, keys.ErrInvalidFlag or keys.ErrNonZeroPadding
+	ErrUnknownHexPrefix = errors.New("unknown partial path hex prefix")
+	// ErrInvalidEmbeddedNode is the kind of DecodeError for children included directly in a branch or extension
+	// node that are not trie nodes shorter than 32 bytes
+	ErrInvalidEmbeddedNode = errors.New("invalid embedded trie node")
+	// ErrInvalidValue is the kind of DecodeError for leaf and branch values that don't decode as the trie's value type
+	ErrInvalidValue = errors.New("invalid trie node value")
+	// ErrUnsupportedCodec is the kind of DecodeError for multicodec types that are not eth trie codecs
+	ErrUnsupportedCodec = errors.New("unsupported trie multicodec type")
+)
+
+// DecodeError is returned when a trie node can't be decoded. It locates the failure within the encoded node,
+// and matches its Kind with errors.Is, so failures can be classified without parsing the message.
+type DecodeError struct {
+	// Kind is one of the Err* values of this package
+	Kind error
+	// Codec is the multicodec type the node was decoded as
+	Codec uint64
+	// Field is the index of the member of the node's RLP list the failure was found in, or -1 for the whole node.
+	// Failures within a node embedded in a branch or extension are located at the member of the outermost node holding it.
+	Field int
+	// Offset is the byte offset of that member within the encoded node, or -1 if it can't be located
+	Offset int
+	// Err is the underlying error, if any
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	msg := fmt.Sprintf("invalid DAG-ETH trie node (codec %#x", e.Codec)
+	if e.Field >= 0 {
+		msg += fmt.Sprintf(", field %d", e.Field)
+		if e.Offset >= 0 {
+			msg += fmt.Sprintf(" at byte %d", e.Offset)
+		}
+	}
+	msg += "): " + e.Kind.Error()
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Is reports whether the target is the Kind of the error
+func (e *DecodeError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the underlying error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeError returns a DecodeError of the kind for the node member at the field index, with a formatted cause
+func decodeError(kind error, field int, format string, args ...interface{}) *DecodeError {
+	return &DecodeError{Kind: kind, Field: field, Offset: -1, Err: fmt.Errorf(format, args...)}
+}
+
+// wrapDecodeError returns a DecodeError of the kind for the node member at the field index, caused by err
+func wrapDecodeError(kind error, field int, err error) *DecodeError {
+	return &DecodeError{Kind: kind, Field: field, Offset: -1, Err: err}
+}
+
+// atField locates the DecodeError at the node member at the index, for failures found within the value or the
+// embedded node that member holds
+func atField(err error, field int) error {
+	var de *DecodeError
+	if errors.As(err, &de) {
+		de.Field = field
+	}
+	return err
+}
+
+// invalidValue reports a failure to decode a leaf or branch value as a DecodeError
+func invalidValue(err error) error {
+	if err == nil {
+		return nil
+	}
+	return wrapDecodeError(ErrInvalidValue, -1, err)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/vulcanize/go-codec-dageth/shared"
 	account "github.com/vulcanize/go-codec-dageth/state_account"
 	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
 )
 
 type NodeKind string
@@ -29,12 +30,13 @@ const (
 	EXTENSION_NODE NodeKind = "TrieExtensionNode"
 	LEAF_NODE      NodeKind = "TrieLeafNode"
 
-	UNKNOWN_VALUE ValueKind = "unknown"
-	TX_VALUE      ValueKind = "Transaction"
-	RCT_VALUE     ValueKind = "Receipt"
-	STATE_VALUE   ValueKind = "Account"
-	STORAGE_VALUE ValueKind = "Bytes"
-	LOG_VALUE     ValueKind = "Log"
+	UNKNOWN_VALUE    ValueKind = "unknown"
+	TX_VALUE         ValueKind = "Transaction"
+	RCT_VALUE        ValueKind = "Receipt"
+	STATE_VALUE      ValueKind = "Account"
+	STORAGE_VALUE    ValueKind = "Bytes"
+	LOG_VALUE        ValueKind = "Log"
+	WITHDRAWAL_VALUE ValueKind = "Withdrawal"
 )
 
 func (n NodeKind) String() string {
@@ -45,64 +47,87 @@ func (v ValueKind) String() string {
 	return string(v)
 }
 
+// EncodeOptions can be used to customize the behavior of trie node encoding.
+// The zero value is the default behavior used by the registered codecs.
+type EncodeOptions struct {
+	// PartialPath selects how the PartialPath of extension and leaf nodes is represented,
+	// it should match the DecodeOptions the node was decoded with
+	PartialPath PartialPathEncoding
+	// UnwrapStorageValues causes Bytes (storage) leaf values to be RLP wrapped before they are encoded,
+	// it should match the DecodeOptions the node was decoded with
+	UnwrapStorageValues bool
+}
+
 // Encode provides an IPLD codec encode interface for eth merkle patricia trie node IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code XXXX when this package is invoked via init.
 func Encode(node ipld.Node, w io.Writer) error {
-	// 1KiB can be allocated on the stack, and covers most small nodes
-	// without having to grow the buffer and cause allocations.
-	enc := make([]byte, 0, 1024)
-
-	enc, err := AppendEncode(enc, node)
-	if err != nil {
-		return err
-	}
-	_, err = w.Write(enc)
-	return err
+	return EncodeOptions{}.Encode(node, w)
 }
 
 // AppendEncode is like Encode, but it uses a destination buffer directly.
 // This means less copying of bytes, and if the destination has enough capacity,
 // fewer allocations.
 func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return EncodeOptions{}.AppendEncode(enc, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options.
+// It streams the node to w field by field, and receipt values log by log, see shared.StreamRLP.
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	nodeFields, err := cfg.packNode(node, true)
+	if err != nil {
+		return err
+	}
+	if err := shared.StreamRLP(w, shared.List(nodeFields)); err != nil {
+		return fmt.Errorf("invalid DAG-ETH TrieNode form (%v)", err)
+	}
+	return nil
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	nodeFields, err := cfg.packNode(inNode, false)
+	if err != nil {
+		return nil, err
+	}
+	wbs := shared.NewWriteableByteSlice(&enc)
+	if err := rlp.Encode(wbs, nodeFields); err != nil {
+		return enc, fmt.Errorf("invalid DAG-ETH TrieNode form (%v)", err)
+	}
+	return enc, nil
+}
+
+// packNode packs the node into the fields of its RLP list, with receipt values as shared.StreamRLP items
+// when streaming
+func (cfg EncodeOptions) packNode(inNode ipld.Node, stream bool) ([]interface{}, error) {
 	// Wrap in a typed node for some basic schema form checking
 	builder := dageth.Type.TrieNode.NewBuilder()
 	if err := builder.AssignNode(inNode); err != nil {
 		return nil, err
 	}
-	n := builder.Build()
+	return cfg.packTrieNode(builder.Build(), stream)
+}
+
+// packTrieNode packs the typed TrieNode into the fields of its RLP list
+func (cfg EncodeOptions) packTrieNode(n ipld.Node, stream bool) ([]interface{}, error) {
 	node, kind, err := NodeAndKind(n)
 	if err != nil {
 		return nil, err
 	}
-	var nodeFields []interface{}
 	switch kind {
 	case BRANCH_NODE:
-		nodeFields, err = packBranchNode(node)
-		if err != nil {
-			return nil, err
-		}
+		return cfg.packBranchNode(node, stream)
 	case EXTENSION_NODE:
-		nodeFields, err = packExtensionNode(node)
-		if err != nil {
-			return nil, err
-		}
+		return cfg.packExtensionNode(node)
 	case LEAF_NODE:
-		nodeFields, err = packLeafNode(node)
-		if err != nil {
-			return nil, err
-		}
+		return cfg.packLeafNode(node, stream)
 	default:
 		return nil, fmt.Errorf("IPLD node is missing the expected Union keys")
 	}
-	wbs := shared.NewWriteableByteSlice(&enc)
-	if err := rlp.Encode(wbs, nodeFields); err != nil {
-		return enc, fmt.Errorf("invalid DAG-ETH TrieNode form (%v)", err)
-	}
-	return enc, nil
 }
 
-func packBranchNode(node ipld.Node) ([]interface{}, error) {
+func (cfg EncodeOptions) packBranchNode(node ipld.Node, stream bool) ([]interface{}, error) {
 	nodeFields := make([]interface{}, 17)
 	for i := 0; i < 16; i++ {
 		key := fmt.Sprintf("Child%s", strings.ToUpper(strconv.FormatInt(int64(i), 16)))
@@ -114,91 +139,19 @@ func packBranchNode(node ipld.Node) ([]interface{}, error) {
 			nodeFields[i] = []byte{}
 			continue
 		}
-		childLinkNode, err := childNode.LookupByString("Link")
-		if err == nil {
-			childLink, err := childLinkNode.AsLink()
-			if err != nil {
-				return nil, err
-			}
-			childCIDLink, ok := childLink.(cidlink.Link)
-			if !ok {
-				return nil, fmt.Errorf("branch node child link needs to be a CID")
-			}
-			childMh := childCIDLink.Hash()
-			decodedChildMh, err := multihash.Decode(childMh)
-			if err != nil {
-				return nil, fmt.Errorf("unable to decode Child multihash: %v", err)
-			}
-			nodeFields[i] = decodedChildMh.Digest
-			continue
-		}
-		childTrieNodeNode, err := childNode.LookupByString("TrieNode")
-		if err == nil {
-			// it must be a leaf node as only RLP encodings of storage leaf nodes can be less than 32 bytes in length and stored direclty in a parent node
-			childLeafNode, err := childTrieNodeNode.LookupByString(LEAF_NODE.String())
-			if err != nil {
-				return nil, fmt.Errorf("only leaf nodes can be less than 32 bytes and stored direclty in a parent node")
-			}
-			childLeafNodeFields, err := packLeafNode(childLeafNode)
-			if err != nil {
-				return nil, err
-			}
-			childLeafNodeRLP, err := rlp.EncodeToBytes(childLeafNodeFields)
-			if err != nil {
-				return nil, err
-			}
-			nodeFields[i] = childLeafNodeRLP
-			continue
-		}
-		return nil, fmt.Errorf("branch node child needs to be of kind bytes, link, or null: %v", err)
-		/* Child should be a kinded Union, in which case we could do the below type switch instead of map key checking
-		switch childNode.Kind() {
-		case ipld.Kind_Null:
-			nodeFields[i] = []byte{}
-		case ipld.Kind_Link:
-			childLink, err := childNode.AsLink()
-			if err != nil {
-				return nil, err
-			}
-			childCIDLink, ok := childLink.(cidlink.Link)
-			if !ok {
-				return nil, fmt.Errorf("branch node child link needs to be a CID")
-			}
-			childMh := childCIDLink.Hash()
-			decodedChildMh, err := multihash.Decode(childMh)
-			if err != nil {
-				return nil, fmt.Errorf("unable to decode Child multihash: %v", err)
-			}
-			nodeFields[i] = decodedChildMh.Digest
-		case ipld.Kind_Map:
-			// it must be a leaf node as only RLP encodings of storage leaf nodes can be less than 32 bytes in length and stored direclty in a parent node
-			childLeafNode, err := childNode.LookupByString(LEAF_NODE.String())
-			if err != nil {
-				return nil, fmt.Errorf("only leaf nodes can be less than 32 bytes and stored direclty in a parent node")
-			}
-			childLeafNodeFields, err := packLeafNode(childLeafNode)
-			if err != nil {
-				return nil, err
-			}
-			childLeafNodeRLP, err := rlp.EncodeToBytes(childLeafNodeFields)
-			if err != nil {
-				return nil, err
-			}
-			nodeFields[i] = childLeafNodeRLP
-		default:
-			return nil, fmt.Errorf("branch node child needs to be of kind bytes, link, or null")
+		if nodeFields[i], err = cfg.packChild(childNode); err != nil {
+			return nil, err
 		}
-		*/
 	}
-	valueBytes, err := packValue(node)
+	value, err := cfg.valueItem(node, stream)
 	if err != nil {
 		return nil, err
 	}
-	nodeFields[16] = valueBytes
+	nodeFields[16] = value
 	return nodeFields, nil
 }
 
-func packExtensionNode(node ipld.Node) ([]interface{}, error) {
+func (cfg EncodeOptions) packExtensionNode(node ipld.Node) ([]interface{}, error) {
 	nodeFields := make([]interface{}, 2)
 	ppNode, err := node.LookupByString("PartialPath")
 	if err != nil {
@@ -208,29 +161,58 @@ func packExtensionNode(node ipld.Node) ([]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	nodeFields[0] = shared.HexToCompact(pp)
-	childNode, err := node.LookupByString("Child")
+	nodeFields[0], err = cfg.PartialPath.toCompact(pp, EXTENSION_NODE)
 	if err != nil {
 		return nil, err
 	}
-	childLink, err := childNode.AsLink()
+	childNode, err := node.LookupByString("Child")
 	if err != nil {
 		return nil, err
 	}
-	childCIDLink, ok := childLink.(cidlink.Link)
-	if !ok {
-		return nil, fmt.Errorf("extension node child link needs to be a CID")
+	if childNode.IsNull() {
+		return nil, fmt.Errorf("extension node requires a child")
 	}
-	childMh := childCIDLink.Hash()
-	decodedChildMh, err := multihash.Decode(childMh)
-	if err != nil {
-		return nil, fmt.Errorf("unable to decode Child multihash: %v", err)
+	if nodeFields[1], err = cfg.packChild(childNode); err != nil {
+		return nil, err
 	}
-	nodeFields[1] = decodedChildMh.Digest
 	return nodeFields, nil
 }
 
-func packLeafNode(node ipld.Node) ([]interface{}, error) {
+// packChild packs the Child union of a branch or extension node: the hash of the child node it links to, or the
+// encoding of the child node itself, of any kind, when it is small enough to be included directly in its parent
+func (cfg EncodeOptions) packChild(childNode ipld.Node) (interface{}, error) {
+	if childLinkNode, err := childNode.LookupByString("Link"); err == nil {
+		childLink, err := childLinkNode.AsLink()
+		if err != nil {
+			return nil, err
+		}
+		childCIDLink, ok := childLink.(cidlink.Link)
+		if !ok {
+			return nil, fmt.Errorf("trie node child link needs to be a CID")
+		}
+		decodedChildMh, err := multihash.Decode(childCIDLink.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode Child multihash: %v", err)
+		}
+		return decodedChildMh.Digest, nil
+	}
+	childTrieNode, err := childNode.LookupByString("TrieNode")
+	if err != nil {
+		return nil, fmt.Errorf("trie node child needs to be a link or a trie node: %v", err)
+	}
+	childNodeFields, err := cfg.packTrieNode(childTrieNode, false)
+	if err != nil {
+		return nil, err
+	}
+	childNodeRLP, err := rlp.EncodeToBytes(childNodeFields)
+	if err != nil {
+		return nil, err
+	}
+	// the embedded node is included as-is in the parent's list, not wrapped as an RLP string
+	return rlp.RawValue(childNodeRLP), nil
+}
+
+func (cfg EncodeOptions) packLeafNode(node ipld.Node, stream bool) ([]interface{}, error) {
 	nodeFields := make([]interface{}, 2)
 	ppNode, err := node.LookupByString("PartialPath")
 	if err != nil {
@@ -240,16 +222,44 @@ func packLeafNode(node ipld.Node) ([]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	nodeFields[0] = shared.HexToCompact(pp)
-	valueBytes, err := packValue(node)
+	nodeFields[0], err = cfg.PartialPath.toCompact(pp, LEAF_NODE)
+	if err != nil {
+		return nil, err
+	}
+	value, err := cfg.valueItem(node, stream)
 	if err != nil {
 		return nil, err
 	}
-	nodeFields[1] = valueBytes
+	nodeFields[1] = value
 	return nodeFields, nil
 }
 
-func packValue(node ipld.Node) ([]byte, error) {
+// valueItem packs the Value of the node, receipts into shared.StreamRLP items that list their logs one by one when
+// streaming, as the receipts of blocks emitting many logs are the largest values tries hold
+func (cfg EncodeOptions) valueItem(node ipld.Node, stream bool) (interface{}, error) {
+	if stream {
+		valUnionNode, err := node.LookupByString("Value")
+		if err != nil {
+			return nil, err
+		}
+		if !valUnionNode.IsNull() {
+			valNode, valKind, err := ValueAndKind(valUnionNode)
+			if err != nil {
+				return nil, err
+			}
+			if valKind == RCT_VALUE {
+				item, err := rct.EncodeOptions{}.StreamItem(valNode)
+				if err != nil {
+					return nil, err
+				}
+				return shared.Wrapped{Item: item}, nil
+			}
+		}
+	}
+	return cfg.packValue(node)
+}
+
+func (cfg EncodeOptions) packValue(node ipld.Node) ([]byte, error) {
 	valUnionNode, err := node.LookupByString("Value")
 	if err != nil {
 		return nil, err
@@ -281,13 +291,26 @@ func packValue(node ipld.Node) ([]byte, error) {
 		}
 		return buf.Bytes(), nil
 	case STORAGE_VALUE:
-		return valNode.AsBytes()
+		val, err := valNode.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		if cfg.UnwrapStorageValues {
+			return rlp.EncodeToBytes(val)
+		}
+		return val, nil
 	case LOG_VALUE:
 		buf := new(bytes.Buffer)
 		if err := log.Encode(valNode, buf); err != nil {
 			return nil, err
 		}
 		return buf.Bytes(), nil
+	case WITHDRAWAL_VALUE:
+		buf := new(bytes.Buffer)
+		if err := withdrawal.Encode(valNode, buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
 	default:
 		return nil, fmt.Errorf("eth trie value of unexpected kind %s", valKind.String())
 	}
@@ -314,6 +337,10 @@ func ValueAndKind(node ipld.Node) (ipld.Node, ValueKind, error) {
 	if err == nil {
 		return n, LOG_VALUE, nil
 	}
+	n, err = node.LookupByString(WITHDRAWAL_VALUE.String())
+	if err == nil {
+		return n, WITHDRAWAL_VALUE, nil
+	}
 	return nil, "", fmt.Errorf("eth trie value IPLD node is missing the expected keyed Union keys")
 }
 
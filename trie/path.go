@@ -0,0 +1,96 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vulcanize/go-codec-dageth/keys"
+)
+
+// PartialPathEncoding is the representation used for the PartialPath of extension and leaf nodes
+type PartialPathEncoding uint8
+
+const (
+	// PartialPathHex represents a partial path as one nibble per byte, with leaf node
+	// paths terminated by a 0x10 flag byte. This is the default representation.
+	PartialPathHex PartialPathEncoding = iota
+	// PartialPathNibbles represents a partial path as one nibble per byte, with no terminator.
+	// Whether the path belongs to a leaf or extension is implied by the node kind.
+	PartialPathNibbles
+	// PartialPathCompact represents a partial path in the hex-prefix (compact) encoding used
+	// in the RLP of the node itself.
+	PartialPathCompact
+)
+
+func (e PartialPathEncoding) String() string {
+	switch e {
+	case PartialPathHex:
+		return "hex"
+	case PartialPathNibbles:
+		return "nibbles"
+	case PartialPathCompact:
+		return "compact"
+	default:
+		return "unknown"
+	}
+}
+
+// fromCompact converts a compact encoded partial path into this representation
+func (e PartialPathEncoding) fromCompact(compact []byte) ([]byte, error) {
+	switch e {
+	case PartialPathHex, PartialPathNibbles:
+		nibbles, leaf, err := keys.CompactToNibbles(compact)
+		if errors.Is(err, keys.ErrNonZeroPadding) {
+			// a set padding nibble, which only strict decoding rejects, does not change the path
+			nibbles, leaf, err = keys.CompactToNibbles(append([]byte{compact[0] & 0xf0}, compact[1:]...))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if leaf && e == PartialPathHex {
+			nibbles = append(nibbles, 16)
+		}
+		return nibbles, nil
+	case PartialPathCompact:
+		return compact, nil
+	default:
+		return nil, fmt.Errorf("unrecognized partial path encoding %d", e)
+	}
+}
+
+// toCompact converts a partial path in this representation into its compact encoding
+// it returns an error if the path cannot be a valid path of the given node kind in this representation
+func (e PartialPathEncoding) toCompact(path []byte, kind NodeKind) ([]byte, error) {
+	switch e {
+	case PartialPathHex:
+		terminated := len(path) > 0 && path[len(path)-1] == 16
+		if terminated != (kind == LEAF_NODE) {
+			return nil, fmt.Errorf("hex partial path terminator does not match node kind %s", kind.String())
+		}
+		if terminated {
+			path = path[:len(path)-1]
+		}
+		return keys.NibblesToCompact(path, terminated)
+	case PartialPathNibbles:
+		return keys.NibblesToCompact(path, kind == LEAF_NODE)
+	case PartialPathCompact:
+		if err := keys.CheckCompact(path); err != nil {
+			return nil, err
+		}
+		if isLeaf := path[0]>>4 >= 2; isLeaf != (kind == LEAF_NODE) {
+			return nil, fmt.Errorf("compact partial path flag does not match node kind %s", kind.String())
+		}
+		return path, nil
+	default:
+		return nil, fmt.Errorf("unrecognized partial path encoding %d", e)
+	}
+}
+
+// nibbles converts a partial path in this representation into its nibbles, without a terminator
+func (e PartialPathEncoding) nibbles(path []byte, kind NodeKind) ([]byte, error) {
+	compact, err := e.toCompact(path, kind)
+	if err != nil {
+		return nil, err
+	}
+	return PartialPathNibbles.fromCompact(compact)
+}
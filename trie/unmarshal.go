@@ -1,9 +1,10 @@
 package trie
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"strconv"
 	"strings"
 
@@ -11,16 +12,70 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
 	"github.com/vulcanize/go-codec-dageth/log"
 	"github.com/vulcanize/go-codec-dageth/rct"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/keys"
 	"github.com/vulcanize/go-codec-dageth/shared"
 	account "github.com/vulcanize/go-codec-dageth/state_account"
 	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
 )
 
-const logTrieMulticodec = uint64(0x99) // Proposed
+const (
+	logTrieMulticodec        = uint64(0x99) // Proposed
+	withdrawalTrieMulticodec = uint64(0x9f) // Proposed
+)
+
+// DefaultMaxNodeSize is the largest trie node streamed from a reader by default, well above the largest receipt a
+// block can hold under current gas limits
+const DefaultMaxNodeSize = 16 << 20
+
+// DecodeOptions can be used to customize the behavior of trie node decoding.
+// The zero value is the default behavior used by the registered codecs.
+type DecodeOptions struct {
+	// Strict causes the decoder to reject nodes whose RLP list does not have 2 or 17 members,
+	// nodes with bytes trailing the RLP payload or with a non-canonical RLP encoding, and
+	// nodes whose partial paths have a non-zero padding nibble. Malformed children are rejected in either mode.
+	Strict bool
+	// PartialPath selects how the PartialPath of extension and leaf nodes is represented
+	PartialPath PartialPathEncoding
+	// Receipt customizes how the receipt values of receipt trie leaves are decoded
+	Receipt rct.DecodeOptions
+	// Account customizes how the account values of state trie leaves are decoded
+	Account account.DecodeOptions
+	// UnwrapStorageValues causes storage trie leaf values, which are RLP encoded slot values,
+	// to be decoded into the underlying slot value bytes rather than kept as their RLP encoding
+	UnwrapStorageValues bool
+	// RawValues leaves the values of transaction, receipt, state, log and withdrawal trie nodes unexpanded,
+	// they are assigned to the Bytes member of the Value union as their consensus encoding rather than
+	// decoded into their typed member; the default EncodeOptions encode them back as is
+	RawValues bool
+	// LinkCodecs overrides the multicodec type of the links to child nodes, which is the multicodec type
+	// of the trie by default
+	LinkCodecs shared.LinkCodecs
+	// LinkHashes overrides the multihash type of the links to child nodes, which is keccak256 by default
+	LinkHashes shared.LinkHashes
+	// LinkCodec resolves the multicodec type of each link to a child node, taking precedence over LinkCodecs
+	// unless it returns 0, e.g. to type the links of a trie whose nodes are stored under different codecs
+	LinkCodec LinkCodecResolver
+	// BorrowBytes lets the decoded node alias the input when it is decoded from a byte slice, or from a reader
+	// exposing its contents with Bytes, rather than copying the bytes it holds out of it. Compact partial paths
+	// and storage or raw values are then slices of the input, so the caller must not modify the input for as long
+	// as the node is in use. Values decoded into their typed member are always copied.
+	BorrowBytes bool
+	// MaxNodeSize bounds the size of the nodes streamed from readers without a Bytes method, defaulting to
+	// DefaultMaxNodeSize. The RLP headers of a node are checked against it before the lengths they claim are
+	// allocated, so a few bytes of input can't claim gigabytes.
+	MaxNodeSize int
+}
+
+// LinkCodecResolver returns the multicodec type of the link to a child node of a node of the given kind, from the
+// nibble path leading from the node to the child: the index of the child of a branch node, or the partial path of an
+// extension node. A return value of 0 leaves the link to LinkCodecs.
+type LinkCodecResolver func(path []byte, kind NodeKind) uint64
 
 // DecodeTrieNode provides an IPLD codec decode interface for eth merkle patricia trie nodes
 // It's not possible to meet the Decode(na ipld.NodeAssembler, in io.Reader) interface
@@ -28,32 +83,163 @@ const logTrieMulticodec = uint64(0x99) // Proposed
 // this is used by Decode functions for each trie type, which are the ones registered to their
 // corresponding multicodec
 func DecodeTrieNode(na ipld.NodeAssembler, in io.Reader, codec uint64) error {
-	var src []byte
+	return DecodeOptions{}.DecodeTrieNode(na, in, codec)
+}
+
+// DecodeTrieNodeBytes is like DecodeTrieNode, but it uses an input buffer directly.
+func DecodeTrieNodeBytes(na ipld.NodeAssembler, src []byte, codec uint64) error {
+	return DecodeOptions{}.DecodeTrieNodeBytes(na, src, codec)
+}
+
+// DecodeTrieNode is like the package level DecodeTrieNode, but uses the provided options.
+// The node is streamed from the reader rather than buffered, up to MaxNodeSize bytes, nodes that can't be decoded are
+// reported with a *DecodeError.
+func (cfg DecodeOptions) DecodeTrieNode(na ipld.NodeAssembler, in io.Reader, codec uint64) error {
 	if buf, ok := in.(interface{ Bytes() []byte }); ok {
-		src = buf.Bytes()
-	} else {
-		var err error
-		src, err = ioutil.ReadAll(in)
+		return cfg.DecodeTrieNodeBytes(na, buf.Bytes(), codec)
+	}
+	maxSize := cfg.MaxNodeSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxNodeSize
+	}
+	return cfg.decodeTrieNode(na, rlp.NewStream(io.LimitReader(in, int64(maxSize)), uint64(maxSize)), codec)
+}
+
+// DecodeTrieNodeBytes is like the package level DecodeTrieNodeBytes, but uses the provided options.
+// Nodes that can't be decoded are reported with a *DecodeError.
+func (cfg DecodeOptions) DecodeTrieNodeBytes(na ipld.NodeAssembler, src []byte, codec uint64) error {
+	if cfg.BorrowBytes {
+		members, err := splitMembers(src)
+		return cfg.decodeMembers(na, members, err, codec)
+	}
+	return cfg.decodeTrieNode(na, rlp.NewStream(bytes.NewReader(src), uint64(len(src))), codec)
+}
+
+// member is a member of the RLP list of a trie node, as read from the stream
+type member struct {
+	// val is the content of a string member, or the whole encoding of a list member
+	val []byte
+	// list is set for list members, only nodes embedded in a branch or extension are valid ones
+	list bool
+	// offset is the byte offset of the member within the encoded node
+	offset int
+}
+
+func (cfg DecodeOptions) decodeTrieNode(na ipld.NodeAssembler, s *rlp.Stream, codec uint64) error {
+	members, err := readMembers(s)
+	return cfg.decodeMembers(na, members, err, codec)
+}
+
+// decodeMembers assembles the node from its members unless reading them failed with err, and locates
+// DecodeErrors within the encoded node
+func (cfg DecodeOptions) decodeMembers(na ipld.NodeAssembler, members []member, err error, codec uint64) error {
+	if err == nil {
+		err = cfg.assembleTrieNode(na, members, codec)
+	}
+	var de *DecodeError
+	if errors.As(err, &de) {
+		de.Codec = codec
+		if de.Field >= 0 && de.Field < len(members) && de.Offset < 0 {
+			de.Offset = members[de.Field].offset
+		}
+	}
+	return err
+}
+
+// readMembers reads the members of the RLP list of a trie node from the stream, copying out their bytes
+// without decoding them into boxed values, and checks that nothing follows the list
+func readMembers(s *rlp.Stream) ([]member, error) {
+	kind, size, err := s.Kind()
+	if err != nil {
+		return nil, wrapDecodeError(ErrMalformedNode, -1, err)
+	}
+	if kind != rlp.List {
+		return nil, decodeError(ErrMalformedNode, -1, "trie node should be an RLP list")
+	}
+	if _, err := s.List(); err != nil {
+		return nil, wrapDecodeError(ErrMalformedNode, -1, err)
+	}
+	offset := encodedSize(kind, size) - int(size)
+	members := make([]member, 0, 17)
+	for {
+		kind, size, err := s.Kind()
+		if err == rlp.EOL {
+			break
+		}
 		if err != nil {
-			return err
+			return members, &DecodeError{Kind: ErrMalformedNode, Field: len(members), Offset: offset, Err: err}
+		}
+		m := member{list: kind == rlp.List, offset: offset}
+		if m.list {
+			m.val, err = s.Raw()
+		} else {
+			m.val, err = s.Bytes()
+		}
+		if err != nil {
+			return members, &DecodeError{Kind: ErrMalformedNode, Field: len(members), Offset: offset, Err: err}
+		}
+		members = append(members, m)
+		offset += encodedSize(kind, size)
+	}
+	if err := s.ListEnd(); err != nil {
+		return members, wrapDecodeError(ErrMalformedNode, -1, err)
+	}
+	if _, _, err := s.Kind(); err != io.EOF {
+		return members, wrapDecodeError(ErrMalformedNode, -1, rlp.ErrMoreThanOneValue)
+	}
+	return members, nil
+}
+
+// splitMembers splits the members of the RLP list of a trie node out of src in place, the members alias src
+func splitMembers(src []byte) ([]member, error) {
+	content, rest, err := rlp.SplitList(src)
+	if err != nil {
+		return nil, wrapDecodeError(ErrMalformedNode, -1, err)
+	}
+	if len(rest) != 0 {
+		return nil, wrapDecodeError(ErrMalformedNode, -1, rlp.ErrMoreThanOneValue)
+	}
+	members := make([]member, 0, 17)
+	for len(content) != 0 {
+		offset := len(src) - len(content)
+		kind, val, tail, err := rlp.Split(content)
+		if err != nil {
+			return members, &DecodeError{Kind: ErrMalformedNode, Field: len(members), Offset: offset, Err: err}
+		}
+		m := member{val: val, list: kind == rlp.List, offset: offset}
+		if m.list {
+			m.val = content[:len(content)-len(tail)]
 		}
+		members = append(members, m)
+		content = tail
 	}
-	return DecodeTrieNodeBytes(na, src, codec)
+	return members, nil
 }
 
-// DecodeTrieNodeBytes is like DecodeTrieNode, but it uses an input buffer directly.
-func DecodeTrieNodeBytes(na ipld.NodeAssembler, src []byte, codec uint64) error {
-	var nodeFields []interface{}
-	if err := rlp.DecodeBytes(src, &nodeFields); err != nil {
-		return err
+// encodedSize returns the length of the RLP encoding of a value of the kind and content size
+func encodedSize(kind rlp.Kind, size uint64) int {
+	switch {
+	case kind == rlp.Byte:
+		return 1
+	case size < 56:
+		return 1 + int(size)
+	default:
+		headerSize := 1
+		for n := size; n > 0; n >>= 8 {
+			headerSize++
+		}
+		return headerSize + int(size)
 	}
+}
+
+func (cfg DecodeOptions) assembleTrieNode(na ipld.NodeAssembler, members []member, codec uint64) error {
 	ma, err := na.BeginMap(1)
 	if err != nil {
 		return err
 	}
-	switch len(nodeFields) {
+	switch len(members) {
 	case 2:
-		nodeKind, decoded, err := decodeTwoMemberNode(nodeFields)
+		nodeKind, partialPath, err := cfg.decodeTwoMemberNode(members[0])
 		if err != nil {
 			return err
 		}
@@ -66,7 +252,7 @@ func DecodeTrieNodeBytes(na ipld.NodeAssembler, src []byte, codec uint64) error
 			if err != nil {
 				return err
 			}
-			if err := unpackExtensionNode(extNodeMA, decoded, codec); err != nil {
+			if err := cfg.unpackExtensionNode(extNodeMA, partialPath, members[1], codec); err != nil {
 				return err
 			}
 			if err := extNodeMA.Finish(); err != nil {
@@ -80,14 +266,14 @@ func DecodeTrieNodeBytes(na ipld.NodeAssembler, src []byte, codec uint64) error
 			if err != nil {
 				return err
 			}
-			if err := unpackLeafNode(leafNodeMA, decoded, codec); err != nil {
+			if err := cfg.unpackLeafNode(leafNodeMA, partialPath, members[1], codec); err != nil {
 				return err
 			}
 			if err := leafNodeMA.Finish(); err != nil {
 				return err
 			}
 		default:
-			return fmt.Errorf("unrecognized trie node type %s", nodeKind.String())
+			return decodeError(ErrMalformedNode, -1, "unrecognized trie node type %s", nodeKind.String())
 		}
 	case 17:
 		if err := ma.AssembleKey().AssignString(BRANCH_NODE.String()); err != nil {
@@ -97,62 +283,105 @@ func DecodeTrieNodeBytes(na ipld.NodeAssembler, src []byte, codec uint64) error
 		if err != nil {
 			return err
 		}
-		if err := unpackBranchNode(branchNodeMA, nodeFields, codec); err != nil {
+		if err := cfg.unpackBranchNode(branchNodeMA, members, codec); err != nil {
 			return err
 		}
 		if err := branchNodeMA.Finish(); err != nil {
 			return err
 		}
+	default:
+		if cfg.Strict {
+			return decodeError(ErrMalformedNode, -1, "trie node RLP list should have 2 or 17 members; got %d", len(members))
+		}
 	}
 	return ma.Finish()
 }
 
-func unpackExtensionNode(ma ipld.MapAssembler, nodeFields []interface{}, codec uint64) error {
-	partialPath, ok := nodeFields[0].([]byte)
-	if !ok {
-		return fmt.Errorf("extension node requires partial path byte slice")
-	}
+func (cfg DecodeOptions) unpackExtensionNode(ma ipld.MapAssembler, partialPath []byte, child member, codec uint64) error {
 	if err := ma.AssembleKey().AssignString("PartialPath"); err != nil {
 		return err
 	}
-	if err := ma.AssembleValue().AssignBytes(partialPath); err != nil { // de-compact partial path first?
+	if err := ma.AssembleValue().AssignBytes(partialPath); err != nil {
 		return err
 	}
 	if err := ma.AssembleKey().AssignString("Child"); err != nil {
 		return err
 	}
-	childLink, ok := nodeFields[1].([]byte)
-	if !ok {
-		return fmt.Errorf("unable to assert second member of extension node to type `[]byte`")
+	childNodeMA, err := ma.AssembleValue().BeginMap(1)
+	if err != nil {
+		return err
+	}
+	if child.list {
+		if err := cfg.unpackEmbeddedNode(childNodeMA, child.val, 1, codec); err != nil {
+			return err
+		}
+		return childNodeMA.Finish()
+	}
+	if len(child.val) != 32 {
+		return decodeError(ErrUnexpectedChildLength, 1, "extension node child of unexpected length %d", len(child.val))
+	}
+	childCodec := cfg.LinkCodecs.Codec(codec)
+	if cfg.LinkCodec != nil {
+		nibbles, err := cfg.PartialPath.nibbles(partialPath, EXTENSION_NODE)
+		if err != nil {
+			return err
+		}
+		childCodec = cfg.linkCodec(childCodec, nibbles, EXTENSION_NODE)
+	}
+	childCID, err := shared.HashToCid(childCodec, cfg.LinkHashes.Type(multihash.KECCAK_256), child.val)
+	if err != nil {
+		return err
 	}
-	childCID := shared.Keccak256ToCid(codec, childLink)
 	childCIDLink := cidlink.Link{Cid: childCID}
-	return ma.AssembleValue().AssignLink(childCIDLink)
+	if err := childNodeMA.AssembleKey().AssignString("Link"); err != nil {
+		return err
+	}
+	if err := childNodeMA.AssembleValue().AssignLink(childCIDLink); err != nil {
+		return err
+	}
+	return childNodeMA.Finish()
 }
 
-func unpackBranchNode(ma ipld.MapAssembler, nodeFields []interface{}, codec uint64) error {
+// linkCodec returns the multicodec type LinkCodec resolves for the child link, or the default if it returns 0
+func (cfg DecodeOptions) linkCodec(def uint64, path []byte, kind NodeKind) uint64 {
+	if codec := cfg.LinkCodec(path, kind); codec != 0 {
+		return codec
+	}
+	return def
+}
+
+func (cfg DecodeOptions) unpackBranchNode(ma ipld.MapAssembler, members []member, codec uint64) error {
 	for i := 0; i < 16; i++ {
 		key := fmt.Sprintf("Child%s", strings.ToUpper(strconv.FormatInt(int64(i), 16)))
 		if err := ma.AssembleKey().AssignString(key); err != nil {
 			return err
 		}
+		if !members[i].list && len(members[i].val) == 0 {
+			if err := ma.AssembleValue().AssignNull(); err != nil {
+				return err
+			}
+			continue
+		}
 		childNodeBuilder := dageth.Type.Child.NewBuilder()
 		childNodeMA, err := childNodeBuilder.BeginMap(1)
 		if err != nil {
 			return err
 		}
-		childLink, ok := nodeFields[i].([]byte)
-		if ok {
+		if !members[i].list {
+			childLink := members[i].val
 			switch len(childLink) {
-			case 0:
-				if err := ma.AssembleValue().AssignNull(); err != nil {
-					return err
-				}
 			case 32:
 				// it's a hash referencing the child node
 				// make CID link from the bytes
 				// assign the link value to the MA
-				childCID := shared.Keccak256ToCid(codec, childLink)
+				childCodec := cfg.LinkCodecs.Codec(codec)
+				if cfg.LinkCodec != nil {
+					childCodec = cfg.linkCodec(childCodec, []byte{byte(i)}, BRANCH_NODE)
+				}
+				childCID, err := shared.HashToCid(childCodec, cfg.LinkHashes.Type(multihash.KECCAK_256), childLink)
+				if err != nil {
+					return err
+				}
 				childCIDLink := cidlink.Link{Cid: childCID}
 				if err := childNodeMA.AssembleKey().AssignString("Link"); err != nil {
 					return err
@@ -167,47 +396,12 @@ func unpackBranchNode(ma ipld.MapAssembler, nodeFields []interface{}, codec uint
 					return err
 				}
 			default:
-				return fmt.Errorf("branch node child of unexpected length %d", len(childLink))
+				return decodeError(ErrUnexpectedChildLength, i, "branch node child of unexpected length %d", len(childLink))
 			}
 			continue
 		}
 		// the child node is included directly
-		// it must be a leaf node, branch and extension will never be less than 32 bytes
-		childLeaf, ok := nodeFields[i].([]interface{})
-		if !ok {
-			return fmt.Errorf("unable to decode branch node entry into []byte or []interface{}")
-		}
-		if len(childLeaf) != 2 {
-			return fmt.Errorf("unexpected number of entries for leaf node; got %d want 2", len(childLeaf))
-		}
-		nodeKind, decodedChildLeaf, err := decodeTwoMemberNode(childLeaf)
-		if err != nil {
-			return err
-		}
-		if nodeKind != LEAF_NODE {
-			return fmt.Errorf("child node included directly in branch must be a leaf; got %s", nodeKind.String())
-		}
-		if err := childNodeMA.AssembleKey().AssignString("TrieNode"); err != nil {
-			return err
-		}
-		childTrieNodeMA, err := childNodeMA.AssembleValue().BeginMap(1)
-		if err != nil {
-			return err
-		}
-		if err := childTrieNodeMA.AssembleKey().AssignString(LEAF_NODE.String()); err != nil {
-			return err
-		}
-		leafNodeMA, err := childTrieNodeMA.AssembleValue().BeginMap(2)
-		if err != nil {
-			return err
-		}
-		if err := unpackLeafNode(leafNodeMA, decodedChildLeaf, codec); err != nil {
-			return err
-		}
-		if err := leafNodeMA.Finish(); err != nil {
-			return err
-		}
-		if err := childTrieNodeMA.Finish(); err != nil {
+		if err := cfg.unpackEmbeddedNode(childNodeMA, members[i].val, i, codec); err != nil {
 			return err
 		}
 		if err := childNodeMA.Finish(); err != nil {
@@ -220,10 +414,10 @@ func unpackBranchNode(ma ipld.MapAssembler, nodeFields []interface{}, codec uint
 	if err := ma.AssembleKey().AssignString("Value"); err != nil {
 		return err
 	}
-	valBytes, ok := nodeFields[16].([]byte)
-	if !ok {
-		return fmt.Errorf("branch node 17th member should be a byte array (val)")
+	if members[16].list {
+		return decodeError(ErrMalformedNode, 16, "branch node 17th member should be a byte array (val)")
 	}
+	valBytes := members[16].val
 	if len(valBytes) == 0 {
 		return ma.AssembleValue().AssignNull()
 	}
@@ -231,21 +425,39 @@ func unpackBranchNode(ma ipld.MapAssembler, nodeFields []interface{}, codec uint
 	if err != nil {
 		return err
 	}
-	if err := unpackValue(valUnionNodeMA, valBytes, codec); err != nil {
-		return err
+	if err := cfg.unpackValue(valUnionNodeMA, valBytes, codec); err != nil {
+		return atField(err, 16)
 	}
 	return valUnionNodeMA.Finish()
 }
 
-func unpackLeafNode(ma ipld.MapAssembler, nodeFields []interface{}, codec uint64) error {
-	partialPath, ok := nodeFields[0].([]byte)
-	if !ok {
-		return fmt.Errorf("leaf node requires partial path byte slice")
+// unpackEmbeddedNode assembles the TrieNode member of the Child union of a branch or extension node from the
+// encoding of the child node held by the node member at the field index. Nodes whose encoding is shorter than 32 bytes
+// are included directly in their parent rather than by hash, which holds for nodes of any kind: leaves with small
+// values, and extension and branch nodes over such leaves when their keys share all but their last nibbles, as in
+// storage tries holding small values. Those are embedded in turn, so they are unpacked recursively.
+func (cfg DecodeOptions) unpackEmbeddedNode(ma ipld.MapAssembler, enc []byte, field int, codec uint64) error {
+	if len(enc) >= 32 {
+		return decodeError(ErrInvalidEmbeddedNode, field, "trie node child included directly must be less than 32 bytes; got %d", len(enc))
+	}
+	members, err := splitEmbeddedNode(enc)
+	if err != nil {
+		return wrapDecodeError(ErrInvalidEmbeddedNode, field, err)
+	}
+	if err := ma.AssembleKey().AssignString("TrieNode"); err != nil {
+		return err
 	}
-	valBytes, ok := nodeFields[1].([]byte)
-	if !ok {
-		return fmt.Errorf("leaf node requires value byte slice")
+	if err := cfg.assembleTrieNode(ma.AssembleValue(), members, codec); err != nil {
+		return atField(err, field)
 	}
+	return nil
+}
+
+func (cfg DecodeOptions) unpackLeafNode(ma ipld.MapAssembler, partialPath []byte, val member, codec uint64) error {
+	if val.list {
+		return decodeError(ErrMalformedNode, 1, "leaf node requires value byte slice")
+	}
+	valBytes := val.val
 	if err := ma.AssembleKey().AssignString("PartialPath"); err != nil {
 		return err
 	}
@@ -259,58 +471,129 @@ func unpackLeafNode(ma ipld.MapAssembler, nodeFields []interface{}, codec uint64
 	if err != nil {
 		return err
 	}
-	if err := unpackValue(valUnionNodeMA, valBytes, codec); err != nil {
-		return err
+	if err := cfg.unpackValue(valUnionNodeMA, valBytes, codec); err != nil {
+		return atField(err, 1)
 	}
 	return valUnionNodeMA.Finish()
 }
 
-func unpackValue(ma ipld.MapAssembler, val []byte, codec uint64) error {
+// unpackValue decodes the value of a leaf or branch node, and reports failures as DecodeErrors
+// to be located at the node member holding the value with atField
+func (cfg DecodeOptions) unpackValue(ma ipld.MapAssembler, val []byte, codec uint64) error {
+	if cfg.RawValues && codec != cid.EthStorageTrie {
+		if err := ma.AssembleKey().AssignString(STORAGE_VALUE.String()); err != nil {
+			return err
+		}
+		return ma.AssembleValue().AssignBytes(val)
+	}
 	switch codec {
 	case cid.EthTxTrie:
 		if err := ma.AssembleKey().AssignString(TX_VALUE.String()); err != nil {
 			return err
 		}
-		return tx.DecodeBytes(ma.AssembleValue(), val)
+		return invalidValue(tx.DecodeBytes(ma.AssembleValue(), val))
 	case cid.EthTxReceiptTrie:
 		if err := ma.AssembleKey().AssignString(RCT_VALUE.String()); err != nil {
 			return err
 		}
-		return rct.DecodeBytes(ma.AssembleValue(), val)
+		return invalidValue(cfg.Receipt.DecodeBytes(ma.AssembleValue(), val))
 	case cid.EthStateTrie:
 		if err := ma.AssembleKey().AssignString(STATE_VALUE.String()); err != nil {
 			return err
 		}
-		return account.DecodeBytes(ma.AssembleValue(), val)
+		return invalidValue(cfg.Account.DecodeBytes(ma.AssembleValue(), val))
 	case cid.EthStorageTrie:
 		if err := ma.AssembleKey().AssignString(STORAGE_VALUE.String()); err != nil {
 			return err
 		}
+		if cfg.UnwrapStorageValues {
+			slotVal, err := cfg.unwrapStorageValue(val)
+			if err != nil {
+				return decodeError(ErrInvalidValue, -1, "storage trie leaf value should be an RLP string: %v", err)
+			}
+			return ma.AssembleValue().AssignBytes(slotVal)
+		}
 		return ma.AssembleValue().AssignBytes(val)
 	case logTrieMulticodec:
 		if err := ma.AssembleKey().AssignString(LOG_VALUE.String()); err != nil {
 			return err
 		}
-		return log.DecodeBytes(ma.AssembleValue(), val)
+		return invalidValue(log.DecodeBytes(ma.AssembleValue(), val))
+	case withdrawalTrieMulticodec:
+		if err := ma.AssembleKey().AssignString(WITHDRAWAL_VALUE.String()); err != nil {
+			return err
+		}
+		return invalidValue(withdrawal.DecodeBytes(ma.AssembleValue(), val))
 	default:
-		return fmt.Errorf("unsupported multicodec type (%d) for eth TrieNode unmarshaller", codec)
+		return decodeError(ErrUnsupportedCodec, -1, "unsupported multicodec type (%d) for eth TrieNode unmarshaller", codec)
 	}
 }
 
-// decodeTwoMemberNode takes a two-member node, discerns its type and decodes its partial path before returning it
-func decodeTwoMemberNode(i []interface{}) (NodeKind, []interface{}, error) {
-	first := i[0].([]byte)
-	decodedPartialPath := shared.CompactToHex(i[0].([]byte))
-	decodedNode := []interface{}{
-		decodedPartialPath,
-		i[1],
-	}
-	switch first[0] / 16 {
-	case '\x00', '\x01':
-		return EXTENSION_NODE, decodedNode, nil
-	case '\x02', '\x03':
-		return LEAF_NODE, decodedNode, nil
-	default:
-		return UNKNOWN_NODE, nil, fmt.Errorf("unknown hex prefix")
+// unwrapStorageValue decodes the RLP string of a storage trie leaf value, the slot value aliases val when borrowing
+func (cfg DecodeOptions) unwrapStorageValue(val []byte) ([]byte, error) {
+	if !cfg.BorrowBytes {
+		var slotVal []byte
+		err := rlp.DecodeBytes(val, &slotVal)
+		return slotVal, err
+	}
+	slotVal, rest, err := rlp.SplitString(val)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, rlp.ErrMoreThanOneValue
+	}
+	return slotVal, nil
+}
+
+// decodeTwoMemberNode takes the first member of a two-member node, discerns the node's type from it and
+// decodes it into the partial path
+func (cfg DecodeOptions) decodeTwoMemberNode(first member) (NodeKind, []byte, error) {
+	if first.list {
+		return UNKNOWN_NODE, nil, decodeError(ErrMalformedNode, 0, "unable to decode two-member node partial path into []byte")
+	}
+	compact := first.val
+	if err := keys.CheckCompact(compact); err != nil {
+		// a set padding nibble does not change the path, so only strict decoding rejects it
+		if cfg.Strict || !errors.Is(err, keys.ErrNonZeroPadding) {
+			return UNKNOWN_NODE, nil, wrapDecodeError(ErrUnknownHexPrefix, 0, err)
+		}
+	}
+	kind := EXTENSION_NODE
+	if compact[0]>>4 >= 2 {
+		kind = LEAF_NODE
+	}
+	decodedPartialPath, err := cfg.PartialPath.fromCompact(compact)
+	if err != nil {
+		return UNKNOWN_NODE, nil, err
+	}
+	return kind, decodedPartialPath, nil
+}
+
+// splitEmbeddedNode splits the encoding of a node embedded in its parent into its members, in place
+func splitEmbeddedNode(enc []byte) ([]member, error) {
+	content, rest, err := rlp.SplitList(enc)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after embedded node", len(rest))
+	}
+	var members []member
+	for len(content) != 0 {
+		kind, val, rest, err := rlp.Split(content)
+		if err != nil {
+			return nil, err
+		}
+		m := member{val: val, list: kind == rlp.List, offset: -1}
+		if m.list {
+			m.val = content[:len(content)-len(rest)]
+		}
+		members = append(members, m)
+		content = rest
+	}
+	if len(members) != 2 && len(members) != 17 {
+		return nil, fmt.Errorf("unexpected number of entries for embedded node; got %d want 2 or 17", len(members))
 	}
+	return members, nil
 }
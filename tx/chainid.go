@@ -0,0 +1,77 @@
+package tx
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipld/go-ipld-prime"
+)
+
+var (
+	big27 = big.NewInt(27)
+	big28 = big.NewInt(28)
+	big35 = big.NewInt(35)
+)
+
+// ChainIDFromV returns the chain ID the V value of an EIP-155 replay protected legacy transaction signature encodes,
+// V = chainID * 2 + 35 + yParity, or nil for the V values 27 and 28 of unprotected signatures.
+// It returns an error for any other V value below 35.
+func ChainIDFromV(v *big.Int) (*big.Int, error) {
+	if v == nil {
+		return nil, fmt.Errorf("missing V value")
+	}
+	if v.Cmp(big27) == 0 || v.Cmp(big28) == 0 {
+		return nil, nil
+	}
+	if v.Cmp(big35) < 0 {
+		return nil, fmt.Errorf("V value %d is neither unprotected nor EIP-155 protected", v)
+	}
+	// (V - 35) / 2 and (V - 36) / 2 are the same chain ID
+	chainID := new(big.Int).Sub(v, big35)
+	return chainID.Rsh(chainID, 1), nil
+}
+
+// IsProtected returns whether the Transaction node is replay protected, that is whether its signature commits to a
+// chain ID. Typed transactions always are, legacy transactions are if their V value follows EIP-155.
+func IsProtected(node ipld.Node) (bool, error) {
+	chainID, err := ChainID(node)
+	return chainID != nil, err
+}
+
+// ChainID returns the chain ID the Transaction node is signed for: the ChainID of typed transactions, or the chain ID
+// the V value of an EIP-155 replay protected legacy transaction encodes. It returns nil for unprotected legacy
+// transactions, which are valid on every chain.
+func ChainID(node ipld.Node) (*big.Int, error) {
+	txTypeNode, err := node.LookupByString("TxType")
+	if err != nil {
+		return nil, err
+	}
+	txType, err := txTypeNode.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(txType) != 1 {
+		return nil, fmt.Errorf("TxType must be a single byte, got %d bytes", len(txType))
+	}
+	if txType[0] != types.LegacyTxType {
+		chainIDNode, err := node.LookupByString("ChainID")
+		if err != nil {
+			return nil, err
+		}
+		chainID, err := chainIDNode.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(chainID), nil
+	}
+	vNode, err := node.LookupByString("V")
+	if err != nil {
+		return nil, err
+	}
+	v, err := vNode.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	return ChainIDFromV(new(big.Int).SetBytes(v))
+}
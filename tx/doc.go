@@ -0,0 +1,9 @@
+/*
+Package tx is the eth-tx codec of transactions of every type.
+
+RecoverSender recovers the sender of a transaction from its signature, and DecodeOptions{RecoverSender: true}
+attaches it as the derived, never encoded, From field. Hash and SigningHash compute the transaction hash and the
+hash its sender signs. ChainID returns the chain ID a transaction is signed for, deriving it from the V value of
+EIP-155 legacy transactions, and DecodeOptions{DeriveChainID: true} fills their ChainID field.
+*/
+package tx
@@ -0,0 +1,39 @@
+package tx
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipld/go-ipld-prime"
+)
+
+// Hash returns the hash of the Transaction node, the conventional transaction hash, which is also the keccak256
+// hash its CID carries
+func Hash(node ipld.Node) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := EncodeTx(tx, node); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+// SigningHash returns the hash the sender of the Transaction node signs for the chain ID. Legacy transactions signed
+// without replay protection sign the same hash on every chain, pass a nil chain ID to get it.
+func SigningHash(node ipld.Node, chainID *big.Int) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := EncodeTx(tx, node); err != nil {
+		return common.Hash{}, err
+	}
+	if chainID == nil {
+		if tx.Type() != types.LegacyTxType {
+			return common.Hash{}, fmt.Errorf("type %d transactions sign a chain ID", tx.Type())
+		}
+		return types.HomesteadSigner{}.Hash(tx), nil
+	}
+	if tx.Type() != types.LegacyTxType && tx.ChainId().Cmp(chainID) != 0 {
+		return common.Hash{}, fmt.Errorf("transaction carries chain ID %d, not %d", tx.ChainId(), chainID)
+	}
+	return types.LatestSignerForChainID(chainID).Hash(tx), nil
+}
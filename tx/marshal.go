@@ -2,14 +2,15 @@ package tx
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"io"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
 	"github.com/ipld/go-ipld-prime"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
@@ -20,28 +21,51 @@ import (
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code 0x93 when this package is invoked via init.
 func Encode(node ipld.Node, w io.Writer) error {
-	// 1KiB can be allocated on the stack, and covers most small nodes
-	// without having to grow the buffer and cause allocations.
-	enc := make([]byte, 0, 1024)
-
-	enc, err := AppendEncode(enc, node)
-	if err != nil {
-		return err
-	}
-	_, err = w.Write(enc)
-	return err
+	return EncodeOptions{}.Encode(node, w)
 }
 
 // AppendEncode is like Encode, but it uses a destination buffer directly.
 // This means less copying of bytes, and if the destination has enough capacity,
 // fewer allocations.
 func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return EncodeOptions{}.AppendEncode(enc, inNode)
+}
+
+// EncodeOptions can be used to customize the behavior of transaction encoding.
+// The zero value is the default behavior used by the registered codec.
+type EncodeOptions struct {
+	// Strict causes the encoder to reject numeric fields not in their canonical form, see shared.CheckNumerics
+	Strict bool
+}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return shared.WriteEncoded(w, node, cfg.AppendEncode)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	// Wrap in a typed node for some basic schema form checking
 	builder := dageth.Type.Transaction.NewBuilder()
 	if err := builder.AssignNode(inNode); err != nil {
 		return enc, err
 	}
 	node := builder.Build()
+	if cfg.Strict {
+		if err := shared.CheckNumerics(node); err != nil {
+			return enc, fmt.Errorf("invalid DAG-ETH Transaction form (%v)", err)
+		}
+	}
 	txType, err := shared.GetTxType(node)
 	if err != nil {
 		return enc, fmt.Errorf("invalid DAG-ETH Transaction form (%v)", err)
@@ -77,6 +101,26 @@ func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 			return enc, fmt.Errorf("invalid DAG-ETH Transaction form (%v)", err)
 		}
 		return enc, nil
+	case types.BlobTxType:
+		tx, err := packBlobTx(node)
+		if err != nil {
+			return enc, fmt.Errorf("invalid DAG-ETH Transaction form (%v)", err)
+		}
+		enc = append(enc, txType)
+		if err := rlp.Encode(wbs, tx); err != nil {
+			return enc, fmt.Errorf("invalid DAG-ETH Transaction form (%v)", err)
+		}
+		return enc, nil
+	case types.SetCodeTxType:
+		tx, err := packSetCodeTx(node)
+		if err != nil {
+			return enc, fmt.Errorf("invalid DAG-ETH Transaction form (%v)", err)
+		}
+		enc = append(enc, txType)
+		if err := rlp.Encode(wbs, tx); err != nil {
+			return enc, fmt.Errorf("invalid DAG-ETH Transaction form (%v)", err)
+		}
+		return enc, nil
 	default:
 		return enc, fmt.Errorf("invalid DAG-ETH Transaction form (unrecognized TxType %d)", txType)
 	}
@@ -92,384 +136,190 @@ func EncodeTx(tx *types.Transaction, inNode ipld.Node) error {
 }
 
 func packLegacyTx(node ipld.Node) (*types.LegacyTx, error) {
+	var err error
 	lTx := &types.LegacyTx{}
-	for _, pFunc := range requiredPackLegacyTxFuncs {
-		if err := pFunc(lTx, node); err != nil {
-			return nil, err
-		}
+	if lTx.Nonce, err = shared.Uint64Field(node, "AccountNonce"); err != nil {
+		return nil, err
 	}
-	return lTx, nil
-}
-
-func packAccessListTx(node ipld.Node) (*types.AccessListTx, error) {
-	alTx := &types.AccessListTx{}
-	for _, pFunc := range requiredPackAccessListTxFuncs {
-		if err := pFunc(alTx, node); err != nil {
-			return nil, err
-		}
+	if lTx.GasPrice, err = shared.BigField(node, "GasPrice"); err != nil {
+		return nil, err
 	}
-	return alTx, nil
-}
-
-func packDynamicFeeTx(node ipld.Node) (*types.DynamicFeeTx, error) {
-	alTx := &types.DynamicFeeTx{}
-	for _, pFunc := range requiredPackDynamicFeeTxFuncs {
-		if err := pFunc(alTx, node); err != nil {
-			return nil, err
-		}
+	if lTx.Gas, err = shared.Uint64Field(node, "GasLimit"); err != nil {
+		return nil, err
 	}
-	return alTx, nil
-}
-
-var requiredPackLegacyTxFuncs = []func(*types.LegacyTx, ipld.Node) error{
-	packAccountNonce,
-	packGasPrice,
-	packGasLimit,
-	packRecipient,
-	packAmount,
-	packData,
-	packSignatureValues,
-}
-
-var requiredPackAccessListTxFuncs = []func(*types.AccessListTx, ipld.Node) error{
-	packChainIDAL,
-	packAccountNonceAL,
-	packGasPriceAL,
-	packGasLimitAL,
-	packRecipientAL,
-	packAmountAL,
-	packDataAL,
-	packAccessListAL,
-	packSignatureValuesAL,
-}
-
-var requiredPackDynamicFeeTxFuncs = []func(*types.DynamicFeeTx, ipld.Node) error{
-	packChainIDDF,
-	packAccountNonceDF,
-	packGasTipCap,
-	packGasFeeCap,
-	packGasLimitDF,
-	packRecipientDF,
-	packAmountDF,
-	packDataDF,
-	packAccessListDF,
-	packSignatureValuesDF,
-}
-
-func packChainIDAL(tx *types.AccessListTx, node ipld.Node) error {
-	chainIDNode, err := node.LookupByString("ChainID")
-	if err != nil {
-		return err
+	if lTx.To, err = recipientField(node); err != nil {
+		return nil, err
 	}
-	chainIDBytes, err := chainIDNode.AsBytes()
-	if err != nil {
-		return err
+	if lTx.Value, err = shared.BigField(node, "Amount"); err != nil {
+		return nil, err
 	}
-	tx.ChainID = new(big.Int).SetBytes(chainIDBytes)
-	return nil
-}
-
-func packChainIDDF(tx *types.DynamicFeeTx, node ipld.Node) error {
-	chainIDNode, err := node.LookupByString("ChainID")
-	if err != nil {
-		return err
+	if lTx.Data, err = dataField(node); err != nil {
+		return nil, err
 	}
-	chainIDBytes, err := chainIDNode.AsBytes()
-	if err != nil {
-		return err
+	if lTx.V, lTx.R, lTx.S, err = createVRS(node); err != nil {
+		return nil, err
 	}
-	tx.ChainID = new(big.Int).SetBytes(chainIDBytes)
-	return nil
+	return lTx, nil
 }
 
-func packAccountNonce(tx *types.LegacyTx, node ipld.Node) error {
-	nonceNode, err := node.LookupByString("AccountNonce")
-	if err != nil {
-		return err
+func packAccessListTx(node ipld.Node) (*types.AccessListTx, error) {
+	var err error
+	alTx := &types.AccessListTx{}
+	if alTx.ChainID, err = shared.BigField(node, "ChainID"); err != nil {
+		return nil, err
 	}
-	nonceBytes, err := nonceNode.AsBytes()
-	if err != nil {
-		return err
+	if alTx.Nonce, err = shared.Uint64Field(node, "AccountNonce"); err != nil {
+		return nil, err
 	}
-	nonce := binary.BigEndian.Uint64(nonceBytes)
-	tx.Nonce = nonce
-	return nil
-}
-
-func packAccountNonceAL(tx *types.AccessListTx, node ipld.Node) error {
-	nonceNode, err := node.LookupByString("AccountNonce")
-	if err != nil {
-		return err
+	if alTx.GasPrice, err = shared.BigField(node, "GasPrice"); err != nil {
+		return nil, err
 	}
-	nonceBytes, err := nonceNode.AsBytes()
-	if err != nil {
-		return err
+	if alTx.Gas, err = shared.Uint64Field(node, "GasLimit"); err != nil {
+		return nil, err
 	}
-	nonce := binary.BigEndian.Uint64(nonceBytes)
-	tx.Nonce = nonce
-	return nil
-}
-
-func packAccountNonceDF(tx *types.DynamicFeeTx, node ipld.Node) error {
-	nonceNode, err := node.LookupByString("AccountNonce")
-	if err != nil {
-		return err
+	if alTx.To, err = recipientField(node); err != nil {
+		return nil, err
 	}
-	nonceBytes, err := nonceNode.AsBytes()
-	if err != nil {
-		return err
+	if alTx.Value, err = shared.BigField(node, "Amount"); err != nil {
+		return nil, err
 	}
-	nonce := binary.BigEndian.Uint64(nonceBytes)
-	tx.Nonce = nonce
-	return nil
-}
-
-func packGasPrice(tx *types.LegacyTx, node ipld.Node) error {
-	gpNode, err := node.LookupByString("GasPrice")
-	if err != nil {
-		return err
+	if alTx.Data, err = dataField(node); err != nil {
+		return nil, err
 	}
-	gpBytes, err := gpNode.AsBytes()
-	if err != nil {
-		return err
+	if alTx.AccessList, err = createAccessList(node); err != nil {
+		return nil, err
 	}
-	gp := new(big.Int).SetBytes(gpBytes)
-	tx.GasPrice = gp
-	return nil
+	if alTx.V, alTx.R, alTx.S, err = createVRS(node); err != nil {
+		return nil, err
+	}
+	return alTx, nil
 }
 
-func packGasPriceAL(tx *types.AccessListTx, node ipld.Node) error {
-	gpNode, err := node.LookupByString("GasPrice")
-	if err != nil {
-		return err
+func packDynamicFeeTx(node ipld.Node) (*types.DynamicFeeTx, error) {
+	var err error
+	dfTx := &types.DynamicFeeTx{}
+	if dfTx.ChainID, err = shared.BigField(node, "ChainID"); err != nil {
+		return nil, err
 	}
-	gpBytes, err := gpNode.AsBytes()
-	if err != nil {
-		return err
+	if dfTx.Nonce, err = shared.Uint64Field(node, "AccountNonce"); err != nil {
+		return nil, err
 	}
-	gp := new(big.Int).SetBytes(gpBytes)
-	tx.GasPrice = gp
-	return nil
-}
-
-func packGasTipCap(tx *types.DynamicFeeTx, node ipld.Node) error {
-	gtcNode, err := node.LookupByString("GasTipCap")
-	if err != nil {
-		return err
+	if dfTx.GasTipCap, err = shared.BigField(node, "GasTipCap"); err != nil {
+		return nil, err
 	}
-	gtcBytes, err := gtcNode.AsBytes()
-	if err != nil {
-		return err
+	if dfTx.GasFeeCap, err = shared.BigField(node, "GasFeeCap"); err != nil {
+		return nil, err
 	}
-	gtc := new(big.Int).SetBytes(gtcBytes)
-	tx.GasTipCap = gtc
-	return nil
-}
-
-func packGasFeeCap(tx *types.DynamicFeeTx, node ipld.Node) error {
-	gfcNode, err := node.LookupByString("GasFeeCap")
-	if err != nil {
-		return err
+	if dfTx.Gas, err = shared.Uint64Field(node, "GasLimit"); err != nil {
+		return nil, err
 	}
-	gfcBytes, err := gfcNode.AsBytes()
-	if err != nil {
-		return err
+	if dfTx.To, err = recipientField(node); err != nil {
+		return nil, err
 	}
-	gfc := new(big.Int).SetBytes(gfcBytes)
-	tx.GasFeeCap = gfc
-	return nil
-}
-
-func packGasLimit(tx *types.LegacyTx, node ipld.Node) error {
-	glNode, err := node.LookupByString("GasLimit")
-	if err != nil {
-		return err
+	if dfTx.Value, err = shared.BigField(node, "Amount"); err != nil {
+		return nil, err
 	}
-	glBytes, err := glNode.AsBytes()
-	if err != nil {
-		return err
+	if dfTx.Data, err = dataField(node); err != nil {
+		return nil, err
 	}
-	gl := binary.BigEndian.Uint64(glBytes)
-	tx.Gas = gl
-	return nil
-}
-
-func packGasLimitAL(tx *types.AccessListTx, node ipld.Node) error {
-	glNode, err := node.LookupByString("GasLimit")
-	if err != nil {
-		return err
+	if dfTx.AccessList, err = createAccessList(node); err != nil {
+		return nil, err
 	}
-	glBytes, err := glNode.AsBytes()
-	if err != nil {
-		return err
+	if dfTx.V, dfTx.R, dfTx.S, err = createVRS(node); err != nil {
+		return nil, err
 	}
-	gl := binary.BigEndian.Uint64(glBytes)
-	tx.Gas = gl
-	return nil
+	return dfTx, nil
 }
 
-func packGasLimitDF(tx *types.DynamicFeeTx, node ipld.Node) error {
-	glNode, err := node.LookupByString("GasLimit")
-	if err != nil {
-		return err
+func packBlobTx(node ipld.Node) (*types.BlobTx, error) {
+	var err error
+	blTx := &types.BlobTx{}
+	if blTx.ChainID, err = shared.Uint256Field(node, "ChainID"); err != nil {
+		return nil, err
 	}
-	glBytes, err := glNode.AsBytes()
-	if err != nil {
-		return err
+	if blTx.Nonce, err = shared.Uint64Field(node, "AccountNonce"); err != nil {
+		return nil, err
 	}
-	gl := binary.BigEndian.Uint64(glBytes)
-	tx.Gas = gl
-	return nil
-}
-
-func packRecipient(tx *types.LegacyTx, node ipld.Node) error {
-	rNode, err := node.LookupByString("Recipient")
-	if err != nil {
-		return err
+	if blTx.GasTipCap, err = shared.Uint256Field(node, "GasTipCap"); err != nil {
+		return nil, err
 	}
-	if rNode.IsNull() {
-		return nil
+	if blTx.GasFeeCap, err = shared.Uint256Field(node, "GasFeeCap"); err != nil {
+		return nil, err
 	}
-	rBytes, err := rNode.AsBytes()
-	if err != nil {
-		return err
+	if blTx.Gas, err = shared.Uint64Field(node, "GasLimit"); err != nil {
+		return nil, err
 	}
-	recipient := common.BytesToAddress(rBytes)
-	tx.To = &recipient
-	return nil
-}
-
-func packRecipientAL(tx *types.AccessListTx, node ipld.Node) error {
-	rNode, err := node.LookupByString("Recipient")
+	to, err := recipientField(node)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if rNode.IsNull() {
-		return nil
+	if to == nil {
+		return nil, fmt.Errorf("blob transaction cannot be a contract creation")
 	}
-	rBytes, err := rNode.AsBytes()
-	if err != nil {
-		return err
+	blTx.To = *to
+	if blTx.Value, err = shared.Uint256Field(node, "Amount"); err != nil {
+		return nil, err
 	}
-	recipient := common.BytesToAddress(rBytes)
-	tx.To = &recipient
-	return nil
-}
-
-func packRecipientDF(tx *types.DynamicFeeTx, node ipld.Node) error {
-	rNode, err := node.LookupByString("Recipient")
-	if err != nil {
-		return err
+	if blTx.Data, err = dataField(node); err != nil {
+		return nil, err
 	}
-	if rNode.IsNull() {
-		return nil
+	if blTx.AccessList, err = createAccessList(node); err != nil {
+		return nil, err
 	}
-	rBytes, err := rNode.AsBytes()
-	if err != nil {
-		return err
+	if blTx.BlobFeeCap, err = shared.Uint256Field(node, "MaxFeePerBlobGas"); err != nil {
+		return nil, err
 	}
-	recipient := common.BytesToAddress(rBytes)
-	tx.To = &recipient
-	return nil
-}
-
-func packAmount(tx *types.LegacyTx, node ipld.Node) error {
-	aNode, err := node.LookupByString("Amount")
-	if err != nil {
-		return err
+	if blTx.BlobHashes, err = createBlobVersionedHashes(node); err != nil {
+		return nil, err
 	}
-	aBytes, err := aNode.AsBytes()
-	if err != nil {
-		return err
+	if blTx.V, blTx.R, blTx.S, err = createUint256VRS(node); err != nil {
+		return nil, err
 	}
-	amount := new(big.Int).SetBytes(aBytes)
-	tx.Value = amount
-	return nil
+	return blTx, nil
 }
 
-func packAmountAL(tx *types.AccessListTx, node ipld.Node) error {
-	aNode, err := node.LookupByString("Amount")
-	if err != nil {
-		return err
+func packSetCodeTx(node ipld.Node) (*types.SetCodeTx, error) {
+	var err error
+	scTx := &types.SetCodeTx{}
+	if scTx.ChainID, err = shared.Uint256Field(node, "ChainID"); err != nil {
+		return nil, err
 	}
-	aBytes, err := aNode.AsBytes()
-	if err != nil {
-		return err
+	if scTx.Nonce, err = shared.Uint64Field(node, "AccountNonce"); err != nil {
+		return nil, err
 	}
-	amount := new(big.Int).SetBytes(aBytes)
-	tx.Value = amount
-	return nil
-}
-
-func packAmountDF(tx *types.DynamicFeeTx, node ipld.Node) error {
-	aNode, err := node.LookupByString("Amount")
-	if err != nil {
-		return err
+	if scTx.GasTipCap, err = shared.Uint256Field(node, "GasTipCap"); err != nil {
+		return nil, err
 	}
-	aBytes, err := aNode.AsBytes()
-	if err != nil {
-		return err
+	if scTx.GasFeeCap, err = shared.Uint256Field(node, "GasFeeCap"); err != nil {
+		return nil, err
 	}
-	amount := new(big.Int).SetBytes(aBytes)
-	tx.Value = amount
-	return nil
-}
-
-func packData(tx *types.LegacyTx, node ipld.Node) error {
-	dNode, err := node.LookupByString("Data")
-	if err != nil {
-		return err
+	if scTx.Gas, err = shared.Uint64Field(node, "GasLimit"); err != nil {
+		return nil, err
 	}
-	dBytes, err := dNode.AsBytes()
+	to, err := recipientField(node)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	tx.Data = dBytes
-	return nil
-}
-
-func packDataAL(tx *types.AccessListTx, node ipld.Node) error {
-	dNode, err := node.LookupByString("Data")
-	if err != nil {
-		return err
+	if to == nil {
+		return nil, fmt.Errorf("set code transaction cannot be a contract creation")
 	}
-	dBytes, err := dNode.AsBytes()
-	if err != nil {
-		return err
+	scTx.To = *to
+	if scTx.Value, err = shared.Uint256Field(node, "Amount"); err != nil {
+		return nil, err
 	}
-	tx.Data = dBytes
-	return nil
-}
-
-func packDataDF(tx *types.DynamicFeeTx, node ipld.Node) error {
-	dNode, err := node.LookupByString("Data")
-	if err != nil {
-		return err
+	if scTx.Data, err = dataField(node); err != nil {
+		return nil, err
 	}
-	dBytes, err := dNode.AsBytes()
-	if err != nil {
-		return err
+	if scTx.AccessList, err = createAccessList(node); err != nil {
+		return nil, err
 	}
-	tx.Data = dBytes
-	return nil
-}
-
-func packAccessListAL(tx *types.AccessListTx, node ipld.Node) error {
-	accessList, err := createAccessList(node)
-	if err != nil {
-		return err
+	if scTx.AuthList, err = createAuthorizationList(node); err != nil {
+		return nil, err
 	}
-	tx.AccessList = accessList
-	return nil
-}
-
-func packAccessListDF(tx *types.DynamicFeeTx, node ipld.Node) error {
-	accessList, err := createAccessList(node)
-	if err != nil {
-		return err
+	if scTx.V, scTx.R, scTx.S, err = createUint256VRS(node); err != nil {
+		return nil, err
 	}
-	tx.AccessList = accessList
-	return nil
+	return scTx, nil
 }
 
 func createAccessList(node ipld.Node) (types.AccessList, error) {
@@ -492,7 +342,10 @@ func createAccessList(node ipld.Node) (types.AccessList, error) {
 		if err != nil {
 			return nil, err
 		}
-		addr := common.BytesToAddress(addrBytes)
+		addr, err := shared.BytesToAddress(addrBytes)
+		if err != nil {
+			return nil, err
+		}
 
 		storageKeysNode, err := accessElementNode.LookupByString("StorageKeys")
 		if err != nil {
@@ -509,7 +362,10 @@ func createAccessList(node ipld.Node) (types.AccessList, error) {
 			if err != nil {
 				return nil, err
 			}
-			storageKeys[index] = common.BytesToHash(storageKeyBytes)
+			storageKeys[index], err = shared.BytesToHash(storageKeyBytes)
+			if err != nil {
+				return nil, err
+			}
 		}
 		accessElement := types.AccessTuple{
 			Address:     addr,
@@ -521,65 +377,164 @@ func createAccessList(node ipld.Node) (types.AccessList, error) {
 }
 
 func createVRS(node ipld.Node) (*big.Int, *big.Int, *big.Int, error) {
-	vNode, err := node.LookupByString("V")
+	v, err := shared.BigField(node, "V")
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	vBytes, err := vNode.AsBytes()
+	r, err := shared.BigField(node, "R")
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	v := new(big.Int).SetBytes(vBytes)
-	rNode, err := node.LookupByString("R")
+	s, err := shared.BigField(node, "S")
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	return v, r, s, nil
+}
+
+// recipientField reads the Recipient of the transaction, nil for contract creations
+func recipientField(node ipld.Node) (*common.Address, error) {
+	rNode, err := node.LookupByString("Recipient")
+	if err != nil {
+		return nil, err
+	}
+	if rNode.IsNull() {
+		return nil, nil
+	}
 	rBytes, err := rNode.AsBytes()
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
-	r := new(big.Int).SetBytes(rBytes)
-	sNode, err := node.LookupByString("S")
+	recipient, err := shared.BytesToAddress(rBytes)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
-	sBytes, err := sNode.AsBytes()
+	return &recipient, nil
+}
+
+func dataField(node ipld.Node) ([]byte, error) {
+	dNode, err := node.LookupByString("Data")
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
-	s := new(big.Int).SetBytes(sBytes)
-	return v, r, s, nil
+	return dNode.AsBytes()
 }
 
-func packSignatureValues(tx *types.LegacyTx, node ipld.Node) error {
-	v, r, s, err := createVRS(node)
+func createBlobVersionedHashes(node ipld.Node) ([]common.Hash, error) {
+	bhNode, err := node.LookupByString("BlobVersionedHashes")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	tx.V = v
-	tx.R = r
-	tx.S = s
-	return nil
+	blobHashes := make([]common.Hash, bhNode.Length())
+	blobHashesIt := bhNode.ListIterator()
+	for !blobHashesIt.Done() {
+		index, blobHashNode, err := blobHashesIt.Next()
+		if err != nil {
+			return nil, err
+		}
+		blobHashBytes, err := blobHashNode.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		blobHashes[index], err = shared.BytesToHash(blobHashBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return blobHashes, nil
 }
 
-func packSignatureValuesAL(tx *types.AccessListTx, node ipld.Node) error {
+// createUint256VRS reads the signature values of the transaction types that hold them as 256 bit values
+func createUint256VRS(node ipld.Node) (*uint256.Int, *uint256.Int, *uint256.Int, error) {
 	v, r, s, err := createVRS(node)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
-	tx.V = v
-	tx.R = r
-	tx.S = s
-	return nil
+	var overflow [3]bool
+	var vrs [3]*uint256.Int
+	vrs[0], overflow[0] = uint256.FromBig(v)
+	vrs[1], overflow[1] = uint256.FromBig(r)
+	vrs[2], overflow[2] = uint256.FromBig(s)
+	if overflow[0] || overflow[1] || overflow[2] {
+		return nil, nil, nil, fmt.Errorf("signature values overflow 256 bits")
+	}
+	return vrs[0], vrs[1], vrs[2], nil
 }
 
-func packSignatureValuesDF(tx *types.DynamicFeeTx, node ipld.Node) error {
-	v, r, s, err := createVRS(node)
+func createAuthorizationList(node ipld.Node) ([]types.SetCodeAuthorization, error) {
+	alNode, err := node.LookupByString("AuthorizationList")
+	if err != nil {
+		return nil, err
+	}
+	authList := make([]types.SetCodeAuthorization, alNode.Length())
+	authListIt := alNode.ListIterator()
+	for !authListIt.Done() {
+		index, authNode, err := authListIt.Next()
+		if err != nil {
+			return nil, err
+		}
+		chainID, err := shared.Uint256Field(authNode, "ChainID")
+		if err != nil {
+			return nil, err
+		}
+		addrNode, err := authNode.LookupByString("Address")
+		if err != nil {
+			return nil, err
+		}
+		addrBytes, err := addrNode.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		addr, err := shared.BytesToAddress(addrBytes)
+		if err != nil {
+			return nil, err
+		}
+		nonce, err := shared.Uint64Field(authNode, "Nonce")
+		if err != nil {
+			return nil, err
+		}
+		yParity, err := shared.Uint64Field(authNode, "YParity")
+		if err != nil {
+			return nil, err
+		}
+		if yParity > 0xff {
+			return nil, fmt.Errorf("authorization YParity %d overflows a byte", yParity)
+		}
+		r, err := shared.Uint256Field(authNode, "R")
+		if err != nil {
+			return nil, err
+		}
+		s, err := shared.Uint256Field(authNode, "S")
+		if err != nil {
+			return nil, err
+		}
+		authList[index] = types.SetCodeAuthorization{
+			ChainID: *chainID,
+			Address: addr,
+			Nonce:   nonce,
+			V:       uint8(yParity),
+			R:       *r,
+			S:       *s,
+		}
+	}
+	return authList, nil
+}
+
+// ValidateAuthorizationList checks the signature values of every authorization in an EIP-7702 transaction node.
+// Decode and Encode do not apply this check, since an authorization with an invalid signature is skipped
+// during execution rather than invalidating the transaction that carries it.
+func ValidateAuthorizationList(node ipld.Node) error {
+	authList, err := createAuthorizationList(node)
 	if err != nil {
 		return err
 	}
-	tx.V = v
-	tx.R = r
-	tx.S = s
+	for i, auth := range authList {
+		if auth.V > 1 {
+			return fmt.Errorf("authorization %d has invalid YParity %d", i, auth.V)
+		}
+		if !crypto.ValidateSignatureValues(auth.V, auth.R.ToBig(), auth.S.ToBig(), true) {
+			return fmt.Errorf("authorization %d has invalid signature values", i)
+		}
+	}
 	return nil
 }
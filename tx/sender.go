@@ -0,0 +1,28 @@
+package tx
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipld/go-ipld-prime"
+)
+
+// RecoverSender recovers the address of the sender of the Transaction node from its signature
+func RecoverSender(node ipld.Node) (common.Address, error) {
+	tx := new(types.Transaction)
+	if err := EncodeTx(tx, node); err != nil {
+		return common.Address{}, err
+	}
+	return Sender(tx)
+}
+
+// Sender recovers the address of the sender of the go-ethereum Transaction from its signature.
+// Typed transactions and EIP-155 replay protected legacy transactions are recovered against the chain ID they carry,
+// while unprotected legacy transactions are recovered the way they were before EIP-155, accepting the high S values
+// of Frontier signatures.
+func Sender(tx *types.Transaction) (common.Address, error) {
+	var signer types.Signer = types.FrontierSigner{}
+	if tx.Protected() {
+		signer = types.LatestSignerForChainID(tx.ChainId())
+	}
+	return types.Sender(signer, tx)
+}
@@ -8,10 +8,15 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/holiman/uint256"
 	"github.com/ipld/go-ipld-prime"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
 	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/testutil"
 	"github.com/vulcanize/go-codec-dageth/tx"
 )
 
@@ -99,6 +104,20 @@ type AccessElement struct {
 
 type AccessList [AccessElement]
 
+type BlobHashes [Hash]
+
+# An EIP-7702 authorization to set the code of the signing account to that of Address
+type Authorization struct {
+	ChainID BigInt
+	Address Address
+	Nonce   Uint
+	YParity Uint
+	R       BigInt
+	S       BigInt
+}
+
+type AuthorizationList [Authorization]
+
 type Transaction struct {
 	Type         TxType
 	ChainID      nullable BigInt # null unless the transaction is an EIP-2930 or EIP-1559 transaction
@@ -111,11 +130,16 @@ type Transaction struct {
 	Amount       BigInt
 	Data         Bytes
 	AccessList   nullable AccessList # null unless the transaction is an EIP-2930 or EIP-1559 transaction
+	MaxFeePerBlobGas    nullable BigInt # null unless the transaction is an EIP-4844 transaction
+	BlobVersionedHashes nullable BlobHashes # null unless the transaction is an EIP-4844 transaction
+	AuthorizationList   nullable AuthorizationList # null unless the transaction is an EIP-7702 transaction
 
 	# Signature values
 	V            BigInt
 	R            BigInt
 	S            BigInt
+
+	From         optional Address
 }
 */
 
@@ -137,6 +161,7 @@ func TestTransactionCodec(t *testing.T) {
 	shared.TestAccessListTransactionNodeContent(t, accessListTxNode, accessListTx)
 	shared.TestDynamicFeeTransactionNodeContent(t, dynamicFeeTxNode, dynamicFeeTx)
 	shared.TestLegacyTransactionNodeContent(t, legacyTxNode, legacyTx)
+	testAccessListPathLookup(t)
 	testTransactionEncoding(t)
 }
 
@@ -163,6 +188,31 @@ func testTransactionDecoding(t *testing.T) {
 	dynamicFeeTxNode = dfTxBuilder.Build()
 }
 
+func testAccessListPathLookup(t *testing.T) {
+	// storage keys touched by a transaction can be selected straight from the typed node
+	path := ipld.ParsePath("AccessList/0/StorageKeys/1")
+	for name, txNode := range map[string]ipld.Node{
+		"access list": accessListTxNode,
+		"dynamic fee": dynamicFeeTxNode,
+	} {
+		if err := traversal.Focus(txNode, path, func(_ traversal.Progress, n ipld.Node) error {
+			storageKey, err := n.AsBytes()
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(storageKey, testStorageKey2.Bytes()) {
+				t.Errorf("%s transaction storage key (%x) does not match expected storage key (%x)", name, storageKey, testStorageKey2.Bytes())
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("unable to select storage key from %s transaction access list: %v", name, err)
+		}
+	}
+	if err := traversal.Focus(legacyTxNode, path, func(traversal.Progress, ipld.Node) error { return nil }); err == nil {
+		t.Errorf("expected selecting a storage key from a legacy transaction to fail")
+	}
+}
+
 func testTransactionEncoding(t *testing.T) {
 	legTxWriter := new(bytes.Buffer)
 	if err := tx.Encode(legacyTxNode, legTxWriter); err != nil {
@@ -191,3 +241,555 @@ func testTransactionEncoding(t *testing.T) {
 		t.Errorf("dynamic fee transaction encoding (%x) does not match the expected consensus encoding (%x)", dfTxBytes, dfTxConsensusEnc)
 	}
 }
+
+func TestDynamicFeeContractCreationTransaction(t *testing.T) {
+	contractCreationTx, err := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     4,
+		Value:     big.NewInt(0),
+		Gas:       1000000,
+		GasTipCap: big.NewInt(2000000000),
+		GasFeeCap: big.NewInt(150000000000),
+		Data:      common.FromHex("6080604052348015600f57600080fd5b50603f80601d6000396000f3fe"),
+	}).WithSignature(
+		types.NewLondonSigner(big.NewInt(1)),
+		common.Hex2Bytes("c9519f4f2b30335884581971573fadf60c6204f59a911df35ee8a540456b266032f1e8e2c5dd761f9e4f88f41c8310aeaba26a8bfcdacfedfa12ec3862d3752100"),
+	)
+	if err != nil {
+		t.Fatalf("unable to sign dynamic fee contract creation transaction: %v", err)
+	}
+	consensusEnc, err := contractCreationTx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal dynamic fee contract creation transaction binary: %v", err)
+	}
+
+	txBuilder := dageth.Type.Transaction.NewBuilder()
+	if err := tx.DecodeBytes(txBuilder, consensusEnc); err != nil {
+		t.Fatalf("unable to decode dynamic fee contract creation transaction into an IPLD node: %v", err)
+	}
+	txNode := txBuilder.Build()
+	shared.TestDynamicFeeTransactionNodeContent(t, txNode, contractCreationTx)
+	gasPriceNode, err := txNode.LookupByString("GasPrice")
+	if err != nil {
+		t.Fatalf("transaction missing GasPrice: %v", err)
+	}
+	if !gasPriceNode.IsNull() {
+		t.Errorf("dynamic fee transaction GasPrice should be null")
+	}
+
+	txBytes, err := tx.AppendEncode(nil, txNode)
+	if err != nil {
+		t.Fatalf("unable to encode dynamic fee contract creation transaction: %v", err)
+	}
+	if !bytes.Equal(txBytes, consensusEnc) {
+		t.Errorf("dynamic fee contract creation transaction encoding (%x) does not match the expected consensus encoding (%x)", txBytes, consensusEnc)
+	}
+	var decodedTx types.Transaction
+	if err := tx.EncodeTx(&decodedTx, txNode); err != nil {
+		t.Fatalf("unable to pack dynamic fee contract creation transaction: %v", err)
+	}
+	if decodedTx.Hash() != contractCreationTx.Hash() {
+		t.Errorf("dynamic fee contract creation transaction hash (%s) does not match expected hash (%s)", decodedTx.Hash().Hex(), contractCreationTx.Hash().Hex())
+	}
+}
+
+func TestBlobTransaction(t *testing.T) {
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{{}},
+		Commitments: []kzg4844.Commitment{{0xc0}},
+		Proofs:      []kzg4844.Proof{{0xc0}},
+	}
+	blobTx, err := types.NewTx(&types.BlobTx{
+		ChainID:    uint256.NewInt(1),
+		Nonce:      5,
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(2),
+		Gas:        25000,
+		To:         testAddr,
+		Value:      uint256.NewInt(10),
+		Data:       common.FromHex("5544"),
+		AccessList: types.AccessList{{Address: testAddr2, StorageKeys: []common.Hash{testStorageKey}}},
+		BlobFeeCap: uint256.NewInt(3),
+		BlobHashes: sidecar.BlobHashes(),
+	}).WithSignature(
+		types.NewCancunSigner(big.NewInt(1)),
+		common.Hex2Bytes("c9519f4f2b30335884581971573fadf60c6204f59a911df35ee8a540456b266032f1e8e2c5dd761f9e4f88f41c8310aeaba26a8bfcdacfedfa12ec3862d3752101"),
+	)
+	if err != nil {
+		t.Fatalf("unable to sign blob transaction: %v", err)
+	}
+	consensusEnc, err := blobTx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal blob transaction binary: %v", err)
+	}
+
+	txBuilder := dageth.Type.Transaction.NewBuilder()
+	if err := tx.DecodeBytes(txBuilder, consensusEnc); err != nil {
+		t.Fatalf("unable to decode blob transaction into an IPLD node: %v", err)
+	}
+	txNode := txBuilder.Build()
+	shared.TestDynamicFeeTransactionNodeContent(t, txNode, blobTx)
+	blobFeeCapNode, err := txNode.LookupByString("MaxFeePerBlobGas")
+	if err != nil {
+		t.Fatalf("transaction missing MaxFeePerBlobGas: %v", err)
+	}
+	blobFeeCapBytes, err := blobFeeCapNode.AsBytes()
+	if err != nil {
+		t.Fatalf("transaction MaxFeePerBlobGas should be of type Bytes: %v", err)
+	}
+	if !bytes.Equal(blobFeeCapBytes, blobTx.BlobGasFeeCap().Bytes()) {
+		t.Errorf("transaction max fee per blob gas (%x) does not match expected max fee per blob gas (%x)", blobFeeCapBytes, blobTx.BlobGasFeeCap().Bytes())
+	}
+	blobHashesNode, err := txNode.LookupByString("BlobVersionedHashes")
+	if err != nil {
+		t.Fatalf("transaction missing BlobVersionedHashes: %v", err)
+	}
+	if blobHashesNode.Length() != int64(len(blobTx.BlobHashes())) {
+		t.Fatalf("transaction blob versioned hashes should have %d entries", len(blobTx.BlobHashes()))
+	}
+	blobHashNode, err := blobHashesNode.LookupByIndex(0)
+	if err != nil {
+		t.Fatalf("unable to look up blob versioned hash: %v", err)
+	}
+	blobHashBytes, err := blobHashNode.AsBytes()
+	if err != nil {
+		t.Fatalf("transaction blob versioned hash should be of type Bytes: %v", err)
+	}
+	if !bytes.Equal(blobHashBytes, blobTx.BlobHashes()[0].Bytes()) {
+		t.Errorf("transaction blob versioned hash (%x) does not match expected hash (%x)", blobHashBytes, blobTx.BlobHashes()[0].Bytes())
+	}
+
+	txBytes, err := tx.AppendEncode(nil, txNode)
+	if err != nil {
+		t.Fatalf("unable to encode blob transaction: %v", err)
+	}
+	if !bytes.Equal(txBytes, consensusEnc) {
+		t.Errorf("blob transaction encoding (%x) does not match the expected consensus encoding (%x)", txBytes, consensusEnc)
+	}
+
+	// the network form carries the sidecar, which is not part of the transaction's consensus encoding
+	networkEnc, err := blobTx.WithBlobTxSidecar(sidecar).MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal blob transaction network binary: %v", err)
+	}
+	if err := tx.DecodeBytes(dageth.Type.Transaction.NewBuilder(), networkEnc); err == nil {
+		t.Errorf("expected decoding a blob transaction with its sidecar to fail")
+	}
+}
+
+func TestSetCodeTransaction(t *testing.T) {
+	authKey, err := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	if err != nil {
+		t.Fatalf("unable to load authority key: %v", err)
+	}
+	auth, err := types.SignSetCode(authKey, types.SetCodeAuthorization{
+		ChainID: *uint256.NewInt(1),
+		Address: testAddr2,
+		Nonce:   7,
+	})
+	if err != nil {
+		t.Fatalf("unable to sign authorization: %v", err)
+	}
+	setCodeTx, err := types.NewTx(&types.SetCodeTx{
+		ChainID:    uint256.NewInt(1),
+		Nonce:      6,
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(2),
+		Gas:        50000,
+		To:         testAddr,
+		Value:      uint256.NewInt(10),
+		Data:       common.FromHex("5544"),
+		AccessList: types.AccessList{{Address: testAddr2, StorageKeys: []common.Hash{testStorageKey}}},
+		AuthList:   []types.SetCodeAuthorization{auth},
+	}).WithSignature(
+		types.NewPragueSigner(big.NewInt(1)),
+		common.Hex2Bytes("c9519f4f2b30335884581971573fadf60c6204f59a911df35ee8a540456b266032f1e8e2c5dd761f9e4f88f41c8310aeaba26a8bfcdacfedfa12ec3862d3752101"),
+	)
+	if err != nil {
+		t.Fatalf("unable to sign set code transaction: %v", err)
+	}
+	consensusEnc, err := setCodeTx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal set code transaction binary: %v", err)
+	}
+
+	txBuilder := dageth.Type.Transaction.NewBuilder()
+	if err := tx.DecodeBytes(txBuilder, consensusEnc); err != nil {
+		t.Fatalf("unable to decode set code transaction into an IPLD node: %v", err)
+	}
+	txNode := txBuilder.Build()
+	shared.TestDynamicFeeTransactionNodeContent(t, txNode, setCodeTx)
+	authNode, err := traversal.Get(txNode, ipld.ParsePath("AuthorizationList/0"))
+	if err != nil {
+		t.Fatalf("unable to look up authorization: %v", err)
+	}
+	for _, field := range []struct {
+		key string
+		val []byte
+	}{
+		{"ChainID", auth.ChainID.Bytes()},
+		{"Address", auth.Address.Bytes()},
+		{"Nonce", []byte{0, 0, 0, 0, 0, 0, 0, 7}},
+		{"YParity", []byte{0, 0, 0, 0, 0, 0, 0, auth.V}},
+		{"R", auth.R.Bytes()},
+		{"S", auth.S.Bytes()},
+	} {
+		fieldNode, err := authNode.LookupByString(field.key)
+		if err != nil {
+			t.Fatalf("authorization missing %s: %v", field.key, err)
+		}
+		fieldBytes, err := fieldNode.AsBytes()
+		if err != nil {
+			t.Fatalf("authorization %s should be of type Bytes: %v", field.key, err)
+		}
+		if !bytes.Equal(fieldBytes, field.val) {
+			t.Errorf("authorization %s (%x) does not match expected %s (%x)", field.key, fieldBytes, field.key, field.val)
+		}
+	}
+	if err := tx.ValidateAuthorizationList(txNode); err != nil {
+		t.Errorf("expected authorization list to be valid: %v", err)
+	}
+
+	txBytes, err := tx.AppendEncode(nil, txNode)
+	if err != nil {
+		t.Fatalf("unable to encode set code transaction: %v", err)
+	}
+	if !bytes.Equal(txBytes, consensusEnc) {
+		t.Errorf("set code transaction encoding (%x) does not match the expected consensus encoding (%x)", txBytes, consensusEnc)
+	}
+
+	// an authorization with an invalid signature still belongs to a valid transaction, it only fails validation
+	badAuth := auth
+	badAuth.V = 2
+	badAuthTx, err := types.NewTx(&types.SetCodeTx{
+		ChainID:   uint256.NewInt(1),
+		GasTipCap: uint256.NewInt(1),
+		GasFeeCap: uint256.NewInt(2),
+		Gas:       50000,
+		To:        testAddr,
+		Value:     uint256.NewInt(0),
+		AuthList:  []types.SetCodeAuthorization{badAuth},
+	}).WithSignature(
+		types.NewPragueSigner(big.NewInt(1)),
+		common.Hex2Bytes("c9519f4f2b30335884581971573fadf60c6204f59a911df35ee8a540456b266032f1e8e2c5dd761f9e4f88f41c8310aeaba26a8bfcdacfedfa12ec3862d3752101"),
+	)
+	if err != nil {
+		t.Fatalf("unable to sign set code transaction: %v", err)
+	}
+	badAuthEnc, err := badAuthTx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal set code transaction binary: %v", err)
+	}
+	badAuthBuilder := dageth.Type.Transaction.NewBuilder()
+	if err := tx.DecodeBytes(badAuthBuilder, badAuthEnc); err != nil {
+		t.Fatalf("unable to decode set code transaction into an IPLD node: %v", err)
+	}
+	if err := tx.ValidateAuthorizationList(badAuthBuilder.Build()); err == nil {
+		t.Errorf("expected authorization with YParity 2 to fail validation")
+	}
+}
+
+func TestTransactionEnvelopeDetection(t *testing.T) {
+	for _, expected := range []*types.Transaction{legacyTx, accessListTx, dynamicFeeTx} {
+		enc, err := expected.MarshalBinary()
+		if err != nil {
+			t.Fatalf("unable to marshal transaction binary: %v", err)
+		}
+		txBuilder := dageth.Type.Transaction.NewBuilder()
+		if err := tx.Decode(txBuilder, bytes.NewReader(enc)); err != nil {
+			t.Fatalf("unable to decode type %d transaction into an IPLD node: %v", expected.Type(), err)
+		}
+		txTypeNode, err := txBuilder.Build().LookupByString("TxType")
+		if err != nil {
+			t.Fatalf("transaction missing TxType: %v", err)
+		}
+		txType, err := txTypeNode.AsBytes()
+		if err != nil {
+			t.Fatalf("transaction TxType should be of type Bytes: %v", err)
+		}
+		if !bytes.Equal(txType, []byte{expected.Type()}) {
+			t.Errorf("transaction type (%x) does not match expected type (%x)", txType, expected.Type())
+		}
+	}
+
+	// 0x7f is inside the EIP-2718 type range but is not a transaction type this codec knows of
+	dfEnc, err := dynamicFeeTx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to marshal dynamic fee transaction binary: %v", err)
+	}
+	unknownTypeEnc := append([]byte{0x7f}, dfEnc[1:]...)
+	if err := tx.DecodeBytes(dageth.Type.Transaction.NewBuilder(), unknownTypeEnc); err == nil {
+		t.Errorf("expected decoding a transaction with an unrecognized type to fail")
+	}
+	if err := tx.DecodeBytes(dageth.Type.Transaction.NewBuilder(), nil); err == nil {
+		t.Errorf("expected decoding an empty transaction binary to fail")
+	}
+}
+
+func TestRecoverSender(t *testing.T) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	expected := crypto.PubkeyToAddress(key.PublicKey)
+	chainID := big.NewInt(5)
+	for name, txData := range map[string]types.TxData{
+		"legacy":      &types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &testAddr, Value: big.NewInt(10)},
+		"EIP-155":     &types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &testAddr, Value: big.NewInt(10)},
+		"access list": &types.AccessListTx{ChainID: chainID, Nonce: 1, GasPrice: big.NewInt(1), Gas: 25000, To: &testAddr, Value: big.NewInt(10)},
+		"dynamic fee": &types.DynamicFeeTx{ChainID: chainID, Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 25000, Value: big.NewInt(10)},
+		"blob":        &types.BlobTx{ChainID: uint256.MustFromBig(chainID), Nonce: 1, Gas: 25000, To: testAddr, BlobHashes: []common.Hash{{1}}},
+		"set code":    &types.SetCodeTx{ChainID: uint256.MustFromBig(chainID), Nonce: 1, Gas: 25000, To: testAddr, AuthList: []types.SetCodeAuthorization{{Address: testAddr2}}},
+	} {
+		var signer types.Signer = types.LatestSignerForChainID(chainID)
+		if name == "legacy" {
+			signer = types.HomesteadSigner{}
+		}
+		signed, err := types.SignNewTx(key, signer, txData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, err := signed.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the sender is left out by default
+		nb := dageth.Type.Transaction.NewBuilder()
+		if err := tx.DecodeBytes(nb, enc); err != nil {
+			t.Fatalf("%s: unable to decode transaction: %v", name, err)
+		}
+		if fromNode, err := nb.Build().LookupByString("From"); err != nil || !fromNode.IsAbsent() {
+			t.Errorf("%s: expected From to be absent by default, got %v (%v)", name, fromNode, err)
+		}
+		sender, err := tx.RecoverSender(nb.Build())
+		if err != nil {
+			t.Fatalf("%s: unable to recover sender: %v", name, err)
+		}
+		if sender != expected {
+			t.Errorf("%s: expected sender %x, got %x", name, expected, sender)
+		}
+
+		nb = dageth.Type.Transaction.NewBuilder()
+		if err := tx.DecodeBytesWithOptions(nb, enc, tx.DecodeOptions{RecoverSender: true}); err != nil {
+			t.Fatalf("%s: unable to decode transaction with its sender: %v", name, err)
+		}
+		txNode := nb.Build()
+		fromNode, err := txNode.LookupByString("From")
+		if err != nil {
+			t.Fatalf("%s: transaction is missing From: %v", name, err)
+		}
+		if from, _ := fromNode.AsBytes(); !bytes.Equal(from, expected.Bytes()) {
+			t.Errorf("%s: expected From %x, got %x", name, expected, from)
+		}
+		// the derived sender is not part of the encoding
+		buf := new(bytes.Buffer)
+		if err := tx.Encode(txNode, buf); err != nil {
+			t.Fatalf("%s: unable to encode transaction with its sender: %v", name, err)
+		}
+		if !bytes.Equal(buf.Bytes(), enc) {
+			t.Errorf("%s: transaction encoding (%x) does not match the input (%x)", name, buf.Bytes(), enc)
+		}
+	}
+}
+
+func TestHashes(t *testing.T) {
+	g := testutil.NewGenerator(1)
+	for _, txType := range testutil.TxTypes {
+		expected := g.Transaction(txType)
+		enc, err := expected.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		nb := dageth.Type.Transaction.NewBuilder()
+		if err := tx.DecodeBytes(nb, enc); err != nil {
+			t.Fatalf("unable to decode type %d transaction: %v", txType, err)
+		}
+		txNode := nb.Build()
+
+		hash, err := tx.Hash(txNode)
+		if err != nil {
+			t.Fatalf("unable to hash type %d transaction: %v", txType, err)
+		}
+		if hash != expected.Hash() {
+			t.Errorf("type %d transaction hash %x does not match go-ethereum's %x", txType, hash, expected.Hash())
+		}
+		if c := testutil.KeccakCid(t, tx.MultiCodecType, hash.Bytes()); !bytes.Equal(c.Hash()[2:], crypto.Keccak256(enc)) {
+			t.Errorf("type %d transaction hash %x does not match the hash of its CID", txType, hash)
+		}
+
+		chainID := expected.ChainId()
+		signingHash, err := tx.SigningHash(txNode, chainID)
+		if err != nil {
+			t.Fatalf("unable to compute the signing hash of type %d transaction: %v", txType, err)
+		}
+		if sigHash := types.LatestSignerForChainID(chainID).Hash(expected); signingHash != sigHash {
+			t.Errorf("type %d transaction signing hash %x does not match go-ethereum's %x", txType, signingHash, sigHash)
+		}
+		if txType != types.LegacyTxType {
+			if _, err := tx.SigningHash(txNode, new(big.Int).Add(chainID, big.NewInt(1))); err == nil {
+				t.Errorf("expected the signing hash of type %d transaction for another chain ID to fail", txType)
+			}
+			if _, err := tx.SigningHash(txNode, nil); err == nil {
+				t.Errorf("expected the signing hash of type %d transaction without a chain ID to fail", txType)
+			}
+		}
+	}
+
+	// unprotected legacy transactions sign the same hash on every chain
+	legacyEnc, _ := legacyTx.MarshalBinary()
+	nb := dageth.Type.Transaction.NewBuilder()
+	if err := tx.DecodeBytes(nb, legacyEnc); err != nil {
+		t.Fatal(err)
+	}
+	signingHash, err := tx.SigningHash(nb.Build(), nil)
+	if err != nil {
+		t.Fatalf("unable to compute the signing hash of an unprotected legacy transaction: %v", err)
+	}
+	if expected := (types.HomesteadSigner{}).Hash(legacyTx); signingHash != expected {
+		t.Errorf("unprotected legacy transaction signing hash %x does not match go-ethereum's %x", signingHash, expected)
+	}
+}
+
+func TestChainID(t *testing.T) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	for _, chainID := range []*big.Int{big.NewInt(1), big.NewInt(100), big.NewInt(11155111), new(big.Int).Lsh(big.NewInt(1), 70)} {
+		legacy := &types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &testAddr, Value: big.NewInt(10)}
+		for name, signer := range map[string]types.Signer{
+			"EIP-155":     types.NewEIP155Signer(chainID),
+			"dynamic fee": types.LatestSignerForChainID(chainID),
+		} {
+			var txData types.TxData = legacy
+			if name == "dynamic fee" {
+				txData = &types.DynamicFeeTx{ChainID: chainID, Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 25000}
+			}
+			signed, err := types.SignNewTx(key, signer, txData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			enc, _ := signed.MarshalBinary()
+			nb := dageth.Type.Transaction.NewBuilder()
+			if err := tx.DecodeBytes(nb, enc); err != nil {
+				t.Fatalf("%s: unable to decode transaction: %v", name, err)
+			}
+			actual, err := tx.ChainID(nb.Build())
+			if err != nil || actual == nil || actual.Cmp(chainID) != 0 {
+				t.Errorf("%s: expected chain ID %d, got %v (%v)", name, chainID, actual, err)
+			}
+			if protected, err := tx.IsProtected(nb.Build()); err != nil || !protected {
+				t.Errorf("%s: expected a protected transaction (%v)", name, err)
+			}
+			if name != "EIP-155" {
+				continue
+			}
+			v, _, _ := signed.RawSignatureValues()
+			if fromV, err := tx.ChainIDFromV(v); err != nil || fromV.Cmp(chainID) != 0 {
+				t.Errorf("expected chain ID %d from V %d, got %v (%v)", chainID, v, fromV, err)
+			}
+
+			// the legacy encoding carries no chain ID, unless it is derived
+			if chainIDNode, _ := nb.Build().LookupByString("ChainID"); !chainIDNode.IsNull() {
+				t.Error("expected the ChainID of a legacy transaction to be null by default")
+			}
+			nb = dageth.Type.Transaction.NewBuilder()
+			if err := tx.DecodeBytesWithOptions(nb, enc, tx.DecodeOptions{DeriveChainID: true}); err != nil {
+				t.Fatalf("unable to decode transaction with its chain ID: %v", err)
+			}
+			chainIDNode, _ := nb.Build().LookupByString("ChainID")
+			if derived, _ := chainIDNode.AsBytes(); new(big.Int).SetBytes(derived).Cmp(chainID) != 0 {
+				t.Errorf("expected the derived ChainID %d, got %x", chainID, derived)
+			}
+			buf := new(bytes.Buffer)
+			if err := tx.Encode(nb.Build(), buf); err != nil || !bytes.Equal(buf.Bytes(), enc) {
+				t.Errorf("expected the derived ChainID to be left out of the encoding (%v)", err)
+			}
+		}
+	}
+
+	// unprotected legacy transactions carry no chain ID, derived or not
+	legacyEnc, _ := legacyTx.MarshalBinary()
+	nb := dageth.Type.Transaction.NewBuilder()
+	if err := tx.DecodeBytesWithOptions(nb, legacyEnc, tx.DecodeOptions{DeriveChainID: true}); err != nil {
+		t.Fatal(err)
+	}
+	if chainIDNode, _ := nb.Build().LookupByString("ChainID"); !chainIDNode.IsNull() {
+		t.Error("expected no ChainID for an unprotected legacy transaction")
+	}
+	if chainID, err := tx.ChainID(nb.Build()); err != nil || chainID != nil {
+		t.Errorf("expected no chain ID for an unprotected legacy transaction, got %v (%v)", chainID, err)
+	}
+	if protected, err := tx.IsProtected(nb.Build()); err != nil || protected {
+		t.Errorf("expected an unprotected transaction (%v)", err)
+	}
+	for _, v := range []int64{0, 1, 29, 34} {
+		if _, err := tx.ChainIDFromV(big.NewInt(v)); err == nil {
+			t.Errorf("expected an error for V value %d", v)
+		}
+	}
+}
+
+func TestNumericForms(t *testing.T) {
+	txBuilder := dageth.Type.Transaction.NewBuilder()
+	if err := tx.DecodeTx(txBuilder, dynamicFeeTx); err != nil {
+		t.Fatalf("unable to decode transaction into an IPLD node: %v", err)
+	}
+	txNode := txBuilder.Build()
+	consensusEnc, _ := dynamicFeeTx.MarshalBinary()
+	if _, err := tx.AppendEncodeWithOptions(nil, txNode, tx.EncodeOptions{Strict: true}); err != nil {
+		t.Errorf("unable to strictly encode a decoded transaction: %v", err)
+	}
+
+	// non-canonical numeric fields encode like their canonical forms, but not strictly
+	for _, field := range []struct {
+		name string
+		b    []byte
+	}{
+		{"AccountNonce", []byte{byte(dynamicFeeTx.Nonce())}},
+		{"GasLimit", append(make([]byte, 4), shared.Uint64ToBytes(dynamicFeeTx.Gas())...)},
+		{"Amount", append([]byte{0}, dynamicFeeTx.Value().Bytes()...)},
+		{"GasFeeCap", append(make([]byte, 32), dynamicFeeTx.GasFeeCap().Bytes()...)},
+	} {
+		node := withBytes(t, txNode, field.name, field.b)
+		enc, err := tx.AppendEncode(nil, node)
+		if err != nil {
+			t.Errorf("unable to encode transaction with a non-canonical %s: %v", field.name, err)
+		} else if !bytes.Equal(enc, consensusEnc) {
+			t.Errorf("transaction encoding with a non-canonical %s (%x) does not match the consensus encoding (%x)", field.name, enc, consensusEnc)
+		}
+		if _, err := tx.AppendEncodeWithOptions(nil, node, tx.EncodeOptions{Strict: true}); err == nil {
+			t.Errorf("expected an error strictly encoding a transaction with a non-canonical %s", field.name)
+		}
+	}
+	if _, err := tx.AppendEncode(nil, withBytes(t, txNode, "GasLimit", append([]byte{1}, make([]byte, 8)...))); err == nil {
+		t.Error("expected an error encoding a transaction with a GasLimit overflowing 64 bits")
+	}
+}
+
+// withBytes copies the map node with the named field set to the bytes
+func withBytes(t *testing.T, node ipld.Node, field string, b []byte) ipld.Node {
+	nb := basicnode.Prototype.Map.NewBuilder()
+	ma, err := nb.BeginMap(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for it := node.MapIterator(); !it.Done(); {
+		k, v, err := it.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.IsAbsent() {
+			continue
+		}
+		key, _ := k.AsString()
+		if key == field {
+			v = basicnode.NewBytes(b)
+		}
+		if err := ma.AssembleKey().AssignString(key); err != nil {
+			t.Fatal(err)
+		}
+		if err := ma.AssembleValue().AssignNode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ma.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	return nb.Build()
+}
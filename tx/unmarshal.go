@@ -1,139 +1,228 @@
 package tx
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
-	"io/ioutil"
 
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
 )
 
 // Decode provides an IPLD codec decode interface for eth transaction IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code 0x93 when this package is invoked via init.
 func Decode(na ipld.NodeAssembler, in io.Reader) error {
-	var src []byte
-	if buf, ok := in.(interface{ Bytes() []byte }); ok {
-		src = buf.Bytes()
-	} else {
-		var err error
-		src, err = ioutil.ReadAll(in)
-		if err != nil {
-			return err
-		}
-	}
-	return DecodeBytes(na, src)
+	return DecodeOptions{}.Decode(na, in)
 }
 
 // DecodeBytes is like Decode, but it uses an input buffer directly.
 // Decode will grab or read all the bytes from an io.Reader anyway, so this can
 // save having to copy the bytes or create a bytes.Buffer.
+// A payload starting with a byte in [0x00, 0x7f] is an EIP-2718 typed transaction envelope, anything else is a legacy
+// RLP list; either way the resulting node carries the TxType.
 func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeOptions{}.DecodeBytes(na, src)
+}
+
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	if len(src) == 0 {
+		return fmt.Errorf("invalid DAG-ETH Transaction binary (empty input)")
+	}
+	if src[0] <= 0x7f && !isKnownTxType(src[0]) {
+		return fmt.Errorf("invalid DAG-ETH Transaction binary (unrecognized TxType %d)", src[0])
+	}
 	var tx types.Transaction
 	if err := tx.UnmarshalBinary(src); err != nil {
 		return err
 	}
-	return DecodeTx(na, tx)
+	if tx.BlobTxSidecar() != nil {
+		return fmt.Errorf("invalid DAG-ETH Transaction binary (blob transaction includes a sidecar, the sidecar belongs in a BlobSidecar)")
+	}
+	return cfg.DecodeTx(na, &tx)
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeOptions can be used to customize the behavior of transaction decoding.
+// The zero value is the default behavior used by the registered codec.
+type DecodeOptions struct {
+	// RecoverSender causes the decoder to recover the sender of the transaction from its signature into the From
+	// field, which is derived rather than encoded and so left out by default
+	RecoverSender bool
+	// DeriveChainID causes the decoder to fill the ChainID field of EIP-155 replay protected legacy transactions with
+	// the chain ID their V value encodes, rather than leaving it null as legacy transactions do not encode one
+	DeriveChainID bool
+}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	return cfg.DecodeBytes(na, src)
 }
 
 // DecodeTx unpacks a go-ethereum Transaction into a NodeAssembler
-func DecodeTx(na ipld.NodeAssembler, tx types.Transaction) error {
-	ma, err := na.BeginMap(14)
+func DecodeTx(na ipld.NodeAssembler, tx *types.Transaction) error {
+	return DecodeOptions{}.DecodeTx(na, tx)
+}
+
+// DecodeTx is like the package level DecodeTx, but uses the provided options
+func (cfg DecodeOptions) DecodeTx(na ipld.NodeAssembler, tx *types.Transaction) error {
+	ma, err := na.BeginMap(18)
 	if err != nil {
 		return err
 	}
 	for _, upFunc := range requiredUnpackFuncs {
-		if err := upFunc(ma, tx); err != nil {
+		if err := upFunc(cfg, ma, tx); err != nil {
+			return fmt.Errorf("invalid DAG-ETH Transaction binary (%v)", err)
+		}
+	}
+	if cfg.RecoverSender {
+		if err := unpackFrom(ma, tx); err != nil {
 			return fmt.Errorf("invalid DAG-ETH Transaction binary (%v)", err)
 		}
 	}
 	return ma.Finish()
 }
 
-var requiredUnpackFuncs = []func(ipld.MapAssembler, types.Transaction) error{
-	unpackTxType,
-	unpackChainID,
-	unpackAccountNonce,
-	unpackGasPrice,
-	unpackGasTipCap,
-	unpackGasFeeCap,
-	unpackGasLimit,
-	unpackRecipient,
-	unpackAmount,
-	unpackData,
-	unpackAccessList,
-	unpackSignatureValues,
+var requiredUnpackFuncs = []func(DecodeOptions, ipld.MapAssembler, *types.Transaction) error{
+	DecodeOptions.unpackTxType,
+	DecodeOptions.unpackChainID,
+	DecodeOptions.unpackAccountNonce,
+	DecodeOptions.unpackGasPrice,
+	DecodeOptions.unpackGasTipCap,
+	DecodeOptions.unpackGasFeeCap,
+	DecodeOptions.unpackGasLimit,
+	DecodeOptions.unpackRecipient,
+	DecodeOptions.unpackAmount,
+	DecodeOptions.unpackData,
+	DecodeOptions.unpackAccessList,
+	DecodeOptions.unpackMaxFeePerBlobGas,
+	DecodeOptions.unpackBlobVersionedHashes,
+	DecodeOptions.unpackAuthorizationList,
+	DecodeOptions.unpackSignatureValues,
 }
 
-func unpackTxType(ma ipld.MapAssembler, tx types.Transaction) error {
+// isKnownTxType returns true if the EIP-2718 type prefix is one this codec can decode
+func isKnownTxType(txType byte) bool {
+	switch txType {
+	case types.AccessListTxType, types.DynamicFeeTxType, types.BlobTxType, types.SetCodeTxType:
+		return true
+	default:
+		return false
+	}
+}
+
+// isDynamicFeeTx returns true if the transaction prices gas with a tip cap and fee cap rather than a gas price
+func isDynamicFeeTx(tx *types.Transaction) bool {
+	switch tx.Type() {
+	case types.DynamicFeeTxType, types.BlobTxType, types.SetCodeTxType:
+		return true
+	default:
+		return false
+	}
+}
+
+func (cfg DecodeOptions) unpackTxType(ma ipld.MapAssembler, tx *types.Transaction) error {
 	if err := ma.AssembleKey().AssignString("TxType"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes([]byte{tx.Type()})
 }
 
-func unpackChainID(ma ipld.MapAssembler, tx types.Transaction) error {
+func (cfg DecodeOptions) unpackChainID(ma ipld.MapAssembler, tx *types.Transaction) error {
 	// We could make ChainID a required field in the schema even though legacy txs dont include it in the consensus encoding
 	if err := ma.AssembleKey().AssignString("ChainID"); err != nil {
 		return err
 	}
 	if tx.Type() == types.LegacyTxType {
-		return ma.AssembleValue().AssignNull()
+		if !cfg.DeriveChainID || !tx.Protected() {
+			return ma.AssembleValue().AssignNull()
+		}
+		v, _, _ := tx.RawSignatureValues()
+		chainID, err := ChainIDFromV(v)
+		if err != nil {
+			return err
+		}
+		return ma.AssembleValue().AssignBytes(chainID.Bytes())
 	}
 	return ma.AssembleValue().AssignBytes(tx.ChainId().Bytes())
 }
 
-func unpackAccountNonce(ma ipld.MapAssembler, tx types.Transaction) error {
+func (cfg DecodeOptions) unpackAccountNonce(ma ipld.MapAssembler, tx *types.Transaction) error {
 	if err := ma.AssembleKey().AssignString("AccountNonce"); err != nil {
 		return err
 	}
-	nonceBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(nonceBytes, tx.Nonce())
+	nonceBytes := shared.Uint64ToBytes(tx.Nonce())
 	return ma.AssembleValue().AssignBytes(nonceBytes)
 }
 
-func unpackGasPrice(ma ipld.MapAssembler, tx types.Transaction) error {
+func (cfg DecodeOptions) unpackGasPrice(ma ipld.MapAssembler, tx *types.Transaction) error {
 	if err := ma.AssembleKey().AssignString("GasPrice"); err != nil {
 		return err
 	}
-	if tx.Type() == types.DynamicFeeTxType {
+	if isDynamicFeeTx(tx) {
 		return ma.AssembleValue().AssignNull()
 	}
 	return ma.AssembleValue().AssignBytes(tx.GasPrice().Bytes())
 }
 
-func unpackGasFeeCap(ma ipld.MapAssembler, tx types.Transaction) error {
+func (cfg DecodeOptions) unpackGasTipCap(ma ipld.MapAssembler, tx *types.Transaction) error {
 	if err := ma.AssembleKey().AssignString("GasTipCap"); err != nil {
 		return err
 	}
-	if tx.Type() != types.DynamicFeeTxType {
+	if !isDynamicFeeTx(tx) {
 		return ma.AssembleValue().AssignNull()
 	}
 	return ma.AssembleValue().AssignBytes(tx.GasTipCap().Bytes())
 }
 
-func unpackGasTipCap(ma ipld.MapAssembler, tx types.Transaction) error {
+func (cfg DecodeOptions) unpackGasFeeCap(ma ipld.MapAssembler, tx *types.Transaction) error {
 	if err := ma.AssembleKey().AssignString("GasFeeCap"); err != nil {
 		return err
 	}
-	if tx.Type() != types.DynamicFeeTxType {
+	if !isDynamicFeeTx(tx) {
 		return ma.AssembleValue().AssignNull()
 	}
 	return ma.AssembleValue().AssignBytes(tx.GasFeeCap().Bytes())
 }
 
-func unpackGasLimit(ma ipld.MapAssembler, tx types.Transaction) error {
+func (cfg DecodeOptions) unpackGasLimit(ma ipld.MapAssembler, tx *types.Transaction) error {
 	if err := ma.AssembleKey().AssignString("GasLimit"); err != nil {
 		return err
 	}
-	gasBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(gasBytes, tx.Gas())
+	gasBytes := shared.Uint64ToBytes(tx.Gas())
 	return ma.AssembleValue().AssignBytes(gasBytes)
 }
 
-func unpackRecipient(ma ipld.MapAssembler, tx types.Transaction) error {
+func (cfg DecodeOptions) unpackRecipient(ma ipld.MapAssembler, tx *types.Transaction) error {
 	if err := ma.AssembleKey().AssignString("Recipient"); err != nil {
 		return err
 	}
@@ -143,21 +232,21 @@ func unpackRecipient(ma ipld.MapAssembler, tx types.Transaction) error {
 	return ma.AssembleValue().AssignBytes(tx.To().Bytes())
 }
 
-func unpackAmount(ma ipld.MapAssembler, tx types.Transaction) error {
+func (cfg DecodeOptions) unpackAmount(ma ipld.MapAssembler, tx *types.Transaction) error {
 	if err := ma.AssembleKey().AssignString("Amount"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes(tx.Value().Bytes())
 }
 
-func unpackData(ma ipld.MapAssembler, tx types.Transaction) error {
+func (cfg DecodeOptions) unpackData(ma ipld.MapAssembler, tx *types.Transaction) error {
 	if err := ma.AssembleKey().AssignString("Data"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes(tx.Data())
 }
 
-func unpackAccessList(ma ipld.MapAssembler, tx types.Transaction) error {
+func (cfg DecodeOptions) unpackAccessList(ma ipld.MapAssembler, tx *types.Transaction) error {
 	if err := ma.AssembleKey().AssignString("AccessList"); err != nil {
 		return err
 	}
@@ -202,7 +291,79 @@ func unpackAccessList(ma ipld.MapAssembler, tx types.Transaction) error {
 	return accessList.Finish()
 }
 
-func unpackSignatureValues(ma ipld.MapAssembler, tx types.Transaction) error {
+func (cfg DecodeOptions) unpackMaxFeePerBlobGas(ma ipld.MapAssembler, tx *types.Transaction) error {
+	if err := ma.AssembleKey().AssignString("MaxFeePerBlobGas"); err != nil {
+		return err
+	}
+	if tx.Type() != types.BlobTxType {
+		return ma.AssembleValue().AssignNull()
+	}
+	return ma.AssembleValue().AssignBytes(tx.BlobGasFeeCap().Bytes())
+}
+
+func (cfg DecodeOptions) unpackBlobVersionedHashes(ma ipld.MapAssembler, tx *types.Transaction) error {
+	if err := ma.AssembleKey().AssignString("BlobVersionedHashes"); err != nil {
+		return err
+	}
+	if tx.Type() != types.BlobTxType {
+		return ma.AssembleValue().AssignNull()
+	}
+	blobHashes, err := ma.AssembleValue().BeginList(int64(len(tx.BlobHashes())))
+	if err != nil {
+		return err
+	}
+	for _, blobHash := range tx.BlobHashes() {
+		if err := blobHashes.AssembleValue().AssignBytes(blobHash.Bytes()); err != nil {
+			return err
+		}
+	}
+	return blobHashes.Finish()
+}
+
+func (cfg DecodeOptions) unpackAuthorizationList(ma ipld.MapAssembler, tx *types.Transaction) error {
+	if err := ma.AssembleKey().AssignString("AuthorizationList"); err != nil {
+		return err
+	}
+	if tx.Type() != types.SetCodeTxType {
+		return ma.AssembleValue().AssignNull()
+	}
+	authList, err := ma.AssembleValue().BeginList(int64(len(tx.SetCodeAuthorizations())))
+	if err != nil {
+		return err
+	}
+	for _, auth := range tx.SetCodeAuthorizations() {
+		authMap, err := authList.AssembleValue().BeginMap(6)
+		if err != nil {
+			return err
+		}
+		nonceBytes := shared.Uint64ToBytes(auth.Nonce)
+		yParityBytes := shared.Uint64ToBytes(uint64(auth.V))
+		for _, field := range []struct {
+			key string
+			val []byte
+		}{
+			{"ChainID", auth.ChainID.Bytes()},
+			{"Address", auth.Address.Bytes()},
+			{"Nonce", nonceBytes},
+			{"YParity", yParityBytes},
+			{"R", auth.R.Bytes()},
+			{"S", auth.S.Bytes()},
+		} {
+			if err := authMap.AssembleKey().AssignString(field.key); err != nil {
+				return err
+			}
+			if err := authMap.AssembleValue().AssignBytes(field.val); err != nil {
+				return err
+			}
+		}
+		if err := authMap.Finish(); err != nil {
+			return err
+		}
+	}
+	return authList.Finish()
+}
+
+func (cfg DecodeOptions) unpackSignatureValues(ma ipld.MapAssembler, tx *types.Transaction) error {
 	v, r, s := tx.RawSignatureValues()
 	if err := ma.AssembleKey().AssignString("R"); err != nil {
 		return err
@@ -221,3 +382,14 @@ func unpackSignatureValues(ma ipld.MapAssembler, tx types.Transaction) error {
 	}
 	return ma.AssembleValue().AssignBytes(v.Bytes())
 }
+
+func unpackFrom(ma ipld.MapAssembler, tx *types.Transaction) error {
+	from, err := Sender(tx)
+	if err != nil {
+		return err
+	}
+	if err := ma.AssembleKey().AssignString("From"); err != nil {
+		return err
+	}
+	return ma.AssembleValue().AssignBytes(from.Bytes())
+}
@@ -16,17 +16,20 @@ import (
 // Encode provides an IPLD codec encode interface for eth transaction list IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code (tbd) when this package is invoked via init.
+// The list is streamed to w transaction by transaction, see shared.StreamRLP.
 func Encode(node ipld.Node, w io.Writer) error {
-	// 1KiB can be allocated on the stack, and covers most small nodes
-	// without having to grow the buffer and cause allocations.
-	enc := make([]byte, 0, 1024)
-
-	enc, err := AppendEncode(enc, node)
-	if err != nil {
+	txs := make([]*types.Transaction, 0, node.Length())
+	if err := EncodeTxs(&txs, node); err != nil {
 		return err
 	}
-	_, err = w.Write(enc)
-	return err
+	items := make(shared.List, len(txs))
+	for i, tx := range txs {
+		items[i] = tx
+	}
+	if err := shared.StreamRLP(w, items); err != nil {
+		return fmt.Errorf("invalid DAG-ETH Transactions form (unable to RLP encode transactions: %v)", err)
+	}
+	return nil
 }
 
 // AppendEncode is like Encode, but it uses a destination buffer directly.
@@ -44,6 +47,30 @@ func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	return enc, nil
 }
 
+// EncodeOptions can be used to customize the behavior of transaction list encoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type EncodeOptions struct{}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return Encode(node, w)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return AppendEncode(enc, inNode)
+}
+
 // EncodeTxs packs the node into a go-ethereum Transactions
 func EncodeTxs(txs *[]*types.Transaction, inNode ipld.Node) error {
 	// Wrap in a typed node for some basic schema form checking
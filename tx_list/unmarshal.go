@@ -7,8 +7,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 
+	"github.com/vulcanize/go-codec-dageth/shared"
 	dageth_tx "github.com/vulcanize/go-codec-dageth/tx"
 )
 
@@ -41,6 +43,43 @@ func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
 	return DecodeTxs(na, txs)
 }
 
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeOptions can be used to customize the behavior of transaction list decoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type DecodeOptions struct{}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return Decode(na, in)
+}
+
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeBytes(na, src)
+}
+
 // DecodeTxs unpacks a list of go-ethereum Transactions into the NodeAssembler
 func DecodeTxs(na ipld.NodeAssembler, txs []*types.Transaction) error {
 	la, err := na.BeginList(int64(len(txs)))
@@ -50,7 +89,7 @@ func DecodeTxs(na ipld.NodeAssembler, txs []*types.Transaction) error {
 	for i, tx := range txs {
 		// node := dageth.Type.Transaction.NewBuilder()
 		node := la.ValuePrototype(int64(i)).NewBuilder()
-		if err := dageth_tx.DecodeTx(node, *tx); err != nil {
+		if err := dageth_tx.DecodeTx(node, tx); err != nil {
 			return fmt.Errorf("invalid DAG-ETH Transactions binary (%v)", err)
 		}
 		if err := la.AssembleValue().AssignNode(node.Build()); err != nil {
@@ -9,6 +9,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	"github.com/multiformats/go-multihash"
@@ -17,28 +18,58 @@ import (
 	"github.com/vulcanize/go-codec-dageth/shared"
 )
 
+// EncodeOptions can be used to customize the behavior of transaction trace encoding.
+// The zero value is the default behavior used by the registered codec.
+type EncodeOptions struct {
+	// Strict causes the encoder to reject links that do not carry the multicodec and multihash types
+	// the transaction trace decoder builds them with
+	Strict bool
+	// LinkCodecs overrides the multicodec types Strict expects links to carry,
+	// it should match the DecodeOptions the node was decoded with
+	LinkCodecs shared.LinkCodecs
+	// LinkHashes overrides the multihash types Strict expects links to carry,
+	// it should match the DecodeOptions the node was decoded with
+	LinkHashes shared.LinkHashes
+}
+
 // Encode provides an IPLD codec encode interface for eth transaction trace IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code 0x9b (proposed) when this package is invoked via init.
 func Encode(node ipld.Node, w io.Writer) error {
-	// 1KiB can be allocated on the stack, and covers most small nodes
-	// without having to grow the buffer and cause allocations.
-	enc := make([]byte, 0, 1024)
-
-	enc, err := AppendEncode(enc, node)
-	if err != nil {
-		return err
-	}
-	_, err = w.Write(enc)
-	return err
+	return EncodeOptions{}.Encode(node, w)
 }
 
 // AppendEncode is like Encode, but it uses a destination buffer directly.
 // This means less copying of bytes, and if the destination has enough capacity,
 // fewer allocations.
 func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return EncodeOptions{}.AppendEncode(enc, inNode)
+}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// EncodeTxTrace packs the node into a go-ethereum TxTrace
+func EncodeTxTrace(txTrace *TxTrace, inNode ipld.Node) error {
+	return EncodeOptions{}.EncodeTxTrace(txTrace, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return shared.WriteEncoded(w, node, cfg.AppendEncode)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	txTrace := new(TxTrace)
-	if err := EncodeTxTrace(txTrace, inNode); err != nil {
+	if err := cfg.EncodeTxTrace(txTrace, inNode); err != nil {
 		return nil, err
 	}
 	wbs := shared.NewWriteableByteSlice(&enc)
@@ -48,8 +79,8 @@ func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	return enc, nil
 }
 
-// EncodeTxTrace packs the node into a go-ethereum TxTrace
-func EncodeTxTrace(txTrace *TxTrace, inNode ipld.Node) error {
+// EncodeTxTrace is like the package level EncodeTxTrace, but uses the provided options
+func (cfg EncodeOptions) EncodeTxTrace(txTrace *TxTrace, inNode ipld.Node) error {
 	// Wrap in a typed node for some basic schema form checking
 	builder := dageth.Type.TxTrace.NewBuilder()
 	if err := builder.AssignNode(inNode); err != nil {
@@ -57,23 +88,31 @@ func EncodeTxTrace(txTrace *TxTrace, inNode ipld.Node) error {
 	}
 	node := builder.Build()
 	for _, pFunc := range requiredPackFuncs {
-		if err := pFunc(txTrace, node); err != nil {
+		if err := pFunc(cfg, txTrace, node); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-var requiredPackFuncs = []func(*TxTrace, ipld.Node) error{
-	packTxCIDs,
-	packStateRootCID,
-	packResult,
-	packFrames,
-	packGas,
-	packFailed,
+// checkLink verifies that a link carries the multicodec and multihash types it is built with, when encoding strictly
+func (cfg EncodeOptions) checkLink(link cidlink.Link, codec uint64) error {
+	if !cfg.Strict {
+		return nil
+	}
+	return shared.CheckLink(link.Cid, cfg.LinkCodecs.Codec(codec), cfg.LinkHashes.Type(multihash.KECCAK_256))
+}
+
+var requiredPackFuncs = []func(EncodeOptions, *TxTrace, ipld.Node) error{
+	EncodeOptions.packTxCIDs,
+	EncodeOptions.packStateRootCID,
+	EncodeOptions.packResult,
+	EncodeOptions.packFrames,
+	EncodeOptions.packGas,
+	EncodeOptions.packFailed,
 }
 
-func packTxCIDs(txTrace *TxTrace, node ipld.Node) error {
+func (cfg EncodeOptions) packTxCIDs(txTrace *TxTrace, node ipld.Node) error {
 	txCIDList, err := node.LookupByString("TxCIDs")
 	if err != nil {
 		return err
@@ -93,6 +132,9 @@ func packTxCIDs(txTrace *TxTrace, node ipld.Node) error {
 		if !ok {
 			return fmt.Errorf("tx trace must have TxCIDs")
 		}
+		if err := cfg.checkLink(txCIDLink, cid.EthTx); err != nil {
+			return err
+		}
 		txMh := txCIDLink.Hash()
 		decodedTxMh, err := multihash.Decode(txMh)
 		if err != nil {
@@ -104,7 +146,7 @@ func packTxCIDs(txTrace *TxTrace, node ipld.Node) error {
 	return nil
 }
 
-func packStateRootCID(txTrace *TxTrace, node ipld.Node) error {
+func (cfg EncodeOptions) packStateRootCID(txTrace *TxTrace, node ipld.Node) error {
 	srNode, err := node.LookupByString("StateRootCID")
 	if err != nil {
 		return err
@@ -117,6 +159,9 @@ func packStateRootCID(txTrace *TxTrace, node ipld.Node) error {
 	if !ok {
 		return fmt.Errorf("tx trace must have a StateRootCID")
 	}
+	if err := cfg.checkLink(srCIDLink, cid.EthStateTrie); err != nil {
+		return err
+	}
 	srMh := srCIDLink.Hash()
 	decodedSrMh, err := multihash.Decode(srMh)
 	if err != nil {
@@ -126,7 +171,7 @@ func packStateRootCID(txTrace *TxTrace, node ipld.Node) error {
 	return nil
 }
 
-func packResult(txTrace *TxTrace, node ipld.Node) error {
+func (cfg EncodeOptions) packResult(txTrace *TxTrace, node ipld.Node) error {
 	resNode, err := node.LookupByString("Result")
 	if err != nil {
 		return err
@@ -139,7 +184,7 @@ func packResult(txTrace *TxTrace, node ipld.Node) error {
 	return nil
 }
 
-func packFrames(txTrace *TxTrace, node ipld.Node) error {
+func (cfg EncodeOptions) packFrames(txTrace *TxTrace, node ipld.Node) error {
 	frameList, err := node.LookupByString("Frames")
 	if err != nil {
 		return err
@@ -161,7 +206,7 @@ func packFrames(txTrace *TxTrace, node ipld.Node) error {
 	return nil
 }
 
-func packGas(txTrace *TxTrace, node ipld.Node) error {
+func (cfg EncodeOptions) packGas(txTrace *TxTrace, node ipld.Node) error {
 	gasNode, err := node.LookupByString("Gas")
 	if err != nil {
 		return err
@@ -175,7 +220,7 @@ func packGas(txTrace *TxTrace, node ipld.Node) error {
 	return nil
 }
 
-func packFailed(txTrace *TxTrace, node ipld.Node) error {
+func (cfg EncodeOptions) packFailed(txTrace *TxTrace, node ipld.Node) error {
 	failedNode, err := node.LookupByString("Failed")
 	if err != nil {
 		return err
@@ -230,7 +275,10 @@ func packFrom(frame *Frame, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	frame.From = common.BytesToAddress(fromBytes)
+	frame.From, err = shared.BytesToAddress(fromBytes)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -243,7 +291,10 @@ func packTo(frame *Frame, node ipld.Node) error {
 	if err != nil {
 		return err
 	}
-	frame.To = common.BytesToAddress(toBytes)
+	frame.To, err = shared.BytesToAddress(toBytes)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
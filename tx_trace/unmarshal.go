@@ -10,16 +10,65 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
-	"github.com/multiformats/go-multihash"
 
+	"github.com/vulcanize/go-codec-dageth/shared"
 	"github.com/vulcanize/go-codec-dageth/state_trie"
 	"github.com/vulcanize/go-codec-dageth/tx"
 )
 
+// DecodeOptions can be used to customize the behavior of transaction trace decoding.
+// The zero value is the default behavior used by the registered codec.
+type DecodeOptions struct {
+	// LinkCodecs overrides the multicodec types of the links to the transactions and the state trie of the trace
+	LinkCodecs shared.LinkCodecs
+	// LinkHashes overrides the multihash types of the links to the transactions and the state trie of the trace
+	LinkHashes shared.LinkHashes
+}
+
 // Decode provides an IPLD codec decode interface for eth transaction trace IPLDs.
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code 0x9b (proposed) when this package is invoked via init.
 func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return DecodeOptions{}.Decode(na, in)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeOptions{}.DecodeBytes(na, src)
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// DecodeTx unpacks a go-ethereum TxTrace into a NodeAssembler
+func DecodeTx(na ipld.NodeAssembler, txTrace TxTrace) error {
+	return DecodeOptions{}.DecodeTx(na, txTrace)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
 	var src []byte
 	if buf, ok := in.(interface{ Bytes() []byte }); ok {
 		src = buf.Bytes()
@@ -30,44 +79,42 @@ func Decode(na ipld.NodeAssembler, in io.Reader) error {
 			return err
 		}
 	}
-	return DecodeBytes(na, src)
+	return cfg.DecodeBytes(na, src)
 }
 
-// DecodeBytes is like Decode, but it uses an input buffer directly.
-// Decode will grab or read all the bytes from an io.Reader anyway, so this can
-// save having to copy the bytes or create a bytes.Buffer.
-func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
 	var txTrace TxTrace
 	if err := rlp.DecodeBytes(src, &txTrace); err != nil {
 		return err
 	}
-	return DecodeTx(na, txTrace)
+	return cfg.DecodeTx(na, txTrace)
 }
 
-// DecodeTx unpacks a go-ethereum TxTrace into a NodeAssembler
-func DecodeTx(na ipld.NodeAssembler, txTrace TxTrace) error {
+// DecodeTx is like the package level DecodeTx, but uses the provided options
+func (cfg DecodeOptions) DecodeTx(na ipld.NodeAssembler, txTrace TxTrace) error {
 	ma, err := na.BeginMap(14)
 	if err != nil {
 		return err
 	}
 	for _, upFunc := range requiredUnpackFuncs {
-		if err := upFunc(ma, txTrace); err != nil {
+		if err := upFunc(cfg, ma, txTrace); err != nil {
 			return fmt.Errorf("invalid DAG-ETH TxTrace binary (%v)", err)
 		}
 	}
 	return ma.Finish()
 }
 
-var requiredUnpackFuncs = []func(ipld.MapAssembler, TxTrace) error{
-	unpackTxCIDs,
-	unpackStateRootCID,
-	unpackResult,
-	unpackFrames,
-	unpackGas,
-	unpackFailed,
+var requiredUnpackFuncs = []func(DecodeOptions, ipld.MapAssembler, TxTrace) error{
+	DecodeOptions.unpackTxCIDs,
+	DecodeOptions.unpackStateRootCID,
+	DecodeOptions.unpackResult,
+	DecodeOptions.unpackFrames,
+	DecodeOptions.unpackGas,
+	DecodeOptions.unpackFailed,
 }
 
-func unpackTxCIDs(ma ipld.MapAssembler, txTrace TxTrace) error {
+func (cfg DecodeOptions) unpackTxCIDs(ma ipld.MapAssembler, txTrace TxTrace) error {
 	if err := ma.AssembleKey().AssignString("TxCIDs"); err != nil {
 		return err
 	}
@@ -76,11 +123,10 @@ func unpackTxCIDs(ma ipld.MapAssembler, txTrace TxTrace) error {
 		return err
 	}
 	for _, txHash := range txTrace.TxHashes {
-		txMh, err := multihash.Encode(txHash.Bytes(), tx.MultiHashType)
+		txCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(cid.EthTx), cfg.LinkHashes.Type(tx.MultiHashType), txHash.Bytes())
 		if err != nil {
 			return err
 		}
-		txCID := cid.NewCidV1(cid.EthTx, txMh)
 		txLinkCID := cidlink.Link{Cid: txCID}
 		if err := la.AssembleValue().AssignLink(txLinkCID); err != nil {
 			return err
@@ -89,12 +135,11 @@ func unpackTxCIDs(ma ipld.MapAssembler, txTrace TxTrace) error {
 	return la.Finish()
 }
 
-func unpackStateRootCID(ma ipld.MapAssembler, txTrace TxTrace) error {
-	srMh, err := multihash.Encode(txTrace.StateRoot.Bytes(), state_trie.MultiHashType)
+func (cfg DecodeOptions) unpackStateRootCID(ma ipld.MapAssembler, txTrace TxTrace) error {
+	srCID, err := shared.HashToCid(cfg.LinkCodecs.Codec(cid.EthStateTrie), cfg.LinkHashes.Type(state_trie.MultiHashType), txTrace.StateRoot.Bytes())
 	if err != nil {
 		return err
 	}
-	srCID := cid.NewCidV1(cid.EthStateTrie, srMh)
 	srLinkCID := cidlink.Link{Cid: srCID}
 	if err := ma.AssembleKey().AssignString("StateRootCID"); err != nil {
 		return err
@@ -102,14 +147,14 @@ func unpackStateRootCID(ma ipld.MapAssembler, txTrace TxTrace) error {
 	return ma.AssembleValue().AssignLink(srLinkCID)
 }
 
-func unpackResult(ma ipld.MapAssembler, txTrace TxTrace) error {
+func (cfg DecodeOptions) unpackResult(ma ipld.MapAssembler, txTrace TxTrace) error {
 	if err := ma.AssembleKey().AssignString("Result"); err != nil {
 		return err
 	}
 	return ma.AssembleValue().AssignBytes(txTrace.Result)
 }
 
-func unpackFrames(ma ipld.MapAssembler, txTrace TxTrace) error {
+func (cfg DecodeOptions) unpackFrames(ma ipld.MapAssembler, txTrace TxTrace) error {
 	if err := ma.AssembleKey().AssignString("Frames"); err != nil {
 		return err
 	}
@@ -132,7 +177,7 @@ func unpackFrames(ma ipld.MapAssembler, txTrace TxTrace) error {
 	return framesLA.Finish()
 }
 
-func unpackGas(ma ipld.MapAssembler, txTrace TxTrace) error {
+func (cfg DecodeOptions) unpackGas(ma ipld.MapAssembler, txTrace TxTrace) error {
 	gasBytes := make([]byte, 8)
 	binary.BigEndian.PutUint64(gasBytes, txTrace.Gas)
 	if err := ma.AssembleKey().AssignString("Gas"); err != nil {
@@ -141,7 +186,7 @@ func unpackGas(ma ipld.MapAssembler, txTrace TxTrace) error {
 	return ma.AssembleValue().AssignBytes(gasBytes)
 }
 
-func unpackFailed(ma ipld.MapAssembler, txTrace TxTrace) error {
+func (cfg DecodeOptions) unpackFailed(ma ipld.MapAssembler, txTrace TxTrace) error {
 	if err := ma.AssembleKey().AssignString("Failed"); err != nil {
 		return err
 	}
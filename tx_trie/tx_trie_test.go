@@ -356,7 +356,11 @@ func verifyExtensionNodeContents(t *testing.T) {
 	if err != nil {
 		t.Fatalf("transaction trie extension node missing Child: %v", err)
 	}
-	childLink, err := childNode.AsLink()
+	childLinkNode, err := childNode.LookupByString("Link")
+	if err != nil {
+		t.Fatalf("transaction trie extension node Child should be of type Link: %v", err)
+	}
+	childLink, err := childLinkNode.AsLink()
 	if err != nil {
 		t.Fatalf("transaction trie extension node Child should be of kind Link: %v", err)
 	}
@@ -0,0 +1,7 @@
+/*
+Package uncles is the eth-block-list codec of the ommers of a block.
+
+Store stores each ommer as its own eth-block header, so ommers dedupe with the headers of other forks, and Links
+computes their links without storing them.
+*/
+package uncles
@@ -0,0 +1,70 @@
+package uncles
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	dageth_header "github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// Links returns the eth-block links of the headers of the Uncles node, the CIDs the ommers have as headers of
+// their own, so they can be cross-referenced with the canonical headers of other forks
+func Links(node ipld.Node) ([]ipld.Link, error) {
+	return links(node, nil)
+}
+
+// Store stores each header of the Uncles node as its own eth-block through the LinkSystem, so ommer headers
+// dedupe with the headers of the forks they were canonical on, and returns their links
+func Store(lsys ipld.LinkSystem, node ipld.Node) ([]ipld.Link, error) {
+	if lsys.StorageWriteOpener == nil {
+		return nil, fmt.Errorf("no storage configured for writing")
+	}
+	return links(node, func(lnk ipld.Link, enc []byte) error {
+		w, commit, err := lsys.StorageWriteOpener(ipld.LinkContext{})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(enc); err != nil {
+			return err
+		}
+		return commit(lnk)
+	})
+}
+
+// links encodes each header of the Uncles node, and returns their links after passing each to the store function
+func links(node ipld.Node, store func(ipld.Link, []byte) error) ([]ipld.Link, error) {
+	lnks := make([]ipld.Link, 0, node.Length())
+	it := node.ListIterator()
+	if it == nil {
+		return nil, fmt.Errorf("uncles node of kind %s is not a list", node.Kind())
+	}
+	buf := shared.GetBuffer()
+	defer shared.PutBuffer(buf)
+	for !it.Done() {
+		i, headerNode, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		enc, err := dageth_header.AppendEncode((*buf)[:0], headerNode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uncle %d: %v", i, err)
+		}
+		*buf = enc
+		c, err := shared.Keccak256ToCid(dageth_header.MultiCodecType, crypto.Keccak256(enc))
+		if err != nil {
+			return nil, err
+		}
+		lnk := cidlink.Link{Cid: c}
+		if store != nil {
+			if err := store(lnk, enc); err != nil {
+				return nil, fmt.Errorf("unable to store uncle %d: %v", i, err)
+			}
+		}
+		lnks = append(lnks, lnk)
+	}
+	return lnks, nil
+}
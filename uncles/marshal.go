@@ -13,28 +13,51 @@ import (
 	"github.com/vulcanize/go-codec-dageth/shared"
 )
 
+// EncodeOptions can be used to customize the behavior of uncles encoding.
+// The zero value is the default behavior used by the registered codec.
+type EncodeOptions struct {
+	// Header customizes how the headers of the list are encoded
+	Header dageth_header.EncodeOptions
+}
+
 // Encode provides an IPLD codec encode interface for eth uncles IPLDs (header list).
 // This function is registered via the go-ipld-prime link loader for multicodec
 // code 0x91 when this package is invoked via init.
 func Encode(node ipld.Node, w io.Writer) error {
-	// 1KiB can be allocated on the stack, and covers most small nodes
-	// without having to grow the buffer and cause allocations.
-	enc := make([]byte, 0, 1024)
-
-	enc, err := AppendEncode(enc, node)
-	if err != nil {
-		return err
-	}
-	_, err = w.Write(enc)
-	return err
+	return EncodeOptions{}.Encode(node, w)
 }
 
 // AppendEncode is like Encode, but it uses a destination buffer directly.
 // This means less copying of bytes, and if the destination has enough capacity,
 // fewer allocations.
 func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return EncodeOptions{}.AppendEncode(enc, inNode)
+}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// EncodeUncles packs the node into a list of go-ethereum headers
+func EncodeUncles(uncles *[]*types.Header, inNode ipld.Node) error {
+	return EncodeOptions{}.EncodeUncles(uncles, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return shared.WriteEncoded(w, node, cfg.AppendEncode)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	uncles := make([]*types.Header, 0, inNode.Length())
-	if err := EncodeUncles(&uncles, inNode); err != nil {
+	if err := cfg.EncodeUncles(&uncles, inNode); err != nil {
 		return enc, err
 	}
 	wbs := shared.NewWriteableByteSlice(&enc)
@@ -44,8 +67,8 @@ func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
 	return enc, nil
 }
 
-// EncodeUncles packs the node into a list of go-ethereum headers
-func EncodeUncles(uncles *[]*types.Header, inNode ipld.Node) error {
+// EncodeUncles is like the package level EncodeUncles, but uses the provided options
+func (cfg EncodeOptions) EncodeUncles(uncles *[]*types.Header, inNode ipld.Node) error {
 	// Wrap in a typed node for some basic schema form checking
 	builder := dageth.Type.Uncles.NewBuilder()
 	if err := builder.AssignNode(inNode); err != nil {
@@ -59,7 +82,7 @@ func EncodeUncles(uncles *[]*types.Header, inNode ipld.Node) error {
 			return err
 		}
 		uncle := new(types.Header)
-		if err := dageth_header.EncodeHeader(uncle, uncleNode); err != nil {
+		if err := cfg.Header.EncodeHeader(uncle, uncleNode); err != nil {
 			return fmt.Errorf("invalid DAG-ETH Uncles form (%v)", err)
 		}
 		*uncles = append(*uncles, uncle)
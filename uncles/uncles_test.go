@@ -12,10 +12,13 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ipld/go-ipld-prime"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
 	"github.com/multiformats/go-multihash"
 
 	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/codecs"
 	unc "github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/util"
 )
 
 var (
@@ -81,6 +84,7 @@ func TestUnclesCodec(t *testing.T) {
 	testUnclesDecode(t)
 	testUnclesNodeContents(t)
 	testUnclesEncode(t)
+	testUnclesStore(t)
 }
 
 func testUnclesDecode(t *testing.T) {
@@ -340,3 +344,37 @@ func testUnclesEncode(t *testing.T) {
 		t.Errorf("uncles encoding (%x) does not match the expected RLP encoding (%x)", encodedUnclesBytes, unclesRLP)
 	}
 }
+
+func testUnclesStore(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	lnks, err := unc.Store(lsys, unclesNode)
+	if err != nil {
+		t.Fatalf("unable to store uncle headers: %v", err)
+	}
+	expected, err := unc.Links(unclesNode)
+	if err != nil {
+		t.Fatalf("unable to compute uncle header links: %v", err)
+	}
+	if len(lnks) != 2 || len(expected) != 2 {
+		t.Fatalf("expected 2 uncle header links, got %d stored and %d computed", len(lnks), len(expected))
+	}
+	for i, uncle := range []*types.Header{uncle1, uncle2} {
+		if lnks[i] != expected[i] {
+			t.Errorf("uncle %d stored link (%s) does not match the computed link (%s)", i, lnks[i], expected[i])
+		}
+		hash, err := util.LinkToKeccak256(lnks[i])
+		if err != nil {
+			t.Fatalf("invalid uncle %d link: %v", i, err)
+		}
+		if hash != uncle.Hash() {
+			t.Errorf("uncle %d link hash (%s) does not match the header hash (%s)", i, hash.Hex(), uncle.Hash().Hex())
+		}
+		if !util.IsHeaderCID(lnks[i].(cidlink.Link).Cid) {
+			t.Errorf("uncle %d link (%s) is not an eth-block CID", i, lnks[i])
+		}
+		if _, err := lsys.Load(ipld.LinkContext{}, lnks[i], dageth.Type.Header); err != nil {
+			t.Errorf("unable to load uncle %d header: %v", i, err)
+		}
+	}
+}
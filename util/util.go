@@ -0,0 +1,122 @@
+/*
+Package util converts the CIDs of DAG-ETH blocks back into the keccak256 hashes Ethereum references them by, the
+reverse of shared.Keccak256ToCid, and tells the kinds of DAG-ETH CIDs apart by their multicodec type.
+The conversions return an error for malformed CIDs, so they are safe to use on CIDs from untrusted sources.
+*/
+package util
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/log_trie"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/rct_list"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/tx_list"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+// CidToKeccak256 returns the keccak256 hash the CID carries.
+// It returns an error if the CID does not carry a 32 byte keccak256 multihash.
+func CidToKeccak256(c cid.Cid) (common.Hash, error) {
+	if !c.Defined() {
+		return common.Hash{}, fmt.Errorf("undefined CID")
+	}
+	mh, err := multihash.Decode(c.Hash())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if mh.Code != multihash.KECCAK_256 {
+		return common.Hash{}, fmt.Errorf("CID %s carries multihash type %#x, not keccak256", c.String(), mh.Code)
+	}
+	if len(mh.Digest) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("CID %s carries a keccak256 digest of %d bytes, expected %d", c.String(), len(mh.Digest), common.HashLength)
+	}
+	return common.BytesToHash(mh.Digest), nil
+}
+
+// LinkToKeccak256 is like CidToKeccak256, for a link that has to be a cidlink.Link
+func LinkToKeccak256(lnk ipld.Link) (common.Hash, error) {
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+	}
+	return CidToKeccak256(cl.Cid)
+}
+
+// IsHeaderCID returns whether the CID references a header
+func IsHeaderCID(c cid.Cid) bool { return isCodec(c, header.MultiCodecType) }
+
+// IsUnclesCID returns whether the CID references the list of uncles of a block
+func IsUnclesCID(c cid.Cid) bool { return isCodec(c, uncles.MultiCodecType) }
+
+// IsTxCID returns whether the CID references a transaction
+func IsTxCID(c cid.Cid) bool { return isCodec(c, tx.MultiCodecType) }
+
+// IsTxListCID returns whether the CID references the list of transactions of a block
+func IsTxListCID(c cid.Cid) bool { return isCodec(c, tx_list.MultiCodecType) }
+
+// IsTxTrieCID returns whether the CID references a transaction trie node
+func IsTxTrieCID(c cid.Cid) bool { return isCodec(c, tx_trie.MultiCodecType) }
+
+// IsRctCID returns whether the CID references a receipt
+func IsRctCID(c cid.Cid) bool { return isCodec(c, rct.MultiCodecType) }
+
+// IsRctListCID returns whether the CID references the list of receipts of a block
+func IsRctListCID(c cid.Cid) bool { return isCodec(c, rct_list.MultiCodecType) }
+
+// IsRctTrieCID returns whether the CID references a receipt trie node
+func IsRctTrieCID(c cid.Cid) bool { return isCodec(c, rct_trie.MultiCodecType) }
+
+// IsLogCID returns whether the CID references a log
+func IsLogCID(c cid.Cid) bool { return isCodec(c, log.MultiCodecType) }
+
+// IsLogTrieCID returns whether the CID references a log trie node
+func IsLogTrieCID(c cid.Cid) bool { return isCodec(c, log_trie.MultiCodecType) }
+
+// IsStateTrieCID returns whether the CID references a state trie node
+func IsStateTrieCID(c cid.Cid) bool { return isCodec(c, state_trie.MultiCodecType) }
+
+// IsStorageTrieCID returns whether the CID references a storage trie node
+func IsStorageTrieCID(c cid.Cid) bool { return isCodec(c, storage_trie.MultiCodecType) }
+
+// IsAccountCID returns whether the CID references a state account
+func IsAccountCID(c cid.Cid) bool { return isCodec(c, account.MultiCodecType) }
+
+// IsCodeCID returns whether the CID references contract bytecode, a raw block under its keccak256 hash
+func IsCodeCID(c cid.Cid) bool { return isCodec(c, cid.Raw) }
+
+// IsWithdrawalCID returns whether the CID references a withdrawal
+func IsWithdrawalCID(c cid.Cid) bool { return isCodec(c, withdrawal.MultiCodecType) }
+
+// IsWithdrawalTrieCID returns whether the CID references a withdrawal trie node
+func IsWithdrawalTrieCID(c cid.Cid) bool { return isCodec(c, withdrawal_trie.MultiCodecType) }
+
+// IsTrieCID returns whether the CID references a node of any of the tries of the DAG
+func IsTrieCID(c cid.Cid) bool {
+	return IsTxTrieCID(c) || IsRctTrieCID(c) || IsLogTrieCID(c) || IsStateTrieCID(c) || IsStorageTrieCID(c) || IsWithdrawalTrieCID(c)
+}
+
+// isCodec returns whether the CID carries the multicodec type and a keccak256 multihash
+func isCodec(c cid.Cid, codec uint64) bool {
+	if !c.Defined() {
+		return false
+	}
+	prefix := c.Prefix()
+	return prefix.Codec == codec && prefix.MhType == multihash.KECCAK_256
+}
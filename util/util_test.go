@@ -0,0 +1,72 @@
+package util_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipfs/go-cid"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/util"
+)
+
+func TestKeccak256ToCid(t *testing.T) {
+	hash := crypto.Keccak256([]byte("dageth"))
+	c, err := shared.Keccak256ToCid(header.MultiCodecType, hash)
+	if err != nil {
+		t.Fatalf("unable to convert hash to CID: %v", err)
+	}
+	back, err := util.CidToKeccak256(c)
+	if err != nil {
+		t.Fatalf("unable to convert CID to hash: %v", err)
+	}
+	if !bytes.Equal(back.Bytes(), hash) {
+		t.Errorf("expected hash %x, got %x", hash, back)
+	}
+	if back, err := util.LinkToKeccak256(cidlink.Link{Cid: c}); err != nil || !bytes.Equal(back.Bytes(), hash) {
+		t.Errorf("expected link to carry hash %x, got %x (%v)", hash, back, err)
+	}
+
+	if _, err := shared.Keccak256ToCid(header.MultiCodecType, hash[:31]); err == nil {
+		t.Error("expected converting a short hash to fail")
+	}
+	sha, _ := multihash.Sum([]byte("dageth"), multihash.SHA2_256, -1)
+	short, _ := multihash.Encode(hash[:20], multihash.KECCAK_256)
+	for name, c := range map[string]cid.Cid{
+		"undefined CID":       cid.Undef,
+		"sha2-256 CID":        cid.NewCidV1(header.MultiCodecType, sha),
+		"truncated keccak256": cid.NewCidV1(header.MultiCodecType, short),
+	} {
+		if _, err := util.CidToKeccak256(c); err == nil {
+			t.Errorf("expected converting the %s to a hash to fail", name)
+		}
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	hash := crypto.Keccak256([]byte("dageth"))
+	stateCID := testutil.KeccakCid(t, state_trie.MultiCodecType, hash)
+	if !util.IsStateTrieCID(stateCID) || !util.IsTrieCID(stateCID) {
+		t.Errorf("expected %s to be a state trie CID", stateCID.String())
+	}
+	if util.IsStorageTrieCID(stateCID) || util.IsHeaderCID(stateCID) {
+		t.Errorf("expected %s to only be a state trie CID", stateCID.String())
+	}
+	if !util.IsStorageTrieCID(testutil.KeccakCid(t, storage_trie.MultiCodecType, hash)) {
+		t.Error("expected a storage trie CID")
+	}
+	if !util.IsCodeCID(testutil.KeccakCid(t, cid.Raw, hash)) {
+		t.Error("expected a raw keccak256 CID to be a code CID")
+	}
+	sha, _ := multihash.Sum([]byte("dageth"), multihash.SHA2_256, -1)
+	if util.IsCodeCID(cid.NewCidV1(cid.Raw, sha)) || util.IsHeaderCID(cid.Undef) {
+		t.Error("expected CIDs without a keccak256 multihash to match no predicate")
+	}
+}
@@ -0,0 +1,130 @@
+package validate
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/tx"
+)
+
+// DefaultMaxTxSize is the size cap of the encoding of a transaction, that of the go-ethereum transaction pool
+const DefaultMaxTxSize = 128 * 1024
+
+// TxOptions can be used to customize the checks of transactions against the consensus limits of their chain.
+// The zero value checks transactions against the limits of mainnet with every fork up to Prague active.
+type TxOptions struct {
+	// Rules, the forks active at the block the transactions are included in, determine the intrinsic gas of a
+	// transaction, whether its initcode size is limited, and which transaction types are valid
+	Rules *params.Rules
+	// MaxBlobs overrides the maximum number of blobs of a blob transaction, which is otherwise the maximum number of
+	// blobs of a block of the active fork
+	MaxBlobs int
+	// MaxSize overrides DefaultMaxTxSize
+	MaxSize int
+}
+
+var allForks = &params.Rules{
+	IsHomestead: true, IsEIP150: true, IsEIP155: true, IsEIP158: true,
+	IsByzantium: true, IsConstantinople: true, IsPetersburg: true, IsIstanbul: true,
+	IsBerlin: true, IsLondon: true,
+	IsMerge: true, IsShanghai: true, IsCancun: true, IsPrague: true,
+}
+
+// ValidateTransaction checks the decoded transaction against the consensus limits, with the default TxOptions
+func ValidateTransaction(node ipld.Node) error {
+	return TxOptions{}.ValidateTransaction(node)
+}
+
+// ValidateTransaction checks that the decoded transaction is of a type of the active forks, that its encoding is not
+// larger than the size cap, that its gas limit covers its intrinsic gas and, after Prague, the EIP-7623 calldata
+// floor, that the initcode of a contract creation is within the EIP-3860 limit, and that a blob transaction carries
+// at least one and at most the maximum number of blobs
+func (opts TxOptions) ValidateTransaction(node ipld.Node) error {
+	transaction := new(types.Transaction)
+	if err := tx.EncodeTx(transaction, node); err != nil {
+		return err
+	}
+	return opts.CheckTransaction(transaction)
+}
+
+// CheckTransaction is like ValidateTransaction, for a go-ethereum transaction
+func (opts TxOptions) CheckTransaction(transaction *types.Transaction) error {
+	rules := opts.Rules
+	if rules == nil {
+		rules = allForks
+	}
+	if err := checkTxType(transaction.Type(), rules); err != nil {
+		return err
+	}
+	maxSize := opts.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxTxSize
+	}
+	if size := transaction.Size(); size > uint64(maxSize) {
+		return fmt.Errorf("transaction of %d bytes exceeds the size cap of %d bytes", size, maxSize)
+	}
+	creation := transaction.To() == nil
+	if creation && rules.IsShanghai && len(transaction.Data()) > params.MaxInitCodeSize {
+		return fmt.Errorf("initcode of %d bytes exceeds the limit of %d bytes", len(transaction.Data()), params.MaxInitCodeSize)
+	}
+	gas, err := core.IntrinsicGas(transaction.Data(), transaction.AccessList(), transaction.SetCodeAuthorizations(),
+		creation, rules.IsHomestead, rules.IsIstanbul, rules.IsShanghai)
+	if err != nil {
+		return err
+	}
+	if transaction.Gas() < gas {
+		return fmt.Errorf("gas limit %d is below the intrinsic gas %d", transaction.Gas(), gas)
+	}
+	if rules.IsPrague {
+		floor, err := core.FloorDataGas(transaction.Data())
+		if err != nil {
+			return err
+		}
+		if transaction.Gas() < floor {
+			return fmt.Errorf("gas limit %d is below the calldata floor gas %d", transaction.Gas(), floor)
+		}
+	}
+	if transaction.Type() == types.BlobTxType {
+		blobs := len(transaction.BlobHashes())
+		if blobs == 0 {
+			return fmt.Errorf("blob transaction carries no blobs")
+		}
+		maxBlobs := opts.MaxBlobs
+		if maxBlobs == 0 {
+			maxBlobs = params.DefaultCancunBlobConfig.Max
+			if rules.IsPrague {
+				maxBlobs = params.DefaultPragueBlobConfig.Max
+			}
+		}
+		if blobs > maxBlobs {
+			return fmt.Errorf("blob transaction carries %d blobs, more than the maximum of %d", blobs, maxBlobs)
+		}
+	}
+	return nil
+}
+
+func checkTxType(txType uint8, rules *params.Rules) error {
+	var active bool
+	switch txType {
+	case types.LegacyTxType:
+		active = true
+	case types.AccessListTxType:
+		active = rules.IsBerlin
+	case types.DynamicFeeTxType:
+		active = rules.IsLondon
+	case types.BlobTxType:
+		active = rules.IsCancun
+	case types.SetCodeTxType:
+		active = rules.IsPrague
+	default:
+		return fmt.Errorf("unknown transaction type %d", txType)
+	}
+	if !active {
+		return fmt.Errorf("transaction type %d is not valid before its fork", txType)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+/*
+Package validate checks blocks exchanged over Bitswap or Graphsync against the eth-* CIDs they are claimed for,
+before they are stored or handed to a traversal, hardening the exchange layer against garbage.
+
+A block is first checked for the structural kind its codec encodes, e.g. a trie node has to be an RLP list of 2 or
+17 items and a header one of the item counts of its forks, which rejects most garbage without decoding it. It is then
+checked against the hash of its CID, decoded with the DAG-ETH codec of the CID into its schema type, and encoded back
+into the same bytes, so a block that is accepted is the canonical encoding of a valid node. Options.Transactions
+also rejects consensus-invalid transactions, with the checks of ValidateTransaction.
+*/
+package validate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+
+	"github.com/vulcanize/go-codec-dageth/blob_sidecar"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/log"
+	"github.com/vulcanize/go-codec-dageth/log_trie"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	"github.com/vulcanize/go-codec-dageth/rct_list"
+	"github.com/vulcanize/go-codec-dageth/rct_trie"
+	"github.com/vulcanize/go-codec-dageth/request"
+	"github.com/vulcanize/go-codec-dageth/request_list"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/tx_list"
+	"github.com/vulcanize/go-codec-dageth/tx_trace"
+	"github.com/vulcanize/go-codec-dageth/tx_trie"
+	"github.com/vulcanize/go-codec-dageth/uncles"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+// Options can be used to customize validation.
+// The zero value validates DAG-ETH blocks, and only checks blocks of other codecs against the hash of their CID.
+type Options struct {
+	// RejectOtherCodecs causes blocks claimed for CIDs of codecs other than the DAG-ETH codecs to be rejected
+	RejectOtherCodecs bool
+	// Transactions, if set, causes transaction blocks to also be checked against the consensus limits of their chain,
+	// so consensus-invalid transactions are rejected at ingestion
+	Transactions *TxOptions
+}
+
+// Block is a block received for a CID, as exchanged by Bitswap, e.g. a go-block-format block
+type Block interface {
+	Cid() cid.Cid
+	RawData() []byte
+}
+
+// ValidateBlock is like Validate, for a block carrying its CID
+func ValidateBlock(b Block) error {
+	return Options{}.Validate(b.Cid(), b.RawData())
+}
+
+// Validate checks that the data is a valid block for the CID, with the default Options
+func Validate(c cid.Cid, data []byte) error {
+	return Options{}.Validate(c, data)
+}
+
+// ValidateBlock is like the package level ValidateBlock, but uses the provided options
+func (opts Options) ValidateBlock(b Block) error {
+	return opts.Validate(b.Cid(), b.RawData())
+}
+
+// Validate is like the package level Validate, but uses the provided options
+func (opts Options) Validate(c cid.Cid, data []byte) error {
+	codec := c.Prefix().Codec
+	dagEthCodec, ok := codecs.Lookup(codec)
+	if !ok {
+		if opts.RejectOtherCodecs {
+			return fmt.Errorf("CID %s does not carry a dag-eth multicodec type", c.String())
+		}
+		actual, err := c.Prefix().Sum(data)
+		if err != nil {
+			return err
+		}
+		if !actual.Equals(c) {
+			return fmt.Errorf("block hashes to CID %s, not the expected CID %s", actual.String(), c.String())
+		}
+		return nil
+	}
+	if check, ok := kindChecks[codec]; ok {
+		if err := check(data); err != nil {
+			return fmt.Errorf("block cannot be a %s block for CID %s: %v", dagEthCodec.Name, c.String(), err)
+		}
+	}
+	np, err := codecs.NodePrototypeChooser(cidlink.Link{Cid: c}, ipld.LinkContext{})
+	if err != nil {
+		return err
+	}
+	nb := np.NewBuilder()
+	if err := codecs.DecodeVerified(nb, bytes.NewReader(data), c); err != nil {
+		return fmt.Errorf("invalid %s block for CID %s: %v", dagEthCodec.Name, c.String(), err)
+	}
+	node := nb.Build()
+	buf := new(bytes.Buffer)
+	if err := dagEthCodec.Encode(node, buf); err != nil {
+		return fmt.Errorf("invalid %s block for CID %s: %v", dagEthCodec.Name, c.String(), err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		return fmt.Errorf("%s block for CID %s is not canonically encoded", dagEthCodec.Name, c.String())
+	}
+	if codec == tx.MultiCodecType && opts.Transactions != nil {
+		if err := opts.Transactions.ValidateTransaction(node); err != nil {
+			return fmt.Errorf("invalid transaction for CID %s: %v", c.String(), err)
+		}
+	}
+	return nil
+}
+
+// LinkSystem returns a copy of the LinkSystem that validates every block it reads from its storage, and every
+// block written through it before it reaches its storage, e.g. to back a Graphsync exchange.
+// Reading or committing a block that fails validation returns the validation error.
+func LinkSystem(lsys ipld.LinkSystem) ipld.LinkSystem {
+	return Options{}.LinkSystem(lsys)
+}
+
+// LinkSystem is like the package level LinkSystem, but uses the provided options
+func (opts Options) LinkSystem(lsys ipld.LinkSystem) ipld.LinkSystem {
+	if readOpener := lsys.StorageReadOpener; readOpener != nil {
+		lsys.StorageReadOpener = func(lnkCtx ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+			r, err := readOpener(lnkCtx, lnk)
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return nil, err
+			}
+			if err := opts.validateLink(lnk, data); err != nil {
+				return nil, err
+			}
+			return bytes.NewReader(data), nil
+		}
+	}
+	if writeOpener := lsys.StorageWriteOpener; writeOpener != nil {
+		lsys.StorageWriteOpener = func(lnkCtx ipld.LinkContext) (io.Writer, ipld.BlockWriteCommitter, error) {
+			buf := new(bytes.Buffer)
+			return buf, func(lnk ipld.Link) error {
+				if err := opts.validateLink(lnk, buf.Bytes()); err != nil {
+					return err
+				}
+				w, commit, err := writeOpener(lnkCtx)
+				if err != nil {
+					return err
+				}
+				if _, err := w.Write(buf.Bytes()); err != nil {
+					return err
+				}
+				return commit(lnk)
+			}, nil
+		}
+	}
+	return lsys
+}
+
+func (opts Options) validateLink(lnk ipld.Link, data []byte) error {
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+	}
+	return opts.Validate(cl.Cid, data)
+}
+
+// kindChecks check the structural kind of the encodings of the RLP codecs, and of the request codecs
+var kindChecks = map[uint64]func([]byte) error{
+	header.MultiCodecType:          rlpList(15, 16, 17, 19, 20, 21),
+	uncles.MultiCodecType:          rlpListOf(rlpList(15, 16, 17, 19, 20, 21)),
+	tx.MultiCodecType:              typedOrLegacy(types.SetCodeTxType, 9),
+	rct.MultiCodecType:             typedOrLegacy(types.SetCodeTxType, 4),
+	tx_list.MultiCodecType:         rlpList(),
+	rct_list.MultiCodecType:        rlpList(),
+	tx_trace.MultiCodecType:        rlpList(),
+	blob_sidecar.MultiCodecType:    rlpList(),
+	account.MultiCodecType:         rlpList(4),
+	log.MultiCodecType:             rlpList(3),
+	withdrawal.MultiCodecType:      rlpList(4),
+	tx_trie.MultiCodecType:         rlpList(2, 17),
+	rct_trie.MultiCodecType:        rlpList(2, 17),
+	state_trie.MultiCodecType:      rlpList(2, 17),
+	storage_trie.MultiCodecType:    rlpList(2, 17),
+	log_trie.MultiCodecType:        rlpList(2, 17),
+	withdrawal_trie.MultiCodecType: rlpList(2, 17),
+	request.MultiCodecType:         requestKind,
+	request_list.MultiCodecType:    requestListKind,
+}
+
+// rlpList returns a check that the data is a single RLP list holding one of the numbers of items, or any number
+// of items if none are given
+func rlpList(counts ...int) func([]byte) error {
+	return func(data []byte) error {
+		content, err := listContent(data)
+		if err != nil {
+			return err
+		}
+		if len(counts) == 0 {
+			return nil
+		}
+		n, err := rlp.CountValues(content)
+		if err != nil {
+			return err
+		}
+		for _, count := range counts {
+			if n == count {
+				return nil
+			}
+		}
+		return fmt.Errorf("RLP list of %d items, expected %v items", n, counts)
+	}
+}
+
+// rlpListOf returns a check that the data is a single RLP list whose items each pass the check
+func rlpListOf(check func([]byte) error) func([]byte) error {
+	return func(data []byte) error {
+		content, err := listContent(data)
+		if err != nil {
+			return err
+		}
+		for i := 0; len(content) > 0; i++ {
+			_, _, rest, err := rlp.Split(content)
+			if err != nil {
+				return err
+			}
+			if err := check(content[:len(content)-len(rest)]); err != nil {
+				return fmt.Errorf("item %d: %v", i, err)
+			}
+			content = rest
+		}
+		return nil
+	}
+}
+
+// typedOrLegacy returns a check that the data is either an EIP-2718 typed envelope of a type up to maxType holding
+// an RLP list, or a legacy RLP list of the number of items
+func typedOrLegacy(maxType byte, legacyCount int) func([]byte) error {
+	return func(data []byte) error {
+		if len(data) > 0 && data[0] < 0x7f {
+			if data[0] == 0 || data[0] > maxType {
+				return fmt.Errorf("unknown envelope type %#x", data[0])
+			}
+			_, err := listContent(data[1:])
+			return err
+		}
+		return rlpList(legacyCount)(data)
+	}
+}
+
+// listContent returns the content of the RLP list the data consists of
+func listContent(data []byte) ([]byte, error) {
+	kind, content, rest, err := rlp.Split(data)
+	if err != nil {
+		return nil, err
+	}
+	if kind != rlp.List {
+		return nil, fmt.Errorf("RLP %s, expected a list", kind)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%d bytes after the RLP list", len(rest))
+	}
+	return content, nil
+}
+
+func requestKind(data []byte) error {
+	if len(data) == 0 || data[0] > request.ConsolidationRequestType {
+		return fmt.Errorf("expected a request type")
+	}
+	return nil
+}
+
+func requestListKind(data []byte) error {
+	if len(data)%32 != 0 {
+		return fmt.Errorf("%d bytes, expected a multiple of 32 byte hashes", len(data))
+	}
+	return nil
+}
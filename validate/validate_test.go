@@ -0,0 +1,225 @@
+package validate_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/beacon_block"
+	"github.com/vulcanize/go-codec-dageth/beacon_block_body"
+	"github.com/vulcanize/go-codec-dageth/beacon_state"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/request"
+	"github.com/vulcanize/go-codec-dageth/request_list"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/validate"
+)
+
+type block struct {
+	c    cid.Cid
+	data []byte
+}
+
+func (b block) Cid() cid.Cid    { return b.c }
+func (b block) RawData() []byte { return b.data }
+
+func sampleCid(tb testing.TB, codec uint64, data []byte) cid.Cid {
+	var c cid.Cid
+	var err error
+	switch codec {
+	case beacon_block.MultiCodecType:
+		c, err = beacon_block.Cid(data)
+	case beacon_block_body.MultiCodecType:
+		c, err = beacon_block_body.Cid(data)
+	case beacon_state.MultiCodecType:
+		c, err = beacon_state.Cid(data)
+	case request.MultiCodecType, request_list.MultiCodecType:
+		c, err = cid.Prefix{Version: 1, Codec: codec, MhType: multihash.SHA2_256, MhLength: -1}.Sum(data)
+	default:
+		c, err = cid.Prefix{Version: 1, Codec: codec, MhType: multihash.KECCAK_256, MhLength: -1}.Sum(data)
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+func TestValidateSamples(t *testing.T) {
+	for _, sample := range testutil.NewGenerator(1).Samples() {
+		c := sampleCid(t, sample.Codec, sample.Data)
+		if err := validate.ValidateBlock(block{c, sample.Data}); err != nil {
+			t.Errorf("expected %s to be valid: %v", sample.Name, err)
+		}
+		if len(sample.Data) == 0 {
+			continue
+		}
+		changed := append([]byte{}, sample.Data...)
+		changed[len(changed)-1]++
+		if err := validate.Validate(c, changed); err == nil {
+			t.Errorf("expected a changed %s to be rejected for the CID of the original", sample.Name)
+		}
+	}
+}
+
+func TestValidateKind(t *testing.T) {
+	threeItems, _ := rlp.EncodeToBytes([]interface{}{[]byte{1}, []byte{2}, []byte{3}})
+	str, _ := rlp.EncodeToBytes([]byte{1, 2, 3})
+	tests := []struct {
+		name  string
+		codec uint64
+		data  []byte
+		err   string
+	}{
+		{"3 item list as a state trie node", state_trie.MultiCodecType, threeItems, "RLP list of 3 items"},
+		{"3 item list as a header", header.MultiCodecType, threeItems, "RLP list of 3 items"},
+		{"string as a header", header.MultiCodecType, str, "expected a list"},
+		{"list with trailing bytes as a state trie node", state_trie.MultiCodecType, append(threeItems, 0), "bytes after the RLP list"},
+		{"unknown envelope type as a transaction", tx.MultiCodecType, append([]byte{0x05}, threeItems...), "unknown envelope type"},
+		{"3 item list as a legacy transaction", tx.MultiCodecType, threeItems, "RLP list of 3 items"},
+		{"unknown request type", request.MultiCodecType, []byte{0x03}, "expected a request type"},
+		{"partial hash as a request list", request_list.MultiCodecType, make([]byte, 33), "multiple of 32"},
+	}
+	for _, test := range tests {
+		err := validate.Validate(sampleCid(t, test.codec, test.data), test.data)
+		if err == nil {
+			t.Errorf("expected the %s to be rejected", test.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected rejecting the %s to report %q, got: %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestValidateOtherCodecs(t *testing.T) {
+	data := []byte("not an eth block")
+	c, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: multihash.SHA2_256, MhLength: -1}.Sum(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validate.Validate(c, data); err != nil {
+		t.Errorf("expected a raw block to be valid for its CID: %v", err)
+	}
+	if err := validate.Validate(c, data[1:]); err == nil {
+		t.Error("expected a raw block to be rejected for another CID")
+	}
+	if err := (validate.Options{RejectOtherCodecs: true}).Validate(c, data); err == nil {
+		t.Error("expected a raw block to be rejected when other codecs are rejected")
+	}
+}
+
+func TestValidateTransaction(t *testing.T) {
+	to := common.HexToAddress("0x01")
+	blobHash := common.Hash{0x01}
+	tests := []struct {
+		name string
+		opts validate.TxOptions
+		tx   types.TxData
+		err  string
+	}{
+		{"transfer", validate.TxOptions{}, &types.LegacyTx{To: &to, Gas: params.TxGas}, ""},
+		{"transfer below the intrinsic gas", validate.TxOptions{}, &types.LegacyTx{To: &to, Gas: params.TxGas - 1}, "intrinsic gas"},
+		{"creation below the intrinsic gas", validate.TxOptions{}, &types.LegacyTx{Gas: params.TxGas}, "intrinsic gas"},
+		{"calldata below the floor gas", validate.TxOptions{}, &types.DynamicFeeTx{To: &to, Gas: 60_000, Data: bytes.Repeat([]byte{1}, 1000)}, "floor gas"},
+		{"calldata before prague", validate.TxOptions{Rules: &params.Rules{IsHomestead: true, IsIstanbul: true, IsBerlin: true, IsLondon: true}},
+			&types.DynamicFeeTx{To: &to, Gas: 60_000, Data: bytes.Repeat([]byte{1}, 1000)}, ""},
+		{"oversized initcode", validate.TxOptions{}, &types.LegacyTx{Gas: 30_000_000, Data: make([]byte, params.MaxInitCodeSize+1)}, "initcode"},
+		{"oversized encoding", validate.TxOptions{MaxSize: 1024}, &types.LegacyTx{To: &to, Gas: 30_000_000, Data: make([]byte, 1024)}, "size cap"},
+		{"blob transaction", validate.TxOptions{}, &types.BlobTx{To: to, Gas: params.TxGas, BlobHashes: []common.Hash{blobHash}}, ""},
+		{"blob transaction without blobs", validate.TxOptions{}, &types.BlobTx{To: to, Gas: params.TxGas}, "no blobs"},
+		{"blob transaction with too many blobs", validate.TxOptions{MaxBlobs: 1}, &types.BlobTx{To: to, Gas: params.TxGas, BlobHashes: []common.Hash{blobHash, blobHash}}, "maximum of 1"},
+		{"blob transaction before cancun", validate.TxOptions{Rules: &params.Rules{IsHomestead: true, IsBerlin: true, IsLondon: true}},
+			&types.BlobTx{To: to, Gas: params.TxGas, BlobHashes: []common.Hash{blobHash}}, "before its fork"},
+	}
+	for _, test := range tests {
+		transaction := types.NewTx(test.tx)
+		enc, err := transaction.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := sampleCid(t, tx.MultiCodecType, enc)
+		nb := testutil.NodePrototype(t, cidlink.Link{Cid: c}).NewBuilder()
+		if err := tx.DecodeBytes(nb, enc); err != nil {
+			t.Fatalf("%s: unable to decode: %v", test.name, err)
+		}
+		err = test.opts.ValidateTransaction(nb.Build())
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("expected the %s to be valid: %v", test.name, err)
+			}
+			if err := (validate.Options{Transactions: &test.opts}).Validate(c, enc); err != nil {
+				t.Errorf("expected the %s block to be valid: %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected rejecting the %s to report %q, got: %v", test.name, test.err, err)
+		}
+		if err := (validate.Options{Transactions: &test.opts}).Validate(c, enc); err == nil {
+			t.Errorf("expected the %s block to be rejected", test.name)
+		}
+		if err := validate.Validate(c, enc); err != nil {
+			t.Errorf("expected the %s block to be valid without transaction checks: %v", test.name, err)
+		}
+	}
+}
+
+func TestLinkSystem(t *testing.T) {
+	g := testutil.NewGenerator(2)
+	headerRLP, err := rlp.EncodeToBytes(g.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerCID := sampleCid(t, header.MultiCodecType, headerRLP)
+	garbage, _ := rlp.EncodeToBytes([]interface{}{[]byte{1}, []byte{2}, []byte{3}})
+	garbageCID := sampleCid(t, state_trie.MultiCodecType, garbage)
+
+	store := new(storage.Memory)
+	lsys := validate.LinkSystem(codecs.NewLinkSystem(store))
+	ctx := ipld.LinkContext{}
+
+	// blocks written through the LinkSystem, as a Graphsync responder would, are validated before they are stored
+	for _, b := range []block{{headerCID, headerRLP}, {garbageCID, garbage}} {
+		w, commit, err := lsys.StorageWriteOpener(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(b.data)
+		err = commit(cidlink.Link{Cid: b.c})
+		if b.c == headerCID && err != nil {
+			t.Errorf("expected the header to be stored: %v", err)
+		}
+		if b.c == garbageCID && err == nil {
+			t.Error("expected the garbage state trie node to be rejected")
+		}
+	}
+	if _, err := store.OpenRead(ctx, cidlink.Link{Cid: garbageCID}); err == nil {
+		t.Error("expected the garbage state trie node not to be stored")
+	}
+	if _, err := lsys.Load(ctx, cidlink.Link{Cid: headerCID}, testutil.NodePrototype(t, cidlink.Link{Cid: headerCID})); err != nil {
+		t.Errorf("unable to load the header: %v", err)
+	}
+
+	// blocks read from storage, as by a Graphsync requestor traversing them, are validated before they are decoded
+	store.Bag = map[ipld.Link][]byte{cidlink.Link{Cid: garbageCID}: garbage}
+	if _, err := lsys.Load(ctx, cidlink.Link{Cid: garbageCID}, testutil.NodePrototype(t, cidlink.Link{Cid: garbageCID})); err == nil {
+		t.Error("expected loading the garbage state trie node to fail")
+	}
+	store.Bag[cidlink.Link{Cid: headerCID}] = bytes.Repeat([]byte{0xc0}, 2)
+	if _, err := lsys.Load(ctx, cidlink.Link{Cid: headerCID}, testutil.NodePrototype(t, cidlink.Link{Cid: headerCID})); err == nil {
+		t.Error("expected loading a block that does not match its CID to fail")
+	}
+}
@@ -0,0 +1,455 @@
+/*
+Package view wraps decoded DAG-ETH nodes in typed accessors returning the go-ethereum types their fields hold, e.g.
+Header.BaseFee() *big.Int, Transaction.To() *common.Address and Account.Balance() *uint256.Int, so consumers no
+longer need to walk nodes with LookupByString chains and convert the bytes themselves.
+Optional fields that are absent from a node are returned as nil, and links are returned as the keccak256 hashes
+they reference blocks by.
+*/
+package view
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+)
+
+// typed returns the node as a node of the schema type built by the prototype, which it is already if it was
+// loaded or built with the dageth.Type prototypes, or else copies it into one, checking it against the schema
+func typed(node ipld.Node, proto ipld.NodePrototype) (ipld.Node, error) {
+	if node.Prototype() == proto {
+		return node, nil
+	}
+	nb := proto.NewBuilder()
+	if err := nb.AssignNode(node); err != nil {
+		return nil, fmt.Errorf("node does not match the schema: %v", err)
+	}
+	return nb.Build(), nil
+}
+
+// Header is a typed view of a Header node
+type Header struct {
+	n dageth.Header
+}
+
+// NewHeader returns a view of the Header node
+func NewHeader(node ipld.Node) (Header, error) {
+	n, err := typed(node, dageth.Type.Header)
+	if err != nil {
+		return Header{}, err
+	}
+	return Header{n: n.(dageth.Header)}, nil
+}
+
+// Node returns the Header node
+func (h Header) Node() dageth.Header { return h.n }
+
+// ParentHash returns the hash of the parent header
+func (h Header) ParentHash() common.Hash { return linkHash(h.n.FieldParentCID()) }
+
+// UncleHash returns the hash of the uncles list
+func (h Header) UncleHash() common.Hash { return linkHash(h.n.FieldUnclesCID()) }
+
+// Coinbase returns the beneficiary address
+func (h Header) Coinbase() common.Address { return common.BytesToAddress(h.n.FieldCoinbase().Bytes()) }
+
+// Root returns the state root
+func (h Header) Root() common.Hash { return linkHash(h.n.FieldStateRootCID()) }
+
+// TxHash returns the transaction trie root
+func (h Header) TxHash() common.Hash { return linkHash(h.n.FieldTxRootCID()) }
+
+// ReceiptHash returns the receipt trie root
+func (h Header) ReceiptHash() common.Hash { return linkHash(h.n.FieldRctRootCID()) }
+
+// Bloom returns the logs bloom
+func (h Header) Bloom() types.Bloom { return types.BytesToBloom(h.n.FieldBloom().Bytes()) }
+
+// Difficulty returns the difficulty
+func (h Header) Difficulty() *big.Int { return new(big.Int).SetBytes(h.n.FieldDifficulty().Bytes()) }
+
+// Number returns the block number
+func (h Header) Number() *big.Int { return new(big.Int).SetBytes(h.n.FieldNumber().Bytes()) }
+
+// GasLimit returns the gas limit
+func (h Header) GasLimit() uint64 { return toUint64(h.n.FieldGasLimit().Bytes()) }
+
+// GasUsed returns the gas used
+func (h Header) GasUsed() uint64 { return toUint64(h.n.FieldGasUsed().Bytes()) }
+
+// Time returns the timestamp
+func (h Header) Time() uint64 { return toUint64(h.n.FieldTime().Bytes()) }
+
+// Extra returns the extra data
+func (h Header) Extra() []byte { return h.n.FieldExtra().Bytes() }
+
+// MixDigest returns the mix digest, which holds prevrandao after the merge
+func (h Header) MixDigest() common.Hash { return common.BytesToHash(h.n.FieldMixDigest().Bytes()) }
+
+// Nonce returns the block nonce
+func (h Header) Nonce() types.BlockNonce {
+	return types.EncodeNonce(toUint64(h.n.FieldNonce().Bytes()))
+}
+
+// BaseFee returns the EIP-1559 base fee, or nil before London
+func (h Header) BaseFee() *big.Int { return maybeBig(h.n.FieldBaseFee()) }
+
+// WithdrawalsHash returns the withdrawal trie root, or nil before Shanghai
+func (h Header) WithdrawalsHash() *common.Hash { return maybeLinkHash(h.n.FieldWithdrawalsRootCID()) }
+
+// BlobGasUsed returns the EIP-4844 blob gas used, or nil before Cancun
+func (h Header) BlobGasUsed() *uint64 { return maybeUint64(h.n.FieldBlobGasUsed()) }
+
+// ExcessBlobGas returns the EIP-4844 excess blob gas, or nil before Cancun
+func (h Header) ExcessBlobGas() *uint64 { return maybeUint64(h.n.FieldExcessBlobGas()) }
+
+// ParentBeaconRoot returns the EIP-4788 parent beacon block root, or nil before Cancun
+func (h Header) ParentBeaconRoot() *common.Hash { return maybeLinkHash(h.n.FieldParentBeaconRootCID()) }
+
+// RequestsHash returns the EIP-7685 requests hash, or nil before Prague
+func (h Header) RequestsHash() *common.Hash { return maybeLinkHash(h.n.FieldRequestsCID()) }
+
+// Transaction is a typed view of a Transaction node
+type Transaction struct {
+	n dageth.Transaction
+}
+
+// NewTransaction returns a view of the Transaction node
+func NewTransaction(node ipld.Node) (Transaction, error) {
+	n, err := typed(node, dageth.Type.Transaction)
+	if err != nil {
+		return Transaction{}, err
+	}
+	return Transaction{n: n.(dageth.Transaction)}, nil
+}
+
+// Node returns the Transaction node
+func (t Transaction) Node() dageth.Transaction { return t.n }
+
+// Type returns the transaction type
+func (t Transaction) Type() uint8 { return txType(t.n.FieldTxType().Bytes()) }
+
+// ChainID returns the chain ID, or nil for legacy transactions, whose chain ID is derived from V
+func (t Transaction) ChainID() *big.Int { return maybeBig(t.n.FieldChainID()) }
+
+// Nonce returns the sender's account nonce
+func (t Transaction) Nonce() uint64 { return toUint64(t.n.FieldAccountNonce().Bytes()) }
+
+// GasPrice returns the gas price, or nil for dynamic fee transactions
+func (t Transaction) GasPrice() *big.Int { return maybeBig(t.n.FieldGasPrice()) }
+
+// GasTipCap returns the max priority fee per gas, or nil for legacy and access list transactions
+func (t Transaction) GasTipCap() *big.Int { return maybeBig(t.n.FieldGasTipCap()) }
+
+// GasFeeCap returns the max fee per gas, or nil for legacy and access list transactions
+func (t Transaction) GasFeeCap() *big.Int { return maybeBig(t.n.FieldGasFeeCap()) }
+
+// Gas returns the gas limit
+func (t Transaction) Gas() uint64 { return toUint64(t.n.FieldGasLimit().Bytes()) }
+
+// To returns the recipient address, or nil for contract creations
+func (t Transaction) To() *common.Address {
+	m := t.n.FieldRecipient()
+	if !m.Exists() {
+		return nil
+	}
+	to := common.BytesToAddress(m.Must().Bytes())
+	return &to
+}
+
+// Value returns the amount of wei transferred
+func (t Transaction) Value() *big.Int { return new(big.Int).SetBytes(t.n.FieldAmount().Bytes()) }
+
+// Data returns the input data
+func (t Transaction) Data() []byte { return t.n.FieldData().Bytes() }
+
+// AccessList returns the EIP-2930 access list, or nil for legacy transactions
+func (t Transaction) AccessList() types.AccessList {
+	m := t.n.FieldAccessList()
+	if !m.Exists() {
+		return nil
+	}
+	list := m.Must()
+	al := make(types.AccessList, 0, list.Length())
+	for it := list.Iterator(); !it.Done(); {
+		_, elem := it.Next()
+		storageKeys := elem.FieldStorageKeys()
+		keys := make([]common.Hash, 0, storageKeys.Length())
+		for kit := storageKeys.Iterator(); !kit.Done(); {
+			_, key := kit.Next()
+			keys = append(keys, common.BytesToHash(key.Bytes()))
+		}
+		al = append(al, types.AccessTuple{Address: common.BytesToAddress(elem.FieldAddress().Bytes()), StorageKeys: keys})
+	}
+	return al
+}
+
+// BlobGasFeeCap returns the max fee per blob gas, or nil for transactions other than blob transactions
+func (t Transaction) BlobGasFeeCap() *big.Int { return maybeBig(t.n.FieldMaxFeePerBlobGas()) }
+
+// BlobHashes returns the versioned hashes of the blobs, or nil for transactions other than blob transactions
+func (t Transaction) BlobHashes() []common.Hash {
+	m := t.n.FieldBlobVersionedHashes()
+	if !m.Exists() {
+		return nil
+	}
+	list := m.Must()
+	hashes := make([]common.Hash, 0, list.Length())
+	for it := list.Iterator(); !it.Done(); {
+		_, h := it.Next()
+		hashes = append(hashes, common.BytesToHash(h.Bytes()))
+	}
+	return hashes
+}
+
+// SetCodeAuthorizations returns the EIP-7702 authorization list, or nil for transactions other than set code
+// transactions
+func (t Transaction) SetCodeAuthorizations() []types.SetCodeAuthorization {
+	m := t.n.FieldAuthorizationList()
+	if !m.Exists() {
+		return nil
+	}
+	list := m.Must()
+	auths := make([]types.SetCodeAuthorization, 0, list.Length())
+	for it := list.Iterator(); !it.Done(); {
+		_, a := it.Next()
+		auth := types.SetCodeAuthorization{
+			Address: common.BytesToAddress(a.FieldAddress().Bytes()),
+			Nonce:   toUint64(a.FieldNonce().Bytes()),
+			V:       uint8(toUint64(a.FieldYParity().Bytes())),
+		}
+		auth.ChainID.SetBytes(a.FieldChainID().Bytes())
+		auth.R.SetBytes(a.FieldR().Bytes())
+		auth.S.SetBytes(a.FieldS().Bytes())
+		auths = append(auths, auth)
+	}
+	return auths
+}
+
+// RawSignatureValues returns the V, R and S signature values
+func (t Transaction) RawSignatureValues() (v, r, s *big.Int) {
+	return new(big.Int).SetBytes(t.n.FieldV().Bytes()),
+		new(big.Int).SetBytes(t.n.FieldR().Bytes()),
+		new(big.Int).SetBytes(t.n.FieldS().Bytes())
+}
+
+// From returns the sender address, or nil if the node was not decoded with it
+func (t Transaction) From() *common.Address {
+	m := t.n.FieldFrom()
+	if !m.Exists() {
+		return nil
+	}
+	from := common.BytesToAddress(m.Must().Bytes())
+	return &from
+}
+
+// Receipt is a typed view of a Receipt node
+type Receipt struct {
+	n dageth.Receipt
+}
+
+// NewReceipt returns a view of the Receipt node
+func NewReceipt(node ipld.Node) (Receipt, error) {
+	n, err := typed(node, dageth.Type.Receipt)
+	if err != nil {
+		return Receipt{}, err
+	}
+	return Receipt{n: n.(dageth.Receipt)}, nil
+}
+
+// Node returns the Receipt node
+func (r Receipt) Node() dageth.Receipt { return r.n }
+
+// Type returns the type of the transaction of the receipt
+func (r Receipt) Type() uint8 { return txType(r.n.FieldTxType().Bytes()) }
+
+// PostState returns the intermediate state root of pre-Byzantium receipts, or nil for receipts with a status
+func (r Receipt) PostState() []byte {
+	m := r.n.FieldPostState()
+	if !m.Exists() {
+		return nil
+	}
+	return m.Must().Bytes()
+}
+
+// Status returns the status code of the transaction, or nil for pre-Byzantium receipts
+func (r Receipt) Status() *uint64 { return maybeUint64(r.n.FieldStatus()) }
+
+// CumulativeGasUsed returns the gas used in the block up to and including the transaction
+func (r Receipt) CumulativeGasUsed() uint64 { return toUint64(r.n.FieldCumulativeGasUsed().Bytes()) }
+
+// Bloom returns the logs bloom
+func (r Receipt) Bloom() types.Bloom { return types.BytesToBloom(r.n.FieldBloom().Bytes()) }
+
+// Logs returns views of the logs
+func (r Receipt) Logs() []Log {
+	list := r.n.FieldLogs()
+	logs := make([]Log, 0, list.Length())
+	for it := list.Iterator(); !it.Done(); {
+		_, l := it.Next()
+		logs = append(logs, Log{n: l})
+	}
+	return logs
+}
+
+// LogRoot returns the root of the log trie
+func (r Receipt) LogRoot() common.Hash { return linkHash(r.n.FieldLogRootCID()) }
+
+// Log is a typed view of a Log node
+type Log struct {
+	n dageth.Log
+}
+
+// NewLog returns a view of the Log node
+func NewLog(node ipld.Node) (Log, error) {
+	n, err := typed(node, dageth.Type.Log)
+	if err != nil {
+		return Log{}, err
+	}
+	return Log{n: n.(dageth.Log)}, nil
+}
+
+// Node returns the Log node
+func (l Log) Node() dageth.Log { return l.n }
+
+// Address returns the address of the contract that emitted the log
+func (l Log) Address() common.Address { return common.BytesToAddress(l.n.FieldAddress().Bytes()) }
+
+// Topics returns the topics
+func (l Log) Topics() []common.Hash {
+	list := l.n.FieldTopics()
+	topics := make([]common.Hash, 0, list.Length())
+	for it := list.Iterator(); !it.Done(); {
+		_, topic := it.Next()
+		topics = append(topics, common.BytesToHash(topic.Bytes()))
+	}
+	return topics
+}
+
+// Data returns the data
+func (l Log) Data() []byte { return l.n.FieldData().Bytes() }
+
+// Log returns the log as a go-ethereum log, with only its consensus fields set
+func (l Log) Log() *types.Log {
+	return &types.Log{Address: l.Address(), Topics: l.Topics(), Data: l.Data()}
+}
+
+// Account is a typed view of an Account node
+type Account struct {
+	n dageth.Account
+}
+
+// NewAccount returns a view of the Account node
+func NewAccount(node ipld.Node) (Account, error) {
+	n, err := typed(node, dageth.Type.Account)
+	if err != nil {
+		return Account{}, err
+	}
+	return Account{n: n.(dageth.Account)}, nil
+}
+
+// Node returns the Account node
+func (a Account) Node() dageth.Account { return a.n }
+
+// Nonce returns the account nonce
+func (a Account) Nonce() uint64 { return toUint64(a.n.FieldNonce().Bytes()) }
+
+// Balance returns the balance in wei
+func (a Account) Balance() *uint256.Int { return new(uint256.Int).SetBytes(a.n.FieldBalance().Bytes()) }
+
+// Root returns the storage root
+func (a Account) Root() common.Hash { return linkHash(a.n.FieldStorageRootCID()) }
+
+// CodeHash returns the hash of the contract code
+func (a Account) CodeHash() common.Hash { return linkHash(a.n.FieldCodeCID()) }
+
+// Withdrawal is a typed view of a Withdrawal node
+type Withdrawal struct {
+	n dageth.Withdrawal
+}
+
+// NewWithdrawal returns a view of the Withdrawal node
+func NewWithdrawal(node ipld.Node) (Withdrawal, error) {
+	n, err := typed(node, dageth.Type.Withdrawal)
+	if err != nil {
+		return Withdrawal{}, err
+	}
+	return Withdrawal{n: n.(dageth.Withdrawal)}, nil
+}
+
+// Node returns the Withdrawal node
+func (w Withdrawal) Node() dageth.Withdrawal { return w.n }
+
+// Index returns the withdrawal index
+func (w Withdrawal) Index() uint64 { return toUint64(w.n.FieldIndex().Bytes()) }
+
+// Validator returns the index of the validator
+func (w Withdrawal) Validator() uint64 { return toUint64(w.n.FieldValidatorIndex().Bytes()) }
+
+// Address returns the address the withdrawal is credited to
+func (w Withdrawal) Address() common.Address {
+	return common.BytesToAddress(w.n.FieldAddress().Bytes())
+}
+
+// Amount returns the amount in gwei
+func (w Withdrawal) Amount() uint64 { return toUint64(w.n.FieldAmount().Bytes()) }
+
+// toUint64 returns the big-endian unsigned integer of up to 8 bytes
+func toUint64(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// txType returns the transaction type held in a single byte
+func txType(b []byte) uint8 {
+	if len(b) == 0 {
+		return 0
+	}
+	return b[len(b)-1]
+}
+
+// linkHash returns the keccak256 hash the link references its block by, or the zero hash if it is not a CID link
+func linkHash(lnk dageth.Link) common.Hash {
+	cl, ok := lnk.Link().(cidlink.Link)
+	if !ok {
+		return common.Hash{}
+	}
+	decoded, err := multihash.Decode(cl.Cid.Hash())
+	if err != nil {
+		return common.Hash{}
+	}
+	return common.BytesToHash(decoded.Digest)
+}
+
+func maybeLinkHash(m dageth.MaybeLink) *common.Hash {
+	if !m.Exists() {
+		return nil
+	}
+	h := linkHash(m.Must())
+	return &h
+}
+
+func maybeUint64(m dageth.MaybeUint) *uint64 {
+	if !m.Exists() {
+		return nil
+	}
+	v := toUint64(m.Must().Bytes())
+	return &v
+}
+
+func maybeBig(m dageth.MaybeBigInt) *big.Int {
+	if !m.Exists() {
+		return nil
+	}
+	return new(big.Int).SetBytes(m.Must().Bytes())
+}
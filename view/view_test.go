@@ -0,0 +1,176 @@
+package view_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipld/go-ipld-prime"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/rct"
+	account "github.com/vulcanize/go-codec-dageth/state_account"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/tx"
+	"github.com/vulcanize/go-codec-dageth/view"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+)
+
+func TestHeader(t *testing.T) {
+	g := testutil.NewGenerator(11)
+	for _, expected := range g.Headers(20) {
+		nb := dageth.Type.Header.NewBuilder()
+		if err := header.DecodeHeader(nb, *expected); err != nil {
+			t.Fatalf("unable to decode header: %v", err)
+		}
+		// a node of another implementation is copied into the schema type
+		anyNode := basicnode.Prototype.Any.NewBuilder()
+		if err := anyNode.AssignNode(nb.Build()); err != nil {
+			t.Fatalf("unable to copy header: %v", err)
+		}
+		h, err := view.NewHeader(anyNode.Build())
+		if err != nil {
+			t.Fatalf("unable to view header: %v", err)
+		}
+		got := &types.Header{
+			ParentHash:       h.ParentHash(),
+			UncleHash:        h.UncleHash(),
+			Coinbase:         h.Coinbase(),
+			Root:             h.Root(),
+			TxHash:           h.TxHash(),
+			ReceiptHash:      h.ReceiptHash(),
+			Bloom:            h.Bloom(),
+			Difficulty:       h.Difficulty(),
+			Number:           h.Number(),
+			GasLimit:         h.GasLimit(),
+			GasUsed:          h.GasUsed(),
+			Time:             h.Time(),
+			Extra:            h.Extra(),
+			MixDigest:        h.MixDigest(),
+			Nonce:            h.Nonce(),
+			BaseFee:          h.BaseFee(),
+			WithdrawalsHash:  h.WithdrawalsHash(),
+			BlobGasUsed:      h.BlobGasUsed(),
+			ExcessBlobGas:    h.ExcessBlobGas(),
+			ParentBeaconRoot: h.ParentBeaconRoot(),
+			RequestsHash:     h.RequestsHash(),
+		}
+		if got.Hash() != expected.Hash() {
+			t.Errorf("header rebuilt from its view hashes to %x, expected %x", got.Hash(), expected.Hash())
+		}
+	}
+
+	if _, err := view.NewHeader(basicnode.NewString("header")); err == nil {
+		t.Error("expected an error viewing a node that is not a header")
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	g := testutil.NewGenerator(12)
+	for _, txType := range testutil.TxTypes {
+		expected := g.Transaction(txType)
+		nb := dageth.Type.Transaction.NewBuilder()
+		if err := tx.DecodeTx(nb, expected); err != nil {
+			t.Fatalf("unable to decode transaction: %v", err)
+		}
+		v, err := view.NewTransaction(nb.Build())
+		if err != nil {
+			t.Fatalf("unable to view transaction: %v", err)
+		}
+		if v.Type() != expected.Type() || v.Nonce() != expected.Nonce() || v.Gas() != expected.Gas() ||
+			v.Value().Cmp(expected.Value()) != 0 || !bytes.Equal(v.Data(), expected.Data()) {
+			t.Errorf("type %d: view does not match the transaction", txType)
+		}
+		if (v.To() == nil) != (expected.To() == nil) || (v.To() != nil && *v.To() != *expected.To()) {
+			t.Errorf("type %d: expected recipient %v, got %v", txType, expected.To(), v.To())
+		}
+		if txType != types.LegacyTxType {
+			if v.ChainID().Cmp(expected.ChainId()) != 0 || !reflect.DeepEqual(v.AccessList(), expected.AccessList()) {
+				t.Errorf("type %d: expected chain ID %v and access list %v, got %v and %v", txType, expected.ChainId(), expected.AccessList(), v.ChainID(), v.AccessList())
+			}
+		}
+		if txType >= types.DynamicFeeTxType {
+			if v.GasTipCap().Cmp(expected.GasTipCap()) != 0 || v.GasFeeCap().Cmp(expected.GasFeeCap()) != 0 {
+				t.Errorf("type %d: view does not match the fee caps of the transaction", txType)
+			}
+		} else if v.GasPrice().Cmp(expected.GasPrice()) != 0 || v.GasTipCap() != nil {
+			t.Errorf("type %d: view does not match the gas price of the transaction", txType)
+		}
+		if !reflect.DeepEqual(v.BlobHashes(), expected.BlobHashes()) || !reflect.DeepEqual(v.SetCodeAuthorizations(), expected.SetCodeAuthorizations()) {
+			t.Errorf("type %d: view does not match the blob hashes or authorizations of the transaction", txType)
+		}
+		ev, er, es := expected.RawSignatureValues()
+		gv, gr, gs := v.RawSignatureValues()
+		if gv.Cmp(ev) != 0 || gr.Cmp(er) != 0 || gs.Cmp(es) != 0 {
+			t.Errorf("type %d: view does not match the signature of the transaction", txType)
+		}
+	}
+}
+
+func TestReceipt(t *testing.T) {
+	g := testutil.NewGenerator(13)
+	for _, expected := range g.Receipts(g.Transactions(30)) {
+		nb := dageth.Type.Receipt.NewBuilder()
+		if err := rct.DecodeReceipt(nb, *expected); err != nil {
+			t.Fatalf("unable to decode receipt: %v", err)
+		}
+		v, err := view.NewReceipt(nb.Build())
+		if err != nil {
+			t.Fatalf("unable to view receipt: %v", err)
+		}
+		if v.Type() != expected.Type || v.CumulativeGasUsed() != expected.CumulativeGasUsed || v.Bloom() != expected.Bloom {
+			t.Errorf("view does not match the receipt")
+		}
+		if len(expected.PostState) > 0 {
+			if !bytes.Equal(v.PostState(), expected.PostState) || v.Status() != nil {
+				t.Errorf("expected post state %x without a status, got %x", expected.PostState, v.PostState())
+			}
+		} else if v.Status() == nil || *v.Status() != expected.Status || v.PostState() != nil {
+			t.Errorf("expected status %d without a post state", expected.Status)
+		}
+		logs := v.Logs()
+		if len(logs) != len(expected.Logs) {
+			t.Fatalf("expected %d logs, got %d", len(expected.Logs), len(logs))
+		}
+		for i, l := range logs {
+			want := &types.Log{Address: expected.Logs[i].Address, Topics: expected.Logs[i].Topics, Data: expected.Logs[i].Data}
+			if got := l.Log(); got.Address != want.Address || !reflect.DeepEqual(got.Topics, want.Topics) || !bytes.Equal(got.Data, want.Data) {
+				t.Errorf("log %d: expected %+v, got %+v", i, want, got)
+			}
+		}
+	}
+}
+
+func TestAccountAndWithdrawal(t *testing.T) {
+	g := testutil.NewGenerator(14)
+	expected := g.Account()
+	nb := dageth.Type.Account.NewBuilder()
+	if err := account.DecodeAccount(nb, *expected); err != nil {
+		t.Fatalf("unable to decode account: %v", err)
+	}
+	a, err := view.NewAccount(nb.Build())
+	if err != nil {
+		t.Fatalf("unable to view account: %v", err)
+	}
+	if a.Nonce() != expected.Nonce || !a.Balance().Eq(expected.Balance) || a.Root() != expected.Root ||
+		!bytes.Equal(a.CodeHash().Bytes(), expected.CodeHash) {
+		t.Errorf("view does not match the account")
+	}
+
+	w := g.Withdrawal()
+	nb = dageth.Type.Withdrawal.NewBuilder()
+	if err := withdrawal.DecodeWithdrawal(nb, *w); err != nil {
+		t.Fatalf("unable to decode withdrawal: %v", err)
+	}
+	var node ipld.Node = nb.Build()
+	wv, err := view.NewWithdrawal(node)
+	if err != nil {
+		t.Fatalf("unable to view withdrawal: %v", err)
+	}
+	if wv.Index() != w.Index || wv.Validator() != w.Validator || wv.Address() != w.Address || wv.Amount() != w.Amount {
+		t.Errorf("view does not match the withdrawal")
+	}
+}
@@ -0,0 +1,125 @@
+package walk
+
+import (
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// Report is the completeness report of a Survey
+type Report struct {
+	// Present is the number of blocks that were loaded and visited
+	Present int
+	// Missing are the links whose blocks could not be loaded, in the order of the walk
+	Missing []MissingLink
+}
+
+// MissingLink is a link whose block could not be loaded, along with where the walk found it
+type MissingLink struct {
+	Link ipld.Link
+	// Depth is the number of links between the root and the block
+	Depth int
+	// Prefix is the nibble path of the block within its trie, one nibble per byte, if the link is the child of a trie
+	// node; links to the root of a trie, e.g. the StorageRootCID of an account, have an empty prefix
+	Prefix []byte
+	// Err is the error loading the block
+	Err error
+}
+
+// Complete returns whether every block of the walk was loaded
+func (r *Report) Complete() bool {
+	return len(r.Missing) == 0
+}
+
+// MissingByDepth returns the number of missing links at each depth
+func (r *Report) MissingByDepth() map[int]int {
+	counts := make(map[int]int)
+	for _, m := range r.Missing {
+		counts[m.Depth]++
+	}
+	return counts
+}
+
+// MissingByPrefix returns the number of missing trie nodes under each prefix of up to the number of nibbles, keyed
+// by the prefix in hex, e.g. "3a" for the subtrie under nibbles 3 and a, so fetches can be targeted at the subtries
+// missing the most nodes
+func (r *Report) MissingByPrefix(nibbles int) map[string]int {
+	counts := make(map[string]int)
+	for _, m := range r.Missing {
+		prefix := m.Prefix
+		if len(prefix) > nibbles {
+			prefix = prefix[:nibbles]
+		}
+		key := make([]byte, len(prefix))
+		for i, nibble := range prefix {
+			key[i] = "0123456789abcdef"[nibble&0xf]
+		}
+		counts[string(key)]++
+	}
+	return counts
+}
+
+// Survey walks the blocks under the root like Walk, with the default Options, but records the links whose blocks
+// cannot be loaded rather than stopping at them, and returns the Report, e.g. to measure how much of a state trie is
+// pinned and schedule fetches of the subtries that are missing
+func Survey(lsys ipld.LinkSystem, root ipld.Link) (*Report, error) {
+	return Options{}.Survey(lsys, root, nil)
+}
+
+// Survey is like the package level Survey, but uses the provided options, and calls fn, if not nil, with every
+// block that is loaded as Walk does. It only returns an error if fn does.
+func (opts Options) Survey(lsys ipld.LinkSystem, root ipld.Link, fn VisitFunc) (*Report, error) {
+	if fn == nil {
+		fn = func(ipld.Link, ipld.Node) error { return nil }
+	}
+	report := new(Report)
+	if err := opts.walk(lsys, root, fn, report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// trieChildPrefixes returns the nibble paths of the nodes the trie node links to, extending the prefix of the node,
+// or nil if the node is not a trie node
+func trieChildPrefixes(node ipld.Node, prefix []byte) map[ipld.Link][]byte {
+	n, kind, err := trie.NodeAndKind(node)
+	if err != nil {
+		return nil
+	}
+	prefixes := make(map[ipld.Link][]byte)
+	switch kind {
+	case trie.BRANCH_NODE:
+		for i := 0; i < 16; i++ {
+			child, err := n.LookupByString(fmt.Sprintf("Child%X", i))
+			if err != nil || child.IsNull() {
+				continue
+			}
+			if linkNode, err := child.LookupByString("Link"); err == nil {
+				if lnk, err := linkNode.AsLink(); err == nil {
+					prefixes[lnk] = append(prefix[:len(prefix):len(prefix)], byte(i))
+				}
+			}
+		}
+	case trie.EXTENSION_NODE:
+		ppNode, err := n.LookupByString("PartialPath")
+		if err != nil {
+			return nil
+		}
+		partialPath, err := ppNode.AsBytes()
+		if err != nil {
+			return nil
+		}
+		child, err := n.LookupByString("Child")
+		if err != nil {
+			return nil
+		}
+		if linkNode, err := child.LookupByString("Link"); err == nil {
+			if lnk, err := linkNode.AsLink(); err == nil {
+				prefixes[lnk] = append(prefix[:len(prefix):len(prefix)], partialPath...)
+			}
+		}
+	}
+	return prefixes
+}
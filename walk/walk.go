@@ -0,0 +1,201 @@
+/*
+Package walk walks DAG-ETH structures depth first, loading the blocks a node links to concurrently on a bounded
+pool of workers, e.g. the 16 children of a branch node, while the callback is still called in the same order as
+a sequential walk would, so deep tries can be walked over network backed LinkSystems without paying the latency of
+every block in turn. Survey walks a partially pinned structure and reports the links it could not load.
+*/
+package walk
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/metrics"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// defaultWorkers is the number of blocks loaded concurrently when Options.Workers is zero
+const defaultWorkers = 16
+
+// SkipChildren can be returned by a VisitFunc to walk on without descending into the links of the visited node
+var SkipChildren = errors.New("skip children")
+
+// VisitFunc is called with the link and node of each block of the walk
+type VisitFunc func(lnk ipld.Link, node ipld.Node) error
+
+// Options can be used to customize walks.
+// The zero value is the default behavior used by Walk.
+type Options struct {
+	// Workers bounds the number of blocks loaded concurrently; it defaults to 16, and 1 loads one block at a time
+	Workers int
+	// Follow decides whether the walk descends into a link found in a node. By default links to DAG-ETH blocks are
+	// followed, except links to headers, which would walk the whole chain through ParentCID, and links to empty
+	// tries, which have no block behind them.
+	Follow func(lnk ipld.Link) bool
+	// Metrics, if set, receives the blocks loaded by the walk and the depth it reaches below the root
+	Metrics metrics.Recorder
+}
+
+// Walk loads the block the root link references through the LinkSystem, calls fn with it, and then walks the
+// blocks it links to in the same way, depth first and in the order the links appear in the node. Blocks reached
+// through more than one link are walked once.
+// The blocks a node links to are loaded in the background as soon as the node has been visited, see
+// Options.Workers, but fn is called from a single goroutine in the order of a sequential walk.
+// Walk stops at the first error returned by fn, other than SkipChildren, or by loading a block, and returns it.
+func Walk(lsys ipld.LinkSystem, root ipld.Link, fn VisitFunc) error {
+	return Options{}.Walk(lsys, root, fn)
+}
+
+// Walk is like the package level Walk, but uses the provided options
+func (opts Options) Walk(lsys ipld.LinkSystem, root ipld.Link, fn VisitFunc) error {
+	return opts.walk(lsys, root, fn, nil)
+}
+
+// walk walks the blocks under the root, recording the blocks it cannot load in the report rather than stopping at
+// them if the report is not nil
+func (opts Options) walk(lsys ipld.LinkSystem, root ipld.Link, fn VisitFunc, report *Report) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	follow := opts.Follow
+	if follow == nil {
+		follow = DefaultFollow
+	}
+	rec := metrics.OrNop(opts.Metrics)
+	w := &walker{lsys: metrics.Instrument(lsys, opts.Metrics), slots: make(chan struct{}, workers), stop: make(chan struct{})}
+	// the loaders are done with the LinkSystem by the time the walk returns
+	defer func() {
+		close(w.stop)
+		w.wg.Wait()
+	}()
+	seen := map[ipld.Link]bool{root: true}
+	stack := []*pending{w.load(root, 0, nil)}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		<-p.done
+		if p.err != nil {
+			if report == nil {
+				return p.err
+			}
+			report.Missing = append(report.Missing, MissingLink{Link: p.link, Depth: p.depth, Prefix: p.prefix, Err: p.err})
+			continue
+		}
+		if report != nil {
+			report.Present++
+		}
+		rec.DepthReached(p.depth)
+		if err := fn(p.link, p.node); err != nil {
+			if err == SkipChildren {
+				continue
+			}
+			return err
+		}
+		var children []*pending
+		prefixes := trieChildPrefixes(p.node, p.prefix)
+		for _, lnk := range collectLinks(p.node, nil) {
+			if seen[lnk] || !follow(lnk) {
+				continue
+			}
+			seen[lnk] = true
+			children = append(children, w.load(lnk, p.depth+1, prefixes[lnk]))
+		}
+		// pushed in reverse, so the first child is walked first
+		for i := len(children) - 1; i >= 0; i-- {
+			stack = append(stack, children[i])
+		}
+	}
+	return nil
+}
+
+// DefaultFollow is the Options.Follow used by default: it follows links to DAG-ETH blocks other than headers and
+// the roots of empty tries
+func DefaultFollow(lnk ipld.Link) bool {
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return false
+	}
+	codec := cl.Cid.Prefix().Codec
+	if _, ok := codecs.Lookup(codec); !ok || codec == header.MultiCodecType {
+		return false
+	}
+	return !shared.HasHash(cl.Cid, multihash.KECCAK_256, types.EmptyRootHash.Bytes())
+}
+
+// walker loads blocks on a bounded number of goroutines
+type walker struct {
+	lsys  ipld.LinkSystem
+	slots chan struct{}
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// pending is a block being loaded at a depth below the root, and at a nibble prefix within its trie if it is a trie
+// node; done is closed once node or err is set
+type pending struct {
+	link   ipld.Link
+	depth  int
+	prefix []byte
+	node   ipld.Node
+	err    error
+	done   chan struct{}
+}
+
+// load starts loading the block of the link as soon as a worker slot is free
+func (w *walker) load(lnk ipld.Link, depth int, prefix []byte) *pending {
+	p := &pending{link: lnk, depth: depth, prefix: prefix, done: make(chan struct{})}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer close(p.done)
+		select {
+		case w.slots <- struct{}{}:
+		case <-w.stop:
+			return
+		}
+		defer func() { <-w.slots }()
+		np, err := codecs.NodePrototypeChooser(lnk, ipld.LinkContext{})
+		if err != nil {
+			p.err = err
+			return
+		}
+		p.node, p.err = w.lsys.Load(ipld.LinkContext{}, lnk, np)
+	}()
+	return p
+}
+
+// collectLinks appends every link found in the node, in iteration order, to links
+func collectLinks(node ipld.Node, links []ipld.Link) []ipld.Link {
+	switch node.Kind() {
+	case ipld.Kind_Link:
+		lnk, err := node.AsLink()
+		if err == nil {
+			links = append(links, lnk)
+		}
+	case ipld.Kind_Map:
+		for it := node.MapIterator(); !it.Done(); {
+			_, v, err := it.Next()
+			if err != nil {
+				break
+			}
+			links = collectLinks(v, links)
+		}
+	case ipld.Kind_List:
+		for it := node.ListIterator(); !it.Done(); {
+			_, v, err := it.Next()
+			if err != nil {
+				break
+			}
+			links = collectLinks(v, links)
+		}
+	}
+	return links
+}
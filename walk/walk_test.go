@@ -0,0 +1,182 @@
+package walk_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/block"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/trie"
+	"github.com/vulcanize/go-codec-dageth/walk"
+)
+
+// slowLinkSystem returns a LinkSystem over the store that takes a while to read each block, as a network backed
+// one would
+func slowLinkSystem(store *storage.Memory) ipld.LinkSystem {
+	lsys := codecs.NewLinkSystem(store)
+	read := lsys.StorageReadOpener
+	lsys.StorageReadOpener = func(ctx ipld.LinkContext, lnk ipld.Link) (io.Reader, error) {
+		time.Sleep(time.Millisecond)
+		return read(ctx, lnk)
+	}
+	return lsys
+}
+
+// walkLinks returns the links of the walk in the order they were visited
+func walkLinks(t *testing.T, opts walk.Options, lsys ipld.LinkSystem, root ipld.Link) []ipld.Link {
+	var links []ipld.Link
+	if err := opts.Walk(lsys, root, func(lnk ipld.Link, _ ipld.Node) error {
+		links = append(links, lnk)
+		return nil
+	}); err != nil {
+		t.Fatalf("unable to walk: %v", err)
+	}
+	return links
+}
+
+// checkSameOrder fails the test unless both walks visited the same links in the same order
+func checkSameOrder(t *testing.T, parallel, sequential []ipld.Link) {
+	if len(parallel) != len(sequential) {
+		t.Fatalf("parallel walk visited %d blocks, sequential walk %d", len(parallel), len(sequential))
+	}
+	for i := range sequential {
+		if parallel[i] != sequential[i] {
+			t.Fatalf("parallel walk visited %s at position %d, sequential walk %s", parallel[i], i, sequential[i])
+		}
+	}
+}
+
+func TestWalkTrie(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	root, _ := testutil.BuildStorageTrie(t, codecs.NewLinkSystem(store), 500)
+	lsys := slowLinkSystem(store)
+
+	sequential := walkLinks(t, walk.Options{Workers: 1}, lsys, root)
+	if len(sequential) != len(store.Bag) {
+		t.Fatalf("expected the walk to visit the %d blocks of the trie, visited %d", len(store.Bag), len(sequential))
+	}
+	checkSameOrder(t, walkLinks(t, walk.Options{Workers: 16}, lsys, root), sequential)
+
+	// skipping the children of the root visits nothing else
+	visited := 0
+	if err := walk.Walk(lsys, root, func(ipld.Link, ipld.Node) error {
+		visited++
+		return walk.SkipChildren
+	}); err != nil {
+		t.Fatalf("unable to walk: %v", err)
+	}
+	if visited != 1 {
+		t.Errorf("expected SkipChildren on the root to visit 1 block, visited %d", visited)
+	}
+
+	stop := errors.New("stop")
+	visited = 0
+	if err := walk.Walk(lsys, root, func(ipld.Link, ipld.Node) error {
+		if visited++; visited == 10 {
+			return stop
+		}
+		return nil
+	}); err != stop {
+		t.Errorf("expected the walk to return the error of the callback, got %v", err)
+	}
+}
+
+func TestWalkBlock(t *testing.T) {
+	blk, receipts := testutil.NewGenerator(3).Block(20)
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	headerLink, err := block.PackBlock(blk, receipts, codecs.NewLinkSystem(store))
+	if err != nil {
+		t.Fatalf("unable to pack block: %v", err)
+	}
+	lsys := slowLinkSystem(store)
+	// the state the header links to is not part of the packed block
+	body := func(lnk ipld.Link) bool {
+		return walk.DefaultFollow(lnk) && lnk.(cidlink.Link).Cid.Prefix().Codec != state_trie.MultiCodecType
+	}
+	sequential := walkLinks(t, walk.Options{Workers: 1, Follow: body}, lsys, headerLink)
+	// the header along with its transaction, receipt, log and withdrawal tries and uncles, but not its parent
+	if len(sequential) < 4 || sequential[0] != headerLink {
+		t.Fatalf("expected the walk to start at the header and visit the tries of the block, visited %d blocks", len(sequential))
+	}
+	seen := make(map[ipld.Link]bool, len(sequential))
+	for _, lnk := range sequential {
+		if _, ok := store.Bag[lnk]; !ok || seen[lnk] {
+			t.Fatalf("expected the walk to visit blocks of the packed block once, visited %s again or outside of it", lnk)
+		}
+		seen[lnk] = true
+	}
+	checkSameOrder(t, walkLinks(t, walk.Options{Follow: body}, lsys, headerLink), sequential)
+
+	// following the parent fails, as it is not in the store
+	follow := func(ipld.Link) bool { return true }
+	if err := (walk.Options{Follow: follow}).Walk(lsys, headerLink, func(ipld.Link, ipld.Node) error { return nil }); err == nil {
+		t.Error("expected an error walking into a parent header missing from the store")
+	}
+}
+
+func TestSurvey(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	root, _ := testutil.BuildStorageTrie(t, codecs.NewLinkSystem(store), 500)
+	lsys := codecs.NewLinkSystem(store)
+
+	report, err := walk.Survey(lsys, root)
+	if err != nil {
+		t.Fatalf("unable to survey: %v", err)
+	}
+	if !report.Complete() || report.Present != len(store.Bag) {
+		t.Fatalf("expected a complete report of %d blocks, got %d present and %d missing", len(store.Bag), report.Present, len(report.Missing))
+	}
+
+	// unpin the subtries under nibbles 3 and a of the root branch
+	rootNode, err := lsys.Load(ipld.LinkContext{}, root, testutil.NodePrototype(t, root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch, _, err := trie.NodeAndKind(rootNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nibble := range []string{"3", "A"} {
+		child, _ := branch.LookupByString("Child" + nibble)
+		linkNode, err := child.LookupByString("Link")
+		if err != nil {
+			t.Fatalf("expected child %s of the root to be a link: %v", nibble, err)
+		}
+		lnk, _ := linkNode.AsLink()
+		delete(store.Bag, lnk)
+	}
+	report, err = (walk.Options{Workers: 4}).Survey(lsys, root, nil)
+	if err != nil {
+		t.Fatalf("unable to survey: %v", err)
+	}
+	if report.Complete() || len(report.Missing) != 2 {
+		t.Fatalf("expected 2 missing links, got %d", len(report.Missing))
+	}
+	if report.Present >= len(store.Bag) || report.Present == 0 {
+		t.Errorf("expected the blocks under the missing links not to be visited, %d of %d present", report.Present, len(store.Bag))
+	}
+	if byDepth := report.MissingByDepth(); byDepth[1] != 2 {
+		t.Errorf("expected both missing links at depth 1, got %v", byDepth)
+	}
+	byPrefix := report.MissingByPrefix(1)
+	if byPrefix["3"] != 1 || byPrefix["a"] != 1 {
+		t.Errorf("expected missing links under prefixes 3 and a, got %v", byPrefix)
+	}
+
+	// the callback still sees every block that is present
+	visited := 0
+	if _, err := (walk.Options{}).Survey(lsys, root, func(ipld.Link, ipld.Node) error {
+		visited++
+		return nil
+	}); err != nil || visited != report.Present {
+		t.Errorf("expected the callback to visit the %d present blocks, visited %d (%v)", report.Present, visited, err)
+	}
+}
@@ -0,0 +1,146 @@
+package withdrawal
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// Encode provides an IPLD codec encode interface for eth withdrawal IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0xf2 when this package is invoked via init.
+func Encode(node ipld.Node, w io.Writer) error {
+	return shared.WriteEncoded(w, node, AppendEncode)
+}
+
+// AppendEncode is like Encode, but it uses a destination buffer directly.
+// This means less copying of bytes, and if the destination has enough capacity,
+// fewer allocations.
+func AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	withdrawal := new(types.Withdrawal)
+	if err := EncodeWithdrawal(withdrawal, inNode); err != nil {
+		return nil, err
+	}
+	wbs := shared.NewWriteableByteSlice(&enc)
+	if err := rlp.Encode(wbs, withdrawal); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+// EncodeOptions can be used to customize the behavior of withdrawal encoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type EncodeOptions struct{}
+
+// EncodeWithOptions is like Encode, but uses the provided options to customize encoding
+func EncodeWithOptions(node ipld.Node, w io.Writer, opts EncodeOptions) error {
+	return opts.Encode(node, w)
+}
+
+// AppendEncodeWithOptions is like AppendEncode, but uses the provided options to customize encoding
+func AppendEncodeWithOptions(enc []byte, inNode ipld.Node, opts EncodeOptions) ([]byte, error) {
+	return opts.AppendEncode(enc, inNode)
+}
+
+// Encode is like the package level Encode, but uses the provided options
+func (cfg EncodeOptions) Encode(node ipld.Node, w io.Writer) error {
+	return Encode(node, w)
+}
+
+// AppendEncode is like the package level AppendEncode, but uses the provided options
+func (cfg EncodeOptions) AppendEncode(enc []byte, inNode ipld.Node) ([]byte, error) {
+	return AppendEncode(enc, inNode)
+}
+
+// EncodeWithdrawal packs the node into the go-ethereum Withdrawal
+func EncodeWithdrawal(withdrawal *types.Withdrawal, inNode ipld.Node) error {
+	// Wrap in a typed node for some basic schema form checking
+	builder := dageth.Type.Withdrawal.NewBuilder()
+	if err := builder.AssignNode(inNode); err != nil {
+		return err
+	}
+	node := builder.Build()
+	for _, pFunc := range requiredPackFuncs {
+		if err := pFunc(withdrawal, node); err != nil {
+			return fmt.Errorf("invalid DAG-ETH Withdrawal form (%v)", err)
+		}
+	}
+	return nil
+}
+
+var requiredPackFuncs = []func(*types.Withdrawal, ipld.Node) error{
+	packIndex,
+	packValidatorIndex,
+	packAddress,
+	packAmount,
+}
+
+func packIndex(withdrawal *types.Withdrawal, node ipld.Node) error {
+	indexNode, err := node.LookupByString("Index")
+	if err != nil {
+		return fmt.Errorf("withdrawal is missing an Index node: %v", err)
+	}
+	indexBytes, err := indexNode.AsBytes()
+	if err != nil {
+		return err
+	}
+	withdrawal.Index, err = shared.BytesToUint64(indexBytes)
+	if err != nil {
+		return fmt.Errorf("Index %v", err)
+	}
+	return nil
+}
+
+func packValidatorIndex(withdrawal *types.Withdrawal, node ipld.Node) error {
+	validatorNode, err := node.LookupByString("ValidatorIndex")
+	if err != nil {
+		return fmt.Errorf("withdrawal is missing a ValidatorIndex node: %v", err)
+	}
+	validatorBytes, err := validatorNode.AsBytes()
+	if err != nil {
+		return err
+	}
+	withdrawal.Validator, err = shared.BytesToUint64(validatorBytes)
+	if err != nil {
+		return fmt.Errorf("ValidatorIndex %v", err)
+	}
+	return nil
+}
+
+func packAddress(withdrawal *types.Withdrawal, node ipld.Node) error {
+	addrNode, err := node.LookupByString("Address")
+	if err != nil {
+		return fmt.Errorf("withdrawal is missing an Address node: %v", err)
+	}
+	addrBytes, err := addrNode.AsBytes()
+	if err != nil {
+		return err
+	}
+	withdrawal.Address, err = shared.BytesToAddress(addrBytes)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func packAmount(withdrawal *types.Withdrawal, node ipld.Node) error {
+	amountNode, err := node.LookupByString("Amount")
+	if err != nil {
+		return fmt.Errorf("withdrawal is missing an Amount node: %v", err)
+	}
+	amountBytes, err := amountNode.AsBytes()
+	if err != nil {
+		return err
+	}
+	withdrawal.Amount, err = shared.BytesToUint64(amountBytes)
+	if err != nil {
+		return fmt.Errorf("Amount %v", err)
+	}
+	return nil
+}
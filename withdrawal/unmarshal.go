@@ -0,0 +1,135 @@
+package withdrawal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
+)
+
+// Decode provides an IPLD codec decode interface for eth withdrawal IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0xf2 when this package is invoked via init.
+func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	var src []byte
+	if buf, ok := in.(interface{ Bytes() []byte }); ok {
+		src = buf.Bytes()
+	} else {
+		var err error
+		src, err = ioutil.ReadAll(in)
+		if err != nil {
+			return err
+		}
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	withdrawal := new(types.Withdrawal)
+	if err := rlp.DecodeBytes(src, withdrawal); err != nil {
+		return err
+	}
+	return DecodeWithdrawal(na, *withdrawal)
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeOptions can be used to customize the behavior of withdrawal decoding.
+// It has no fields yet, its zero value is the behavior used by the registered codec.
+type DecodeOptions struct{}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts DecodeOptions) error {
+	return opts.Decode(na, in)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts DecodeOptions) error {
+	return opts.DecodeBytes(na, src)
+}
+
+// Decode is like the package level Decode, but uses the provided options
+func (cfg DecodeOptions) Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return Decode(na, in)
+}
+
+// DecodeBytes is like the package level DecodeBytes, but uses the provided options
+func (cfg DecodeOptions) DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return DecodeBytes(na, src)
+}
+
+// DecodeWithdrawal unpacks a go-ethereum Withdrawal into the NodeAssembler
+func DecodeWithdrawal(na ipld.NodeAssembler, withdrawal types.Withdrawal) error {
+	ma, err := na.BeginMap(4)
+	if err != nil {
+		return err
+	}
+	for _, upFunc := range requiredUnpackFuncs {
+		if err := upFunc(ma, withdrawal); err != nil {
+			return fmt.Errorf("invalid DAG-ETH Withdrawal binary (%v)", err)
+		}
+	}
+	return ma.Finish()
+}
+
+var requiredUnpackFuncs = []func(ipld.MapAssembler, types.Withdrawal) error{
+	unpackIndex,
+	unpackValidatorIndex,
+	unpackAddress,
+	unpackAmount,
+}
+
+func unpackIndex(ma ipld.MapAssembler, withdrawal types.Withdrawal) error {
+	if err := ma.AssembleKey().AssignString("Index"); err != nil {
+		return err
+	}
+	indexBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(indexBytes, withdrawal.Index)
+	return ma.AssembleValue().AssignBytes(indexBytes)
+}
+
+func unpackValidatorIndex(ma ipld.MapAssembler, withdrawal types.Withdrawal) error {
+	if err := ma.AssembleKey().AssignString("ValidatorIndex"); err != nil {
+		return err
+	}
+	validatorBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(validatorBytes, withdrawal.Validator)
+	return ma.AssembleValue().AssignBytes(validatorBytes)
+}
+
+func unpackAddress(ma ipld.MapAssembler, withdrawal types.Withdrawal) error {
+	if err := ma.AssembleKey().AssignString("Address"); err != nil {
+		return err
+	}
+	return ma.AssembleValue().AssignBytes(withdrawal.Address.Bytes())
+}
+
+func unpackAmount(ma ipld.MapAssembler, withdrawal types.Withdrawal) error {
+	if err := ma.AssembleKey().AssignString("Amount"); err != nil {
+		return err
+	}
+	amountBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(amountBytes, withdrawal.Amount)
+	return ma.AssembleValue().AssignBytes(amountBytes)
+}
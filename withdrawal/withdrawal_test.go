@@ -0,0 +1,134 @@
+package withdrawal_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipld/go-ipld-prime"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+)
+
+var (
+	mockWithdrawal = &types.Withdrawal{
+		Index:     15213,
+		Validator: 588614,
+		Address:   common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b"),
+		Amount:    32000000000,
+	}
+	withdrawalEncoding, _ = rlp.EncodeToBytes(mockWithdrawal)
+	withdrawalNode        ipld.Node
+)
+
+/* IPLD Schemas
+type Withdrawal struct {
+	Index          Uint
+	ValidatorIndex Uint
+	Address        Address
+	Amount         Uint
+}
+*/
+
+func TestWithdrawalCodec(t *testing.T) {
+	testWithdrawalDecoding(t)
+	testWithdrawalNodeContents(t)
+	testWithdrawalEncoding(t)
+}
+
+func testWithdrawalDecoding(t *testing.T) {
+	withdrawalBuilder := dageth.Type.Withdrawal.NewBuilder()
+	withdrawalReader := bytes.NewReader(withdrawalEncoding)
+	if err := withdrawal.Decode(withdrawalBuilder, withdrawalReader); err != nil {
+		t.Fatalf("unable to decode withdrawal into an IPLD node: %v", err)
+	}
+	withdrawalNode = withdrawalBuilder.Build()
+}
+
+func testWithdrawalNodeContents(t *testing.T) {
+	for key, expected := range map[string]uint64{
+		"Index":          mockWithdrawal.Index,
+		"ValidatorIndex": mockWithdrawal.Validator,
+		"Amount":         mockWithdrawal.Amount,
+	} {
+		n, err := withdrawalNode.LookupByString(key)
+		if err != nil {
+			t.Fatalf("withdrawal is missing %s: %v", key, err)
+		}
+		nBytes, err := n.AsBytes()
+		if err != nil {
+			t.Fatalf("withdrawal %s should be of type Bytes: %v", key, err)
+		}
+		if val := binary.BigEndian.Uint64(nBytes); val != expected {
+			t.Errorf("withdrawal %s (%d) does not match expected value (%d)", key, val, expected)
+		}
+	}
+
+	addressNode, err := withdrawalNode.LookupByString("Address")
+	if err != nil {
+		t.Fatalf("withdrawal is missing Address: %v", err)
+	}
+	addrBytes, err := addressNode.AsBytes()
+	if err != nil {
+		t.Fatalf("withdrawal Address should be of type Bytes")
+	}
+	if !bytes.Equal(addrBytes, mockWithdrawal.Address.Bytes()) {
+		t.Errorf("withdrawal Address (%x) does not match expected Address (%x)", addrBytes, mockWithdrawal.Address.Bytes())
+	}
+}
+
+func testWithdrawalEncoding(t *testing.T) {
+	withdrawalWriter := new(bytes.Buffer)
+	if err := withdrawal.Encode(withdrawalNode, withdrawalWriter); err != nil {
+		t.Fatalf("unable to encode withdrawal into writer: %v", err)
+	}
+	withdrawalBytes := withdrawalWriter.Bytes()
+	if !bytes.Equal(withdrawalBytes, withdrawalEncoding) {
+		t.Errorf("withdrawal encoding (%x) does not match the expected consensus encoding (%x)", withdrawalBytes, withdrawalEncoding)
+	}
+}
+
+func TestUintFieldLengths(t *testing.T) {
+	withdrawalNode := func(index []byte) ipld.Node {
+		nb := basicnode.Prototype.Map.NewBuilder()
+		ma, _ := nb.BeginMap(4)
+		for _, field := range []struct {
+			key string
+			val []byte
+		}{
+			{"Index", index},
+			{"ValidatorIndex", shared.Uint64ToBytes(mockWithdrawal.Validator)},
+			{"Address", mockWithdrawal.Address.Bytes()},
+			{"Amount", shared.Uint64ToBytes(mockWithdrawal.Amount)},
+		} {
+			ma.AssembleKey().AssignString(field.key)
+			ma.AssembleValue().AssignBytes(field.val)
+		}
+		ma.Finish()
+		return nb.Build()
+	}
+	for _, test := range []struct {
+		name  string
+		index []byte
+	}{
+		{"shorter than 8 bytes", []byte{0x3b, 0x6d}},
+		{"zero padded to more than 8 bytes", append(make([]byte, 3), shared.Uint64ToBytes(mockWithdrawal.Index)...)},
+	} {
+		enc := new(bytes.Buffer)
+		if err := withdrawal.Encode(withdrawalNode(test.index), enc); err != nil {
+			t.Fatalf("unable to encode withdrawal with an Index %s: %v", test.name, err)
+		}
+		if !bytes.Equal(enc.Bytes(), withdrawalEncoding) {
+			t.Errorf("withdrawal with an Index %s encoding (%x) does not match the expected consensus encoding (%x)", test.name, enc.Bytes(), withdrawalEncoding)
+		}
+	}
+	if err := withdrawal.Encode(withdrawalNode(append([]byte{1}, shared.Uint64ToBytes(mockWithdrawal.Index)...)), new(bytes.Buffer)); err == nil {
+		t.Error("expected encoding a withdrawal with an Index overflowing 64 bits to fail")
+	}
+}
@@ -0,0 +1,50 @@
+package withdrawal_trie
+
+import (
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+
+	"github.com/vulcanize/go-codec-dageth/shared"
+	dageth_trie "github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// Decode provides an IPLD codec decode interface for eth withdrawal trie node IPLDs.
+// This function is registered via the go-ipld-prime link loader for multicodec
+// code 0x9f when this package is invoked via init.
+// This simply wraps dageth_trie.DecodeTrieNode with the proper multicodec type
+func Decode(na ipld.NodeAssembler, in io.Reader) error {
+	return dageth_trie.DecodeTrieNode(na, in, MultiCodecType)
+}
+
+// DecodeBytes is like Decode, but it uses an input buffer directly.
+// Decode will grab or read all the bytes from an io.Reader anyway, so this can
+// save having to copy the bytes or create a bytes.Buffer.
+// This simply wraps dageth_trie.DecodeTrieNodeBytes with the proper multicodec type
+func DecodeBytes(na ipld.NodeAssembler, src []byte) error {
+	return dageth_trie.DecodeTrieNodeBytes(na, src, MultiCodecType)
+}
+
+// DecodeVerified is like Decode, but it first checks that the input hashes to the expected CID, and that the CID
+// carries the multicodec type of this codec, for decoding blocks from untrusted sources
+func DecodeVerified(na ipld.NodeAssembler, in io.Reader, expected cid.Cid) error {
+	src, err := shared.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if err := shared.VerifyCID(src, expected, MultiCodecType); err != nil {
+		return err
+	}
+	return DecodeBytes(na, src)
+}
+
+// DecodeWithOptions is like Decode, but uses the provided options to customize decoding
+func DecodeWithOptions(na ipld.NodeAssembler, in io.Reader, opts dageth_trie.DecodeOptions) error {
+	return opts.DecodeTrieNode(na, in, MultiCodecType)
+}
+
+// DecodeBytesWithOptions is like DecodeBytes, but uses the provided options to customize decoding
+func DecodeBytesWithOptions(na ipld.NodeAssembler, src []byte, opts dageth_trie.DecodeOptions) error {
+	return opts.DecodeTrieNodeBytes(na, src, MultiCodecType)
+}
@@ -0,0 +1,166 @@
+package withdrawal_trie_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	gethtrie "github.com/ethereum/go-ethereum/trie"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/storage"
+	"github.com/ipld/go-ipld-prime/traversal"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/trie"
+	"github.com/vulcanize/go-codec-dageth/withdrawal"
+	"github.com/vulcanize/go-codec-dageth/withdrawal_trie"
+)
+
+var (
+	mockWithdrawal = &types.Withdrawal{
+		Index:     1,
+		Validator: 42,
+		Address:   common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b"),
+		Amount:    32000000000,
+	}
+	mockWithdrawalVal, _ = rlp.EncodeToBytes(mockWithdrawal)
+	// the withdrawal at index 0 of a block is keyed by rlp(0) = 0x80
+	mockLeafPartialPath = shared.HexToCompact([]byte{8, 0, 16})
+	mockLeafNodeRLP, _  = rlp.EncodeToBytes([]interface{}{
+		mockLeafPartialPath,
+		mockWithdrawalVal,
+	})
+)
+
+/* IPLD Schemas
+type TrieNode union {
+	| TrieBranchNode "branch"
+	| TrieExtensionNode "extension"
+	| TrieLeafNode "leaf"
+} representation keyed
+
+type Value union {
+	| Transaction "tx"
+	| Receipt "rct"
+	| Account "state"
+	| Bytes "storage"
+	| Log "log"
+	| Withdrawal "withdrawal"
+} representation keyed
+
+type Withdrawal struct {
+	Index          Uint
+	ValidatorIndex Uint
+	Address        Address
+	Amount         Uint
+}
+
+type TrieLeafNode struct {
+	PartialPath Bytes
+	Value       Value
+}
+*/
+
+func TestWithdrawalTrieCodec(t *testing.T) {
+	// a single withdrawal trie is a lone leaf node, so its hash is the withdrawals root
+	withdrawalsRoot := types.DeriveSha(types.Withdrawals{mockWithdrawal}, gethtrie.NewStackTrie(nil))
+	if leafHash := crypto.Keccak256Hash(mockLeafNodeRLP); leafHash != withdrawalsRoot {
+		t.Fatalf("withdrawal trie leaf hash (%s) does not match the withdrawals root (%s)", leafHash.Hex(), withdrawalsRoot.Hex())
+	}
+
+	leafNodeBuilder := dageth.Type.TrieNode.NewBuilder()
+	if err := withdrawal_trie.Decode(leafNodeBuilder, bytes.NewReader(mockLeafNodeRLP)); err != nil {
+		t.Fatalf("unable to decode withdrawal trie leaf node into an IPLD node: %v", err)
+	}
+	leafNode := leafNodeBuilder.Build()
+	leaf, err := leafNode.LookupByString(trie.LEAF_NODE.String())
+	if err != nil {
+		t.Fatalf("withdrawal trie leaf node missing enum key: %v", err)
+	}
+	valUnionNode, err := leaf.LookupByString("Value")
+	if err != nil {
+		t.Fatalf("withdrawal trie leaf node missing Value: %v", err)
+	}
+	valNode, valKind, err := trie.ValueAndKind(valUnionNode)
+	if err != nil {
+		t.Fatalf("unable to select withdrawal trie leaf value: %v", err)
+	}
+	if valKind != trie.WITHDRAWAL_VALUE {
+		t.Fatalf("withdrawal trie leaf value should be of kind %s, got %s", trie.WITHDRAWAL_VALUE, valKind)
+	}
+	indexNode, err := valNode.LookupByString("Index")
+	if err != nil {
+		t.Fatalf("withdrawal trie leaf value is missing Index: %v", err)
+	}
+	indexBytes, err := indexNode.AsBytes()
+	if err != nil {
+		t.Fatalf("withdrawal Index should be of type Bytes: %v", err)
+	}
+	if index := binary.BigEndian.Uint64(indexBytes); index != mockWithdrawal.Index {
+		t.Errorf("withdrawal Index (%d) does not match expected Index (%d)", index, mockWithdrawal.Index)
+	}
+	valBuf := new(bytes.Buffer)
+	if err := withdrawal.Encode(valNode, valBuf); err != nil {
+		t.Fatalf("unable to encode withdrawal trie leaf value: %v", err)
+	}
+	if !bytes.Equal(valBuf.Bytes(), mockWithdrawalVal) {
+		t.Errorf("withdrawal trie leaf value encoding (%x) does not match expected value (%x)", valBuf.Bytes(), mockWithdrawalVal)
+	}
+
+	leafWriter := new(bytes.Buffer)
+	if err := withdrawal_trie.Encode(leafNode, leafWriter); err != nil {
+		t.Fatalf("unable to encode withdrawal trie leaf node into writer: %v", err)
+	}
+	if !bytes.Equal(leafWriter.Bytes(), mockLeafNodeRLP) {
+		t.Errorf("withdrawal trie leaf node encoding (%x) does not match the expected RLP encoding (%x)", leafWriter.Bytes(), mockLeafNodeRLP)
+	}
+}
+
+func TestHeaderToWithdrawalTraversal(t *testing.T) {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	withdrawalsRoot := crypto.Keccak256(mockLeafNodeRLP)
+	store.Bag[testutil.KeccakLink(t, withdrawal_trie.MultiCodecType, withdrawalsRoot)] = mockLeafNodeRLP
+
+	withdrawalsHash := common.BytesToHash(withdrawalsRoot)
+	shanghaiHeader := &types.Header{
+		Difficulty:      big.NewInt(0),
+		Number:          big.NewInt(17034870),
+		BaseFee:         big.NewInt(1000000000),
+		WithdrawalsHash: &withdrawalsHash,
+	}
+	headerRLP, err := rlp.EncodeToBytes(shanghaiHeader)
+	if err != nil {
+		t.Fatalf("unable to RLP encode header: %v", err)
+	}
+	headerBuilder := dageth.Type.Header.NewBuilder()
+	if err := header.DecodeBytes(headerBuilder, headerRLP); err != nil {
+		t.Fatalf("unable to decode header: %v", err)
+	}
+
+	prog := traversal.Progress{Cfg: &traversal.Config{
+		LinkSystem:                     codecs.NewLinkSystem(store),
+		LinkTargetNodePrototypeChooser: codecs.NodePrototypeChooser,
+	}}
+	path := ipld.ParsePath("WithdrawalsRootCID/TrieLeafNode/Value/Withdrawal/Amount")
+	if err := prog.Focus(headerBuilder.Build(), path, func(_ traversal.Progress, n ipld.Node) error {
+		amountBytes, err := n.AsBytes()
+		if err != nil {
+			return err
+		}
+		if amount := binary.BigEndian.Uint64(amountBytes); amount != mockWithdrawal.Amount {
+			t.Errorf("withdrawal leaf Amount (%d) does not match expected Amount (%d)", amount, mockWithdrawal.Amount)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("unable to traverse from header to withdrawal leaf: %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+/*
+Package witness encodes and decodes the execution witnesses of stateless clients, which bundle the state trie nodes,
+contract codes and ancestor headers a block reads when it is executed, in the RLP encoding go-ethereum exchanges them
+in.
+
+A witness is stored as DAG-ETH blocks: its headers as eth-block headers, its codes as raw blocks and its trie nodes as
+state and storage trie nodes, tied together by a dag-cbor container listing the links to them, so the witness of a
+block can be fetched and traversed like the rest of its DAG. Verify checks that a witness holds every node needed to
+look up the accounts and slots a block accesses.
+*/
+package witness
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	_ "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/multiformats/go-multihash"
+
+	dageth "github.com/vulcanize/go-codec-dageth"
+	"github.com/vulcanize/go-codec-dageth/header"
+	"github.com/vulcanize/go-codec-dageth/proof"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/trie"
+)
+
+// Witness is the execution witness of a block
+type Witness struct {
+	// Headers are the parent of the block, whose state root the witness is rooted at, followed by the older
+	// ancestors whose hashes the block reads, each the parent of the one before it
+	Headers []*types.Header
+	// Codes are the contract codes the block reads
+	Codes [][]byte
+	// State are the RLP encoded state and storage trie nodes the block reads
+	State [][]byte
+}
+
+// Decode decodes the RLP encoding of a witness
+func Decode(data []byte) (*Witness, error) {
+	w := new(Witness)
+	if err := rlp.DecodeBytes(data, w); err != nil {
+		return nil, fmt.Errorf("invalid execution witness (%v)", err)
+	}
+	return w, nil
+}
+
+// Encode returns the RLP encoding of the witness
+func (w *Witness) Encode() ([]byte, error) {
+	return rlp.EncodeToBytes(w)
+}
+
+// Root returns the state root the witness is rooted at, that of its first header
+func (w *Witness) Root() (common.Hash, error) {
+	if len(w.Headers) == 0 {
+		return common.Hash{}, fmt.Errorf("witness has no headers")
+	}
+	return w.Headers[0].Root, nil
+}
+
+// ContainerPrefix is the CID prefix of the dag-cbor containers Store writes
+var ContainerPrefix = cid.Prefix{Version: 1, Codec: cid.DagCBOR, MhType: multihash.SHA2_256, MhLength: -1}
+
+// Store writes the headers, codes and trie nodes of the witness through the LinkSystem as DAG-ETH blocks, and a
+// dag-cbor container with the Headers, Codes and State lists of links to them, in the order of the witness, and
+// returns the link to the container.
+// The codec of each trie node is found by walking the tries from the state root, so every node of the witness has
+// to be reachable from the state root or from the storage root of an account in it.
+func (w *Witness) Store(lsys ipld.LinkSystem) (ipld.Link, error) {
+	codecs, err := w.trieCodecs()
+	if err != nil {
+		return nil, err
+	}
+	headerLinks := make([]cid.Cid, len(w.Headers))
+	for i, h := range w.Headers {
+		enc, err := rlp.EncodeToBytes(h)
+		if err != nil {
+			return nil, err
+		}
+		if headerLinks[i], err = storeBlock(lsys, header.MultiCodecType, enc); err != nil {
+			return nil, err
+		}
+	}
+	codeLinks := make([]cid.Cid, len(w.Codes))
+	for i, code := range w.Codes {
+		if codeLinks[i], err = storeBlock(lsys, cid.Raw, code); err != nil {
+			return nil, err
+		}
+	}
+	stateLinks := make([]cid.Cid, len(w.State))
+	for i, node := range w.State {
+		if stateLinks[i], err = storeBlock(lsys, codecs[crypto.Keccak256Hash(node)], node); err != nil {
+			return nil, err
+		}
+	}
+	nb := basicnode.Prototype.Map.NewBuilder()
+	ma, err := nb.BeginMap(3)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range []struct {
+		name  string
+		links []cid.Cid
+	}{{"Headers", headerLinks}, {"Codes", codeLinks}, {"State", stateLinks}} {
+		la, err := ma.AssembleEntry(field.name)
+		if err != nil {
+			return nil, err
+		}
+		if err := assembleLinks(la, field.links); err != nil {
+			return nil, err
+		}
+	}
+	if err := ma.Finish(); err != nil {
+		return nil, err
+	}
+	return lsys.Store(ipld.LinkContext{}, cidlink.LinkPrototype{Prefix: ContainerPrefix}, nb.Build())
+}
+
+// Load reads the container the link references through the LinkSystem, along with the blocks it links to, and
+// returns the witness. Every block is checked against the hash of its CID.
+func Load(lsys ipld.LinkSystem, lnk ipld.Link) (*Witness, error) {
+	lsys.TrustedStorage = false
+	container, err := lsys.Load(ipld.LinkContext{}, lnk, basicnode.Prototype.Map)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load witness container: %v", err)
+	}
+	w := new(Witness)
+	headers, err := readLinked(lsys, container, "Headers")
+	if err != nil {
+		return nil, err
+	}
+	for i, enc := range headers {
+		h := new(types.Header)
+		if err := rlp.DecodeBytes(enc, h); err != nil {
+			return nil, fmt.Errorf("invalid witness header %d: %v", i, err)
+		}
+		w.Headers = append(w.Headers, h)
+	}
+	if w.Codes, err = readLinked(lsys, container, "Codes"); err != nil {
+		return nil, err
+	}
+	if w.State, err = readLinked(lsys, container, "State"); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Verify checks that the headers of the witness form a chain of ancestors, and that the witness suffices to look up
+// every account and storage slot of the access list from its state root: that it holds the trie nodes on the paths
+// to them, or proving them absent, and the code of every account it finds
+func (w *Witness) Verify(access types.AccessList) error {
+	root, err := w.Root()
+	if err != nil {
+		return err
+	}
+	for i := 1; i < len(w.Headers); i++ {
+		if w.Headers[i-1].ParentHash != w.Headers[i].Hash() {
+			return fmt.Errorf("witness header %d is not the parent of header %d", i, i-1)
+		}
+	}
+	codes := make(map[common.Hash]bool, len(w.Codes))
+	for _, code := range w.Codes {
+		codes[crypto.Keccak256Hash(code)] = true
+	}
+	stateRootCID, err := shared.Keccak256ToCid(state_trie.MultiCodecType, root.Bytes())
+	if err != nil {
+		return err
+	}
+	stateRoot := cidlink.Link{Cid: stateRootCID}
+	for _, tuple := range access {
+		val, err := proof.Verify(stateRoot, crypto.Keccak256(tuple.Address.Bytes()), w.State)
+		if err != nil {
+			return fmt.Errorf("witness cannot look up account %s: %v", tuple.Address.Hex(), err)
+		}
+		if val == nil {
+			continue
+		}
+		account, err := val.LookupByString(trie.STATE_VALUE.String())
+		if err != nil {
+			return err
+		}
+		codeHash, err := linkedHash(account, "CodeCID")
+		if err != nil {
+			return err
+		}
+		if codeHash != types.EmptyCodeHash && !codes[codeHash] {
+			return fmt.Errorf("witness is missing the code %s of account %s", codeHash.Hex(), tuple.Address.Hex())
+		}
+		storageRoot, err := linkedHash(account, "StorageRootCID")
+		if err != nil {
+			return err
+		}
+		if storageRoot == types.EmptyRootHash {
+			continue
+		}
+		storageRootCID, err := shared.Keccak256ToCid(storage_trie.MultiCodecType, storageRoot.Bytes())
+		if err != nil {
+			return err
+		}
+		storageRootLink := cidlink.Link{Cid: storageRootCID}
+		for _, slot := range tuple.StorageKeys {
+			if _, err := proof.Verify(storageRootLink, crypto.Keccak256(slot.Bytes()), w.State); err != nil {
+				return fmt.Errorf("witness cannot look up slot %s of account %s: %v", slot.Hex(), tuple.Address.Hex(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// trieCodecs walks the tries of the witness from its state root, and returns the codec of every trie node by hash
+func (w *Witness) trieCodecs() (map[common.Hash]uint64, error) {
+	nodes := make(map[common.Hash][]byte, len(w.State))
+	for _, node := range w.State {
+		nodes[crypto.Keccak256Hash(node)] = node
+	}
+	codecs := make(map[common.Hash]uint64, len(w.State))
+	if len(w.State) == 0 {
+		return codecs, nil
+	}
+	root, err := w.Root()
+	if err != nil {
+		return nil, err
+	}
+	rootCID, err := shared.Keccak256ToCid(state_trie.MultiCodecType, root.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	queue := []cid.Cid{rootCID}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		hash := common.BytesToHash(c.Hash()[2:])
+		enc, ok := nodes[hash]
+		if !ok {
+			continue
+		}
+		if _, ok := codecs[hash]; ok {
+			continue
+		}
+		codec := c.Prefix().Codec
+		codecs[hash] = codec
+		nb := dageth.Type.TrieNode.NewBuilder()
+		if err := trie.DecodeTrieNodeBytes(nb, enc, codec); err != nil {
+			return nil, fmt.Errorf("invalid witness trie node %s: %v", hash.Hex(), err)
+		}
+		for _, lnk := range collectLinks(nb.Build(), nil) {
+			child := lnk.(cidlink.Link).Cid
+			if codec := child.Prefix().Codec; codec == state_trie.MultiCodecType || codec == storage_trie.MultiCodecType {
+				queue = append(queue, child)
+			}
+		}
+	}
+	for hash := range nodes {
+		if _, ok := codecs[hash]; !ok {
+			return nil, fmt.Errorf("witness trie node %s is not reachable from its state root", hash.Hex())
+		}
+	}
+	return codecs, nil
+}
+
+// storeBlock writes the encoded block through the LinkSystem under its keccak256 CID of the codec
+func storeBlock(lsys ipld.LinkSystem, codec uint64, enc []byte) (cid.Cid, error) {
+	if lsys.StorageWriteOpener == nil {
+		return cid.Undef, fmt.Errorf("no storage configured for writing")
+	}
+	c, err := shared.Keccak256ToCid(codec, crypto.Keccak256(enc))
+	if err != nil {
+		return cid.Undef, err
+	}
+	wr, commit, err := lsys.StorageWriteOpener(ipld.LinkContext{})
+	if err != nil {
+		return cid.Undef, err
+	}
+	if _, err := wr.Write(enc); err != nil {
+		return cid.Undef, err
+	}
+	return c, commit(cidlink.Link{Cid: c})
+}
+
+// readLinked reads the blocks the list of links of the field of the container references, checking each of them
+// against its CID
+func readLinked(lsys ipld.LinkSystem, container ipld.Node, field string) ([][]byte, error) {
+	list, err := container.LookupByString(field)
+	if err != nil {
+		return nil, fmt.Errorf("witness container is missing %s: %v", field, err)
+	}
+	if lsys.StorageReadOpener == nil {
+		return nil, fmt.Errorf("no storage configured for reading")
+	}
+	blocks := make([][]byte, 0, list.Length())
+	for it := list.ListIterator(); it != nil && !it.Done(); {
+		i, linkNode, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		lnk, err := linkNode.AsLink()
+		if err != nil {
+			return nil, fmt.Errorf("%s %d: %v", field, i, err)
+		}
+		r, err := lsys.StorageReadOpener(ipld.LinkContext{}, lnk)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s %d: %v", field, i, err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		c := lnk.(cidlink.Link).Cid
+		if err := shared.VerifyCID(data, c, c.Prefix().Codec); err != nil {
+			return nil, fmt.Errorf("%s %d: %v", field, i, err)
+		}
+		blocks = append(blocks, data)
+	}
+	return blocks, nil
+}
+
+func assembleLinks(na ipld.NodeAssembler, links []cid.Cid) error {
+	la, err := na.BeginList(int64(len(links)))
+	if err != nil {
+		return err
+	}
+	for _, c := range links {
+		if err := la.AssembleValue().AssignLink(cidlink.Link{Cid: c}); err != nil {
+			return err
+		}
+	}
+	return la.Finish()
+}
+
+// linkedHash returns the keccak256 hash the link of the field of the node carries
+func linkedHash(node ipld.Node, field string) (common.Hash, error) {
+	linkNode, err := node.LookupByString(field)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	lnk, err := linkNode.AsLink()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	cl, ok := lnk.(cidlink.Link)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("expected a cidlink.Link, got %T", lnk)
+	}
+	return common.BytesToHash(cl.Hash()[2:]), nil
+}
+
+// collectLinks appends every link found in the node, in iteration order, to links
+func collectLinks(node ipld.Node, links []ipld.Link) []ipld.Link {
+	switch node.Kind() {
+	case ipld.Kind_Link:
+		if lnk, err := node.AsLink(); err == nil {
+			links = append(links, lnk)
+		}
+	case ipld.Kind_Map:
+		for it := node.MapIterator(); !it.Done(); {
+			_, v, err := it.Next()
+			if err != nil {
+				break
+			}
+			links = collectLinks(v, links)
+		}
+	case ipld.Kind_List:
+		for it := node.ListIterator(); !it.Done(); {
+			_, v, err := it.Next()
+			if err != nil {
+				break
+			}
+			links = collectLinks(v, links)
+		}
+	}
+	return links
+}
@@ -0,0 +1,206 @@
+package witness_test
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/storage"
+
+	"github.com/vulcanize/go-codec-dageth/codecs"
+	"github.com/vulcanize/go-codec-dageth/proof"
+	"github.com/vulcanize/go-codec-dageth/shared"
+	"github.com/vulcanize/go-codec-dageth/state_trie"
+	"github.com/vulcanize/go-codec-dageth/storage_trie"
+	"github.com/vulcanize/go-codec-dageth/testutil"
+	"github.com/vulcanize/go-codec-dageth/witness"
+)
+
+var (
+	contract = common.BigToAddress(big.NewInt(7))
+	eoa      = common.BigToAddress(big.NewInt(42))
+	absent   = common.HexToAddress("0xdeadbeef")
+	slots    = []common.Hash{common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(2)), common.BigToHash(big.NewInt(3))}
+	code     = []byte{0x60, 0x00, 0x60, 0x00, 0xfd}
+)
+
+// mockWitness builds a state holding a contract with storage and code among other accounts, and returns the witness
+// of the access list, gathered from the proofs of its accounts and slots, rooted at a parent header of that state
+func mockWitness(t *testing.T, access types.AccessList) *witness.Witness {
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	storageRoot := testutil.BuildTrie(t, store, storage_trie.MultiCodecType, map[string][]byte{
+		string(crypto.Keccak256(slots[0].Bytes())): {0x01},
+		string(crypto.Keccak256(slots[1].Bytes())): {0x02},
+		string(crypto.Keccak256(slots[2].Bytes())): {0x03},
+	})
+	accounts := make(map[string][]byte)
+	for i := int64(1); i <= 100; i++ {
+		acct := &types.StateAccount{
+			Nonce:    uint64(i),
+			Balance:  uint256.NewInt(uint64(i)),
+			Root:     types.EmptyRootHash,
+			CodeHash: types.EmptyCodeHash.Bytes(),
+		}
+		addr := common.BigToAddress(big.NewInt(i))
+		if addr == contract {
+			acct.Root, acct.CodeHash = storageRoot, crypto.Keccak256(code)
+		}
+		enc, _ := rlp.EncodeToBytes(acct)
+		accounts[string(crypto.Keccak256(addr.Bytes()))] = enc
+	}
+	stateRoot := testutil.BuildTrie(t, store, state_trie.MultiCodecType, accounts)
+	lsys := codecs.NewLinkSystem(store)
+
+	w := new(witness.Witness)
+	seen := make(map[common.Hash]bool)
+	addProof := func(codec uint64, root common.Hash, key []byte) {
+		nodes, err := proof.GenerateProof(lsys, testutil.KeccakLink(t, codec, root.Bytes()), key)
+		if err != nil {
+			t.Fatalf("unable to generate proof: %v", err)
+		}
+		for _, node := range nodes {
+			if hash := crypto.Keccak256Hash(node.RLP); !seen[hash] {
+				seen[hash] = true
+				w.State = append(w.State, node.RLP)
+			}
+		}
+	}
+	for _, tuple := range access {
+		addProof(state_trie.MultiCodecType, stateRoot, crypto.Keccak256(tuple.Address.Bytes()))
+		if tuple.Address == contract {
+			w.Codes = append(w.Codes, code)
+		}
+		for _, slot := range tuple.StorageKeys {
+			addProof(storage_trie.MultiCodecType, storageRoot, crypto.Keccak256(slot.Bytes()))
+		}
+	}
+	grandparent := &types.Header{Number: big.NewInt(9), Difficulty: big.NewInt(0), Root: shared.RandomHash()}
+	parent := &types.Header{Number: big.NewInt(10), Difficulty: big.NewInt(0), Root: stateRoot, ParentHash: grandparent.Hash()}
+	w.Headers = []*types.Header{parent, grandparent}
+	return w
+}
+
+var access = types.AccessList{
+	{Address: contract, StorageKeys: []common.Hash{slots[0], slots[2]}},
+	{Address: eoa},
+	{Address: absent},
+}
+
+func TestEncodeDecode(t *testing.T) {
+	w := mockWitness(t, access)
+	enc, err := w.Encode()
+	if err != nil {
+		t.Fatalf("unable to encode witness: %v", err)
+	}
+	decoded, err := witness.Decode(enc)
+	if err != nil {
+		t.Fatalf("unable to decode witness: %v", err)
+	}
+	reenc, _ := decoded.Encode()
+	if !bytes.Equal(reenc, enc) {
+		t.Error("re-encoding the decoded witness does not give back its encoding")
+	}
+
+	// go-ethereum reads the same encoding
+	gw := new(stateless.Witness)
+	if err := rlp.DecodeBytes(enc, gw); err != nil {
+		t.Fatalf("go-ethereum is unable to decode the witness: %v", err)
+	}
+	if len(gw.Headers) != len(w.Headers) || len(gw.Codes) != len(w.Codes) || len(gw.State) != len(w.State) {
+		t.Errorf("go-ethereum decoded %d headers, %d codes and %d nodes, expected %d, %d and %d",
+			len(gw.Headers), len(gw.Codes), len(gw.State), len(w.Headers), len(w.Codes), len(w.State))
+	}
+	if gw.Root() != w.Headers[0].Root {
+		t.Errorf("go-ethereum roots the witness at %s, expected %s", gw.Root().Hex(), w.Headers[0].Root.Hex())
+	}
+
+	if _, err := witness.Decode([]byte{0x01}); err == nil {
+		t.Error("expected an error decoding garbage")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	w := mockWitness(t, access)
+	if err := w.Verify(access); err != nil {
+		t.Fatalf("expected the witness to suffice for its access list: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		access types.AccessList
+		change func(*witness.Witness)
+		err    string
+	}{
+		{"slot outside of the witness", types.AccessList{{Address: contract, StorageKeys: []common.Hash{slots[1]}}}, nil, "slot"},
+		{"account outside of the witness", types.AccessList{{Address: common.BigToAddress(big.NewInt(99))}}, nil, "account"},
+		{"missing code", access, func(w *witness.Witness) { w.Codes = nil }, "code"},
+		{"broken header chain", access, func(w *witness.Witness) { w.Headers[1] = &types.Header{Number: big.NewInt(8)} }, "parent"},
+		{"no headers", access, func(w *witness.Witness) { w.Headers = nil }, "no headers"},
+	}
+	for _, test := range tests {
+		w := mockWitness(t, access)
+		if test.change != nil {
+			test.change(w)
+		}
+		err := w.Verify(test.access)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected the %s to report %q, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestStoreLoad(t *testing.T) {
+	w := mockWitness(t, access)
+	store := &storage.Memory{Bag: make(map[ipld.Link][]byte)}
+	lsys := codecs.NewLinkSystem(store)
+	lnk, err := w.Store(lsys)
+	if err != nil {
+		t.Fatalf("unable to store witness: %v", err)
+	}
+	if codec := lnk.(cidlink.Link).Prefix().Codec; codec != cid.DagCBOR {
+		t.Errorf("expected a dag-cbor container, got codec %#x", codec)
+	}
+	// the trie nodes are stored under the codecs of their tries
+	var stateNodes, storageNodes int
+	for l := range store.Bag {
+		switch l.(cidlink.Link).Prefix().Codec {
+		case state_trie.MultiCodecType:
+			stateNodes++
+		case storage_trie.MultiCodecType:
+			storageNodes++
+		}
+	}
+	if stateNodes == 0 || storageNodes == 0 || stateNodes+storageNodes != len(w.State) {
+		t.Errorf("expected the %d trie nodes split into state and storage nodes, got %d and %d", len(w.State), stateNodes, storageNodes)
+	}
+	if _, ok := store.Bag[testutil.KeccakLink(t, state_trie.MultiCodecType, w.Headers[0].Root.Bytes())]; !ok {
+		t.Error("expected the state root node to be stored")
+	}
+
+	loaded, err := witness.Load(lsys, lnk)
+	if err != nil {
+		t.Fatalf("unable to load witness: %v", err)
+	}
+	enc, _ := w.Encode()
+	if reenc, _ := loaded.Encode(); !bytes.Equal(reenc, enc) {
+		t.Error("loaded witness does not encode like the stored one")
+	}
+	if err := loaded.Verify(access); err != nil {
+		t.Errorf("expected the loaded witness to suffice for its access list: %v", err)
+	}
+
+	w.State = append(w.State, []byte{0xc2, 0x80, 0x80})
+	if _, err := w.Store(lsys); err == nil {
+		t.Error("expected an error storing a witness with a trie node that is not reachable from its state root")
+	}
+}